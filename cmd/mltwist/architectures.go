@@ -0,0 +1,11 @@
+package main
+
+// Packages of supported architectures register themselves in the architecture
+// registry of the parser package when they are initialized.
+import (
+	_ "mltwist/internal/aarch64"
+	_ "mltwist/internal/ebpf"
+	_ "mltwist/internal/mips"
+	_ "mltwist/internal/riscv"
+	_ "mltwist/internal/x86"
+)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"mltwist/internal/consoleui"
 	"mltwist/internal/consoleui/disassemble"
@@ -8,38 +9,143 @@ import (
 	"mltwist/internal/deps"
 	"mltwist/internal/elf"
 	"mltwist/internal/parser"
-	"mltwist/internal/riscv"
 	"mltwist/internal/state"
 	"mltwist/internal/state/memory"
 	"mltwist/pkg/model"
 	"os"
 )
 
-func parseElf(filename string) (*elf.Memory, model.Addr, *elf.Memory, error) {
-	p, err := elf.NewParser(filename)
+// elfFile represents content of an ELF file the program needs to analyze and
+// modify the machine code.
+type elfFile struct {
+	filename string
+	base     model.Addr
+
+	code       *elf.Memory
+	entrypoint model.Addr
+	memory     *elf.Memory
+	symbols    *elf.Symbols
+	lines      *elf.LineTable
+
+	// arch is the architecture the file is built for.
+	arch parser.Architecture
+}
+
+// parseElf reads ELF file called filename loaded at address base. The
+// architecture of the file is detected from the file. The RISC-V target is
+// detected as well unless isa is non-empty.
+func parseElf(filename string, base model.Addr, isa string) (*elfFile, error) {
+	p, err := elf.NewParser(filename, base)
 	if err != nil {
-		return nil, 0, nil, fmt.Errorf("cannot create elf parser: %w", err)
+		return nil, fmt.Errorf("cannot create elf parser: %w", err)
 	}
 	defer p.Close()
 
 	code, err := p.MachineCode()
 	if err != nil {
-		err = fmt.Errorf("machine code cannot be extracted from ELF: %w", err)
-		return nil, 0, nil, err
+		return nil, fmt.Errorf(
+			"machine code cannot be extracted from ELF: %w", err)
 	}
 
 	mem, err := p.Memory()
 	if err != nil {
-		err = fmt.Errorf("cannot extract program memory from ELF: %w", err)
-		return nil, 0, nil, err
+		return nil, fmt.Errorf(
+			"cannot extract program memory from ELF: %w", err)
+	}
+
+	syms, err := p.Symbols()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read symbols from ELF: %w", err)
+	}
+
+	lines, err := p.LineTable()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read line table from ELF: %w", err)
+	}
+
+	arch, err := parser.NewArchitecture(p, isa)
+	if err != nil {
+		return nil, fmt.Errorf("cannot identify architecture: %w", err)
+	}
+
+	return &elfFile{
+		filename: filename,
+		base:     base,
+
+		code:       code,
+		entrypoint: p.Entrypoint(),
+		memory:     mem,
+		symbols:    syms,
+		lines:      lines,
+
+		arch: arch,
+	}, nil
+}
+
+// parseCode parses machine code of f. If reachable is set, only instructions
+// reachable from the entrypoint and from symbols of functions are parsed and
+// all other bytes of the machine code are returned as data. Otherwise the
+// whole machine code is parsed. Parsing is configured by options opts in both
+// cases.
+func parseCode(
+	f *elfFile,
+	p parser.Parser,
+	reachable bool,
+	opts ...parser.Option,
+) ([]parser.Instruction, []parser.Data, error) {
+	code := make([]parser.Block, len(f.code.Blocks))
+	for i, b := range f.code.Blocks {
+		code[i] = b
+	}
+
+	if !reachable {
+		ins, err := parser.Parse(code, p, opts...)
+		return ins, nil, err
+	}
+
+	starts := []model.Addr{f.entrypoint}
+	for _, s := range f.symbols.All() {
+		if s.Func {
+			starts = append(starts, s.Addr)
+		}
+	}
+
+	return parser.ParseReachable(code, p, starts, opts...)
+}
+
+// addSources attaches source code positions from line table t to all
+// instructions in instrs.
+func addSources(instrs []parser.Instruction, t *elf.LineTable) {
+	for i := range instrs {
+		if src, ok := t.Lookup(instrs[i].Addr); ok {
+			instrs[i].Source = parser.SourceLine{File: src.File, Line: src.Line}
+		}
+	}
+}
+
+func writeElf(f *elfFile, p *deps.Code, output string) error {
+	blocks := p.MachineCode()
+	code := make([]elf.CodeBlock, len(blocks))
+	for i, b := range blocks {
+		code[i] = b
+	}
+
+	elfParser, err := elf.NewParser(f.filename, f.base)
+	if err != nil {
+		return fmt.Errorf("cannot create elf parser: %w", err)
 	}
+	defer elfParser.Close()
 
-	return code, p.Entrypoint(), mem, nil
+	if err := elfParser.Write(output, code); err != nil {
+		return fmt.Errorf("cannot write ELF file: %w", err)
+	}
+
+	return nil
 }
 
-func runIU(p *deps.Code, mem *elf.Memory) error {
-	memBlocks := make([]memory.ByteBlock, len(mem.Blocks))
-	for i, b := range mem.Blocks {
+func runIU(f *elfFile, p *deps.Code) error {
+	memBlocks := make([]memory.ByteBlock, len(f.memory.Blocks))
+	for i, b := range f.memory.Blocks {
 		memBlocks[i] = b
 	}
 
@@ -54,11 +160,14 @@ func runIU(p *deps.Code, mem *elf.Memory) error {
 		stat := &state.State{
 			Regs: state.NewRegMap(),
 			Mems: memory.MemMap{
-				riscv.MemoryKey: m,
+				f.arch.MemoryKey: m,
 			},
 		}
+		if reset := f.arch.ResetState; reset != nil {
+			reset(stat.Regs)
+		}
 
-		emul, err := emulate.New(p, ip, stat)
+		emul, err := emulate.New(p, f.symbols, f.arch, ip, stat)
 		if err != nil {
 			return nil, fmt.Errorf("cannot create emulation mode: %w", err)
 		}
@@ -66,7 +175,27 @@ func runIU(p *deps.Code, mem *elf.Memory) error {
 		return emul, nil
 	}
 
-	disass := disassemble.New(p, emulF)
+	writeF := func(p *deps.Code, output string) error {
+		return writeElf(f, p, output)
+	}
+
+	patchF := func(addr model.Addr, s string) (parser.Instruction, error) {
+		asm := f.arch.Assembler
+		if asm == nil {
+			return parser.Instruction{}, fmt.Errorf(
+				"assembling of %s instructions is not supported",
+				f.arch.Name)
+		}
+
+		bytes, err := asm.Assemble(addr, s)
+		if err != nil {
+			return parser.Instruction{}, err
+		}
+
+		return parser.ParseInstruction(f.arch.Parser, addr, bytes)
+	}
+
+	disass := disassemble.New(p, f.symbols, f.arch, emulF, writeF, patchF)
 	ui, err := consoleui.New(disass)
 	if err != nil {
 		return fmt.Errorf("cannot create console UI: %w", err)
@@ -76,28 +205,47 @@ func runIU(p *deps.Code, mem *elf.Memory) error {
 }
 
 func run() error {
-	if l := len(os.Args); l != 2 {
-		return fmt.Errorf("unexpected number of arguments: %d", l)
+	base := flag.Uint64("base", 0,
+		"load base address of a position-independent executable or "+
+			"a relocatable object")
+	reachable := flag.Bool("reachable", false,
+		"disassemble only code reachable from the entrypoint and "+
+			"functions, treat the rest as data")
+	dataOnError := flag.Bool("data-on-error", false,
+		"represent words which are not valid instructions as data "+
+			"instead of failing")
+	isa := flag.String("isa", "",
+		"RISC-V ISA string (e.g. rv64ima) overriding the one detected "+
+			"from the ELF file")
+	flag.Parse()
+
+	if n := flag.NArg(); n != 1 {
+		return fmt.Errorf("unexpected number of arguments: %d", n)
 	}
-	filename := os.Args[1]
+	filename := flag.Arg(0)
 
-	code, entrypoint, memory, err := parseElf(filename)
+	f, err := parseElf(filename, model.Addr(*base), *isa)
 	if err != nil {
 		return fmt.Errorf("ELF parsing failed: %w", err)
 	}
 
-	riscvParser := riscv.NewParser(riscv.Variant64, riscv.ExtM, riscv.ExtA)
-	ins, err := parser.Parse(code, riscvParser)
+	var opts []parser.Option
+	if *dataOnError {
+		opts = append(opts, parser.DataOnError(f.arch.MinInstrLen, f.arch.ByteOrder))
+	}
+
+	ins, data, err := parseCode(f, f.arch.Parser, *reachable, opts...)
 	if err != nil {
 		return fmt.Errorf("instruction parsing failed: %w", err)
 	}
+	addSources(ins, f.lines)
 
-	program, err := deps.NewCode(entrypoint, ins)
+	program, err := deps.NewCode(f.entrypoint, ins, data)
 	if err != nil {
 		return fmt.Errorf("cannot parse model: %w", err)
 	}
 
-	return runIU(program, memory)
+	return runIU(f, program)
 }
 
 func main() {
@@ -0,0 +1,54 @@
+package aarch64
+
+import (
+	debugelf "debug/elf"
+	"encoding/binary"
+	"mltwist/internal/elf"
+	"mltwist/internal/parser"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"strconv"
+	"strings"
+)
+
+func init() { parser.Register(debugelf.EM_AARCH64, newArchitecture) }
+
+// newArchitecture creates description of AArch64 architecture. There is only
+// one A64 target, so ISA strings are not supported.
+func newArchitecture(_ *elf.Parser, isa string) (parser.Architecture, error) {
+	if isa != "" {
+		return parser.Architecture{}, parser.ErrISAUnsupported
+	}
+
+	p := NewParser()
+	return parser.Architecture{
+		Name:        "AArch64",
+		Parser:      p,
+		MemoryKey:   MemoryKey,
+		MinInstrLen: p.MinInstrLen(),
+		IPWidth:     width64,
+		ByteOrder:   binary.LittleEndian,
+
+		IPName:       "pc",
+		RegisterName: registerName,
+		// Code is displayed the same way GNU objdump displays it by
+		// default: with preferred aliases of instructions.
+		Syntax: model.Syntax{Aliases: true},
+	}, nil
+}
+
+// registerName returns name of register key in assembler code. Names defined by
+// the calling convention are returned for the frame pointer and the link
+// register if abi is set. Other registers are named by their keys.
+func registerName(key expr.Key, abi bool) string {
+	s := string(key)
+	if !strings.HasPrefix(s, "x") {
+		return s
+	}
+
+	n, err := strconv.ParseUint(s[1:], 10, 8)
+	if err != nil || n >= uint64(reg31) {
+		return s
+	}
+	return regNum(n).name(width64, zeroReg, abi)
+}
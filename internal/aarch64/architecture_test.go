@@ -0,0 +1,26 @@
+package aarch64
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterName(t *testing.T) {
+	tests := []struct {
+		key  expr.Key
+		abi  bool
+		want string
+	}{
+		{key: "x29", want: "x29"},
+		{key: "x29", abi: true, want: "fp"},
+		{key: "x30", abi: true, want: "lr"},
+		{key: "sp", abi: true, want: "sp"},
+		{key: NKey, abi: true, want: "n"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, registerName(tt.key, tt.abi), tt.key)
+	}
+}
@@ -0,0 +1,147 @@
+package aarch64
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+const (
+	// condBits is number of bits of a condition code.
+	condBits = 4
+
+	// condAL is the condition code which always holds.
+	condAL = 0b1110
+	// condNV is the condition code which always holds as well. Unlike
+	// condAL, it's not an inversion of any other condition.
+	condNV = 0b1111
+)
+
+// condNames are names of condition codes in assembler code. Index in the array
+// is the value of condition code encoded in an instruction.
+var condNames = [1 << condBits]string{
+	"eq", "ne", "cs", "cc", "mi", "pl", "vs", "vc",
+	"hi", "ls", "ge", "lt", "gt", "le", "al", "nv",
+}
+
+// condName returns name of condition code cond in assembler code.
+func condName(cond uint32) string { return condNames[cond] }
+
+// invertCond returns condition code which holds if cond doesn't hold. It's
+// undefined for condAL and condNV.
+func invertCond(cond uint32) uint32 { return cond ^ 1 }
+
+// flagLoad loads value of condition flag identified by key k.
+func flagLoad(k expr.Key) expr.Expr { return expr.NewRegLoad(k, flagWidth) }
+
+// condition returns an expression of width w which evaluates to t if condition
+// code cond holds for the current value of NZCV flags and to f otherwise.
+//
+// Condition codes are organized in pairs where the lowest bit of a condition
+// code inverts the condition. The only exception is the last pair (al and nv)
+// which both always hold.
+func condition(cond uint32, t, f expr.Expr, w expr.Width) expr.Expr {
+	if cond&1 != 0 && cond != condNV {
+		t, f = f, t
+	}
+
+	n, z, c, v := flagLoad(NKey), flagLoad(ZKey), flagLoad(CKey), flagLoad(VKey)
+	switch cond >> 1 {
+	case 0: // eq
+		return exprtools.BoolCond(z, t, f, w)
+	case 1: // cs
+		return exprtools.BoolCond(c, t, f, w)
+	case 2: // mi
+		return exprtools.BoolCond(n, t, f, w)
+	case 3: // vs
+		return exprtools.BoolCond(v, t, f, w)
+	case 4: // hi
+		return exprtools.BoolCond(c, exprtools.BoolCond(z, f, t, w), f, w)
+	case 5: // ge
+		return exprtools.Eq(n, v, t, f, w)
+	case 6: // gt
+		return exprtools.BoolCond(z, f, exprtools.Eq(n, v, t, f, w), w)
+	default: // al
+		return t
+	}
+}
+
+// nzcv holds values of NZCV condition flags. Every value is an expression
+// evaluating to 1 if the flag is set and to 0 otherwise.
+type nzcv struct {
+	n, z, c, v expr.Expr
+}
+
+// nzcvConst returns value of flags encoded in the lowest 4 bits of bits. The
+// order of bits from the highest one is N, Z, C and V.
+func nzcvConst(bits uint32) nzcv {
+	flag := func(b uint32) expr.Expr {
+		return expr.ConstFromUint(uint8(bits >> b & 1))
+	}
+	return nzcv{n: flag(3), z: flag(2), c: flag(1), v: flag(0)}
+}
+
+// resultFlags returns N and Z flags set according to value res of width w and
+// carry flag c and overflow flag v.
+func resultFlags(res expr.Expr, c, v expr.Expr, w expr.Width) nzcv {
+	return nzcv{
+		n: exprtools.Bool(exprtools.IntNegative(res, w)),
+		z: exprtools.Not(res),
+		c: c,
+		v: v,
+	}
+}
+
+// logicFlags returns flags set by logical operations producing value res of
+// width w. Such operations always clear C and V flags.
+func logicFlags(res expr.Expr, w expr.Width) nzcv {
+	return resultFlags(res, expr.Zero, expr.Zero, w)
+}
+
+// selectFlags returns flags t if condition code cond holds and flags f
+// otherwise.
+func selectFlags(cond uint32, t, f nzcv) nzcv {
+	sel := func(t, f expr.Expr) expr.Expr {
+		return condition(cond, t, f, flagWidth)
+	}
+	return nzcv{n: sel(t.n, f.n), z: sel(t.z, f.z), c: sel(t.c, f.c), v: sel(t.v, f.v)}
+}
+
+// store returns effects writing flags f to their registers.
+func (f nzcv) store() []expr.Effect {
+	return []expr.Effect{
+		expr.NewRegStore(f.n, NKey, flagWidth),
+		expr.NewRegStore(f.z, ZKey, flagWidth),
+		expr.NewRegStore(f.c, CKey, flagWidth),
+		expr.NewRegStore(f.v, VKey, flagWidth),
+	}
+}
+
+// addCarry returns sum of values e1 and e2 of width w and carry c together with
+// flags the sum sets.
+//
+// Carry flag is set if the unsigned sum doesn't fit w bytes, overflow flag is
+// set if the signed sum does not. Subtraction of e2 is implemented as addition
+// of bitwise negation of e2 with carry set.
+func addCarry(e1, e2, c expr.Expr, w expr.Width) (expr.Expr, nzcv) {
+	res := expr.NewBinary(expr.Add, expr.NewBinary(expr.Add, e1, e2, w), c, w)
+
+	// Both arguments are zero extended to double width, so the bit above
+	// the sum is the unsigned carry.
+	wide := expr.NewBinary(expr.Add, expr.NewBinary(expr.Add, e1, e2, 2*w), c, 2*w)
+	carry := expr.NewBinary(expr.Rsh, wide, expr.ConstFromUint(w.Bits()), 2*w)
+
+	// Signed overflow happens if both arguments have the same sign and
+	// the sign of the sum differs.
+	overflow := exprtools.BitAnd(
+		exprtools.BitXor(res, e1, w),
+		exprtools.BitXor(res, e2, w),
+		w,
+	)
+
+	return res, resultFlags(
+		res,
+		exprtools.Bool(carry),
+		exprtools.Bool(exprtools.IntNegative(overflow, w)),
+		w,
+	)
+}
@@ -0,0 +1,67 @@
+package aarch64
+
+import (
+	"fmt"
+	"mltwist/internal/exprtransform"
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// conditionHolds is a reference implementation of condition codes as described
+// by the architecture manual.
+func conditionHolds(cond uint32, n, z, c, v bool) bool {
+	var res bool
+	switch cond >> 1 {
+	case 0:
+		res = z
+	case 1:
+		res = c
+	case 2:
+		res = n
+	case 3:
+		res = v
+	case 4:
+		res = c && !z
+	case 5:
+		res = n == v
+	case 6:
+		res = n == v && !z
+	default:
+		res = true
+	}
+
+	if cond&1 != 0 && cond != condNV {
+		res = !res
+	}
+	return res
+}
+
+func TestCondition(t *testing.T) {
+	for cond := uint32(0); cond < 1<<condBits; cond++ {
+		cond := cond
+		t.Run(condName(cond), func(t *testing.T) {
+			for flags := uint64(0); flags < 16; flags++ {
+				regs := map[expr.Key]uint64{
+					NKey: flags >> 3 & 1,
+					ZKey: flags >> 2 & 1,
+					CKey: flags >> 1 & 1,
+					VKey: flags & 1,
+				}
+
+				ex := condition(cond, expr.One, expr.Zero, expr.Width8)
+				ex = exprtransform.ReplaceAll(ex, func(r expr.RegLoad) (expr.Expr, bool) {
+					return expr.NewConstUint(regs[r.Key()], r.Width()), true
+				})
+				c, ok := exprtransform.ConstFold(ex).(expr.Const)
+				require.True(t, ok)
+				v, _ := expr.ConstUint[uint64](c)
+
+				want := conditionHolds(cond, regs[NKey] != 0, regs[ZKey] != 0,
+					regs[CKey] != 0, regs[VKey] != 0)
+				require.Equal(t, want, v != 0, fmt.Sprintf("nzcv: %04b", flags))
+			}
+		})
+	}
+}
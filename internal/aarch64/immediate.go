@@ -0,0 +1,196 @@
+package aarch64
+
+import (
+	"fmt"
+	"math/bits"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// widthMask returns mask of all bits of a value of width w.
+func widthMask(w expr.Width) uint64 {
+	if w >= width64 {
+		return ^uint64(0)
+	}
+	return uint64(1)<<w.Bits() - 1
+}
+
+// immConst creates a constant of width w with value v. Bits of v which don't
+// fit into w bytes are dropped.
+func immConst(v uint64, w expr.Width) expr.Const {
+	return expr.NewConstUint(v&widthMask(w), w)
+}
+
+// hexImm returns immediate value v in assembler code written in hexadecimal
+// notation.
+func hexImm(v uint64) string { return fmt.Sprintf("#0x%x", v) }
+
+// decImm returns immediate value v in assembler code written in decimal
+// notation.
+func decImm(v int64) string { return fmt.Sprintf("#%d", v) }
+
+// addrOperand returns address a in assembler code. Addresses are targets of
+// PC-relative instructions.
+func addrOperand(a uint64) string { return fmt.Sprintf("0x%x", a) }
+
+// decodeBitMask decodes immediate value of logical instructions with immediate
+// operand. The value is encoded in fields N, immr and imms of an instruction.
+// Number of bits of the value is given by width w. It returns false if the
+// fields don't encode any value.
+//
+// The value is a pattern of elements of 2, 4, 8, 16, 32 or 64 bits repeated
+// in the whole value. Every element consists of a block of ones rotated right
+// by immr bits. Size of an element is given by the highest bit of N and
+// inverted imms, the remaining bits of imms encode number of ones minus one.
+func decodeBitMask(n, immr, imms uint32, w expr.Width) (uint64, bool) {
+	combined := n<<6 | ^imms&0x3f
+	if combined == 0 {
+		return 0, false
+	}
+
+	esize := uint(1) << (bits.Len32(combined) - 1)
+	if esize < 2 || esize > uint(w.Bits()) {
+		return 0, false
+	}
+
+	levels := uint32(esize - 1)
+	s, r := uint(imms&levels), uint(immr&levels)
+	if s == uint(levels) {
+		return 0, false
+	}
+
+	emask := ^uint64(0) >> (64 - esize)
+	ones := uint64(1)<<(s+1) - 1
+	elem := (ones>>r | ones<<(esize-r)) & emask
+
+	var value uint64
+	for i := uint(0); i < uint(w.Bits()); i += esize {
+		value |= elem << i
+	}
+	return value, true
+}
+
+// moveWideable indicates that value v of width w can be written by a single
+// movz or movn instruction. In other words, all 16 bit chunks of either v or
+// negation of v but one are zero.
+func moveWideable(v uint64, w expr.Width) bool {
+	single := func(v uint64) bool {
+		for sh := 0; sh < int(w.Bits()); sh += 16 {
+			if v&^(0xffff<<sh) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	return single(v) || single(^v&widthMask(w))
+}
+
+// shiftType is type of a shift applied on a register operand.
+type shiftType uint32
+
+const (
+	shiftLSL shiftType = iota
+	shiftLSR
+	shiftASR
+	shiftROR
+)
+
+// shiftNames are names of shift types in assembler code.
+var shiftNames = [...]string{"lsl", "lsr", "asr", "ror"}
+
+func (t shiftType) String() string { return shiftNames[t] }
+
+// apply shifts value e of width w by amount bits.
+func (t shiftType) apply(e expr.Expr, amount uint32, w expr.Width) expr.Expr {
+	if amount == 0 {
+		return e
+	}
+
+	sh := expr.ConstFromUint(uint8(amount))
+	switch t {
+	case shiftLSL:
+		return expr.NewBinary(expr.Lsh, e, sh, w)
+	case shiftLSR:
+		return expr.NewBinary(expr.Rsh, e, sh, w)
+	case shiftASR:
+		return exprtools.RshA(e, sh, w)
+	default:
+		return exprtools.RotateRight(e, sh, w)
+	}
+}
+
+// shiftedReg describes register operand shifted by a constant amount encoded in
+// bits [22:23] (type of shift) and [10:15] (amount) of an instruction.
+type shiftedReg struct {
+	reg    regNum
+	shift  shiftType
+	amount uint32
+}
+
+// shiftedRm returns shifted register operand of instruction i. The register
+// is encoded in rm field.
+func shiftedRm(i instruction) shiftedReg {
+	return shiftedReg{
+		reg:    i.rm(),
+		shift:  shiftType(i.field(22, 2)),
+		amount: i.field(10, 6),
+	}
+}
+
+// invalid indicates that the shift is an unallocated encoding for registers of
+// width w. Rotation is allowed only if ror is set.
+func (s shiftedReg) invalid(w expr.Width, ror bool) bool {
+	return s.amount >= uint32(w.Bits()) || (!ror && s.shift == shiftROR)
+}
+
+// operands returns the register and the shift (if there is any) in assembler
+// code.
+func (s shiftedReg) operands(w expr.Width, abi bool) []string {
+	ops := []string{s.reg.name(w, zeroReg, abi)}
+	if s.shift != shiftLSL || s.amount != 0 {
+		ops = append(ops, fmt.Sprintf("%s %s", s.shift, decImm(int64(s.amount))))
+	}
+	return ops
+}
+
+// value returns value of the shifted register of width w.
+func (s shiftedReg) value(w expr.Width) expr.Expr {
+	return s.shift.apply(regLoad(s.reg, w, zeroReg), s.amount, w)
+}
+
+// extendNames are names of extensions of register operands in assembler code.
+// Index in the array is the option field of an instruction.
+var extendNames = [...]string{
+	"uxtb", "uxth", "uxtw", "uxtx",
+	"sxtb", "sxth", "sxtw", "sxtx",
+}
+
+// extend returns value e of width w extended according to option field of an
+// instruction. The lowest 2 bits of option are log2 of number of bytes of e
+// which are extended, the highest bit indicates sign extension.
+func extend(e expr.Expr, option uint32, w expr.Width) expr.Expr {
+	bytes := expr.Width(1) << (option & 0b11)
+	if bytes >= w {
+		return e
+	}
+
+	if option&0b100 != 0 {
+		return sext(e, uint8(bytes.Bits()-1), w)
+	}
+	return exprtools.MaskBits(e, exprtools.BitCnt(bytes.Bits()), w)
+}
+
+// extendRegWidth returns width of register extended according to option field
+// of an instruction. Doubleword extensions use 64 bit registers, all other
+// extensions use 32 bit registers.
+func extendRegWidth(option uint32) expr.Width {
+	if option&0b11 == 0b11 {
+		return width64
+	}
+	return width32
+}
+
+// sext sign extends value e where bit signBit is the sign bit to width w.
+func sext(e expr.Expr, signBit uint8, w expr.Width) expr.Expr {
+	return exprtools.SignExtend(e, expr.ConstFromUint(signBit), w)
+}
@@ -0,0 +1,103 @@
+package aarch64
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeBitMask(t *testing.T) {
+	tests := []struct {
+		name          string
+		n, immr, imms uint32
+		w             expr.Width
+		want          uint64
+		wantInvalid   bool
+	}{{
+		name: "single_bit",
+		n:    1,
+		w:    width64,
+		want: 1,
+	}, {
+		name: "rotated_64",
+		n:    1,
+		immr: 4,
+		imms: 0b000111,
+		w:    width64,
+		want: 0xf0000000_0000000f,
+	}, {
+		name: "element_32",
+		imms: 0b001111,
+		w:    width64,
+		want: 0x0000ffff_0000ffff,
+	}, {
+		name: "element_32_in_32_bits",
+		imms: 0b001111,
+		w:    width32,
+		want: 0x0000ffff,
+	}, {
+		name: "element_2",
+		imms: 0b111100,
+		w:    width64,
+		want: 0x55555555_55555555,
+	}, {
+		name: "element_8_rotated",
+		immr: 1,
+		imms: 0b110010,
+		w:    width32,
+		want: 0x83838383,
+	}, {
+		name:        "all_ones",
+		n:           1,
+		imms:        0b111111,
+		w:           width64,
+		wantInvalid: true,
+	}, {
+		name:        "all_ones_element",
+		imms:        0b111101,
+		w:           width64,
+		wantInvalid: true,
+	}, {
+		name:        "no_element",
+		imms:        0b111111,
+		w:           width64,
+		wantInvalid: true,
+	}, {
+		name:        "element_64_in_32_bits",
+		n:           1,
+		w:           width32,
+		wantInvalid: true,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			v, ok := decodeBitMask(tt.n, tt.immr, tt.imms, tt.w)
+			require.Equal(t, !tt.wantInvalid, ok)
+			if ok {
+				require.Equal(t, tt.want, v)
+			}
+		})
+	}
+}
+
+func TestMoveWideable(t *testing.T) {
+	r := require.New(t)
+
+	r.True(moveWideable(0, width64))
+	r.True(moveWideable(0xffff_0000_0000, width64))
+	r.True(moveWideable(0xffffffff_fffffffe, width64))
+	r.True(moveWideable(0xffff1234, width32))
+	r.False(moveWideable(0x1_0001, width64))
+	r.False(moveWideable(0xffff1234, width64))
+}
+
+func TestSignExtend(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(int64(5), signExtend(5, 4))
+	r.Equal(int64(-8), signExtend(8, 4))
+	r.Equal(int64(-1), signExtend(0x3ffffff, 26))
+	r.Equal(int64(0x1ffffff), signExtend(0x1ffffff, 26))
+}
@@ -0,0 +1,167 @@
+package aarch64
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"strings"
+)
+
+// instruction represents a parsed A64 instruction. Unlike the instructionType
+// which describes only instruction opcode and properties of the opcode,
+// instruction represents the whole instruction including encoding of registers,
+// immediate values and last but not least the position in the code.
+type instruction struct {
+	// addr is virtual address of the instruction in a program memory.
+	addr model.Addr
+
+	// value represents the value of the instruction in the memory.
+	//
+	// As encoding of A64 instructions is little-endian, the byte of an
+	// instruction with the smallest in-memory address is represented by
+	// bits [0..7] of the value.
+	value uint32
+
+	// instrType refers the type of the instruction.
+	instrType *instructionType
+}
+
+// newInstruction crates a new instance of instruction. The new instruction is
+// at address a, is represented of first instructionLen bytes of b and has type
+// t.
+func newInstruction(a model.Addr, b []byte, t *instructionType) instruction {
+	if l := len(b); l < instructionLen {
+		panic(fmt.Sprintf("not enough bytes to represent valid opcode: %d", l))
+	}
+
+	return instruction{
+		addr:      a,
+		value:     opcodeValue(b[:instructionLen]),
+		instrType: t,
+	}
+}
+
+// field returns bits bits of the instruction starting with bit at index
+// offset.
+func (i instruction) field(offset uint8, bits uint8) uint32 {
+	return bitRange(i.value, offset, bits)
+}
+
+// bit indicates that bit n of the instruction is set.
+func (i instruction) bit(n uint8) bool { return i.field(n, 1) != 0 }
+
+// rd returns register number encoded in bits [0:4] of the instruction. The
+// field encodes the output register or the register transferred by loads and
+// stores (Rt).
+func (i instruction) rd() regNum { return regNum(i.field(0, regBits)) }
+
+// rn returns register number encoded in bits [5:9] of the instruction.
+func (i instruction) rn() regNum { return regNum(i.field(5, regBits)) }
+
+// ra returns register number encoded in bits [10:14] of the instruction. The
+// field encodes the addend register of multiply-add instructions or the second
+// register transferred by load and store pair instructions (Rt2).
+func (i instruction) ra() regNum { return regNum(i.field(10, regBits)) }
+
+// rm returns register number encoded in bits [16:20] of the instruction. The
+// field encodes the second input register or the status register of store
+// exclusive instructions (Rs).
+func (i instruction) rm() regNum { return regNum(i.field(16, regBits)) }
+
+// sf indicates that the instruction operates 64 bit registers.
+func (i instruction) sf() bool { return i.bit(31) }
+
+// width returns width of registers the instruction operates.
+func (i instruction) width() expr.Width {
+	if i.sf() {
+		return width64
+	}
+	return width32
+}
+
+// next returns address of the instruction following i.
+func (i instruction) next() model.Addr { return i.addr + instructionLen }
+
+// target returns the address PC-relative offset of the instruction refers.
+func (i instruction) target() model.Addr {
+	t := i.instrType.offset
+	return t.base(i.addr) + model.Addr(t.parse(i.value))
+}
+
+var _ model.Relocator = instruction{}
+
+// Name returns name of the instruction in assembler code.
+func (i instruction) Name() string {
+	if m := i.instrType.mnemonic; m != nil {
+		return m(i)
+	}
+	return i.instrType.name
+}
+
+// String returns a string representation of an instruction which corresponds to
+// standard A64 assembler notation of instructions.
+//
+// Instructions are written without aliases, so for example mov is written as
+// orr or add instruction it's encoded as.
+func (i instruction) String() string { return i.Format(model.Syntax{}, nil) }
+
+var _ model.Formatter = instruction{}
+
+// Format returns a string representation of an instruction in assembler syntax
+// s. If aliases are enabled, instructions are written as their preferred
+// aliases the same way GNU objdump does.
+func (i instruction) Format(s model.Syntax, _ model.PlatformDetails) string {
+	name, ops := i.Name(), i.instrType.operands(i, s.ABINames)
+	if a := i.instrType.alias; s.Aliases && a != nil {
+		if n, aliasOps, ok := a(i, ops); ok {
+			name, ops = n, aliasOps
+		}
+	}
+
+	return instructionString(name, ops...)
+}
+
+// instructionString returns assembler code of an instruction called name with
+// operands ops.
+func instructionString(name string, ops ...string) string {
+	if len(ops) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s %s", name, strings.Join(ops, ", "))
+}
+
+// bytes returns the instruction encoded as a sequence of bytes in the memory.
+func (i instruction) bytes() []byte {
+	bs := make([]byte, instructionLen)
+	for j := range bs {
+		bs[j] = byte(i.value >> (8 * j))
+	}
+	return bs
+}
+
+// Relocate returns the instruction and its opcode bytes as if the instruction
+// was placed at address a.
+//
+// Offsets of PC-relative instructions are re-encoded so that the instruction
+// refers the same address as it referred before the move. An error is returned
+// if the new offset cannot be represented by the instruction. Other
+// instructions are returned unchanged.
+func (i instruction) Relocate(
+	a model.Addr,
+) (model.PlatformDetails, []byte, error) {
+	t := i.instrType.offset
+	if t == offsetNone {
+		i.addr = a
+		return i, i.bytes(), nil
+	}
+
+	offset := model.Offset(t.base(a), i.target())
+	encoded, err := t.encode(offset)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot encode offset of %q: %w", i, err)
+	}
+
+	i.addr = a
+	i.value = i.value&^t.fieldMask() | encoded
+	return i, i.bytes(), nil
+}
@@ -0,0 +1,215 @@
+package aarch64
+
+import (
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstruction_Format(t *testing.T) {
+	abi := model.Syntax{ABINames: true}
+	aliases := model.Syntax{Aliases: true}
+	both := model.Syntax{ABINames: true, Aliases: true}
+
+	tests := []struct {
+		name   string
+		bytes  []byte
+		syntax model.Syntax
+		want   string
+	}{{
+		// orr x0, xzr, x1
+		name:  "default",
+		bytes: []byte{0xe0, 0x03, 0x01, 0xaa},
+		want:  "orr x0, xzr, x1",
+	}, {
+		// orr x0, xzr, x1
+		name:   "mov_register",
+		bytes:  []byte{0xe0, 0x03, 0x01, 0xaa},
+		syntax: aliases,
+		want:   "mov x0, x1",
+	}, {
+		// add x0, sp, #0
+		name:   "mov_sp",
+		bytes:  []byte{0xe0, 0x03, 0x00, 0x91},
+		syntax: aliases,
+		want:   "mov x0, sp",
+	}, {
+		// movz w0, #0x1, lsl #16
+		name:   "mov_wide",
+		bytes:  []byte{0x20, 0x00, 0xa0, 0x52},
+		syntax: aliases,
+		want:   "mov w0, #0x10000",
+	}, {
+		// movn x0, #0
+		name:   "mov_inverted",
+		bytes:  []byte{0x00, 0x00, 0x80, 0x92},
+		syntax: aliases,
+		want:   "mov x0, #0xffffffffffffffff",
+	}, {
+		// subs wzr, w1, #4
+		name:   "cmp",
+		bytes:  []byte{0x3f, 0x10, 0x00, 0x71},
+		syntax: aliases,
+		want:   "cmp w1, #0x4",
+	}, {
+		// ubfm x0, x1, #60, #59
+		name:   "lsl",
+		bytes:  []byte{0x20, 0xec, 0x7c, 0xd3},
+		syntax: aliases,
+		want:   "lsl x0, x1, #4",
+	}, {
+		// csinc w0, wzr, wzr, ne
+		name:   "cset",
+		bytes:  []byte{0xe0, 0x17, 0x9f, 0x1a},
+		syntax: aliases,
+		want:   "cset w0, eq",
+	}, {
+		// stp x29, x30, [sp, #-16]!
+		name:   "abi_names",
+		bytes:  []byte{0xfd, 0x7b, 0xbf, 0xa9},
+		syntax: abi,
+		want:   "stp fp, lr, [sp, #-16]!",
+	}, {
+		// ldr x0, [x1, w2, sxtw #3]
+		name:  "register_offset",
+		bytes: []byte{0x20, 0xd8, 0x62, 0xf8},
+		want:  "ldr x0, [x1, w2, sxtw #3]",
+	}, {
+		// ret
+		name:   "ret",
+		bytes:  []byte{0xc0, 0x03, 0x5f, 0xd6},
+		syntax: both,
+		want:   "ret",
+	}, {
+		// b.hs #8
+		name:  "b.cond",
+		bytes: []byte{0x42, 0x00, 0x00, 0x54},
+		want:  "b.cs 0x1008",
+	}, {
+		// dmb ishld
+		name:  "barrier",
+		bytes: []byte{0xbf, 0x39, 0x03, 0xd5},
+		want:  "dmb ishld",
+	}, {
+		// mrs x0, tpidr_el0
+		name:  "system_register",
+		bytes: []byte{0x40, 0xd0, 0x3b, 0xd5},
+		want:  "mrs x0, tpidr_el0",
+	}}
+
+	p := NewParser()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := p.Parse(testAddr, tt.bytes)
+			require.NoError(t, err)
+
+			f := ins.Details.(model.Formatter)
+			require.Equal(t, tt.want, f.Format(tt.syntax, nil))
+		})
+	}
+}
+
+func TestInstruction_Relocate(t *testing.T) {
+	tests := []struct {
+		name   string
+		bytes  []byte
+		addr   model.Addr
+		to     model.Addr
+		hasErr bool
+	}{{
+		// b #0x100
+		name:  "b",
+		bytes: []byte{0x40, 0x00, 0x00, 0x14},
+		addr:  0x1000,
+		to:    0x1080,
+	}, {
+		// bl #-0x1000
+		name:  "bl",
+		bytes: []byte{0x00, 0xfc, 0xff, 0x97},
+		addr:  0x100000,
+		to:    0x8000000,
+	}, {
+		// bl #0x1000
+		name:   "bl_too_far",
+		bytes:  []byte{0x00, 0x04, 0x00, 0x94},
+		addr:   0x1000,
+		to:     0x9000000,
+		hasErr: true,
+	}, {
+		// b.eq #0x40
+		name:  "b.cond",
+		bytes: []byte{0x00, 0x02, 0x00, 0x54},
+		addr:  0x1000,
+		to:    0x0ff0,
+	}, {
+		// b.eq #0x40
+		name:   "b.cond_too_far",
+		bytes:  []byte{0x00, 0x02, 0x00, 0x54},
+		addr:   0x1000,
+		to:     0x200000,
+		hasErr: true,
+	}, {
+		// tbz w0, #1, #0x20
+		name:  "tbz",
+		bytes: []byte{0x00, 0x01, 0x08, 0x36},
+		addr:  0x1000,
+		to:    0x1010,
+	}, {
+		// ldr x0, #0x100
+		name:  "ldr_literal",
+		bytes: []byte{0x00, 0x08, 0x00, 0x58},
+		addr:  0x1000,
+		to:    0x2000,
+	}, {
+		// adr x0, #3
+		name:  "adr",
+		bytes: []byte{0x00, 0x00, 0x00, 0x70},
+		addr:  0x1000,
+		to:    0x1010,
+	}, {
+		// adrp x0, #0x2000
+		name:  "adrp",
+		bytes: []byte{0x00, 0x00, 0x00, 0xd0},
+		addr:  0x1234,
+		to:    0x10008,
+	}, {
+		// add x0, x1, #5
+		name:  "not_relative",
+		bytes: []byte{0x20, 0x14, 0x00, 0x91},
+		addr:  0x1000,
+		to:    0x1010,
+	}}
+
+	p := NewParser()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			ins, err := p.Parse(tt.addr, tt.bytes)
+			r.NoError(err)
+
+			details, bytes, err := ins.Details.(model.Relocator).Relocate(tt.to)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			moved, err := p.Parse(tt.to, bytes)
+			r.NoError(err)
+			r.Equal(moved.Details, details)
+
+			orig, i := ins.Details.(instruction), details.(instruction)
+			r.Equal(tt.to, i.addr)
+			r.Equal(orig.Name(), i.Name())
+			if orig.instrType.offset != offsetNone {
+				r.Equal(orig.target(), i.target())
+			} else {
+				r.Equal(orig.value, i.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package aarch64
+
+import (
+	"fmt"
+	"mltwist/internal/opcode"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"strings"
+)
+
+// instructionLen is length of A64 instruction opcode in bytes.
+const instructionLen = 4
+
+// instructionType describes a single A64 instruction opcode.
+type instructionType struct {
+	// name is a symbolic name of an instruction in assembler code.
+	//
+	// For example: add, ldr, b.cond, stp etc.
+	name string
+	// opcode describes opcode bits in an instruction.
+	opcode opcode.Opcode
+
+	// mnemonic returns the name of instruction i in assembler code if it
+	// depends on operands of the instruction (for example condition of
+	// a conditional branch). The name field is used if mnemonic is nil.
+	mnemonic func(i instruction) string
+	// operands returns operands of instruction i in assembler code in the
+	// order they are written. Registers are referred by names defined by
+	// the calling convention if abi is set.
+	operands func(i instruction, abi bool) []string
+	// alias returns name and operands of the preferred alias of
+	// instruction i with operands ops. It returns false if i has no alias.
+	// Instructions of types with nil alias have no aliases.
+	//
+	// Operands ops are those returned by operands function, so aliases
+	// can reuse names of registers in there.
+	alias func(i instruction, ops []string) (string, []string, bool)
+	// reserved indicates that operands of instruction i make the
+	// instruction an unallocated encoding even though its opcode matched.
+	// All encodings of the opcode are allocated if reserved is nil.
+	reserved func(i instruction) bool
+
+	// offset describes encoding of PC-relative offset in an instruction.
+	offset offsetType
+
+	// instrType is set of instruction types of an opcode.
+	instrType model.Type
+	// typeOf returns instruction type of instruction i if it depends on
+	// operands of the instruction (for example system register writes).
+	// The instrType field is used if typeOf is nil.
+	typeOf func(i instruction) model.Type
+	// order returns memory accesses ordered by instruction i. It has to be
+	// set if the instruction is of model.TypeMemOrder type and it's not a
+	// full barrier.
+	order func(i instruction) model.MemOrder
+
+	// effects is a function which based on specific instruction i evaluates
+	// all effects of the given instruction.
+	//
+	// The array returned is allowed to contain nil expr.Effect values.
+	// Those nils will be interpreted as no effects. The reasoning behind
+	// nil effects is simplification of handling of writes to the zero
+	// register which are effectively defined as having no side effect.
+	effects func(i instruction) []expr.Effect
+}
+
+// Opcode returns the opcode definition of a given instruction type.
+func (t instructionType) Opcode() opcode.Opcode { return t.opcode }
+func (t instructionType) Name() string          { return t.name }
+
+// pattern creates an opcode from a textual description of bits of an
+// instruction. The description lists all 32 bits of the instruction starting
+// with bit 31 as they are written in the A64 encoding tables. Character 0 and 1
+// are bits of the opcode, x is any other bit. Spaces are ignored and they are
+// meant to split the description to bytes.
+//
+// This function panics if the description is invalid.
+func pattern(p string) opcode.Opcode {
+	bits := strings.ReplaceAll(p, " ", "")
+	if len(bits) != 8*instructionLen {
+		panic(fmt.Sprintf("invalid opcode pattern length: %q", p))
+	}
+
+	var value, mask uint32
+	for _, b := range bits {
+		value <<= 1
+		mask <<= 1
+		switch b {
+		case '0':
+			mask |= 1
+		case '1':
+			value |= 1
+			mask |= 1
+		case 'x':
+		default:
+			panic(fmt.Sprintf("invalid opcode pattern bit %q: %q", b, p))
+		}
+	}
+
+	return opcode.Opcode{
+		Bytes: instruction{value: value}.bytes(),
+		Mask:  instruction{value: mask}.bytes(),
+	}
+}
+
+// opcodeValue converts the first instructionLen bytes of bs into a number. The
+// encoding of A64 instructions is little-endian.
+func opcodeValue(bs []byte) uint32 {
+	var value uint32
+	for i := instructionLen - 1; i >= 0; i-- {
+		value = value<<8 | uint32(bs[i])
+	}
+	return value
+}
+
+// validate checks that instructionType description is valid (follows all the
+// assumptions the code imposes on the struct).
+func (t instructionType) validate() error {
+	if t.name == "" {
+		return fmt.Errorf("instruction name cannot be empty")
+	}
+	if err := t.opcode.Validate(); err != nil {
+		return fmt.Errorf("invalid opcode description: %w", err)
+	}
+	if l := len(t.opcode.Mask); l != instructionLen {
+		return fmt.Errorf("invalid opcode length: %d", l)
+	}
+
+	if m := opcodeValue(t.opcode.Mask) & t.offset.fieldMask(); m != 0 {
+		return fmt.Errorf("opcode bits overlap with offset: 0x%x", m)
+	}
+	if t.typeOf != nil && t.instrType != model.TypeNone {
+		return fmt.Errorf("instruction type is given by operands")
+	}
+	if t.order != nil && t.typeOf == nil && !t.instrType.MemOrder() {
+		return fmt.Errorf("memory order of instruction not ordering memory")
+	}
+
+	if t.operands == nil {
+		return fmt.Errorf("operands function must be always set")
+	}
+	if t.effects == nil {
+		return fmt.Errorf("effects function must be always set")
+	}
+
+	return nil
+}
+
+// typeOfInstr returns instruction type of instruction i of type t.
+func (t instructionType) typeOfInstr(i instruction) model.Type {
+	if t.typeOf != nil {
+		return t.typeOf(i)
+	}
+	return t.instrType
+}
+
+// memOrder returns memory accesses ordered by instruction i of type t.
+func (t instructionType) memOrder(i instruction) model.MemOrder {
+	if t.order == nil || !t.typeOfInstr(i).MemOrder() {
+		return model.MemOrder{}
+	}
+	return t.order(i)
+}
+
+// validEffects filters nil effects from a list of effects returned by effects
+// function.
+//
+// As A64 has the zero register which means to drop any result of the
+// operation, it's possible that effects function of an instructionType returns
+// nil expr.Effect. On the other hand the interface defined in model.Instruction
+// requires all effects to be non-nil. Consequently, we have to filter nil
+// expression away from the array.
+func (t instructionType) validEffects(i instruction) []expr.Effect {
+	effs := t.effects(i)
+	if len(effs) == 0 {
+		return nil
+	}
+
+	effects := make([]expr.Effect, 0, len(effs))
+	for _, e := range effs {
+		if e != nil {
+			effects = append(effects, e)
+		}
+	}
+
+	return effects
+}
+
+// mergeInstructions merges multiple lists of instructionType into a single
+// list.
+func mergeInstructions(lists ...[]*instructionType) []*instructionType {
+	length := 0
+	for _, a := range lists {
+		length += len(a)
+	}
+
+	merged := make([]*instructionType, 0, length)
+	for _, a := range lists {
+		merged = append(merged, a...)
+	}
+
+	return merged
+}
@@ -0,0 +1,122 @@
+package aarch64
+
+import (
+	"fmt"
+	"mltwist/pkg/model"
+)
+
+// pageSize is size of a page adrp instruction computes addresses of.
+const pageSize = 0x1000
+
+// offsetType describes encoding of a PC-relative offset in an instruction.
+type offsetType uint8
+
+const (
+	// offsetNone means that an instruction is not PC-relative.
+	offsetNone offsetType = iota
+	// offsetImm26 is a signed offset of words encoded in bits [0:25] of
+	// unconditional branches.
+	offsetImm26
+	// offsetImm19 is a signed offset of words encoded in bits [5:23] of
+	// conditional branches, compare and branch instructions and loads of
+	// literals.
+	offsetImm19
+	// offsetImm14 is a signed offset of words encoded in bits [5:18] of
+	// test and branch instructions.
+	offsetImm14
+	// offsetAdr is a signed offset of bytes used by adr instruction. Its
+	// low 2 bits are encoded in bits [29:30] and high 19 bits in bits
+	// [5:23] of the instruction.
+	offsetAdr
+	// offsetAdrp is a signed offset of pages from the page of the
+	// instruction used by adrp instruction. It's encoded in the same way
+	// as offsetAdr.
+	offsetAdrp
+)
+
+// layout returns bit offset and number of bits of the offset field in an
+// instruction together with number of bits the value is shifted left by.
+func (t offsetType) layout() (offset, bits, shift uint8) {
+	switch t {
+	case offsetImm26:
+		return 0, 26, 2
+	case offsetImm19:
+		return 5, 19, 2
+	case offsetImm14:
+		return 5, 14, 2
+	case offsetAdr:
+		return 0, 21, 0
+	case offsetAdrp:
+		return 0, 21, 12
+	default:
+		return 0, 0, 0
+	}
+}
+
+// field returns unsigned value of the offset field of instruction value. The
+// split field of adr and adrp instructions is joined.
+func (t offsetType) field(value uint32) uint32 {
+	if t == offsetAdr || t == offsetAdrp {
+		return bitRange(value, 5, 19)<<2 | bitRange(value, 29, 2)
+	}
+
+	offset, bits, _ := t.layout()
+	return bitRange(value, offset, bits)
+}
+
+// fieldMask returns mask of bits of an instruction encoding the offset.
+func (t offsetType) fieldMask() uint32 {
+	if t == offsetAdr || t == offsetAdrp {
+		return (1<<19-1)<<5 | 0b11<<29
+	}
+
+	offset, bits, _ := t.layout()
+	return (1<<bits - 1) << offset
+}
+
+// parse returns offset encoded in instruction value in bytes.
+func (t offsetType) parse(value uint32) int64 {
+	_, bits, shift := t.layout()
+	return signExtend(t.field(value), bits) << shift
+}
+
+// base returns the address offsets of type t are relative to for an
+// instruction at address a.
+func (t offsetType) base(a model.Addr) model.Addr {
+	if t == offsetAdrp {
+		return a &^ (pageSize - 1)
+	}
+	return a
+}
+
+// encode returns offset off encoded in bits of an instruction. Bits of the
+// instruction which don't encode the offset are zero.
+func (t offsetType) encode(off int64) (uint32, error) {
+	offset, bits, shift := t.layout()
+	if off&(1<<shift-1) != 0 {
+		return 0, fmt.Errorf("offset is not aligned to %d bytes: %d",
+			1<<shift, off)
+	}
+
+	field := off >> shift
+	if field < -(1<<(bits-1)) || field >= 1<<(bits-1) {
+		return 0, fmt.Errorf("offset doesn't fit %d bits: %d", bits, off)
+	}
+
+	value := uint32(field) & (1<<bits - 1)
+	if t == offsetAdr || t == offsetAdrp {
+		return value>>2<<5 | value&0b11<<29, nil
+	}
+	return value << offset, nil
+}
+
+// bitRange returns bits bits of value starting with bit at index offset.
+func bitRange(value uint32, offset uint8, bits uint8) uint32 {
+	return value >> offset & (1<<bits - 1)
+}
+
+// signExtend interprets the lowest bits bits of value as a signed integer.
+func signExtend(value uint32, bits uint8) int64 {
+	shift := 64 - bits
+	return int64(uint64(value)<<shift) >> shift
+}
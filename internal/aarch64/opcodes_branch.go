@@ -0,0 +1,156 @@
+package aarch64
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// branchInstructions are branch instructions.
+var branchInstructions = []*instructionType{
+	{
+		name:     "b",
+		opcode:   pattern("000101xx xxxxxxxx xxxxxxxx xxxxxxxx"),
+		offset:   offsetImm26,
+		operands: targetOperands,
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{ipStore(addrConst(i.target()))}
+		},
+	}, {
+		name:     "bl",
+		opcode:   pattern("100101xx xxxxxxxx xxxxxxxx xxxxxxxx"),
+		offset:   offsetImm26,
+		operands: targetOperands,
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{
+				linkStore(i),
+				ipStore(addrConst(i.target())),
+			}
+		},
+	}, {
+		name:   "b.cond",
+		opcode: pattern("01010100 xxxxxxxx xxxxxxxx xxx0xxxx"),
+		offset: offsetImm19,
+		mnemonic: func(i instruction) string {
+			return "b." + condName(i.field(0, condBits))
+		},
+		operands: targetOperands,
+		effects: func(i instruction) []expr.Effect {
+			cond := i.field(0, condBits)
+			return []expr.Effect{ipStore(condition(
+				cond,
+				addrConst(i.target()),
+				addrConst(i.next()),
+				width64,
+			))}
+		},
+	},
+
+	compareBranch("cbz", "0"),
+	compareBranch("cbnz", "1"),
+	testBranch("tbz", "0"),
+	testBranch("tbnz", "1"),
+
+	{
+		name:   "br",
+		opcode: pattern("11010110 00011111 000000xx xxx00000"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rn().name(width64, zeroReg, abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{ipStore(regLoad(i.rn(), width64, zeroReg))}
+		},
+	}, {
+		name:   "blr",
+		opcode: pattern("11010110 00111111 000000xx xxx00000"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rn().name(width64, zeroReg, abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			// Effects are evaluated at once, so the jump target is the
+			// register value before the link register is written.
+			return []expr.Effect{
+				linkStore(i),
+				ipStore(regLoad(i.rn(), width64, zeroReg)),
+			}
+		},
+	}, {
+		name:   "ret",
+		opcode: pattern("11010110 01011111 000000xx xxx00000"),
+		operands: func(i instruction, abi bool) []string {
+			if i.rn() == regLR {
+				return nil
+			}
+			return []string{i.rn().name(width64, zeroReg, abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{ipStore(regLoad(i.rn(), width64, zeroReg))}
+		},
+	},
+}
+
+// targetOperands returns operands of a branch instruction which has only a
+// single operand: the target address.
+func targetOperands(i instruction, _ bool) []string {
+	return []string{addrOperand(uint64(i.target()))}
+}
+
+// compareBranch creates compare and branch instruction called name with bit op
+// given by op. The instruction jumps if the register is zero (op 0) or if it's
+// not zero (op 1).
+func compareBranch(name string, op string) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern("x011010" + op + " xxxxxxxx xxxxxxxx xxxxxxxx"),
+		offset: offsetImm19,
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				i.rd().name(i.width(), zeroReg, abi),
+				addrOperand(uint64(i.target())),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			taken, notTaken := addrConst(i.target()), addrConst(i.next())
+			if op == "0" {
+				taken, notTaken = notTaken, taken
+			}
+
+			rt := regLoad(i.rd(), i.width(), zeroReg)
+			return []expr.Effect{ipStore(exprtools.BoolCond(rt, taken, notTaken, width64))}
+		},
+	}
+}
+
+// testBranch creates test bit and branch instruction called name with bit op
+// given by op. The instruction jumps if the tested bit is zero (op 0) or if
+// it's set (op 1).
+//
+// Number of the tested bit is encoded in bit 31 (the highest bit) and bits
+// [19:23] of the instruction. If the highest bit is zero, the tested register
+// is written as 32 bit register.
+func testBranch(name string, op string) *instructionType {
+	bitNum := func(i instruction) uint32 { return i.field(31, 1)<<5 | i.field(19, 5) }
+
+	return &instructionType{
+		name:   name,
+		opcode: pattern("x011011" + op + " xxxxxxxx xxxxxxxx xxxxxxxx"),
+		offset: offsetImm14,
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				i.rd().name(i.width(), zeroReg, abi),
+				decImm(int64(bitNum(i))),
+				addrOperand(uint64(i.target())),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			taken, notTaken := addrConst(i.target()), addrConst(i.next())
+			if op == "0" {
+				taken, notTaken = notTaken, taken
+			}
+
+			rt := regLoad(i.rd(), width64, zeroReg)
+			mask := immConst(uint64(1)<<bitNum(i), width64)
+			tested := exprtools.BitAnd(rt, mask, width64)
+			return []expr.Effect{ipStore(exprtools.BoolCond(tested, taken, notTaken, width64))}
+		},
+	}
+}
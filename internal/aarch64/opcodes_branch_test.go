@@ -0,0 +1,122 @@
+package aarch64
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranchEffects(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		regs  map[expr.Key]uint64
+		want  map[expr.Key]uint64
+	}{{
+		// b #8
+		name:  "b",
+		bytes: []byte{0x02, 0x00, 0x00, 0x14},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 8},
+	}, {
+		// bl #-8
+		name:  "bl",
+		bytes: []byte{0xfe, 0xff, 0xff, 0x97},
+		want: map[expr.Key]uint64{
+			expr.IPKey: testAddr - 8,
+			"x30":      testAddr + 4,
+		},
+	}, {
+		// b.ne #8
+		name:  "b.ne_taken",
+		bytes: []byte{0x41, 0x00, 0x00, 0x54},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 8},
+	}, {
+		// b.ne #8
+		name:  "b.ne_not_taken",
+		bytes: []byte{0x41, 0x00, 0x00, 0x54},
+		regs:  map[expr.Key]uint64{ZKey: 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 4},
+	}, {
+		// b.ge #8
+		name:  "b.ge",
+		bytes: []byte{0x4a, 0x00, 0x00, 0x54},
+		regs:  map[expr.Key]uint64{NKey: 1, VKey: 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 8},
+	}, {
+		// b.gt #8
+		name:  "b.gt_zero",
+		bytes: []byte{0x4c, 0x00, 0x00, 0x54},
+		regs:  map[expr.Key]uint64{ZKey: 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 4},
+	}, {
+		// b.hi #8
+		name:  "b.hi",
+		bytes: []byte{0x48, 0x00, 0x00, 0x54},
+		regs:  map[expr.Key]uint64{CKey: 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 8},
+	}, {
+		// b.ls #8
+		name:  "b.ls",
+		bytes: []byte{0x49, 0x00, 0x00, 0x54},
+		regs:  map[expr.Key]uint64{CKey: 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 4},
+	}, {
+		// b.al #8
+		name:  "b.al",
+		bytes: []byte{0x4e, 0x00, 0x00, 0x54},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 8},
+	}, {
+		// cbz w0, #16
+		name:  "cbz_32",
+		bytes: []byte{0x80, 0x00, 0x00, 0x34},
+		regs:  map[expr.Key]uint64{"x0": 0x1_00000000},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 16},
+	}, {
+		// cbnz x0, #-16
+		name:  "cbnz",
+		bytes: []byte{0x80, 0xff, 0xff, 0xb5},
+		regs:  map[expr.Key]uint64{"x0": 0x1_00000000},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr - 16},
+	}, {
+		// tbz w0, #3, #8
+		name:  "tbz",
+		bytes: []byte{0x40, 0x00, 0x18, 0x36},
+		regs:  map[expr.Key]uint64{"x0": 8},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 4},
+	}, {
+		// tbnz x0, #63, #8
+		name:  "tbnz",
+		bytes: []byte{0x40, 0x00, 0xf8, 0xb7},
+		regs:  map[expr.Key]uint64{"x0": 1 << 63},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 8},
+	}, {
+		// br x1
+		name:  "br",
+		bytes: []byte{0x20, 0x00, 0x1f, 0xd6},
+		regs:  map[expr.Key]uint64{"x1": 0x5000},
+		want:  map[expr.Key]uint64{expr.IPKey: 0x5000},
+	}, {
+		// blr x30
+		name:  "blr_link_register",
+		bytes: []byte{0xc0, 0x03, 0x3f, 0xd6},
+		regs:  map[expr.Key]uint64{"x30": 0x5000},
+		want: map[expr.Key]uint64{
+			expr.IPKey: 0x5000,
+			"x30":      testAddr + 4,
+		},
+	}, {
+		// ret
+		name:  "ret",
+		bytes: []byte{0xc0, 0x03, 0x5f, 0xd6},
+		regs:  map[expr.Key]uint64{"x30": 0x5000},
+		want:  map[expr.Key]uint64{expr.IPKey: 0x5000},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, evalRegs(t, tt.bytes, tt.regs))
+		})
+	}
+}
@@ -0,0 +1,432 @@
+package aarch64
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// dataImmInstructions are data processing instructions with immediate operands.
+var dataImmInstructions = []*instructionType{
+	{
+		name:   "adr",
+		opcode: pattern("0xx10000 xxxxxxxx xxxxxxxx xxxxxxxx"),
+		offset: offsetAdr,
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				i.rd().name(width64, zeroReg, abi),
+				addrOperand(uint64(i.target())),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{regStore(addrConst(i.target()), i.rd(), zeroReg)}
+		},
+	}, {
+		name:   "adrp",
+		opcode: pattern("1xx10000 xxxxxxxx xxxxxxxx xxxxxxxx"),
+		offset: offsetAdrp,
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				i.rd().name(width64, zeroReg, abi),
+				addrOperand(uint64(i.target())),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{regStore(addrConst(i.target()), i.rd(), zeroReg)}
+		},
+	},
+
+	addSubImm("add", "00"),
+	addSubImm("adds", "01"),
+	addSubImm("sub", "10"),
+	addSubImm("subs", "11"),
+
+	logicalImm("and", "00", exprtools.BitAnd),
+	logicalImm("orr", "01", exprtools.BitOr),
+	logicalImm("eor", "10", exprtools.BitXor),
+	logicalImm("ands", "11", exprtools.BitAnd),
+
+	moveWide("movn", "00"),
+	moveWide("movz", "10"),
+	moveWide("movk", "11"),
+
+	bitfield("sbfm", "00"),
+	bitfield("bfm", "01"),
+	bitfield("ubfm", "10"),
+
+	{
+		name:     "extr",
+		opcode:   pattern("x0010011 1x0xxxxx xxxxxxxx xxxxxxxx"),
+		reserved: bitfieldReserved,
+		operands: func(i instruction, abi bool) []string {
+			w := i.width()
+			return []string{
+				i.rd().name(w, zeroReg, abi),
+				i.rn().name(w, zeroReg, abi),
+				i.rm().name(w, zeroReg, abi),
+				decImm(int64(i.field(10, 6))),
+			}
+		},
+		alias: func(i instruction, ops []string) (string, []string, bool) {
+			if i.rn() != i.rm() {
+				return "", nil, false
+			}
+			return "ror", []string{ops[0], ops[1], ops[3]}, true
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			lsb := i.field(10, 6)
+			low := regLoad(i.rm(), w, zeroReg)
+			if lsb == 0 {
+				return []expr.Effect{regStore(low, i.rd(), zeroReg)}
+			}
+
+			high := regLoad(i.rn(), w, zeroReg)
+			res := exprtools.BitOr(
+				expr.NewBinary(expr.Rsh, low, expr.ConstFromUint(lsb), w),
+				expr.NewBinary(expr.Lsh, high,
+					expr.ConstFromUint(uint32(w.Bits())-lsb), w),
+				w,
+			)
+			return []expr.Effect{regStore(res, i.rd(), zeroReg)}
+		},
+	},
+}
+
+// addSubImm creates add or subtract instruction called name with immediate
+// operand. Bits op and S of the instruction are given by opS. The immediate is
+// 12 bit unsigned value optionally shifted left by 12 bits.
+//
+// Instructions which don't set flags use the stack pointer as the output
+// register, the others use the zero register. The input register is always
+// the stack pointer.
+func addSubImm(name string, opS string) *instructionType {
+	sub, setFlags := opS[0] == '1', opS[1] == '1'
+	rdKind := stackReg
+	if setFlags {
+		rdKind = zeroReg
+	}
+
+	imm := func(i instruction) uint64 {
+		v := uint64(i.field(10, 12))
+		if i.bit(22) {
+			v <<= 12
+		}
+		return v
+	}
+
+	return &instructionType{
+		name:   name,
+		opcode: pattern("x" + opS + "10001 0xxxxxxx xxxxxxxx xxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			w := i.width()
+			ops := []string{
+				i.rd().name(w, rdKind, abi),
+				i.rn().name(w, stackReg, abi),
+				hexImm(uint64(i.field(10, 12))),
+			}
+			if i.bit(22) {
+				ops = append(ops, "lsl #12")
+			}
+			return ops
+		},
+		alias: func(i instruction, ops []string) (string, []string, bool) {
+			switch {
+			case setFlags && i.rd() == reg31 && sub:
+				return "cmp", ops[1:], true
+			case setFlags && i.rd() == reg31:
+				return "cmn", ops[1:], true
+			case !setFlags && !sub && imm(i) == 0 &&
+				(i.rd() == reg31 || i.rn() == reg31):
+				return "mov", ops[:2], true
+			default:
+				return "", nil, false
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			rn := regLoad(i.rn(), w, stackReg)
+			return addSub(rn, immConst(imm(i), w), i.rd(), rdKind, sub, setFlags, w)
+		},
+	}
+}
+
+// addSub returns effects of an instruction adding (or subtracting if sub is
+// set) values e1 and e2 of width w and storing the result to register rd where
+// register number 31 has meaning k. NZCV flags are set according to the result
+// if setFlags is set.
+func addSub(
+	e1, e2 expr.Expr,
+	rd regNum,
+	k reg31Kind,
+	sub, setFlags bool,
+	w expr.Width,
+) []expr.Effect {
+	var c expr.Expr = expr.Zero
+	if sub {
+		e2, c = exprtools.BitNot(e2, w), expr.One
+	}
+
+	res, flags := addCarry(e1, e2, c, w)
+	effects := []expr.Effect{regStore(res, rd, k)}
+	if setFlags {
+		effects = append(effects, flags.store()...)
+	}
+	return effects
+}
+
+// logicalImm creates logical instruction called name with immediate operand.
+// Bits opc of the instruction are given by opc and the operation is f. The
+// instruction with opc 11 sets flags.
+//
+// The immediate is encoded as a bit mask (see decodeBitMask).
+func logicalImm(name string, opc string, f binaryExprFunc) *instructionType {
+	setFlags := opc == "11"
+	rdKind := stackReg
+	if setFlags {
+		rdKind = zeroReg
+	}
+
+	imm := func(i instruction) (uint64, bool) {
+		w := i.width()
+		n := i.field(22, 1)
+		if !i.sf() && n != 0 {
+			return 0, false
+		}
+		return decodeBitMask(n, i.field(16, 6), i.field(10, 6), w)
+	}
+
+	return &instructionType{
+		name:   name,
+		opcode: pattern("x" + opc + "10010 0xxxxxxx xxxxxxxx xxxxxxxx"),
+		reserved: func(i instruction) bool {
+			_, ok := imm(i)
+			return !ok
+		},
+		operands: func(i instruction, abi bool) []string {
+			w := i.width()
+			v, _ := imm(i)
+			return []string{
+				i.rd().name(w, rdKind, abi),
+				i.rn().name(w, zeroReg, abi),
+				hexImm(v),
+			}
+		},
+		alias: func(i instruction, ops []string) (string, []string, bool) {
+			v, _ := imm(i)
+			switch {
+			case setFlags && i.rd() == reg31:
+				return "tst", ops[1:], true
+			case name == "orr" && i.rn() == reg31 && !moveWideable(v, i.width()):
+				return "mov", []string{ops[0], ops[2]}, true
+			default:
+				return "", nil, false
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			v, _ := imm(i)
+			res := f(regLoad(i.rn(), w, zeroReg), immConst(v, w), w)
+
+			effects := []expr.Effect{regStore(res, i.rd(), rdKind)}
+			if setFlags {
+				effects = append(effects, logicFlags(res, w).store()...)
+			}
+			return effects
+		},
+	}
+}
+
+type binaryExprFunc func(e1, e2 expr.Expr, w expr.Width) expr.Expr
+
+// moveWide creates move wide instruction called name with bits opc given by
+// opc. Move wide instructions move 16 bit immediate shifted left by a multiple
+// of 16 bits into a register:
+//
+//   - movn moves negation of the immediate,
+//   - movz moves the immediate,
+//   - movk keeps all the other bits of the register unchanged.
+func moveWide(name string, opc string) *instructionType {
+	shift := func(i instruction) uint32 { return 16 * i.field(21, 2) }
+	imm := func(i instruction) uint64 { return uint64(i.field(5, 16)) << shift(i) }
+
+	return &instructionType{
+		name:   name,
+		opcode: pattern("x" + opc + "10010 1xxxxxxx xxxxxxxx xxxxxxxx"),
+		reserved: func(i instruction) bool {
+			return !i.sf() && i.bit(22)
+		},
+		operands: func(i instruction, abi bool) []string {
+			ops := []string{
+				i.rd().name(i.width(), zeroReg, abi),
+				hexImm(uint64(i.field(5, 16))),
+			}
+			if sh := shift(i); sh != 0 {
+				ops = append(ops, "lsl "+decImm(int64(sh)))
+			}
+			return ops
+		},
+		alias: func(i instruction, ops []string) (string, []string, bool) {
+			w := i.width()
+			imm16 := i.field(5, 16)
+			if imm16 == 0 && shift(i) != 0 {
+				return "", nil, false
+			}
+
+			switch name {
+			case "movz":
+				return "mov", []string{ops[0], hexImm(imm(i))}, true
+			case "movn":
+				if w == width32 && imm16 == 0xffff {
+					return "", nil, false
+				}
+				v := ^imm(i) & widthMask(w)
+				return "mov", []string{ops[0], hexImm(v)}, true
+			default:
+				return "", nil, false
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			var res expr.Expr = immConst(imm(i), w)
+			switch name {
+			case "movn":
+				res = immConst(^imm(i), w)
+			case "movk":
+				keep := immConst(^(uint64(0xffff) << shift(i)), w)
+				rd := regLoad(i.rd(), w, zeroReg)
+				res = exprtools.BitOr(exprtools.BitAnd(rd, keep, w), res, w)
+			}
+			return []expr.Effect{regStore(res, i.rd(), zeroReg)}
+		},
+	}
+}
+
+// bitfieldReserved indicates that instruction i of bitfield or extract type is
+// an unallocated encoding. Field N has to be equal to sf and 32 bit variants
+// cannot encode shift amounts above 31.
+func bitfieldReserved(i instruction) bool {
+	if i.bit(22) != i.sf() {
+		return true
+	}
+	return !i.sf() && (i.bit(21) || i.bit(15))
+}
+
+// bitfield creates bitfield move instruction called name with bits opc given
+// by opc. Bitfield moves take a field of bits of the input register and move
+// it to other position in the output register:
+//
+//   - sbfm sign extends the field,
+//   - bfm keeps other bits of the output register unchanged,
+//   - ubfm zero extends the field.
+//
+// If imms is greater or equal to immr, bits [immr:imms] are moved to the
+// lowest bits of the output register. Otherwise the lowest imms+1 bits are
+// moved to bit position width-immr.
+func bitfield(name string, opc string) *instructionType {
+	return &instructionType{
+		name:     name,
+		opcode:   pattern("x" + opc + "10011 0xxxxxxx xxxxxxxx xxxxxxxx"),
+		reserved: bitfieldReserved,
+		operands: func(i instruction, abi bool) []string {
+			w := i.width()
+			return []string{
+				i.rd().name(w, zeroReg, abi),
+				i.rn().name(w, zeroReg, abi),
+				decImm(int64(i.field(16, 6))),
+				decImm(int64(i.field(10, 6))),
+			}
+		},
+		alias: func(i instruction, ops []string) (string, []string, bool) {
+			return bitfieldAlias(name, i, ops)
+		},
+		effects: func(i instruction) []expr.Effect {
+			return bitfieldEffects(name, i)
+		},
+	}
+}
+
+// bitfieldAlias returns the preferred alias of bitfield move instruction i
+// called name with operands ops.
+func bitfieldAlias(name string, i instruction, ops []string) (string, []string, bool) {
+	w := i.width()
+	bits := uint32(w.Bits())
+	immr, imms := i.field(16, 6), i.field(10, 6)
+
+	// Operands of insert and extract aliases.
+	insert := []string{ops[0], ops[1],
+		decImm(int64(bits - immr)), decImm(int64(imms + 1))}
+	extract := []string{ops[0], ops[1],
+		decImm(int64(immr)), decImm(int64(imms - immr + 1))}
+	// Extension aliases always extend 32 bit register.
+	ext := []string{ops[0], i.rn().name(width32, zeroReg, false)}
+
+	switch {
+	case name == "sbfm" && imms == bits-1:
+		return "asr", []string{ops[0], ops[1], ops[2]}, true
+	case name == "ubfm" && imms == bits-1:
+		return "lsr", []string{ops[0], ops[1], ops[2]}, true
+	case name == "ubfm" && imms+1 == immr:
+		return "lsl", []string{ops[0], ops[1], decImm(int64(bits - immr))}, true
+	case name != "bfm" && immr == 0 && (imms == 7 || imms == 15 || imms == 31):
+		names := map[uint32]string{7: "xtb", 15: "xth", 31: "xtw"}
+		if name == "ubfm" && (imms == 31 || w == width64) {
+			break
+		}
+		return name[:1] + names[imms], ext, true
+	case name == "bfm" && imms < immr && i.rn() == reg31:
+		return "bfc", []string{ops[0], insert[2], insert[3]}, true
+	case name == "bfm" && imms < immr:
+		return "bfi", insert, true
+	case imms < immr:
+		return name[:len(name)-1] + "iz", insert, true
+	}
+
+	switch name {
+	case "bfm":
+		return "bfxil", extract, true
+	default:
+		return name[:len(name)-1] + "x", extract, true
+	}
+}
+
+// bitfieldEffects returns effects of bitfield move instruction i called name.
+func bitfieldEffects(name string, i instruction) []expr.Effect {
+	w := i.width()
+	bits := uint32(w.Bits())
+	immr, imms := i.field(16, 6), i.field(10, 6)
+	rn := regLoad(i.rn(), w, zeroReg)
+
+	// Field of len bits is moved to bit position pos.
+	var field expr.Expr
+	var pos, len uint32
+	if imms >= immr {
+		len = imms - immr + 1
+		field = expr.NewBinary(expr.Rsh, rn, expr.ConstFromUint(immr), w)
+	} else {
+		pos, len = bits-immr, imms+1
+		field = rn
+	}
+	field = exprtools.MaskBits(field, exprtools.BitCnt(len), w)
+
+	var res expr.Expr
+	switch name {
+	case "sbfm":
+		res = shiftLeft(sext(field, uint8(len-1), w), pos, w)
+	case "ubfm":
+		res = shiftLeft(field, pos, w)
+	default:
+		mask := (uint64(1)<<len - 1) << pos
+		res = exprtools.BitOr(
+			exprtools.BitAnd(regLoad(i.rd(), w, zeroReg), immConst(^mask, w), w),
+			shiftLeft(field, pos, w),
+			w,
+		)
+	}
+
+	return []expr.Effect{regStore(res, i.rd(), zeroReg)}
+}
+
+// shiftLeft shifts e of width w left by constant number of bits sh.
+func shiftLeft(e expr.Expr, sh uint32, w expr.Width) expr.Expr {
+	return shiftLSL.apply(e, sh, w)
+}
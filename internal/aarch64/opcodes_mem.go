@@ -0,0 +1,572 @@
+package aarch64
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+)
+
+// memKind is a kind of memory access performed by load and store instructions.
+type memKind uint8
+
+const (
+	memStoreKind memKind = iota
+	memLoadKind
+	// memLoadSignedKind is a load sign extending the value loaded.
+	memLoadSignedKind
+	// memPrefetchKind is a prefetch of memory which has no effect on the
+	// program.
+	memPrefetchKind
+)
+
+// memAccess describes a load or store instruction which is available in
+// multiple addressing modes.
+type memAccess struct {
+	// name is name of the instruction in all addressing modes but the
+	// unscaled immediate offset.
+	name string
+	// unscaled is name of the instruction with unscaled immediate offset.
+	unscaled string
+
+	// size is pattern of bits [30:31] of the instruction which encode
+	// log2 of number of bytes accessed.
+	size string
+	// opc is pattern of bits [22:23] of the instruction.
+	opc string
+
+	kind memKind
+	// literal is set for PC-relative loads. Bits [30:31] of those loads
+	// don't encode the size, so size is the actual size of the access.
+	literal bool
+}
+
+// memAccesses lists all single register loads and stores.
+var memAccesses = []memAccess{
+	{name: "strb", unscaled: "sturb", size: "00", opc: "00", kind: memStoreKind},
+	{name: "ldrb", unscaled: "ldurb", size: "00", opc: "01", kind: memLoadKind},
+	{name: "ldrsb", unscaled: "ldursb", size: "00", opc: "1x", kind: memLoadSignedKind},
+	{name: "strh", unscaled: "sturh", size: "01", opc: "00", kind: memStoreKind},
+	{name: "ldrh", unscaled: "ldurh", size: "01", opc: "01", kind: memLoadKind},
+	{name: "ldrsh", unscaled: "ldursh", size: "01", opc: "1x", kind: memLoadSignedKind},
+	{name: "str", unscaled: "stur", size: "1x", opc: "00", kind: memStoreKind},
+	{name: "ldr", unscaled: "ldur", size: "1x", opc: "01", kind: memLoadKind},
+	{name: "ldrsw", unscaled: "ldursw", size: "10", opc: "10", kind: memLoadSignedKind},
+	{name: "prfm", unscaled: "prfum", size: "11", opc: "10", kind: memPrefetchKind},
+}
+
+// memSize returns log2 of number of bytes accessed by load or store
+// instruction i. The value is encoded in bits [30:31] of the instruction.
+func memSize(i instruction) uint32 { return i.field(30, 2) }
+
+// memWidth returns number of bytes accessed by load or store instruction i.
+func memWidth(i instruction) expr.Width { return expr.Width(1) << memSize(i) }
+
+// sizeOf returns log2 of number of bytes instruction i performing access a
+// accesses.
+func (a memAccess) sizeOf(i instruction) uint32 {
+	if a.literal {
+		return uint32(a.size[0]-'0')<<1 | uint32(a.size[1]-'0')
+	}
+	return memSize(i)
+}
+
+// regWidth returns width of register transferred by load or store instruction
+// i. Signed loads with bit 22 unset extend the value to 64 bits, all other
+// instructions transfer 64 bit register only if they access 8 bytes.
+func (a memAccess) regWidth(i instruction) expr.Width {
+	if a.kind == memLoadSignedKind {
+		if !a.literal && i.bit(22) {
+			return width32
+		}
+		return width64
+	}
+	if a.sizeOf(i) == 0b11 {
+		return width64
+	}
+	return width32
+}
+
+// rtOperand returns the transferred register of instruction i in assembler
+// code. Prefetch instructions have prefetch operation in place of the
+// register.
+func (a memAccess) rtOperand(i instruction, abi bool) string {
+	if a.kind == memPrefetchKind {
+		return prefetchName(uint32(i.rd()))
+	}
+	return i.rd().name(a.regWidth(i), zeroReg, abi)
+}
+
+// transfer returns effects of instruction i performing memory access a at
+// address addr.
+func (a memAccess) transfer(i instruction, addr expr.Expr) []expr.Effect {
+	w, mw := a.regWidth(i), expr.Width(1)<<a.sizeOf(i)
+	switch a.kind {
+	case memStoreKind:
+		return []expr.Effect{memStore(regLoad(i.rd(), mw, zeroReg), addr, mw)}
+	case memLoadKind:
+		return []expr.Effect{regStore(memLoad(addr, mw), i.rd(), zeroReg)}
+	case memLoadSignedKind:
+		v := sext(memLoad(addr, mw), uint8(mw.Bits()-1), w)
+		return []expr.Effect{regStore(v, i.rd(), zeroReg)}
+	default:
+		return nil
+	}
+}
+
+// prefetchTypes are names of prefetch types and targets. Index in the arrays
+// is the value of the field of prefetch operation.
+var (
+	prefetchTypes   = [...]string{"pld", "pli", "pst"}
+	prefetchTargets = [...]string{"l1", "l2", "l3"}
+	prefetchPolicy  = [...]string{"keep", "strm"}
+)
+
+// prefetchName returns name of prefetch operation op encoded in place of the
+// transferred register of prefetch instructions. Operations without a name are
+// written as immediate value.
+func prefetchName(op uint32) string {
+	typ, target, policy := op>>3, op>>1&0b11, op&1
+	if int(typ) >= len(prefetchTypes) || int(target) >= len(prefetchTargets) {
+		return decImm(int64(op))
+	}
+	return prefetchTypes[typ] + prefetchTargets[target] + prefetchPolicy[policy]
+}
+
+// baseLoad returns the value of the base register of load or store instruction
+// i.
+func baseLoad(i instruction) expr.Expr { return regLoad(i.rn(), width64, stackReg) }
+
+// baseOperand returns the base register with offset off in assembler code. The
+// offset is omitted if it's empty.
+func baseOperand(i instruction, off string, abi bool) string {
+	base := i.rn().name(width64, stackReg, abi)
+	if off == "" {
+		return fmt.Sprintf("[%s]", base)
+	}
+	return fmt.Sprintf("[%s, %s]", base, off)
+}
+
+// offsetOperand returns immediate offset off in assembler code. Zero offset is
+// represented by an empty string.
+func offsetOperand(off int64) string {
+	if off == 0 {
+		return ""
+	}
+	return decImm(off)
+}
+
+// addOffset returns address e incremented by constant offset off.
+func addOffset(e expr.Expr, off int64) expr.Expr {
+	if off == 0 {
+		return e
+	}
+	return expr.NewBinary(expr.Add, e, immConst(uint64(off), width64), width64)
+}
+
+// loadStoreInstructions generates all load and store instructions.
+func loadStoreInstructions() []*instructionType {
+	var instrs []*instructionType
+	for _, a := range memAccesses {
+		instrs = append(instrs,
+			a.unsignedOffset(),
+			a.unscaledOffset(),
+			a.registerOffset(),
+		)
+		if a.kind != memPrefetchKind {
+			instrs = append(instrs, a.indexed(false), a.indexed(true))
+		}
+	}
+
+	return mergeInstructions(
+		instrs,
+		literalInstructions,
+		pairInstructions,
+		exclusiveInstructions(),
+	)
+}
+
+// unsignedOffset creates instruction performing access a with 12 bit unsigned
+// immediate offset scaled by number of bytes accessed.
+func (a memAccess) unsignedOffset() *instructionType {
+	offset := func(i instruction) int64 { return int64(i.field(10, 12)) << memSize(i) }
+
+	return &instructionType{
+		name:   a.name,
+		opcode: pattern(a.size + "111001" + a.opc + "xxxxxx xxxxxxxx xxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				a.rtOperand(i, abi),
+				baseOperand(i, offsetOperand(offset(i)), abi),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return a.transfer(i, addOffset(baseLoad(i), offset(i)))
+		},
+	}
+}
+
+// imm9 returns signed 9 bit immediate offset in bits [12:20] of instruction i.
+func imm9(i instruction) int64 { return signExtend(i.field(12, 9), 9) }
+
+// unscaledOffset creates instruction performing access a with 9 bit signed
+// immediate offset.
+func (a memAccess) unscaledOffset() *instructionType {
+	return &instructionType{
+		name:   a.unscaled,
+		opcode: pattern(a.size + "111000" + a.opc + "0xxxxx xxxx00xx xxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				a.rtOperand(i, abi),
+				baseOperand(i, offsetOperand(imm9(i)), abi),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return a.transfer(i, addOffset(baseLoad(i), imm9(i)))
+		},
+	}
+}
+
+// indexed creates instruction performing access a with 9 bit signed immediate
+// offset which writes the address back to the base register. Pre-indexed
+// instruction accesses the address with the offset, post-indexed one accesses
+// the address in the base register.
+func (a memAccess) indexed(pre bool) *instructionType {
+	mode := "01"
+	if pre {
+		mode = "11"
+	}
+
+	return &instructionType{
+		name:   a.name,
+		opcode: pattern(a.size + "111000" + a.opc + "0xxxxx xxxx" + mode + "xx xxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			return indexedOperands(i, a.rtOperand(i, abi), imm9(i), pre, abi)
+		},
+		effects: func(i instruction) []expr.Effect {
+			return indexedEffects(i, imm9(i), pre, func(addr expr.Expr) []expr.Effect {
+				return a.transfer(i, addr)
+			})
+		},
+	}
+}
+
+// indexedOperands returns operands of instruction i with writeback of the base
+// register. Registers transferred are given by rts and the base register is
+// incremented by off before (pre) or after the memory is accessed.
+func indexedOperands(i instruction, rt string, off int64, pre bool, abi bool) []string {
+	if pre {
+		return []string{rt, baseOperand(i, decImm(off), abi) + "!"}
+	}
+	return []string{rt, baseOperand(i, "", abi), decImm(off)}
+}
+
+// indexedEffects returns effects of instruction i with writeback of the base
+// register incremented by offset off. The memory is accessed by effects
+// returned by transfer. The address passed to transfer includes the offset only
+// if pre is set.
+func indexedEffects(
+	i instruction,
+	off int64,
+	pre bool,
+	transfer func(addr expr.Expr) []expr.Effect,
+) []expr.Effect {
+	base := baseLoad(i)
+	updated := addOffset(base, off)
+
+	addr := base
+	if pre {
+		addr = updated
+	}
+
+	return append(transfer(addr), regStore(updated, i.rn(), stackReg))
+}
+
+// registerOffset creates instruction performing access a with register offset.
+// The offset register is extended according to option field in bits [13:15] of
+// the instruction and shifted left by log2 of number of bytes accessed if bit
+// 12 (S) is set.
+func (a memAccess) registerOffset() *instructionType {
+	option := func(i instruction) uint32 { return i.field(13, 3) }
+	amount := func(i instruction) uint32 {
+		if i.bit(12) {
+			return memSize(i)
+		}
+		return 0
+	}
+
+	return &instructionType{
+		name:   a.name,
+		opcode: pattern(a.size + "111000" + a.opc + "1xxxxx xxxx10xx xxxxxxxx"),
+		reserved: func(i instruction) bool {
+			return option(i)&0b010 == 0
+		},
+		operands: func(i instruction, abi bool) []string {
+			opt := option(i)
+			off := i.rm().name(extendRegWidth(opt), zeroReg, abi)
+
+			ext := extendNames[opt]
+			if opt == 0b011 {
+				ext = "lsl"
+			}
+			if i.bit(12) {
+				off += fmt.Sprintf(", %s %s", ext, decImm(int64(amount(i))))
+			} else if opt != 0b011 {
+				off += ", " + ext
+			}
+
+			return []string{a.rtOperand(i, abi), baseOperand(i, off, abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			off := extend(regLoad(i.rm(), width64, zeroReg), option(i), width64)
+			off = shiftLSL.apply(off, amount(i), width64)
+			addr := expr.NewBinary(expr.Add, baseLoad(i), off, width64)
+			return a.transfer(i, addr)
+		},
+	}
+}
+
+// literalInstructions are loads of PC-relative addresses.
+var literalInstructions = []*instructionType{
+	literal(memAccess{name: "ldr", size: "10", kind: memLoadKind}, "00"),
+	literal(memAccess{name: "ldr", size: "11", kind: memLoadKind}, "01"),
+	literal(memAccess{name: "ldrsw", size: "10", kind: memLoadSignedKind}, "10"),
+	literal(memAccess{name: "prfm", size: "11", kind: memPrefetchKind}, "11"),
+}
+
+// literal creates PC-relative load instruction performing access a. Bits [30:31]
+// of the instruction are given by opc.
+func literal(a memAccess, opc string) *instructionType {
+	a.literal = true
+	return &instructionType{
+		name:   a.name,
+		opcode: pattern(opc + "011000 xxxxxxxx xxxxxxxx xxxxxxxx"),
+		offset: offsetImm19,
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				a.rtOperand(i, abi),
+				addrOperand(uint64(i.target())),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return a.transfer(i, addrConst(i.target()))
+		},
+	}
+}
+
+// pairAccess describes load or store pair instruction.
+type pairAccess struct {
+	name string
+	// opc is pattern of bits [30:31] of the instruction.
+	opc string
+	// load is set for loads, it's bit 22 (L) of the instruction.
+	load bool
+	// signed is set for loads sign extending words to 64 bits.
+	signed bool
+}
+
+// pairInstructions are loads and stores of pairs of registers.
+var pairInstructions = func() []*instructionType {
+	var instrs []*instructionType
+	for _, p := range []pairAccess{
+		{name: "stp", opc: "x0"},
+		{name: "ldp", opc: "x0", load: true},
+		{name: "ldpsw", opc: "01", load: true, signed: true},
+	} {
+		instrs = append(instrs,
+			p.instruction("010"),
+			p.instruction("001"),
+			p.instruction("011"),
+		)
+	}
+
+	return append(instrs,
+		pairAccess{name: "stnp", opc: "x0"}.instruction("000"),
+		pairAccess{name: "ldnp", opc: "x0", load: true}.instruction("000"),
+	)
+}()
+
+// regWidth returns width of registers transferred by pair instruction i.
+func (p pairAccess) regWidth(i instruction) expr.Width {
+	if p.signed || i.sf() {
+		return width64
+	}
+	return width32
+}
+
+// memWidth returns number of bytes of every register transferred by pair
+// instruction i.
+func (p pairAccess) memWidth(i instruction) expr.Width {
+	if i.sf() {
+		return width64
+	}
+	return width32
+}
+
+// offset returns signed 7 bit immediate offset in bits [15:21] of pair
+// instruction i scaled by size of a register in memory.
+func (p pairAccess) offset(i instruction) int64 {
+	return signExtend(i.field(15, 7), 7) * int64(p.memWidth(i))
+}
+
+// instruction creates pair instruction with addressing mode given by bits
+// [23:25] of the instruction: signed offset (010), post-index (001), pre-index
+// (011) or signed offset with non-temporal hint (000).
+func (p pairAccess) instruction(mode string) *instructionType {
+	l := "0"
+	if p.load {
+		l = "1"
+	}
+	writeback, pre := mode == "001" || mode == "011", mode == "011"
+
+	return &instructionType{
+		name:   p.name,
+		opcode: pattern(p.opc + "1010" + mode + l + "xxxxxx xxxxxxxx xxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			w := p.regWidth(i)
+			rts := fmt.Sprintf("%s, %s",
+				i.rd().name(w, zeroReg, abi), i.ra().name(w, zeroReg, abi))
+			if writeback {
+				return indexedOperands(i, rts, p.offset(i), pre, abi)
+			}
+			return []string{rts, baseOperand(i, offsetOperand(p.offset(i)), abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			if writeback {
+				return indexedEffects(i, p.offset(i), pre, func(addr expr.Expr) []expr.Effect {
+					return p.transfer(i, addr)
+				})
+			}
+			return p.transfer(i, addOffset(baseLoad(i), p.offset(i)))
+		},
+	}
+}
+
+// transfer returns effects of pair instruction i accessing memory at address
+// addr.
+func (p pairAccess) transfer(i instruction, addr expr.Expr) []expr.Effect {
+	w, mw := p.regWidth(i), p.memWidth(i)
+	addr2 := expr.NewBinary(expr.Add, addr, immConst(uint64(mw), width64), width64)
+
+	if !p.load {
+		return []expr.Effect{
+			memStore(regLoad(i.rd(), mw, zeroReg), addr, mw),
+			memStore(regLoad(i.ra(), mw, zeroReg), addr2, mw),
+		}
+	}
+
+	load := func(addr expr.Expr) expr.Expr {
+		v := memLoad(addr, mw)
+		if p.signed {
+			v = sext(v, uint8(mw.Bits()-1), w)
+		}
+		return v
+	}
+	return []expr.Effect{
+		regStore(load(addr), i.rd(), zeroReg),
+		regStore(load(addr2), i.ra(), zeroReg),
+	}
+}
+
+// exclusiveAccess describes exclusive or ordered load or store instruction.
+type exclusiveAccess struct {
+	name string
+	// bits are pattern of bits o2, L and o1 in bits [21:23] of the
+	// instruction.
+	bits string
+	// o0 is pattern of bit 15 of the instruction. It's acquire or release
+	// bit.
+	o0 string
+}
+
+// exclusiveInstructions generates exclusive and ordered loads and stores.
+func exclusiveInstructions() []*instructionType {
+	var instrs []*instructionType
+	for _, e := range []exclusiveAccess{
+		{name: "stxr", bits: "000", o0: "0"},
+		{name: "stlxr", bits: "000", o0: "1"},
+		{name: "ldxr", bits: "010", o0: "0"},
+		{name: "ldaxr", bits: "010", o0: "1"},
+		{name: "stlr", bits: "100", o0: "1"},
+		{name: "ldar", bits: "110", o0: "1"},
+	} {
+		instrs = append(instrs, e.instruction())
+	}
+	return instrs
+}
+
+// instruction creates exclusive or ordered load or store instruction. The
+// instruction accesses 1, 2, 4 or 8 bytes given by bits [30:31].
+//
+// Exclusive stores are modelled as if they always succeeded, so they always
+// write zero to the status register. This is a valid behaviour of a single
+// threaded program as nothing can access the memory between exclusive load
+// and store.
+//
+// Fields of registers the instruction doesn't use (Rs of loads and Rt2) should
+// be all ones, but other values are accepted the same way disassemblers do.
+func (e exclusiveAccess) instruction() *instructionType {
+	load, exclusive := e.bits[1] == '1', e.bits[0] == '0'
+	acqRel := e.o0 == "1"
+
+	// Acquire loads order all later accesses after themselves, release
+	// stores order all earlier accesses before them.
+	var t model.Type
+	var order func(i instruction) model.MemOrder
+	if acqRel {
+		t = model.TypeMemOrder
+		order = func(instruction) model.MemOrder {
+			if load {
+				return model.MemOrder{Succ: model.AccessRead | model.AccessWrite}
+			}
+			return model.MemOrder{Pred: model.AccessRead | model.AccessWrite}
+		}
+	}
+
+	return &instructionType{
+		name:   e.name,
+		opcode: pattern("xx001000 " + e.bits + "xxxxx " + e.o0 + "xxxxxxx xxxxxxxx"),
+		mnemonic: func(i instruction) string {
+			switch memSize(i) {
+			case 0b00:
+				return e.name + "b"
+			case 0b01:
+				return e.name + "h"
+			default:
+				return e.name
+			}
+		},
+		instrType: t,
+		order:     order,
+		operands: func(i instruction, abi bool) []string {
+			ops := []string{
+				i.rd().name(exclusiveWidth(i), zeroReg, abi),
+				baseOperand(i, "", abi),
+			}
+			if exclusive && !load {
+				ops = append([]string{i.rm().name(width32, zeroReg, abi)}, ops...)
+			}
+			return ops
+		},
+		effects: func(i instruction) []expr.Effect {
+			mw, addr := memWidth(i), baseLoad(i)
+			if load {
+				return []expr.Effect{regStore(memLoad(addr, mw), i.rd(), zeroReg)}
+			}
+
+			effects := []expr.Effect{memStore(regLoad(i.rd(), mw, zeroReg), addr, mw)}
+			if exclusive {
+				effects = append(effects, regStore(immConst(0, width32), i.rm(), zeroReg))
+			}
+			return effects
+		},
+	}
+}
+
+// exclusiveWidth returns width of register transferred by exclusive or ordered
+// load or store i.
+func exclusiveWidth(i instruction) expr.Width {
+	if memSize(i) == 0b11 {
+		return width64
+	}
+	return width32
+}
@@ -0,0 +1,248 @@
+package aarch64
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memBytes returns memory with bytes bs starting at address a.
+func memBytes(a uint64, bs ...byte) map[uint64]byte {
+	m := make(map[uint64]byte, len(bs))
+	for i, b := range bs {
+		m[a+uint64(i)] = b
+	}
+	return m
+}
+
+func TestLoadStoreEffects(t *testing.T) {
+	// Memory of tests is 16 bytes at address 0x2000 with values 0x80, 0x81
+	// etc.
+	var bs []byte
+	for i := 0; i < 16; i++ {
+		bs = append(bs, byte(0x80+i))
+	}
+	mem := memBytes(0x2000, bs...)
+
+	tests := []struct {
+		name    string
+		bytes   []byte
+		regs    map[expr.Key]uint64
+		want    map[expr.Key]uint64
+		wantMem map[uint64]byte
+	}{{
+		// ldr x0, [x1, #8]
+		name:  "ldr",
+		bytes: []byte{0x20, 0x04, 0x40, 0xf9},
+		regs:  map[expr.Key]uint64{"x1": 0x2000},
+		want:  map[expr.Key]uint64{"x0": 0x8f8e8d8c_8b8a8988},
+	}, {
+		// ldrsb w0, [x1]
+		name:  "ldrsb_32",
+		bytes: []byte{0x20, 0x00, 0xc0, 0x39},
+		regs:  map[expr.Key]uint64{"x1": 0x2000},
+		want:  map[expr.Key]uint64{"x0": 0xffffff80},
+	}, {
+		// ldrsb x0, [x1]
+		name:  "ldrsb_64",
+		bytes: []byte{0x20, 0x00, 0x80, 0x39},
+		regs:  map[expr.Key]uint64{"x1": 0x2000},
+		want:  map[expr.Key]uint64{"x0": 0xffffffff_ffffff80},
+	}, {
+		// ldrsw x0, [x1, #4]
+		name:  "ldrsw",
+		bytes: []byte{0x20, 0x04, 0x80, 0xb9},
+		regs:  map[expr.Key]uint64{"x1": 0x2000},
+		want:  map[expr.Key]uint64{"x0": 0xffffffff_87868584},
+	}, {
+		// ldrh w0, [x1, #2]
+		name:  "ldrh",
+		bytes: []byte{0x20, 0x04, 0x40, 0x79},
+		regs:  map[expr.Key]uint64{"x1": 0x2000},
+		want:  map[expr.Key]uint64{"x0": 0x8382},
+	}, {
+		// ldur w0, [x1, #-3]
+		name:  "ldur",
+		bytes: []byte{0x20, 0xd0, 0x5f, 0xb8},
+		regs:  map[expr.Key]uint64{"x1": 0x2004},
+		want:  map[expr.Key]uint64{"x0": 0x84838281},
+	}, {
+		// ldr x0, [x1, #8]!
+		name:  "ldr_pre",
+		bytes: []byte{0x20, 0x8c, 0x40, 0xf8},
+		regs:  map[expr.Key]uint64{"x1": 0x2000},
+		want:  map[expr.Key]uint64{"x0": 0x8f8e8d8c_8b8a8988, "x1": 0x2008},
+	}, {
+		// ldr x0, [x1], #8
+		name:  "ldr_post",
+		bytes: []byte{0x20, 0x84, 0x40, 0xf8},
+		regs:  map[expr.Key]uint64{"x1": 0x2000},
+		want:  map[expr.Key]uint64{"x0": 0x87868584_83828180, "x1": 0x2008},
+	}, {
+		// ldr x0, [x1, w2, sxtw #3]
+		name:  "ldr_register_extended",
+		bytes: []byte{0x20, 0xd8, 0x62, 0xf8},
+		regs:  map[expr.Key]uint64{"x1": 0x2008, "x2": 0xffffffff},
+		want:  map[expr.Key]uint64{"x0": 0x87868584_83828180},
+	}, {
+		// ldrb w0, [x1, x2]
+		name:  "ldrb_register",
+		bytes: []byte{0x20, 0x68, 0x62, 0x38},
+		regs:  map[expr.Key]uint64{"x1": 0x1000, "x2": 0x100f},
+		want:  map[expr.Key]uint64{"x0": 0x8f},
+	}, {
+		// ldr w0, #0x1000
+		name:  "ldr_literal",
+		bytes: []byte{0x00, 0x80, 0x00, 0x18},
+		want:  map[expr.Key]uint64{"x0": 0x83828180},
+	}, {
+		// ldrsw x0, #0x1000
+		name:  "ldrsw_literal",
+		bytes: []byte{0x00, 0x80, 0x00, 0x98},
+		want:  map[expr.Key]uint64{"x0": 0xffffffff_83828180},
+	}, {
+		// prfm pldl1keep, [x1]
+		name:  "prfm",
+		bytes: []byte{0x20, 0x00, 0x80, 0xf9},
+		regs:  map[expr.Key]uint64{"x1": 0x2000},
+		want:  map[expr.Key]uint64{},
+	}, {
+		// str x0, [sp, #-16]!
+		name:    "str_pre",
+		bytes:   []byte{0xe0, 0x0f, 0x1f, 0xf8},
+		regs:    map[expr.Key]uint64{"x0": 0x01020304_05060708, "sp": 0x3010},
+		want:    map[expr.Key]uint64{"sp": 0x3000},
+		wantMem: memBytes(0x3000, 8, 7, 6, 5, 4, 3, 2, 1),
+	}, {
+		// strb w0, [x1], #-1
+		name:    "strb_post",
+		bytes:   []byte{0x20, 0xf4, 0x1f, 0x38},
+		regs:    map[expr.Key]uint64{"x0": 0x1ff, "x1": 0x3000},
+		want:    map[expr.Key]uint64{"x1": 0x2fff},
+		wantMem: memBytes(0x3000, 0xff),
+	}, {
+		// strh w0, [x1, x2, lsl #1]
+		name:    "strh_register",
+		bytes:   []byte{0x20, 0x78, 0x22, 0x78},
+		regs:    map[expr.Key]uint64{"x0": 0xabcd, "x1": 0x3000, "x2": 2},
+		want:    map[expr.Key]uint64{},
+		wantMem: memBytes(0x3004, 0xcd, 0xab),
+	}, {
+		// stp x29, x30, [sp, #-16]!
+		name:  "stp_pre",
+		bytes: []byte{0xfd, 0x7b, 0xbf, 0xa9},
+		regs: map[expr.Key]uint64{
+			"x29": 0x11,
+			"x30": 0x22,
+			"sp":  0x3010,
+		},
+		want:    map[expr.Key]uint64{"sp": 0x3000},
+		wantMem: memBytes(0x3000, 0x11, 0, 0, 0, 0, 0, 0, 0, 0x22, 0, 0, 0, 0, 0, 0, 0),
+	}, {
+		// ldp x29, x30, [sp], #16
+		name:  "ldp_post",
+		bytes: []byte{0xfd, 0x7b, 0xc1, 0xa8},
+		regs:  map[expr.Key]uint64{"sp": 0x2000},
+		want: map[expr.Key]uint64{
+			"x29": 0x87868584_83828180,
+			"x30": 0x8f8e8d8c_8b8a8988,
+			"sp":  0x2010,
+		},
+	}, {
+		// ldpsw x0, x1, [x2, #8]
+		name:  "ldpsw",
+		bytes: []byte{0x40, 0x04, 0x41, 0x69},
+		regs:  map[expr.Key]uint64{"x2": 0x2000},
+		want: map[expr.Key]uint64{
+			"x0": 0xffffffff_8b8a8988,
+			"x1": 0xffffffff_8f8e8d8c,
+		},
+	}, {
+		// stnp w0, w1, [x2, #8]
+		name:    "stnp",
+		bytes:   []byte{0x40, 0x04, 0x01, 0x28},
+		regs:    map[expr.Key]uint64{"x0": 0x1_00000001, "x1": 2, "x2": 0x3000},
+		want:    map[expr.Key]uint64{},
+		wantMem: memBytes(0x3008, 1, 0, 0, 0, 2, 0, 0, 0),
+	}, {
+		// ldxr x0, [x1]
+		name:  "ldxr",
+		bytes: []byte{0x20, 0x7c, 0x5f, 0xc8},
+		regs:  map[expr.Key]uint64{"x1": 0x2000},
+		want:  map[expr.Key]uint64{"x0": 0x87868584_83828180},
+	}, {
+		// stxr w2, x0, [x1]
+		name:    "stxr",
+		bytes:   []byte{0x20, 0x7c, 0x02, 0xc8},
+		regs:    map[expr.Key]uint64{"x0": 1, "x1": 0x3000, "x2": 7},
+		want:    map[expr.Key]uint64{"x2": 0},
+		wantMem: memBytes(0x3000, 1, 0, 0, 0, 0, 0, 0, 0),
+	}, {
+		// stlxrh w2, w0, [x1]
+		name:    "stlxrh",
+		bytes:   []byte{0x20, 0xfc, 0x02, 0x48},
+		regs:    map[expr.Key]uint64{"x0": 0x12345, "x1": 0x3000},
+		want:    map[expr.Key]uint64{"x2": 0},
+		wantMem: memBytes(0x3000, 0x45, 0x23),
+	}, {
+		// ldarb w0, [sp]
+		name:  "ldarb",
+		bytes: []byte{0xe0, 0xff, 0xdf, 0x08},
+		regs:  map[expr.Key]uint64{"sp": 0x2001},
+		want:  map[expr.Key]uint64{"x0": 0x81},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			regs, m := evalEffects(t, tt.bytes, tt.regs, mem)
+			require.Equal(t, tt.want, regs)
+
+			if tt.wantMem == nil {
+				tt.wantMem = map[uint64]byte{}
+			}
+			require.Equal(t, tt.wantMem, m)
+		})
+	}
+}
+
+func TestLoadStoreOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		typ   model.Type
+		order model.MemOrder
+	}{{
+		// ldr x0, [x1]
+		name:  "ldr",
+		bytes: []byte{0x20, 0x00, 0x40, 0xf9},
+	}, {
+		// ldxr x0, [x1]
+		name:  "ldxr",
+		bytes: []byte{0x20, 0x7c, 0x5f, 0xc8},
+	}, {
+		// ldaxr x0, [x1]
+		name:  "ldaxr",
+		bytes: []byte{0x20, 0xfc, 0x5f, 0xc8},
+		typ:   model.TypeMemOrder,
+		order: model.MemOrder{Succ: model.AccessRead | model.AccessWrite},
+	}, {
+		// stlr w0, [x1]
+		name:  "stlr",
+		bytes: []byte{0x20, 0xfc, 0x9f, 0x88},
+		typ:   model.TypeMemOrder,
+		order: model.MemOrder{Pred: model.AccessRead | model.AccessWrite},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := NewParser().Parse(testAddr, tt.bytes)
+			require.NoError(t, err)
+			require.Equal(t, tt.typ, ins.Type)
+			require.Equal(t, tt.order, ins.Order)
+		})
+	}
+}
@@ -0,0 +1,657 @@
+package aarch64
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// dataRegInstructions are data processing instructions with register operands.
+var dataRegInstructions = mergeInstructions(
+	[]*instructionType{
+		logicalReg("and", "00", false, exprtools.BitAnd),
+		logicalReg("bic", "00", true, exprtools.BitAnd),
+		logicalReg("orr", "01", false, exprtools.BitOr),
+		logicalReg("orn", "01", true, exprtools.BitOr),
+		logicalReg("eor", "10", false, exprtools.BitXor),
+		logicalReg("eon", "10", true, exprtools.BitXor),
+		logicalReg("ands", "11", false, exprtools.BitAnd),
+		logicalReg("bics", "11", true, exprtools.BitAnd),
+
+		addSubShifted("add", "00"),
+		addSubShifted("adds", "01"),
+		addSubShifted("sub", "10"),
+		addSubShifted("subs", "11"),
+
+		addSubExtended("add", "00"),
+		addSubExtended("adds", "01"),
+		addSubExtended("sub", "10"),
+		addSubExtended("subs", "11"),
+
+		addSubCarry("adc", "00"),
+		addSubCarry("adcs", "01"),
+		addSubCarry("sbc", "10"),
+		addSubCarry("sbcs", "11"),
+
+		condCompare("ccmn", "0", false),
+		condCompare("ccmn", "0", true),
+		condCompare("ccmp", "1", false),
+		condCompare("ccmp", "1", true),
+
+		condSelect("csel", "0", "00"),
+		condSelect("csinc", "0", "01"),
+		condSelect("csinv", "1", "00"),
+		condSelect("csneg", "1", "01"),
+	},
+	dataSource2Instructions,
+	dataSource1Instructions,
+	dataSource3Instructions,
+)
+
+// logicalReg creates logical instruction called name with shifted register
+// operand. Bits opc of the instruction are given by opc and the operation is
+// f. If invert is set, the second operand is negated before the operation is
+// applied. Instructions with opc 11 set flags.
+func logicalReg(name string, opc string, invert bool, f binaryExprFunc) *instructionType {
+	setFlags := opc == "11"
+	n := "0"
+	if invert {
+		n = "1"
+	}
+
+	return &instructionType{
+		name:   name,
+		opcode: pattern("x" + opc + "01010 xx" + n + "xxxxx xxxxxxxx xxxxxxxx"),
+		reserved: func(i instruction) bool {
+			return shiftedRm(i).invalid(i.width(), true)
+		},
+		operands: func(i instruction, abi bool) []string {
+			w := i.width()
+			return append([]string{
+				i.rd().name(w, zeroReg, abi),
+				i.rn().name(w, zeroReg, abi),
+			}, shiftedRm(i).operands(w, abi)...)
+		},
+		alias: func(i instruction, ops []string) (string, []string, bool) {
+			sh := shiftedRm(i)
+			switch {
+			case setFlags && !invert && i.rd() == reg31:
+				return "tst", ops[1:], true
+			case name == "orr" && i.rn() == reg31 && sh.amount == 0 &&
+				sh.shift == shiftLSL:
+				return "mov", []string{ops[0], ops[2]}, true
+			case name == "orn" && i.rn() == reg31:
+				return "mvn", append([]string{ops[0]}, ops[2:]...), true
+			default:
+				return "", nil, false
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			op2 := shiftedRm(i).value(w)
+			if invert {
+				op2 = exprtools.BitNot(op2, w)
+			}
+			res := f(regLoad(i.rn(), w, zeroReg), op2, w)
+
+			effects := []expr.Effect{regStore(res, i.rd(), zeroReg)}
+			if setFlags {
+				effects = append(effects, logicFlags(res, w).store()...)
+			}
+			return effects
+		},
+	}
+}
+
+// addSubShifted creates add or subtract instruction called name with shifted
+// register operand. Bits op and S of the instruction are given by opS.
+func addSubShifted(name string, opS string) *instructionType {
+	sub, setFlags := opS[0] == '1', opS[1] == '1'
+
+	return &instructionType{
+		name:   name,
+		opcode: pattern("x" + opS + "01011 xx0xxxxx xxxxxxxx xxxxxxxx"),
+		reserved: func(i instruction) bool {
+			return shiftedRm(i).invalid(i.width(), false)
+		},
+		operands: func(i instruction, abi bool) []string {
+			w := i.width()
+			return append([]string{
+				i.rd().name(w, zeroReg, abi),
+				i.rn().name(w, zeroReg, abi),
+			}, shiftedRm(i).operands(w, abi)...)
+		},
+		alias: func(i instruction, ops []string) (string, []string, bool) {
+			switch {
+			case setFlags && i.rd() == reg31 && sub:
+				return "cmp", ops[1:], true
+			case setFlags && i.rd() == reg31:
+				return "cmn", ops[1:], true
+			case sub && i.rn() == reg31:
+				return "neg" + name[3:], append([]string{ops[0]}, ops[2:]...), true
+			default:
+				return "", nil, false
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			rn := regLoad(i.rn(), w, zeroReg)
+			op2 := shiftedRm(i).value(w)
+			return addSub(rn, op2, i.rd(), zeroReg, sub, setFlags, w)
+		},
+	}
+}
+
+// addSubExtended creates add or subtract instruction called name with extended
+// register operand. Bits op and S of the instruction are given by opS.
+//
+// The second operand is extended according to option field in bits [13:15] of
+// the instruction and then shifted left by 0 to 4 bits.
+func addSubExtended(name string, opS string) *instructionType {
+	sub, setFlags := opS[0] == '1', opS[1] == '1'
+	rdKind := stackReg
+	if setFlags {
+		rdKind = zeroReg
+	}
+
+	option := func(i instruction) uint32 { return i.field(13, 3) }
+	amount := func(i instruction) uint32 { return i.field(10, 3) }
+
+	return &instructionType{
+		name:   name,
+		opcode: pattern("x" + opS + "01011 001xxxxx xxxxxxxx xxxxxxxx"),
+		reserved: func(i instruction) bool {
+			return amount(i) > 4
+		},
+		operands: func(i instruction, abi bool) []string {
+			w := i.width()
+			ops := []string{
+				i.rd().name(w, rdKind, abi),
+				i.rn().name(w, stackReg, abi),
+				i.rm().name(extendedRmWidth(option(i), w), zeroReg, abi),
+			}
+
+			// Extension of the full register width is written as lsl
+			// if the stack pointer is involved.
+			ext := extendNames[option(i)]
+			full := (w == width64 && option(i) == 0b011) ||
+				(w == width32 && option(i) == 0b010)
+			sp := i.rn() == reg31 || (!setFlags && i.rd() == reg31)
+			if full && sp {
+				if amount(i) == 0 {
+					return ops
+				}
+				ext = "lsl"
+			}
+
+			if amount(i) != 0 {
+				ext += " " + decImm(int64(amount(i)))
+			}
+			return append(ops, ext)
+		},
+		alias: func(i instruction, ops []string) (string, []string, bool) {
+			switch {
+			case setFlags && i.rd() == reg31 && sub:
+				return "cmp", ops[1:], true
+			case setFlags && i.rd() == reg31:
+				return "cmn", ops[1:], true
+			default:
+				return "", nil, false
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			rn := regLoad(i.rn(), w, stackReg)
+			op2 := extend(regLoad(i.rm(), w, zeroReg), option(i), w)
+			op2 = shiftLSL.apply(op2, amount(i), w)
+			return addSub(rn, op2, i.rd(), rdKind, sub, setFlags, w)
+		},
+	}
+}
+
+// extendedRmWidth returns width of register extended according to option field
+// of an instruction operating registers of width w.
+func extendedRmWidth(option uint32, w expr.Width) expr.Width {
+	if w == width32 {
+		return width32
+	}
+	return extendRegWidth(option)
+}
+
+// addSubCarry creates add or subtract with carry instruction called name. Bits
+// op and S of the instruction are given by opS.
+func addSubCarry(name string, opS string) *instructionType {
+	sub, setFlags := opS[0] == '1', opS[1] == '1'
+
+	return &instructionType{
+		name:   name,
+		opcode: pattern("x" + opS + "11010 000xxxxx 000000xx xxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			w := i.width()
+			return []string{
+				i.rd().name(w, zeroReg, abi),
+				i.rn().name(w, zeroReg, abi),
+				i.rm().name(w, zeroReg, abi),
+			}
+		},
+		alias: func(i instruction, ops []string) (string, []string, bool) {
+			if !sub || i.rn() != reg31 {
+				return "", nil, false
+			}
+			return "ngc" + name[3:], []string{ops[0], ops[2]}, true
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			rm := regLoad(i.rm(), w, zeroReg)
+			if sub {
+				rm = exprtools.BitNot(rm, w)
+			}
+
+			res, flags := addCarry(regLoad(i.rn(), w, zeroReg), rm, flagLoad(CKey), w)
+			effects := []expr.Effect{regStore(res, i.rd(), zeroReg)}
+			if setFlags {
+				effects = append(effects, flags.store()...)
+			}
+			return effects
+		},
+	}
+}
+
+// condCompare creates conditional compare instruction called name with bit op
+// given by op. If imm is set, the second operand is 5 bit unsigned immediate
+// value, otherwise it's a register.
+//
+// If the condition holds, flags are set according to comparison of operands.
+// Otherwise flags are set to immediate value nzcv encoded in the instruction.
+func condCompare(name string, op string, imm bool) *instructionType {
+	sub := op == "1"
+	immBit := "0"
+	if imm {
+		immBit = "1"
+	}
+
+	return &instructionType{
+		name:   name,
+		opcode: pattern("x" + op + "111010 010xxxxx xxxx" + immBit + "0xx xxx0xxxx"),
+		operands: func(i instruction, abi bool) []string {
+			w := i.width()
+			op2 := i.rm().name(w, zeroReg, abi)
+			if imm {
+				op2 = decImm(int64(i.rm()))
+			}
+			return []string{
+				i.rn().name(w, zeroReg, abi),
+				op2,
+				decImm(int64(i.field(0, 4))),
+				condName(i.field(12, condBits)),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			var op2 expr.Expr = immConst(uint64(i.rm()), w)
+			if !imm {
+				op2 = regLoad(i.rm(), w, zeroReg)
+			}
+
+			var c expr.Expr = expr.Zero
+			if sub {
+				op2, c = exprtools.BitNot(op2, w), expr.One
+			}
+
+			_, flags := addCarry(regLoad(i.rn(), w, zeroReg), op2, c, w)
+			cond := i.field(12, condBits)
+			return selectFlags(cond, flags, nzcvConst(i.field(0, 4))).store()
+		},
+	}
+}
+
+// condSelect creates conditional select instruction called name with bit op
+// given by op and bits op2 given by op2. The instruction selects the first
+// register if the condition holds. Otherwise it selects the second register
+// which is incremented (op2 01), inverted (op 1) or both (negated).
+func condSelect(name string, op string, op2 string) *instructionType {
+	inc, inv := op2 == "01", op == "1"
+
+	return &instructionType{
+		name:   name,
+		opcode: pattern("x" + op + "011010 100xxxxx xxxx" + op2 + "xx xxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			w := i.width()
+			return []string{
+				i.rd().name(w, zeroReg, abi),
+				i.rn().name(w, zeroReg, abi),
+				i.rm().name(w, zeroReg, abi),
+				condName(i.field(12, condBits)),
+			}
+		},
+		alias: func(i instruction, ops []string) (string, []string, bool) {
+			cond := i.field(12, condBits)
+			if cond>>1 == condAL>>1 || i.rn() != i.rm() {
+				return "", nil, false
+			}
+
+			inverted := condName(invertCond(cond))
+			switch {
+			case name == "csel":
+				return "", nil, false
+			case i.rn() == reg31 && name != "csneg":
+				return map[string]string{
+					"csinc": "cset",
+					"csinv": "csetm",
+				}[name], []string{ops[0], inverted}, true
+			default:
+				return map[string]string{
+					"csinc": "cinc",
+					"csinv": "cinv",
+					"csneg": "cneg",
+				}[name], []string{ops[0], ops[1], inverted}, true
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			f := regLoad(i.rm(), w, zeroReg)
+			switch {
+			case inc && inv:
+				f = exprtools.Negate(f, w)
+			case inc:
+				f = expr.NewBinary(expr.Add, f, immConst(1, w), w)
+			case inv:
+				f = exprtools.BitNot(f, w)
+			}
+
+			cond := i.field(12, condBits)
+			res := condition(cond, regLoad(i.rn(), w, zeroReg), f, w)
+			return []expr.Effect{regStore(res, i.rd(), zeroReg)}
+		},
+	}
+}
+
+// dataSource2Instructions are data processing instructions with two register
+// operands.
+var dataSource2Instructions = []*instructionType{
+	dataSource2("udiv", "000010", func(e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		return divNonZero(e2, expr.NewBinary(expr.Div, e1, e2, w), w)
+	}),
+	dataSource2("sdiv", "000011", func(e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		return divNonZero(e2, exprtools.SignedDiv(e1, e2, w), w)
+	}),
+	dataSource2("lslv", "001000", shiftVar(shiftLSL)),
+	dataSource2("lsrv", "001001", shiftVar(shiftLSR)),
+	dataSource2("asrv", "001010", shiftVar(shiftASR)),
+	dataSource2("rorv", "001011", shiftVar(shiftROR)),
+}
+
+// divNonZero returns division result res of width w unless the divisor is
+// zero. Unlike the expression language, A64 division by zero results in zero.
+func divNonZero(divisor, res expr.Expr, w expr.Width) expr.Expr {
+	return exprtools.BoolCond(divisor, res, immConst(0, w), w)
+}
+
+// shiftVar returns function shifting the first operand by the second operand
+// using shift t. Only the lowest 5 or 6 bits of shift amount are used for 32
+// or 64 bit registers respectively.
+func shiftVar(t shiftType) binaryExprFunc {
+	return func(e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		amount := exprtools.MaskBits(e2, exprtools.BitCnt(w.Bits()/32+4), w)
+		switch t {
+		case shiftLSL:
+			return expr.NewBinary(expr.Lsh, e1, amount, w)
+		case shiftLSR:
+			return expr.NewBinary(expr.Rsh, e1, amount, w)
+		case shiftASR:
+			return exprtools.RshA(e1, amount, w)
+		default:
+			return exprtools.RotateRight(e1, amount, w)
+		}
+	}
+}
+
+// dataSource2 creates data processing instruction called name with two register
+// operands. Bits opcode of the instruction are given by opcode and the result
+// is computed by f.
+func dataSource2(name string, opcode string, f binaryExprFunc) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern("x0011010 110xxxxx " + opcode + "xx xxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			w := i.width()
+			return []string{
+				i.rd().name(w, zeroReg, abi),
+				i.rn().name(w, zeroReg, abi),
+				i.rm().name(w, zeroReg, abi),
+			}
+		},
+		alias: func(i instruction, ops []string) (string, []string, bool) {
+			// Shifts by register are written without the v suffix.
+			if opcode[:4] != "0010" {
+				return "", nil, false
+			}
+			return name[:len(name)-1], ops, true
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			rn, rm := regLoad(i.rn(), w, zeroReg), regLoad(i.rm(), w, zeroReg)
+			return []expr.Effect{regStore(f(rn, rm, w), i.rd(), zeroReg)}
+		},
+	}
+}
+
+// dataSource1Instructions are data processing instructions with a single
+// register operand.
+var dataSource1Instructions = []*instructionType{
+	dataSource1("rbit", "000000", nil, func(e expr.Expr, w expr.Width) expr.Expr {
+		// Bits within bytes are reversed by swapping of neighbouring
+		// bits, pairs of bits and nibbles. Bytes are then reversed as
+		// well.
+		for _, s := range []struct {
+			shift uint8
+			mask  uint64
+		}{{1, 0x5555555555555555}, {2, 0x3333333333333333}, {4, 0x0f0f0f0f0f0f0f0f}} {
+			sh, mask := expr.ConstFromUint(s.shift), immConst(s.mask, w)
+			e = exprtools.BitOr(
+				exprtools.BitAnd(expr.NewBinary(expr.Rsh, e, sh, w), mask, w),
+				expr.NewBinary(expr.Lsh, exprtools.BitAnd(e, mask, w), sh, w),
+				w,
+			)
+		}
+		return exprtools.ReverseBytes(e, w)
+	}),
+	dataSource1("rev16", "000001", nil, func(e expr.Expr, w expr.Width) expr.Expr {
+		return reverseBytesIn(e, width16, w)
+	}),
+	dataSource1("rev", "00001x",
+		func(i instruction) string {
+			if i.sf() && !i.bit(10) {
+				return "rev32"
+			}
+			return "rev"
+		},
+		nil,
+	),
+	dataSource1("clz", "000100", nil, exprtools.LeadingZeros),
+	dataSource1("cls", "000101", nil, func(e expr.Expr, w expr.Width) expr.Expr {
+		// Leading sign bits (excluding the sign bit) are leading zeros
+		// of xor of the value and the value shifted by one. The lowest
+		// bit is set to avoid counting of the lowest bit.
+		x := exprtools.BitXor(e, expr.NewBinary(expr.Lsh, e, expr.One, w), w)
+		x = exprtools.BitOr(x, immConst(1, w), w)
+		return exprtools.LeadingZeros(x, w)
+	}),
+}
+
+// reverseBytesIn reverses order of bytes in every chunk of width chunk in value
+// e of width w.
+func reverseBytesIn(e expr.Expr, chunk expr.Width, w expr.Width) expr.Expr {
+	if chunk >= w {
+		return exprtools.ReverseBytes(e, w)
+	}
+
+	var res expr.Expr = immConst(0, w)
+	for off := expr.Width(0); off < w; off += chunk {
+		sh := expr.ConstFromUint(off.Bits())
+		part := exprtools.ReverseBytes(expr.NewBinary(expr.Rsh, e, sh, w), chunk)
+		part = exprtools.MaskBits(part, exprtools.BitCnt(chunk.Bits()), w)
+		res = exprtools.BitOr(res, expr.NewBinary(expr.Lsh, part, sh, w), w)
+	}
+	return res
+}
+
+// dataSource1 creates data processing instruction called name with a single
+// register operand. Bits opcode of the instruction are given by opcode.
+//
+// The result is computed by f. If f is nil, the instruction is rev or rev32
+// which reverses bytes in words or in the whole register.
+func dataSource1(
+	name string,
+	opcode string,
+	mnemonic func(i instruction) string,
+	f func(e expr.Expr, w expr.Width) expr.Expr,
+) *instructionType {
+	t := &instructionType{
+		name:     name,
+		opcode:   pattern("x1011010 11000000 " + opcode + "xx xxxxxxxx"),
+		mnemonic: mnemonic,
+		operands: func(i instruction, abi bool) []string {
+			w := i.width()
+			return []string{
+				i.rd().name(w, zeroReg, abi),
+				i.rn().name(w, zeroReg, abi),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			rn := regLoad(i.rn(), w, zeroReg)
+			var res expr.Expr
+			if f != nil {
+				res = f(rn, w)
+			} else if i.sf() && !i.bit(10) {
+				res = reverseBytesIn(rn, width32, w)
+			} else {
+				res = exprtools.ReverseBytes(rn, w)
+			}
+			return []expr.Effect{regStore(res, i.rd(), zeroReg)}
+		},
+	}
+
+	if f == nil {
+		// 32 bit rev with opc 11 is unallocated.
+		t.reserved = func(i instruction) bool { return !i.sf() && i.bit(10) }
+	}
+	return t
+}
+
+// dataSource3Instructions are data processing instructions with three register
+// operands.
+var dataSource3Instructions = []*instructionType{
+	multiplyAdd("madd", "x", "000", "0", nil),
+	multiplyAdd("msub", "x", "000", "1", nil),
+	multiplyAdd("smaddl", "1", "001", "0", signedLong),
+	multiplyAdd("smsubl", "1", "001", "1", signedLong),
+	multiplyAdd("umaddl", "1", "101", "0", unsignedLong),
+	multiplyAdd("umsubl", "1", "101", "1", unsignedLong),
+	multiplyHigh("smulh", "010", func(e1, e2 expr.Expr) expr.Expr {
+		return exprtools.SignedMul(e1, e2, width64)
+	}),
+	multiplyHigh("umulh", "110", func(e1, e2 expr.Expr) expr.Expr {
+		return expr.NewBinary(expr.Mul, e1, e2, width128)
+	}),
+}
+
+// signedLong sign extends 32 bit value e to 64 bits.
+func signedLong(e expr.Expr) expr.Expr { return sext(e, 31, width64) }
+
+// unsignedLong zero extends 32 bit value e to 64 bits.
+func unsignedLong(e expr.Expr) expr.Expr { return e }
+
+// multiplyAdd creates multiply-add instruction called name with bit sf given by
+// sf, bits op31 given by op31 and bit o0 given by o0. Instructions with o0 set
+// subtract the product from the addend.
+//
+// If long is nil, all the registers have the same width. Otherwise, the
+// instruction multiplies 32 bit registers extended by long to 64 bits.
+func multiplyAdd(
+	name string,
+	sf, op31, o0 string,
+	long func(e expr.Expr) expr.Expr,
+) *instructionType {
+	sub := o0 == "1"
+	srcWidth := func(i instruction) expr.Width {
+		if long != nil {
+			return width32
+		}
+		return i.width()
+	}
+
+	aliases := map[string]string{
+		"madd":   "mul",
+		"msub":   "mneg",
+		"smaddl": "smull",
+		"smsubl": "smnegl",
+		"umaddl": "umull",
+		"umsubl": "umnegl",
+	}
+
+	return &instructionType{
+		name:   name,
+		opcode: pattern(sf + "0011011 " + op31 + "xxxxx " + o0 + "xxxxxxx xxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			w, sw := i.width(), srcWidth(i)
+			return []string{
+				i.rd().name(w, zeroReg, abi),
+				i.rn().name(sw, zeroReg, abi),
+				i.rm().name(sw, zeroReg, abi),
+				i.ra().name(w, zeroReg, abi),
+			}
+		},
+		alias: func(i instruction, ops []string) (string, []string, bool) {
+			if i.ra() != reg31 {
+				return "", nil, false
+			}
+			return aliases[name], ops[:3], true
+		},
+		effects: func(i instruction) []expr.Effect {
+			w, sw := i.width(), srcWidth(i)
+			rn, rm := regLoad(i.rn(), sw, zeroReg), regLoad(i.rm(), sw, zeroReg)
+			if long != nil {
+				rn, rm = long(rn), long(rm)
+			}
+
+			prod := expr.NewBinary(expr.Mul, rn, rm, w)
+			ra := regLoad(i.ra(), w, zeroReg)
+
+			var res expr.Expr
+			if sub {
+				res = exprtools.Sub(ra, prod, w)
+			} else {
+				res = expr.NewBinary(expr.Add, ra, prod, w)
+			}
+			return []expr.Effect{regStore(res, i.rd(), zeroReg)}
+		},
+	}
+}
+
+// multiplyHigh creates instruction called name with bits op31 given by op31
+// which computes the highest 64 bits of 128 bit product mul of two 64 bit
+// registers. Field Ra should be all ones, but it's ignored.
+func multiplyHigh(
+	name string,
+	op31 string,
+	mul func(e1, e2 expr.Expr) expr.Expr,
+) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern("10011011 " + op31 + "xxxxx 0xxxxxxx xxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				i.rd().name(width64, zeroReg, abi),
+				i.rn().name(width64, zeroReg, abi),
+				i.rm().name(width64, zeroReg, abi),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			rn, rm := regLoad(i.rn(), width64, zeroReg), regLoad(i.rm(), width64, zeroReg)
+			prod := mul(rn, rm)
+			high := expr.NewBinary(expr.Rsh, prod, expr.ConstFromUint(uint8(64)), width128)
+			return []expr.Effect{regStore(high, i.rd(), zeroReg)}
+		},
+	}
+}
@@ -0,0 +1,348 @@
+package aarch64
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"mltwist/pkg/model"
+)
+
+// systemInstructions are exception generating, hint, barrier and system
+// register instructions.
+var systemInstructions = []*instructionType{
+	{
+		name:      "svc",
+		opcode:    pattern("11010100 000xxxxx xxxxxxxx xxx00001"),
+		instrType: model.TypeSyscall,
+		operands:  exceptionOperands,
+		effects:   func(instruction) []expr.Effect { return nil },
+	}, {
+		name:      "brk",
+		opcode:    pattern("11010100 001xxxxx xxxxxxxx xxx00000"),
+		instrType: model.TypeSyscall,
+		operands:  exceptionOperands,
+		effects:   func(instruction) []expr.Effect { return nil },
+	}, {
+		name:     "hint",
+		opcode:   pattern("11010101 00000011 0010xxxx xxx11111"),
+		mnemonic: hintName,
+		typeOf: func(i instruction) model.Type {
+			if hintName(i) == "wfi" || hintName(i) == "wfe" {
+				return model.TypeCPUStateChange
+			}
+			return model.TypeNone
+		},
+		operands: func(i instruction, _ bool) []string {
+			if hintName(i) == "hint" {
+				return []string{decImm(int64(hintNum(i)))}
+			}
+			if t := btiTargets[hintNum(i)]; t != "" {
+				return []string{t}
+			}
+			return nil
+		},
+		// Hints don't change the behaviour of the program. Pointer
+		// authentication hints are modelled as no-ops as well as the
+		// program runs the same way if the authentication succeeds.
+		effects: func(instruction) []expr.Effect { return nil },
+	}, {
+		name:     "clrex",
+		opcode:   pattern("11010101 00000011 0011xxxx 01011111"),
+		operands: barrierOperands,
+		// As exclusive stores always succeed, the exclusive monitor is
+		// not modelled and there is nothing to clear.
+		effects: func(instruction) []expr.Effect { return nil },
+	}, {
+		name:   "dsb",
+		opcode: pattern("11010101 00000011 0011xxxx 10011111"),
+		mnemonic: func(i instruction) string {
+			switch barrierOption(i) {
+			case 0b0000:
+				return "ssbb"
+			case 0b0100:
+				return "pssbb"
+			default:
+				return "dsb"
+			}
+		},
+		instrType: model.TypeMemOrder | model.TypeCPUStateChange,
+		order:     barrierOrder,
+		operands: func(i instruction, _ bool) []string {
+			if opt := barrierOption(i); opt == 0b0000 || opt == 0b0100 {
+				return nil
+			}
+			return []string{barrierName(barrierOption(i))}
+		},
+		effects: func(instruction) []expr.Effect { return nil },
+	}, {
+		name:      "dmb",
+		opcode:    pattern("11010101 00000011 0011xxxx 10111111"),
+		instrType: model.TypeMemOrder,
+		order:     barrierOrder,
+		operands: func(i instruction, _ bool) []string {
+			return []string{barrierName(barrierOption(i))}
+		},
+		effects: func(instruction) []expr.Effect { return nil },
+	}, {
+		name:      "isb",
+		opcode:    pattern("11010101 00000011 0011xxxx 11011111"),
+		instrType: model.TypeCPUStateChange,
+		operands:  barrierOperands,
+		effects:   func(instruction) []expr.Effect { return nil },
+	}, {
+		name:   "mrs",
+		opcode: pattern("11010101 0011xxxx xxxxxxxx xxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				i.rd().name(width64, zeroReg, abi),
+				sysRegNum(i).String(),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{regStore(sysRegNum(i).load(), i.rd(), zeroReg)}
+		},
+	}, {
+		name:   "msr",
+		opcode: pattern("11010101 0001xxxx xxxxxxxx xxxxxxxx"),
+		reserved: func(i instruction) bool {
+			return sysRegNum(i) == sysRegCntvct
+		},
+		typeOf: func(i instruction) model.Type {
+			if _, ok := sysRegKeys[sysRegNum(i)]; ok || sysRegNum(i) == sysRegNZCV {
+				return model.TypeNone
+			}
+			return model.TypeCPUStateChange
+		},
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				sysRegNum(i).String(),
+				i.rd().name(width64, zeroReg, abi),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return sysRegNum(i).store(regLoad(i.rd(), width64, zeroReg))
+		},
+	},
+}
+
+// exceptionOperands returns operands of exception generating instructions. The
+// only operand is 16 bit immediate value in bits [5:20] of the instruction.
+func exceptionOperands(i instruction, _ bool) []string {
+	return []string{hexImm(uint64(i.field(5, 16)))}
+}
+
+// hintNum returns number of hint instruction i. The number consists of fields
+// CRm and op2 of the instruction.
+func hintNum(i instruction) uint32 { return i.field(5, 7) }
+
+// hintNames are names of hint instructions which have their own mnemonic. Index
+// in the array is number of the hint.
+var hintNames = map[uint32]string{
+	0:  "nop",
+	1:  "yield",
+	2:  "wfe",
+	3:  "wfi",
+	4:  "sev",
+	5:  "sevl",
+	7:  "xpaclri",
+	24: "paciaz",
+	25: "paciasp",
+	26: "pacibz",
+	27: "pacibsp",
+	28: "autiaz",
+	29: "autiasp",
+	30: "autibz",
+	31: "autibsp",
+	32: "bti",
+	34: "bti",
+	36: "bti",
+	38: "bti",
+}
+
+// btiTargets are operands of bti instruction. Index in the array is number of
+// the hint.
+var btiTargets = map[uint32]string{34: "c", 36: "j", 38: "jc"}
+
+// hintName returns mnemonic of hint instruction i. Hints without their own
+// mnemonic are written as hint instruction with the hint number.
+func hintName(i instruction) string {
+	if n, ok := hintNames[hintNum(i)]; ok {
+		return n
+	}
+	return "hint"
+}
+
+// barrierOption returns option field (CRm) of barrier instruction i.
+func barrierOption(i instruction) uint32 { return i.field(8, 4) }
+
+// barrierNames are names of options of data barrier instructions. Options
+// without name are written as immediate value.
+var barrierNames = map[uint32]string{
+	0b0001: "oshld",
+	0b0010: "oshst",
+	0b0011: "osh",
+	0b0101: "nshld",
+	0b0110: "nshst",
+	0b0111: "nsh",
+	0b1001: "ishld",
+	0b1010: "ishst",
+	0b1011: "ish",
+	0b1101: "ld",
+	0b1110: "st",
+	0b1111: "sy",
+}
+
+// barrierName returns name of option opt of data barrier instruction.
+func barrierName(opt uint32) string {
+	if n, ok := barrierNames[opt]; ok {
+		return n
+	}
+	return decImm(int64(opt))
+}
+
+// barrierOperands returns operands of barrier instruction i which doesn't have
+// any operand by default (option sy). Other options are written as immediate
+// value.
+func barrierOperands(i instruction, _ bool) []string {
+	if opt := barrierOption(i); opt != 0b1111 {
+		return []string{decImm(int64(opt))}
+	}
+	return nil
+}
+
+// barrierOrder returns memory accesses ordered by data barrier instruction i.
+// The lowest 2 bits of the option select accesses ordered: loads before all
+// accesses (ld) or stores before stores (st). All other options (including
+// reserved ones) are treated as full barriers.
+//
+// The shareability domain given by the highest 2 bits of the option is not
+// modelled as the program runs on a single machine.
+func barrierOrder(i instruction) model.MemOrder {
+	switch barrierOption(i) & 0b11 {
+	case 0b01:
+		return model.MemOrder{
+			Pred: model.AccessRead | model.AccessInput,
+			Succ: model.AccessAll,
+		}
+	case 0b10:
+		return model.MemOrder{
+			Pred: model.AccessWrite | model.AccessOutput,
+			Succ: model.AccessWrite | model.AccessOutput,
+		}
+	default:
+		return model.MemOrder{}
+	}
+}
+
+// sysReg is number of a system register accessed by mrs and msr instructions.
+// The number consists of fields op0, op1, CRn, CRm and op2 of the instruction.
+type sysReg uint32
+
+// sysRegBits is number of bits of system register number encoded in bits [5:20]
+// of mrs and msr instructions.
+const sysRegBits = 16
+
+// newSysReg creates system register number from its fields.
+func newSysReg(op0, op1, crn, crm, op2 uint32) sysReg {
+	return sysReg(op0<<14 | op1<<11 | crn<<7 | crm<<3 | op2)
+}
+
+// System registers accessible from user space the package knows.
+var (
+	sysRegNZCV      = newSysReg(3, 3, 4, 2, 0)
+	sysRegFPCR      = newSysReg(3, 3, 4, 4, 0)
+	sysRegFPSR      = newSysReg(3, 3, 4, 4, 1)
+	sysRegTpidr     = newSysReg(3, 3, 13, 0, 2)
+	sysRegTpidrro   = newSysReg(3, 3, 13, 0, 3)
+	sysRegCntfrq    = newSysReg(3, 3, 14, 0, 0)
+	sysRegCntvct    = newSysReg(3, 3, 14, 0, 2)
+	sysRegCtr       = newSysReg(3, 3, 0, 0, 1)
+	sysRegDczid     = newSysReg(3, 3, 0, 0, 7)
+	sysRegMidr      = newSysReg(3, 0, 0, 0, 0)
+	sysRegCurrentEL = newSysReg(3, 0, 4, 2, 2)
+	sysRegTpidrEL1  = newSysReg(3, 0, 13, 0, 4)
+	sysRegVbarEL1   = newSysReg(3, 0, 12, 0, 0)
+	sysRegSctlrEL1  = newSysReg(3, 0, 1, 0, 0)
+)
+
+// sysRegNames are names of system registers in assembler code.
+var sysRegNames = map[sysReg]string{
+	sysRegNZCV:      "nzcv",
+	sysRegFPCR:      "fpcr",
+	sysRegFPSR:      "fpsr",
+	sysRegTpidr:     "tpidr_el0",
+	sysRegTpidrro:   "tpidrro_el0",
+	sysRegCntfrq:    "cntfrq_el0",
+	sysRegCntvct:    "cntvct_el0",
+	sysRegCtr:       "ctr_el0",
+	sysRegDczid:     "dczid_el0",
+	sysRegMidr:      "midr_el1",
+	sysRegCurrentEL: "currentel",
+	sysRegTpidrEL1:  "tpidr_el1",
+	sysRegVbarEL1:   "vbar_el1",
+	sysRegSctlrEL1:  "sctlr_el1",
+}
+
+// sysRegKeys lists system registers which are modelled as a plain register.
+// Writes to those registers don't affect execution of other instructions.
+var sysRegKeys = map[sysReg]expr.Key{
+	sysRegFPCR:  expr.Key("fpcr"),
+	sysRegFPSR:  expr.Key("fpsr"),
+	sysRegTpidr: expr.Key("tpidr_el0"),
+}
+
+// sysRegNum returns number of system register accessed by instruction i.
+func sysRegNum(i instruction) sysReg { return sysReg(i.field(5, sysRegBits)) }
+
+// String returns name of system register r in assembler code. Registers
+// without a name are written as their fields the way GNU assembler does.
+func (r sysReg) String() string {
+	if n, ok := sysRegNames[r]; ok {
+		return n
+	}
+	return fmt.Sprintf("s%d_%d_c%d_c%d_%d",
+		r>>14&0b11, r>>11&0b111, r>>7&0b1111, r>>3&0b1111, r&0b111)
+}
+
+// key returns register key representing system register r. Registers which
+// are not modelled by any specific register are keyed by their names.
+func (r sysReg) key() expr.Key {
+	if k, ok := sysRegKeys[r]; ok {
+		return k
+	}
+	return expr.Key(r.String())
+}
+
+// load returns 64 bit value of system register r.
+//
+// NZCV flags are returned in bits [28:31] and the virtual counter is the
+// wall-clock time counter provided by the environment executing the code.
+func (r sysReg) load() expr.Expr {
+	switch r {
+	case sysRegNZCV:
+		var res expr.Expr = immConst(0, width64)
+		for j, k := range []expr.Key{VKey, CKey, ZKey, NKey} {
+			flag := expr.NewBinary(expr.Lsh, flagLoad(k),
+				expr.ConstFromUint(uint8(28+j)), width64)
+			res = exprtools.BitOr(res, flag, width64)
+		}
+		return res
+	case sysRegCntvct:
+		return expr.NewRegLoad(expr.TimeKey, width64)
+	default:
+		return expr.NewRegLoad(r.key(), width64)
+	}
+}
+
+// store returns effects writing 64 bit value e to system register r.
+func (r sysReg) store(e expr.Expr) []expr.Effect {
+	if r != sysRegNZCV {
+		return []expr.Effect{expr.NewRegStore(e, r.key(), width64)}
+	}
+
+	flag := func(b uint8) expr.Expr {
+		bit := expr.NewBinary(expr.Rsh, e, expr.ConstFromUint(b), width64)
+		return exprtools.MaskBits(bit, 1, flagWidth)
+	}
+	return nzcv{n: flag(31), z: flag(30), c: flag(29), v: flag(28)}.store()
+}
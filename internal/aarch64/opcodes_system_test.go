@@ -0,0 +1,147 @@
+package aarch64
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemEffects(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		regs  map[expr.Key]uint64
+		want  map[expr.Key]uint64
+	}{{
+		// mrs x0, nzcv
+		name:  "mrs_nzcv",
+		bytes: []byte{0x00, 0x42, 0x3b, 0xd5},
+		regs:  map[expr.Key]uint64{NKey: 1, CKey: 1},
+		want:  map[expr.Key]uint64{"x0": 0xa0000000},
+	}, {
+		// msr nzcv, x1
+		name:  "msr_nzcv",
+		bytes: []byte{0x01, 0x42, 0x1b, 0xd5},
+		regs:  map[expr.Key]uint64{"x1": 0xffffffff_6fffffff},
+		want:  flags(0, 1, 1, 0),
+	}, {
+		// mrs x0, tpidr_el0
+		name:  "mrs_tpidr",
+		bytes: []byte{0x40, 0xd0, 0x3b, 0xd5},
+		regs:  map[expr.Key]uint64{"tpidr_el0": 0x1234},
+		want:  map[expr.Key]uint64{"x0": 0x1234},
+	}, {
+		// msr tpidr_el0, x1
+		name:  "msr_tpidr",
+		bytes: []byte{0x41, 0xd0, 0x1b, 0xd5},
+		regs:  map[expr.Key]uint64{"x1": 0x1234},
+		want:  map[expr.Key]uint64{"tpidr_el0": 0x1234},
+	}, {
+		// mrs x0, cntvct_el0
+		name:  "mrs_cntvct",
+		bytes: []byte{0x40, 0xe0, 0x3b, 0xd5},
+		regs:  map[expr.Key]uint64{expr.TimeKey: 0x1234},
+		want:  map[expr.Key]uint64{"x0": 0x1234},
+	}, {
+		// mrs x0, s3_3_c15_c2_0
+		name:  "mrs_unknown",
+		bytes: []byte{0x00, 0xf2, 0x3b, 0xd5},
+		regs:  map[expr.Key]uint64{"s3_3_c15_c2_0": 0x1234},
+		want:  map[expr.Key]uint64{"x0": 0x1234},
+	}, {
+		// nop
+		name:  "nop",
+		bytes: []byte{0x1f, 0x20, 0x03, 0xd5},
+		want:  map[expr.Key]uint64{},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, evalRegs(t, tt.bytes, tt.regs))
+		})
+	}
+}
+
+func TestSystemTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		typ   model.Type
+		order model.MemOrder
+	}{{
+		// svc #0
+		name:  "svc",
+		bytes: []byte{0x01, 0x00, 0x00, 0xd4},
+		typ:   model.TypeSyscall,
+	}, {
+		// brk #0x3e8
+		name:  "brk",
+		bytes: []byte{0x00, 0x7d, 0x20, 0xd4},
+		typ:   model.TypeSyscall,
+	}, {
+		// wfi
+		name:  "wfi",
+		bytes: []byte{0x7f, 0x20, 0x03, 0xd5},
+		typ:   model.TypeCPUStateChange,
+	}, {
+		// paciasp
+		name:  "paciasp",
+		bytes: []byte{0x3f, 0x23, 0x03, 0xd5},
+	}, {
+		// dmb ish
+		name:  "dmb_ish",
+		bytes: []byte{0xbf, 0x3b, 0x03, 0xd5},
+		typ:   model.TypeMemOrder,
+	}, {
+		// dmb ishld
+		name:  "dmb_ishld",
+		bytes: []byte{0xbf, 0x39, 0x03, 0xd5},
+		typ:   model.TypeMemOrder,
+		order: model.MemOrder{
+			Pred: model.AccessRead | model.AccessInput,
+			Succ: model.AccessAll,
+		},
+	}, {
+		// dmb ishst
+		name:  "dmb_ishst",
+		bytes: []byte{0xbf, 0x3a, 0x03, 0xd5},
+		typ:   model.TypeMemOrder,
+		order: model.MemOrder{
+			Pred: model.AccessWrite | model.AccessOutput,
+			Succ: model.AccessWrite | model.AccessOutput,
+		},
+	}, {
+		// dsb sy
+		name:  "dsb",
+		bytes: []byte{0x9f, 0x3f, 0x03, 0xd5},
+		typ:   model.TypeMemOrder | model.TypeCPUStateChange,
+	}, {
+		// isb
+		name:  "isb",
+		bytes: []byte{0xdf, 0x3f, 0x03, 0xd5},
+		typ:   model.TypeCPUStateChange,
+	}, {
+		// msr tpidr_el0, x0
+		name:  "msr_modelled",
+		bytes: []byte{0x40, 0xd0, 0x1b, 0xd5},
+	}, {
+		// msr vbar_el1, x0
+		name:  "msr_not_modelled",
+		bytes: []byte{0x00, 0xc0, 0x18, 0xd5},
+		typ:   model.TypeCPUStateChange,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := NewParser().Parse(testAddr, tt.bytes)
+			require.NoError(t, err)
+			require.NoError(t, ins.Validate())
+			require.Equal(t, tt.typ, ins.Type)
+			require.Equal(t, tt.order, ins.Order)
+		})
+	}
+}
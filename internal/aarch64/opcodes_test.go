@@ -0,0 +1,462 @@
+package aarch64
+
+import (
+	"mltwist/internal/archtest"
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstructions(t *testing.T) {
+	archtest.Instructions(t, instructions, (*instructionType).validate)
+}
+
+// testAddr is address instructions are parsed at in tests.
+const testAddr = 0x1000
+
+// evalEffects evaluates effects of an instruction encoded in bs at testAddr
+// with registers set to regs and memory set to mem. Registers and bytes of
+// memory not present in regs or mem are zero. It returns values of all
+// registers and bytes of memory the instruction writes.
+func evalEffects(
+	t testing.TB,
+	bs []byte,
+	regs map[expr.Key]uint64,
+	mem map[uint64]byte,
+) (map[expr.Key]uint64, map[uint64]byte) {
+	ins, err := NewParser().Parse(testAddr, bs)
+	require.NoError(t, err)
+	return archtest.EvalEffects(t, ins, regs, mem)
+}
+
+// evalRegs evaluates register stores of an instruction encoded in bs with
+// registers set to regs. Registers not present in regs are zero.
+func evalRegs(t testing.TB, bs []byte, regs map[expr.Key]uint64) map[expr.Key]uint64 {
+	res, mem := evalEffects(t, bs, regs, nil)
+	require.Empty(t, mem)
+	return res
+}
+
+// flags returns values of NZCV flag registers with the given values.
+func flags(n, z, c, v uint64) map[expr.Key]uint64 {
+	return map[expr.Key]uint64{NKey: n, ZKey: z, CKey: c, VKey: v}
+}
+
+// withFlags returns regs with flag registers set to the given values.
+func withFlags(regs map[expr.Key]uint64, n, z, c, v uint64) map[expr.Key]uint64 {
+	res := flags(n, z, c, v)
+	for k, val := range regs {
+		res[k] = val
+	}
+	return res
+}
+
+func TestDataImmEffects(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		regs  map[expr.Key]uint64
+		want  map[expr.Key]uint64
+	}{{
+		// add x0, x1, #16
+		name:  "add",
+		bytes: []byte{0x20, 0x40, 0x00, 0x91},
+		regs:  map[expr.Key]uint64{"x1": 0x10},
+		want:  map[expr.Key]uint64{"x0": 0x20},
+	}, {
+		// add w0, w1, #1, lsl #12
+		name:  "add_shifted_32",
+		bytes: []byte{0x20, 0x04, 0x40, 0x11},
+		regs:  map[expr.Key]uint64{"x1": 0xffffffff_ffffffff},
+		want:  map[expr.Key]uint64{"x0": 0xfff},
+	}, {
+		// mov x0, sp
+		name:  "mov_sp",
+		bytes: []byte{0xe0, 0x03, 0x00, 0x91},
+		regs:  map[expr.Key]uint64{"sp": 0x7ff0},
+		want:  map[expr.Key]uint64{"x0": 0x7ff0},
+	}, {
+		// subs x0, x1, #3
+		name:  "subs_zero",
+		bytes: []byte{0x20, 0x0c, 0x00, 0xf1},
+		regs:  map[expr.Key]uint64{"x1": 3},
+		want:  withFlags(map[expr.Key]uint64{"x0": 0}, 0, 1, 1, 0),
+	}, {
+		// cmp w1, #1
+		name:  "cmp_borrow",
+		bytes: []byte{0x3f, 0x04, 0x00, 0x71},
+		regs:  map[expr.Key]uint64{"x1": 0x1_00000000},
+		want:  flags(1, 0, 0, 0),
+	}, {
+		// orr w0, wzr, #0x55555555
+		name:  "orr_pattern",
+		bytes: []byte{0xe0, 0xf3, 0x00, 0x32},
+		want:  map[expr.Key]uint64{"x0": 0x55555555},
+	}, {
+		// ands x0, x1, #0xff
+		name:  "ands",
+		bytes: []byte{0x20, 0x1c, 0x40, 0xf2},
+		regs:  map[expr.Key]uint64{"x1": 0x180},
+		want:  withFlags(map[expr.Key]uint64{"x0": 0x80}, 0, 0, 0, 0),
+	}, {
+		// tst w1, #0x80000000
+		name:  "tst_negative",
+		bytes: []byte{0x3f, 0x00, 0x01, 0x72},
+		regs:  map[expr.Key]uint64{"x1": 0x80000000, NKey: 0, CKey: 1, VKey: 1},
+		want:  flags(1, 0, 0, 0),
+	}, {
+		// movk x0, #0x1234, lsl #48
+		name:  "movk",
+		bytes: []byte{0x80, 0x46, 0xe2, 0xf2},
+		regs:  map[expr.Key]uint64{"x0": 0xffffffff_ffffffff},
+		want:  map[expr.Key]uint64{"x0": 0x1234ffff_ffffffff},
+	}, {
+		// movn x0, #1
+		name:  "movn",
+		bytes: []byte{0x20, 0x00, 0x80, 0x92},
+		want:  map[expr.Key]uint64{"x0": 0xffffffff_fffffffe},
+	}, {
+		// movn w0, #0
+		name:  "movn_32",
+		bytes: []byte{0x00, 0x00, 0x80, 0x12},
+		want:  map[expr.Key]uint64{"x0": 0xffffffff},
+	}, {
+		// movz x0, #0xbeef, lsl #32
+		name:  "movz",
+		bytes: []byte{0xe0, 0xdd, 0xd7, 0xd2},
+		regs:  map[expr.Key]uint64{"x0": 0xffff},
+		want:  map[expr.Key]uint64{"x0": 0xbeef_00000000},
+	}, {
+		// asr x0, x1, #3
+		name:  "asr",
+		bytes: []byte{0x20, 0xfc, 0x43, 0x93},
+		regs:  map[expr.Key]uint64{"x1": 0x80000000_00000000},
+		want:  map[expr.Key]uint64{"x0": 0xf0000000_00000000},
+	}, {
+		// lsl w0, w1, #4
+		name:  "lsl",
+		bytes: []byte{0x20, 0x6c, 0x1c, 0x53},
+		regs:  map[expr.Key]uint64{"x1": 0xf000000f},
+		want:  map[expr.Key]uint64{"x0": 0xf0},
+	}, {
+		// sxtb x0, w1
+		name:  "sxtb",
+		bytes: []byte{0x20, 0x1c, 0x40, 0x93},
+		regs:  map[expr.Key]uint64{"x1": 0x80},
+		want:  map[expr.Key]uint64{"x0": 0xffffffff_ffffff80},
+	}, {
+		// uxth w0, w1
+		name:  "uxth",
+		bytes: []byte{0x20, 0x3c, 0x00, 0x53},
+		regs:  map[expr.Key]uint64{"x1": 0x12345678},
+		want:  map[expr.Key]uint64{"x0": 0x5678},
+	}, {
+		// ubfx x0, x1, #4, #8
+		name:  "ubfx",
+		bytes: []byte{0x20, 0x2c, 0x44, 0xd3},
+		regs:  map[expr.Key]uint64{"x1": 0xabcd},
+		want:  map[expr.Key]uint64{"x0": 0xbc},
+	}, {
+		// sbfiz w0, w1, #4, #8
+		name:  "sbfiz",
+		bytes: []byte{0x20, 0x1c, 0x1c, 0x13},
+		regs:  map[expr.Key]uint64{"x1": 0x80},
+		want:  map[expr.Key]uint64{"x0": 0xfffff800},
+	}, {
+		// bfi x0, x1, #8, #8
+		name:  "bfi",
+		bytes: []byte{0x20, 0x1c, 0x78, 0xb3},
+		regs:  map[expr.Key]uint64{"x0": 0xffffffff, "x1": 0x12},
+		want:  map[expr.Key]uint64{"x0": 0xffff12ff},
+	}, {
+		// bfxil w0, w1, #4, #8
+		name:  "bfxil",
+		bytes: []byte{0x20, 0x2c, 0x04, 0x33},
+		regs:  map[expr.Key]uint64{"x0": 0xffffffff, "x1": 0xab0},
+		want:  map[expr.Key]uint64{"x0": 0xffffffab},
+	}, {
+		// extr x0, x1, x2, #8
+		name:  "extr",
+		bytes: []byte{0x20, 0x20, 0xc2, 0x93},
+		regs:  map[expr.Key]uint64{"x1": 0x11, "x2": 0x22334455_66778899},
+		want:  map[expr.Key]uint64{"x0": 0x11223344_55667788},
+	}, {
+		// ror w0, w1, #4
+		name:  "ror_imm",
+		bytes: []byte{0x20, 0x10, 0x81, 0x13},
+		regs:  map[expr.Key]uint64{"x1": 0x12345678},
+		want:  map[expr.Key]uint64{"x0": 0x81234567},
+	}, {
+		// adr x0, #16
+		name:  "adr",
+		bytes: []byte{0x80, 0x00, 0x00, 0x10},
+		want:  map[expr.Key]uint64{"x0": testAddr + 16},
+	}, {
+		// adrp x0, #8192
+		name:  "adrp",
+		bytes: []byte{0x00, 0x00, 0x00, 0xd0},
+		want:  map[expr.Key]uint64{"x0": testAddr + 0x2000},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, evalRegs(t, tt.bytes, tt.regs))
+		})
+	}
+}
+
+func TestDataRegEffects(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		regs  map[expr.Key]uint64
+		want  map[expr.Key]uint64
+	}{{
+		// adds x0, x1, x2
+		name:  "adds_overflow",
+		bytes: []byte{0x20, 0x00, 0x02, 0xab},
+		regs:  map[expr.Key]uint64{"x1": 0x7fffffff_ffffffff, "x2": 1},
+		want:  withFlags(map[expr.Key]uint64{"x0": 0x80000000_00000000}, 1, 0, 0, 1),
+	}, {
+		// adds w0, w1, w2
+		name:  "adds_carry",
+		bytes: []byte{0x20, 0x00, 0x02, 0x2b},
+		regs:  map[expr.Key]uint64{"x1": 0xffffffff, "x2": 1},
+		want:  withFlags(map[expr.Key]uint64{"x0": 0}, 0, 1, 1, 0),
+	}, {
+		// bic x0, x1, x2, lsl #4
+		name:  "bic",
+		bytes: []byte{0x20, 0x10, 0x22, 0x8a},
+		regs:  map[expr.Key]uint64{"x1": 0xff, "x2": 1},
+		want:  map[expr.Key]uint64{"x0": 0xef},
+	}, {
+		// mvn w0, w1
+		name:  "mvn",
+		bytes: []byte{0xe0, 0x03, 0x21, 0x2a},
+		want:  map[expr.Key]uint64{"x0": 0xffffffff},
+	}, {
+		// eon x0, x1, x2
+		name:  "eon",
+		bytes: []byte{0x20, 0x00, 0x22, 0xca},
+		regs:  map[expr.Key]uint64{"x1": 0xf0, "x2": 0xff},
+		want:  map[expr.Key]uint64{"x0": 0xffffffff_fffffff0},
+	}, {
+		// orr x0, x1, x2, ror #4
+		name:  "orr_ror",
+		bytes: []byte{0x20, 0x10, 0xc2, 0xaa},
+		regs:  map[expr.Key]uint64{"x2": 0x1f},
+		want:  map[expr.Key]uint64{"x0": 0xf0000000_00000001},
+	}, {
+		// add x0, x1, w2, sxtw #2
+		name:  "add_extended",
+		bytes: []byte{0x20, 0xc8, 0x22, 0x8b},
+		regs:  map[expr.Key]uint64{"x1": 0x100, "x2": 0xffffffff},
+		want:  map[expr.Key]uint64{"x0": 0xfc},
+	}, {
+		// sub sp, sp, x1
+		name:  "sub_sp",
+		bytes: []byte{0xff, 0x63, 0x21, 0xcb},
+		regs:  map[expr.Key]uint64{"sp": 0x1000, "x1": 0x10},
+		want:  map[expr.Key]uint64{"sp": 0xff0},
+	}, {
+		// neg w0, w1
+		name:  "neg",
+		bytes: []byte{0xe0, 0x03, 0x01, 0x4b},
+		regs:  map[expr.Key]uint64{"x1": 1},
+		want:  map[expr.Key]uint64{"x0": 0xffffffff},
+	}, {
+		// adc x0, x1, x2
+		name:  "adc",
+		bytes: []byte{0x20, 0x00, 0x02, 0x9a},
+		regs:  map[expr.Key]uint64{"x1": 1, "x2": 2, CKey: 1},
+		want:  map[expr.Key]uint64{"x0": 4},
+	}, {
+		// sbcs w0, w1, w2
+		name:  "sbcs",
+		bytes: []byte{0x20, 0x00, 0x02, 0x7a},
+		regs:  map[expr.Key]uint64{"x1": 5, "x2": 3},
+		want:  withFlags(map[expr.Key]uint64{"x0": 1}, 0, 0, 1, 0),
+	}, {
+		// ccmp x1, x2, #2, ne
+		name:  "ccmp_holds",
+		bytes: []byte{0x22, 0x10, 0x42, 0xfa},
+		regs:  map[expr.Key]uint64{"x1": 7, "x2": 7},
+		want:  flags(0, 1, 1, 0),
+	}, {
+		// ccmp x1, x2, #2, ne
+		name:  "ccmp_fails",
+		bytes: []byte{0x22, 0x10, 0x42, 0xfa},
+		regs:  map[expr.Key]uint64{"x1": 7, "x2": 7, ZKey: 1},
+		want:  flags(0, 0, 1, 0),
+	}, {
+		// ccmn w1, #5, #0, eq
+		name:  "ccmn_imm",
+		bytes: []byte{0x20, 0x08, 0x45, 0x3a},
+		regs:  map[expr.Key]uint64{"x1": 0xfffffffb, ZKey: 1},
+		want:  flags(0, 1, 1, 0),
+	}, {
+		// csel x0, x1, x2, lt
+		name:  "csel",
+		bytes: []byte{0x20, 0xb0, 0x82, 0x9a},
+		regs:  map[expr.Key]uint64{"x1": 1, "x2": 2, NKey: 1},
+		want:  map[expr.Key]uint64{"x0": 1},
+	}, {
+		// cset w0, eq
+		name:  "cset",
+		bytes: []byte{0xe0, 0x17, 0x9f, 0x1a},
+		regs:  map[expr.Key]uint64{ZKey: 1},
+		want:  map[expr.Key]uint64{"x0": 1},
+	}, {
+		// cneg x0, x1, mi
+		name:  "cneg",
+		bytes: []byte{0x20, 0x54, 0x81, 0xda},
+		regs:  map[expr.Key]uint64{"x1": 5, NKey: 1},
+		want:  map[expr.Key]uint64{"x0": 0xffffffff_fffffffb},
+	}, {
+		// csinv w0, w1, w2, hi
+		name:  "csinv",
+		bytes: []byte{0x20, 0x80, 0x82, 0x5a},
+		regs:  map[expr.Key]uint64{"x1": 1, "x2": 2, CKey: 1, ZKey: 1},
+		want:  map[expr.Key]uint64{"x0": 0xfffffffd},
+	}, {
+		// udiv x0, x1, x2
+		name:  "udiv",
+		bytes: []byte{0x20, 0x08, 0xc2, 0x9a},
+		regs:  map[expr.Key]uint64{"x1": 7, "x2": 2},
+		want:  map[expr.Key]uint64{"x0": 3},
+	}, {
+		// udiv x0, x1, x2
+		name:  "udiv_zero",
+		bytes: []byte{0x20, 0x08, 0xc2, 0x9a},
+		regs:  map[expr.Key]uint64{"x1": 7},
+		want:  map[expr.Key]uint64{"x0": 0},
+	}, {
+		// sdiv w0, w1, w2
+		name:  "sdiv",
+		bytes: []byte{0x20, 0x0c, 0xc2, 0x1a},
+		regs:  map[expr.Key]uint64{"x1": 0xfffffff9, "x2": 2},
+		want:  map[expr.Key]uint64{"x0": 0xfffffffd},
+	}, {
+		// lsl x0, x1, x2
+		name:  "lslv",
+		bytes: []byte{0x20, 0x20, 0xc2, 0x9a},
+		regs:  map[expr.Key]uint64{"x1": 1, "x2": 65},
+		want:  map[expr.Key]uint64{"x0": 2},
+	}, {
+		// asr w0, w1, w2
+		name:  "asrv",
+		bytes: []byte{0x20, 0x28, 0xc2, 0x1a},
+		regs:  map[expr.Key]uint64{"x1": 0x80000000, "x2": 33},
+		want:  map[expr.Key]uint64{"x0": 0xc0000000},
+	}, {
+		// ror x0, x1, x2
+		name:  "rorv",
+		bytes: []byte{0x20, 0x2c, 0xc2, 0x9a},
+		regs:  map[expr.Key]uint64{"x1": 1, "x2": 1},
+		want:  map[expr.Key]uint64{"x0": 0x80000000_00000000},
+	}, {
+		// rbit w0, w1
+		name:  "rbit_32",
+		bytes: []byte{0x20, 0x00, 0xc0, 0x5a},
+		regs:  map[expr.Key]uint64{"x1": 1},
+		want:  map[expr.Key]uint64{"x0": 0x80000000},
+	}, {
+		// rbit x0, x1
+		name:  "rbit_64",
+		bytes: []byte{0x20, 0x00, 0xc0, 0xda},
+		regs:  map[expr.Key]uint64{"x1": 0x0000000f_00000006},
+		want:  map[expr.Key]uint64{"x0": 0x60000000_f0000000},
+	}, {
+		// rev16 w0, w1
+		name:  "rev16",
+		bytes: []byte{0x20, 0x04, 0xc0, 0x5a},
+		regs:  map[expr.Key]uint64{"x1": 0x11223344},
+		want:  map[expr.Key]uint64{"x0": 0x22114433},
+	}, {
+		// rev32 x0, x1
+		name:  "rev32",
+		bytes: []byte{0x20, 0x08, 0xc0, 0xda},
+		regs:  map[expr.Key]uint64{"x1": 0x11223344_55667788},
+		want:  map[expr.Key]uint64{"x0": 0x44332211_88776655},
+	}, {
+		// rev x0, x1
+		name:  "rev_64",
+		bytes: []byte{0x20, 0x0c, 0xc0, 0xda},
+		regs:  map[expr.Key]uint64{"x1": 0x11223344_55667788},
+		want:  map[expr.Key]uint64{"x0": 0x88776655_44332211},
+	}, {
+		// rev w0, w1
+		name:  "rev_32",
+		bytes: []byte{0x20, 0x08, 0xc0, 0x5a},
+		regs:  map[expr.Key]uint64{"x1": 0x11223344},
+		want:  map[expr.Key]uint64{"x0": 0x44332211},
+	}, {
+		// clz w0, w1
+		name:  "clz",
+		bytes: []byte{0x20, 0x10, 0xc0, 0x5a},
+		regs:  map[expr.Key]uint64{"x1": 0x10000},
+		want:  map[expr.Key]uint64{"x0": 15},
+	}, {
+		// cls x0, x1
+		name:  "cls",
+		bytes: []byte{0x20, 0x14, 0xc0, 0xda},
+		regs:  map[expr.Key]uint64{"x1": 0xff000000_00000000},
+		want:  map[expr.Key]uint64{"x0": 7},
+	}, {
+		// cls w0, w1
+		name:  "cls_zero",
+		bytes: []byte{0x20, 0x14, 0xc0, 0x5a},
+		want:  map[expr.Key]uint64{"x0": 31},
+	}, {
+		// madd x0, x1, x2, x3
+		name:  "madd",
+		bytes: []byte{0x20, 0x0c, 0x02, 0x9b},
+		regs:  map[expr.Key]uint64{"x1": 3, "x2": 4, "x3": 5},
+		want:  map[expr.Key]uint64{"x0": 17},
+	}, {
+		// msub w0, w1, w2, w3
+		name:  "msub",
+		bytes: []byte{0x20, 0x8c, 0x02, 0x1b},
+		regs:  map[expr.Key]uint64{"x1": 2, "x2": 3, "x3": 10},
+		want:  map[expr.Key]uint64{"x0": 4},
+	}, {
+		// smull x0, w1, w2
+		name:  "smull",
+		bytes: []byte{0x20, 0x7c, 0x22, 0x9b},
+		regs:  map[expr.Key]uint64{"x1": 0xfffffffe, "x2": 3},
+		want:  map[expr.Key]uint64{"x0": 0xffffffff_fffffffa},
+	}, {
+		// umull x0, w1, w2
+		name:  "umull",
+		bytes: []byte{0x20, 0x7c, 0xa2, 0x9b},
+		regs:  map[expr.Key]uint64{"x1": 0xffffffff, "x2": 2},
+		want:  map[expr.Key]uint64{"x0": 0x1_fffffffe},
+	}, {
+		// umsubl x0, w1, w2, x3
+		name:  "umsubl",
+		bytes: []byte{0x20, 0x8c, 0xa2, 0x9b},
+		regs:  map[expr.Key]uint64{"x1": 3, "x2": 5, "x3": 100},
+		want:  map[expr.Key]uint64{"x0": 85},
+	}, {
+		// smulh x0, x1, x2
+		name:  "smulh",
+		bytes: []byte{0x20, 0x7c, 0x42, 0x9b},
+		regs:  map[expr.Key]uint64{"x1": 0xffffffff_ffffffff, "x2": 5},
+		want:  map[expr.Key]uint64{"x0": 0xffffffff_ffffffff},
+	}, {
+		// umulh x0, x1, x2
+		name:  "umulh",
+		bytes: []byte{0x20, 0x7c, 0xc2, 0x9b},
+		regs:  map[expr.Key]uint64{"x1": 0xffffffff_ffffffff, "x2": 0x10},
+		want:  map[expr.Key]uint64{"x0": 0xf},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, evalRegs(t, tt.bytes, tt.regs))
+		})
+	}
+}
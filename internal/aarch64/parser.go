@@ -0,0 +1,65 @@
+package aarch64
+
+import (
+	"fmt"
+	"mltwist/internal/opcode"
+	"mltwist/pkg/model"
+)
+
+// Parser parses A64 instructions of 64 bit ARM architecture (AArch64).
+type Parser struct {
+	matcher *opcode.Matcher[*instructionType]
+}
+
+// NewParser creates a new A64 instruction parser.
+func NewParser() Parser {
+	decoder, err := opcode.NewMatcher(instructions)
+
+	// This means that instruction opcodes defined in this package are
+	// either invalid or they collide. This is non-recoverable as the
+	// package code has to be modified.
+	if err != nil {
+		panic(fmt.Sprintf("bug: matcher creation failed: %s", err.Error()))
+	}
+
+	return Parser{matcher: decoder}
+}
+
+// Parse parses an instruction starting at address a comprising of bytes at the
+// beginning of bs.
+//
+// The array of bytes bs is allowed to be longer than the instruction. In such a
+// case the Parse method will take into consideration only those bytes at the
+// beginning of the array which represent a single instruction. All A64
+// instructions are 4 bytes long.
+func (p Parser) Parse(a model.Addr, bs []byte) (model.Instruction, error) {
+	if l := len(bs); l < instructionLen {
+		return model.Instruction{}, fmt.Errorf(
+			"bytes are too short to be an A64 instruction opcode: %d", l)
+	}
+
+	opcode, ok := p.matcher.Match(bs[:instructionLen])
+	if !ok {
+		return model.Instruction{}, fmt.Errorf(
+			"unknown instruction opcode: 0x%x", bs[:instructionLen])
+	}
+
+	instr := newInstruction(a, bs, opcode)
+	if r := opcode.reserved; r != nil && r(instr) {
+		return model.Instruction{}, fmt.Errorf(
+			"unallocated encoding of %s: 0x%08x", opcode.name, instr.value)
+	}
+
+	return model.Instruction{
+		Type:    opcode.typeOfInstr(instr),
+		ByteLen: instructionLen,
+		Order:   opcode.memOrder(instr),
+
+		Effects: opcode.validEffects(instr),
+		Details: instr,
+	}, nil
+}
+
+// MinInstrLen returns length of the shortest A64 instruction in bytes. All A64
+// instructions have the same length.
+func (Parser) MinInstrLen() model.Addr { return instructionLen }
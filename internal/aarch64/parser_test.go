@@ -0,0 +1,60 @@
+package aarch64
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewParser(_ *testing.T) {
+	NewParser()
+}
+
+func TestParser_Parse(t *testing.T) {
+	tests := []struct {
+		name   string
+		bytes  []byte
+		hasErr bool
+	}{{
+		// add x0, x1, #5
+		name:  "valid",
+		bytes: []byte{0x20, 0x14, 0x00, 0x91},
+	}, {
+		// add x0, x1, #5
+		name:  "longer_bytes",
+		bytes: []byte{0x20, 0x14, 0x00, 0x91, 0xff, 0xff},
+	}, {
+		name:   "too_short",
+		bytes:  []byte{0x20, 0x14, 0x00},
+		hasErr: true,
+	}, {
+		name:   "unknown",
+		bytes:  []byte{0x00, 0x00, 0x00, 0x00},
+		hasErr: true,
+	}, {
+		// and w0, w1, #1 with N bit set
+		name:   "reserved_logical_immediate",
+		bytes:  []byte{0x20, 0x00, 0x40, 0x12},
+		hasErr: true,
+	}, {
+		// movz w0, #1, lsl #32
+		name:   "reserved_move_wide",
+		bytes:  []byte{0x20, 0x00, 0xc0, 0x52},
+		hasErr: true,
+	}}
+
+	p := NewParser()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := p.Parse(testAddr, tt.bytes)
+			if tt.hasErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, instructionLen, int(ins.ByteLen))
+			require.NoError(t, ins.Validate())
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package aarch64
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+)
+
+const (
+	// regBits is number of bits used to represent a register number.
+	regBits uint8 = 5
+
+	// regCnt is number of register numbers in A64 instruction set.
+	regCnt = 1 << regBits
+
+	// regFP is number of the frame pointer register (x29).
+	regFP regNum = 29
+	// regLR is number of the link register (x30).
+	regLR regNum = 30
+	// reg31 is number of register which refers either the zero register or
+	// the stack pointer based on an instruction and an operand.
+	reg31 regNum = regCnt - 1
+)
+
+// Keys of NZCV condition flags. Every flag is a single byte register which
+// holds value 1 if the flag is set and value 0 otherwise.
+const (
+	// NKey identifies the negative flag.
+	NKey = expr.Key("n")
+	// ZKey identifies the zero flag.
+	ZKey = expr.Key("z")
+	// CKey identifies the carry flag.
+	CKey = expr.Key("c")
+	// VKey identifies the overflow flag.
+	VKey = expr.Key("v")
+)
+
+// flagWidth is width of registers of NZCV condition flags.
+const flagWidth = expr.Width8
+
+// spKey identifies the stack pointer register.
+const spKey = expr.Key("sp")
+
+// regNum represents an A64 register number. Range of valid values is [0..31]
+// (i.e. [0..regCnt-1]).
+type regNum uint8
+
+// reg31Kind describes meaning of register number 31 in an operand of an
+// instruction.
+type reg31Kind bool
+
+const (
+	// zeroReg means that register number 31 refers the zero register.
+	zeroReg reg31Kind = false
+	// stackReg means that register number 31 refers the stack pointer.
+	stackReg reg31Kind = true
+)
+
+// key returns key of register r where register number 31 has meaning k. It
+// returns false if r is the zero register.
+//
+// Registers are identified by names of their 64 bit views. Writes to 32 bit
+// views of registers clear the upper half of the register, so there is no
+// reason to distinguish the views.
+func (r regNum) key(k reg31Kind) (expr.Key, bool) {
+	switch {
+	case r != reg31:
+		return expr.Key(fmt.Sprintf("x%d", r)), true
+	case k == stackReg:
+		return spKey, true
+	default:
+		return "", false
+	}
+}
+
+// name returns name of view of width w of register r in assembler code.
+// Register number 31 has meaning k. Names defined by the calling convention
+// (fp and lr) are used for 64 bit views if abi is set.
+func (r regNum) name(w expr.Width, k reg31Kind, abi bool) string {
+	prefix := "x"
+	if w != width64 {
+		prefix = "w"
+	}
+
+	switch {
+	case r == reg31 && k == stackReg && w == width64:
+		return "sp"
+	case r == reg31 && k == stackReg:
+		return "wsp"
+	case r == reg31:
+		return prefix + "zr"
+	case abi && w == width64 && r == regFP:
+		return "fp"
+	case abi && w == width64 && r == regLR:
+		return "lr"
+	default:
+		return fmt.Sprintf("%s%d", prefix, r)
+	}
+}
+
+// regLoad loads w bytes of register r where register number 31 has meaning k.
+func regLoad(r regNum, w expr.Width, k reg31Kind) expr.Expr {
+	key, ok := r.key(k)
+	if !ok {
+		return expr.Zero
+	}
+	return expr.NewRegLoad(key, w)
+}
+
+// regStore stores e to register r where register number 31 has meaning k. The
+// whole register is written, so value narrower than the register is zero
+// extended. Writes to the zero register have no effect, so nil is returned.
+func regStore(e expr.Expr, r regNum, k reg31Kind) expr.Effect {
+	key, ok := r.key(k)
+	if !ok {
+		return nil
+	}
+	return expr.NewRegStore(e, key, width64)
+}
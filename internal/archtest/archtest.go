@@ -0,0 +1,111 @@
+// Package archtest provides helpers shared by tests of packages implementing
+// instruction set architectures.
+package archtest
+
+import (
+	"fmt"
+	"mltwist/internal/exprtransform"
+	"mltwist/internal/opcode"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type hashableOpcode struct {
+	Bytes string
+	Mask  string
+}
+
+func newHashableOpcode(o opcode.Opcode) hashableOpcode {
+	return hashableOpcode{
+		Bytes: fmt.Sprintf("%x", o.Bytes),
+		Mask:  fmt.Sprintf("%x", o.Mask),
+	}
+}
+
+// UniqueOpcodes asserts that no two instructions of instrs have the same
+// opcode.
+func UniqueOpcodes[T opcode.Opcoder](t testing.TB, instrs []T) {
+	opcodeSet := make(map[hashableOpcode]struct{}, len(instrs))
+	for i, ins := range instrs {
+		h := newHashableOpcode(ins.Opcode())
+		if _, ok := opcodeSet[h]; ok {
+			require.Failf(t, "opcode is not unique", "%d: %s", i, ins.Name())
+		}
+		opcodeSet[h] = struct{}{}
+	}
+}
+
+// Instructions asserts that all instructions of instrs are valid according to
+// validate and that their opcodes are unique.
+func Instructions[T opcode.Opcoder](t testing.TB, instrs []T, validate func(T) error) {
+	for _, ins := range instrs {
+		require.NoError(t, validate(ins), ins.Name())
+	}
+	UniqueOpcodes(t, instrs)
+}
+
+// EvalEffects evaluates effects of instruction ins with registers set to regs
+// and memory set to mem. Registers and bytes of memory not present in regs or
+// mem are zero. It returns values of all registers and bytes of memory the
+// instruction writes.
+//
+// Memory address spaces are not distinguished, so all of them share mem.
+func EvalEffects(
+	t testing.TB,
+	ins model.Instruction,
+	regs map[expr.Key]uint64,
+	mem map[uint64]byte,
+) (map[expr.Key]uint64, map[uint64]byte) {
+	require.NoError(t, ins.Validate())
+
+	eval := func(ex expr.Expr) uint64 {
+		ex = exprtransform.ReplaceAll(ex, func(r expr.RegLoad) (expr.Expr, bool) {
+			c := expr.NewConstUint(regs[r.Key()], expr.Width64)
+			return exprtransform.SetWidth(c, r.Width()), true
+		})
+		ex = exprtransform.ConstFold(ex)
+		ex = exprtransform.ReplaceAll(ex, func(l expr.MemLoad) (expr.Expr, bool) {
+			addr, ok := l.Addr().(expr.Const)
+			require.True(t, ok)
+			a, _ := expr.ConstUint[uint64](addr)
+
+			var v uint64
+			for j := uint64(0); j < uint64(l.Width()); j++ {
+				v |= uint64(mem[a+j]) << (8 * j)
+			}
+			return expr.NewConstUint(v, l.Width()), true
+		})
+
+		c, ok := exprtransform.ConstFold(ex).(expr.Const)
+		require.True(t, ok)
+		v, _ := expr.ConstUint[uint64](c)
+		return v
+	}
+
+	resRegs, resMem := map[expr.Key]uint64{}, map[uint64]byte{}
+	for _, ef := range ins.Effects {
+		switch e := ef.(type) {
+		case expr.RegStore:
+			v := eval(exprtransform.SetWidth(e.Value(), e.Width()))
+			resRegs[e.Key()] = v & widthMask(e.Width())
+		case expr.MemStore:
+			a, v := eval(e.Addr()), eval(e.Value())
+			for j := uint64(0); j < uint64(e.Width()); j++ {
+				resMem[a+j] = byte(v >> (8 * j))
+			}
+		}
+	}
+
+	return resRegs, resMem
+}
+
+// widthMask returns mask of all bits of a value of width w.
+func widthMask(w expr.Width) uint64 {
+	if w >= expr.Width64 {
+		return ^uint64(0)
+	}
+	return uint64(1)<<w.Bits() - 1
+}
@@ -8,6 +8,7 @@ import (
 	"mltwist/internal/consoleui/internal/linereader"
 	"mltwist/internal/consoleui/internal/lines"
 	"regexp"
+	"strconv"
 )
 
 func commands(m *mode) []consoleui.Command {
@@ -125,13 +126,26 @@ func commands(m *mode) []consoleui.Command {
 		},
 	}, {
 		Keys: []string{"goto", "g"},
-		Help: "Go to line number <N>.",
+		Help: "Go to line number <N> or to address of symbol <SYMBOL>.",
 		Args: []consoleui.ArgParseFunc{
-			cmdtools.ParseNum(0, math.MaxInt),
+			cmdtools.ParseString,
 		},
 		Action: func(_ *consoleui.UI, args ...interface{}) error {
-			n := args[0].(int)
-			if l := m.view.Lines.Len(); n > l {
+			arg := args[0].(string)
+
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				s, ok := m.syms.Lookup(arg)
+				if !ok {
+					return fmt.Errorf("unknown symbol: %q", arg)
+				}
+
+				return m.gotoAddr(s.Addr)
+			}
+
+			if n < 0 {
+				return fmt.Errorf("negative line number: %d", n)
+			} else if l := m.view.Lines.Len(); n > l {
 				return fmt.Errorf("line number too big: %d > %d", n, l)
 			}
 
@@ -145,19 +159,33 @@ func commands(m *mode) []consoleui.Command {
 		Keys: []string{"entrypoint", "entry"},
 		Help: "Sets cursor to app entrypoint.",
 		Action: func(_ *consoleui.UI, args ...interface{}) error {
-			a := m.code.Entrypoint()
-			block, ok := m.code.Address(a)
-			if !ok {
-				return fmt.Errorf("cannot find block at address 0x%x", a)
-			}
-
-			ins, ok := block.Address(a)
-			if !ok {
-				return fmt.Errorf("cannot find instruction at address 0x%x", a)
-			}
-
-			line := m.view.Lines.Line(block, ins.Idx())
-			m.view.Cursor.Set(line)
+			return m.gotoAddr(m.code.Entrypoint())
+		},
+	}, {
+		Keys: []string{"source", "src"},
+		Help: "Toggle display of source file and line of instructions.",
+		Action: func(_ *consoleui.UI, args ...interface{}) error {
+			m.view.Lines.ShowSources(!m.view.Lines.SourcesShown())
+			return nil
+		},
+	}, {
+		Keys: []string{"abi"},
+		Help: "Toggle ABI names of registers (a0, sp, ra, ...) in " +
+			"instructions.",
+		Action: func(_ *consoleui.UI, args ...interface{}) error {
+			s := m.view.Lines.Syntax()
+			s.ABINames = !s.ABINames
+			m.view.Lines.SetSyntax(s)
+			return nil
+		},
+	}, {
+		Keys: []string{"aliases", "alias"},
+		Help: "Toggle pseudo-instructions (li, mv, nop, ret, j, call, " +
+			"beqz, la) in place of instructions implementing them.",
+		Action: func(_ *consoleui.UI, args ...interface{}) error {
+			s := m.view.Lines.Syntax()
+			s.Aliases = !s.Aliases
+			m.view.Lines.SetSyntax(s)
 			return nil
 		},
 	}, {
@@ -202,6 +230,74 @@ func commands(m *mode) []consoleui.Command {
 
 			return ui.AddMode("emulate", emul)
 		},
+	}, {
+		Keys: []string{"patch", "p"},
+		Help: "Replace instruction at current line by instruction " +
+			"<INSTR> written in assembler code.\n\n" +
+			"The new instruction must have the same length as the " +
+			"replaced one. Assemblers might not produce all " +
+			"instruction lengths: RISC-V compressed (2 byte) " +
+			"instructions are never assembled, so they cannot be " +
+			"patched.",
+		Args: []consoleui.ArgParseFunc{
+			cmdtools.ParseString,
+		},
+		OptionalArgs: cmdtools.JoinOptStrings,
+		Action: func(_ *consoleui.UI, args ...interface{}) error {
+			s := args[0].(string)
+			if len(args) > 1 {
+				s = s + " " + args[1].(string)
+			}
+
+			l := m.view.Cursor.Value()
+			m.view.Lines.UnmarkAll()
+
+			block, ok := m.view.Lines.Block(l)
+			if !ok {
+				return fmt.Errorf("line %d belongs to no block", l)
+			}
+
+			insIdx, ok := m.view.Lines.Index(l).Instruction()
+			if !ok {
+				return fmt.Errorf("line %d is not instruction line", l)
+			}
+
+			old := block.Index(insIdx)
+			ins, err := m.patchFunc(old.Begin(), s)
+			if err != nil {
+				m.view.Lines.SetMark(l, lines.MarkErr)
+				return fmt.Errorf("cannot assemble %q: %w", s, err)
+			}
+			if ins.Len() != old.Len() {
+				m.view.Lines.SetMark(l, lines.MarkErr)
+				return fmt.Errorf("%q assembled into %d bytes, but the "+
+					"replaced instruction is %d bytes long (assemblers "+
+					"don't produce all lengths, e.g. RISC-V compressed "+
+					"instructions)", s, ins.Len(), old.Len())
+			}
+
+			if err := m.code.Replace(block.Idx(), insIdx, ins); err != nil {
+				m.view.Lines.SetMark(l, lines.MarkErr)
+				return fmt.Errorf("cannot replace instruction: %w", err)
+			}
+
+			m.view.Lines.Reload(block.Idx())
+			return nil
+		},
+	}, {
+		Keys: []string{"write", "w"},
+		Help: "Write current machine code into an executable <FILE>.",
+		Args: []consoleui.ArgParseFunc{
+			cmdtools.ParseString,
+		},
+		Action: func(_ *consoleui.UI, args ...interface{}) error {
+			filename := args[0].(string)
+			if err := m.writeFunc(m.code, filename); err != nil {
+				return fmt.Errorf("cannot write file %q: %w", filename, err)
+			}
+
+			return linereader.ErrMsgf("Code written to %q.\n", filename)
+		},
 	},
 	}
 }
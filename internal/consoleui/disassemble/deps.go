@@ -3,9 +3,18 @@ package disassemble
 import (
 	"mltwist/internal/consoleui"
 	"mltwist/internal/deps"
+	"mltwist/internal/parser"
 	"mltwist/pkg/model"
 )
 
 // EmulFunc is a function creating program emulation from current value of a
 // program and current value of an instruction pointer.
 type EmulFunc func(p *deps.Code, ip model.Addr) (consoleui.Mode, error)
+
+// WriteFunc is a function storing current value of a program into a file
+// called filename.
+type WriteFunc func(p *deps.Code, filename string) error
+
+// PatchFunc is a function assembling an instruction written in assembler code s
+// which is going to be placed at address addr.
+type PatchFunc func(addr model.Addr, s string) (parser.Instruction, error)
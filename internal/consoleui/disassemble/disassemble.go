@@ -1,30 +1,66 @@
 package disassemble
 
 import (
+	"fmt"
 	"mltwist/internal/consoleui"
 	"mltwist/internal/consoleui/internal/lines"
 	"mltwist/internal/consoleui/internal/view"
 	"mltwist/internal/deps"
+	"mltwist/internal/elf"
+	"mltwist/internal/parser"
+	"mltwist/pkg/model"
 )
 
 var _ consoleui.Mode = &mode{}
 
 type mode struct {
 	code *deps.Code
+	syms *elf.Symbols
 	view *lines.View
 
-	emulFunc EmulFunc
+	emulFunc  EmulFunc
+	writeFunc WriteFunc
+	patchFunc PatchFunc
 }
 
 // New creates a new disassembler UI mode displaying and manipulating
-// instructions from p.
-func New(code *deps.Code, emulF EmulFunc) consoleui.Mode {
+// instructions from p. Symbols syms are used to name addresses in the code.
+// Instructions are displayed in the default syntax of architecture arch.
+func New(
+	code *deps.Code,
+	syms *elf.Symbols,
+	arch parser.Architecture,
+	emulF EmulFunc,
+	writeF WriteFunc,
+	patchF PatchFunc,
+) consoleui.Mode {
+	view := lines.NewView(code, syms, arch.ByteOrder)
+	view.Lines.SetSyntax(arch.Syntax)
+
 	return &mode{
-		code:     code,
-		view:     lines.NewView(code),
-		emulFunc: emulF,
+		code:      code,
+		syms:      syms,
+		view:      view,
+		emulFunc:  emulF,
+		writeFunc: writeF,
+		patchFunc: patchF,
 	}
 }
 
 func (d *mode) Commands() []consoleui.Command { return commands(d) }
 func (d *mode) View() view.View               { return d.view }
+
+// gotoAddr sets cursor to the instruction at address a.
+func (d *mode) gotoAddr(a model.Addr) error {
+	block, ok := d.code.Address(a)
+	if !ok {
+		return fmt.Errorf("cannot find block at address 0x%x", a)
+	}
+
+	ins, ok := block.Address(a)
+	if !ok {
+		return fmt.Errorf("cannot find instruction at address 0x%x", a)
+	}
+
+	return d.view.Cursor.Set(d.view.Lines.Line(block, ins.Idx()))
+}
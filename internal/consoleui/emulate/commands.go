@@ -65,7 +65,8 @@ func commands(m *mode) []consoleui.Command {
 		},
 	}, {
 		Keys: []string{"regmod", "rmod"},
-		Help: "Modify register of the running application.",
+		Help: "Modify register of the running application. The " +
+			"register is identified either by its name or its key.",
 		Args: []consoleui.ArgParseFunc{cmdtools.ParseString},
 		// Please note that it makes absolutely no sense to change the
 		// register width. The register width is given by instructions
@@ -82,7 +83,7 @@ func commands(m *mode) []consoleui.Command {
 		// final statement that register width cannot be changed
 		// dynamically.
 		Action: func(_ *consoleui.UI, args ...interface{}) error {
-			key := expr.Key(args[0].(string))
+			key := m.regKey(args[0].(string))
 			regs := m.emul.State.Regs
 
 			r, ok := regs.Values()[key]
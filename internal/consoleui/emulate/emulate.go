@@ -6,8 +6,11 @@ import (
 	"mltwist/internal/consoleui/internal/lines"
 	"mltwist/internal/consoleui/internal/view"
 	"mltwist/internal/deps"
+	"mltwist/internal/elf"
 	"mltwist/internal/emulator"
+	"mltwist/internal/parser"
 	"mltwist/internal/state"
+	"mltwist/pkg/expr"
 	"mltwist/pkg/model"
 )
 
@@ -15,20 +18,33 @@ var _ consoleui.Mode = &mode{}
 
 type mode struct {
 	code *deps.Code
+	arch parser.Architecture
 	emul *emulator.Emulator
 
 	lineView *lines.View
 	view     view.View
 }
 
-func New(code *deps.Code, ip model.Addr, stat *state.State) (*mode, error) {
+// New creates a new emulation UI mode emulating code of architecture arch from
+// address ip with initial state stat. Symbols syms are used to name addresses
+// in the code. Instructions and registers are displayed according to display
+// conventions of the architecture.
+func New(
+	code *deps.Code,
+	syms *elf.Symbols,
+	arch parser.Architecture,
+	ip model.Addr,
+	stat *state.State,
+) (*mode, error) {
 	emul := emulator.New(code, ip, &stateProvider{}, stat)
 
-	lineView := lines.NewView(code)
-	regView := newRegView(stat)
+	lineView := lines.NewView(code, syms, arch.ByteOrder)
+	lineView.Lines.SetSyntax(arch.Syntax)
+	regView := newRegView(stat, arch)
 
 	e := &mode{
 		code: code,
+		arch: arch,
 		emul: emul,
 
 		lineView: lineView,
@@ -60,3 +76,16 @@ func (e *mode) refreshCursor() error {
 	e.lineView.Cursor.Set(e.lineView.Lines.Line(block, ins.Idx()))
 	return nil
 }
+
+// regKey returns key of a register called name. Registers are named the same
+// way the register view names them. If no register set is called name, name
+// is understood as a register key.
+func (e *mode) regKey(name string) expr.Key {
+	abi := e.arch.Syntax.ABINames
+	for k := range e.emul.State.Regs.Values() {
+		if e.arch.RegName(k, abi) == name {
+			return k
+		}
+	}
+	return expr.Key(name)
+}
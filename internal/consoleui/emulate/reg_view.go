@@ -3,6 +3,7 @@ package emulate
 import (
 	"fmt"
 	"mltwist/internal/consoleui/internal/view"
+	"mltwist/internal/parser"
 	"mltwist/internal/state"
 	"mltwist/pkg/expr"
 	"sort"
@@ -18,11 +19,13 @@ var _ view.View = &regView{}
 
 type regView struct {
 	state *state.State
+	arch  parser.Architecture
 }
 
-func newRegView(state *state.State) *regView {
+func newRegView(state *state.State, arch parser.Architecture) *regView {
 	return &regView{
 		state: state,
+		arch:  arch,
 	}
 }
 
@@ -58,11 +61,19 @@ func (v *regView) printLine(line []expr.Key) error {
 	for i, k := range line {
 		val := v.state.Regs.Values()[k].(expr.Const)
 
-		bs := make([]byte, val.Width())
+		// The instruction pointer is stored as an address, so it's
+		// wider than the instruction pointer of the architecture.
+		w := val.Width()
+		if k == expr.IPKey && v.arch.IPWidth != 0 && v.arch.IPWidth < w {
+			w = v.arch.IPWidth
+		}
+
+		bs := make([]byte, w)
 		copy(bs, val.Bytes())
 		revertBytes(bs)
 
-		regs[i] = fmt.Sprintf("%s: 0x%x", k, bs)
+		name := v.arch.RegName(k, v.arch.Syntax.ABINames)
+		regs[i] = fmt.Sprintf("%s: 0x%x", name, bs)
 	}
 
 	var maxWidth int
@@ -1,14 +1,32 @@
 package lines
 
 import (
+	"encoding/binary"
 	"fmt"
 	"mltwist/internal/deps"
+	"mltwist/internal/elf"
+	"mltwist/internal/parser"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"path"
 	"strings"
 )
 
 // instrMaxLen is maximal expected length of instruction textual representation.
 const instrMaxLen = 24
 
+// lineOpts configures how lines describe blocks and instructions.
+type lineOpts struct {
+	// syms are used to name addresses in the code.
+	syms *elf.Symbols
+	// sources enables a column with source code position of instructions.
+	sources bool
+	// syntax is assembler syntax instructions are written in.
+	syntax model.Syntax
+	// order is byte order data words are written in.
+	order binary.ByteOrder
+}
+
 // instrLineFormat is cached format string to produce an instruction line value.
 var instrLineFormat = fmt.Sprintf("%4s %%-%ds | %%s", "", instrMaxLen)
 
@@ -16,6 +34,8 @@ var instrLineFormat = fmt.Sprintf("%4s %%-%ds | %%s", "", instrMaxLen)
 type Line struct {
 	value string
 	mark  Mark
+	// defaultMark is the mark of the line when no other mark is set.
+	defaultMark Mark
 
 	// block is number of basic block in the model this line refers to.
 	// Negative value of block means that the line doesn't belong to any
@@ -34,16 +54,53 @@ func newEmptyLine() Line {
 	}
 }
 
-func newBlockLine(b deps.Block) Line {
+func newBlockLine(b deps.Block, opts lineOpts) Line {
+	// We number blocks from 1 as Block zero doesn't look good to humans.
+	value := fmt.Sprintf("Block %d: 0x%x", b.Idx()+1, b.Begin())
+	if s, ok := opts.syms.At(b.Begin()); ok {
+		value += fmt.Sprintf(" <%s>:", s.Name)
+	}
+
 	return Line{
-		// We number blocks from 1 as Block zero doesn't look good to
-		// humans.
-		value: fmt.Sprintf("Block %d: 0x%x", b.Idx()+1, b.Begin()),
+		value: value,
 		block: b.Idx(),
 		instr: -1,
 	}
 }
 
+// symbolOffset describes address a relative to the symbol a belongs to in
+// form of name+offset. If a doesn't belong to any symbol, this function
+// returns false.
+func symbolOffset(syms *elf.Symbols, a model.Addr) (string, bool) {
+	s, ok := syms.Containing(a)
+	if !ok {
+		return "", false
+	}
+
+	if off := a - s.Addr; off != 0 {
+		return fmt.Sprintf("%s+0x%x", s.Name, off), true
+	}
+	return s.Name, true
+}
+
+// jumpTargets describes all constant jump targets of ins using symbols.
+func jumpTargets(ins deps.Instruction, syms *elf.Symbols) []string {
+	var targets []string
+	for _, j := range ins.Jumps() {
+		c, ok := j.(expr.Const)
+		if !ok {
+			continue
+		}
+
+		a, _ := expr.ConstUint[model.Addr](c)
+		if s, ok := symbolOffset(syms, a); ok {
+			targets = append(targets, fmt.Sprintf("<%s>", s))
+		}
+	}
+
+	return targets
+}
+
 func byteStr(bs []byte) string {
 	var sb strings.Builder
 	sb.Grow(3*len(bs) - 1)
@@ -58,12 +115,71 @@ func byteStr(bs []byte) string {
 	return sb.String()
 }
 
-func newInstrLine(b deps.Block, ins deps.Instruction) Line {
+func newInstrLine(b deps.Block, ins deps.Instruction, opts lineOpts) Line {
+	str := b.Format(ins.Idx(), opts.syntax)
+	value := fmt.Sprintf(instrLineFormat, str, byteStr(ins.Bytes()))
+	if targets := jumpTargets(ins, opts.syms); len(targets) > 0 {
+		value += " " + strings.Join(targets, " ")
+	}
+	if src := ins.Source(); opts.sources && src.Known() {
+		value += fmt.Sprintf(" ; %s:%d", path.Base(src.File), src.Line)
+	}
+
+	var mark Mark
+	if ins.Type().Data() {
+		mark = MarkData
+	}
+
 	return Line{
-		value: fmt.Sprintf(instrLineFormat, ins.String(), byteStr(ins.Bytes())),
-		block: b.Idx(),
-		instr: ins.Idx(),
+		value:       value,
+		mark:        mark,
+		defaultMark: mark,
+		block:       b.Idx(),
+		instr:       ins.Idx(),
+	}
+}
+
+// dataWordLen is number of bytes displayed as a single data word.
+const dataWordLen = 4
+
+func newDataHeaderLine(d parser.Data, syms *elf.Symbols) Line {
+	value := fmt.Sprintf("Data: 0x%x", d.Begin())
+	if s, ok := syms.At(d.Begin()); ok {
+		value += fmt.Sprintf(" <%s>:", s.Name)
+	}
+
+	l := newEmptyLine()
+	l.value = value
+	return l
+}
+
+// dataToLines represents data region d as a header line followed by a single
+// line per data word. Bytes behind the last whole word are displayed
+// individually.
+func dataToLines(d parser.Data, opts lineOpts) []Line {
+	n := (len(d.Bytes) + dataWordLen - 1) / dataWordLen
+	lines := make([]Line, 1, n+1)
+	lines[0] = newDataHeaderLine(d, opts.syms)
+
+	for off := 0; off < len(d.Bytes); {
+		bs := d.Bytes[off:]
+		if len(bs) >= dataWordLen {
+			bs = bs[:dataWordLen]
+		} else {
+			bs = bs[:1]
+		}
+
+		l := newEmptyLine()
+		l.value = fmt.Sprintf(instrLineFormat,
+			parser.DataString(bs, opts.order), byteStr(bs))
+		l.defaultMark = MarkData
+		l.mark = MarkData
+		lines = append(lines, l)
+
+		off += len(bs)
 	}
+
+	return lines
 }
 
 func (l *Line) setMark(m Mark) {
@@ -74,6 +190,9 @@ func (l *Line) setMark(m Mark) {
 	l.mark = m
 }
 
+// unmark resets the mark of l to its default mark.
+func (l *Line) unmark() { l.mark = l.defaultMark }
+
 func (l Line) String() string           { return l.value }
 func (l Line) Mark() Mark               { return l.mark }
 func (l Line) Block() (int, bool)       { return l.block, l.block >= 0 }
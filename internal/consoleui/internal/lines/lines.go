@@ -1,49 +1,75 @@
 package lines
 
 import (
+	"encoding/binary"
 	"fmt"
 	"mltwist/internal/deps"
+	"mltwist/internal/elf"
+	"mltwist/pkg/model"
 )
 
 type Lines struct {
 	lines []Line
 	code  *deps.Code
+	opts  lineOpts
 
 	blockStarts []int
 	marks       map[int]struct{}
 }
 
-func newLines(code *deps.Code) *Lines {
-	// Each block will have a header and will be delimited by a blank line.
-	// Each instruction will be a single line. In the end, there will be a
-	// single empty line.
-	lns := make([]Line, 0, 2*code.Len()+code.NumInstr()+1)
+func newLines(
+	code *deps.Code,
+	syms *elf.Symbols,
+	order binary.ByteOrder,
+) *Lines {
+	opts := lineOpts{syms: syms, order: order}
+	data := code.Data()
+
+	// Each block and each data region will have a header and will be
+	// delimited by a blank line. Each instruction will be a single line. In
+	// the end, there will be a single empty line.
+	lns := make([]Line, 0, 2*(code.Len()+len(data))+code.NumInstr()+1)
 	blockStarts := make([]int, code.Len())
+
+	// Data regions are placed in front of the first block following them
+	// in memory.
+	appendData := func(until model.Addr) {
+		for ; len(data) > 0 && data[0].Begin() < until; data = data[1:] {
+			if len(lns) != 0 {
+				lns = append(lns, newEmptyLine())
+			}
+			lns = append(lns, dataToLines(data[0], opts)...)
+		}
+	}
+
 	for i, b := range code.Blocks() {
-		if i != 0 {
+		appendData(b.Begin())
+		if len(lns) != 0 {
 			lns = append(lns, newEmptyLine())
 		}
 
 		blockStarts[i] = len(lns)
-		lns = append(lns, blockToLines(b)...)
+		lns = append(lns, blockToLines(b, opts)...)
 	}
+	appendData(model.MaxAddress)
 
 	lns = append(lns, newEmptyLine())
 
 	return &Lines{
 		lines:       lns,
 		code:        code,
+		opts:        opts,
 		blockStarts: blockStarts,
 		marks:       make(map[int]struct{}, 2),
 	}
 }
 
-func blockToLines(b deps.Block) []Line {
+func blockToLines(b deps.Block, opts lineOpts) []Line {
 	lines := make([]Line, 1, b.Num()+1)
-	lines[0] = newBlockLine(b)
+	lines[0] = newBlockLine(b, opts)
 
 	for _, ins := range b.Instructions() {
-		lines = append(lines, newInstrLine(b, ins))
+		lines = append(lines, newInstrLine(b, ins, opts))
 	}
 
 	return lines
@@ -59,18 +85,37 @@ func (l *Lines) SetMark(lineIdx int, m Mark) {
 
 func (l *Lines) UnmarkAll() {
 	for i := range l.marks {
-		l.lines[i].setMark(MarkNone)
+		l.lines[i].unmark()
 		delete(l.marks, i)
 	}
 }
 
 func (l *Lines) Reload(blockIdx int) {
-	newBlock := blockToLines(l.code.Index(blockIdx))
+	newBlock := blockToLines(l.code.Index(blockIdx), l.opts)
 	lines := l.lines[l.blockStarts[blockIdx]:]
 	lines = lines[:len(newBlock)]
 	copy(lines, newBlock)
 }
 
+// ShowSources enables or disables the column with source code position of
+// instructions.
+func (l *Lines) ShowSources(show bool) {
+	l.opts.sources = show
+	l.reloadRange(0, l.code.Len()-1)
+}
+
+// SourcesShown indicates that the column with source code positions is shown.
+func (l *Lines) SourcesShown() bool { return l.opts.sources }
+
+// SetSyntax sets assembler syntax instructions are written in.
+func (l *Lines) SetSyntax(s model.Syntax) {
+	l.opts.syntax = s
+	l.reloadRange(0, l.code.Len()-1)
+}
+
+// Syntax returns assembler syntax instructions are written in.
+func (l *Lines) Syntax() model.Syntax { return l.opts.syntax }
+
 func (l *Lines) reloadRange(from int, to int) {
 	if from > to {
 		from, to = to, from
@@ -24,4 +24,7 @@ const (
 	MarkErrMovedTo   Mark = "!>"
 
 	MarkErr Mark = "!"
+
+	// MarkData marks lines which contain data instead of instructions.
+	MarkData Mark = "dat"
 )
@@ -1,10 +1,12 @@
 package lines
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math"
 	"mltwist/internal/consoleui/internal/cursor"
 	"mltwist/internal/deps"
+	"mltwist/internal/elf"
 )
 
 type View struct {
@@ -14,8 +16,10 @@ type View struct {
 	format string
 }
 
-func NewView(p *deps.Code) *View {
-	lns := newLines(p)
+// NewView creates a view of code p. Symbols syms are used to name addresses in
+// the code and data words are written in byte order order.
+func NewView(p *deps.Code, syms *elf.Symbols, order binary.ByteOrder) *View {
+	lns := newLines(p, syms, order)
 
 	idFormat := fmt.Sprintf("%%%dd", numDigits(lns.Len(), 10))
 	markFormat := fmt.Sprintf("%%%ds", MaxMarkLen)
@@ -2,6 +2,7 @@ package deps
 
 import (
 	"fmt"
+	"mltwist/internal/parser"
 	"mltwist/pkg/model"
 	"sort"
 )
@@ -29,11 +30,7 @@ func newBlock(idx int, seq []*instruction) *block {
 		ins.setIndex(i)
 	}
 
-	findTrueDeps(seq)
-	findAntiDeps(seq)
-	findOutputDeps(seq)
-	findControlDeps(seq)
-	findSpecialDeps(seq)
+	findDeps(seq)
 
 	return &block{
 		begin: seq[0].Begin(),
@@ -44,6 +41,15 @@ func newBlock(idx int, seq []*instruction) *block {
 	}
 }
 
+// findDeps analyzes all dependencies in between instructions in seq.
+func findDeps(seq []*instruction) {
+	findTrueDeps(seq)
+	findAntiDeps(seq)
+	findOutputDeps(seq)
+	findControlDeps(seq)
+	findSpecialDeps(seq)
+}
+
 // Begin returns starting in-memory address of the block. The address relates to
 // the original address space of a binary.
 func (b *block) Begin() model.Addr { return b.begin }
@@ -55,6 +61,16 @@ func (b *block) Len() model.Addr { return b.end - b.begin }
 // relates to the original address space of a binary.
 func (b *block) End() model.Addr { return b.end }
 
+// Bytes returns machine code of all instructions of the block in their current
+// order.
+func (b *block) Bytes() []byte {
+	bytes := make([]byte, 0, b.Len())
+	for _, ins := range b.seq {
+		bytes = append(bytes, ins.Bytes()...)
+	}
+	return bytes
+}
+
 // Num returns number of instructions in b.
 func (b *block) Num() int { return len(b.seq) }
 
@@ -72,20 +88,26 @@ func (b *block) index(i int) *instruction { return b.seq[i] }
 // instruction dependency constraints or if either from or to are not valid
 // indices of an instruction in the block.
 //
-// Move of an instruction correctly changes its address, but instruction bytes
-// are left untouched. This might result in a state when bytes or relative jumps
-// (jumps to an instruction address plus some offset) contain different jump
-// target than the real instruction.
+// Move of an instruction correctly changes its address. If platform details of
+// an instruction implement model.Relocator, bytes of every instruction which
+// changed its address are re-encoded to preserve instruction semantics at the
+// new address. This allows PC-relative instructions (jumps to an instruction
+// address plus some offset, address computations relative to an instruction
+// address etc.) to refer the same address as before the move. If an instruction
+// cannot be re-encoded at its new address - for example because the new offset
+// doesn't fit into the instruction opcode - the move fails and the block
+// remains unchanged. Bytes of instructions without model.Relocator support are
+// left untouched. This might result in a state when bytes of relative jumps
+// contain different jump target than the real instruction.
 //
 // Unfortunately it's not possible to modify instruction bytes to be sure that
 // the instruction binary encoding is always valid even after the move
-// operation. To do so, we'd need a platform-dependent module which would encode
-// the change into instruction bytes. But even if we had such a module, the
-// module might not be able to assemble a valid instruction opcode for the
-// target architecture. In a typical CPU architecture a relative jump can jump
-// only in some small area around the instruction (typically a few MB). For
-// longer jumps, the CPU architecture typically requires to load the value into
-// a register and jump to an address in register. Consequently, a move of a
+// operation. Even a platform-dependent module which encodes the change into
+// instruction bytes might not be able to assemble a valid instruction opcode
+// for the target architecture. In a typical CPU architecture a relative jump
+// can jump only in some small area around the instruction (typically a few MB).
+// For longer jumps, the CPU architecture typically requires to load the value
+// into a register and jump to an address in register. Consequently, a move of a
 // single instruction might result in expansion of a single instruction into
 // multiple. Moreover, remember that we move whole range of [begin, end] or
 // [end, begin] respectively. So in the worst case there can be multiple
@@ -135,15 +157,136 @@ func (b *block) index(i int) *instruction { return b.seq[i] }
 // blocks and expansion of L instructions, the users ability to validate
 // correctness of such a step is very limited.
 //
-// For all the reasons described above, move of an instruction doesn't change
-// its bytes even for the cost of making the instruction opcode incorrect in the
-// modified program code.
+// For all the reasons described above, move of an instruction never expands an
+// instruction into multiple ones nor changes length of any instruction. An
+// instruction which cannot be re-encoded in place makes the move fail.
 func (b *block) Move(from int, to int) error {
 	if err := b.checkMove(from, to); err != nil {
 		return fmt.Errorf("cannot move %d to %d: %w", from, to, err)
 	}
 
 	move(b.seq, from, to)
+	if err := b.relocate(from, to); err != nil {
+		move(b.seq, to, from)
+		return fmt.Errorf("cannot move %d to %d: %w", from, to, err)
+	}
+
+	return nil
+}
+
+// relocate re-encodes all instructions in between indices from and to
+// (inclusive) to match their current addresses. Instructions are modified only
+// if all of them can be relocated. Otherwise an error is returned and no
+// instruction is modified.
+func (b *block) relocate(from int, to int) error {
+	if from > to {
+		from, to = to, from
+	}
+
+	type relocation struct {
+		details model.PlatformDetails
+		bytes   []byte
+	}
+
+	relocs := make([]relocation, to-from+1)
+	for i, ins := range b.seq[from : to+1] {
+		r, ok := ins.details.(model.Relocator)
+		if !ok {
+			continue
+		}
+
+		details, bytes, err := r.Relocate(ins.Begin())
+		if err != nil {
+			return fmt.Errorf("cannot relocate instruction at 0x%x: %w",
+				ins.OrigAddr(), err)
+		} else if l := model.Addr(len(bytes)); l != ins.Len() {
+			return fmt.Errorf("bug: relocated instruction length differs: "+
+				"%d != %d", l, ins.Len())
+		}
+
+		relocs[i] = relocation{details: details, bytes: bytes}
+	}
+
+	for i, ins := range b.seq[from : to+1] {
+		if r := relocs[i]; r.details != nil {
+			ins.details, ins.bytes = r.details, r.bytes
+		}
+	}
+
+	return nil
+}
+
+// replace replaces instruction at index i by instruction ins and analyzes
+// dependencies in between all instructions of the block again.
+//
+// The new instruction has to be placed at the current address of the replaced
+// instruction and it has to have the same length. This guarantees that
+// addresses of no other instruction in the program change. A control flow
+// instruction (jump) can replace only the last instruction of the block as no
+// jump is allowed in the middle of a basic block. A delayed jump can replace
+// only the instruction followed by the last one which becomes its delay slot.
+//
+// This method doesn't check jump targets of the new instruction as the block
+// doesn't know other blocks of the program. See Replace method of Code.
+//
+// If source code position of ins is unknown, the new instruction inherits the
+// position of the replaced instruction.
+func (b *block) replace(i int, ins parser.Instruction) error {
+	if i < 0 || i >= len(b.seq) {
+		return fmt.Errorf("index out of range: %d", i)
+	}
+
+	old := b.seq[i]
+	if a := ins.Begin(); a != old.Begin() {
+		return fmt.Errorf("address of the new instruction doesn't match: "+
+			"0x%x != 0x%x", a, old.Begin())
+	}
+	if l := ins.Len(); l != old.Len() {
+		return fmt.Errorf("length of the new instruction doesn't match: "+
+			"%d != %d", l, old.Len())
+	}
+
+	newIns := newInstruction(ins)
+	if len(newIns.jumpTargets) > 0 {
+		if err := b.checkJump(i, newIns.delayed); err != nil {
+			return err
+		}
+	}
+
+	if !newIns.source.Known() {
+		newIns.source = old.source
+	}
+
+	newIns.setIndex(i)
+	b.seq[i] = newIns
+
+	for _, ins := range b.seq {
+		ins.depsFwd = make(insSet, len(ins.depsFwd))
+		ins.depsBack = make(insSet, len(ins.depsBack))
+	}
+	findDeps(b.seq)
+
+	return nil
+}
+
+// checkJump asserts that a jump can be placed at index i of the block. The jump
+// is delayed if delayed is set.
+func (b *block) checkJump(i int, delayed bool) error {
+	last := len(b.seq) - 1
+	if delayed && i != last-1 {
+		return fmt.Errorf("delayed jump can be only followed by " +
+			"the last instruction of a block")
+	} else if !delayed && i != last {
+		return fmt.Errorf("jump can be only the last instruction of a block")
+	}
+
+	if slot := b.seq[last]; delayed && len(slot.jumpTargets) > 0 {
+		return fmt.Errorf("delay slot cannot contain a jump")
+	} else if prev := i - 1; !delayed && prev >= 0 &&
+		b.seq[prev].delayed && len(b.seq[prev].jumpTargets) > 0 {
+		return fmt.Errorf("jump cannot be placed in a delay slot")
+	}
+
 	return nil
 }
 
@@ -2,6 +2,7 @@ package deps
 
 import (
 	"fmt"
+	"mltwist/internal/parser"
 	"mltwist/pkg/expr"
 	"mltwist/pkg/model"
 	"strconv"
@@ -501,3 +502,120 @@ func TestBlock_Address(t *testing.T) {
 		})
 	}
 }
+
+// relocDetails is platform details of an instruction which can be relocated
+// only to addresses lower than limit. Bytes of relocated instruction contain
+// the relocation address.
+type relocDetails struct {
+	limit model.Addr
+}
+
+func (relocDetails) Name() string   { return "reloc" }
+func (relocDetails) String() string { return "reloc" }
+
+func (d relocDetails) Relocate(
+	a model.Addr,
+) (model.PlatformDetails, []byte, error) {
+	if a >= d.limit {
+		return nil, nil, fmt.Errorf("address too high: 0x%x", a)
+	}
+	return d, []byte{byte(a)}, nil
+}
+
+func TestBlock_Move_Relocate(t *testing.T) {
+	r := require.New(t)
+
+	seq := make([]*instruction, 4)
+	for i := range seq {
+		seq[i] = &instruction{
+			origAddr: model.Addr(i),
+			currAddr: model.Addr(i),
+			bytes:    []byte{byte(i)},
+			blockIdx: i,
+			details:  relocDetails{limit: 3},
+			depsFwd:  make(insSet),
+			depsBack: make(insSet),
+		}
+	}
+	seq[3].details = nil
+
+	b := &block{seq: seq}
+	r.NoError(b.Move(0, 2))
+	for i, orig := range []model.Addr{1, 2, 0, 3} {
+		ins := b.index(i)
+		r.Equal(orig, ins.OrigAddr())
+		r.Equal(model.Addr(i), ins.Begin())
+		r.Equal([]byte{byte(i)}, ins.Bytes())
+	}
+
+	// Instruction at index 2 cannot be moved to address 3.
+	r.Error(b.Move(2, 3))
+	for i, orig := range []model.Addr{1, 2, 0, 3} {
+		ins := b.index(i)
+		r.Equal(orig, ins.OrigAddr())
+		r.Equal(model.Addr(i), ins.Begin())
+		r.Equal(i, ins.Idx())
+		r.Equal([]byte{byte(i)}, ins.Bytes())
+	}
+}
+
+func TestBlock_Replace(t *testing.T) {
+	regIns := func(addr model.Addr, out expr.Key, in expr.Key) parser.Instruction {
+		load := expr.NewRegLoad(in, expr.Width64)
+		return parser.Instruction{
+			Addr:    addr,
+			Bytes:   make([]byte, 4),
+			Effects: []expr.Effect{expr.NewRegStore(load, out, expr.Width64)},
+		}
+	}
+
+	r := require.New(t)
+
+	src := parser.SourceLine{File: "main.c", Line: 7}
+	orig := regIns(4, "r3", "r4")
+	orig.Source = src
+
+	seq := []*instruction{
+		newInstruction(regIns(0, "r1", "r2")),
+		newInstruction(orig),
+		newInstruction(regIns(8, "r5", "r1")),
+	}
+	b := newBlock(0, seq)
+	r.Equal(0, b.LowerBound(1))
+	r.Equal(2, b.UpperBound(1))
+
+	r.Error(b.replace(3, regIns(12, "r3", "r1")))
+	r.Error(b.replace(1, regIns(0, "r3", "r1")))
+
+	long := regIns(4, "r3", "r1")
+	long.Bytes = make([]byte, 8)
+	r.Error(b.replace(1, long))
+
+	jump := testInputInsJump(4, 4, 0x100)
+	r.Error(b.replace(1, jump))
+
+	replacement := regIns(4, "r3", "r1")
+	r.NoError(b.replace(1, replacement))
+	r.Equal(1, b.index(1).Idx())
+	r.Equal(replacement.Effects, b.index(1).Effects())
+	r.Equal(src, b.index(1).Source())
+	r.Equal(1, b.LowerBound(1))
+	r.Equal(2, b.UpperBound(1))
+	r.Equal(1, b.LowerBound(2))
+	r.Equal(0, b.UpperBound(0))
+
+	r.NoError(b.replace(1, regIns(4, "r1", "r4")))
+	r.Equal(1, b.LowerBound(1))
+	r.Equal(1, b.UpperBound(1))
+	r.Equal(0, b.UpperBound(0))
+	r.Equal(2, b.LowerBound(2))
+
+	r.Error(b.replace(2, testInputInsDelayedJump(8, 4, 0x100)))
+	r.NoError(b.replace(1, testInputInsDelayedJump(4, 4, 0x100)))
+	// The delay slot stays right behind the jump.
+	r.Equal(0, b.UpperBound(0))
+	r.Equal(1, b.LowerBound(1))
+	r.Equal(1, b.UpperBound(1))
+	r.Equal(2, b.LowerBound(2))
+	r.Error(b.replace(2, testInputInsJump(8, 4, 0x100)))
+}
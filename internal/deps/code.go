@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"mltwist/internal/deps/internal/basicblock"
 	"mltwist/internal/parser"
+	"mltwist/pkg/expr"
 	"mltwist/pkg/model"
 	"sort"
 )
@@ -26,11 +27,24 @@ type Code struct {
 
 	// instrCnt is number of instructions in all basic blocks.
 	instrCnt int
+
+	// data is list of regions in between instructions which don't contain
+	// any code sorted by ascending address.
+	data []parser.Data
 }
 
 // NewCode finds basic blocks in the program and identifies instruction
 // dependencies within basic blocks.
-func NewCode(entrypoint model.Addr, seq []parser.Instruction) (*Code, error) {
+//
+// Machine code of the program doesn't have to be continuous. Regions in
+// between instructions which are known not to contain any code (for example
+// literal pools or jump tables) can be passed in data. Those regions are never
+// modified or moved, but they must not overlap any instruction.
+func NewCode(
+	entrypoint model.Addr,
+	seq []parser.Instruction,
+	data []parser.Data,
+) (*Code, error) {
 	ins := make([]*instruction, len(seq))
 	for i, instruction := range seq {
 		ins[i] = newInstruction(instruction)
@@ -54,13 +68,40 @@ func NewCode(entrypoint model.Addr, seq []parser.Instruction) (*Code, error) {
 	blocksByAddr := make([]*block, len(blocks))
 	copy(blocksByAddr, blocks)
 
-	return &Code{
+	c := &Code{
 		entrypoint:   entrypoint,
 		blocks:       blocks,
 		blocksByAddr: blocksByAddr,
 
 		instrCnt: instrCnt,
-	}, nil
+
+		data: make([]parser.Data, len(data)),
+	}
+
+	copy(c.data, data)
+	sort.Slice(c.data, func(i, j int) bool {
+		return c.data[i].Addr < c.data[j].Addr
+	})
+	for _, d := range c.data {
+		if err := c.checkData(d); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// checkData asserts that data region d doesn't overlap any basic block.
+func (c *Code) checkData(d parser.Data) error {
+	i := sort.Search(len(c.blocksByAddr), func(i int) bool {
+		return c.blocksByAddr[i].end > d.Begin()
+	})
+	if i < len(c.blocksByAddr) && c.blocksByAddr[i].begin < d.End() {
+		return fmt.Errorf("data at address 0x%x overlap block at 0x%x",
+			d.Begin(), c.blocksByAddr[i].begin)
+	}
+
+	return nil
 }
 
 // Entrypoint returns address of program entrypoint.
@@ -88,6 +129,10 @@ func (c *Code) Blocks() []Block {
 	return blocks
 }
 
+// Data returns read-only list of all data regions in between instructions
+// sorted by ascending address.
+func (c *Code) Data() []parser.Data { return c.data }
+
 // NumInstr counts number of instructions in all all basic blocks in the code.
 func (c *Code) NumInstr() int { return c.instrCnt }
 
@@ -107,6 +152,67 @@ func (c *Code) Move(from int, to int) error {
 	return nil
 }
 
+// Replace replaces instruction at index i of block at index blockIdx by
+// instruction ins and analyzes dependencies in between all instructions of the
+// block again.
+//
+// The new instruction has to be placed at the current address of the replaced
+// instruction and it has to have the same length. This guarantees that
+// addresses of no other instruction in the program change. A jump can replace
+// only the last instruction of the block and a delayed jump only the
+// instruction followed by the last one which becomes its delay slot. If source
+// code position of ins is unknown, the new instruction inherits the position of
+// the replaced instruction.
+//
+// Constant jump targets of the new instruction have to be starts of basic
+// blocks in the program. A jump into the middle of a basic block would split
+// the block and later moves of instructions in the block would silently change
+// the instruction the jump lands on. A jump target can't be a delay slot
+// either as the slot would be separated from its jump.
+func (c *Code) Replace(blockIdx int, i int, ins parser.Instruction) error {
+	if blockIdx < 0 || blockIdx >= len(c.blocks) {
+		return fmt.Errorf("block index out of range: %d", blockIdx)
+	}
+
+	for _, j := range jumps(ins) {
+		jc, ok := j.(expr.Const)
+		if !ok {
+			continue
+		}
+
+		addr, _ := expr.ConstUint[model.Addr](jc)
+		if err := c.checkJumpTarget(addr); err != nil {
+			return fmt.Errorf("invalid jump target 0x%x: %w", addr, err)
+		}
+	}
+
+	return c.blocks[blockIdx].replace(i, ins)
+}
+
+// checkJumpTarget asserts that a jump to address a doesn't split any basic
+// block in the program.
+func (c *Code) checkJumpTarget(a model.Addr) error {
+	b, ok := c.Address(a)
+	if !ok {
+		return fmt.Errorf("address doesn't belong to any basic block")
+	}
+	if b.Begin() == a {
+		return nil
+	}
+
+	ins, ok := b.Address(a)
+	if !ok {
+		return fmt.Errorf("address is not at instruction boundary")
+	}
+	if i := ins.Idx(); i > 0 {
+		if prev := b.index(i - 1); prev.delayed && len(prev.jumpTargets) > 0 {
+			return fmt.Errorf("address is a delay slot")
+		}
+	}
+
+	return fmt.Errorf("address is not a start of a basic block")
+}
+
 // Address find a block containing address a. If no such block exists in the
 // code, this method returns zero value of block and false.
 func (c *Code) Address(a model.Addr) (Block, bool) {
@@ -124,3 +230,20 @@ func (c *Code) Address(a model.Addr) (Block, bool) {
 
 	return wrapBlock(b), true
 }
+
+// MachineCode returns current machine code of the program. Every returned block
+// corresponds to a single basic block and its Bytes method returns bytes of all
+// its instructions placed at their current addresses. Blocks are sorted by
+// their ascending begin addresses. Data regions are never modified, so those
+// are not part of the returned code.
+//
+// Bytes of moved instructions are re-encoded only if the platform supports
+// instruction relocation (see Move method of Block). Otherwise the returned
+// code might not behave the same way as the original program.
+func (c *Code) MachineCode() []Block {
+	blocks := make([]Block, len(c.blocksByAddr))
+	for i, b := range c.blocksByAddr {
+		blocks[i] = wrapBlock(b)
+	}
+	return blocks
+}
@@ -28,6 +28,19 @@ func testInputInsJump(
 	}
 }
 
+func testInputInsDelayedJump(
+	addr model.Addr,
+	bytes model.Addr,
+	jumpAddr model.Addr,
+) parser.Instruction {
+	ins := testInputInsJump(addr, bytes)
+	a := expr.NewConstUint(jumpAddr, model.AddrWidth)
+	ins.Effects = []expr.Effect{
+		expr.NewRegStore(a, expr.DelayedIPKey, expr.Width32),
+	}
+	return ins
+}
+
 func TestCode_New(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -60,6 +73,17 @@ func TestCode_New(t *testing.T) {
 			},
 			blocks: []int{3, 4, 2},
 		},
+		{
+			name: "delay_slot",
+			seq: []parser.Instruction{
+				testInputInsJump(128, 4),
+				testInputInsDelayedJump(132, 4, 128),
+				testInputInsJump(136, 4),
+
+				testInputInsJump(140, 4),
+			},
+			blocks: []int{3, 1},
+		},
 	}
 
 	for _, tt := range tests {
@@ -67,7 +91,7 @@ func TestCode_New(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			r := require.New(t)
 
-			p, err := NewCode(tt.seq[0].Addr, tt.seq)
+			p, err := NewCode(tt.seq[0].Addr, tt.seq, nil)
 			r.NoError(err)
 
 			r.Equal(len(tt.blocks), p.Len())
@@ -150,3 +174,142 @@ func TestCode_Move(t *testing.T) {
 		})
 	}
 }
+
+func TestCode_MachineCode(t *testing.T) {
+	ins := func(addr model.Addr, bytes ...byte) parser.Instruction {
+		i := testInputInsJump(addr, model.Addr(len(bytes)))
+		copy(i.Bytes, bytes)
+		return i
+	}
+
+	r := require.New(t)
+	c, err := NewCode(0x100, []parser.Instruction{
+		ins(0x100, 1, 2),
+		ins(0x102, 3, 4, 5, 6),
+		ins(0x106, 7),
+		ins(0x200, 8, 9),
+	}, nil)
+	r.NoError(err)
+	r.Equal(2, c.Len())
+
+	r.NoError(c.Index(0).Move(2, 0))
+	r.NoError(c.Move(1, 0))
+
+	code := c.MachineCode()
+	r.Len(code, 2)
+
+	r.Equal(model.Addr(0x100), code[0].Begin())
+	r.Equal([]byte{7, 1, 2, 3, 4, 5, 6}, code[0].Bytes())
+	r.Equal(model.Addr(0x200), code[1].Begin())
+	r.Equal([]byte{8, 9}, code[1].Bytes())
+}
+
+func TestCode_Data(t *testing.T) {
+	seq := func() []parser.Instruction {
+		return []parser.Instruction{
+			testInputInsJump(0x100, 4),
+			testInputInsJump(0x108, 4),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		data   []parser.Data
+		hasErr bool
+	}{{
+		name: "gap",
+		data: []parser.Data{
+			{Addr: 0x10c, Bytes: []byte{1, 2}},
+			{Addr: 0x104, Bytes: []byte{1, 2, 3, 4}},
+		},
+	}, {
+		name:   "overlap_begin",
+		data:   []parser.Data{{Addr: 0xfe, Bytes: []byte{1, 2, 3}}},
+		hasErr: true,
+	}, {
+		name:   "overlap_end",
+		data:   []parser.Data{{Addr: 0x104, Bytes: []byte{1, 2, 3, 4, 5}}},
+		hasErr: true,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			c, err := NewCode(0x100, seq(), tt.data)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			r.Equal(2, c.Len())
+			r.Len(c.Data(), len(tt.data))
+			for i := 1; i < len(c.Data()); i++ {
+				r.Less(c.Data()[i-1].Addr, c.Data()[i].Addr)
+			}
+		})
+	}
+}
+
+func TestCode_Replace(t *testing.T) {
+	r := require.New(t)
+	c, err := NewCode(0x100, []parser.Instruction{
+		testInputInsJump(0x100, 4),
+		testInputInsJump(0x104, 4),
+		testInputInsJump(0x108, 4, 0x200),
+		testInputInsJump(0x200, 4),
+		testInputInsJump(0x204, 4),
+		testInputInsJump(0x208, 4, 0x100),
+		testInputInsJump(0x300, 4),
+		testInputInsDelayedJump(0x304, 4, 0x100),
+		testInputInsJump(0x308, 4),
+	}, nil)
+	r.NoError(err)
+	r.Equal(3, c.Len())
+
+	tests := []struct {
+		name   string
+		target model.Addr
+		errStr string
+	}{{
+		name:   "mid_block",
+		target: 0x204,
+		errStr: "not a start of a basic block",
+	}, {
+		name:   "delay_slot",
+		target: 0x308,
+		errStr: "delay slot",
+	}, {
+		name:   "middle_of_instruction",
+		target: 0x202,
+		errStr: "instruction boundary",
+	}, {
+		name:   "outside_code",
+		target: 0x500,
+		errStr: "doesn't belong to any basic block",
+	}}
+
+	for _, tt := range tests {
+		err := c.Replace(0, 2, testInputInsJump(0x108, 4, tt.target))
+		r.ErrorContains(err, tt.errStr, tt.name)
+	}
+
+	// Failed replacements keep the original instruction.
+	r.Equal(
+		[]expr.Expr{expr.NewConstUint[model.Addr](0x200, model.AddrWidth)},
+		c.Index(0).Index(2).Jumps(),
+	)
+
+	r.NoError(c.Replace(0, 2, testInputInsJump(0x108, 4, 0x300)))
+	r.NoError(c.Replace(0, 2, testInputInsJump(0x108, 4, 0x100)))
+	r.Error(c.Replace(3, 0, testInputInsJump(0x100, 4)))
+
+	// A block can be patched after a move of its instructions as long as the
+	// jump targets a block start.
+	r.NoError(c.Index(1).Move(1, 0))
+	r.NoError(c.Replace(1, 2, testInputInsJump(0x208, 4, 0x300)))
+	r.ErrorContains(c.Replace(1, 2, testInputInsJump(0x208, 4, 0x204)),
+		"not a start of a basic block")
+}
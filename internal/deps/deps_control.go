@@ -10,7 +10,22 @@ package deps
 // in such a case the jump instruction as to be the last in the block. This
 // implies that every instruction in the basic block is dependent on the jump
 // instruction at the block end.
+//
+// A delayed jump is followed by its delay slot, so the delay slot is the last
+// instruction of the block and the jump precedes it. Every other instruction
+// depends on the jump and the delay slot depends on the jump as well. This
+// keeps the jump and its delay slot together at the end of the block.
 func findControlDeps(instrs []*instruction) {
+	if n := len(instrs); n >= 2 {
+		if jump := instrs[n-2]; jump.delayed && len(jump.jumpTargets) > 0 {
+			for _, ins := range instrs[:n-2] {
+				addDep(ins, jump)
+			}
+			addDep(jump, instrs[n-1])
+			return
+		}
+	}
+
 	last := instrs[len(instrs)-1]
 
 	// If last instruction is not a jump, the basic block ends there simply
@@ -10,6 +10,12 @@ func testInsJumpTarget(exprs ...expr.Expr) *instruction {
 	return testIns(model.TypeNone, exprs)
 }
 
+func testInsDelayedJump(exprs ...expr.Expr) *instruction {
+	ins := testIns(model.TypeNone, exprs)
+	ins.delayed = true
+	return ins
+}
+
 func TestControlDeps(t *testing.T) {
 	tests := []testCase{
 		{
@@ -69,6 +75,32 @@ func TestControlDeps(t *testing.T) {
 				{2, 3},
 			},
 		},
+		{
+			name: "delay_slot_follows_jump",
+			ins: []*instruction{
+				testInsReg(1),
+				testInsReg(2),
+				testInsDelayedJump(expr.ConstFromUint[uint32](56)),
+				testInsReg(3, 2, 1),
+			},
+			deps: []dep{
+				{0, 2},
+				{1, 2},
+				{2, 3},
+			},
+		},
+		{
+			name: "last_is_delayed_jump",
+			ins: []*instruction{
+				testInsReg(1),
+				testInsReg(2),
+				testInsDelayedJump(expr.ConstFromUint[uint32](56)),
+			},
+			deps: []dep{
+				{0, 2},
+				{1, 2},
+			},
+		},
 	}
 
 	runDepsTest(t, tests, findControlDeps)
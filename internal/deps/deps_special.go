@@ -1,5 +1,7 @@
 package deps
 
+import "mltwist/pkg/model"
+
 // isMemAccess checks if ins either loads or stores value from or to memory.
 func isMemAccess(ins *instruction) bool {
 	return len(ins.stores) > 0 || len(ins.loads) > 0
@@ -11,6 +13,22 @@ func insMemOrder(ins *instruction) bool {
 	return ins.typ.MemOrder()
 }
 
+// insAccess returns kinds of memory accesses of an instruction.
+//
+// We cannot distinguish main memory from device memory, so every load might be
+// both read and device input and every store might be both write and device
+// output.
+func insAccess(ins *instruction) model.Access {
+	var a model.Access
+	if len(ins.loads) > 0 {
+		a |= model.AccessRead | model.AccessInput
+	}
+	if len(ins.stores) > 0 {
+		a |= model.AccessWrite | model.AccessOutput
+	}
+	return a
+}
+
 // insSpecial identifies if an instruction is a special type of an instruction
 // other than memory order.
 func insSpecial(ins *instruction) bool {
@@ -18,75 +36,79 @@ func insSpecial(ins *instruction) bool {
 	return t.Syscall() || t.CPUStateChange()
 }
 
-// parseSpecialDeps finds dependencies in between instructions we don't fully
+// findSpecialDeps finds dependencies in between instructions we don't fully
 // understand.
 //
 // Even thought we have description of most of actions of all the instructions,
 // there are still instructions which very special meaning which we cannot
-// analyze. A good example of such an instruction are atomic instructions which
-// might cause either acquire of release semantic synchronization (or both).
-// Without any loss of genericity, let's assume that the atomic instruction has
-// acquire semantics. In such a case no following memory reads and writes can be
-// reordered before this instruction. But as we don't understand acquire
-// semantics, we have to be conservative and prohibit any reordering with any
-// memory order instruction. Another good example is syscall which in our
+// analyze. A good example of such an instruction is syscall which in our
 // representation has no dependencies. But the OS has the capability to change
 // an arbitrary memory address and value of an arbitrary register, so we have to
 // prohibit any reordering with the syscall instruction.
+//
+// Memory order instructions are not understood by expressions either, but they
+// describe memory accesses they order, see findMemOrderDeps.
 func findSpecialDeps(instrs []*instruction) {
-	var (
-		lastMemOrder *instruction
-		lastSpecial  *instruction
-	)
+	findMemOrderDeps(instrs)
 
+	var lastSpecial *instruction
 	for _, ins := range instrs {
-		if lastMemOrder != nil && isMemAccess(ins) {
-			addDep(lastMemOrder, ins)
-		}
 		if lastSpecial != nil {
 			addDep(lastSpecial, ins)
 		}
-
-		if insMemOrder(ins) {
-			lastMemOrder = ins
-		}
 		if insSpecial(ins) {
-			// This is jump optimization of graph size. As special
-			// instruction is dependent on everything we don't need
-			// to add dependencies for memory order instructions as
-			// well.
-			lastMemOrder = nil
 			lastSpecial = ins
 		}
 	}
 
 	// There is no special instruction in the block so the other walk will
 	// be no-op. This is just performance optimizations.
-	if lastMemOrder == nil && lastSpecial == nil {
+	if lastSpecial == nil {
 		return
 	}
 
-	lastMemOrder, lastSpecial = nil, nil
+	lastSpecial = nil
 	for i := len(instrs) - 1; i >= 0; i-- {
 		ins := instrs[i]
 
-		if lastMemOrder != nil && (isMemAccess(ins) || insMemOrder(ins)) {
-			addDep(ins, lastMemOrder)
-		}
 		if lastSpecial != nil {
 			addDep(ins, lastSpecial)
 		}
-
-		if insMemOrder(ins) {
-			lastMemOrder = ins
-		}
 		if insSpecial(ins) {
-			// This is jump optimization of graph size. As special
-			// instruction is dependent on everything we don't need
-			// to add dependencies for memory order instructions as
-			// well.
-			lastMemOrder = nil
 			lastSpecial = ins
 		}
 	}
 }
+
+// findMemOrderDeps finds dependencies of memory order instructions.
+//
+// Memory accesses preceding a memory order instruction which are of kinds in
+// its predecessor set have to be performed before the instruction and memory
+// accesses following the instruction of kinds in its successor set have to be
+// performed after it. Memory order instructions are not ordered in between
+// each other unless they both access memory - ordered memory accesses (such as
+// atomic instructions with acquire or release semantics) are sequentially
+// consistent.
+//
+// Edges implied by other edges are not omitted as memory order instructions
+// are not ordered with each other in general.
+func findMemOrderDeps(instrs []*instruction) {
+	for i, m := range instrs {
+		if !insMemOrder(m) {
+			continue
+		}
+
+		o := m.order.Full()
+		for _, ins := range instrs[:i] {
+			ordered := insMemOrder(ins) && isMemAccess(ins) && isMemAccess(m)
+			if ordered || insAccess(ins)&o.Pred != 0 {
+				addDep(ins, m)
+			}
+		}
+		for _, ins := range instrs[i+1:] {
+			if insAccess(ins)&o.Succ != 0 {
+				addDep(m, ins)
+			}
+		}
+	}
+}
@@ -39,7 +39,9 @@ func TestSpecialDeps(t *testing.T) {
 			{1, 3},
 			{3, 4},
 			{3, 5},
-			{3, 7},
+			{3, 8},
+			{0, 7},
+			{1, 7},
 			{4, 7},
 			{5, 7},
 			{7, 8},
@@ -87,7 +89,6 @@ func TestSpecialDeps(t *testing.T) {
 			{2, 3},
 			{2, 4},
 			{2, 5},
-			{3, 4},
 			{3, 5},
 			{4, 5},
 			{5, 6},
@@ -95,7 +96,76 @@ func TestSpecialDeps(t *testing.T) {
 			{6, 8},
 			{7, 8},
 		},
+	}, {
+		name: "fence_pred_succ",
+		ins: []*instruction{
+			testInsMem(nil, []expr.Key{"foo"}),
+			testInsMem([]expr.Key{"foo"}, nil),
+			testInsOrder(model.AccessRead, model.AccessWrite),
+			testInsMem(nil, []expr.Key{"foo"}),
+			testInsMem([]expr.Key{"foo"}, nil),
+		},
+		deps: []dep{
+			{0, 2},
+			{2, 4},
+		},
+	}, {
+		name: "fences_not_ordered",
+		ins: []*instruction{
+			testInsOrder(model.AccessAll, model.AccessAll),
+			testInsOrder(model.AccessOutput, model.AccessOutput),
+			testInsReg(1),
+		},
+	}, {
+		name: "relaxed_atomics",
+		ins: []*instruction{
+			testInsMem([]expr.Key{"foo"}, []expr.Key{"foo"}),
+			testInsMem([]expr.Key{"foo"}, []expr.Key{"foo"}),
+			testInsMem([]expr.Key{"foo"}, []expr.Key{"foo"}),
+		},
+	}, {
+		name: "acquire_release",
+		ins: []*instruction{
+			testInsMem([]expr.Key{"foo"}, nil),
+			testAtomic(0, model.AccessRead|model.AccessWrite),
+			testInsMem(nil, []expr.Key{"foo"}),
+			testAtomic(model.AccessRead|model.AccessWrite, 0),
+			testInsMem([]expr.Key{"foo"}, nil),
+		},
+		deps: []dep{
+			{0, 3},
+			{1, 2},
+			{1, 3},
+			{1, 4},
+			{2, 3},
+		},
+	}, {
+		name: "release_acquire",
+		ins: []*instruction{
+			testInsMem(nil, []expr.Key{"foo"}),
+			testAtomic(model.AccessRead|model.AccessWrite, 0),
+			testAtomic(0, model.AccessRead|model.AccessWrite),
+			testInsMem(nil, []expr.Key{"foo"}),
+		},
+		deps: []dep{
+			{0, 1},
+			{1, 2},
+			{2, 3},
+		},
 	}}
 
 	runDepsTest(t, tests, findSpecialDeps)
 }
+
+func testInsOrder(pred, succ model.Access) *instruction {
+	ins := testIns(model.TypeMemOrder, nil)
+	ins.order = model.MemOrder{Pred: pred, Succ: succ}
+	return ins
+}
+
+func testAtomic(pred, succ model.Access) *instruction {
+	ins := testInsMem([]expr.Key{"foo"}, []expr.Key{"foo"})
+	ins.typ = model.TypeMemOrder
+	ins.order = model.MemOrder{Pred: pred, Succ: succ}
+	return ins
+}
@@ -14,6 +14,8 @@ type regSet map[expr.Key]struct{}
 type instruction struct {
 	// typ is an instruction special type.
 	typ model.Type
+	// order describes memory accesses a memory order instruction orders.
+	order model.MemOrder
 	// origAddr is original address of the instruction in the code. This
 	// value remains constant even in case the instruction is moved to other
 	// place.
@@ -23,6 +25,9 @@ type instruction struct {
 	bytes []byte
 	// details provide platform-specific instruction methods.
 	details model.PlatformDetails
+	// source is position in the source code the instruction was compiled
+	// from.
+	source parser.SourceLine
 
 	// effects contains all side effects the instruction has.
 	effects []expr.Effect
@@ -37,6 +42,10 @@ type instruction struct {
 	// instructions as well, those could be interpreted as real jump if the
 	// instruction would be moved to other position (memory address).
 	jumpTargets []expr.Expr
+	// delayed indicates that the instruction is a delayed jump - i.e. the
+	// jump takes effect only after the following instruction (a delay
+	// slot) executes.
+	delayed bool
 
 	// currAddr is current address of the instruction in the moved code.
 	currAddr model.Addr
@@ -66,12 +75,15 @@ type instruction struct {
 func newInstruction(ins parser.Instruction) *instruction {
 	return &instruction{
 		typ:      ins.Type,
+		order:    ins.Order,
 		origAddr: ins.Addr,
 		bytes:    ins.Bytes,
 		details:  ins.Details,
+		source:   ins.Source,
 
 		effects:     ins.Effects,
 		jumpTargets: jumps(ins),
+		delayed:     delayed(ins),
 
 		currAddr: ins.Addr,
 
@@ -88,9 +100,11 @@ func newInstruction(ins parser.Instruction) *instruction {
 	}
 }
 
-// Jumps extracts all expressions the instruction can jump to. Jumps to address
-// following the instruction (to address of End()) are filtered away as those
-// are not read jump addresses.
+// Jumps extracts all expressions the instruction can jump to including targets
+// of delayed jumps. Jumps to address following the instruction (to address of
+// End()) are filtered away as those are not read jump addresses. A delayed jump
+// to End() only executes its delay slot again which doesn't leave the basic
+// block of the jump.
 func jumps(ins parser.Instruction) []expr.Expr {
 	var jumpAddrs []expr.Expr
 	for _, ef := range ins.Effects {
@@ -99,7 +113,7 @@ func jumps(ins parser.Instruction) []expr.Expr {
 			continue
 		}
 
-		if e.Key() != expr.IPKey {
+		if e.Key() != expr.IPKey && e.Key() != expr.DelayedIPKey {
 			continue
 		}
 
@@ -130,9 +144,22 @@ func jumps(ins parser.Instruction) []expr.Expr {
 	return jumpAddrs
 }
 
+// delayed checks whether ins writes delayed instruction pointer.
+func delayed(ins parser.Instruction) bool {
+	for _, ef := range ins.Effects {
+		if e, ok := ef.(expr.RegStore); ok && e.Key() == expr.DelayedIPKey {
+			return true
+		}
+	}
+	return false
+}
+
 // Idx returns index of an instruction in its basic block.
 func (i *instruction) Idx() int { return i.blockIdx }
 
+// Type returns set of special types of the instruction.
+func (i *instruction) Type() model.Type { return i.typ }
+
 // Begin returns the in-memory address of the instruction in the current order
 // of the program - i.e. after all instruction moves.
 func (i *instruction) Begin() model.Addr { return i.currAddr }
@@ -153,6 +180,10 @@ func (i *instruction) Bytes() []byte { return i.bytes }
 // binary.
 func (i *instruction) OrigAddr() model.Addr { return i.origAddr }
 
+// Source returns position in the source code the instruction was compiled
+// from. The position is unknown if the program has no debug information.
+func (i *instruction) Source() parser.SourceLine { return i.source }
+
 // Effects returns a read-only list of all side effects if an instruction.
 func (i *instruction) Effects() []expr.Effect { return i.effects }
 
@@ -160,6 +191,10 @@ func (i *instruction) Effects() []expr.Effect { return i.effects }
 // jump to.
 func (i *instruction) Jumps() []expr.Expr { return i.jumpTargets }
 
+// Delayed indicates that jumps of the instruction take effect only after the
+// following instruction (a delay slot) executes.
+func (i *instruction) Delayed() bool { return i.delayed }
+
 func (i *instruction) setIndex(idx int)     { i.blockIdx = idx }
 func (i *instruction) setAddr(a model.Addr) { i.currAddr = a }
 
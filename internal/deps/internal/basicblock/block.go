@@ -50,8 +50,9 @@ func (b block[T]) contains(addr model.Addr) bool {
 
 // split creates 2 new basic blocks consisting of instructions of b, but
 // separated by addr respectively. The instruction starting at addr will be
-// already included in the later block. If addr doesn't belong to the block or
-// is not at an instruction boundary, this method returns an error.
+// already included in the later block. If addr doesn't belong to the block, is
+// not at an instruction boundary or it's address of a delay slot, this method
+// returns an error.
 //
 // Please note that even though adds==b.Begin() is technically correct and will
 // result in empty first block returned, it makes just little sense to perform
@@ -71,6 +72,10 @@ func (b block[T]) split(addr model.Addr) (block[T], block[T], error) {
 		err := fmt.Errorf("address 0x%x is not at instruction boundary", addr)
 		return block[T]{}, block[T]{}, err
 	}
+	if i > 0 && b.seq[i-1].Delayed() && len(b.seq[i-1].Jumps()) > 0 {
+		err := fmt.Errorf("address 0x%x is a delay slot", addr)
+		return block[T]{}, block[T]{}, err
+	}
 
 	return newBlock(b.seq[:i]), newBlock(b.seq[i:]), nil
 }
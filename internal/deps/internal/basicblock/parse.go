@@ -11,6 +11,10 @@ type Instruction interface {
 	Begin() model.Addr
 	End() model.Addr
 	Jumps() []expr.Expr
+	Type() model.Type
+	// Delayed indicates that jumps of the instruction take effect only
+	// after the following instruction (a delay slot) executes.
+	Delayed() bool
 }
 
 // Parse identifies basic blocks in a sequence of program instructions. This
@@ -23,10 +27,15 @@ type Instruction interface {
 // glibc). Unfortunately those require non trivial platform and OS knowledge to
 // identify. The entrypoint is the only well-defined jump target comming from
 // outside of the program.
+//
+// Delay slot of a delayed jump always belongs to the same basic block as the
+// jump and it's the last instruction of the block. A jump target pointing to a
+// delay slot would separate the slot from its jump, so it's an error.
 func Parse[T Instruction](entrypoint model.Addr, seq []T) ([][]T, error) {
 	sort.Slice(seq, func(i, j int) bool { return seq[i].Begin() < seq[j].Begin() })
 
-	seqs := pipelineApply([][]T{seq}, splitByAddress[T], splitByJumps[T])
+	seqs := pipelineApply([][]T{seq},
+		splitByAddress[T], splitByJumps[T], splitByData[T])
 
 	bs, err := splitByJumpTargets(seqsToBlocks(seqs))
 	if err != nil {
@@ -79,11 +88,43 @@ func splitByJumps[T Instruction](seq []T) [][]T {
 	seqs := make([][]T, 0, 1)
 	begin := 0
 
+	for i := 0; i < len(seq); i++ {
+		if len(seq[i].Jumps()) == 0 {
+			continue
+		}
+
+		// The delay slot executes before the jump takes effect, so
+		// it's the last instruction of the block.
+		if seq[i].Delayed() && i+1 < len(seq) {
+			i++
+		}
+
+		seqs = append(seqs, seq[begin:i+1])
+		begin = i + 1
+	}
+
+	if end := len(seq); begin < end {
+		seqs = append(seqs, seq[begin:end])
+	}
+
+	return seqs
+}
+
+// splitByData places every data pseudo-instruction into a sequence of its own.
+func splitByData[T Instruction](seq []T) [][]T {
+	seqs := make([][]T, 0, 1)
+	begin := 0
+
 	for i, ins := range seq {
-		if len(ins.Jumps()) > 0 {
-			seqs = append(seqs, seq[begin:i+1])
-			begin = i + 1
+		if !ins.Type().Data() {
+			continue
 		}
+
+		if begin < i {
+			seqs = append(seqs, seq[begin:i])
+		}
+		seqs = append(seqs, seq[i:i+1])
+		begin = i + 1
 	}
 
 	if end := len(seq); begin < end {
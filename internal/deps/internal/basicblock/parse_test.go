@@ -12,8 +12,10 @@ import (
 const instrLen = 4
 
 type instruction struct {
-	addr  model.Addr
-	jumps []expr.Expr
+	addr    model.Addr
+	jumps   []expr.Expr
+	typ     model.Type
+	delayed bool
 
 	desc string
 }
@@ -21,6 +23,8 @@ type instruction struct {
 func (i instruction) Begin() model.Addr  { return i.addr }
 func (i instruction) End() model.Addr    { return i.addr + instrLen }
 func (i instruction) Jumps() []expr.Expr { return i.jumps }
+func (i instruction) Type() model.Type   { return i.typ }
+func (i instruction) Delayed() bool      { return i.delayed }
 
 func jumps(jumpAddrs []model.Addr) []expr.Expr {
 	jumps := make([]expr.Expr, len(jumpAddrs))
@@ -38,6 +42,20 @@ func insInput(addr model.Addr, desc string, jumpAddrs ...model.Addr) instruction
 	}
 }
 
+func insDelayed(addr model.Addr, desc string, jumpAddrs ...model.Addr) instruction {
+	ins := insInput(addr, desc, jumpAddrs...)
+	ins.delayed = true
+	return ins
+}
+
+func insData(addr model.Addr, desc string) instruction {
+	return instruction{
+		addr: addr,
+		typ:  model.TypeData,
+		desc: desc,
+	}
+}
+
 func TestParse_Succ(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -89,6 +107,50 @@ func TestParse_Succ(t *testing.T) {
 			insInput(84, "4"),
 			insInput(88, "5"),
 		}},
+	}, {
+		name: "delay_slot_stays_in_block",
+		instrs: []instruction{
+			insInput(72, "1"),
+			insDelayed(76, "2", 72),
+			insInput(80, "3"),
+			insInput(84, "4"),
+		},
+		expected: [][]instruction{{
+			insInput(72, "1"),
+			insDelayed(76, "2", 72),
+			insInput(80, "3"),
+		}, {
+			insInput(84, "4"),
+		}},
+	}, {
+		name: "delayed_jump_without_delay_slot",
+		instrs: []instruction{
+			insInput(72, "1"),
+			insDelayed(76, "2", 72),
+		},
+		expected: [][]instruction{{
+			insInput(72, "1"),
+			insDelayed(76, "2", 72),
+		}},
+	}, {
+		name: "data_splits_block",
+		instrs: []instruction{
+			insInput(72, "1"),
+			insData(76, "2"),
+			insData(80, "3"),
+			insInput(84, "4"),
+			insInput(88, "5"),
+		},
+		expected: [][]instruction{{
+			insInput(72, "1"),
+		}, {
+			insData(76, "2"),
+		}, {
+			insData(80, "3"),
+		}, {
+			insInput(84, "4"),
+			insInput(88, "5"),
+		}},
 	}, {
 		name: "jump_target_splits_block",
 		instrs: []instruction{
@@ -200,6 +262,14 @@ func TestParse_Fail(t *testing.T) {
 			insInput(76, "5"),
 			insInput(80, "6", 92),
 		},
+	}, {
+		name: "jump_into_delay_slot",
+		instrs: []instruction{
+			insInput(72, "1"),
+			insDelayed(76, "2", 80),
+			insInput(80, "3"),
+			insInput(84, "4"),
+		},
 	}, {
 		name: "jump_into_instruction",
 		instrs: []instruction{
@@ -1,5 +1,7 @@
 package deps
 
+import "mltwist/pkg/model"
+
 // Instruction represents a single instruction in the code.
 type Instruction struct {
 	*instruction
@@ -30,3 +32,20 @@ func (b Block) Instructions() []Instruction {
 
 // Index returns instruction at index i in b.
 func (b Block) Index(i int) Instruction { return wrapInstruction(b.index(i)) }
+
+// Format returns string representation of instruction at index i in b written
+// in assembler syntax s. Instructions which don't implement model.Formatter are
+// written in the syntax of their String method.
+func (b Block) Format(i int, s model.Syntax) string {
+	ins := b.index(i)
+	f, ok := ins.details.(model.Formatter)
+	if !ok {
+		return ins.details.String()
+	}
+
+	var prev model.PlatformDetails
+	if i > 0 {
+		prev = b.index(i - 1).details
+	}
+	return f.Format(s, prev)
+}
@@ -0,0 +1,33 @@
+package ebpf
+
+import (
+	debugelf "debug/elf"
+	"encoding/binary"
+	"mltwist/internal/elf"
+	"mltwist/internal/parser"
+)
+
+func init() { parser.Register(debugelf.EM_BPF, newArchitecture) }
+
+// newArchitecture creates description of eBPF architecture. ISA strings are not
+// supported.
+//
+// Keys of registers are their names, so no register names have to be
+// provided.
+func newArchitecture(_ *elf.Parser, isa string) (parser.Architecture, error) {
+	if isa != "" {
+		return parser.Architecture{}, parser.ErrISAUnsupported
+	}
+
+	p := NewParser()
+	return parser.Architecture{
+		Name:        "eBPF",
+		Parser:      p,
+		MemoryKey:   MemoryKey,
+		MinInstrLen: p.MinInstrLen(),
+		IPWidth:     width64,
+		ByteOrder:   binary.LittleEndian,
+		IPName:      "pc",
+		ResetState:  ResetState,
+	}, nil
+}
@@ -0,0 +1,148 @@
+package ebpf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+)
+
+// instruction represents a parsed eBPF instruction. Unlike the instructionType
+// which describes only instruction opcode and properties of the opcode,
+// instruction represents the whole instruction including encoding of registers,
+// immediate values and last but not least the position in the code.
+//
+// Every instruction slot consists of the opcode byte, a byte of register
+// numbers, 16 bit signed offset and 32 bit signed immediate value. Only
+// little-endian encoding of instructions is supported.
+type instruction struct {
+	// addr is virtual address of the instruction in a program memory.
+	addr model.Addr
+
+	// code is the opcode byte of the instruction.
+	code uint8
+	// regs encodes the destination register in its low 4 bits and the
+	// source register in its high 4 bits.
+	regs uint8
+	// off is the offset field of the instruction.
+	off int16
+	// imm is the immediate field of the instruction.
+	imm int32
+	// immHigh is the immediate field of the second slot of a wide
+	// instruction. All other fields of the second slot are zero.
+	immHigh int32
+
+	// instrType refers the type of the instruction.
+	instrType *instructionType
+}
+
+// newInstruction crates a new instance of instruction. The new instruction is
+// at address a, is represented by first bytes of b and has type t.
+func newInstruction(a model.Addr, b []byte, t *instructionType) instruction {
+	if l, want := model.Addr(len(b)), t.len(); l < want {
+		panic(fmt.Sprintf("not enough bytes to represent valid opcode: %d", l))
+	}
+
+	i := instruction{
+		addr:      a,
+		code:      b[0],
+		regs:      b[1],
+		off:       int16(binary.LittleEndian.Uint16(b[2:])),
+		imm:       int32(binary.LittleEndian.Uint32(b[4:])),
+		instrType: t,
+	}
+	if t.wide {
+		i.immHigh = int32(binary.LittleEndian.Uint32(b[slotLen+4:]))
+	}
+
+	return i
+}
+
+// dst returns number of the destination register.
+func (i instruction) dst() regNum { return regNum(i.regs & 0xf) }
+
+// src returns number of the source register.
+func (i instruction) src() regNum { return regNum(i.regs >> 4) }
+
+// class returns class of the instruction encoded in the opcode byte.
+func (i instruction) class() uint8 { return i.code & 0x07 }
+
+// width returns width of registers arithmetic and jump instructions operate.
+func (i instruction) width() expr.Width {
+	if c := i.class(); c == classALU || c == classJMP32 {
+		return width32
+	}
+	return width64
+}
+
+// imm64 returns the 64 bit immediate value of a wide instruction.
+func (i instruction) imm64() uint64 {
+	return uint64(uint32(i.immHigh))<<32 | uint64(uint32(i.imm))
+}
+
+// len returns length of the instruction in bytes.
+func (i instruction) len() model.Addr { return i.instrType.len() }
+
+// next returns address of the instruction following i.
+func (i instruction) next() model.Addr { return i.addr + i.len() }
+
+// target returns the address PC-relative offset of the instruction refers.
+func (i instruction) target() model.Addr {
+	t := i.instrType.offsetOfInstr(i)
+	return t.base(i.addr) + model.Addr(t.parse(i))
+}
+
+var _ model.Relocator = instruction{}
+
+// Name returns name of the instruction.
+func (i instruction) Name() string {
+	if m := i.instrType.mnemonic; m != nil {
+		return m(i)
+	}
+	return i.instrType.name
+}
+
+// String returns a string representation of an instruction which corresponds to
+// the notation of eBPF instructions used by LLVM.
+func (i instruction) String() string { return i.instrType.format(i) }
+
+// bytes returns the instruction encoded as a sequence of bytes in the memory.
+func (i instruction) bytes() []byte {
+	bs := make([]byte, slotLen, 2*slotLen)
+	bs[0], bs[1] = i.code, i.regs
+	binary.LittleEndian.PutUint16(bs[2:], uint16(i.off))
+	binary.LittleEndian.PutUint32(bs[4:], uint32(i.imm))
+
+	if i.instrType != nil && i.instrType.wide {
+		high := make([]byte, slotLen)
+		binary.LittleEndian.PutUint32(high[4:], uint32(i.immHigh))
+		bs = append(bs, high...)
+	}
+
+	return bs
+}
+
+// Relocate returns the instruction and its opcode bytes as if the instruction
+// was placed at address a.
+//
+// Offsets of PC-relative instructions are re-encoded so that the instruction
+// refers the same address as it referred before the move. An error is returned
+// if the new offset cannot be represented by the instruction. Other
+// instructions are returned unchanged.
+func (i instruction) Relocate(
+	a model.Addr,
+) (model.PlatformDetails, []byte, error) {
+	t := i.instrType.offsetOfInstr(i)
+	if t == offsetNone {
+		i.addr = a
+		return i, i.bytes(), nil
+	}
+
+	moved, err := t.encode(i, model.Offset(t.base(a), i.target()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot encode offset of %q: %w", i, err)
+	}
+
+	moved.addr = a
+	return moved, moved.bytes(), nil
+}
@@ -0,0 +1,291 @@
+package ebpf
+
+import (
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstruction_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		want  string
+	}{{
+		name:  "mov_imm",
+		bytes: []byte{0xb7, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
+		want:  "r0 = 1",
+	}, {
+		name:  "mov32_imm",
+		bytes: []byte{0xb4, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
+		want:  "w0 = 1",
+	}, {
+		name:  "mov32",
+		bytes: []byte{0xbc, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "w1 = w2",
+	}, {
+		name:  "movsx",
+		bytes: []byte{0xbf, 0x21, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "r1 = (s8)r2",
+	}, {
+		name:  "add",
+		bytes: []byte{0x0f, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "r1 += r2",
+	}, {
+		name:  "add32_negative_imm",
+		bytes: []byte{0x04, 0x01, 0x00, 0x00, 0xfb, 0xff, 0xff, 0xff},
+		want:  "w1 += -5",
+	}, {
+		name:  "and_imm",
+		bytes: []byte{0x57, 0x01, 0x00, 0x00, 0xff, 0x00, 0x00, 0x00},
+		want:  "r1 &= 255",
+	}, {
+		name:  "arsh",
+		bytes: []byte{0xc7, 0x01, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00},
+		want:  "r1 s>>= 4",
+	}, {
+		name:  "sdiv",
+		bytes: []byte{0x3f, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "r1 s/= r2",
+	}, {
+		name:  "neg32",
+		bytes: []byte{0x84, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "w1 = -w1",
+	}, {
+		name:  "be16",
+		bytes: []byte{0xdc, 0x01, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00},
+		want:  "r1 = be16 r1",
+	}, {
+		name:  "le32",
+		bytes: []byte{0xd4, 0x01, 0x00, 0x00, 0x20, 0x00, 0x00, 0x00},
+		want:  "r1 = le32 r1",
+	}, {
+		name:  "bswap64",
+		bytes: []byte{0xd7, 0x01, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00},
+		want:  "r1 = bswap64 r1",
+	}, {
+		name: "lddw",
+		bytes: []byte{
+			0x18, 0x01, 0x00, 0x00, 0x88, 0x77, 0x66, 0x55,
+			0x00, 0x00, 0x00, 0x00, 0x44, 0x33, 0x22, 0x11,
+		},
+		want: "r1 = 1234605616436508552 ll",
+	}, {
+		name:  "ldxdw",
+		bytes: []byte{0x79, 0xa1, 0xf8, 0xff, 0x00, 0x00, 0x00, 0x00},
+		want:  "r1 = *(u64 *)(r10 - 8)",
+	}, {
+		name:  "ldxb",
+		bytes: []byte{0x71, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "w1 = *(u8 *)(r2 + 0)",
+	}, {
+		name:  "ldxsh",
+		bytes: []byte{0x89, 0x21, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "r1 = *(s16 *)(r2 + 2)",
+	}, {
+		name:  "stw",
+		bytes: []byte{0x62, 0x0a, 0xfc, 0xff, 0x05, 0x00, 0x00, 0x00},
+		want:  "*(u32 *)(r10 - 4) = 5",
+	}, {
+		name:  "stxw",
+		bytes: []byte{0x63, 0x1a, 0xfc, 0xff, 0x00, 0x00, 0x00, 0x00},
+		want:  "*(u32 *)(r10 - 4) = w1",
+	}, {
+		name:  "atomic_add",
+		bytes: []byte{0xdb, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "lock *(u64 *)(r1 + 0) += r2",
+	}, {
+		name:  "atomic_fetch_or32",
+		bytes: []byte{0xc3, 0x21, 0x00, 0x00, 0x41, 0x00, 0x00, 0x00},
+		want:  "w2 = atomic_fetch_or((u32 *)(r1 + 0), w2)",
+	}, {
+		name:  "xchg32",
+		bytes: []byte{0xc3, 0x21, 0x08, 0x00, 0xe1, 0x00, 0x00, 0x00},
+		want:  "w2 = xchg32_32(r1 + 8, w2)",
+	}, {
+		name:  "cmpxchg",
+		bytes: []byte{0xdb, 0x21, 0x00, 0x00, 0xf1, 0x00, 0x00, 0x00},
+		want:  "r0 = cmpxchg_64(r1 + 0, r0, r2)",
+	}, {
+		name:  "ja",
+		bytes: []byte{0x05, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "goto +1",
+	}, {
+		name:  "jal",
+		bytes: []byte{0x06, 0x00, 0x00, 0x00, 0xfe, 0xff, 0xff, 0xff},
+		want:  "gotol -2",
+	}, {
+		name:  "jne_backwards",
+		bytes: []byte{0x5d, 0x21, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00},
+		want:  "if r1 != r2 goto -1",
+	}, {
+		name:  "jslt_imm",
+		bytes: []byte{0xc5, 0x01, 0x01, 0x00, 0xfb, 0xff, 0xff, 0xff},
+		want:  "if r1 s< -5 goto +1",
+	}, {
+		name:  "jset",
+		bytes: []byte{0x45, 0x01, 0x01, 0x00, 0x04, 0x00, 0x00, 0x00},
+		want:  "if r1 & 4 goto +1",
+	}, {
+		name:  "jslt32",
+		bytes: []byte{0xce, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "if w1 s< w2 goto +1",
+	}, {
+		name:  "call",
+		bytes: []byte{0x85, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
+		want:  "call 1",
+	}, {
+		name:  "exit",
+		bytes: []byte{0x95, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "exit",
+	}}
+
+	p := NewParser()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := p.Parse(testAddr, tt.bytes)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, ins.Details.String())
+		})
+	}
+}
+
+func TestInstruction_Name(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		want  string
+	}{{
+		// r1 s%= r2
+		name:  "smod",
+		bytes: []byte{0x9f, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "smod",
+	}, {
+		// w1 = (s16)w2
+		name:  "movs32",
+		bytes: []byte{0xbc, 0x21, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "movs32",
+	}, {
+		// r1 = be16 r1
+		name:  "be16",
+		bytes: []byte{0xdc, 0x01, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00},
+		want:  "be16",
+	}, {
+		// if w1 > 5 goto +1
+		name:  "jgt32",
+		bytes: []byte{0x26, 0x01, 0x01, 0x00, 0x05, 0x00, 0x00, 0x00},
+		want:  "jgt32",
+	}}
+
+	p := NewParser()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := p.Parse(testAddr, tt.bytes)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, ins.Details.Name())
+		})
+	}
+}
+
+func TestInstruction_Relocate(t *testing.T) {
+	tests := []struct {
+		name   string
+		bytes  []byte
+		addr   model.Addr
+		to     model.Addr
+		hasErr bool
+	}{{
+		// goto +1
+		name:  "ja",
+		bytes: []byte{0x05, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		addr:  0x1000,
+		to:    0x1800,
+	}, {
+		// goto +1
+		name:   "ja_too_far",
+		bytes:  []byte{0x05, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		addr:   0x1000,
+		to:     0x100000,
+		hasErr: true,
+	}, {
+		// goto +1
+		name:   "ja_unaligned",
+		bytes:  []byte{0x05, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		addr:   0x1000,
+		to:     0x1004,
+		hasErr: true,
+	}, {
+		// gotol +1
+		name:  "jal",
+		bytes: []byte{0x06, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
+		addr:  0x1000,
+		to:    0x100000,
+	}, {
+		// if r1 == r2 goto +1
+		name:  "jeq",
+		bytes: []byte{0x1d, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		addr:  0x1000,
+		to:    0x0800,
+	}, {
+		// call 2
+		name:  "call_local",
+		bytes: []byte{0x85, 0x10, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00},
+		addr:  0x1000,
+		to:    0x2000,
+	}, {
+		// call 2
+		name:  "call_helper",
+		bytes: []byte{0x85, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00},
+		addr:  0x1000,
+		to:    0x2000,
+	}, {
+		// r1 = 2 ll
+		name: "lddw_func",
+		bytes: []byte{
+			0x18, 0x41, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+		addr: 0x1000,
+		to:   0x2000,
+	}, {
+		// r1 += r2
+		name:  "not_relative",
+		bytes: []byte{0x0f, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		addr:  0x1000,
+		to:    0x1010,
+	}}
+
+	p := NewParser()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			ins, err := p.Parse(tt.addr, tt.bytes)
+			r.NoError(err)
+
+			details, bytes, err := ins.Details.(model.Relocator).Relocate(tt.to)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			moved, err := p.Parse(tt.to, bytes)
+			r.NoError(err)
+			r.Equal(moved.Details, details)
+
+			orig, i := ins.Details.(instruction), details.(instruction)
+			r.Equal(tt.to, i.addr)
+			r.Equal(orig.Name(), i.Name())
+			if orig.instrType.offsetOfInstr(orig) != offsetNone {
+				r.Equal(orig.target(), i.target())
+			} else {
+				r.Equal(orig.bytes(), i.bytes())
+			}
+		})
+	}
+}
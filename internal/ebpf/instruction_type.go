@@ -0,0 +1,167 @@
+package ebpf
+
+import (
+	"fmt"
+	"mltwist/internal/opcode"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+)
+
+// slotLen is length of an eBPF instruction slot in bytes. Instructions occupy
+// either one slot or two slots (wide instructions).
+const slotLen = 8
+
+// Instruction classes encoded in the lowest 3 bits of the opcode byte.
+const (
+	classLD    = 0x00
+	classLDX   = 0x01
+	classST    = 0x02
+	classSTX   = 0x03
+	classALU   = 0x04
+	classJMP   = 0x05
+	classJMP32 = 0x06
+	classALU64 = 0x07
+)
+
+// Operand sources of arithmetic and jump instructions encoded in bit 3 of the
+// opcode byte.
+const (
+	// sourceK selects the 32 bit immediate operand.
+	sourceK = 0x00
+	// sourceX selects the source register operand.
+	sourceX = 0x08
+)
+
+// instructionType describes a single eBPF instruction opcode.
+type instructionType struct {
+	// name is a symbolic name of an instruction.
+	//
+	// Names follow mnemonics of GNU assembler for eBPF, for example: add,
+	// add32, ldxdw, jeq, call etc. Assembler code of instructions itself
+	// uses the C-like notation of LLVM.
+	name string
+	// opcode describes opcode bits in an instruction.
+	opcode opcode.Opcode
+	// wide indicates that the instruction occupies two slots.
+	wide bool
+
+	// mnemonic returns the name of instruction i if it depends on operands
+	// of the instruction (for example signed division). The name field is
+	// used if mnemonic is nil.
+	mnemonic func(i instruction) string
+	// format returns instruction i in assembler code.
+	format func(i instruction) string
+	// reserved indicates that fields of instruction i make the
+	// instruction an invalid encoding even though its opcode matched. All
+	// encodings of the opcode are valid if reserved is nil.
+	reserved func(i instruction) bool
+
+	// offset describes encoding of PC-relative offset in an instruction.
+	offset offsetType
+	// offsetOf returns encoding of PC-relative offset in instruction i if
+	// it depends on operands of the instruction (for example calls of
+	// helpers and of BPF functions). The offset field is used if offsetOf
+	// is nil.
+	offsetOf func(i instruction) offsetType
+
+	// instrType is set of instruction types of an opcode.
+	instrType model.Type
+	// typeOf returns instruction type of instruction i if it depends on
+	// operands of the instruction. The instrType field is used if typeOf
+	// is nil.
+	typeOf func(i instruction) model.Type
+
+	// effects is a function which based on specific instruction i evaluates
+	// all effects of the given instruction.
+	effects func(i instruction) []expr.Effect
+}
+
+// Opcode returns the opcode definition of a given instruction type.
+func (t instructionType) Opcode() opcode.Opcode { return t.opcode }
+func (t instructionType) Name() string          { return t.name }
+
+// codeByte creates an opcode matching instructions with opcode byte c.
+func codeByte(c byte) opcode.Opcode {
+	return opcode.Opcode{Bytes: []byte{c}, Mask: []byte{0xff}}
+}
+
+// codeImm creates an opcode matching instructions with opcode byte c and the
+// imm field imm.
+func codeImm(c byte, imm int32) opcode.Opcode {
+	bs := instruction{code: c, imm: imm}.bytes()
+	return opcode.Opcode{
+		Bytes: bs,
+		Mask:  []byte{0xff, 0, 0, 0, 0xff, 0xff, 0xff, 0xff},
+	}
+}
+
+// len returns length of instructions of type t in bytes.
+func (t instructionType) len() model.Addr {
+	if t.wide {
+		return 2 * slotLen
+	}
+	return slotLen
+}
+
+// validate checks that instructionType description is valid (follows all the
+// assumptions the code imposes on the struct).
+func (t instructionType) validate() error {
+	if t.name == "" {
+		return fmt.Errorf("instruction name cannot be empty")
+	}
+	if err := t.opcode.Validate(); err != nil {
+		return fmt.Errorf("invalid opcode description: %w", err)
+	}
+	if l := len(t.opcode.Mask); l != 1 && l != slotLen {
+		return fmt.Errorf("invalid opcode length: %d", l)
+	}
+
+	if t.typeOf != nil && t.instrType != model.TypeNone {
+		return fmt.Errorf("instruction type is given by operands")
+	}
+	if t.offsetOf != nil && t.offset != offsetNone {
+		return fmt.Errorf("offset type is given by operands")
+	}
+
+	if t.format == nil {
+		return fmt.Errorf("format function must be always set")
+	}
+	if t.effects == nil {
+		return fmt.Errorf("effects function must be always set")
+	}
+
+	return nil
+}
+
+// typeOfInstr returns instruction type of instruction i of type t.
+func (t instructionType) typeOfInstr(i instruction) model.Type {
+	if t.typeOf != nil {
+		return t.typeOf(i)
+	}
+	return t.instrType
+}
+
+// offsetOfInstr returns encoding of PC-relative offset in instruction i of type
+// t.
+func (t instructionType) offsetOfInstr(i instruction) offsetType {
+	if t.offsetOf != nil {
+		return t.offsetOf(i)
+	}
+	return t.offset
+}
+
+// mergeInstructions merges multiple lists of instructionType into a single
+// list.
+func mergeInstructions(lists ...[]*instructionType) []*instructionType {
+	length := 0
+	for _, a := range lists {
+		length += len(a)
+	}
+
+	merged := make([]*instructionType, 0, length)
+	for _, a := range lists {
+		merged = append(merged, a...)
+	}
+
+	return merged
+}
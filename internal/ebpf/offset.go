@@ -0,0 +1,67 @@
+package ebpf
+
+import (
+	"fmt"
+	"math"
+	"mltwist/pkg/model"
+)
+
+// offsetType describes encoding of a PC-relative offset in an instruction.
+//
+// All eBPF offsets are signed numbers of instruction slots relative to the
+// slot following the first slot of an instruction.
+type offsetType uint8
+
+const (
+	// offsetNone means that an instruction is not PC-relative.
+	offsetNone offsetType = iota
+	// offsetOff is an offset encoded in the 16 bit off field. It's used by
+	// jumps.
+	offsetOff
+	// offsetImm is an offset encoded in the 32 bit imm field. It's used by
+	// long jumps, BPF to BPF calls and loads of function addresses.
+	offsetImm
+)
+
+// parse returns offset of instruction i of type t in bytes.
+func (t offsetType) parse(i instruction) int64 {
+	switch t {
+	case offsetOff:
+		return int64(i.off) * slotLen
+	case offsetImm:
+		return int64(i.imm) * slotLen
+	default:
+		return 0
+	}
+}
+
+// base returns the address offsets are relative to for an instruction at
+// address a.
+func (offsetType) base(a model.Addr) model.Addr { return a + slotLen }
+
+// encode returns instruction i with offset off of type t encoded in its
+// fields.
+func (t offsetType) encode(i instruction, off int64) (instruction, error) {
+	if off%slotLen != 0 {
+		return instruction{}, fmt.Errorf(
+			"offset is not aligned to %d bytes: %d", slotLen, off)
+	}
+
+	slots := off / slotLen
+	switch t {
+	case offsetOff:
+		if slots < math.MinInt16 || slots > math.MaxInt16 {
+			return instruction{}, fmt.Errorf(
+				"offset doesn't fit 16 bits: %d", off)
+		}
+		i.off = int16(slots)
+	case offsetImm:
+		if slots < math.MinInt32 || slots > math.MaxInt32 {
+			return instruction{}, fmt.Errorf(
+				"offset doesn't fit 32 bits: %d", off)
+		}
+		i.imm = int32(slots)
+	}
+
+	return i, nil
+}
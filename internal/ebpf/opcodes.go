@@ -0,0 +1,62 @@
+package ebpf
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"mltwist/pkg/model"
+)
+
+// MemoryKey is identifier of the memory address space.
+//
+// Given that eBPF defines only one memory space, there is no reason to explain
+// which memory is identified by this key.
+const MemoryKey = expr.Key("memory")
+
+// CallStackKey is identifier of the memory address space of the call stack.
+//
+// The call stack isn't accessible by eBPF programs, it exists to model BPF to
+// BPF calls. Every call stores a record of frameLen bytes at address given by
+// the CallDepthKey register multiplied by frameLen. The record consists of the
+// return address followed by values of callee-saved registers r6-r9 and the
+// frame pointer. The exit instruction restores all of them.
+const CallStackKey = expr.Key("call_stack")
+
+const (
+	width8  = expr.Width8
+	width16 = expr.Width16
+	width32 = expr.Width32
+	width64 = expr.Width64
+)
+
+// instructions is the list of all instructions the package parses.
+var instructions = mergeInstructions(
+	aluInstructions(),
+	jumpInstructions(),
+	memInstructions(),
+)
+
+// addrConst creates a constant of width64 representing address a.
+func addrConst(a model.Addr) expr.Const { return expr.NewConstUint(a, width64) }
+
+// ipStore returns an effect jumping to address e.
+func ipStore(e expr.Expr) expr.Effect { return expr.NewRegStore(e, expr.IPKey, width64) }
+
+// immConst creates a constant of width w with signed value v.
+func immConst(v int64, w expr.Width) expr.Const { return expr.NewConstInt(v, w) }
+
+// sext sign extends value of width from to width w.
+func sext(e expr.Expr, from, w expr.Width) expr.Expr {
+	return exprtools.SignExtend(e, expr.ConstFromUint(from.Bits()-1), w)
+}
+
+// decImm returns immediate value v in assembler code.
+func decImm(v int64) string { return fmt.Sprintf("%d", v) }
+
+func memLoad(addr expr.Expr, w expr.Width) expr.Expr {
+	return expr.NewMemLoad(MemoryKey, addr, w)
+}
+
+func memStore(e expr.Expr, addr expr.Expr, w expr.Width) expr.Effect {
+	return expr.NewMemStore(e, MemoryKey, addr, w)
+}
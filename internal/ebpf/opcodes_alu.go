@@ -0,0 +1,338 @@
+package ebpf
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// Arithmetic operations encoded in the highest 4 bits of the opcode byte of ALU
+// and ALU64 instructions.
+const (
+	aluADD  = 0x00
+	aluSUB  = 0x10
+	aluMUL  = 0x20
+	aluDIV  = 0x30
+	aluOR   = 0x40
+	aluAND  = 0x50
+	aluLSH  = 0x60
+	aluRSH  = 0x70
+	aluNEG  = 0x80
+	aluMOD  = 0x90
+	aluXOR  = 0xa0
+	aluMOV  = 0xb0
+	aluARSH = 0xc0
+	aluEND  = 0xd0
+)
+
+// aluOpFunc calculates result of an arithmetic operation of instruction i with
+// operands e1 (the destination register) and e2 (the source operand) of width
+// w.
+type aluOpFunc func(i instruction, e1, e2 expr.Expr, w expr.Width) expr.Expr
+
+// aluOp describes an arithmetic operation with two operands.
+type aluOp struct {
+	// name is the name of the operation.
+	name string
+	// op is the operation code.
+	op uint8
+	// assign is the compound assignment operator of the operation.
+	assign string
+	// signed indicates that the offset field value 1 selects signed
+	// variant of the operation. The offset field of other operations has
+	// to be zero.
+	signed bool
+	// f calculates result of the operation.
+	f aluOpFunc
+}
+
+// aluOps are all arithmetic operations with two operands except moves.
+var aluOps = []aluOp{
+	{name: "add", op: aluADD, assign: "+=", f: binaryOp(expr.Add)},
+	{name: "sub", op: aluSUB, assign: "-=", f: func(_ instruction, e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		return exprtools.Sub(e1, e2, w)
+	}},
+	{name: "mul", op: aluMUL, assign: "*=", f: binaryOp(expr.Mul)},
+	{name: "div", op: aluDIV, assign: "/=", signed: true, f: divide},
+	{name: "or", op: aluOR, assign: "|=", f: func(_ instruction, e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		return exprtools.BitOr(e1, e2, w)
+	}},
+	{name: "and", op: aluAND, assign: "&=", f: func(_ instruction, e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		return exprtools.BitAnd(e1, e2, w)
+	}},
+	{name: "lsh", op: aluLSH, assign: "<<=", f: shift(expr.Lsh)},
+	{name: "rsh", op: aluRSH, assign: ">>=", f: shift(expr.Rsh)},
+	{name: "mod", op: aluMOD, assign: "%=", signed: true, f: modulo},
+	{name: "xor", op: aluXOR, assign: "^=", f: func(_ instruction, e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		return exprtools.BitXor(e1, e2, w)
+	}},
+	{name: "arsh", op: aluARSH, assign: "s>>=", f: func(_ instruction, e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		return exprtools.RshA(e1, shiftAmount(e2, w), w)
+	}},
+}
+
+// aluInstructions returns all arithmetic instructions of ALU64 and ALU classes.
+func aluInstructions() []*instructionType {
+	var list []*instructionType
+	for _, class := range []uint8{classALU64, classALU} {
+		for _, op := range aluOps {
+			list = append(list,
+				aluBinary(op, class|sourceK),
+				aluBinary(op, class|sourceX),
+			)
+		}
+
+		list = append(list,
+			movImm(class),
+			movReg(class),
+			neg(class),
+		)
+	}
+
+	return append(list,
+		byteSwap("le", classALU|sourceK|aluEND),
+		byteSwap("be", classALU|sourceX|aluEND),
+		byteSwap("bswap", classALU64|sourceK|aluEND),
+	)
+}
+
+// aluName returns name of an arithmetic instruction called name of class
+// class. Names of 32 bit instructions have suffix 32.
+func aluName(name string, class uint8) string {
+	if class&0x07 == classALU {
+		return name + "32"
+	}
+	return name
+}
+
+// aluSource returns the source operand of arithmetic instruction i of width w.
+// Immediate values are sign extended to w.
+func aluSource(i instruction, w expr.Width) expr.Expr {
+	if i.code&sourceX != 0 {
+		return regLoad(i.src(), w)
+	}
+	return immConst(int64(i.imm), w)
+}
+
+// aluSourceString returns the source operand of arithmetic instruction i in
+// assembler code.
+func aluSourceString(i instruction) string {
+	if i.code&sourceX != 0 {
+		return i.src().name(i.width())
+	}
+	return decImm(int64(i.imm))
+}
+
+// invalidRegs indicates that instruction i refers a register which doesn't
+// exist or that it writes the read-only frame pointer.
+func invalidRegs(i instruction) bool {
+	return !i.dst().valid() || !i.src().valid() || i.dst() == regFP
+}
+
+// aluBinary creates arithmetic instruction with operation op. The instruction
+// has opcode byte given by op and by bits code which select the class and the
+// source of the instruction.
+func aluBinary(op aluOp, code uint8) *instructionType {
+	signedName := func(i instruction) string {
+		if i.off == 1 {
+			return "s"
+		}
+		return ""
+	}
+
+	return &instructionType{
+		name:   aluName(op.name, code),
+		opcode: codeByte(code | op.op),
+		mnemonic: func(i instruction) string {
+			return aluName(signedName(i)+op.name, code)
+		},
+		format: func(i instruction) string {
+			return fmt.Sprintf("%s %s%s %s", i.dst().name(i.width()),
+				signedName(i), op.assign, aluSourceString(i))
+		},
+		reserved: func(i instruction) bool {
+			if op.signed && i.off == 1 {
+				return invalidRegs(i)
+			}
+			return i.off != 0 || invalidRegs(i)
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			res := op.f(i, regLoad(i.dst(), w), aluSource(i, w), w)
+			return []expr.Effect{regStore(res, i.dst())}
+		},
+	}
+}
+
+// binaryOp returns function calculating binary operation op.
+func binaryOp(op expr.BinaryOp) aluOpFunc {
+	return func(_ instruction, e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		return expr.NewBinary(op, e1, e2, w)
+	}
+}
+
+// shiftAmount returns shift amount e of width w. Only the lowest 5 or 6 bits of
+// shift amount are used for 32 or 64 bit operations respectively.
+func shiftAmount(e expr.Expr, w expr.Width) expr.Expr {
+	return exprtools.MaskBits(e, exprtools.BitCnt(w.Bits()/32+4), w)
+}
+
+// shift returns function shifting the first operand by the second operand using
+// binary operation op.
+func shift(op expr.BinaryOp) aluOpFunc {
+	return func(_ instruction, e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		return expr.NewBinary(op, e1, shiftAmount(e2, w), w)
+	}
+}
+
+// divide calculates quotient of e1 and e2. The division is signed if the offset
+// field of instruction i is 1. Division by zero results in zero.
+func divide(i instruction, e1, e2 expr.Expr, w expr.Width) expr.Expr {
+	var res expr.Expr
+	if i.off == 1 {
+		res = exprtools.SignedDiv(e1, e2, w)
+	} else {
+		res = expr.NewBinary(expr.Div, e1, e2, w)
+	}
+
+	return exprtools.BoolCond(e2, res, immConst(0, w), w)
+}
+
+// modulo calculates remainder of division of e1 by e2. The division is signed
+// if the offset field of instruction i is 1 and the remainder has then sign of
+// the dividend. Modulo by zero results in e1.
+func modulo(i instruction, e1, e2 expr.Expr, w expr.Width) expr.Expr {
+	if i.off != 1 {
+		return exprtools.Mod(e1, e2, w)
+	}
+
+	// Signed division by zero results in -1 which makes the remainder
+	// equal to e1 as required.
+	q := exprtools.SignedDiv(e1, e2, w)
+	return exprtools.Sub(e1, expr.NewBinary(expr.Mul, q, e2, w), w)
+}
+
+// movImm creates move instruction of class class with immediate operand.
+func movImm(class uint8) *instructionType {
+	return &instructionType{
+		name:   aluName("mov", class),
+		opcode: codeByte(class | sourceK | aluMOV),
+		format: func(i instruction) string {
+			return fmt.Sprintf("%s = %s", i.dst().name(i.width()), aluSourceString(i))
+		},
+		reserved: func(i instruction) bool { return i.off != 0 || invalidRegs(i) },
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{regStore(aluSource(i, i.width()), i.dst())}
+		},
+	}
+}
+
+// movReg creates move instruction of class class with register operand. The
+// offset field selects width of sign extension of the source register. Zero
+// offset means that the value is moved without extension.
+func movReg(class uint8) *instructionType {
+	extWidth := func(i instruction) (expr.Width, bool) {
+		switch {
+		case i.off == 8:
+			return width8, true
+		case i.off == 16:
+			return width16, true
+		case i.off == 32 && class == classALU64:
+			return width32, true
+		default:
+			return 0, false
+		}
+	}
+
+	return &instructionType{
+		name:   aluName("mov", class),
+		opcode: codeByte(class | sourceX | aluMOV),
+		mnemonic: func(i instruction) string {
+			if _, ok := extWidth(i); ok {
+				return aluName("movs", class)
+			}
+			return aluName("mov", class)
+		},
+		format: func(i instruction) string {
+			w := i.width()
+			if ew, ok := extWidth(i); ok {
+				return fmt.Sprintf("%s = (s%d)%s", i.dst().name(w), ew.Bits(), i.src().name(w))
+			}
+			return fmt.Sprintf("%s = %s", i.dst().name(w), i.src().name(w))
+		},
+		reserved: func(i instruction) bool {
+			_, ok := extWidth(i)
+			return (i.off != 0 && !ok) || invalidRegs(i)
+		},
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			if ew, ok := extWidth(i); ok {
+				val := sext(regLoad(i.src(), ew), ew, w)
+				return []expr.Effect{regStore(val, i.dst())}
+			}
+			return []expr.Effect{regStore(regLoad(i.src(), w), i.dst())}
+		},
+	}
+}
+
+// neg creates negation instruction of class class.
+func neg(class uint8) *instructionType {
+	return &instructionType{
+		name:   aluName("neg", class),
+		opcode: codeByte(class | sourceK | aluNEG),
+		format: func(i instruction) string {
+			r := i.dst().name(i.width())
+			return fmt.Sprintf("%s = -%s", r, r)
+		},
+		reserved: func(i instruction) bool { return i.off != 0 || invalidRegs(i) },
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			return []expr.Effect{regStore(exprtools.Negate(regLoad(i.dst(), w), w), i.dst())}
+		},
+	}
+}
+
+// byteSwap creates byte swap instruction called name with opcode byte code.
+// Width of the swapped value is given by the immediate field.
+//
+// Instructions converting to little-endian (le) don't swap bytes as only
+// little-endian eBPF is supported, they only truncate the value. The other
+// instructions reverse order of bytes.
+func byteSwap(name string, code uint8) *instructionType {
+	swapWidth := func(i instruction) (expr.Width, bool) {
+		switch i.imm {
+		case 16:
+			return width16, true
+		case 32:
+			return width32, true
+		case 64:
+			return width64, true
+		default:
+			return 0, false
+		}
+	}
+
+	return &instructionType{
+		name:   name,
+		opcode: codeByte(code),
+		mnemonic: func(i instruction) string {
+			return fmt.Sprintf("%s%d", name, i.imm)
+		},
+		format: func(i instruction) string {
+			r := i.dst().name(width64)
+			return fmt.Sprintf("%s = %s%d %s", r, name, i.imm, r)
+		},
+		reserved: func(i instruction) bool {
+			_, ok := swapWidth(i)
+			return !ok || i.off != 0 || invalidRegs(i)
+		},
+		effects: func(i instruction) []expr.Effect {
+			w, _ := swapWidth(i)
+			val := regLoad(i.dst(), w)
+			if name != "le" {
+				val = exprtools.ReverseBytes(val, w)
+			}
+			return []expr.Effect{regStore(val, i.dst())}
+		},
+	}
+}
@@ -0,0 +1,212 @@
+package ebpf
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+)
+
+func TestALUEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// r1 += r2
+		name:  "add",
+		bytes: []byte{0x0f, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 1, "r2": 2},
+		want:  map[expr.Key]uint64{"r1": 3},
+	}, {
+		// r1 += -1
+		name:  "add_negative_imm",
+		bytes: []byte{0x07, 0x01, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff},
+		regs:  map[expr.Key]uint64{"r1": 5},
+		want:  map[expr.Key]uint64{"r1": 4},
+	}, {
+		// w1 += w2
+		name:  "add32",
+		bytes: []byte{0x0c, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0xffffffff_ffffffff, "r2": 2},
+		want:  map[expr.Key]uint64{"r1": 1},
+	}, {
+		// r1 -= 5
+		name:  "sub",
+		bytes: []byte{0x17, 0x01, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 3},
+		want:  map[expr.Key]uint64{"r1": 0xffffffff_fffffffe},
+	}, {
+		// w1 *= w2
+		name:  "mul32",
+		bytes: []byte{0x2c, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0x1_00000003, "r2": 0x1_00000005},
+		want:  map[expr.Key]uint64{"r1": 15},
+	}, {
+		// r1 /= r2
+		name:  "div",
+		bytes: []byte{0x3f, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 7, "r2": 2},
+		want:  map[expr.Key]uint64{"r1": 3},
+	}, {
+		// r1 /= r2
+		name:  "div_by_zero",
+		bytes: []byte{0x3f, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 7},
+		want:  map[expr.Key]uint64{"r1": 0},
+	}, {
+		// r1 s/= r2
+		name:  "sdiv",
+		bytes: []byte{0x3f, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0xffffffff_fffffff9, "r2": 2},
+		want:  map[expr.Key]uint64{"r1": 0xffffffff_fffffffd},
+	}, {
+		// r1 s/= r2
+		name:  "sdiv_by_zero",
+		bytes: []byte{0x3f, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0xffffffff_fffffff9},
+		want:  map[expr.Key]uint64{"r1": 0},
+	}, {
+		// w1 s/= -2
+		name:  "sdiv32",
+		bytes: []byte{0x34, 0x01, 0x01, 0x00, 0xfe, 0xff, 0xff, 0xff},
+		regs:  map[expr.Key]uint64{"r1": 7},
+		want:  map[expr.Key]uint64{"r1": 0xfffffffd},
+	}, {
+		// r1 %= r2
+		name:  "mod",
+		bytes: []byte{0x9f, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 7, "r2": 3},
+		want:  map[expr.Key]uint64{"r1": 1},
+	}, {
+		// w1 %= w2
+		name:  "mod32_by_zero",
+		bytes: []byte{0x9c, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0x1_00000007},
+		want:  map[expr.Key]uint64{"r1": 7},
+	}, {
+		// r1 s%= r2
+		name:  "smod",
+		bytes: []byte{0x9f, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0xffffffff_fffffff9, "r2": 2},
+		want:  map[expr.Key]uint64{"r1": 0xffffffff_ffffffff},
+	}, {
+		// r1 s%= r2
+		name:  "smod_negative_divisor",
+		bytes: []byte{0x9f, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 7, "r2": 0xffffffff_fffffffe},
+		want:  map[expr.Key]uint64{"r1": 1},
+	}, {
+		// r1 s%= r2
+		name:  "smod_by_zero",
+		bytes: []byte{0x9f, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0xffffffff_fffffff9},
+		want:  map[expr.Key]uint64{"r1": 0xffffffff_fffffff9},
+	}, {
+		// r1 &= 255
+		name:  "and",
+		bytes: []byte{0x57, 0x01, 0x00, 0x00, 0xff, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0x1234},
+		want:  map[expr.Key]uint64{"r1": 0x34},
+	}, {
+		// r1 |= r2
+		name:  "or",
+		bytes: []byte{0x4f, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0x1200, "r2": 0x34},
+		want:  map[expr.Key]uint64{"r1": 0x1234},
+	}, {
+		// w1 ^= -1
+		name:  "xor32",
+		bytes: []byte{0xa4, 0x01, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff},
+		regs:  map[expr.Key]uint64{"r1": 0x1_0000000f},
+		want:  map[expr.Key]uint64{"r1": 0xfffffff0},
+	}, {
+		// r1 <<= r2
+		name:  "lsh_masked",
+		bytes: []byte{0x6f, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 1, "r2": 65},
+		want:  map[expr.Key]uint64{"r1": 2},
+	}, {
+		// w1 >>= 5
+		name:  "rsh32",
+		bytes: []byte{0x74, 0x01, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0xffffffff_ffffffff},
+		want:  map[expr.Key]uint64{"r1": 0x07ffffff},
+	}, {
+		// r1 s>>= 4
+		name:  "arsh",
+		bytes: []byte{0xc7, 0x01, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0x80000000_00000000},
+		want:  map[expr.Key]uint64{"r1": 0xf8000000_00000000},
+	}, {
+		// w1 s>>= w2
+		name:  "arsh32",
+		bytes: []byte{0xcc, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0x80000000, "r2": 4},
+		want:  map[expr.Key]uint64{"r1": 0xf8000000},
+	}, {
+		// r1 = -r1
+		name:  "neg",
+		bytes: []byte{0x87, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 1},
+		want:  map[expr.Key]uint64{"r1": 0xffffffff_ffffffff},
+	}, {
+		// w1 = -w1
+		name:  "neg32",
+		bytes: []byte{0x84, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 1},
+		want:  map[expr.Key]uint64{"r1": 0xffffffff},
+	}, {
+		// r1 = -1
+		name:  "mov_imm",
+		bytes: []byte{0xb7, 0x01, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff},
+		want:  map[expr.Key]uint64{"r1": 0xffffffff_ffffffff},
+	}, {
+		// w1 = -1
+		name:  "mov32_imm",
+		bytes: []byte{0xb4, 0x01, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff},
+		want:  map[expr.Key]uint64{"r1": 0xffffffff},
+	}, {
+		// w1 = w2
+		name:  "mov32",
+		bytes: []byte{0xbc, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r2": 0x1_00000002},
+		want:  map[expr.Key]uint64{"r1": 2},
+	}, {
+		// r1 = (s8)r2
+		name:  "movsx8",
+		bytes: []byte{0xbf, 0x21, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r2": 0x180},
+		want:  map[expr.Key]uint64{"r1": 0xffffffff_ffffff80},
+	}, {
+		// r1 = (s32)r2
+		name:  "movsx32",
+		bytes: []byte{0xbf, 0x21, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r2": 0x80000000},
+		want:  map[expr.Key]uint64{"r1": 0xffffffff_80000000},
+	}, {
+		// w1 = (s16)w2
+		name:  "movsx32_16",
+		bytes: []byte{0xbc, 0x21, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r2": 0x8000},
+		want:  map[expr.Key]uint64{"r1": 0xffff8000},
+	}, {
+		// r1 = be16 r1
+		name:  "be16",
+		bytes: []byte{0xdc, 0x01, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0x11223344},
+		want:  map[expr.Key]uint64{"r1": 0x4433},
+	}, {
+		// r1 = be64 r1
+		name:  "be64",
+		bytes: []byte{0xdc, 0x01, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0x11223344_55667788},
+		want:  map[expr.Key]uint64{"r1": 0x88776655_44332211},
+	}, {
+		// r1 = le32 r1
+		name:  "le32",
+		bytes: []byte{0xd4, 0x01, 0x00, 0x00, 0x20, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0x11223344_55667788},
+		want:  map[expr.Key]uint64{"r1": 0x55667788},
+	}, {
+		// r1 = bswap16 r1
+		name:  "bswap16",
+		bytes: []byte{0xd7, 0x01, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0xffff1234},
+		want:  map[expr.Key]uint64{"r1": 0x3412},
+	}})
+}
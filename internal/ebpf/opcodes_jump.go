@@ -0,0 +1,254 @@
+package ebpf
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"mltwist/pkg/model"
+)
+
+// Jump operations encoded in the highest 4 bits of the opcode byte of JMP and
+// JMP32 instructions.
+const (
+	jmpJA   = 0x00
+	jmpCALL = 0x80
+	jmpEXIT = 0x90
+)
+
+// Values of the source register field of call instructions.
+const (
+	// callHelper calls a helper function identified by the immediate
+	// field.
+	callHelper = 0
+	// callLocal calls a BPF function at PC-relative offset given by the
+	// immediate field.
+	callLocal = 1
+	// callKfunc calls a kernel function identified by BTF id given by the
+	// immediate field.
+	callKfunc = 2
+)
+
+const (
+	// frameLen is length of a record of the call stack in bytes.
+	frameLen = 48
+	// stackLen is size of the stack of a single BPF function in bytes.
+	stackLen = 512
+)
+
+// savedRegs are registers preserved across BPF to BPF calls in the order they
+// are stored in a record of the call stack following the return address.
+var savedRegs = []regNum{6, 7, 8, 9, regFP}
+
+// condFunc returns trueExpr if operands e1 and e2 of width64 satisfy a
+// condition and falseExpr otherwise. The result has width64.
+type condFunc func(e1, e2, trueExpr, falseExpr expr.Expr) expr.Expr
+
+// jumpCond describes condition of a conditional jump.
+type jumpCond struct {
+	// name is the name of the jump.
+	name string
+	// op is the operation code.
+	op uint8
+	// operator is the comparison operator of the condition.
+	operator string
+	// signed indicates that the operands are compared as signed integers.
+	signed bool
+	// f evaluates the condition.
+	f condFunc
+}
+
+// jumpConds are conditions of all conditional jumps.
+var jumpConds = []jumpCond{
+	{name: "jeq", op: 0x10, operator: "==", f: func(e1, e2, t, f expr.Expr) expr.Expr {
+		return exprtools.Eq(e1, e2, t, f, width64)
+	}},
+	{name: "jgt", op: 0x20, operator: ">", f: func(e1, e2, t, f expr.Expr) expr.Expr {
+		return expr.NewLess(e2, e1, t, f, width64)
+	}},
+	{name: "jge", op: 0x30, operator: ">=", f: func(e1, e2, t, f expr.Expr) expr.Expr {
+		return exprtools.Leu(e2, e1, t, f, width64)
+	}},
+	{name: "jset", op: 0x40, operator: "&", f: func(e1, e2, t, f expr.Expr) expr.Expr {
+		return exprtools.BoolCond(exprtools.BitAnd(e1, e2, width64), t, f, width64)
+	}},
+	{name: "jne", op: 0x50, operator: "!=", f: func(e1, e2, t, f expr.Expr) expr.Expr {
+		return exprtools.Eq(e1, e2, f, t, width64)
+	}},
+	{name: "jsgt", op: 0x60, operator: "s>", signed: true, f: func(e1, e2, t, f expr.Expr) expr.Expr {
+		return exprtools.Lts(e2, e1, t, f, width64)
+	}},
+	{name: "jsge", op: 0x70, operator: "s>=", signed: true, f: func(e1, e2, t, f expr.Expr) expr.Expr {
+		return exprtools.Les(e2, e1, t, f, width64)
+	}},
+	{name: "jlt", op: 0xa0, operator: "<", f: func(e1, e2, t, f expr.Expr) expr.Expr {
+		return expr.NewLess(e1, e2, t, f, width64)
+	}},
+	{name: "jle", op: 0xb0, operator: "<=", f: func(e1, e2, t, f expr.Expr) expr.Expr {
+		return exprtools.Leu(e1, e2, t, f, width64)
+	}},
+	{name: "jslt", op: 0xc0, operator: "s<", signed: true, f: func(e1, e2, t, f expr.Expr) expr.Expr {
+		return exprtools.Lts(e1, e2, t, f, width64)
+	}},
+	{name: "jsle", op: 0xd0, operator: "s<=", signed: true, f: func(e1, e2, t, f expr.Expr) expr.Expr {
+		return exprtools.Les(e1, e2, t, f, width64)
+	}},
+}
+
+// jumpInstructions returns all instructions of JMP and JMP32 classes.
+func jumpInstructions() []*instructionType {
+	list := []*instructionType{
+		{
+			name:   "ja",
+			opcode: codeByte(classJMP | jmpJA),
+			offset: offsetOff,
+			format: func(i instruction) string {
+				return fmt.Sprintf("goto %+d", i.off)
+			},
+			reserved: func(i instruction) bool { return i.regs != 0 },
+			effects: func(i instruction) []expr.Effect {
+				return []expr.Effect{ipStore(addrConst(i.target()))}
+			},
+		}, {
+			name:   "jal",
+			opcode: codeByte(classJMP32 | jmpJA),
+			offset: offsetImm,
+			format: func(i instruction) string {
+				return fmt.Sprintf("gotol %+d", i.imm)
+			},
+			reserved: func(i instruction) bool { return i.regs != 0 || i.off != 0 },
+			effects: func(i instruction) []expr.Effect {
+				return []expr.Effect{ipStore(addrConst(i.target()))}
+			},
+		}, {
+			name:   "call",
+			opcode: codeByte(classJMP | jmpCALL),
+			offsetOf: func(i instruction) offsetType {
+				if i.src() == callLocal {
+					return offsetImm
+				}
+				return offsetNone
+			},
+			format: func(i instruction) string {
+				return fmt.Sprintf("call %d", i.imm)
+			},
+			reserved: func(i instruction) bool {
+				return i.dst() != 0 || i.src() > callKfunc || i.off != 0
+			},
+			typeOf: func(i instruction) model.Type {
+				if i.src() == callLocal {
+					return model.TypeNone
+				}
+				return model.TypeSyscall
+			},
+			effects: callEffects,
+		}, {
+			name:     "exit",
+			opcode:   codeByte(classJMP | jmpEXIT),
+			format:   func(instruction) string { return "exit" },
+			reserved: func(i instruction) bool { return i.regs != 0 || i.off != 0 || i.imm != 0 },
+			effects:  exitEffects,
+		},
+	}
+
+	for _, class := range []uint8{classJMP, classJMP32} {
+		for _, cond := range jumpConds {
+			list = append(list,
+				condJump(cond, class|sourceK),
+				condJump(cond, class|sourceX),
+			)
+		}
+	}
+
+	return list
+}
+
+// condJump creates conditional jump with condition cond. The instruction has
+// opcode byte given by cond and by bits code which select the class and the
+// source of the instruction.
+func condJump(cond jumpCond, code uint8) *instructionType {
+	name := cond.name
+	if code&0x07 == classJMP32 {
+		name += "32"
+	}
+
+	// Both operands are extended to 64 bits, so that the condition can
+	// select from 64 bit addresses.
+	operand := func(e expr.Expr, w expr.Width) expr.Expr {
+		if cond.signed && w != width64 {
+			return sext(e, w, width64)
+		}
+		return e
+	}
+
+	return &instructionType{
+		name:   name,
+		opcode: codeByte(code | cond.op),
+		offset: offsetOff,
+		format: func(i instruction) string {
+			return fmt.Sprintf("if %s %s %s goto %+d", i.dst().name(i.width()),
+				cond.operator, aluSourceString(i), i.off)
+		},
+		reserved: func(i instruction) bool { return !i.dst().valid() || !i.src().valid() },
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			e1 := operand(regLoad(i.dst(), w), w)
+			e2 := operand(aluSource(i, w), w)
+			target := cond.f(e1, e2, addrConst(i.target()), addrConst(i.next()))
+			return []expr.Effect{ipStore(target)}
+		},
+	}
+}
+
+// callStackAddr returns address of field at offset off in a record of the call
+// stack at depth depth.
+func callStackAddr(depth expr.Expr, off uint64) expr.Expr {
+	base := expr.NewBinary(expr.Mul, depth, expr.NewConstUint[uint64](frameLen, width64), width64)
+	return expr.NewBinary(expr.Add, base, expr.NewConstUint(off, width64), width64)
+}
+
+// callEffects returns effects of call instruction i.
+//
+// Calls of helpers and kernel functions are represented as system calls, so
+// they have no effects. BPF to BPF calls push a record of the call stack and
+// allocate a new stack frame of the callee.
+func callEffects(i instruction) []expr.Effect {
+	if i.src() != callLocal {
+		return nil
+	}
+
+	depth := expr.NewRegLoad(CallDepthKey, width64)
+	effects := []expr.Effect{
+		expr.NewMemStore(addrConst(i.next()), CallStackKey, callStackAddr(depth, 0), width64),
+	}
+	for j, r := range savedRegs {
+		addr := callStackAddr(depth, uint64(j+1)*8)
+		effects = append(effects, expr.NewMemStore(regLoad(r, width64), CallStackKey, addr, width64))
+	}
+
+	fp := exprtools.Sub(regLoad(regFP, width64), immConst(stackLen, width64), width64)
+	return append(effects,
+		expr.NewRegStore(expr.NewBinary(expr.Add, depth, expr.One, width64), CallDepthKey, width64),
+		regStore(fp, regFP),
+		ipStore(addrConst(i.target())),
+	)
+}
+
+// exitEffects returns effects of exit instruction. The instruction pops a record
+// of the call stack and returns to the caller.
+//
+// Exit from the main function of a program has no caller to return to. Such an
+// exit reads the record below the bottom of the call stack, so it's a return to
+// an unknown address.
+func exitEffects(instruction) []expr.Effect {
+	depth := exprtools.Sub(expr.NewRegLoad(CallDepthKey, width64), expr.One, width64)
+	effects := []expr.Effect{
+		ipStore(expr.NewMemLoad(CallStackKey, callStackAddr(depth, 0), width64)),
+		expr.NewRegStore(depth, CallDepthKey, width64),
+	}
+	for j, r := range savedRegs {
+		addr := callStackAddr(depth, uint64(j+1)*8)
+		effects = append(effects, regStore(expr.NewMemLoad(CallStackKey, addr, width64), r))
+	}
+
+	return effects
+}
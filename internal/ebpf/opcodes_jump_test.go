@@ -0,0 +1,190 @@
+package ebpf
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJumpEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// goto +1
+		name:  "ja",
+		bytes: []byte{0x05, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// goto -1
+		name:  "ja_backwards",
+		bytes: []byte{0x05, 0x00, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr},
+	}, {
+		// gotol +2
+		name:  "jal",
+		bytes: []byte{0x06, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x18},
+	}, {
+		// if r1 == r2 goto +1
+		name:  "jeq_taken",
+		bytes: []byte{0x1d, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 5, "r2": 5},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// if r1 == r2 goto +1
+		name:  "jeq_not_taken",
+		bytes: []byte{0x1d, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 5, "r2": 6},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 8},
+	}, {
+		// if r1 != r2 goto +1
+		name:  "jne",
+		bytes: []byte{0x5d, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 5, "r2": 6},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// if r1 > r2 goto +1
+		name:  "jgt",
+		bytes: []byte{0x2d, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0xffffffff_ffffffff, "r2": 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// if r1 >= r2 goto +1
+		name:  "jge_equal",
+		bytes: []byte{0x3d, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 3, "r2": 3},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// if r1 < r2 goto +1
+		name:  "jlt",
+		bytes: []byte{0xad, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 3, "r2": 3},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 8},
+	}, {
+		// if r1 <= r2 goto +1
+		name:  "jle",
+		bytes: []byte{0xbd, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 3, "r2": 3},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// if r1 s> r2 goto +1
+		name:  "jsgt",
+		bytes: []byte{0x6d, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0xffffffff_ffffffff, "r2": 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 8},
+	}, {
+		// if r1 s>= r2 goto +1
+		name:  "jsge",
+		bytes: []byte{0x7d, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 1, "r2": 0xffffffff_ffffffff},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// if r1 s< -5 goto +1
+		name:  "jslt_imm",
+		bytes: []byte{0xc5, 0x01, 0x01, 0x00, 0xfb, 0xff, 0xff, 0xff},
+		regs:  map[expr.Key]uint64{"r1": 0xffffffff_fffffffa},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// if r1 s<= r2 goto +1
+		name:  "jsle",
+		bytes: []byte{0xdd, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 2, "r2": 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 8},
+	}, {
+		// if r1 & 4 goto +1
+		name:  "jset",
+		bytes: []byte{0x45, 0x01, 0x01, 0x00, 0x04, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 5},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// if w1 == w2 goto +1
+		name:  "jeq32",
+		bytes: []byte{0x1e, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0x1_00000005, "r2": 5},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// if w1 > 5 goto +1
+		name:  "jgt32_imm",
+		bytes: []byte{0x26, 0x01, 0x01, 0x00, 0x05, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0x1_00000001},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 8},
+	}, {
+		// if w1 s< w2 goto +1
+		name:  "jslt32",
+		bytes: []byte{0xce, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r1": 0x80000000},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// if w1 s< -5 goto +1
+		name:  "jslt32_imm",
+		bytes: []byte{0xc6, 0x01, 0x01, 0x00, 0xfb, 0xff, 0xff, 0xff},
+		regs:  map[expr.Key]uint64{"r1": 0xfffffffa},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// call 1
+		name:  "call_helper",
+		bytes: []byte{0x85, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
+		want:  map[expr.Key]uint64{},
+	}, {
+		// call 2
+		name:  "call_local",
+		bytes: []byte{0x85, 0x10, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00},
+		regs: map[expr.Key]uint64{
+			CallDepthKey: 1,
+			"r6":         6, "r7": 7, "r8": 8, "r9": 9, "r10": 0x3000,
+		},
+		want: map[expr.Key]uint64{
+			expr.IPKey:   testAddr + 0x18,
+			CallDepthKey: 2,
+			"r10":        0x2e00,
+		},
+		wantMem: frame(1, testAddr+8, 6, 7, 8, 9, 0x3000),
+	}, {
+		// exit
+		name:  "exit",
+		bytes: []byte{0x95, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{CallDepthKey: 1},
+		mem:   frame(0, 0x1234, 6, 7, 8, 9, 0x3000),
+		want: map[expr.Key]uint64{
+			expr.IPKey:   0x1234,
+			CallDepthKey: 0,
+			"r6":         6, "r7": 7, "r8": 8, "r9": 9, "r10": 0x3000,
+		},
+	}})
+}
+
+func TestCallTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		typ   model.Type
+	}{{
+		// call 1
+		name:  "helper",
+		bytes: []byte{0x85, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
+		typ:   model.TypeSyscall,
+	}, {
+		// call 2
+		name:  "local",
+		bytes: []byte{0x85, 0x10, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00},
+	}, {
+		// call 12345
+		name:  "kfunc",
+		bytes: []byte{0x85, 0x20, 0x00, 0x00, 0x39, 0x30, 0x00, 0x00},
+		typ:   model.TypeSyscall,
+	}, {
+		// exit
+		name:  "exit",
+		bytes: []byte{0x95, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := NewParser().Parse(testAddr, tt.bytes)
+			require.NoError(t, err)
+			require.NoError(t, ins.Validate())
+			require.Equal(t, tt.typ, ins.Type)
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package ebpf
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"mltwist/pkg/model"
+)
+
+// Access modes encoded in the highest 3 bits of the opcode byte of load and
+// store instructions.
+const (
+	modeIMM    = 0x00
+	modeMEM    = 0x60
+	modeMEMSX  = 0x80
+	modeATOMIC = 0xc0
+)
+
+// Values of the source register field of 64 bit immediate loads.
+const (
+	// pseudoConst loads the 64 bit immediate value.
+	pseudoConst = 0
+	// pseudoFunc loads address of a BPF function at PC-relative offset
+	// given by the immediate field.
+	pseudoFunc = 4
+)
+
+// atomicFetch is flag of atomic operations which return the original value of
+// the memory in the source register.
+const atomicFetch = 0x01
+
+// memSize describes size of memory accessed by load and store instructions.
+type memSize struct {
+	// suffix is the suffix of names of instructions.
+	suffix string
+	// code is the size encoded in bits 3 and 4 of the opcode byte.
+	code uint8
+	// w is the width of accessed memory.
+	w expr.Width
+}
+
+// memSizes are all sizes of memory accesses.
+var memSizes = []memSize{
+	{suffix: "w", code: 0x00, w: width32},
+	{suffix: "h", code: 0x08, w: width16},
+	{suffix: "b", code: 0x10, w: width8},
+	{suffix: "dw", code: 0x18, w: width64},
+}
+
+// memInstructions returns all instructions of LD, LDX, ST and STX classes.
+func memInstructions() []*instructionType {
+	list := []*instructionType{lddw()}
+	for _, s := range memSizes {
+		list = append(list,
+			load(s, false),
+			storeImm(s),
+			storeReg(s),
+		)
+		if s.w != width64 {
+			list = append(list, load(s, true))
+		}
+	}
+
+	return append(list, atomicInstructions()...)
+}
+
+// memAddr returns address of memory accessed by an instruction. The address is
+// value of register r with the offset field of instruction i added.
+func memAddr(i instruction, r regNum) expr.Expr {
+	return expr.NewBinary(expr.Add, regLoad(r, width64), immConst(int64(i.off), width64), width64)
+}
+
+// memOperand returns memory of width w accessed by instruction i at address in
+// register r in assembler code.
+func memOperand(i instruction, r regNum, w expr.Width, signed bool) string {
+	sign := "u"
+	if signed {
+		sign = "s"
+	}
+	return fmt.Sprintf("*(%s%d *)(%s)", sign, w.Bits(), addrOperand(i, r))
+}
+
+// addrOperand returns the address of memory accessed by instruction i at
+// address in register r in assembler code.
+func addrOperand(i instruction, r regNum) string {
+	if i.off < 0 {
+		return fmt.Sprintf("%s - %d", r.name(width64), -int64(i.off))
+	}
+	return fmt.Sprintf("%s + %d", r.name(width64), i.off)
+}
+
+// valueWidth returns width of register names holding values of memory of width
+// w. Values narrower than 64 bits are written using 32 bit register names.
+func valueWidth(w expr.Width) expr.Width {
+	if w == width64 {
+		return width64
+	}
+	return width32
+}
+
+// lddw creates the 64 bit immediate load instruction. The instruction occupies
+// two slots, the high 32 bits of the value are in the immediate field of the
+// second slot.
+func lddw() *instructionType {
+	return &instructionType{
+		name:   "lddw",
+		opcode: codeByte(classLD | modeIMM | 0x18),
+		wide:   true,
+		offsetOf: func(i instruction) offsetType {
+			if i.src() == pseudoFunc {
+				return offsetImm
+			}
+			return offsetNone
+		},
+		format: func(i instruction) string {
+			return fmt.Sprintf("%s = %d ll", i.dst().name(width64), int64(i.imm64()))
+		},
+		reserved: func(i instruction) bool {
+			switch {
+			case invalidRegs(i) || i.off != 0:
+				return true
+			case i.src() == pseudoFunc:
+				return i.immHigh != 0
+			default:
+				return i.src() != pseudoConst
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			if i.src() == pseudoFunc {
+				return []expr.Effect{regStore(addrConst(i.target()), i.dst())}
+			}
+			return []expr.Effect{regStore(expr.NewConstUint(i.imm64(), width64), i.dst())}
+		},
+	}
+}
+
+// load creates load instruction of memory of size s. The loaded value is sign
+// extended if signed is set, otherwise it's zero extended.
+func load(s memSize, signed bool) *instructionType {
+	name, mode := "ldx", uint8(modeMEM)
+	if signed {
+		name, mode = "ldxs", modeMEMSX
+	}
+
+	return &instructionType{
+		name:   name + s.suffix,
+		opcode: codeByte(classLDX | mode | s.code),
+		format: func(i instruction) string {
+			w := valueWidth(s.w)
+			if signed {
+				w = width64
+			}
+			return fmt.Sprintf("%s = %s", i.dst().name(w), memOperand(i, i.src(), s.w, signed))
+		},
+		reserved: func(i instruction) bool { return i.imm != 0 || invalidRegs(i) },
+		effects: func(i instruction) []expr.Effect {
+			val := memLoad(memAddr(i, i.src()), s.w)
+			if signed {
+				val = sext(val, s.w, width64)
+			}
+			return []expr.Effect{regStore(val, i.dst())}
+		},
+	}
+}
+
+// storeImm creates store instruction of the immediate value to memory of size
+// s. The value is truncated to the size.
+func storeImm(s memSize) *instructionType {
+	return &instructionType{
+		name:   "st" + s.suffix,
+		opcode: codeByte(classST | modeMEM | s.code),
+		format: func(i instruction) string {
+			return fmt.Sprintf("%s = %s", memOperand(i, i.dst(), s.w, false), decImm(int64(i.imm)))
+		},
+		reserved: func(i instruction) bool { return !i.dst().valid() || i.src() != 0 },
+		effects: func(i instruction) []expr.Effect {
+			val := immConst(int64(i.imm), width64)
+			return []expr.Effect{memStore(val, memAddr(i, i.dst()), s.w)}
+		},
+	}
+}
+
+// storeReg creates store instruction of a register to memory of size s.
+func storeReg(s memSize) *instructionType {
+	return &instructionType{
+		name:   "stx" + s.suffix,
+		opcode: codeByte(classSTX | modeMEM | s.code),
+		format: func(i instruction) string {
+			return fmt.Sprintf("%s = %s", memOperand(i, i.dst(), s.w, false),
+				i.src().name(valueWidth(s.w)))
+		},
+		reserved: func(i instruction) bool {
+			return !i.dst().valid() || !i.src().valid() || i.imm != 0
+		},
+		effects: func(i instruction) []expr.Effect {
+			val := regLoad(i.src(), s.w)
+			return []expr.Effect{memStore(val, memAddr(i, i.dst()), s.w)}
+		},
+	}
+}
+
+// atomicOp describes an atomic read-modify-write operation.
+type atomicOp struct {
+	// name is the name of the operation used by LLVM.
+	name string
+	// op is the operation code encoded in the immediate field.
+	op int32
+	// assign is the compound assignment operator of the operation.
+	assign string
+	// f calculates the new value of memory of width w from its value e1
+	// and from the source register e2.
+	f func(e1, e2 expr.Expr, w expr.Width) expr.Expr
+}
+
+// atomicOps are atomic operations which can be used with and without returning
+// the original value.
+var atomicOps = []atomicOp{
+	{name: "add", op: 0x00, assign: "+=", f: func(e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		return expr.NewBinary(expr.Add, e1, e2, w)
+	}},
+	{name: "or", op: 0x40, assign: "|=", f: exprtools.BitOr},
+	{name: "and", op: 0x50, assign: "&=", f: exprtools.BitAnd},
+	{name: "xor", op: 0xa0, assign: "^=", f: exprtools.BitXor},
+}
+
+// atomicInstructions returns all atomic instructions.
+//
+// Atomic operations returning the original value are fully ordered. Other
+// atomic operations don't order any memory accesses.
+func atomicInstructions() []*instructionType {
+	var list []*instructionType
+	for _, s := range memSizes {
+		if s.w != width32 && s.w != width64 {
+			continue
+		}
+
+		code := classSTX | modeATOMIC | s.code
+		for _, op := range atomicOps {
+			list = append(list, atomic(op, s, code), atomicFetchOp(op, s, code))
+		}
+		list = append(list, xchg(s, code), cmpxchg(s, code))
+	}
+
+	return list
+}
+
+// atomicName returns name of an atomic instruction called name accessing memory
+// of size s. Names of 32 bit instructions have suffix 32.
+func atomicName(name string, s memSize) string {
+	if s.w == width32 {
+		return name + "32"
+	}
+	return name
+}
+
+// atomicReserved indicates that registers of atomic instruction i are invalid.
+// The source register of instructions returning the original value must not be
+// the read-only frame pointer.
+func atomicReserved(i instruction, fetch bool) bool {
+	return !i.dst().valid() || !i.src().valid() || (fetch && i.src() == regFP)
+}
+
+// atomic creates atomic operation op on memory of size s which doesn't return
+// the original value.
+func atomic(op atomicOp, s memSize, code uint8) *instructionType {
+	return &instructionType{
+		name:   atomicName("a"+op.name, s),
+		opcode: codeImm(code, op.op),
+		format: func(i instruction) string {
+			return fmt.Sprintf("lock %s %s %s", memOperand(i, i.dst(), s.w, false),
+				op.assign, i.src().name(valueWidth(s.w)))
+		},
+		reserved: func(i instruction) bool { return atomicReserved(i, false) },
+		effects: func(i instruction) []expr.Effect {
+			addr := memAddr(i, i.dst())
+			val := op.f(memLoad(addr, s.w), regLoad(i.src(), s.w), s.w)
+			return []expr.Effect{memStore(val, addr, s.w)}
+		},
+	}
+}
+
+// atomicFetchOp creates atomic operation op on memory of size s which returns
+// the original value in the source register.
+func atomicFetchOp(op atomicOp, s memSize, code uint8) *instructionType {
+	return &instructionType{
+		name:   atomicName("af"+op.name, s),
+		opcode: codeImm(code, op.op|atomicFetch),
+		format: func(i instruction) string {
+			r := i.src().name(valueWidth(s.w))
+			return fmt.Sprintf("%s = atomic_fetch_%s((u%d *)(%s), %s)",
+				r, op.name, s.w.Bits(), addrOperand(i, i.dst()), r)
+		},
+		reserved:  func(i instruction) bool { return atomicReserved(i, true) },
+		instrType: model.TypeMemOrder,
+		effects: func(i instruction) []expr.Effect {
+			addr := memAddr(i, i.dst())
+			old := memLoad(addr, s.w)
+			return []expr.Effect{
+				memStore(op.f(old, regLoad(i.src(), s.w), s.w), addr, s.w),
+				regStore(old, i.src()),
+			}
+		},
+	}
+}
+
+// xchg creates atomic exchange of memory of size s with the source register.
+func xchg(s memSize, code uint8) *instructionType {
+	return &instructionType{
+		name:   atomicName("axchg", s),
+		opcode: codeImm(code, 0xe0|atomicFetch),
+		format: func(i instruction) string {
+			r := i.src().name(valueWidth(s.w))
+			return fmt.Sprintf("%s = %s_%d(%s, %s)",
+				r, atomicName("xchg", s), s.w.Bits(), addrOperand(i, i.dst()), r)
+		},
+		reserved:  func(i instruction) bool { return atomicReserved(i, true) },
+		instrType: model.TypeMemOrder,
+		effects: func(i instruction) []expr.Effect {
+			addr := memAddr(i, i.dst())
+			return []expr.Effect{
+				memStore(regLoad(i.src(), s.w), addr, s.w),
+				regStore(memLoad(addr, s.w), i.src()),
+			}
+		},
+	}
+}
+
+// cmpxchg creates atomic compare and exchange of memory of size s. The memory is
+// compared with register r0 and it's replaced by the source register if they
+// are equal. The original value of the memory is returned in r0.
+func cmpxchg(s memSize, code uint8) *instructionType {
+	return &instructionType{
+		name:   atomicName("acmp", s),
+		opcode: codeImm(code, 0xf0|atomicFetch),
+		format: func(i instruction) string {
+			w := valueWidth(s.w)
+			return fmt.Sprintf("%s = %s_%d(%s, %s, %s)",
+				regRet.name(w), atomicName("cmpxchg", s), s.w.Bits(),
+				addrOperand(i, i.dst()), regRet.name(w), i.src().name(w))
+		},
+		reserved:  func(i instruction) bool { return atomicReserved(i, false) },
+		instrType: model.TypeMemOrder,
+		effects: func(i instruction) []expr.Effect {
+			addr := memAddr(i, i.dst())
+			old := memLoad(addr, s.w)
+			val := exprtools.Eq(old, regLoad(regRet, s.w), regLoad(i.src(), s.w), old, s.w)
+			return []expr.Effect{
+				memStore(val, addr, s.w),
+				regStore(old, regRet),
+			}
+		},
+	}
+}
@@ -0,0 +1,184 @@
+package ebpf
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// r1 = 1234605616436508552 ll
+		name: "lddw",
+		bytes: []byte{
+			0x18, 0x01, 0x00, 0x00, 0x88, 0x77, 0x66, 0x55,
+			0x00, 0x00, 0x00, 0x00, 0x44, 0x33, 0x22, 0x11,
+		},
+		want: map[expr.Key]uint64{"r1": 0x11223344_55667788},
+	}, {
+		// r1 = 2 ll
+		name: "lddw_func",
+		bytes: []byte{
+			0x18, 0x41, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+		want: map[expr.Key]uint64{"r1": testAddr + 0x18},
+	}, {
+		// r1 = *(u64 *)(r2 + 8)
+		name:  "ldxdw",
+		bytes: []byte{0x79, 0x21, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r2": 0x2000},
+		want:  map[expr.Key]uint64{"r1": 0x8f8e8d8c_8b8a8988},
+	}, {
+		// w1 = *(u32 *)(r2 + 4)
+		name:  "ldxw",
+		bytes: []byte{0x61, 0x21, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r2": 0x2000},
+		want:  map[expr.Key]uint64{"r1": 0x87868584},
+	}, {
+		// w1 = *(u16 *)(r10 - 2)
+		name:  "ldxh_negative_offset",
+		bytes: []byte{0x69, 0xa1, 0xfe, 0xff, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r10": 0x2002},
+		want:  map[expr.Key]uint64{"r1": 0x8180},
+	}, {
+		// w1 = *(u8 *)(r2 + 0)
+		name:  "ldxb",
+		bytes: []byte{0x71, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r2": 0x2000},
+		want:  map[expr.Key]uint64{"r1": 0x80},
+	}, {
+		// r1 = *(s8 *)(r2 + 1)
+		name:  "ldxsb",
+		bytes: []byte{0x91, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r2": 0x2000},
+		want:  map[expr.Key]uint64{"r1": 0xffffffff_ffffff81},
+	}, {
+		// r1 = *(s32 *)(r2 + 0)
+		name:  "ldxsw",
+		bytes: []byte{0x81, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"r2": 0x2000},
+		want:  map[expr.Key]uint64{"r1": 0xffffffff_83828180},
+	}, {
+		// *(u32 *)(r10 - 4) = 5
+		name:    "stw",
+		bytes:   []byte{0x62, 0x0a, 0xfc, 0xff, 0x05, 0x00, 0x00, 0x00},
+		regs:    map[expr.Key]uint64{"r10": 0x3000},
+		want:    map[expr.Key]uint64{},
+		wantMem: memBytes(0x2ffc, 0x05, 0x00, 0x00, 0x00),
+	}, {
+		// *(u8 *)(r10 - 1) = -1
+		name:    "stb_negative",
+		bytes:   []byte{0x72, 0x0a, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		regs:    map[expr.Key]uint64{"r10": 0x3000},
+		want:    map[expr.Key]uint64{},
+		wantMem: memBytes(0x2fff, 0xff),
+	}, {
+		// *(u64 *)(r10 - 8) = r1
+		name:    "stxdw",
+		bytes:   []byte{0x7b, 0x1a, 0xf8, 0xff, 0x00, 0x00, 0x00, 0x00},
+		regs:    map[expr.Key]uint64{"r1": 0x11223344_55667788, "r10": 0x3000},
+		want:    map[expr.Key]uint64{},
+		wantMem: memBytes(0x2ff8, 0x88, 0x77, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11),
+	}, {
+		// *(u16 *)(r1 + 2) = w2
+		name:    "stxh",
+		bytes:   []byte{0x6b, 0x21, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:    map[expr.Key]uint64{"r1": 0x3000, "r2": 0x11223344},
+		want:    map[expr.Key]uint64{},
+		wantMem: memBytes(0x3002, 0x44, 0x33),
+	}, {
+		// lock *(u64 *)(r1 + 0) += r2
+		name:    "atomic_add",
+		bytes:   []byte{0xdb, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		regs:    map[expr.Key]uint64{"r1": 0x2000, "r2": 1},
+		want:    map[expr.Key]uint64{},
+		wantMem: memBytes(0x2000, 0x81, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87),
+	}, {
+		// lock *(u32 *)(r1 + 0) |= w2
+		name:    "atomic_or32",
+		bytes:   []byte{0xc3, 0x21, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00},
+		regs:    map[expr.Key]uint64{"r1": 0x2000, "r2": 0x0f},
+		want:    map[expr.Key]uint64{},
+		wantMem: memBytes(0x2000, 0x8f, 0x81, 0x82, 0x83),
+	}, {
+		// r2 = atomic_fetch_and((u64 *)(r1 + 0), r2)
+		name:    "atomic_fetch_and",
+		bytes:   []byte{0xdb, 0x21, 0x00, 0x00, 0x51, 0x00, 0x00, 0x00},
+		regs:    map[expr.Key]uint64{"r1": 0x2000, "r2": 0xff},
+		want:    map[expr.Key]uint64{"r2": 0x87868584_83828180},
+		wantMem: memBytes(0x2000, 0x80, 0, 0, 0, 0, 0, 0, 0),
+	}, {
+		// w2 = atomic_fetch_xor((u32 *)(r1 + 0), w2)
+		name:    "atomic_fetch_xor32",
+		bytes:   []byte{0xc3, 0x21, 0x00, 0x00, 0xa1, 0x00, 0x00, 0x00},
+		regs:    map[expr.Key]uint64{"r1": 0x2000, "r2": 0x1_000000ff},
+		want:    map[expr.Key]uint64{"r2": 0x83828180},
+		wantMem: memBytes(0x2000, 0x7f, 0x81, 0x82, 0x83),
+	}, {
+		// r2 = xchg_64(r1 + 0, r2)
+		name:    "xchg",
+		bytes:   []byte{0xdb, 0x21, 0x00, 0x00, 0xe1, 0x00, 0x00, 0x00},
+		regs:    map[expr.Key]uint64{"r1": 0x2000, "r2": 1},
+		want:    map[expr.Key]uint64{"r2": 0x87868584_83828180},
+		wantMem: memBytes(0x2000, 0x01, 0, 0, 0, 0, 0, 0, 0),
+	}, {
+		// r0 = cmpxchg_64(r1 + 8, r0, r2)
+		name:  "cmpxchg_equal",
+		bytes: []byte{0xdb, 0x21, 0x08, 0x00, 0xf1, 0x00, 0x00, 0x00},
+		regs: map[expr.Key]uint64{
+			"r0": 0x8f8e8d8c_8b8a8988,
+			"r1": 0x2000,
+			"r2": 5,
+		},
+		want:    map[expr.Key]uint64{"r0": 0x8f8e8d8c_8b8a8988},
+		wantMem: memBytes(0x2008, 0x05, 0, 0, 0, 0, 0, 0, 0),
+	}, {
+		// w0 = cmpxchg32_32(r1 + 0, w0, w2)
+		name:    "cmpxchg32_not_equal",
+		bytes:   []byte{0xc3, 0x21, 0x00, 0x00, 0xf1, 0x00, 0x00, 0x00},
+		regs:    map[expr.Key]uint64{"r1": 0x2000, "r2": 5},
+		want:    map[expr.Key]uint64{"r0": 0x83828180},
+		wantMem: memBytes(0x2000, 0x80, 0x81, 0x82, 0x83),
+	}})
+}
+
+func TestAtomicTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		typ   model.Type
+	}{{
+		// lock *(u64 *)(r1 + 0) += r2
+		name:  "atomic_add",
+		bytes: []byte{0xdb, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	}, {
+		// w2 = atomic_fetch_add((u32 *)(r1 + 0), w2)
+		name:  "atomic_fetch_add",
+		bytes: []byte{0xc3, 0x21, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
+		typ:   model.TypeMemOrder,
+	}, {
+		// r2 = xchg_64(r1 + 0, r2)
+		name:  "xchg",
+		bytes: []byte{0xdb, 0x21, 0x00, 0x00, 0xe1, 0x00, 0x00, 0x00},
+		typ:   model.TypeMemOrder,
+	}, {
+		// r0 = cmpxchg_64(r1 + 0, r0, r2)
+		name:  "cmpxchg",
+		bytes: []byte{0xdb, 0x21, 0x00, 0x00, 0xf1, 0x00, 0x00, 0x00},
+		typ:   model.TypeMemOrder,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := NewParser().Parse(testAddr, tt.bytes)
+			require.NoError(t, err)
+			require.NoError(t, ins.Validate())
+			require.Equal(t, tt.typ, ins.Type)
+			require.Equal(t, model.MemOrder{}, ins.Order)
+		})
+	}
+}
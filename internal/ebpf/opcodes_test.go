@@ -0,0 +1,99 @@
+package ebpf
+
+import (
+	"mltwist/internal/archtest"
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstructions(t *testing.T) {
+	archtest.Instructions(t, instructions, (*instructionType).validate)
+}
+
+// testAddr is address instructions are parsed at in tests.
+const testAddr = 0x1000
+
+// testMem returns memory of tests: 16 bytes at address 0x2000 with values
+// 0x80, 0x81 etc.
+func testMem() map[uint64]byte {
+	var bs []byte
+	for i := 0; i < 16; i++ {
+		bs = append(bs, byte(0x80+i))
+	}
+	return memBytes(0x2000, bs...)
+}
+
+// memBytes returns memory with bytes bs starting at address a.
+func memBytes(a uint64, bs ...byte) map[uint64]byte {
+	m := make(map[uint64]byte, len(bs))
+	for i, b := range bs {
+		m[a+uint64(i)] = b
+	}
+	return m
+}
+
+// frame returns memory of a record of the call stack at depth depth with return
+// address ret followed by values of saved registers regs.
+func frame(depth uint64, ret uint64, regs ...uint64) map[uint64]byte {
+	m := map[uint64]byte{}
+	for j, v := range append([]uint64{ret}, regs...) {
+		for k := 0; k < 8; k++ {
+			m[depth*frameLen+uint64(8*j+k)] = byte(v >> (8 * k))
+		}
+	}
+	return m
+}
+
+// evalEffects evaluates effects of an instruction encoded in bs at testAddr
+// with registers set to regs and memory set to mem. Registers and bytes of
+// memory not present in regs or mem are zero. It returns values of all
+// registers and bytes of memory the instruction writes.
+//
+// Memory address spaces are not distinguished, so the call stack shares mem
+// with the main memory.
+func evalEffects(
+	t testing.TB,
+	bs []byte,
+	regs map[expr.Key]uint64,
+	mem map[uint64]byte,
+) (map[expr.Key]uint64, map[uint64]byte) {
+	ins, err := NewParser().Parse(testAddr, bs)
+	require.NoError(t, err)
+	return archtest.EvalEffects(t, ins, regs, mem)
+}
+
+// evalTest is a test case of effects of an instruction.
+type evalTest struct {
+	name  string
+	bytes []byte
+	regs  map[expr.Key]uint64
+	// mem is the memory of the test. Memory returned by testMem is used
+	// if mem is nil.
+	mem     map[uint64]byte
+	want    map[expr.Key]uint64
+	wantMem map[uint64]byte
+}
+
+// runEvalTests evaluates effects of instructions of tests and compares them
+// with expected registers and memory.
+func runEvalTests(t *testing.T, tests []evalTest) {
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mem := tt.mem
+			if mem == nil {
+				mem = testMem()
+			}
+
+			regs, m := evalEffects(t, tt.bytes, tt.regs, mem)
+			require.Equal(t, tt.want, regs)
+
+			if tt.wantMem == nil {
+				tt.wantMem = map[uint64]byte{}
+			}
+			require.Equal(t, tt.wantMem, m)
+		})
+	}
+}
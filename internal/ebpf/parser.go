@@ -0,0 +1,87 @@
+package ebpf
+
+import (
+	"fmt"
+	"mltwist/internal/opcode"
+	"mltwist/pkg/model"
+)
+
+// Parser parses little-endian eBPF instructions.
+//
+// Legacy packet access instructions (LD class with ABS and IND modes) are not
+// supported.
+type Parser struct {
+	matcher *opcode.Matcher[*instructionType]
+}
+
+// NewParser creates a new eBPF instruction parser.
+func NewParser() Parser {
+	decoder, err := opcode.NewMatcher(instructions)
+
+	// This means that instruction opcodes defined in this package are
+	// either invalid or they collide. This is non-recoverable as the
+	// package code has to be modified.
+	if err != nil {
+		panic(fmt.Sprintf("bug: matcher creation failed: %s", err.Error()))
+	}
+
+	return Parser{matcher: decoder}
+}
+
+// Parse parses an instruction starting at address a comprising of bytes at the
+// beginning of bs.
+//
+// The array of bytes bs is allowed to be longer than the instruction. In such a
+// case the Parse method will take into consideration only those bytes at the
+// beginning of the array which represent a single instruction. Instructions
+// are 8 bytes long except of the 64 bit immediate load which is 16 bytes long.
+func (p Parser) Parse(a model.Addr, bs []byte) (model.Instruction, error) {
+	if l := len(bs); l < slotLen {
+		return model.Instruction{}, fmt.Errorf(
+			"bytes are too short to be an eBPF instruction opcode: %d", l)
+	}
+
+	opcode, ok := p.matcher.Match(bs[:slotLen])
+	if !ok {
+		return model.Instruction{}, fmt.Errorf(
+			"unknown instruction opcode: 0x%x", bs[:slotLen])
+	}
+
+	l := opcode.len()
+	if model.Addr(len(bs)) < l {
+		return model.Instruction{}, fmt.Errorf(
+			"bytes are too short to be %s instruction: %d", opcode.name, len(bs))
+	}
+	if opcode.wide && !zeroSlot(bs[slotLen:slotLen+4]) {
+		return model.Instruction{}, fmt.Errorf(
+			"invalid second slot of %s: 0x%x", opcode.name, bs[slotLen:l])
+	}
+
+	instr := newInstruction(a, bs[:l], opcode)
+	if r := opcode.reserved; r != nil && r(instr) {
+		return model.Instruction{}, fmt.Errorf(
+			"invalid encoding of %s: 0x%x", opcode.name, bs[:l])
+	}
+
+	return model.Instruction{
+		Type:    opcode.typeOfInstr(instr),
+		ByteLen: l,
+
+		Effects: opcode.effects(instr),
+		Details: instr,
+	}, nil
+}
+
+// zeroSlot indicates that all bytes of bs are zero. Only the immediate field of
+// the second slot of wide instructions is allowed to be non-zero.
+func zeroSlot(bs []byte) bool {
+	for _, b := range bs {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MinInstrLen returns length of the shortest eBPF instruction in bytes.
+func (Parser) MinInstrLen() model.Addr { return slotLen }
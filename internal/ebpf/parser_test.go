@@ -0,0 +1,122 @@
+package ebpf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewParser(_ *testing.T) {
+	NewParser()
+}
+
+func TestParser_Parse(t *testing.T) {
+	tests := []struct {
+		name   string
+		bytes  []byte
+		len    int
+		hasErr bool
+	}{{
+		// r1 += r2
+		name:  "valid",
+		bytes: []byte{0x0f, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		len:   8,
+	}, {
+		// r1 += r2
+		name:  "longer_bytes",
+		bytes: []byte{0x0f, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff},
+		len:   8,
+	}, {
+		// r1 = 1 ll
+		name: "wide",
+		bytes: []byte{
+			0x18, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+		len: 16,
+	}, {
+		name:   "too_short",
+		bytes:  []byte{0x0f, 0x21, 0x00, 0x00},
+		hasErr: true,
+	}, {
+		// r1 = 1 ll
+		name:   "wide_too_short",
+		bytes:  []byte{0x18, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
+		hasErr: true,
+	}, {
+		name: "wide_invalid_second_slot",
+		bytes: []byte{
+			0x18, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+			0x0f, 0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+		hasErr: true,
+	}, {
+		// r0 = *(u32 *)skb[0]
+		name:   "unknown",
+		bytes:  []byte{0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		hasErr: true,
+	}, {
+		// lddw with map file descriptor
+		name: "lddw_map_fd",
+		bytes: []byte{
+			0x18, 0x11, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+		hasErr: true,
+	}, {
+		// r10 = 1
+		name:   "write_frame_pointer",
+		bytes:  []byte{0xb7, 0x0a, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
+		hasErr: true,
+	}, {
+		// r11 += r2
+		name:   "invalid_register",
+		bytes:  []byte{0x0f, 0x2b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		hasErr: true,
+	}, {
+		// r1 += r2 with signed offset
+		name:   "reserved_offset",
+		bytes:  []byte{0x0f, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00},
+		hasErr: true,
+	}, {
+		// w1 = (s32)w2
+		name:   "reserved_movsx32",
+		bytes:  []byte{0xbc, 0x21, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00},
+		hasErr: true,
+	}, {
+		// r1 = be8 r1
+		name:   "reserved_byte_swap",
+		bytes:  []byte{0xdc, 0x01, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00},
+		hasErr: true,
+	}, {
+		// call with unknown source
+		name:   "reserved_call",
+		bytes:  []byte{0x85, 0x30, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
+		hasErr: true,
+	}, {
+		// r10 = atomic_fetch_add((u64 *)(r1 + 0), r10)
+		name:   "reserved_atomic_fetch",
+		bytes:  []byte{0xdb, 0xa1, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
+		hasErr: true,
+	}, {
+		// atomic with unknown operation
+		name:   "unknown_atomic",
+		bytes:  []byte{0xdb, 0x21, 0x00, 0x00, 0x20, 0x00, 0x00, 0x00},
+		hasErr: true,
+	}}
+
+	p := NewParser()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := p.Parse(testAddr, tt.bytes)
+			if tt.hasErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.len, int(ins.ByteLen))
+			require.NoError(t, ins.Validate())
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package ebpf
+
+import (
+	"fmt"
+	"mltwist/internal/state"
+	"mltwist/pkg/expr"
+)
+
+const (
+	// regCnt is number of eBPF registers.
+	regCnt = 11
+
+	// regRet is number of the register holding return values of functions
+	// and helpers (r0).
+	regRet regNum = 0
+	// regFP is number of the read-only frame pointer register (r10).
+	regFP regNum = 10
+)
+
+// CallDepthKey identifies register holding number of active BPF to BPF calls.
+//
+// The register is not a real eBPF register. It indexes records of the call
+// stack addressed by CallStackKey. It's zero when a program starts.
+const CallDepthKey = expr.Key("call_depth")
+
+// ResetState stores state of registers right before a program starts into
+// regs. No BPF to BPF call is active, all other registers are left unknown.
+func ResetState(regs *state.RegMap) {
+	regs.Store(CallDepthKey, expr.Zero, width64)
+}
+
+// regNum represents an eBPF register number. Range of valid values is [0..10]
+// (i.e. [0..regCnt-1]).
+type regNum uint8
+
+// valid indicates that r is a number of an existing register. Register fields
+// of instructions are 4 bits wide, so they can encode numbers which don't
+// refer any register.
+func (r regNum) valid() bool { return r < regCnt }
+
+// key returns key of register r.
+//
+// Registers are identified by names of their 64 bit views. Writes to 32 bit
+// views of registers clear the upper half of the register, so there is no
+// reason to distinguish the views.
+func (r regNum) key() expr.Key { return expr.Key(fmt.Sprintf("r%d", r)) }
+
+// name returns name of view of width w of register r in assembler code.
+func (r regNum) name(w expr.Width) string {
+	if w == width64 {
+		return fmt.Sprintf("r%d", r)
+	}
+	return fmt.Sprintf("w%d", r)
+}
+
+// regLoad loads w bytes of register r.
+func regLoad(r regNum, w expr.Width) expr.Expr {
+	return expr.NewRegLoad(r.key(), w)
+}
+
+// regStore stores e to register r. The whole register is written, so value
+// narrower than the register is zero extended.
+func regStore(e expr.Expr, r regNum) expr.Effect {
+	return expr.NewRegStore(e, r.key(), width64)
+}
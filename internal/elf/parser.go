@@ -9,27 +9,133 @@ import (
 
 type Parser struct {
 	f *elf.File
+
+	// filename is name of the file the parser reads. The name is necessary
+	// to be able to create a modified copy of the file.
+	filename string
+
+	// base is load base of a position-independent file. All addresses in
+	// the file are shifted by base. The base is always zero for
+	// executable files with fixed addresses.
+	base model.Addr
+
+	// sectionAddrs are addresses of sections of a relocatable file
+	// indexed by section index. Sections of relocatable files have no
+	// addresses assigned, so the parser places every allocated section
+	// behind the previous one. The slice is nil for other file types.
+	sectionAddrs []model.Addr
 }
 
-func NewParser(filename string) (*Parser, error) {
+// relocatableStart is the address of the first allocated section of a
+// relocatable file. Zero address is not represented in program memory address
+// space, so the sections are placed behind it.
+const relocatableStart model.Addr = 0x1000
+
+// NewParser opens ELF file called filename. The file has to be either an
+// executable (ET_EXEC), a position-independent file (ET_DYN) - i.e. a
+// position-independent executable or a shared object - or a relocatable
+// object (ET_REL) such as a compiled BPF program.
+//
+// Position-independent files are loaded at address base. In other words, all
+// addresses in the file are shifted by base and all relative relocations of the
+// file are applied to the program memory. As executable files are always
+// loaded at fixed addresses, base must be zero for those.
+//
+// Allocated sections of relocatable files are laid out one after another
+// starting at address base+0x1000 in the order of section headers. Relocations
+// of relocatable files are not applied.
+func NewParser(filename string, base model.Addr) (*Parser, error) {
 	f, err := elf.Open(filename)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open file %q: %w", filename, err)
 	}
 
-	if f.Type&elf.ET_EXEC == 0 {
-		return nil, fmt.Errorf("file %q is not an executable ELF file", filename)
+	var sectionAddrs []model.Addr
+	switch f.Type {
+	case elf.ET_EXEC:
+		if base != 0 {
+			f.Close()
+			return nil, fmt.Errorf(
+				"executable %q cannot be loaded at base 0x%x",
+				filename, base)
+		}
+	case elf.ET_DYN:
+	case elf.ET_REL:
+		sectionAddrs = layoutSections(f.Sections)
+	default:
+		f.Close()
+		return nil, fmt.Errorf("file %q is neither an executable, a "+
+			"position-independent nor a relocatable ELF file: %v",
+			filename, f.Type)
 	}
 
-	return &Parser{f: f}, nil
+	return &Parser{
+		f:            f,
+		filename:     filename,
+		base:         base,
+		sectionAddrs: sectionAddrs,
+	}, nil
 }
 
-func (p *Parser) Entrypoint() model.Addr { return model.Addr(p.f.Entry) }
+// layoutSections assigns consecutive addresses to all allocated sections of a
+// relocatable file. Each section is aligned as required by its header.
+func layoutSections(sections []*elf.Section) []model.Addr {
+	addrs := make([]model.Addr, len(sections))
+	next := relocatableStart
+	for i, s := range sections {
+		if s.Flags&elf.SHF_ALLOC == 0 || s.Size == 0 {
+			continue
+		}
 
+		if align := model.Addr(s.Addralign); align > 1 {
+			next = (next + align - 1) &^ (align - 1)
+		}
+
+		addrs[i] = next
+		next += model.Addr(s.Size)
+	}
+
+	return addrs
+}
+
+// sectionAddr returns address of i-th section in the original address space of
+// the file - i.e. without the load base. Zero address means that the section is
+// not mapped into program memory.
+func (p *Parser) sectionAddr(i int) model.Addr {
+	if p.sectionAddrs != nil {
+		return p.sectionAddrs[i]
+	}
+	return model.Addr(p.f.Sections[i].Addr)
+}
+
+// Entrypoint returns address of program entrypoint. Relocatable files have no
+// entrypoint, so the address of the first section containing machine code is
+// returned for those.
+func (p *Parser) Entrypoint() model.Addr {
+	if p.f.Type != elf.ET_REL {
+		return p.base + model.Addr(p.f.Entry)
+	}
+
+	for i, s := range p.f.Sections {
+		if addr := p.sectionAddr(i); !skipMachineCodeSection(s, addr) {
+			return p.base + addr
+		}
+	}
+	return 0
+}
+
+// MachineCode returns content of all sections of the file containing machine
+// code.
+//
+// Unlike Memory method, this method doesn't apply relocations. The reason is
+// that machine code of position-independent files is not supposed to be
+// relocated and that bytes of the code have to correspond to the file content
+// to be able to write the code back to the file.
 func (p *Parser) MachineCode() (*Memory, error) {
 	var blocks []Block
-	for _, s := range p.f.Sections {
-		if skipMachineCodeSection(s) {
+	for i, s := range p.f.Sections {
+		addr := p.sectionAddr(i)
+		if skipMachineCodeSection(s, addr) {
 			continue
 		}
 		data, err := s.Data()
@@ -43,39 +149,84 @@ func (p *Parser) MachineCode() (*Memory, error) {
 				s.Size, len(data))
 		}
 
-		b := newBlock(model.Addr(s.Addr), data)
+		b := newBlock(p.base+addr, data)
 		blocks = append(blocks, b)
 	}
 
 	return nonEmptyMemory(blocks)
 }
 
+// Memory returns program memory image of the file. Relative relocations of
+// position-independent files are applied to the image.
+//
+// Relocatable files have no program headers, so the image of those consists of
+// all allocated sections.
 func (p *Parser) Memory() (*Memory, error) {
+	if p.f.Type == elf.ET_REL {
+		return p.sectionMemory()
+	}
+
 	var blocks []Block
-	for _, p := range p.f.Progs {
-		if p.Type != elf.PT_LOAD {
+	for _, prog := range p.f.Progs {
+		if prog.Type != elf.PT_LOAD {
 			continue
 		}
 
-		if p.Memsz < p.Filesz {
+		if prog.Memsz < prog.Filesz {
 			return nil, fmt.Errorf(
 				"program section in memory less then in file: %d < %d",
-				p.Memsz, p.Filesz)
+				prog.Memsz, prog.Filesz)
 		}
 
-		data, err := io.ReadAll(p.Open())
+		data, err := io.ReadAll(prog.Open())
 		if err != nil {
 			return nil, fmt.Errorf("cannot read program section: %w", err)
 		}
 
-		if missing := p.Memsz - uint64(len(data)); missing > 0 {
+		if missing := prog.Memsz - uint64(len(data)); missing > 0 {
 			data = append(data, make([]byte, missing)...)
 		}
 
-		b := newBlock(model.Addr(p.Vaddr), data)
+		b := newBlock(p.base+model.Addr(prog.Vaddr), data)
 		blocks = append(blocks, b)
 	}
 
+	mem, err := nonEmptyMemory(blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.relocate(mem); err != nil {
+		return nil, fmt.Errorf("cannot apply relocations: %w", err)
+	}
+
+	return mem, nil
+}
+
+// sectionMemory returns program memory image composed of allocated sections of
+// a relocatable file. Sections without content in the file (SHT_NOBITS) are
+// filled with zeros.
+func (p *Parser) sectionMemory() (*Memory, error) {
+	var blocks []Block
+	for i, s := range p.f.Sections {
+		addr := p.sectionAddr(i)
+		if addr == 0 {
+			continue
+		}
+
+		data := make([]byte, s.Size)
+		if s.Type != elf.SHT_NOBITS {
+			var err error
+			data, err = s.Data()
+			if err != nil {
+				return nil, fmt.Errorf("cannot read section %q: %w",
+					s.Name, err)
+			}
+		}
+
+		blocks = append(blocks, newBlock(p.base+addr, data))
+	}
+
 	return nonEmptyMemory(blocks)
 }
 
@@ -92,7 +243,9 @@ func nonEmptyMemory(blocks []Block) (*Memory, error) {
 	return mem, nil
 }
 
-func skipMachineCodeSection(s *elf.Section) bool {
+// skipMachineCodeSection indicates that section s placed at address addr
+// doesn't contain machine code mapped into program memory.
+func skipMachineCodeSection(s *elf.Section, addr model.Addr) bool {
 	if s.Type != elf.SHT_PROGBITS || s.Size == 0 {
 		return true
 	}
@@ -102,7 +255,7 @@ func skipMachineCodeSection(s *elf.Section) bool {
 	// byte should reside.  Otherwise, the member contains zero".
 	// Consequentlyzero address is not represented in program memory address
 	// space.
-	if s.Addr == 0 {
+	if addr == 0 {
 		return true
 	}
 
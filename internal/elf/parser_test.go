@@ -0,0 +1,133 @@
+package elf
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		typ    elf.Type
+		base   model.Addr
+		hasErr bool
+	}{{
+		name: "executable",
+		typ:  elf.ET_EXEC,
+	}, {
+		name:   "executable_base",
+		typ:    elf.ET_EXEC,
+		base:   0x1000,
+		hasErr: true,
+	}, {
+		name: "position_independent",
+		typ:  elf.ET_DYN,
+	}, {
+		name: "position_independent_base",
+		typ:  elf.ET_DYN,
+		base: 0x1000,
+	}, {
+		name: "relocatable",
+		typ:  elf.ET_REL,
+	}, {
+		name:   "core",
+		typ:    elf.ET_CORE,
+		hasErr: true,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			f := testExecutable()
+			f.typ = tt.typ
+
+			p, err := NewParser(f.write(t), tt.base)
+			if tt.hasErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, p.Close())
+		})
+	}
+}
+
+func TestParser_PositionIndependent(t *testing.T) {
+	const base model.Addr = 0x4000_0000
+
+	r := require.New(t)
+
+	f := testPIE(t)
+	p, err := NewParser(f.write(t), base)
+	r.NoError(err)
+	defer p.Close()
+
+	r.Equal(base+0x1000, p.Entrypoint())
+
+	code, err := p.MachineCode()
+	r.NoError(err)
+	r.Len(code.Blocks, 1)
+	r.Equal(base+0x1000, code.Blocks[0].Begin())
+	r.Equal(f.sections[0].data, code.Blocks[0].Bytes())
+
+	mem, err := p.Memory()
+	r.NoError(err)
+	r.Len(mem.Blocks, 3)
+	r.Equal(base+0x1000, mem.Blocks[0].Begin())
+	r.Equal(base+0x2000, mem.Blocks[1].Begin())
+	r.Equal(base+0x3000, mem.Blocks[2].Begin())
+
+	data := mem.Address(base + 0x2000)
+	r.Equal(uint64(base+0x1004), binary.LittleEndian.Uint64(data[0:]))
+	// Relocations requiring dynamic linker are left untouched.
+	r.Equal(uint64(0), binary.LittleEndian.Uint64(data[8:]))
+	r.Equal(uint64(base+0x2000), binary.LittleEndian.Uint64(data[16:]))
+}
+
+func TestParser_Relocatable(t *testing.T) {
+	tests := []struct {
+		name string
+		base model.Addr
+	}{{
+		name: "no_base",
+	}, {
+		name: "base",
+		base: 0x4000_0000,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			f := testRelocatable()
+			p, err := NewParser(f.write(t), tt.base)
+			r.NoError(err)
+			defer p.Close()
+
+			r.Equal(tt.base+0x1000, p.Entrypoint())
+
+			code, err := p.MachineCode()
+			r.NoError(err)
+			r.Len(code.Blocks, 2)
+			r.Equal(tt.base+0x1000, code.Blocks[0].Begin())
+			r.Equal(f.sections[0].data, code.Blocks[0].Bytes())
+			r.Equal(tt.base+0x1010, code.Blocks[1].Begin())
+			r.Equal(f.sections[1].data, code.Blocks[1].Bytes())
+
+			mem, err := p.Memory()
+			r.NoError(err)
+			r.Len(mem.Blocks, 4)
+			r.Equal(tt.base+0x1018, mem.Blocks[2].Begin())
+			r.Equal(f.sections[2].data, mem.Blocks[2].Bytes())
+			// Sections are aligned as required by their headers.
+			r.Equal(tt.base+0x101c, mem.Blocks[3].Begin())
+			r.Equal(make([]byte, 8), mem.Blocks[3].Bytes())
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package elf
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"mltwist/pkg/model"
+)
+
+// relativeRelocs maps ELF machine type to type of relocation which adjusts a
+// value by the load base of a position-independent file.
+//
+// Relative relocation is the only relocation type which can be applied without
+// dynamic linker as it doesn't refer any symbol.
+var relativeRelocs = map[elf.Machine]uint32{
+	elf.EM_RISCV:   uint32(elf.R_RISCV_RELATIVE),
+	elf.EM_AARCH64: uint32(elf.R_AARCH64_RELATIVE),
+	elf.EM_X86_64:  uint32(elf.R_X86_64_RELATIVE),
+}
+
+// relocation is a single relative relocation.
+type relocation struct {
+	// offset is address of relocated value in the original address space
+	// of the file.
+	offset uint64
+	// addend is the value relative to load base.
+	addend int64
+}
+
+// relocations reads all relative relocations from dynamic relocation sections.
+//
+// Relocations of other types than relative are ignored as those require
+// dynamic linking.
+func (p *Parser) relocations() ([]relocation, error) {
+	relType, ok := relativeRelocs[p.f.Machine]
+	if !ok {
+		return nil, nil
+	}
+
+	var relocs []relocation
+	for _, s := range p.f.Sections {
+		if s.Type != elf.SHT_RELA || s.Flags&elf.SHF_ALLOC == 0 {
+			continue
+		}
+
+		data, err := s.Data()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read section %q: %w", s.Name, err)
+		}
+
+		rs, err := p.parseRela(data, relType)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse section %q: %w", s.Name, err)
+		}
+
+		relocs = append(relocs, rs...)
+	}
+
+	return relocs, nil
+}
+
+// parseRela parses all relocations of type relType from content of SHT_RELA
+// section.
+func (p *Parser) parseRela(data []byte, relType uint32) ([]relocation, error) {
+	r := bytes.NewReader(data)
+
+	var relocs []relocation
+	for r.Len() > 0 {
+		var reloc relocation
+		var typ uint32
+
+		switch p.f.Class {
+		case elf.ELFCLASS32:
+			var rela elf.Rela32
+			if err := binary.Read(r, p.f.ByteOrder, &rela); err != nil {
+				return nil, fmt.Errorf("cannot read relocation: %w", err)
+			}
+
+			typ = elf.R_TYPE32(rela.Info)
+			reloc = relocation{
+				offset: uint64(rela.Off),
+				addend: int64(rela.Addend),
+			}
+		case elf.ELFCLASS64:
+			var rela elf.Rela64
+			if err := binary.Read(r, p.f.ByteOrder, &rela); err != nil {
+				return nil, fmt.Errorf("cannot read relocation: %w", err)
+			}
+
+			typ = elf.R_TYPE64(rela.Info)
+			reloc = relocation{
+				offset: rela.Off,
+				addend: rela.Addend,
+			}
+		default:
+			return nil, fmt.Errorf("unsupported ELF class: %v", p.f.Class)
+		}
+
+		if typ == relType {
+			relocs = append(relocs, reloc)
+		}
+	}
+
+	return relocs, nil
+}
+
+// relocate applies all relative relocations of the file to memory m.
+func (p *Parser) relocate(m *Memory) error {
+	relocs, err := p.relocations()
+	if err != nil {
+		return fmt.Errorf("cannot read relocations: %w", err)
+	}
+
+	width := 8
+	if p.f.Class == elf.ELFCLASS32 {
+		width = 4
+	}
+
+	value := make([]byte, 8)
+	for _, r := range relocs {
+		v := uint64(p.base) + uint64(r.addend)
+		if width == 4 {
+			p.f.ByteOrder.PutUint32(value, uint32(v))
+		} else {
+			p.f.ByteOrder.PutUint64(value, v)
+		}
+
+		addr := p.base + model.Addr(r.offset)
+		b := m.Address(addr)
+		if len(b) < width {
+			return fmt.Errorf("relocation at 0x%x is out of memory", addr)
+		}
+
+		copy(b, value[:width])
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package elf
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"io"
+	"mltwist/pkg/model"
+	"sort"
+)
+
+// SourceLine is a position in a source code file a piece of machine code was
+// compiled from.
+type SourceLine struct {
+	// File is path to the source file.
+	File string
+	// Line is line number in the file starting from 1. Zero line means
+	// that the position is unknown.
+	Line int
+}
+
+// Known indicates that the source position is known.
+func (s SourceLine) Known() bool { return s.Line > 0 }
+
+func (s SourceLine) String() string { return fmt.Sprintf("%s:%d", s.File, s.Line) }
+
+// lineRow is a single row of a line table. The row describes all addresses
+// starting at addr and ending at address of the following row.
+type lineRow struct {
+	addr model.Addr
+	src  SourceLine
+	// end marks the first address behind a sequence of machine code.
+	// Addresses in between end of a sequence and the following row don't
+	// belong to any source line.
+	end bool
+}
+
+// LineTable maps addresses of machine code to source code lines the machine
+// code was compiled from.
+type LineTable struct {
+	// rows are rows of the table sorted by ascending address. Ends of
+	// sequences precede other rows at the same address.
+	rows []lineRow
+}
+
+func newLineTable(rows []lineRow) *LineTable {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if a1, a2 := rows[i].addr, rows[j].addr; a1 != a2 {
+			return a1 < a2
+		}
+		return rows[i].end && !rows[j].end
+	})
+
+	return &LineTable{rows: rows}
+}
+
+// Lookup finds the source line address a was compiled from.
+func (t *LineTable) Lookup(a model.Addr) (SourceLine, bool) {
+	i := sort.Search(len(t.rows), func(i int) bool {
+		return t.rows[i].addr > a
+	})
+	if i == 0 || t.rows[i-1].end || !t.rows[i-1].src.Known() {
+		return SourceLine{}, false
+	}
+
+	return t.rows[i-1].src, true
+}
+
+// LineTable decodes DWARF line programs (.debug_line) of all compilation units
+// of the file. Missing debug information is not an error, in such case the
+// returned table is empty. Addresses in the table are shifted by the load base
+// of the file.
+//
+// Line programs of relocatable files refer addresses through relocations which
+// are not applied by the parser, so the table is always empty for those.
+func (p *Parser) LineTable() (*LineTable, error) {
+	if p.f.Type == elf.ET_REL || !p.hasDebugLines() {
+		return newLineTable(nil), nil
+	}
+
+	d, err := p.f.DWARF()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read DWARF: %w", err)
+	}
+
+	var rows []lineRow
+	r := d.Reader()
+	for {
+		cu, err := r.Next()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read compilation unit: %w", err)
+		} else if cu == nil {
+			break
+		}
+
+		if cu.Tag != dwarf.TagCompileUnit {
+			r.SkipChildren()
+			continue
+		}
+
+		cuRows, err := p.lineRows(d, cu)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read line table of %q: %w",
+				cu.Val(dwarf.AttrName), err)
+		}
+		rows = append(rows, cuRows...)
+
+		r.SkipChildren()
+	}
+
+	return newLineTable(rows), nil
+}
+
+// hasDebugLines checks whether the file contains both DWARF line programs and
+// compilation units referring them.
+func (p *Parser) hasDebugLines() bool {
+	for _, n := range []string{"info", "line"} {
+		if p.f.Section(".debug_"+n) == nil &&
+			p.f.Section(".zdebug_"+n) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// lineRows decodes the line program of compilation unit cu.
+func (p *Parser) lineRows(d *dwarf.Data, cu *dwarf.Entry) ([]lineRow, error) {
+	lr, err := d.LineReader(cu)
+	if err != nil {
+		return nil, err
+	} else if lr == nil {
+		return nil, nil
+	}
+
+	var rows []lineRow
+	for {
+		var e dwarf.LineEntry
+		if err := lr.Next(&e); errors.Is(err, io.EOF) {
+			return rows, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		row := lineRow{
+			addr: p.base + model.Addr(e.Address),
+			end:  e.EndSequence,
+		}
+		if !e.EndSequence && e.File != nil {
+			row.src = SourceLine{File: e.File.Name, Line: e.Line}
+		}
+
+		rows = append(rows, row)
+	}
+}
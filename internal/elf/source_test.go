@@ -0,0 +1,143 @@
+package elf
+
+import (
+	"debug/elf"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineTable_Lookup(t *testing.T) {
+	table := newLineTable([]lineRow{
+		{addr: 0x108, end: true},
+		{addr: 0x100, src: SourceLine{File: "a.c", Line: 3}},
+		{addr: 0x104, src: SourceLine{File: "a.c", Line: 5}},
+		{addr: 0x108, src: SourceLine{File: "b.c", Line: 1}},
+		{addr: 0x10c, end: true},
+		{addr: 0x200, src: SourceLine{File: "b.c"}},
+		{addr: 0x204, end: true},
+	})
+
+	tests := []struct {
+		name string
+		addr model.Addr
+		want SourceLine
+		ok   bool
+	}{{
+		name: "before_table",
+		addr: 0xff,
+	}, {
+		name: "row_start",
+		addr: 0x100,
+		want: SourceLine{File: "a.c", Line: 3},
+		ok:   true,
+	}, {
+		name: "row_middle",
+		addr: 0x106,
+		want: SourceLine{File: "a.c", Line: 5},
+		ok:   true,
+	}, {
+		name: "adjacent_sequence",
+		addr: 0x108,
+		want: SourceLine{File: "b.c", Line: 1},
+		ok:   true,
+	}, {
+		name: "after_sequence",
+		addr: 0x10c,
+	}, {
+		name: "unknown_line",
+		addr: 0x200,
+	}, {
+		name: "after_table",
+		addr: 0x300,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			src, ok := table.Lookup(tt.addr)
+			r.Equal(tt.ok, ok)
+			r.Equal(tt.want, src)
+		})
+	}
+}
+
+func TestParser_LineTable(t *testing.T) {
+	tests := []struct {
+		name string
+		base model.Addr
+	}{{
+		name: "executable",
+		base: 0,
+	}, {
+		name: "position_independent",
+		base: 0x4000_0000,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			f := testExecutable()
+			if tt.base != 0 {
+				f.typ = elf.ET_DYN
+			}
+			f = f.withDebugLine(t, "/src", "main.c", 0x1000c, testLine{
+				addr: 0x10000,
+				line: 10,
+			}, testLine{
+				addr: 0x10008,
+				line: 12,
+			})
+
+			p, err := NewParser(f.write(t), tt.base)
+			r.NoError(err)
+			defer p.Close()
+
+			table, err := p.LineTable()
+			r.NoError(err)
+
+			src, ok := table.Lookup(tt.base + 0x10004)
+			r.True(ok)
+			r.Equal(SourceLine{File: "/src/main.c", Line: 10}, src)
+
+			src, ok = table.Lookup(tt.base + 0x10008)
+			r.True(ok)
+			r.Equal(SourceLine{File: "/src/main.c", Line: 12}, src)
+
+			_, ok = table.Lookup(tt.base + 0x1000c)
+			r.False(ok)
+		})
+	}
+
+	t.Run("no_debug_info", func(t *testing.T) {
+		p, err := NewParser(testExecutable().write(t), 0)
+		require.NoError(t, err)
+		defer p.Close()
+
+		table, err := p.LineTable()
+		require.NoError(t, err)
+
+		_, ok := table.Lookup(0x10000)
+		require.False(t, ok)
+	})
+
+	t.Run("relocatable", func(t *testing.T) {
+		f := testRelocatable().withDebugLine(t, "/src", "probe.c", 0x10,
+			testLine{addr: 0, line: 10})
+
+		p, err := NewParser(f.write(t), 0)
+		require.NoError(t, err)
+		defer p.Close()
+
+		table, err := p.LineTable()
+		require.NoError(t, err)
+
+		_, ok := table.Lookup(0x1000)
+		require.False(t, ok)
+	})
+}
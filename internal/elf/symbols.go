@@ -0,0 +1,213 @@
+package elf
+
+import (
+	"debug/elf"
+	"errors"
+	"fmt"
+	"mltwist/pkg/model"
+	"sort"
+	"strings"
+)
+
+// Symbol is a named address in a program address space.
+type Symbol struct {
+	// Name is name of the symbol.
+	Name string
+	// Addr is address of the symbol in the program address space.
+	Addr model.Addr
+	// Size is size of the object (function, variable etc.) the symbol
+	// refers in bytes. Zero size means that the size is unknown.
+	Size model.Addr
+	// Func indicates that the symbol refers a function.
+	Func bool
+}
+
+// contains checks whether address a belongs to s. If size of the symbol is
+// unknown, only the symbol address belongs to the symbol.
+func (s Symbol) contains(a model.Addr) bool {
+	if s.Size == 0 {
+		return a == s.Addr
+	}
+	return a >= s.Addr && a-s.Addr < s.Size
+}
+
+// Symbols is a set of symbols of a program which allows fast symbol lookup by
+// symbol address and by symbol name.
+type Symbols struct {
+	// byAddr is list of symbols sorted by ascending address. Symbols of
+	// functions precede other symbols with the same address.
+	byAddr []Symbol
+	byName map[string]Symbol
+}
+
+// NewSymbols creates a new set of symbols from syms. Duplicate symbols (with
+// the same name and address) are dropped.
+func NewSymbols(syms []Symbol) *Symbols {
+	byAddr := make([]Symbol, len(syms))
+	copy(byAddr, syms)
+
+	sort.SliceStable(byAddr, func(i, j int) bool {
+		if a1, a2 := byAddr[i].Addr, byAddr[j].Addr; a1 != a2 {
+			return a1 < a2
+		}
+		return byAddr[i].Func && !byAddr[j].Func
+	})
+
+	byName := make(map[string]Symbol, len(byAddr))
+	unique := byAddr[:0]
+	for _, s := range byAddr {
+		if prev, ok := byName[s.Name]; ok {
+			if prev.Addr == s.Addr {
+				continue
+			}
+		} else {
+			byName[s.Name] = s
+		}
+		unique = append(unique, s)
+	}
+
+	return &Symbols{
+		byAddr: unique,
+		byName: byName,
+	}
+}
+
+// Len returns number of symbols in s.
+func (s *Symbols) Len() int { return len(s.byAddr) }
+
+// All returns read-only list of all symbols in s sorted by ascending address.
+func (s *Symbols) All() []Symbol { return s.byAddr }
+
+// Lookup finds a symbol called name. If there are multiple symbols with the
+// same name (for example static functions in different compilation units),
+// the one with the lowest address is returned.
+func (s *Symbols) Lookup(name string) (Symbol, bool) {
+	sym, ok := s.byName[name]
+	return sym, ok
+}
+
+// At finds a symbol at address a. If there are multiple symbols at the same
+// address, symbols of functions are preferred.
+func (s *Symbols) At(a model.Addr) (Symbol, bool) {
+	i := sort.Search(len(s.byAddr), func(i int) bool {
+		return s.byAddr[i].Addr >= a
+	})
+	if i == len(s.byAddr) || s.byAddr[i].Addr != a {
+		return Symbol{}, false
+	}
+
+	return s.byAddr[i], true
+}
+
+// Containing finds a symbol which address a belongs to. If a belongs to
+// multiple symbols, the one with the highest address is returned.
+func (s *Symbols) Containing(a model.Addr) (Symbol, bool) {
+	i := sort.Search(len(s.byAddr), func(i int) bool {
+		return s.byAddr[i].Addr > a
+	})
+
+	for i--; i >= 0; i-- {
+		sym := s.byAddr[i]
+		if !sym.contains(a) {
+			continue
+		}
+
+		// Symbols of functions precede other symbols at the same
+		// address.
+		for ; i > 0; i-- {
+			prev := s.byAddr[i-1]
+			if prev.Addr != sym.Addr || !prev.contains(a) {
+				break
+			}
+		}
+
+		return s.byAddr[i], true
+	}
+
+	return Symbol{}, false
+}
+
+// Symbols reads symbols from both static (.symtab) and dynamic (.dynsym)
+// symbol table of the file. Missing symbol tables are not an error.
+//
+// Only symbols of functions, objects and untyped symbols (labels) which are
+// defined in the file are returned. Addresses of symbols are shifted by the
+// load base of the file. Symbols of relocatable files are placed relative to
+// the address assigned to their section.
+func (p *Parser) Symbols() (*Symbols, error) {
+	tables := []struct {
+		name string
+		read func() ([]elf.Symbol, error)
+	}{
+		{"static", p.f.Symbols},
+		{"dynamic", p.f.DynamicSymbols},
+	}
+
+	var syms []Symbol
+	for _, t := range tables {
+		ss, err := t.read()
+		if errors.Is(err, elf.ErrNoSymbols) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot read %s symbols: %w", t.name, err)
+		}
+
+		for _, s := range ss {
+			if skipSymbol(s) {
+				continue
+			}
+
+			addr, ok := p.symbolAddr(s)
+			if !ok {
+				continue
+			}
+
+			syms = append(syms, Symbol{
+				Name: s.Name,
+				Addr: addr,
+				Size: model.Addr(s.Size),
+				Func: elf.ST_TYPE(s.Info) == elf.STT_FUNC,
+			})
+		}
+	}
+
+	return NewSymbols(syms), nil
+}
+
+// symbolAddr returns address of symbol s in the program address space. Symbol
+// values of relocatable files are offsets in their sections, so this method
+// returns false if the section of such symbol isn't mapped into program memory.
+func (p *Parser) symbolAddr(s elf.Symbol) (model.Addr, bool) {
+	if p.f.Type != elf.ET_REL {
+		return p.base + model.Addr(s.Value), true
+	}
+
+	i := int(s.Section)
+	if i >= len(p.f.Sections) || p.sectionAddr(i) == 0 {
+		return 0, false
+	}
+
+	return p.base + p.sectionAddr(i) + model.Addr(s.Value), true
+}
+
+// skipSymbol indicates that symbol s doesn't name any address in the program
+// address space.
+func skipSymbol(s elf.Symbol) bool {
+	// Mapping symbols ($x, $d etc.) mark type of content of a section, so
+	// those are not names of addresses.
+	if s.Name == "" || strings.HasPrefix(s.Name, "$") {
+		return true
+	}
+
+	if s.Section == elf.SHN_UNDEF || s.Section == elf.SHN_ABS ||
+		s.Section >= elf.SHN_LORESERVE {
+		return true
+	}
+
+	switch elf.ST_TYPE(s.Info) {
+	case elf.STT_FUNC, elf.STT_OBJECT, elf.STT_NOTYPE:
+		return false
+	default:
+		return true
+	}
+}
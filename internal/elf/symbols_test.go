@@ -0,0 +1,200 @@
+package elf
+
+import (
+	"debug/elf"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbols(t *testing.T) {
+	syms := NewSymbols([]Symbol{
+		{Name: "label", Addr: 0x100},
+		{Name: "main", Addr: 0x100, Size: 0x40, Func: true},
+		{Name: "main", Addr: 0x100, Size: 0x40, Func: true},
+		{Name: "inner", Addr: 0x110},
+		{Name: "helper", Addr: 0x140, Size: 0x10, Func: true},
+		{Name: "data", Addr: 0x200, Size: 8},
+		{Name: "data", Addr: 0x300, Size: 8},
+	})
+
+	r := require.New(t)
+	r.Equal(6, syms.Len())
+
+	tests := []struct {
+		name       string
+		addr       model.Addr
+		at         string
+		containing string
+	}{
+		{name: "function_start", addr: 0x100, at: "main", containing: "main"},
+		{name: "function_body", addr: 0x104, containing: "main"},
+		{name: "label", addr: 0x110, at: "inner", containing: "inner"},
+		{name: "behind_label", addr: 0x114, containing: "main"},
+		{name: "second_function", addr: 0x14c, containing: "helper"},
+		{name: "gap", addr: 0x150},
+		{name: "before_all", addr: 0x10},
+		{name: "behind_all", addr: 0x400},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			s, ok := syms.At(tt.addr)
+			r.Equal(tt.at != "", ok)
+			r.Equal(tt.at, s.Name)
+
+			s, ok = syms.Containing(tt.addr)
+			r.Equal(tt.containing != "", ok)
+			r.Equal(tt.containing, s.Name)
+		})
+	}
+
+	s, ok := syms.Lookup("helper")
+	r.True(ok)
+	r.Equal(model.Addr(0x140), s.Addr)
+
+	s, ok = syms.Lookup("data")
+	r.True(ok)
+	r.Equal(model.Addr(0x200), s.Addr)
+
+	_, ok = syms.Lookup("missing")
+	r.False(ok)
+}
+
+func TestParser_Symbols(t *testing.T) {
+	tests := []struct {
+		name string
+		file testFile
+		base model.Addr
+	}{{
+		name: "executable",
+		file: testExecutable(),
+	}, {
+		name: "position_independent",
+		file: testFile{
+			typ:      elf.ET_DYN,
+			sections: testExecutable().sections,
+		},
+		base: 0x4000_0000,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			f := tt.file.withSymtab(t, testSym{
+				name:  "_start",
+				typ:   elf.STT_FUNC,
+				shndx: 1,
+				value: 0x10000,
+				size:  8,
+			}, testSym{
+				name:  "loop",
+				typ:   elf.STT_NOTYPE,
+				shndx: 1,
+				value: 0x10004,
+			}, testSym{
+				name:  "counter",
+				typ:   elf.STT_OBJECT,
+				shndx: 2,
+				value: 0x20000,
+				size:  4,
+			}, testSym{
+				name:  "text",
+				typ:   elf.STT_SECTION,
+				shndx: 1,
+				value: 0x10000,
+			}, testSym{
+				name: "printf",
+				typ:  elf.STT_FUNC,
+			}, testSym{
+				name:  "$x",
+				typ:   elf.STT_NOTYPE,
+				shndx: 1,
+				value: 0x10000,
+			})
+
+			p, err := NewParser(f.write(t), tt.base)
+			r.NoError(err)
+			defer p.Close()
+
+			syms, err := p.Symbols()
+			r.NoError(err)
+			r.Equal(3, syms.Len())
+
+			s, ok := syms.Lookup("_start")
+			r.True(ok)
+			r.Equal(Symbol{
+				Name: "_start",
+				Addr: tt.base + 0x10000,
+				Size: 8,
+				Func: true,
+			}, s)
+
+			s, ok = syms.Containing(tt.base + 0x10006)
+			r.True(ok)
+			r.Equal("_start", s.Name)
+
+			s, ok = syms.At(tt.base + 0x10004)
+			r.True(ok)
+			r.Equal("loop", s.Name)
+
+			s, ok = syms.At(tt.base + 0x20000)
+			r.True(ok)
+			r.Equal("counter", s.Name)
+		})
+	}
+
+	t.Run("relocatable", func(t *testing.T) {
+		r := require.New(t)
+
+		f := testRelocatable().withSymtab(t, testSym{
+			name:  "handler",
+			typ:   elf.STT_FUNC,
+			shndx: 2,
+			value: 0,
+			size:  8,
+		}, testSym{
+			name:  "counter",
+			typ:   elf.STT_OBJECT,
+			shndx: 3,
+			value: 1,
+			size:  1,
+		}, testSym{
+			name:  "unmapped",
+			typ:   elf.STT_NOTYPE,
+			shndx: 5,
+		})
+
+		p, err := NewParser(f.write(t), 0)
+		r.NoError(err)
+		defer p.Close()
+
+		syms, err := p.Symbols()
+		r.NoError(err)
+		r.Equal(2, syms.Len())
+
+		s, ok := syms.Lookup("handler")
+		r.True(ok)
+		r.Equal(model.Addr(0x1010), s.Addr)
+
+		s, ok = syms.Lookup("counter")
+		r.True(ok)
+		r.Equal(model.Addr(0x1019), s.Addr)
+	})
+
+	t.Run("no_symbols", func(t *testing.T) {
+		p, err := NewParser(testExecutable().write(t), 0)
+		require.NoError(t, err)
+		defer p.Close()
+
+		syms, err := p.Symbols()
+		require.NoError(t, err)
+		require.Zero(t, syms.Len())
+	})
+}
@@ -0,0 +1,239 @@
+package elf
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Target describes the machine an ELF file was built for.
+type Target struct {
+	// Class is the file class - i.e. 32bit or 64bit architecture.
+	Class elf.Class
+	// Machine is the machine architecture.
+	Machine elf.Machine
+	// Flags are architecture-specific flags of the file (e_flags).
+	Flags uint32
+	// ByteOrder is byte order of data and instructions in the file.
+	ByteOrder binary.ByteOrder
+}
+
+// Target returns description of the machine the file was built for.
+func (p *Parser) Target() (Target, error) {
+	flags, err := p.flags()
+	if err != nil {
+		return Target{}, fmt.Errorf("cannot read ELF flags: %w", err)
+	}
+
+	return Target{
+		Class:     p.f.Class,
+		Machine:   p.f.Machine,
+		Flags:     flags,
+		ByteOrder: p.f.ByteOrder,
+	}, nil
+}
+
+// flags reads e_flags field of the ELF header. The debug/elf package doesn't
+// provide the field, so it has to be read from the file directly.
+func (p *Parser) flags() (uint32, error) {
+	var off int64
+	switch p.f.Class {
+	case elf.ELFCLASS32:
+		off = 36
+	case elf.ELFCLASS64:
+		off = 48
+	default:
+		return 0, fmt.Errorf("unknown ELF class: %v", p.f.Class)
+	}
+
+	f, err := os.Open(p.filename)
+	if err != nil {
+		return 0, fmt.Errorf("cannot open file %q: %w", p.filename, err)
+	}
+	defer f.Close()
+
+	var b [4]byte
+	if _, err := f.ReadAt(b[:], off); err != nil {
+		return 0, fmt.Errorf("cannot read ELF header: %w", err)
+	}
+
+	return p.f.ByteOrder.Uint32(b[:]), nil
+}
+
+// Attributes are build attributes of a single vendor stored in an ELF file.
+// Every attribute is identified by its tag and it holds either an integer or a
+// string value.
+type Attributes struct {
+	ints map[uint64]uint64
+	strs map[uint64]string
+}
+
+// Int returns integer value of attribute tag.
+func (a *Attributes) Int(tag uint64) (uint64, bool) {
+	v, ok := a.ints[tag]
+	return v, ok
+}
+
+// String returns string value of attribute tag.
+func (a *Attributes) String(tag uint64) (string, bool) {
+	v, ok := a.strs[tag]
+	return v, ok
+}
+
+// attributesFormatVersion is the only known version of build attributes
+// section format.
+const attributesFormatVersion = 'A'
+
+// tagFile identifies attributes applying to the whole file.
+const tagFile = 1
+
+// Attributes parses file-level build attributes of vendor from section called
+// section (for example .riscv.attributes). If the file has no such section or
+// the section contains no attributes of vendor, nil is returned.
+//
+// Attributes with odd tags are expected to hold null-terminated strings and
+// attributes with even tags are expected to hold ULEB128 integers. This
+// convention is shared by all known vendors which don't define tags with
+// values above 32.
+func (p *Parser) Attributes(section string, vendor string) (*Attributes, error) {
+	s := p.f.Section(section)
+	if s == nil {
+		return nil, nil
+	}
+
+	data, err := s.Data()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read section %q: %w", section, err)
+	}
+
+	attrs, err := parseAttributes(data, vendor, p.f.ByteOrder)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attributes in section %q: %w",
+			section, err)
+	}
+
+	return attrs, nil
+}
+
+func parseAttributes(
+	data []byte,
+	vendor string,
+	order binary.ByteOrder,
+) (*Attributes, error) {
+	if len(data) == 0 || data[0] != attributesFormatVersion {
+		return nil, errors.New("unknown format version")
+	}
+
+	for data = data[1:]; len(data) > 0; {
+		sub, rest, err := lengthPrefixed(data, 0, order)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subsection: %w", err)
+		}
+		data = rest
+
+		name, sub, err := readString(sub)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vendor name: %w", err)
+		}
+		if name != vendor {
+			continue
+		}
+
+		return parseVendorAttributes(sub, order)
+	}
+
+	return nil, nil
+}
+
+func parseVendorAttributes(data []byte, order binary.ByteOrder) (*Attributes, error) {
+	attrs := &Attributes{
+		ints: make(map[uint64]uint64),
+		strs: make(map[uint64]string),
+	}
+
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errors.New("invalid sub-subsection tag")
+		}
+
+		sub, rest, err := lengthPrefixed(data[n:], n, order)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sub-subsection: %w", err)
+		}
+		data = rest
+
+		// Attributes of sections and symbols don't describe the file.
+		if tag != tagFile {
+			continue
+		}
+
+		if err := attrs.parse(sub); err != nil {
+			return nil, err
+		}
+	}
+
+	return attrs, nil
+}
+
+func (a *Attributes) parse(data []byte) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("invalid attribute tag")
+		}
+		data = data[n:]
+
+		if tag%2 == 1 {
+			s, rest, err := readString(data)
+			if err != nil {
+				return fmt.Errorf("invalid value of attribute %d: %w",
+					tag, err)
+			}
+			a.strs[tag], data = s, rest
+			continue
+		}
+
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("invalid value of attribute %d", tag)
+		}
+		a.ints[tag], data = v, data[n:]
+	}
+
+	return nil
+}
+
+// lengthPrefixed splits data prefixed by 4 bytes long length into the content
+// of the length-prefixed block and the rest of data. The length includes the
+// length itself and hdr bytes in front of data.
+func lengthPrefixed(
+	data []byte,
+	hdr int,
+	order binary.ByteOrder,
+) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, errors.New("missing length")
+	}
+
+	l := int(order.Uint32(data)) - hdr
+	if l < 4 || l > len(data) {
+		return nil, nil, fmt.Errorf("invalid length: %d", l+hdr)
+	}
+
+	return data[4:l], data[l:], nil
+}
+
+// readString reads null-terminated string from the beginning of data and
+// returns the string and the rest of data.
+func readString(data []byte) (string, []byte, error) {
+	i := bytes.IndexByte(data, 0)
+	if i < 0 {
+		return "", nil, errors.New("string is not terminated")
+	}
+
+	return string(data[:i]), data[i+1:], nil
+}
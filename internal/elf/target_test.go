@@ -0,0 +1,159 @@
+package elf
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_Target(t *testing.T) {
+	f := testExecutable()
+	f.flags = 0x5
+
+	p, err := NewParser(f.write(t), 0)
+	require.NoError(t, err)
+	defer p.Close()
+
+	target, err := p.Target()
+	require.NoError(t, err)
+	require.Equal(t, Target{
+		Class:     elf.ELFCLASS64,
+		Machine:   elf.EM_RISCV,
+		Flags:     0x5,
+		ByteOrder: binary.LittleEndian,
+	}, target)
+}
+
+// testAttributes encodes build attributes section with a single subsection per
+// vendor. Every subsection contains content sub.
+func testAttributes(t testing.TB, sub map[string][]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('A')
+	for vendor, content := range sub {
+		l := uint32(4 + len(vendor) + 1 + len(content))
+		require.NoError(t, binary.Write(&buf, binary.LittleEndian, l))
+		buf.WriteString(vendor + "\x00")
+		buf.Write(content)
+	}
+	return buf.Bytes()
+}
+
+// testFileAttributes encodes file-level attributes content.
+func testFileAttributes(t testing.TB, content []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(tagFile)
+	l := uint32(1 + 4 + len(content))
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, l))
+	buf.Write(content)
+	return buf.Bytes()
+}
+
+func TestParser_Attributes(t *testing.T) {
+	riscv := testFileAttributes(t, append(
+		[]byte{4, 16, 5},
+		[]byte("rv64i2p1_m2p0\x00")...,
+	))
+	// Attributes of section 1 are followed by file attributes.
+	riscv = append([]byte{2, 9, 0, 0, 0, 1, 0, 4, 8}, riscv...)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		vendor string
+		ints   map[uint64]uint64
+		strs   map[uint64]string
+		hasErr bool
+	}{{
+		name:   "riscv",
+		data:   testAttributes(t, map[string][]byte{"riscv": riscv}),
+		vendor: "riscv",
+		ints:   map[uint64]uint64{4: 16},
+		strs:   map[uint64]string{5: "rv64i2p1_m2p0"},
+	}, {
+		name: "multiple_vendors",
+		data: testAttributes(t, map[string][]byte{
+			"gnu":   testFileAttributes(t, []byte{4, 1}),
+			"riscv": riscv,
+		}),
+		vendor: "riscv",
+		ints:   map[uint64]uint64{4: 16},
+		strs:   map[uint64]string{5: "rv64i2p1_m2p0"},
+	}, {
+		name:   "other_vendor",
+		data:   testAttributes(t, map[string][]byte{"gnu": riscv}),
+		vendor: "riscv",
+	}, {
+		name:   "unknown_version",
+		data:   []byte{'B'},
+		vendor: "riscv",
+		hasErr: true,
+	}, {
+		name:   "invalid_length",
+		data:   []byte{'A', 0xff, 0, 0, 0, 'r', 0},
+		vendor: "riscv",
+		hasErr: true,
+	}, {
+		name: "unterminated_string",
+		data: testAttributes(t, map[string][]byte{
+			"riscv": testFileAttributes(t, []byte{5, 'r', 'v'}),
+		}),
+		vendor: "riscv",
+		hasErr: true,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			f := testExecutable()
+			f.sections = append(f.sections, testSection{
+				name: ".riscv.attributes",
+				typ:  elf.SHT_RISCV_ATTRIBUTES,
+				data: tt.data,
+			})
+
+			p, err := NewParser(f.write(t), 0)
+			r.NoError(err)
+			defer p.Close()
+
+			attrs, err := p.Attributes(".riscv.attributes", tt.vendor)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			if tt.ints == nil && tt.strs == nil {
+				r.Nil(attrs)
+				return
+			}
+
+			for tag, v := range tt.ints {
+				got, ok := attrs.Int(tag)
+				r.True(ok)
+				r.Equal(v, got)
+			}
+			for tag, v := range tt.strs {
+				got, ok := attrs.String(tag)
+				r.True(ok)
+				r.Equal(v, got)
+			}
+			_, ok := attrs.Int(5)
+			r.False(ok)
+		})
+	}
+
+	t.Run("no_section", func(t *testing.T) {
+		p, err := NewParser(testExecutable().write(t), 0)
+		require.NoError(t, err)
+		defer p.Close()
+
+		attrs, err := p.Attributes(".riscv.attributes", "riscv")
+		require.NoError(t, err)
+		require.Nil(t, attrs)
+	})
+}
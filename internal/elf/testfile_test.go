@@ -0,0 +1,458 @@
+package elf
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testSection describes a single section of an ELF file generated by tests.
+type testSection struct {
+	name  string
+	typ   elf.SectionType
+	flags elf.SectionFlag
+	addr  uint64
+	data  []byte
+
+	link    uint32
+	info    uint32
+	entsize uint64
+}
+
+// testFile describes a minimal 64bit little-endian ELF file generated by
+// tests.
+//
+// Every section with nonzero address is mapped into program memory by its own
+// PT_LOAD program header.
+type testFile struct {
+	typ      elf.Type
+	machine  elf.Machine
+	flags    uint32
+	entry    uint64
+	sections []testSection
+}
+
+func align8(n int) int { return (n + 7) &^ 7 }
+
+// bytes encodes the ELF file described by f.
+func (f testFile) bytes(t testing.TB) []byte {
+	const (
+		headerSize  = 64
+		progSize    = 56
+		sectionSize = 64
+	)
+
+	var loaded []int
+	for i, s := range f.sections {
+		if s.addr != 0 {
+			loaded = append(loaded, i)
+		}
+	}
+
+	shstrtab := []byte{0}
+	names := make([]uint32, len(f.sections))
+	for i, s := range f.sections {
+		names[i] = uint32(len(shstrtab))
+		shstrtab = append(shstrtab, []byte(s.name)...)
+		shstrtab = append(shstrtab, 0)
+	}
+	shstrtabName := uint32(len(shstrtab))
+	shstrtab = append(shstrtab, []byte(".shstrtab\x00")...)
+
+	offset := align8(headerSize + progSize*len(loaded))
+	offsets := make([]int, len(f.sections))
+	for i, s := range f.sections {
+		offsets[i] = offset
+		offset = align8(offset + len(s.data))
+	}
+	shstrtabOffset := offset
+	shOffset := align8(offset + len(shstrtab))
+
+	machine := f.machine
+	if machine == elf.EM_NONE {
+		machine = elf.EM_RISCV
+	}
+
+	var buf bytes.Buffer
+	write := func(v any) {
+		require.NoError(t, binary.Write(&buf, binary.LittleEndian, v))
+	}
+	pad := func(to int) {
+		buf.Write(make([]byte, to-buf.Len()))
+	}
+
+	header := elf.Header64{
+		Type:      uint16(f.typ),
+		Machine:   uint16(machine),
+		Version:   uint32(elf.EV_CURRENT),
+		Entry:     f.entry,
+		Flags:     f.flags,
+		Phoff:     headerSize,
+		Shoff:     uint64(shOffset),
+		Ehsize:    headerSize,
+		Phentsize: progSize,
+		Phnum:     uint16(len(loaded)),
+		Shentsize: sectionSize,
+		Shnum:     uint16(len(f.sections) + 2),
+		Shstrndx:  uint16(len(f.sections) + 1),
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	write(header)
+
+	for _, i := range loaded {
+		s := f.sections[i]
+		flags := elf.PF_R
+		if s.flags&elf.SHF_EXECINSTR != 0 {
+			flags |= elf.PF_X
+		}
+		if s.flags&elf.SHF_WRITE != 0 {
+			flags |= elf.PF_W
+		}
+
+		write(elf.Prog64{
+			Type:   uint32(elf.PT_LOAD),
+			Flags:  uint32(flags),
+			Off:    uint64(offsets[i]),
+			Vaddr:  s.addr,
+			Paddr:  s.addr,
+			Filesz: uint64(len(s.data)),
+			Memsz:  uint64(len(s.data)),
+			Align:  8,
+		})
+	}
+
+	for i, s := range f.sections {
+		pad(offsets[i])
+		buf.Write(s.data)
+	}
+	pad(shstrtabOffset)
+	buf.Write(shstrtab)
+	pad(shOffset)
+
+	write(elf.Section64{})
+	for i, s := range f.sections {
+		write(elf.Section64{
+			Name:      names[i],
+			Type:      uint32(s.typ),
+			Flags:     uint64(s.flags),
+			Addr:      s.addr,
+			Off:       uint64(offsets[i]),
+			Size:      uint64(len(s.data)),
+			Link:      s.link,
+			Info:      s.info,
+			Addralign: 4,
+			Entsize:   s.entsize,
+		})
+	}
+	write(elf.Section64{
+		Name:      shstrtabName,
+		Type:      uint32(elf.SHT_STRTAB),
+		Off:       uint64(shstrtabOffset),
+		Size:      uint64(len(shstrtab)),
+		Addralign: 1,
+	})
+
+	return buf.Bytes()
+}
+
+// write stores the ELF file described by f into a temporary file and returns
+// its name.
+func (f testFile) write(t testing.TB) string {
+	name := filepath.Join(t.TempDir(), "test.elf")
+	require.NoError(t, os.WriteFile(name, f.bytes(t), 0755))
+	return name
+}
+
+// testExecutable returns a simple executable file with a single code section
+// and a single data section.
+func testExecutable() testFile {
+	return testFile{
+		typ:   elf.ET_EXEC,
+		entry: 0x10000,
+		sections: []testSection{{
+			name:  ".text",
+			typ:   elf.SHT_PROGBITS,
+			flags: elf.SHF_ALLOC | elf.SHF_EXECINSTR,
+			addr:  0x10000,
+			data:  []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		}, {
+			name:  ".data",
+			typ:   elf.SHT_PROGBITS,
+			flags: elf.SHF_ALLOC | elf.SHF_WRITE,
+			addr:  0x20000,
+			data:  []byte{0xaa, 0xbb, 0xcc, 0xdd},
+		}},
+	}
+}
+
+// testRela encodes 64bit little-endian relocation entries with addend.
+func testRela(t testing.TB, relas ...elf.Rela64) []byte {
+	var buf bytes.Buffer
+	for _, r := range relas {
+		require.NoError(t, binary.Write(&buf, binary.LittleEndian, r))
+	}
+	return buf.Bytes()
+}
+
+// testPIE returns a simple position-independent executable with a single code
+// section, a single data section and a section of dynamic relocations.
+func testPIE(t testing.TB) testFile {
+	relas := testRela(t, elf.Rela64{
+		Off:    0x2000,
+		Info:   elf.R_INFO(0, uint32(elf.R_RISCV_RELATIVE)),
+		Addend: 0x1004,
+	}, elf.Rela64{
+		Off:    0x2008,
+		Info:   elf.R_INFO(1, uint32(elf.R_RISCV_64)),
+		Addend: 0x10,
+	}, elf.Rela64{
+		Off:    0x2010,
+		Info:   elf.R_INFO(0, uint32(elf.R_RISCV_RELATIVE)),
+		Addend: 0x2000,
+	})
+
+	return testFile{
+		typ:   elf.ET_DYN,
+		entry: 0x1000,
+		sections: []testSection{{
+			name:  ".text",
+			typ:   elf.SHT_PROGBITS,
+			flags: elf.SHF_ALLOC | elf.SHF_EXECINSTR,
+			addr:  0x1000,
+			data:  []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		}, {
+			name:  ".data",
+			typ:   elf.SHT_PROGBITS,
+			flags: elf.SHF_ALLOC | elf.SHF_WRITE,
+			addr:  0x2000,
+			data:  make([]byte, 24),
+		}, {
+			name:    ".rela.dyn",
+			typ:     elf.SHT_RELA,
+			flags:   elf.SHF_ALLOC,
+			addr:    0x3000,
+			data:    relas,
+			entsize: 24,
+		}},
+	}
+}
+
+// testRelocatable returns a simple relocatable BPF object with two code
+// sections, a data section and a section of uninitialized data. Sections of the
+// file have no addresses assigned.
+func testRelocatable() testFile {
+	return testFile{
+		typ:     elf.ET_REL,
+		machine: elf.EM_BPF,
+		sections: []testSection{{
+			name:  ".text",
+			typ:   elf.SHT_PROGBITS,
+			flags: elf.SHF_ALLOC | elf.SHF_EXECINSTR,
+			data:  []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		}, {
+			name:  "kprobe/sys_open",
+			typ:   elf.SHT_PROGBITS,
+			flags: elf.SHF_ALLOC | elf.SHF_EXECINSTR,
+			data:  []byte{17, 18, 19, 20, 21, 22, 23, 24},
+		}, {
+			name:  ".data",
+			typ:   elf.SHT_PROGBITS,
+			flags: elf.SHF_ALLOC | elf.SHF_WRITE,
+			data:  []byte{0xaa, 0xbb, 0xcc},
+		}, {
+			name:  ".bss",
+			typ:   elf.SHT_NOBITS,
+			flags: elf.SHF_ALLOC | elf.SHF_WRITE,
+			data:  make([]byte, 8),
+		}},
+	}
+}
+
+// testSym describes a single symbol in a symbol table generated by tests.
+type testSym struct {
+	name  string
+	typ   elf.SymType
+	shndx elf.SectionIndex
+	value uint64
+	size  uint64
+}
+
+// withSymtab appends a static symbol table containing syms and its string table
+// to sections of f.
+func (f testFile) withSymtab(t testing.TB, syms ...testSym) testFile {
+	strtab := []byte{0}
+	var buf bytes.Buffer
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, elf.Sym64{}))
+	for _, s := range syms {
+		require.NoError(t, binary.Write(&buf, binary.LittleEndian, elf.Sym64{
+			Name:  uint32(len(strtab)),
+			Info:  elf.ST_INFO(elf.STB_GLOBAL, s.typ),
+			Shndx: uint16(s.shndx),
+			Value: s.value,
+			Size:  s.size,
+		}))
+		strtab = append(strtab, []byte(s.name)...)
+		strtab = append(strtab, 0)
+	}
+
+	// Section header index 0 is the null section and symtab is followed by
+	// strtab.
+	strtabIdx := uint32(len(f.sections) + 2)
+
+	f.sections = append(append([]testSection{}, f.sections...), testSection{
+		name:    ".symtab",
+		typ:     elf.SHT_SYMTAB,
+		data:    buf.Bytes(),
+		link:    strtabIdx,
+		info:    1,
+		entsize: 24,
+	}, testSection{
+		name: ".strtab",
+		typ:  elf.SHT_STRTAB,
+		data: strtab,
+	})
+
+	return f
+}
+
+// testLine is a single row of a DWARF line program generated by tests. Each row
+// starts at address addr and ends at address of the next row.
+type testLine struct {
+	addr uint64
+	line int
+}
+
+// withDebugLine appends a single DWARF 4 compilation unit compiled from file
+// in directory dir to sections of f. The line program of the unit consists of
+// a single sequence of lines ending at address end.
+func (f testFile) withDebugLine(
+	t testing.TB,
+	dir string,
+	file string,
+	end uint64,
+	lines ...testLine,
+) testFile {
+	const (
+		lineBase   = -5
+		lineRange  = 14
+		opcodeBase = 13
+	)
+
+	var abbrev bytes.Buffer
+	abbrev.Write([]byte{
+		1,                          // Abbreviation code.
+		byte(dwarf.TagCompileUnit), // Tag.
+		0,                          // DW_CHILDREN_no.
+		byte(dwarf.AttrName), 0x08, // DW_FORM_string.
+		byte(dwarf.AttrCompDir), 0x08, // DW_FORM_string.
+		byte(dwarf.AttrStmtList), 0x17, // DW_FORM_sec_offset.
+		0, 0, // End of attributes.
+		0, // End of abbreviations.
+	})
+
+	var die bytes.Buffer
+	die.WriteByte(1)
+	die.WriteString(file + "\x00")
+	die.WriteString(dir + "\x00")
+	die.Write([]byte{0, 0, 0, 0})
+
+	var info bytes.Buffer
+	le := func(buf *bytes.Buffer, v any) {
+		require.NoError(t, binary.Write(buf, binary.LittleEndian, v))
+	}
+	le(&info, uint32(2+4+1+die.Len()))
+	le(&info, uint16(4))
+	le(&info, uint32(0))
+	info.WriteByte(8)
+	info.Write(die.Bytes())
+
+	var program bytes.Buffer
+	setAddr := func(a uint64) {
+		program.Write([]byte{0, 9, 2}) // DW_LNE_set_address.
+		le(&program, a)
+	}
+	prevLine := 1
+	for i, l := range lines {
+		if i == 0 {
+			setAddr(l.addr)
+		} else {
+			program.WriteByte(2) // DW_LNS_advance_pc.
+			program.Write(uleb128(l.addr - lines[i-1].addr))
+		}
+		program.WriteByte(3) // DW_LNS_advance_line.
+		program.Write(sleb128(int64(l.line - prevLine)))
+		program.WriteByte(1) // DW_LNS_copy.
+		prevLine = l.line
+	}
+	if len(lines) > 0 {
+		program.WriteByte(2) // DW_LNS_advance_pc.
+		program.Write(uleb128(end - lines[len(lines)-1].addr))
+	}
+	program.Write([]byte{0, 1, 1}) // DW_LNE_end_sequence.
+
+	var header bytes.Buffer
+	header.Write([]byte{1, 1, 1, byte(lineBase & 0xff), lineRange, opcodeBase})
+	header.Write([]byte{0, 1, 1, 1, 1, 0, 0, 0, 1, 0, 0, 1})
+	header.WriteByte(0) // No include directories.
+	header.WriteString(file + "\x00")
+	header.Write([]byte{0, 0, 0, 0}) // Directory, time, size; end of files.
+
+	var line bytes.Buffer
+	le(&line, uint32(2+4+header.Len()+program.Len()))
+	le(&line, uint16(4))
+	le(&line, uint32(header.Len()))
+	line.Write(header.Bytes())
+	line.Write(program.Bytes())
+
+	f.sections = append(append([]testSection{}, f.sections...), testSection{
+		name: ".debug_abbrev",
+		typ:  elf.SHT_PROGBITS,
+		data: abbrev.Bytes(),
+	}, testSection{
+		name: ".debug_info",
+		typ:  elf.SHT_PROGBITS,
+		data: info.Bytes(),
+	}, testSection{
+		name: ".debug_line",
+		typ:  elf.SHT_PROGBITS,
+		data: line.Bytes(),
+	})
+
+	return f
+}
+
+func uleb128(v uint64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+		if v == 0 {
+			return b
+		}
+	}
+}
+
+func sleb128(v int64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
@@ -0,0 +1,96 @@
+package elf
+
+import (
+	"debug/elf"
+	"fmt"
+	"mltwist/pkg/model"
+	"os"
+)
+
+// CodeBlock is a continuous sequence of machine code bytes placed at an address
+// of the program address space.
+type CodeBlock interface {
+	Begin() model.Addr
+	Bytes() []byte
+}
+
+// Write writes a copy of the ELF file p was created from into a file called
+// filename. Bytes of every block in code replace bytes of the original file
+// which are mapped to the same address in the program address space.
+//
+// Every block of code has to lie fully inside a single executable section of
+// the original file. This restriction guarantees that all other sections, ELF
+// header and program headers remain byte-identical to the original file. As
+// the block cannot grow behind the section end, the layout of the file never
+// changes.
+//
+// The file written has the same permissions as the original file. So if the
+// original file was executable, the file written is executable as well.
+func (p *Parser) Write(filename string, code []CodeBlock) error {
+	info, err := os.Stat(p.filename)
+	if err != nil {
+		return fmt.Errorf("cannot stat file %q: %w", p.filename, err)
+	}
+
+	bytes, err := os.ReadFile(p.filename)
+	if err != nil {
+		return fmt.Errorf("cannot read file %q: %w", p.filename, err)
+	}
+
+	for _, b := range code {
+		begin := b.Begin()
+		end := begin + model.Addr(len(b.Bytes()))
+
+		offset, err := p.fileOffset(begin, end)
+		if err != nil {
+			return fmt.Errorf("cannot place block [0x%x, 0x%x): %w",
+				begin, end, err)
+		}
+
+		copy(bytes[offset:], b.Bytes())
+	}
+
+	err = os.WriteFile(filename, bytes, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("cannot write file %q: %w", filename, err)
+	}
+
+	return nil
+}
+
+// fileOffset finds an offset in the ELF file where bytes of address range
+// [begin, end) are stored. This method returns an error if the range doesn't
+// fully belong to a single section containing machine code.
+func (p *Parser) fileOffset(begin, end model.Addr) (uint64, error) {
+	for i, s := range p.f.Sections {
+		addr := p.sectionAddr(i)
+		if skipMachineCodeSection(s, addr) {
+			continue
+		}
+
+		offset, ok := sectionOffset(s.SectionHeader, addr,
+			begin-p.base, end-p.base)
+		if ok {
+			return offset, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no machine code section contains the block")
+}
+
+// sectionOffset returns file offset of address begin if range [begin, end)
+// fits into section described by header h placed at address addr. If the range
+// doesn't fit into the section, this function returns false.
+func sectionOffset(
+	h elf.SectionHeader,
+	addr, begin, end model.Addr,
+) (uint64, bool) {
+	sBegin := addr
+	sEnd := sBegin + model.Addr(h.Size)
+
+	if begin < sBegin || end > sEnd || begin > end {
+		return 0, false
+	}
+
+	return h.Offset + uint64(begin-sBegin), true
+}
@@ -0,0 +1,127 @@
+package elf
+
+import (
+	"bytes"
+	"mltwist/pkg/model"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_Write(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []CodeBlock
+		code   []byte
+		hasErr bool
+	}{{
+		name:   "no_change",
+		blocks: nil,
+		code:   []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	}, {
+		name: "reordered_instructions",
+		blocks: []CodeBlock{
+			newBlock(0x10000, []byte{5, 6, 7, 8, 1, 2, 3, 4}),
+			newBlock(0x1000c, []byte{16, 15, 14, 13}),
+		},
+		code: []byte{5, 6, 7, 8, 1, 2, 3, 4, 9, 10, 11, 12, 16, 15, 14, 13},
+	}, {
+		name:   "block_behind_section",
+		blocks: []CodeBlock{newBlock(0x1000c, []byte{1, 2, 3, 4, 5})},
+		hasErr: true,
+	}, {
+		name:   "data_section",
+		blocks: []CodeBlock{newBlock(0x20000, []byte{1, 2, 3, 4})},
+		hasErr: true,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			in := testExecutable()
+			p, err := NewParser(in.write(t), 0)
+			r.NoError(err)
+			defer p.Close()
+
+			out := filepath.Join(t.TempDir(), "out.elf")
+			err = p.Write(out, tt.blocks)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			info, err := os.Stat(out)
+			r.NoError(err)
+			r.Equal(os.FileMode(0755), info.Mode().Perm())
+
+			outBytes, err := os.ReadFile(out)
+			r.NoError(err)
+
+			expected := in
+			expected.sections = append(
+				[]testSection{}, expected.sections...)
+			expected.sections[0].data = tt.code
+			r.True(bytes.Equal(expected.bytes(t), outBytes))
+
+			written, err := NewParser(out, 0)
+			r.NoError(err)
+			defer written.Close()
+
+			mem, err := written.MachineCode()
+			r.NoError(err)
+			r.Len(mem.Blocks, 1)
+			r.Equal(tt.code, mem.Blocks[0].Bytes())
+		})
+	}
+}
+
+func TestParser_Write_PositionIndependent(t *testing.T) {
+	const base model.Addr = 0x4000_0000
+
+	r := require.New(t)
+
+	in := testPIE(t)
+	p, err := NewParser(in.write(t), base)
+	r.NoError(err)
+	defer p.Close()
+
+	code := []CodeBlock{newBlock(base+0x1004, []byte{9, 9})}
+
+	out := filepath.Join(t.TempDir(), "out.elf")
+	r.NoError(p.Write(out, code))
+
+	outBytes, err := os.ReadFile(out)
+	r.NoError(err)
+
+	expected := in
+	expected.sections = append([]testSection{}, expected.sections...)
+	expected.sections[0].data = []byte{1, 2, 3, 4, 9, 9, 7, 8}
+	r.True(bytes.Equal(expected.bytes(t), outBytes))
+}
+
+func TestParser_Write_Relocatable(t *testing.T) {
+	r := require.New(t)
+
+	in := testRelocatable()
+	p, err := NewParser(in.write(t), 0)
+	r.NoError(err)
+	defer p.Close()
+
+	code := []CodeBlock{newBlock(0x1010, []byte{9, 9})}
+
+	out := filepath.Join(t.TempDir(), "out.elf")
+	r.NoError(p.Write(out, code))
+
+	outBytes, err := os.ReadFile(out)
+	r.NoError(err)
+
+	expected := in
+	expected.sections = append([]testSection{}, expected.sections...)
+	expected.sections[1].data = []byte{9, 9, 19, 20, 21, 22, 23, 24}
+	r.True(bytes.Equal(expected.bytes(t), outBytes))
+}
@@ -24,14 +24,27 @@ type Emulator struct {
 	// State must be synchronized with Step method calls. Any violation of
 	// this synchronization might result in undefined behaviour.
 	State *state.State
+
+	// delayedIP is target of a delayed jump executed by the previous
+	// instruction. The jump takes effect after the current instruction (a
+	// delay slot) executes. It's nil if there is no delayed jump pending.
+	delayedIP *model.Addr
 }
 
+// counterKeys lists read-only counter registers provided by the emulator. All
+// the counters are 64 bits wide and they are incremented by one after every
+// instruction executed. The emulator executes one instruction per clock cycle
+// and one cycle is one tick of the real-time counter.
+var counterKeys = []expr.Key{expr.CycleKey, expr.TimeKey, expr.InstretKey}
+
 // New creates new emulator instance.
 //
 // The newly created emulator emulates instructions in prog and starts emulation
 // at address ip. The initial state of program memory and registers is given by
 // state. If value of any memory bytes or a register is unknown to the emulator,
-// such value is obtained using stateProv.
+// such value is obtained using stateProv. Counters (expr.CycleKey,
+// expr.TimeKey and expr.InstretKey) are provided by the emulator itself. They
+// start at zero unless their values are stored in state.
 //
 // Argument prog is treated as read-only value, but it must not be modified
 // during the Emulator lifetime.
@@ -42,6 +55,11 @@ func New(
 	state *state.State,
 ) *Emulator {
 	state.Regs.Store(expr.IPKey, expr.ConstFromUint(ip), model.AddrWidth)
+	for _, key := range counterKeys {
+		if _, ok := state.Regs.Load(key, expr.Width64); !ok {
+			state.Regs.Store(key, expr.Zero.WithWidth(expr.Width64), expr.Width64)
+		}
+	}
 
 	return &Emulator{
 		code:      code,
@@ -97,6 +115,9 @@ func (e *Emulator) Step() (*Step, error) {
 	if err != nil {
 		return nil, err
 	}
+	if ins.Type().Data() {
+		return nil, fmt.Errorf("cannot execute data at address 0x%x", ip)
+	}
 
 	efs := ins.Effects()
 	s := newStep(len(efs))
@@ -105,6 +126,9 @@ func (e *Emulator) Step() (*Step, error) {
 		return e.eval(ex, s)
 	})
 
+	delayedIP := e.delayedIP
+	e.delayedIP = nil
+
 	var jumped bool
 	for _, ef := range efs {
 		// We can calculate new value of instruction pointer from our
@@ -116,6 +140,10 @@ func (e *Emulator) Step() (*Step, error) {
 		// are still valid.
 		if rStore, ok := ef.(expr.RegStore); ok && rStore.Key() == expr.IPKey {
 			jumped = true
+		} else if ok && rStore.Key() == expr.DelayedIPKey {
+			c := rStore.Value().(expr.Const)
+			addr, _ := expr.ConstUint[model.Addr](c)
+			e.delayedIP = &addr
 		}
 
 		s.recordOutput(ef)
@@ -125,6 +153,15 @@ func (e *Emulator) Step() (*Step, error) {
 		}
 	}
 
+	// Delayed jump of the previous instruction takes effect after its
+	// delay slot. Jumps which are not taken continue behind the delay
+	// slot, so the address is written in any case.
+	if delayedIP != nil && !jumped {
+		c := expr.ConstFromUint(*delayedIP)
+		e.State.Regs.Store(expr.IPKey, c, model.AddrWidth)
+		jumped = true
+	}
+
 	// Instruction is not a jump instruction so we have to adjust
 	// instruction pointer ourselves.
 	if !jumped {
@@ -132,9 +169,19 @@ func (e *Emulator) Step() (*Step, error) {
 		e.State.Regs.Store(expr.IPKey, c, model.AddrWidth)
 	}
 
+	e.advanceCounters()
 	return s, nil
 }
 
+// advanceCounters increments all counters provided by the emulator by one.
+func (e *Emulator) advanceCounters() {
+	for _, key := range counterKeys {
+		val := e.regValue(key, expr.Width64)
+		cnt, _ := expr.ConstUint[uint64](val)
+		e.State.Regs.Store(key, expr.NewConstUint(cnt+1, expr.Width64), expr.Width64)
+	}
+}
+
 // eval substitutes all non-constant expressions (register loads and memory
 // loads) for values from the emulator storage. If the value read is not stored
 // in the storage, the value is supplied by stateProvider interface and stored
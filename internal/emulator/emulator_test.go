@@ -0,0 +1,99 @@
+package emulator_test
+
+import (
+	"encoding/binary"
+	"mltwist/internal/deps"
+	"mltwist/internal/emulator"
+	"mltwist/internal/mips"
+	"mltwist/internal/parser"
+	"mltwist/internal/state"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type zeroProvider struct{}
+
+func (zeroProvider) Register(_ expr.Key, w expr.Width) expr.Const {
+	return expr.Zero.WithWidth(w)
+}
+
+func (zeroProvider) Memory(_ expr.Key, _ model.Addr, w expr.Width) expr.Const {
+	return expr.Zero.WithWidth(w)
+}
+
+type testBlock struct {
+	begin model.Addr
+	bytes []byte
+}
+
+func (b testBlock) Begin() model.Addr { return b.begin }
+func (b testBlock) Bytes() []byte     { return b.bytes }
+
+func TestEmulator_Step_DelaySlot(t *testing.T) {
+	const begin = 0x1000
+
+	tests := []struct {
+		name string
+		// branch is the branch instruction at the beginning of the code
+		// followed by addiu $2, $2, 1 in its delay slot.
+		branch []byte
+		ips    []model.Addr
+		r2     uint64
+	}{{
+		// beq $0, $0, 0x1004
+		name:   "taken_to_delay_slot",
+		branch: []byte{0x00, 0x00, 0x00, 0x10},
+		ips:    []model.Addr{0x1004, 0x1004, 0x1008},
+		r2:     2,
+	}, {
+		// bne $0, $0, 0x1004
+		name:   "not_taken",
+		branch: []byte{0x00, 0x00, 0x00, 0x14},
+		ips:    []model.Addr{0x1004, 0x1008},
+		r2:     1,
+	}, {
+		// beq $0, $0, 0x100c
+		name:   "taken",
+		branch: []byte{0x02, 0x00, 0x00, 0x10},
+		ips:    []model.Addr{0x1004, 0x100c},
+		r2:     1,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			var bytes []byte
+			bytes = append(bytes, tt.branch...)
+			bytes = append(bytes,
+				0x01, 0x00, 0x42, 0x24, // addiu $2, $2, 1
+				0x00, 0x00, 0x00, 0x00, // nop
+				0x00, 0x00, 0x00, 0x00, // nop
+			)
+
+			p := mips.NewParser(binary.LittleEndian)
+			ins, err := parser.Parse(
+				[]parser.Block{testBlock{begin, bytes}}, p)
+			r.NoError(err)
+
+			code, err := deps.NewCode(begin, ins, nil)
+			r.NoError(err)
+
+			e := emulator.New(code, begin, zeroProvider{}, state.New())
+			for _, ip := range tt.ips {
+				_, err := e.Step()
+				r.NoError(err)
+				r.Equal(ip, e.MustIP())
+			}
+
+			r2, ok := e.State.Regs.Load("r2", expr.Width32)
+			r.True(ok)
+			v, _ := expr.ConstUint[uint64](r2.(expr.Const))
+			r.Equal(tt.r2, v)
+		})
+	}
+}
@@ -35,6 +35,17 @@ func binaryEval(op expr.BinaryOp, c1 expr.Const, c2 expr.Const, w expr.Width) ex
 	return f(v1, v2, w).Const(w)
 }
 
+func floatEval(f expr.Float, rm expr.Const, args []expr.Const) expr.Const {
+	vals := make([]expreval.Value, len(args))
+	for i, a := range args {
+		vals[i] = expreval.ParseConst(a)
+	}
+
+	v := expreval.Float(f.Op(), expreval.ParseConst(rm), vals, f.ArgWidth(),
+		f.Width(), f.IsFlags())
+	return v.Const(f.Width())
+}
+
 func lessEval(c1 expr.Const, c2 expr.Const, w expr.Width) bool {
 	v1, v2 := expreval.ParseConst(c1), expreval.ParseConst(c2)
 	return expreval.Ltu(v1, v2, w)
@@ -110,6 +121,28 @@ func constFold(ex expr.Expr) (expr.Expr, bool) {
 			return ex, false
 		}
 		return expr.NewLess(arg1, arg2, t, f, e.Width()), true
+	case expr.Float:
+		rm, changed := constFold(e.Rounding())
+		rmConst, allConst := rm.(expr.Const)
+
+		args := make([]expr.Expr, len(e.Args()))
+		consts := make([]expr.Const, len(e.Args()))
+		for i, a := range e.Args() {
+			arg, changedArg := constFold(a)
+			c, ok := arg.(expr.Const)
+
+			args[i], consts[i] = arg, c
+			changed, allConst = changed || changedArg, allConst && ok
+		}
+
+		if allConst {
+			return floatEval(e, rmConst, consts), true
+		}
+
+		if !changed {
+			return ex, false
+		}
+		return e.WithArgs(rm, args...), true
 	case expr.Const:
 		return ex, false
 	case expr.MemLoad:
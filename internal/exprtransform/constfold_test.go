@@ -283,6 +283,46 @@ func TestConstFold(t *testing.T) {
 			),
 			expr.Width64,
 		),
+	}, {
+		name: "float_eval",
+		e: expr.NewFloat(expr.FAdd,
+			expr.RoundNearestEven.Const(),
+			expr.Width32,
+			expr.ConstFromUint[uint32](0x3f800000),
+			expr.NewBinary(expr.Add,
+				expr.ConstFromUint[uint32](0x3f000000),
+				expr.ConstFromUint[uint32](0x01000000),
+				expr.Width32,
+			),
+		),
+		exp: expr.ConstFromUint[uint32](0x40400000),
+	}, {
+		name: "float_flags",
+		e: expr.NewFloat(expr.FDiv,
+			expr.RoundNearestEven.Const(),
+			expr.Width32,
+			expr.ConstFromUint[uint32](0x3f800000),
+			expr.ConstFromUint[uint32](0),
+		).Flags(),
+		exp: expr.ConstFromUint[uint32](uint32(expr.FloatDivByZero)),
+	}, {
+		name: "float_args",
+		e: expr.NewFloat(expr.FMul,
+			expr.NewRegLoad("frm", expr.Width8),
+			expr.Width64,
+			expr.NewRegLoad("f1", expr.Width64),
+			expr.NewBinary(expr.Add,
+				expr.ConstFromUint[uint64](1),
+				expr.ConstFromUint[uint64](2),
+				expr.Width64,
+			),
+		),
+		exp: expr.NewFloat(expr.FMul,
+			expr.NewRegLoad("frm", expr.Width8),
+			expr.Width64,
+			expr.NewRegLoad("f1", expr.Width64),
+			expr.ConstFromUint[uint64](3),
+		),
 	}}
 
 	for _, tt := range tests {
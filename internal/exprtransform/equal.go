@@ -35,6 +35,23 @@ func Equal(ex1 expr.Expr, ex2 expr.Expr) bool {
 			Equal(e1.Arg2(), e2.Arg2()) &&
 			Equal(e1.ExprTrue(), e2.ExprTrue()) &&
 			Equal(e1.ExprFalse(), e2.ExprFalse())
+	case expr.Float:
+		e2, ok := ex2.(expr.Float)
+		if !ok {
+			return false
+		}
+
+		if e1.Op() != e2.Op() || e1.IsFlags() != e2.IsFlags() ||
+			e1.ArgWidth() != e2.ArgWidth() ||
+			!Equal(e1.Rounding(), e2.Rounding()) {
+			return false
+		}
+		for i, a := range e1.Args() {
+			if !Equal(a, e2.Args()[i]) {
+				return false
+			}
+		}
+		return true
 	case expr.Const:
 		e2, ok := ex2.(expr.Const)
 		if !ok {
@@ -22,6 +22,11 @@ func findAll[T expr.Expr](ex expr.Expr, found []T) []T {
 		found = findAll(e.Arg2(), found)
 		found = findAll(e.ExprTrue(), found)
 		found = findAll(e.ExprFalse(), found)
+	case expr.Float:
+		found = findAll(e.Rounding(), found)
+		for _, a := range e.Args() {
+			found = findAll(a, found)
+		}
 	case expr.Const, expr.RegLoad:
 	case expr.MemLoad:
 		found = findAll(e.Addr(), found)
@@ -0,0 +1,679 @@
+package expreval
+
+import (
+	"fmt"
+	"math/big"
+	"mltwist/pkg/expr"
+)
+
+// floatFormat describes an IEEE 754 binary interchange format.
+type floatFormat struct {
+	// prec is number of bits of the significand including the implicit
+	// leading bit.
+	prec int
+	// expBits is number of bits of the biased exponent.
+	expBits int
+}
+
+var (
+	binary32 = floatFormat{prec: 24, expBits: 8}
+	binary64 = floatFormat{prec: 53, expBits: 11}
+)
+
+// formatOf returns the floating point format of width w.
+func formatOf(w expr.Width) floatFormat {
+	switch w {
+	case expr.Width32:
+		return binary32
+	case expr.Width64:
+		return binary64
+	default:
+		panic(fmt.Sprintf("unsupported floating point width: %d", w))
+	}
+}
+
+func (f floatFormat) fracBits() int { return f.prec - 1 }
+func (f floatFormat) emax() int     { return 1<<(f.expBits-1) - 1 }
+func (f floatFormat) emin() int     { return 1 - f.emax() }
+
+func (f floatFormat) signBit() uint64 { return 1 << (f.fracBits() + f.expBits) }
+func (f floatFormat) expMask() uint64 { return (1<<f.expBits - 1) << f.fracBits() }
+
+func (f floatFormat) sign(neg bool) uint64 {
+	if neg {
+		return f.signBit()
+	}
+	return 0
+}
+
+func (f floatFormat) zero(neg bool) uint64 { return f.sign(neg) }
+func (f floatFormat) inf(neg bool) uint64  { return f.sign(neg) | f.expMask() }
+
+// nan returns the canonical quiet NaN.
+func (f floatFormat) nan() uint64 { return f.expMask() | 1<<(f.fracBits()-1) }
+
+func (f floatFormat) maxFinite(neg bool) uint64 {
+	return f.sign(neg) | (f.expMask() - 1<<f.fracBits()) | (1<<f.fracBits() - 1)
+}
+
+// floatClass is a class of floating point values.
+type floatClass uint8
+
+const (
+	classZero floatClass = iota
+	classFinite
+	classInf
+	classQNaN
+	classSNaN
+)
+
+// floatValue is a decoded floating point value. Finite nonzero values equal
+// to mant * 2^exp.
+type floatValue struct {
+	class floatClass
+	neg   bool
+	mant  *big.Int
+	exp   int
+}
+
+func (v floatValue) nan() bool { return v.class == classQNaN || v.class == classSNaN }
+
+// decode decodes bits of a value of format f.
+func (f floatFormat) decode(bits uint64) floatValue {
+	frac := bits & (1<<f.fracBits() - 1)
+	biased := int((bits & f.expMask()) >> f.fracBits())
+	v := floatValue{neg: bits&f.signBit() != 0}
+
+	switch {
+	case biased == 1<<f.expBits-1 && frac == 0:
+		v.class = classInf
+	case biased == 1<<f.expBits-1 && frac>>(f.fracBits()-1) != 0:
+		v.class = classQNaN
+	case biased == 1<<f.expBits-1:
+		v.class = classSNaN
+	case biased == 0 && frac == 0:
+		v.class = classZero
+	case biased == 0:
+		v.class = classFinite
+		v.mant = new(big.Int).SetUint64(frac)
+		v.exp = f.emin() - f.fracBits()
+	default:
+		v.class = classFinite
+		v.mant = new(big.Int).SetUint64(frac | 1<<f.fracBits())
+		v.exp = biased - f.emax() - f.fracBits()
+	}
+
+	return v
+}
+
+// roundUp decides whether a magnitude truncated to odd or even value should be
+// incremented based on its half (round) bit and rest (sticky) bits.
+func roundUp(rm expr.Rounding, neg, half, rest, odd bool) bool {
+	switch rm {
+	case expr.RoundTowardZero:
+		return false
+	case expr.RoundDown:
+		return neg
+	case expr.RoundUp:
+		return !neg
+	case expr.RoundNearestMaxMagnitude:
+		return half
+	default:
+		return half && (rest || odd)
+	}
+}
+
+// roundMag rounds magnitude mag * 2^exp to a multiple of 2^lsb. Argument sticky
+// indicates that the exact magnitude is slightly bigger than mag * 2^exp, but
+// less than (mag+1) * 2^exp. It returns the rounded magnitude in units of 2^lsb
+// and whether the rounding was inexact.
+func roundMag(
+	neg bool,
+	mag *big.Int,
+	exp int,
+	sticky bool,
+	lsb int,
+	rm expr.Rounding,
+) (*big.Int, bool) {
+	shift := lsb - exp
+	if shift <= 0 {
+		return new(big.Int).Lsh(mag, uint(-shift)), sticky
+	}
+
+	q := new(big.Int).Rsh(mag, uint(shift))
+	half := mag.Bit(shift-1) == 1
+	rest := sticky || (mag.Sign() != 0 && mag.TrailingZeroBits() < uint(shift-1))
+	if !half && !rest {
+		return q, false
+	}
+
+	if roundUp(rm, neg, half, rest, q.Bit(0) == 1) {
+		q.Add(q, big.NewInt(1))
+	}
+	return q, true
+}
+
+// round rounds value (-1)^neg * mag * 2^exp to format f. See roundMag for
+// meaning of sticky. Zero mag is rounded to zero of sign neg.
+func (f floatFormat) round(
+	neg bool,
+	mag *big.Int,
+	exp int,
+	sticky bool,
+	rm expr.Rounding,
+) (uint64, expr.FloatException) {
+	if mag.Sign() == 0 {
+		return f.zero(neg), 0
+	}
+
+	// Tininess is detected after rounding - i.e. based on the result
+	// rounded as if the exponent range was unbounded.
+	msb := exp + mag.BitLen() - 1
+	unbounded, _ := roundMag(neg, mag, exp, sticky, msb-f.prec+1, rm)
+	tiny := msb+unbounded.BitLen()-f.prec < f.emin()
+
+	lsb := msb - f.prec + 1
+	if min := f.emin() - f.prec + 1; lsb < min {
+		lsb = min
+	}
+
+	q, inexact := roundMag(neg, mag, exp, sticky, lsb, rm)
+	var flags expr.FloatException
+	if inexact {
+		flags |= expr.FloatInexact
+		if tiny {
+			flags |= expr.FloatUnderflow
+		}
+	}
+
+	// Rounding up to the next power of two. The bit shifted out is zero.
+	if q.BitLen() > f.prec {
+		q.Rsh(q, 1)
+		lsb++
+	}
+
+	if q.Sign() == 0 {
+		return f.zero(neg), flags
+	}
+
+	msb = lsb + q.BitLen() - 1
+	if msb > f.emax() {
+		return f.overflow(neg, rm), expr.FloatOverflow | expr.FloatInexact
+	}
+
+	bits := q.Uint64()
+	if q.BitLen() < f.prec {
+		// Subnormal number.
+		return f.sign(neg) | bits, flags
+	}
+
+	biased := uint64(msb + f.emax())
+	frac := bits &^ (1 << f.fracBits())
+	return f.sign(neg) | biased<<f.fracBits() | frac, flags
+}
+
+// overflow returns result of an operation whose result overflowed.
+func (f floatFormat) overflow(neg bool, rm expr.Rounding) uint64 {
+	switch {
+	case rm == expr.RoundTowardZero,
+		rm == expr.RoundDown && !neg,
+		rm == expr.RoundUp && neg:
+		return f.maxFinite(neg)
+	default:
+		return f.inf(neg)
+	}
+}
+
+// less compares two values of format f which are not NaN. Negative zero is
+// less than positive zero.
+func (f floatFormat) less(a, b uint64) bool {
+	negA, negB := a&f.signBit() != 0, b&f.signBit() != 0
+	magA, magB := a&^f.signBit(), b&^f.signBit()
+
+	switch {
+	case negA != negB:
+		return negA
+	case negA:
+		return magA > magB
+	default:
+		return magA < magB
+	}
+}
+
+// equal compares two values of format f which are not NaN.
+func (f floatFormat) equal(a, b uint64) bool {
+	return a == b || (a|b)&^f.signBit() == 0
+}
+
+// invalidNaN checks whether any of vals is NaN. If so, it returns the canonical
+// NaN of format f and the invalid exception if any of vals is signaling NaN.
+func (f floatFormat) invalidNaN(vals ...floatValue) (uint64, expr.FloatException, bool) {
+	var nan bool
+	var flags expr.FloatException
+	for _, v := range vals {
+		nan = nan || v.nan()
+		if v.class == classSNaN {
+			flags = expr.FloatInvalid
+		}
+	}
+
+	return f.nan(), flags, nan
+}
+
+// floatRounding converts value of a rounding mode expression into a rounding
+// mode.
+func floatRounding(v Value) expr.Rounding {
+	rm, ok := uint64Value(v)
+	if !ok || rm > uint64(expr.RoundNearestMaxMagnitude) {
+		return expr.RoundNearestEven
+	}
+	return expr.Rounding(rm)
+}
+
+// uint64Value converts v into uint64. It returns false if v doesn't fit.
+func uint64Value(v Value) (uint64, bool) {
+	var val uint64
+	for i, b := range v.bytes() {
+		if i >= 8 {
+			if b != 0 {
+				return 0, false
+			}
+			continue
+		}
+		val |= uint64(b) << (8 * i)
+	}
+	return val, true
+}
+
+// valueUint64 converts val into value of width w.
+func valueUint64(val uint64, w expr.Width) Value {
+	bs := make([]byte, w)
+	for i := range bs {
+		if i < 8 {
+			bs[i] = byte(val >> (8 * i))
+		}
+	}
+	return newValue(bs)
+}
+
+// Float evaluates floating point operation op with arguments args of width argW
+// producing value of width w rounded with rounding mode rm. The operation
+// follows semantics of expr.Float. If flags is set, the value returned are
+// exception flags raised by the operation instead of its result.
+func Float(
+	op expr.FloatOp,
+	rm Value,
+	args []Value,
+	argW expr.Width,
+	w expr.Width,
+	flags bool,
+) Value {
+	vals := make([]uint64, len(args))
+	for i, a := range args {
+		vals[i], _ = uint64Value(a.setWidth(argW))
+	}
+
+	res, ex := evalFloat(op, floatRounding(rm), vals, argW, w)
+	if flags {
+		return valueUint64(uint64(ex), w)
+	}
+	return valueUint64(res, w)
+}
+
+func evalFloat(
+	op expr.FloatOp,
+	rm expr.Rounding,
+	args []uint64,
+	argW expr.Width,
+	w expr.Width,
+) (uint64, expr.FloatException) {
+	switch op {
+	case expr.FFromInt:
+		return fromInt(formatOf(w), args[0], argW, true, rm)
+	case expr.FFromUint:
+		return fromInt(formatOf(w), args[0], argW, false, rm)
+	}
+
+	f := formatOf(argW)
+	vals := make([]floatValue, len(args))
+	for i, a := range args {
+		vals[i] = f.decode(a)
+	}
+
+	switch op {
+	case expr.FAdd:
+		return f.add(vals[0], vals[1], rm)
+	case expr.FSub:
+		vals[1].neg = !vals[1].neg
+		return f.add(vals[0], vals[1], rm)
+	case expr.FMul:
+		if nan, ex, ok := f.invalidNaN(vals...); ok {
+			return nan, ex
+		}
+		p, ok := product(vals[0], vals[1])
+		if !ok {
+			return f.nan(), expr.FloatInvalid
+		}
+		return f.roundValue(p, rm)
+	case expr.FDiv:
+		return f.div(vals[0], vals[1], rm)
+	case expr.FSqrt:
+		return f.sqrt(vals[0], rm)
+	case expr.FMulAdd:
+		return f.mulAdd(vals[0], vals[1], vals[2], rm)
+	case expr.FMin, expr.FMax:
+		return f.minMax(op == expr.FMax, args[0], args[1], vals[0], vals[1])
+	case expr.FEq, expr.FLt, expr.FLe:
+		return f.compare(op, args[0], args[1], vals[0], vals[1])
+	case expr.FConvert:
+		return convert(formatOf(w), vals[0], rm)
+	case expr.FToInt:
+		return toInt(vals[0], w, true, rm)
+	case expr.FToUint:
+		return toInt(vals[0], w, false, rm)
+	default:
+		panic(fmt.Sprintf("unknown floating point operation: %v", op))
+	}
+}
+
+// roundValue rounds an exact value v to format f. Infinities and zeros are
+// preserved.
+func (f floatFormat) roundValue(v floatValue, rm expr.Rounding) (uint64, expr.FloatException) {
+	switch v.class {
+	case classZero:
+		return f.zero(v.neg), 0
+	case classInf:
+		return f.inf(v.neg), 0
+	default:
+		return f.round(v.neg, v.mant, v.exp, false, rm)
+	}
+}
+
+// product calculates exact product of x and y which are not NaN. It returns
+// false if the product is invalid (zero times infinity).
+func product(x, y floatValue) (floatValue, bool) {
+	neg := x.neg != y.neg
+
+	switch {
+	case x.class == classInf && y.class == classZero,
+		x.class == classZero && y.class == classInf:
+		return floatValue{}, false
+	case x.class == classInf || y.class == classInf:
+		return floatValue{class: classInf, neg: neg}, true
+	case x.class == classZero || y.class == classZero:
+		return floatValue{class: classZero, neg: neg}, true
+	}
+
+	return floatValue{
+		class: classFinite,
+		neg:   neg,
+		mant:  new(big.Int).Mul(x.mant, y.mant),
+		exp:   x.exp + y.exp,
+	}, true
+}
+
+// add calculates x + y rounded to format f.
+func (f floatFormat) add(x, y floatValue, rm expr.Rounding) (uint64, expr.FloatException) {
+	if nan, ex, ok := f.invalidNaN(x, y); ok {
+		return nan, ex
+	}
+	return f.addExact(x, y, rm)
+}
+
+// addExact adds values x and y which are not NaN, but which might be results of
+// previous exact calculations.
+func (f floatFormat) addExact(x, y floatValue, rm expr.Rounding) (uint64, expr.FloatException) {
+	switch {
+	case x.class == classInf && y.class == classInf && x.neg != y.neg:
+		return f.nan(), expr.FloatInvalid
+	case x.class == classInf:
+		return f.inf(x.neg), 0
+	case y.class == classInf:
+		return f.inf(y.neg), 0
+	case x.class == classZero && y.class == classZero:
+		if x.neg == y.neg {
+			return f.zero(x.neg), 0
+		}
+		return f.zero(rm == expr.RoundDown), 0
+	case x.class == classZero:
+		return f.round(y.neg, y.mant, y.exp, false, rm)
+	case y.class == classZero:
+		return f.round(x.neg, x.mant, x.exp, false, rm)
+	}
+
+	exp := x.exp
+	if y.exp < exp {
+		exp = y.exp
+	}
+
+	signed := func(v floatValue) *big.Int {
+		m := new(big.Int).Lsh(v.mant, uint(v.exp-exp))
+		if v.neg {
+			m.Neg(m)
+		}
+		return m
+	}
+
+	sum := new(big.Int).Add(signed(x), signed(y))
+	if sum.Sign() == 0 {
+		return f.zero(rm == expr.RoundDown), 0
+	}
+
+	neg := sum.Sign() < 0
+	return f.round(neg, sum.Abs(sum), exp, false, rm)
+}
+
+// div calculates x / y rounded to format f.
+func (f floatFormat) div(x, y floatValue, rm expr.Rounding) (uint64, expr.FloatException) {
+	if nan, ex, ok := f.invalidNaN(x, y); ok {
+		return nan, ex
+	}
+
+	neg := x.neg != y.neg
+	switch {
+	case x.class == y.class && (x.class == classInf || x.class == classZero):
+		return f.nan(), expr.FloatInvalid
+	case x.class == classInf:
+		return f.inf(neg), 0
+	case y.class == classInf, x.class == classZero:
+		return f.zero(neg), 0
+	case y.class == classZero:
+		return f.inf(neg), expr.FloatDivByZero
+	}
+
+	// The quotient has to have at least 2 more bits than the precision so
+	// that the remainder can be represented by the sticky bit.
+	shift := f.prec + 2 + y.mant.BitLen() - x.mant.BitLen()
+	if shift < 0 {
+		shift = 0
+	}
+
+	num := new(big.Int).Lsh(x.mant, uint(shift))
+	q, r := new(big.Int).QuoRem(num, y.mant, new(big.Int))
+	return f.round(neg, q, x.exp-y.exp-shift, r.Sign() != 0, rm)
+}
+
+// sqrt calculates square root of x rounded to format f.
+func (f floatFormat) sqrt(x floatValue, rm expr.Rounding) (uint64, expr.FloatException) {
+	if nan, ex, ok := f.invalidNaN(x); ok {
+		return nan, ex
+	}
+
+	switch {
+	case x.class == classZero:
+		return f.zero(x.neg), 0
+	case x.neg:
+		return f.nan(), expr.FloatInvalid
+	case x.class == classInf:
+		return f.inf(false), 0
+	}
+
+	// The root has to have at least 2 more bits than the precision and the
+	// exponent has to be even.
+	shift := 2*(f.prec+2) - x.mant.BitLen()
+	if shift < 0 {
+		shift = 0
+	}
+	if (x.exp-shift)%2 != 0 {
+		shift++
+	}
+
+	n := new(big.Int).Lsh(x.mant, uint(shift))
+	s := new(big.Int).Sqrt(n)
+	sticky := new(big.Int).Mul(s, s).Cmp(n) != 0
+	return f.round(false, s, (x.exp-shift)/2, sticky, rm)
+}
+
+// mulAdd calculates x * y + z rounded to format f.
+func (f floatFormat) mulAdd(x, y, z floatValue, rm expr.Rounding) (uint64, expr.FloatException) {
+	// NaN factors have no significand, so they have to be handled before
+	// the product is calculated.
+	if x.nan() || y.nan() {
+		nan, ex, _ := f.invalidNaN(x, y, z)
+		return nan, ex
+	}
+
+	// Invalid multiplication raises the invalid exception even if z is a
+	// quiet NaN.
+	p, ok := product(x, y)
+	if !ok {
+		_, ex, _ := f.invalidNaN(z)
+		return f.nan(), ex | expr.FloatInvalid
+	}
+	if nan, ex, ok := f.invalidNaN(z); ok {
+		return nan, ex
+	}
+
+	return f.addExact(p, z, rm)
+}
+
+// minMax returns the smaller (or bigger if max is set) of a and b.
+func (f floatFormat) minMax(max bool, a, b uint64, x, y floatValue) (uint64, expr.FloatException) {
+	var ex expr.FloatException
+	if x.class == classSNaN || y.class == classSNaN {
+		ex = expr.FloatInvalid
+	}
+
+	switch {
+	case x.nan() && y.nan():
+		return f.nan(), ex
+	case x.nan():
+		return b, ex
+	case y.nan():
+		return a, ex
+	}
+
+	if f.less(a, b) != max {
+		return a, ex
+	}
+	return b, ex
+}
+
+// compare evaluates comparison op of a and b.
+func (f floatFormat) compare(op expr.FloatOp, a, b uint64, x, y floatValue) (uint64, expr.FloatException) {
+	if x.nan() || y.nan() {
+		if op != expr.FEq || x.class == classSNaN || y.class == classSNaN {
+			return 0, expr.FloatInvalid
+		}
+		return 0, 0
+	}
+
+	var res bool
+	switch op {
+	case expr.FEq:
+		res = f.equal(a, b)
+	case expr.FLt:
+		res = f.less(a, b) && !f.equal(a, b)
+	default:
+		res = f.less(a, b) || f.equal(a, b)
+	}
+
+	if res {
+		return 1, 0
+	}
+	return 0, 0
+}
+
+// convert rounds x to format f.
+func convert(f floatFormat, x floatValue, rm expr.Rounding) (uint64, expr.FloatException) {
+	if nan, ex, ok := f.invalidNaN(x); ok {
+		return nan, ex
+	}
+	return f.roundValue(x, rm)
+}
+
+// fromInt converts integer of width w to format f.
+func fromInt(
+	f floatFormat,
+	val uint64,
+	w expr.Width,
+	signed bool,
+	rm expr.Rounding,
+) (uint64, expr.FloatException) {
+	bits := uint(w) * 8
+	if bits < 64 {
+		val &= 1<<bits - 1
+	}
+
+	var neg bool
+	if signed && val>>(bits-1)&1 == 1 {
+		neg = true
+		val = -(val | ^uint64(0)<<(bits-1))
+	}
+
+	return f.round(neg, new(big.Int).SetUint64(val), 0, false, rm)
+}
+
+// toInt converts x to an integer of width w. It saturates values out of range
+// of the integer type.
+func toInt(x floatValue, w expr.Width, signed bool, rm expr.Rounding) (uint64, expr.FloatException) {
+	bits := uint(w) * 8
+
+	max := new(big.Int).Lsh(big.NewInt(1), bits)
+	min := new(big.Int)
+	if signed {
+		max.Rsh(max, 1)
+		min.Neg(max)
+	}
+	max.Sub(max, big.NewInt(1))
+
+	// Two's complement encoding of i in bits.
+	encode := func(i *big.Int) uint64 {
+		if i.Sign() >= 0 {
+			return i.Uint64()
+		}
+		v := uint64(i.Int64())
+		if bits < 64 {
+			v &= 1<<bits - 1
+		}
+		return v
+	}
+
+	switch x.class {
+	case classZero:
+		return 0, 0
+	case classQNaN, classSNaN:
+		return encode(max), expr.FloatInvalid
+	case classInf:
+		if x.neg {
+			return encode(min), expr.FloatInvalid
+		}
+		return encode(max), expr.FloatInvalid
+	}
+
+	mag, inexact := roundMag(x.neg, x.mant, x.exp, false, 0, rm)
+	if x.neg {
+		mag.Neg(mag)
+	}
+
+	switch {
+	case mag.Cmp(min) < 0:
+		return encode(min), expr.FloatInvalid
+	case mag.Cmp(max) > 0:
+		return encode(max), expr.FloatInvalid
+	case inexact:
+		return encode(mag), expr.FloatInexact
+	default:
+		return encode(mag), 0
+	}
+}
@@ -0,0 +1,451 @@
+package expreval_test
+
+import (
+	"math"
+	"math/rand"
+	"mltwist/internal/exprtransform/internal/expreval"
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	nan32 = 0x7fc00000
+	nan64 = 0x7ff8000000000000
+)
+
+func f32(f float32) expreval.Value {
+	return valUint(uint64(math.Float32bits(f)), expr.Width32)
+}
+
+func f64(f float64) expreval.Value {
+	return valUint(math.Float64bits(f), expr.Width64)
+}
+
+func rmValue(rm expr.Rounding) expreval.Value {
+	return expreval.ParseConst(rm.Const())
+}
+
+func TestFloat(t *testing.T) {
+	tests := []struct {
+		name   string
+		op     expr.FloatOp
+		rm     expr.Rounding
+		args   []expreval.Value
+		argW   expr.Width
+		w      expr.Width
+		result expreval.Value
+		flags  expr.FloatException
+	}{{
+		name:   "add_exact",
+		op:     expr.FAdd,
+		args:   []expreval.Value{f64(1.5), f64(2.25)},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: f64(3.75),
+	}, {
+		name:   "add_inexact",
+		op:     expr.FAdd,
+		args:   []expreval.Value{f64(1), f64(0x1p-60)},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: f64(1),
+		flags:  expr.FloatInexact,
+	}, {
+		name:   "add_round_up",
+		op:     expr.FAdd,
+		rm:     expr.RoundUp,
+		args:   []expreval.Value{f64(1), f64(0x1p-60)},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: f64(1 + 0x1p-52),
+		flags:  expr.FloatInexact,
+	}, {
+		name:   "sub_zero_round_down",
+		op:     expr.FSub,
+		rm:     expr.RoundDown,
+		args:   []expreval.Value{f32(1), f32(1)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: f32(float32(math.Copysign(0, -1))),
+	}, {
+		name:   "tie_to_even",
+		op:     expr.FAdd,
+		args:   []expreval.Value{f32(1), f32(0x1p-24)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: f32(1),
+		flags:  expr.FloatInexact,
+	}, {
+		name:   "tie_max_magnitude",
+		op:     expr.FAdd,
+		rm:     expr.RoundNearestMaxMagnitude,
+		args:   []expreval.Value{f32(1), f32(0x1p-24)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: f32(1 + 0x1p-23),
+		flags:  expr.FloatInexact,
+	}, {
+		name:   "overflow",
+		op:     expr.FMul,
+		args:   []expreval.Value{f32(math.MaxFloat32), f32(2)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: f32(float32(math.Inf(1))),
+		flags:  expr.FloatOverflow | expr.FloatInexact,
+	}, {
+		name:   "overflow_toward_zero",
+		op:     expr.FMul,
+		rm:     expr.RoundTowardZero,
+		args:   []expreval.Value{f32(math.MaxFloat32), f32(2)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: f32(math.MaxFloat32),
+		flags:  expr.FloatOverflow | expr.FloatInexact,
+	}, {
+		name:   "underflow",
+		op:     expr.FMul,
+		args:   []expreval.Value{f64(0x1p-1074), f64(0.5)},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: f64(0),
+		flags:  expr.FloatUnderflow | expr.FloatInexact,
+	}, {
+		name:   "subnormal_exact",
+		op:     expr.FMul,
+		args:   []expreval.Value{f64(0x1p-1070), f64(0x1p-2)},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: f64(0x1p-1072),
+	}, {
+		name:   "subnormal_no_underflow",
+		op:     expr.FDiv,
+		args:   []expreval.Value{f64(0x1p-1070), f64(2)},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: f64(0x1p-1071),
+	}, {
+		name:   "div_by_zero",
+		op:     expr.FDiv,
+		args:   []expreval.Value{f64(-1), f64(0)},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: f64(math.Inf(-1)),
+		flags:  expr.FloatDivByZero,
+	}, {
+		name:   "zero_div_zero",
+		op:     expr.FDiv,
+		args:   []expreval.Value{f64(0), f64(0)},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: valUint(nan64, expr.Width64),
+		flags:  expr.FloatInvalid,
+	}, {
+		name:   "sqrt_negative",
+		op:     expr.FSqrt,
+		args:   []expreval.Value{f32(-4)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: valUint(nan32, expr.Width32),
+		flags:  expr.FloatInvalid,
+	}, {
+		name:   "sqrt_negative_zero",
+		op:     expr.FSqrt,
+		args:   []expreval.Value{f32(float32(math.Copysign(0, -1)))},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: f32(float32(math.Copysign(0, -1))),
+	}, {
+		name:   "signaling_nan",
+		op:     expr.FAdd,
+		args:   []expreval.Value{valUint(0x7f800001, expr.Width32), f32(1)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: valUint(nan32, expr.Width32),
+		flags:  expr.FloatInvalid,
+	}, {
+		name:   "quiet_nan",
+		op:     expr.FAdd,
+		args:   []expreval.Value{valUint(0xffc00123, expr.Width32), f32(1)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: valUint(nan32, expr.Width32),
+	}, {
+		name:   "fma_single_rounding",
+		op:     expr.FMulAdd,
+		args:   []expreval.Value{f64(1 + 0x1p-30), f64(1 - 0x1p-30), f64(-1)},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: f64(-0x1p-60),
+	}, {
+		name: "fma_inf_zero_qnan",
+		op:   expr.FMulAdd,
+		args: []expreval.Value{
+			f64(math.Inf(1)),
+			f64(0),
+			valUint(nan64, expr.Width64),
+		},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: valUint(nan64, expr.Width64),
+		flags:  expr.FloatInvalid,
+	}, {
+		name: "fma_zero_inf",
+		op:   expr.FMulAdd,
+		args: []expreval.Value{
+			f64(0),
+			f64(math.Inf(-1)),
+			f64(1),
+		},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: valUint(nan64, expr.Width64),
+		flags:  expr.FloatInvalid,
+	}, {
+		name:   "fma_qnan_first",
+		op:     expr.FMulAdd,
+		args:   []expreval.Value{valUint(nan32, expr.Width32), f32(1), f32(1)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: valUint(nan32, expr.Width32),
+	}, {
+		name:   "fma_qnan_second",
+		op:     expr.FMulAdd,
+		args:   []expreval.Value{f64(0), valUint(nan64, expr.Width64), f64(1)},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: valUint(nan64, expr.Width64),
+	}, {
+		name: "fma_snan_first",
+		op:   expr.FMulAdd,
+		args: []expreval.Value{
+			valUint(0x7f800001, expr.Width32),
+			f32(float32(math.Inf(1))),
+			f32(1),
+		},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: valUint(nan32, expr.Width32),
+		flags:  expr.FloatInvalid,
+	}, {
+		name:   "fma_qnan_addend",
+		op:     expr.FMulAdd,
+		args:   []expreval.Value{f32(2), f32(3), valUint(nan32, expr.Width32)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: valUint(nan32, expr.Width32),
+	}, {
+		name:   "fma_snan_addend",
+		op:     expr.FMulAdd,
+		args:   []expreval.Value{f32(2), f32(3), valUint(0x7f800001, expr.Width32)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: valUint(nan32, expr.Width32),
+		flags:  expr.FloatInvalid,
+	}, {
+		name:   "min_zeros",
+		op:     expr.FMin,
+		args:   []expreval.Value{f32(0), f32(float32(math.Copysign(0, -1)))},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: f32(float32(math.Copysign(0, -1))),
+	}, {
+		name:   "max_nan",
+		op:     expr.FMax,
+		args:   []expreval.Value{valUint(nan32, expr.Width32), f32(-3)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: f32(-3),
+	}, {
+		name:   "max_snan",
+		op:     expr.FMax,
+		args:   []expreval.Value{valUint(0x7f800001, expr.Width32), f32(-3)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: f32(-3),
+		flags:  expr.FloatInvalid,
+	}, {
+		name:   "eq_zeros",
+		op:     expr.FEq,
+		args:   []expreval.Value{f64(0), f64(math.Copysign(0, -1))},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: valUint(1, expr.Width64),
+	}, {
+		name:   "eq_nan_quiet",
+		op:     expr.FEq,
+		args:   []expreval.Value{valUint(nan64, expr.Width64), f64(1)},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: valUint(0, expr.Width64),
+	}, {
+		name:   "lt_nan_signaling",
+		op:     expr.FLt,
+		args:   []expreval.Value{valUint(nan64, expr.Width64), f64(1)},
+		argW:   expr.Width64,
+		w:      expr.Width64,
+		result: valUint(0, expr.Width64),
+		flags:  expr.FloatInvalid,
+	}, {
+		name:   "le_negative",
+		op:     expr.FLe,
+		args:   []expreval.Value{f32(-2), f32(-1)},
+		argW:   expr.Width32,
+		w:      expr.Width32,
+		result: valUint(1, expr.Width32),
+	}, {
+		name:   "convert_narrow",
+		op:     expr.FConvert,
+		args:   []expreval.Value{f64(0.1)},
+		argW:   expr.Width64,
+		w:      expr.Width32,
+		result: f32(0.1),
+		flags:  expr.FloatInexact,
+	}, {
+		name:   "convert_widen",
+		op:     expr.FConvert,
+		args:   []expreval.Value{f32(0.1)},
+		argW:   expr.Width32,
+		w:      expr.Width64,
+		result: f64(float64(float32(0.1))),
+	}, {
+		name:   "from_int",
+		op:     expr.FFromInt,
+		args:   []expreval.Value{valUint(uint64(math.MaxUint32), expr.Width32)},
+		argW:   expr.Width32,
+		w:      expr.Width64,
+		result: f64(-1),
+	}, {
+		name:   "from_uint_inexact",
+		op:     expr.FFromUint,
+		args:   []expreval.Value{valUint(math.MaxUint64, expr.Width64)},
+		argW:   expr.Width64,
+		w:      expr.Width32,
+		result: f32(0x1p64),
+		flags:  expr.FloatInexact,
+	}, {
+		name:   "to_int_truncate",
+		op:     expr.FToInt,
+		rm:     expr.RoundTowardZero,
+		args:   []expreval.Value{f64(-2.75)},
+		argW:   expr.Width64,
+		w:      expr.Width32,
+		result: valUint(uint64(math.MaxUint32)-1, expr.Width32),
+		flags:  expr.FloatInexact,
+	}, {
+		name:   "to_int_saturate",
+		op:     expr.FToInt,
+		args:   []expreval.Value{f64(1e10)},
+		argW:   expr.Width64,
+		w:      expr.Width32,
+		result: valUint(math.MaxInt32, expr.Width32),
+		flags:  expr.FloatInvalid,
+	}, {
+		name:   "to_int_nan",
+		op:     expr.FToInt,
+		args:   []expreval.Value{valUint(nan32, expr.Width32)},
+		argW:   expr.Width32,
+		w:      expr.Width64,
+		result: valUint(math.MaxInt64, expr.Width64),
+		flags:  expr.FloatInvalid,
+	}, {
+		name:   "to_uint_negative",
+		op:     expr.FToUint,
+		args:   []expreval.Value{f32(-1)},
+		argW:   expr.Width32,
+		w:      expr.Width64,
+		result: valUint(0, expr.Width64),
+		flags:  expr.FloatInvalid,
+	}, {
+		name:   "to_uint_small_negative",
+		op:     expr.FToUint,
+		args:   []expreval.Value{f32(-0.25)},
+		argW:   expr.Width32,
+		w:      expr.Width64,
+		result: valUint(0, expr.Width64),
+		flags:  expr.FloatInexact,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			rm := rmValue(tt.rm)
+
+			res := expreval.Float(tt.op, rm, tt.args, tt.argW, tt.w, false)
+			r.Equal(tt.result, res)
+
+			flags := expreval.Float(tt.op, rm, tt.args, tt.argW, tt.w, true)
+			r.Equal(valUint(uint64(tt.flags), tt.w), flags)
+		})
+	}
+}
+
+// TestFloat_Native compares results of operations in the default rounding mode
+// with results of the native floating point implementation.
+func TestFloat_Native(t *testing.T) {
+	tests := []struct {
+		name string
+		op   expr.FloatOp
+		f    func(args ...float64) float64
+	}{{
+		name: "add",
+		op:   expr.FAdd,
+		f:    func(a ...float64) float64 { return a[0] + a[1] },
+	}, {
+		name: "sub",
+		op:   expr.FSub,
+		f:    func(a ...float64) float64 { return a[0] - a[1] },
+	}, {
+		name: "mul",
+		op:   expr.FMul,
+		f:    func(a ...float64) float64 { return a[0] * a[1] },
+	}, {
+		name: "div",
+		op:   expr.FDiv,
+		f:    func(a ...float64) float64 { return a[0] / a[1] },
+	}, {
+		name: "sqrt",
+		op:   expr.FSqrt,
+		f:    func(a ...float64) float64 { return math.Sqrt(a[0]) },
+	}, {
+		name: "fma",
+		op:   expr.FMulAdd,
+		f:    func(a ...float64) float64 { return math.FMA(a[0], a[1], a[2]) },
+	}}
+
+	rnd := rand.New(rand.NewSource(1))
+	randFloat := func() float64 {
+		// Random bit patterns cover all classes of values including
+		// subnormals, while values close to each other exercise
+		// cancellation.
+		if rnd.Intn(4) == 0 {
+			return float64(rnd.Intn(8) - 4)
+		}
+		return math.Float64frombits(rnd.Uint64() &^ (1 << 62 >> rnd.Intn(2)))
+	}
+
+	rm := rmValue(expr.RoundNearestEven)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 10000; i++ {
+				fs := make([]float64, tt.op.Arity())
+				vals := make([]expreval.Value, len(fs))
+				for j := range fs {
+					fs[j] = randFloat()
+					vals[j] = f64(fs[j])
+				}
+
+				exp := tt.f(fs...)
+				if math.IsNaN(exp) {
+					exp = math.Float64frombits(nan64)
+				}
+
+				res := expreval.Float(tt.op, rm, vals, expr.Width64, expr.Width64, false)
+				require.Equal(t, f64(exp), res, "%v", fs)
+			}
+		})
+	}
+}
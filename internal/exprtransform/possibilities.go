@@ -39,6 +39,29 @@ func Possibilities(ex expr.Expr) []expr.Expr {
 			es = append(es, SetWidth(e2, e.Width()))
 		}
 
+		return es
+	case expr.Float:
+		// Every argument (including the rounding mode) multiplies number
+		// of possibilities.
+		combinations := [][]expr.Expr{nil}
+		for _, a := range append([]expr.Expr{e.Rounding()}, e.Args()...) {
+			as := Possibilities(a)
+
+			next := make([][]expr.Expr, 0, len(combinations)*len(as))
+			for _, c := range combinations {
+				for _, arg := range as {
+					comb := append(append([]expr.Expr{}, c...), arg)
+					next = append(next, comb)
+				}
+			}
+			combinations = next
+		}
+
+		es := make([]expr.Expr, len(combinations))
+		for i, c := range combinations {
+			es[i] = e.WithArgs(c[0], c[1:]...)
+		}
+
 		return es
 	case expr.Const, expr.RegLoad:
 		return []expr.Expr{ex}
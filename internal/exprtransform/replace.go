@@ -51,7 +51,19 @@ func replaceAll[T expr.Expr](
 		if changed {
 			ex = expr.NewLess(arg1, arg2, et, ef, e.Width())
 		}
+	case expr.Float:
+		rm, changedRm := replaceAll(e.Rounding(), f)
+		changed = changedRm
 
+		args := make([]expr.Expr, len(e.Args()))
+		for i, a := range e.Args() {
+			arg, changedArg := replaceAll(a, f)
+			args[i], changed = arg, changed || changedArg
+		}
+
+		if changed {
+			ex = e.WithArgs(rm, args...)
+		}
 	case expr.MemLoad:
 		addr, changedAddr := replaceAll(e.Addr(), f)
 
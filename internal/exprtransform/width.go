@@ -36,7 +36,7 @@ func setWidth(ex expr.Expr, w expr.Width) (expr.Expr, bool) {
 	}
 
 	switch e := ex.(type) {
-	case expr.Binary, expr.Less:
+	case expr.Binary, expr.Less, expr.Float:
 		// We ignore any smart optimization here as it's significantly
 		// simpler to now enter gadgets and drop them later in
 		// purgeWidthGadgets function.
@@ -133,6 +133,21 @@ func purgeWidthGadgets(ex expr.Expr) (expr.Expr, bool) {
 			return ex, false
 		}
 		return expr.NewLess(c1, c2, et, ef, e.Width()), true
+	case expr.Float:
+		// Rounding mode keeps its width as it's evaluated as a whole.
+		rm, changed := purgeWidthGadgetsKeepWidth(e.Rounding())
+
+		args := make([]expr.Expr, len(e.Args()))
+		for i, a := range e.Args() {
+			arg, changedArg := purgeWidthGadgets(a)
+			arg, prunedArg := pruneUselessWidthGadgets(arg, e.ArgWidth())
+			args[i], changed = arg, changed || changedArg || prunedArg
+		}
+
+		if !changed {
+			return ex, false
+		}
+		return e.WithArgs(rm, args...), true
 	case expr.MemLoad:
 		// Address keeps its width independently on width of MemLoad.
 		addr, changedAddr := purgeWidthGadgetsKeepWidth(e.Addr())
@@ -0,0 +1,64 @@
+package mips
+
+import (
+	debugelf "debug/elf"
+	"fmt"
+	"mltwist/internal/elf"
+	"mltwist/internal/parser"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"strconv"
+	"strings"
+)
+
+func init() { parser.Register(debugelf.EM_MIPS, newArchitecture) }
+
+// newArchitecture creates description of MIPS32 architecture of ELF file p.
+// Byte order of instructions is given by the file. ISA strings are not
+// supported.
+func newArchitecture(p *elf.Parser, isa string) (parser.Architecture, error) {
+	if isa != "" {
+		return parser.Architecture{}, parser.ErrISAUnsupported
+	}
+
+	et, err := p.Target()
+	if err != nil {
+		return parser.Architecture{}, err
+	}
+	if et.Class != debugelf.ELFCLASS32 {
+		return parser.Architecture{}, fmt.Errorf(
+			"only 32 bit MIPS files are supported")
+	}
+
+	mp := NewParser(et.ByteOrder)
+	return parser.Architecture{
+		Name:        "MIPS32",
+		Parser:      mp,
+		MemoryKey:   MemoryKey,
+		MinInstrLen: mp.MinInstrLen(),
+		IPWidth:     width32,
+		ByteOrder:   et.ByteOrder,
+
+		IPName:       "pc",
+		RegisterName: registerName,
+		// Code is displayed the same way GNU objdump displays it by
+		// default: with ABI names of registers and aliases.
+		Syntax: model.Syntax{ABINames: true, Aliases: true},
+	}, nil
+}
+
+// registerName returns name of register key in assembler code. Names defined by
+// the calling convention are returned if abi is set. Other registers are named
+// by their keys.
+func registerName(key expr.Key, abi bool) string {
+	s := string(key)
+	if !strings.HasPrefix(s, "r") {
+		return s
+	}
+
+	n, err := strconv.ParseUint(s[1:], 10, 8)
+	if err != nil || n >= regCnt {
+		return s
+	}
+	return regNum(n).name(abi)
+}
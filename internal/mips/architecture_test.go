@@ -0,0 +1,25 @@
+package mips
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterName(t *testing.T) {
+	tests := []struct {
+		key  expr.Key
+		abi  bool
+		want string
+	}{
+		{key: "r29", want: "$29"},
+		{key: "r29", abi: true, want: "$sp"},
+		{key: HiKey, abi: true, want: "hi"},
+		{key: "r32", abi: true, want: "r32"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, registerName(tt.key, tt.abi), tt.key)
+	}
+}
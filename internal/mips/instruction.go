@@ -0,0 +1,157 @@
+package mips
+
+import (
+	"encoding/binary"
+	"fmt"
+	"mltwist/pkg/model"
+	"strings"
+)
+
+// instruction represents a parsed MIPS32 instruction. Unlike the
+// instructionType which describes only instruction opcode and properties of the
+// opcode, instruction represents the whole instruction including encoding of
+// registers, immediate values and last but not least the position in the code.
+type instruction struct {
+	// addr is virtual address of the instruction in a program memory.
+	addr model.Addr
+
+	// value represents the value of the instruction as a 32 bit word.
+	// Bit 0 of the value is the least significant bit of the word
+	// regardless of byte order the instruction is stored in the memory.
+	value uint32
+	// order is byte order of the instruction in the memory.
+	order binary.ByteOrder
+
+	// instrType refers the type of the instruction.
+	instrType *instructionType
+}
+
+// newInstruction crates a new instance of instruction. The new instruction is
+// at address a, is represented of first instructionLen bytes of b stored in
+// byte order order and has type t.
+func newInstruction(
+	a model.Addr,
+	b []byte,
+	order binary.ByteOrder,
+	t *instructionType,
+) instruction {
+	if l := len(b); l < instructionLen {
+		panic(fmt.Sprintf("not enough bytes to represent valid opcode: %d", l))
+	}
+
+	return instruction{
+		addr:      a,
+		value:     order.Uint32(b[:instructionLen]),
+		order:     order,
+		instrType: t,
+	}
+}
+
+// field returns bits bits of the instruction starting with bit at index
+// offset.
+func (i instruction) field(offset uint8, bits uint8) uint32 {
+	return bitRange(i.value, offset, bits)
+}
+
+// rs returns register number encoded in bits [21:25] of the instruction.
+func (i instruction) rs() regNum { return regNum(i.field(21, regBits)) }
+
+// rt returns register number encoded in bits [16:20] of the instruction.
+func (i instruction) rt() regNum { return regNum(i.field(16, regBits)) }
+
+// rd returns register number encoded in bits [11:15] of the instruction.
+func (i instruction) rd() regNum { return regNum(i.field(11, regBits)) }
+
+// sa returns shift amount encoded in bits [6:10] of the instruction.
+func (i instruction) sa() uint32 { return i.field(6, 5) }
+
+// imm returns unsigned 16 bit immediate value encoded in bits [0:15] of the
+// instruction.
+func (i instruction) imm() uint32 { return i.field(0, 16) }
+
+// simm returns signed 16 bit immediate value encoded in bits [0:15] of the
+// instruction.
+func (i instruction) simm() int64 { return signExtend(i.imm(), 16) }
+
+// bigEndian indicates that the instruction belongs to a big-endian program.
+func (i instruction) bigEndian() bool { return i.order == binary.BigEndian }
+
+// linkAddr returns the address branch and link instructions store to the link
+// register. It's the address following the delay slot.
+func (i instruction) linkAddr() model.Addr { return i.addr + 2*instructionLen }
+
+// target returns the address the branch instruction jumps to.
+func (i instruction) target() model.Addr {
+	a := i.instrType.offset.target(i.value, i.addr)
+	return model.Addr(uint32(a))
+}
+
+var _ model.Relocator = instruction{}
+
+// Name returns name of the instruction in assembler code.
+func (i instruction) Name() string { return i.instrType.name }
+
+// String returns a string representation of an instruction which corresponds to
+// standard MIPS assembler notation of instructions.
+//
+// Registers are written by their numbers and instructions are written without
+// aliases, so for example move is written as addu instruction it's encoded as.
+func (i instruction) String() string { return i.Format(model.Syntax{}, nil) }
+
+var _ model.Formatter = instruction{}
+
+// Format returns a string representation of an instruction in assembler syntax
+// s. If aliases are enabled, instructions are written as their preferred
+// aliases the same way GNU objdump does.
+func (i instruction) Format(s model.Syntax, _ model.PlatformDetails) string {
+	name, ops := i.Name(), i.instrType.operands(i, s.ABINames)
+	if a := i.instrType.alias; s.Aliases && a != nil {
+		if n, aliasOps, ok := a(i, ops); ok {
+			name, ops = n, aliasOps
+		}
+	}
+
+	return instructionString(name, ops...)
+}
+
+// instructionString returns assembler code of an instruction called name with
+// operands ops.
+func instructionString(name string, ops ...string) string {
+	if len(ops) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s %s", name, strings.Join(ops, ", "))
+}
+
+// bytes returns the instruction encoded as a sequence of bytes in the memory.
+func (i instruction) bytes() []byte {
+	bs := make([]byte, instructionLen)
+	i.order.PutUint32(bs, i.value)
+	return bs
+}
+
+// Relocate returns the instruction and its opcode bytes as if the instruction
+// was placed at address a.
+//
+// Targets of branch instructions are re-encoded so that the instruction jumps
+// to the same address as it jumped before the move. An error is returned if
+// the target cannot be represented by the instruction at the new address.
+// Other instructions are returned unchanged.
+func (i instruction) Relocate(
+	a model.Addr,
+) (model.PlatformDetails, []byte, error) {
+	t := i.instrType.offset
+	if t == offsetNone {
+		i.addr = a
+		return i, i.bytes(), nil
+	}
+
+	encoded, err := t.encode(i.target(), a)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot encode target of %q: %w", i, err)
+	}
+
+	i.addr = a
+	i.value = i.value&^t.fieldMask() | encoded
+	return i, i.bytes(), nil
+}
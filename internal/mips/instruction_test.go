@@ -0,0 +1,206 @@
+package mips
+
+import (
+	"encoding/binary"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstruction_Format(t *testing.T) {
+	abi := model.Syntax{ABINames: true}
+	aliases := model.Syntax{Aliases: true}
+	both := model.Syntax{ABINames: true, Aliases: true}
+
+	tests := []struct {
+		name   string
+		bytes  []byte
+		syntax model.Syntax
+		want   string
+	}{{
+		// addiu $sp, $sp, -32
+		name:  "default",
+		bytes: []byte{0xe0, 0xff, 0xbd, 0x27},
+		want:  "addiu $29, $29, -32",
+	}, {
+		// addiu $sp, $sp, -32
+		name:   "abi_names",
+		bytes:  []byte{0xe0, 0xff, 0xbd, 0x27},
+		syntax: abi,
+		want:   "addiu $sp, $sp, -32",
+	}, {
+		// lw $ra, 28($sp)
+		name:   "memory",
+		bytes:  []byte{0x1c, 0x00, 0xbf, 0x8f},
+		syntax: abi,
+		want:   "lw $ra, 28($sp)",
+	}, {
+		// lwl $2, 3($3)
+		name:   "unaligned_memory",
+		bytes:  []byte{0x03, 0x00, 0x62, 0x88},
+		syntax: abi,
+		want:   "lwl $v0, 3($v1)",
+	}, {
+		// nop
+		name:   "nop",
+		bytes:  []byte{0x00, 0x00, 0x00, 0x00},
+		syntax: aliases,
+		want:   "nop",
+	}, {
+		// or $2, $3, $0
+		name:   "move",
+		bytes:  []byte{0x25, 0x10, 0x60, 0x00},
+		syntax: both,
+		want:   "move $v0, $v1",
+	}, {
+		// ori $2, $0, 0x1234
+		name:   "li",
+		bytes:  []byte{0x34, 0x12, 0x02, 0x34},
+		syntax: aliases,
+		want:   "li $2, 0x1234",
+	}, {
+		// beq $0, $0, 8
+		name:   "b",
+		bytes:  []byte{0x02, 0x00, 0x00, 0x10},
+		syntax: aliases,
+		want:   "b 0x100c",
+	}, {
+		// bnez $4, -8
+		name:   "bnez",
+		bytes:  []byte{0xfe, 0xff, 0x80, 0x14},
+		syntax: both,
+		want:   "bnez $a0, 0xffc",
+	}, {
+		// bgezal $0, 8
+		name:   "bal",
+		bytes:  []byte{0x02, 0x00, 0x11, 0x04},
+		syntax: aliases,
+		want:   "bal 0x100c",
+	}, {
+		// jal 0x2000
+		name:  "jal",
+		bytes: []byte{0x00, 0x08, 0x00, 0x0c},
+		want:  "jal 0x2000",
+	}, {
+		// jalr $25
+		name:   "jalr",
+		bytes:  []byte{0x09, 0xf8, 0x20, 0x03},
+		syntax: abi,
+		want:   "jalr $t9",
+	}, {
+		// ext $2, $3, 4, 8
+		name:  "ext",
+		bytes: []byte{0x00, 0x39, 0x62, 0x7c},
+		want:  "ext $2, $3, 4, 8",
+	}, {
+		// ins $2, $3, 4, 8
+		name:  "ins",
+		bytes: []byte{0x04, 0x59, 0x62, 0x7c},
+		want:  "ins $2, $3, 4, 8",
+	}, {
+		// rdhwr $3, $29
+		name:   "rdhwr",
+		bytes:  []byte{0x3b, 0xe8, 0x03, 0x7c},
+		syntax: abi,
+		want:   "rdhwr $v1, $29",
+	}}
+
+	p := NewParser(binary.LittleEndian)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := p.Parse(testAddr, tt.bytes)
+			require.NoError(t, err)
+
+			f := ins.Details.(model.Formatter)
+			require.Equal(t, tt.want, f.Format(tt.syntax, nil))
+		})
+	}
+}
+
+func TestInstruction_Relocate(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  binary.ByteOrder
+		bytes  []byte
+		addr   model.Addr
+		to     model.Addr
+		hasErr bool
+	}{{
+		// beq $4, $5, 16
+		name:  "branch",
+		bytes: []byte{0x04, 0x00, 0x85, 0x10},
+		addr:  0x1000,
+		to:    0x1080,
+	}, {
+		// beq $4, $5, 16
+		name:  "branch_big_endian",
+		order: binary.BigEndian,
+		bytes: []byte{0x10, 0x85, 0x00, 0x04},
+		addr:  0x1000,
+		to:    0x0f00,
+	}, {
+		// beq $4, $5, 16
+		name:   "branch_too_far",
+		bytes:  []byte{0x04, 0x00, 0x85, 0x10},
+		addr:   0x1000,
+		to:     0x100000,
+		hasErr: true,
+	}, {
+		// jal 0x2000
+		name:  "jump",
+		bytes: []byte{0x00, 0x08, 0x00, 0x0c},
+		addr:  0x1000,
+		to:    0x400000,
+	}, {
+		// jal 0x2000
+		name:   "jump_other_region",
+		bytes:  []byte{0x00, 0x08, 0x00, 0x0c},
+		addr:   0x1000,
+		to:     0x10000000,
+		hasErr: true,
+	}, {
+		// addu $2, $3, $4
+		name:  "not_relative",
+		bytes: []byte{0x21, 0x10, 0x64, 0x00},
+		addr:  0x1000,
+		to:    0x1010,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			order := tt.order
+			if order == nil {
+				order = binary.LittleEndian
+			}
+			p := NewParser(order)
+
+			ins, err := p.Parse(tt.addr, tt.bytes)
+			r.NoError(err)
+
+			details, bytes, err := ins.Details.(model.Relocator).Relocate(tt.to)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			moved, err := p.Parse(tt.to, bytes)
+			r.NoError(err)
+			r.Equal(moved.Details, details)
+
+			orig, i := ins.Details.(instruction), details.(instruction)
+			r.Equal(tt.to, i.addr)
+			r.Equal(orig.Name(), i.Name())
+			if orig.instrType.offset != offsetNone {
+				r.Equal(orig.target(), i.target())
+			} else {
+				r.Equal(orig.value, i.value)
+			}
+		})
+	}
+}
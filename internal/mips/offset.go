@@ -0,0 +1,88 @@
+package mips
+
+import (
+	"fmt"
+	"mltwist/pkg/model"
+)
+
+// jumpRegion is size of the region of memory j and jal instructions can jump
+// within.
+const jumpRegion = 1 << 28
+
+// offsetType describes encoding of a branch target in an instruction.
+//
+// Targets of all MIPS32 branches are relative to address of the delay slot,
+// i.e. to address of the instruction following the branch.
+type offsetType uint8
+
+const (
+	// offsetNone means that an instruction has no target encoded.
+	offsetNone offsetType = iota
+	// offsetBranch is a signed offset of words encoded in bits [0:15] of
+	// conditional branches.
+	offsetBranch
+	// offsetJump is an index of a word within the 256 MB aligned region
+	// of the delay slot encoded in bits [0:25] of j and jal instructions.
+	offsetJump
+)
+
+// fieldMask returns mask of bits of an instruction encoding the target.
+func (t offsetType) fieldMask() uint32 {
+	switch t {
+	case offsetBranch:
+		return 1<<16 - 1
+	case offsetJump:
+		return 1<<26 - 1
+	default:
+		return 0
+	}
+}
+
+// target returns address instruction value at address a refers.
+func (t offsetType) target(value uint32, a model.Addr) model.Addr {
+	slot := a + instructionLen
+	switch t {
+	case offsetBranch:
+		return slot + model.Addr(signExtend(value&t.fieldMask(), 16)<<2)
+	case offsetJump:
+		return slot&^(jumpRegion-1) | model.Addr(value&t.fieldMask())<<2
+	default:
+		return 0
+	}
+}
+
+// encode returns target address target encoded in bits of an instruction at
+// address a. Bits of the instruction which don't encode the target are zero.
+func (t offsetType) encode(target, a model.Addr) (uint32, error) {
+	if target&0b11 != 0 {
+		return 0, fmt.Errorf("target is not aligned to 4 bytes: 0x%x", target)
+	}
+
+	slot := a + instructionLen
+	if t == offsetJump {
+		if slot&^(jumpRegion-1) != target&^(jumpRegion-1) {
+			return 0, fmt.Errorf(
+				"target 0x%x is outside of the 256 MB region of 0x%x",
+				target, slot)
+		}
+		return uint32(target&(jumpRegion-1)) >> 2, nil
+	}
+
+	field := model.Offset(slot, target) >> 2
+	if field < -(1<<15) || field >= 1<<15 {
+		return 0, fmt.Errorf("offset doesn't fit 16 bits: %d",
+			model.Offset(slot, target))
+	}
+	return uint32(field) & t.fieldMask(), nil
+}
+
+// bitRange returns bits bits of value starting with bit at index offset.
+func bitRange(value uint32, offset uint8, bits uint8) uint32 {
+	return value >> offset & (1<<bits - 1)
+}
+
+// signExtend interprets the lowest bits bits of value as a signed integer.
+func signExtend(value uint32, bits uint8) int64 {
+	shift := 64 - bits
+	return int64(uint64(value)<<shift) >> shift
+}
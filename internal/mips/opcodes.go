@@ -0,0 +1,85 @@
+package mips
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"mltwist/pkg/model"
+)
+
+// MemoryKey is identifier of the memory address space.
+//
+// Given that MIPS32 defines only one memory space, there is no reason to
+// explain which memory is identified by this key.
+const MemoryKey = expr.Key("memory")
+
+const (
+	width8  = expr.Width8
+	width16 = expr.Width16
+	width32 = expr.Width32
+	width64 = expr.Width64
+)
+
+// instructions is the list of all instructions the package parses.
+var instructions = mergeInstructions(
+	aluInstructions,
+	mulDivInstructions,
+	branchInstructions,
+	memInstructions,
+	systemInstructions,
+)
+
+// addrConst creates a constant of width32 representing address a.
+func addrConst(a model.Addr) expr.Const { return expr.NewConstUint(uint32(a), width32) }
+
+// immConst creates a constant of width32 with value v. Bits of v which don't
+// fit into 32 bits are dropped.
+func immConst(v int64) expr.Const { return expr.NewConstUint(uint32(v), width32) }
+
+// decImm returns immediate value v in assembler code written in decimal
+// notation.
+func decImm(v int64) string { return fmt.Sprintf("%d", v) }
+
+// hexImm returns immediate value v in assembler code written in hexadecimal
+// notation.
+func hexImm(v uint32) string { return fmt.Sprintf("0x%x", v) }
+
+// addrOperand returns address a in assembler code. Addresses are targets of
+// branch instructions.
+func addrOperand(a model.Addr) string { return fmt.Sprintf("0x%x", a) }
+
+// delayedStore returns an effect jumping to address e after the delay slot is
+// executed.
+func delayedStore(e expr.Expr) expr.Effect {
+	return expr.NewRegStore(e, expr.DelayedIPKey, width32)
+}
+
+// linkStore returns an effect storing address following the delay slot of
+// instruction i to register r.
+func linkStore(i instruction, r regNum) expr.Effect {
+	return regStore(addrConst(i.linkAddr()), r)
+}
+
+// memLoad loads w bytes from address addr. Value of a big-endian program is
+// converted to the little-endian byte order of memory loads.
+func memLoad(i instruction, addr expr.Expr, w expr.Width) expr.Expr {
+	e := expr.NewMemLoad(MemoryKey, addr, w)
+	if i.bigEndian() && w > width8 {
+		return exprtools.ReverseBytes(e, w)
+	}
+	return e
+}
+
+// memStore stores w bytes of e to address addr. Value of a big-endian program
+// is converted from the little-endian byte order of memory stores.
+func memStore(i instruction, e expr.Expr, addr expr.Expr, w expr.Width) expr.Effect {
+	if i.bigEndian() && w > width8 {
+		e = exprtools.ReverseBytes(e, w)
+	}
+	return expr.NewMemStore(e, MemoryKey, addr, w)
+}
+
+// sext sign extends value e where bit signBit is the sign bit to width32.
+func sext(e expr.Expr, signBit uint8) expr.Expr {
+	return exprtools.SignExtend(e, expr.ConstFromUint(signBit), width32)
+}
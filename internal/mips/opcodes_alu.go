@@ -0,0 +1,397 @@
+package mips
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"mltwist/pkg/model"
+)
+
+// aluInstructions are instructions computing a value in general purpose
+// registers.
+var aluInstructions = []*instructionType{
+	shiftImm("sll", "00000", "000000", func(rt, sa expr.Expr) expr.Expr {
+		return expr.NewBinary(expr.Lsh, rt, sa, width32)
+	}),
+	shiftImm("srl", "00000", "000010", func(rt, sa expr.Expr) expr.Expr {
+		return expr.NewBinary(expr.Rsh, rt, sa, width32)
+	}),
+	shiftImm("rotr", "00001", "000010", func(rt, sa expr.Expr) expr.Expr {
+		return exprtools.RotateRight(rt, sa, width32)
+	}),
+	shiftImm("sra", "00000", "000011", func(rt, sa expr.Expr) expr.Expr {
+		return exprtools.RshA(rt, sa, width32)
+	}),
+
+	shiftReg("sllv", "00000", "000100", func(rt, sa expr.Expr) expr.Expr {
+		return expr.NewBinary(expr.Lsh, rt, sa, width32)
+	}),
+	shiftReg("srlv", "00000", "000110", func(rt, sa expr.Expr) expr.Expr {
+		return expr.NewBinary(expr.Rsh, rt, sa, width32)
+	}),
+	shiftReg("rotrv", "00001", "000110", func(rt, sa expr.Expr) expr.Expr {
+		return exprtools.RotateRight(rt, sa, width32)
+	}),
+	shiftReg("srav", "00000", "000111", func(rt, sa expr.Expr) expr.Expr {
+		return exprtools.RshA(rt, sa, width32)
+	}),
+
+	// Both add and sub trap if the signed result overflows. The value
+	// written is the one computed by addu and subu, so the trap is
+	// expressed only by the instruction type.
+	withType(regOp("add", "100000", add), model.TypeSyscall),
+	withAlias(regOp("addu", "100001", add), moveAlias),
+	withType(regOp("sub", "100010", sub), model.TypeSyscall),
+	withAlias(regOp("subu", "100011", sub), func(i instruction, ops []string) (string, []string, bool) {
+		if i.rs() != regZero {
+			return "", nil, false
+		}
+		return "negu", []string{ops[0], ops[2]}, true
+	}),
+	regOp("and", "100100", func(rs, rt expr.Expr) expr.Expr {
+		return exprtools.BitAnd(rs, rt, width32)
+	}),
+	withAlias(regOp("or", "100101", func(rs, rt expr.Expr) expr.Expr {
+		return exprtools.BitOr(rs, rt, width32)
+	}), moveAlias),
+	regOp("xor", "100110", func(rs, rt expr.Expr) expr.Expr {
+		return exprtools.BitXor(rs, rt, width32)
+	}),
+	withAlias(regOp("nor", "100111", func(rs, rt expr.Expr) expr.Expr {
+		return exprtools.BitNot(exprtools.BitOr(rs, rt, width32), width32)
+	}), func(i instruction, ops []string) (string, []string, bool) {
+		if i.rt() != regZero {
+			return "", nil, false
+		}
+		return "not", ops[:2], true
+	}),
+	regOp("slt", "101010", func(rs, rt expr.Expr) expr.Expr {
+		return exprtools.Lts(rs, rt, expr.One, expr.Zero, width32)
+	}),
+	regOp("sltu", "101011", func(rs, rt expr.Expr) expr.Expr {
+		return expr.NewLess(rs, rt, expr.One, expr.Zero, width32)
+	}),
+
+	condMove("movz", "001010", true),
+	condMove("movn", "001011", false),
+
+	withType(immOp("addi", "001000", add), model.TypeSyscall),
+	withAlias(immOp("addiu", "001001", add), func(i instruction, ops []string) (string, []string, bool) {
+		if i.rs() != regZero {
+			return "", nil, false
+		}
+		return "li", []string{ops[0], ops[2]}, true
+	}),
+	immOp("slti", "001010", func(rs, imm expr.Expr) expr.Expr {
+		return exprtools.Lts(rs, imm, expr.One, expr.Zero, width32)
+	}),
+	immOp("sltiu", "001011", func(rs, imm expr.Expr) expr.Expr {
+		return expr.NewLess(rs, imm, expr.One, expr.Zero, width32)
+	}),
+	logicImmOp("andi", "001100", func(rs, imm expr.Expr) expr.Expr {
+		return exprtools.BitAnd(rs, imm, width32)
+	}),
+	withAlias(logicImmOp("ori", "001101", func(rs, imm expr.Expr) expr.Expr {
+		return exprtools.BitOr(rs, imm, width32)
+	}), func(i instruction, ops []string) (string, []string, bool) {
+		if i.rs() != regZero {
+			return "", nil, false
+		}
+		return "li", []string{ops[0], ops[2]}, true
+	}),
+	logicImmOp("xori", "001110", func(rs, imm expr.Expr) expr.Expr {
+		return exprtools.BitXor(rs, imm, width32)
+	}),
+	{
+		name:   "lui",
+		opcode: pattern("001111 00000 xxxxx xxxxxxxxxxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rt().name(abi), hexImm(i.imm())}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{regStore(immConst(int64(i.imm())<<16), i.rt())}
+		},
+	},
+
+	{
+		name:   "clz",
+		opcode: pattern("011100 xxxxx xxxxx xxxxx 00000 100000"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rd().name(abi), i.rs().name(abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			clz := exprtools.LeadingZeros(regLoad(i.rs()), width32)
+			return []expr.Effect{regStore(clz, i.rd())}
+		},
+	}, {
+		name:   "clo",
+		opcode: pattern("011100 xxxxx xxxxx xxxxx 00000 100001"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rd().name(abi), i.rs().name(abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			inverted := exprtools.BitNot(regLoad(i.rs()), width32)
+			clo := exprtools.LeadingZeros(inverted, width32)
+			return []expr.Effect{regStore(clo, i.rd())}
+		},
+	},
+
+	{
+		name:   "ext",
+		opcode: pattern("011111 xxxxx xxxxx xxxxx xxxxx 000000"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				i.rt().name(abi),
+				i.rs().name(abi),
+				decImm(int64(i.sa())),
+				decImm(int64(i.rd()) + 1),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			pos := expr.ConstFromUint(uint8(i.sa()))
+			shifted := expr.NewBinary(expr.Rsh, regLoad(i.rs()), pos, width32)
+			size := exprtools.BitCnt(i.rd()) + 1
+			return []expr.Effect{regStore(exprtools.MaskBits(shifted, size, width32), i.rt())}
+		},
+	}, {
+		name:   "ins",
+		opcode: pattern("011111 xxxxx xxxxx xxxxx xxxxx 000100"),
+		operands: func(i instruction, abi bool) []string {
+			size := int64(i.rd()) - int64(i.sa()) + 1
+			return []string{
+				i.rt().name(abi),
+				i.rs().name(abi),
+				decImm(int64(i.sa())),
+				decImm(size),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			// Encodings with msb lower than lsb are unpredictable.
+			// The mask is empty for them, so the register keeps
+			// its value.
+			lsb, msb := i.sa(), uint32(i.rd())
+			var mask uint32
+			if msb >= lsb {
+				mask = uint32((uint64(1)<<(msb-lsb+1) - 1) << lsb)
+			}
+
+			pos := expr.ConstFromUint(uint8(lsb))
+			shifted := expr.NewBinary(expr.Lsh, regLoad(i.rs()), pos, width32)
+			inserted := exprtools.BitAnd(shifted, immConst(int64(mask)), width32)
+			kept := exprtools.BitAnd(regLoad(i.rt()), immConst(int64(^mask)), width32)
+			return []expr.Effect{regStore(exprtools.BitOr(kept, inserted, width32), i.rt())}
+		},
+	},
+
+	bitShuffle("wsbh", "00010", func(rt expr.Expr) expr.Expr {
+		eight := expr.ConstFromUint(uint8(8))
+		high := exprtools.BitAnd(rt, immConst(0xff00ff00), width32)
+		low := exprtools.BitAnd(rt, immConst(0x00ff00ff), width32)
+		return exprtools.BitOr(
+			expr.NewBinary(expr.Rsh, high, eight, width32),
+			expr.NewBinary(expr.Lsh, low, eight, width32),
+			width32,
+		)
+	}),
+	bitShuffle("seb", "10000", func(rt expr.Expr) expr.Expr { return sext(rt, 7) }),
+	bitShuffle("seh", "11000", func(rt expr.Expr) expr.Expr { return sext(rt, 15) }),
+}
+
+func add(e1, e2 expr.Expr) expr.Expr { return expr.NewBinary(expr.Add, e1, e2, width32) }
+func sub(e1, e2 expr.Expr) expr.Expr { return exprtools.Sub(e1, e2, width32) }
+
+// withType sets instruction type of t to typ and returns t.
+func withType(t *instructionType, typ model.Type) *instructionType {
+	t.instrType = typ
+	return t
+}
+
+// withAlias sets alias function of t to alias and returns t.
+func withAlias(
+	t *instructionType,
+	alias func(i instruction, ops []string) (string, []string, bool),
+) *instructionType {
+	t.alias = alias
+	return t
+}
+
+// moveAlias returns move alias of register operation i with operands ops which
+// adds or ors a register and the zero register.
+func moveAlias(i instruction, ops []string) (string, []string, bool) {
+	switch {
+	case i.rt() == regZero:
+		return "move", ops[:2], true
+	case i.rs() == regZero:
+		return "move", []string{ops[0], ops[2]}, true
+	default:
+		return "", nil, false
+	}
+}
+
+// regOperands returns operands rd, rs and rt of instruction i.
+func regOperands(i instruction, abi bool) []string {
+	return []string{i.rd().name(abi), i.rs().name(abi), i.rt().name(abi)}
+}
+
+// regOp creates an instruction called name of SPECIAL opcode with function
+// field funct. The instruction stores result of op applied on registers rs
+// and rt to register rd.
+func regOp(
+	name string,
+	funct string,
+	op func(rs, rt expr.Expr) expr.Expr,
+) *instructionType {
+	return &instructionType{
+		name:     name,
+		opcode:   pattern("000000 xxxxx xxxxx xxxxx 00000 " + funct),
+		operands: regOperands,
+		effects: func(i instruction) []expr.Effect {
+			value := op(regLoad(i.rs()), regLoad(i.rt()))
+			return []expr.Effect{regStore(value, i.rd())}
+		},
+	}
+}
+
+// shiftImm creates a shift instruction called name of SPECIAL opcode with rs
+// field rs and function field funct. The instruction stores rt shifted by
+// constant amount sa using op to register rd.
+func shiftImm(
+	name string,
+	rs string,
+	funct string,
+	op func(rt, sa expr.Expr) expr.Expr,
+) *instructionType {
+	t := &instructionType{
+		name:   name,
+		opcode: pattern("000000 " + rs + " xxxxx xxxxx xxxxx " + funct),
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				i.rd().name(abi),
+				i.rt().name(abi),
+				decImm(int64(i.sa())),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			sa := expr.ConstFromUint(uint8(i.sa()))
+			return []expr.Effect{regStore(op(regLoad(i.rt()), sa), i.rd())}
+		},
+	}
+
+	if name == "sll" {
+		t.alias = func(i instruction, _ []string) (string, []string, bool) {
+			return "nop", nil, i.value == 0
+		}
+	}
+	return t
+}
+
+// shiftReg creates a shift instruction called name of SPECIAL opcode with sa
+// field sa and function field funct. The instruction stores rt shifted by the
+// lowest 5 bits of register rs using op to register rd.
+func shiftReg(
+	name string,
+	sa string,
+	funct string,
+	op func(rt, sa expr.Expr) expr.Expr,
+) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern("000000 xxxxx xxxxx xxxxx " + sa + " " + funct),
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				i.rd().name(abi),
+				i.rt().name(abi),
+				i.rs().name(abi),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			amount := exprtools.MaskBits(regLoad(i.rs()), 5, width32)
+			return []expr.Effect{regStore(op(regLoad(i.rt()), amount), i.rd())}
+		},
+	}
+}
+
+// condMove creates conditional move instruction called name with function field
+// funct. The instruction copies register rs to register rd if register rt is
+// zero (zero set) or if it's not zero (zero unset).
+func condMove(name string, funct string, zero bool) *instructionType {
+	return &instructionType{
+		name:     name,
+		opcode:   pattern("000000 xxxxx xxxxx xxxxx 00000 " + funct),
+		operands: regOperands,
+		effects: func(i instruction) []expr.Effect {
+			moved, kept := regLoad(i.rs()), regLoad(i.rd())
+			if zero {
+				moved, kept = kept, moved
+			}
+
+			value := exprtools.BoolCond(regLoad(i.rt()), moved, kept, width32)
+			return []expr.Effect{regStore(value, i.rd())}
+		},
+	}
+}
+
+// immOp creates an instruction called name with opcode op. The instruction
+// stores result of op applied on register rs and sign extended immediate value
+// to register rt.
+func immOp(
+	name string,
+	op string,
+	fn func(rs, imm expr.Expr) expr.Expr,
+) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern(op + " xxxxx xxxxx xxxxxxxxxxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				i.rt().name(abi),
+				i.rs().name(abi),
+				decImm(i.simm()),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			value := fn(regLoad(i.rs()), immConst(i.simm()))
+			return []expr.Effect{regStore(value, i.rt())}
+		},
+	}
+}
+
+// logicImmOp creates a logical instruction called name with opcode op. The
+// instruction stores result of op applied on register rs and zero extended
+// immediate value to register rt.
+func logicImmOp(
+	name string,
+	op string,
+	fn func(rs, imm expr.Expr) expr.Expr,
+) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern(op + " xxxxx xxxxx xxxxxxxxxxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				i.rt().name(abi),
+				i.rs().name(abi),
+				hexImm(i.imm()),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			value := fn(regLoad(i.rs()), immConst(int64(i.imm())))
+			return []expr.Effect{regStore(value, i.rt())}
+		},
+	}
+}
+
+// bitShuffle creates an instruction called name of BSHFL class with operation
+// field op. The instruction stores result of fn applied on register rt to
+// register rd.
+func bitShuffle(name string, op string, fn func(rt expr.Expr) expr.Expr) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern("011111 00000 xxxxx xxxxx " + op + " 100000"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rd().name(abi), i.rt().name(abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{regStore(fn(regLoad(i.rt())), i.rd())}
+		},
+	}
+}
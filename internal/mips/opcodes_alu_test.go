@@ -0,0 +1,155 @@
+package mips
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestALUEffects(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		regs  map[expr.Key]uint64
+		want  map[expr.Key]uint64
+	}{{
+		// addu $2, $3, $4
+		name:  "addu",
+		bytes: []byte{0x21, 0x10, 0x64, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 0xffffffff, "r4": 2},
+		want:  map[expr.Key]uint64{"r2": 1},
+	}, {
+		// addiu $sp, $sp, -32
+		name:  "addiu",
+		bytes: []byte{0xe0, 0xff, 0xbd, 0x27},
+		regs:  map[expr.Key]uint64{"r29": 0x7ff0},
+		want:  map[expr.Key]uint64{"r29": 0x7fd0},
+	}, {
+		// negu $2, $3
+		name:  "negu",
+		bytes: []byte{0x23, 0x10, 0x03, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 1},
+		want:  map[expr.Key]uint64{"r2": 0xffffffff},
+	}, {
+		// not $2, $3
+		name:  "not",
+		bytes: []byte{0x27, 0x10, 0x60, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 0x0000ffff},
+		want:  map[expr.Key]uint64{"r2": 0xffff0000},
+	}, {
+		// slt $2, $3, $4
+		name:  "slt",
+		bytes: []byte{0x2a, 0x10, 0x64, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 0xffffffff, "r4": 1},
+		want:  map[expr.Key]uint64{"r2": 1},
+	}, {
+		// sltu $2, $3, $4
+		name:  "sltu",
+		bytes: []byte{0x2b, 0x10, 0x64, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 0xffffffff, "r4": 1},
+		want:  map[expr.Key]uint64{"r2": 0},
+	}, {
+		// sltiu $2, $3, -1
+		name:  "sltiu",
+		bytes: []byte{0xff, 0xff, 0x62, 0x2c},
+		regs:  map[expr.Key]uint64{"r3": 0xfffffffe},
+		want:  map[expr.Key]uint64{"r2": 1},
+	}, {
+		// andi $2, $3, 0xff
+		name:  "andi",
+		bytes: []byte{0xff, 0x00, 0x62, 0x30},
+		regs:  map[expr.Key]uint64{"r3": 0x1234},
+		want:  map[expr.Key]uint64{"r2": 0x34},
+	}, {
+		// lui $2, 0x1234
+		name:  "lui",
+		bytes: []byte{0x34, 0x12, 0x02, 0x3c},
+		want:  map[expr.Key]uint64{"r2": 0x12340000},
+	}, {
+		// sra $2, $3, 4
+		name:  "sra",
+		bytes: []byte{0x03, 0x11, 0x03, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 0x80000000},
+		want:  map[expr.Key]uint64{"r2": 0xf8000000},
+	}, {
+		// rotr $2, $3, 8
+		name:  "rotr",
+		bytes: []byte{0x02, 0x12, 0x23, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 0x12345678},
+		want:  map[expr.Key]uint64{"r2": 0x78123456},
+	}, {
+		// srlv $2, $3, $4
+		name:  "srlv",
+		bytes: []byte{0x06, 0x10, 0x83, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 0x100, "r4": 0x24},
+		want:  map[expr.Key]uint64{"r2": 0x10},
+	}, {
+		// movz $2, $3, $4
+		name:  "movz_moved",
+		bytes: []byte{0x0a, 0x10, 0x64, 0x00},
+		regs:  map[expr.Key]uint64{"r2": 1, "r3": 2},
+		want:  map[expr.Key]uint64{"r2": 2},
+	}, {
+		// movn $2, $3, $4
+		name:  "movn_kept",
+		bytes: []byte{0x0b, 0x10, 0x64, 0x00},
+		regs:  map[expr.Key]uint64{"r2": 1, "r3": 2},
+		want:  map[expr.Key]uint64{"r2": 1},
+	}, {
+		// clz $2, $3
+		name:  "clz",
+		bytes: []byte{0x20, 0x10, 0x62, 0x70},
+		regs:  map[expr.Key]uint64{"r3": 0x00010000},
+		want:  map[expr.Key]uint64{"r2": 15},
+	}, {
+		// clo $2, $3
+		name:  "clo",
+		bytes: []byte{0x21, 0x10, 0x62, 0x70},
+		regs:  map[expr.Key]uint64{"r3": 0xff000000},
+		want:  map[expr.Key]uint64{"r2": 8},
+	}, {
+		// ext $2, $3, 4, 8
+		name:  "ext",
+		bytes: []byte{0x00, 0x39, 0x62, 0x7c},
+		regs:  map[expr.Key]uint64{"r3": 0x12345678},
+		want:  map[expr.Key]uint64{"r2": 0x67},
+	}, {
+		// ins $2, $3, 4, 8
+		name:  "ins",
+		bytes: []byte{0x04, 0x59, 0x62, 0x7c},
+		regs:  map[expr.Key]uint64{"r2": 0xffffffff, "r3": 0x12345600},
+		want:  map[expr.Key]uint64{"r2": 0xfffff00f},
+	}, {
+		// wsbh $2, $3
+		name:  "wsbh",
+		bytes: []byte{0xa0, 0x10, 0x03, 0x7c},
+		regs:  map[expr.Key]uint64{"r3": 0x11223344},
+		want:  map[expr.Key]uint64{"r2": 0x22114433},
+	}, {
+		// seb $2, $3
+		name:  "seb",
+		bytes: []byte{0x20, 0x14, 0x03, 0x7c},
+		regs:  map[expr.Key]uint64{"r3": 0x180},
+		want:  map[expr.Key]uint64{"r2": 0xffffff80},
+	}, {
+		// seh $2, $3
+		name:  "seh",
+		bytes: []byte{0x20, 0x16, 0x03, 0x7c},
+		regs:  map[expr.Key]uint64{"r3": 0x7fff},
+		want:  map[expr.Key]uint64{"r2": 0x7fff},
+	}, {
+		// addu $0, $3, $4
+		name:  "zero_register",
+		bytes: []byte{0x21, 0x00, 0x64, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 1},
+		want:  map[expr.Key]uint64{},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, evalRegs(t, tt.bytes, tt.regs))
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package mips
+
+import (
+	"mltwist/internal/opcode"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// branchInstructions are branch and jump instructions.
+//
+// All of them have a delay slot: the instruction following a branch is
+// executed before the branch takes effect. Consequently, branches write
+// expr.DelayedIPKey instead of expr.IPKey and a branch which isn't taken
+// writes the address following its delay slot. Link addresses point behind the
+// delay slot as well.
+var branchInstructions = []*instructionType{
+	{
+		name:     "j",
+		opcode:   pattern("000010 xxxxxxxxxxxxxxxxxxxxxxxxxx"),
+		offset:   offsetJump,
+		operands: targetOperands,
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{delayedStore(addrConst(i.target()))}
+		},
+	}, {
+		name:     "jal",
+		opcode:   pattern("000011 xxxxxxxxxxxxxxxxxxxxxxxxxx"),
+		offset:   offsetJump,
+		operands: targetOperands,
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{
+				linkStore(i, regRA),
+				delayedStore(addrConst(i.target())),
+			}
+		},
+	}, {
+		name:   "jr",
+		opcode: pattern("000000 xxxxx 00000 00000 00000 001000"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rs().name(abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{delayedStore(regLoad(i.rs()))}
+		},
+	}, {
+		name:   "jalr",
+		opcode: pattern("000000 xxxxx 00000 xxxxx 00000 001001"),
+		operands: func(i instruction, abi bool) []string {
+			if i.rd() == regRA {
+				return []string{i.rs().name(abi)}
+			}
+			return []string{i.rd().name(abi), i.rs().name(abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			// Effects are evaluated at once, so the jump target is the
+			// register value before the link register is written.
+			return []expr.Effect{
+				linkStore(i, i.rd()),
+				delayedStore(regLoad(i.rs())),
+			}
+		},
+	},
+
+	withAlias(compareBranch("beq", "000100", true), func(i instruction, ops []string) (string, []string, bool) {
+		switch {
+		case i.rs() == regZero && i.rt() == regZero:
+			return "b", ops[2:], true
+		case i.rt() == regZero:
+			return "beqz", []string{ops[0], ops[2]}, true
+		default:
+			return "", nil, false
+		}
+	}),
+	withAlias(compareBranch("bne", "000101", false), func(i instruction, ops []string) (string, []string, bool) {
+		if i.rt() != regZero {
+			return "", nil, false
+		}
+		return "bnez", []string{ops[0], ops[2]}, true
+	}),
+
+	zeroBranch("blez", pattern("000110 xxxxx 00000 xxxxxxxxxxxxxxxx"), lez, false),
+	zeroBranch("bgtz", pattern("000111 xxxxx 00000 xxxxxxxxxxxxxxxx"), lez, true),
+	zeroBranch("bltz", pattern("000001 xxxxx 00000 xxxxxxxxxxxxxxxx"), ltz, false),
+	zeroBranch("bgez", pattern("000001 xxxxx 00001 xxxxxxxxxxxxxxxx"), ltz, true),
+	linkBranch(zeroBranch("bltzal", pattern("000001 xxxxx 10000 xxxxxxxxxxxxxxxx"), ltz, false)),
+	withAlias(
+		linkBranch(zeroBranch("bgezal", pattern("000001 xxxxx 10001 xxxxxxxxxxxxxxxx"), ltz, true)),
+		func(i instruction, ops []string) (string, []string, bool) {
+			return "bal", ops[1:], i.rs() == regZero
+		},
+	),
+}
+
+// targetOperands returns operands of a branch instruction which has only a
+// single operand: the target address.
+func targetOperands(i instruction, _ bool) []string {
+	return []string{addrOperand(i.target())}
+}
+
+// branchStore returns an effect of branch instruction i which jumps if cond is
+// nonzero. The execution continues behind the delay slot otherwise.
+func branchStore(i instruction, cond expr.Expr) expr.Effect {
+	taken, notTaken := addrConst(i.target()), addrConst(i.linkAddr())
+	return delayedStore(exprtools.BoolCond(cond, taken, notTaken, width32))
+}
+
+// compareBranch creates a branch instruction called name with opcode op which
+// jumps if registers rs and rt are equal (eq set) or if they differ (eq
+// unset).
+func compareBranch(name string, op string, eq bool) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern(op + " xxxxx xxxxx xxxxxxxxxxxxxxxx"),
+		offset: offsetBranch,
+		operands: func(i instruction, abi bool) []string {
+			return []string{
+				i.rs().name(abi),
+				i.rt().name(abi),
+				addrOperand(i.target()),
+			}
+		},
+		effects: func(i instruction) []expr.Effect {
+			equal, differ := expr.Expr(expr.One), expr.Expr(expr.Zero)
+			if !eq {
+				equal, differ = differ, equal
+			}
+
+			cond := exprtools.Eq(regLoad(i.rs()), regLoad(i.rt()), equal, differ, width32)
+			return []expr.Effect{branchStore(i, cond)}
+		},
+	}
+}
+
+// lez returns nonzero value if signed value e is less than or equal to zero.
+func lez(e expr.Expr) expr.Expr {
+	return exprtools.Les(e, expr.Zero, expr.One, expr.Zero, width32)
+}
+
+// ltz returns nonzero value if signed value e is less than zero.
+func ltz(e expr.Expr) expr.Expr { return exprtools.IntNegative(e, width32) }
+
+// zeroBranch creates a branch instruction called name with opcode opc which
+// compares register rs with zero. The instruction jumps if cond is nonzero for
+// value of the register. The condition is negated if negate is set.
+func zeroBranch(
+	name string,
+	opc opcode.Opcode,
+	cond func(e expr.Expr) expr.Expr,
+	negate bool,
+) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: opc,
+		offset: offsetBranch,
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rs().name(abi), addrOperand(i.target())}
+		},
+		effects: func(i instruction) []expr.Effect {
+			c := cond(regLoad(i.rs()))
+			if negate {
+				c = exprtools.Not(c)
+			}
+			return []expr.Effect{branchStore(i, c)}
+		},
+	}
+}
+
+// linkBranch extends branch instruction t to store address following its delay
+// slot to the return address register. The register is written regardless of
+// whether the branch is taken or not.
+func linkBranch(t *instructionType) *instructionType {
+	effects := t.effects
+	t.effects = func(i instruction) []expr.Effect {
+		return append([]expr.Effect{linkStore(i, regRA)}, effects(i)...)
+	}
+	return t
+}
@@ -0,0 +1,101 @@
+package mips
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranchEffects(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		regs  map[expr.Key]uint64
+		want  map[expr.Key]uint64
+	}{{
+		// beq $4, $5, 16
+		name:  "beq_taken",
+		bytes: []byte{0x04, 0x00, 0x85, 0x10},
+		regs:  map[expr.Key]uint64{"r4": 1, "r5": 1},
+		want:  map[expr.Key]uint64{expr.DelayedIPKey: testAddr + 4 + 16},
+	}, {
+		// beq $4, $5, 16
+		name:  "beq_not_taken",
+		bytes: []byte{0x04, 0x00, 0x85, 0x10},
+		regs:  map[expr.Key]uint64{"r4": 1},
+		want:  map[expr.Key]uint64{expr.DelayedIPKey: testAddr + 8},
+	}, {
+		// bnez $4, -8
+		name:  "bne",
+		bytes: []byte{0xfe, 0xff, 0x80, 0x14},
+		regs:  map[expr.Key]uint64{"r4": 1},
+		want:  map[expr.Key]uint64{expr.DelayedIPKey: testAddr + 4 - 8},
+	}, {
+		// blez $4, 8
+		name:  "blez_zero",
+		bytes: []byte{0x02, 0x00, 0x80, 0x18},
+		want:  map[expr.Key]uint64{expr.DelayedIPKey: testAddr + 4 + 8},
+	}, {
+		// bgtz $4, 8
+		name:  "bgtz_negative",
+		bytes: []byte{0x02, 0x00, 0x80, 0x1c},
+		regs:  map[expr.Key]uint64{"r4": 0xffffffff},
+		want:  map[expr.Key]uint64{expr.DelayedIPKey: testAddr + 8},
+	}, {
+		// bltz $4, 8
+		name:  "bltz",
+		bytes: []byte{0x02, 0x00, 0x80, 0x04},
+		regs:  map[expr.Key]uint64{"r4": 0x80000000},
+		want:  map[expr.Key]uint64{expr.DelayedIPKey: testAddr + 4 + 8},
+	}, {
+		// bgez $4, 8
+		name:  "bgez",
+		bytes: []byte{0x02, 0x00, 0x81, 0x04},
+		want:  map[expr.Key]uint64{expr.DelayedIPKey: testAddr + 4 + 8},
+	}, {
+		// bltzal $4, 8
+		name:  "bltzal_not_taken",
+		bytes: []byte{0x02, 0x00, 0x90, 0x04},
+		regs:  map[expr.Key]uint64{"r4": 1},
+		want: map[expr.Key]uint64{
+			expr.DelayedIPKey: testAddr + 8,
+			"r31":             testAddr + 8,
+		},
+	}, {
+		// j 0x2000
+		name:  "j",
+		bytes: []byte{0x00, 0x08, 0x00, 0x08},
+		want:  map[expr.Key]uint64{expr.DelayedIPKey: 0x2000},
+	}, {
+		// jal 0x2000
+		name:  "jal",
+		bytes: []byte{0x00, 0x08, 0x00, 0x0c},
+		want: map[expr.Key]uint64{
+			expr.DelayedIPKey: 0x2000,
+			"r31":             testAddr + 8,
+		},
+	}, {
+		// jr $ra
+		name:  "jr",
+		bytes: []byte{0x08, 0x00, 0xe0, 0x03},
+		regs:  map[expr.Key]uint64{"r31": 0x4000},
+		want:  map[expr.Key]uint64{expr.DelayedIPKey: 0x4000},
+	}, {
+		// jalr $2, $3
+		name:  "jalr",
+		bytes: []byte{0x09, 0x10, 0x60, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 0x4000},
+		want: map[expr.Key]uint64{
+			expr.DelayedIPKey: 0x4000,
+			"r2":              testAddr + 8,
+		},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, evalRegs(t, tt.bytes, tt.regs))
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package mips
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// memInstructions are load and store instructions.
+var memInstructions = []*instructionType{
+	load("lb", "100000", width8, true),
+	load("lh", "100001", width16, true),
+	load("lw", "100011", width32, false),
+	load("lbu", "100100", width8, false),
+	load("lhu", "100101", width16, false),
+	store("sb", "101000", width8),
+	store("sh", "101001", width16),
+	store("sw", "101011", width32),
+
+	// Unaligned word accesses transfer only bytes of the aligned word
+	// containing the address. A pair of lwl and lwr (swl and swr) loads
+	// (stores) a whole unaligned word.
+	loadUnaligned("lwl", "100010", false),
+	loadUnaligned("lwr", "100110", true),
+	storeUnaligned("swl", "101010", false),
+	storeUnaligned("swr", "101110", true),
+
+	// There is no other thread the program could interfere with, so the
+	// link is never broken and ll and sc behave as an ordinary load and
+	// a store which always succeeds.
+	load("ll", "110000", width32, false),
+	{
+		name:     "sc",
+		opcode:   pattern("111000 xxxxx xxxxx xxxxxxxxxxxxxxxx"),
+		operands: memOperands,
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{
+				memStore(i, regLoad(i.rt()), memAddr(i), width32),
+				regStore(expr.One, i.rt()),
+			}
+		},
+	}, {
+		name:   "pref",
+		opcode: pattern("110011 xxxxx xxxxx xxxxxxxxxxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			ops := memOperands(i, abi)
+			ops[0] = decImm(int64(i.rt()))
+			return ops
+		},
+		effects: func(instruction) []expr.Effect { return nil },
+	},
+}
+
+// memAddr returns address instruction i accesses. The address is register rs
+// (base) plus signed 16 bit offset.
+func memAddr(i instruction) expr.Expr {
+	return expr.NewBinary(expr.Add, regLoad(i.rs()), immConst(i.simm()), width32)
+}
+
+// alignedAddr returns address of the aligned word containing the address
+// instruction i accesses.
+func alignedAddr(i instruction) expr.Expr {
+	return exprtools.BitAnd(memAddr(i), immConst(^3), width32)
+}
+
+// unalignedShift returns number of bits the word accessed by an unaligned load
+// or store instruction i is shifted by. For lwl and swl, it's the number of
+// bits of the aligned word which are more significant than the addressed byte.
+// For lwr and swr (right is set), it's the number of bits which are less
+// significant than the addressed byte.
+func unalignedShift(i instruction, right bool) expr.Expr {
+	// Byte at offset 0 of a word is the most significant byte in
+	// big-endian and the least significant byte in little-endian.
+	pos := exprtools.BitAnd(memAddr(i), immConst(3), width32)
+	if i.bigEndian() == right {
+		pos = exprtools.BitXor(pos, immConst(3), width32)
+	}
+	return expr.NewBinary(expr.Lsh, pos, immConst(3), width32)
+}
+
+// mergeLeft returns e shifted left by shift bits with the bits shifted in taken
+// from old.
+func mergeLeft(e expr.Expr, old expr.Expr, shift expr.Expr) expr.Expr {
+	kept := sub(expr.NewBinary(expr.Lsh, expr.One, shift, width32), expr.One)
+	return exprtools.BitOr(
+		expr.NewBinary(expr.Lsh, e, shift, width32),
+		exprtools.BitAnd(old, kept, width32),
+		width32,
+	)
+}
+
+// mergeRight returns e shifted right by shift bits with the bits shifted in
+// taken from old.
+func mergeRight(e expr.Expr, old expr.Expr, shift expr.Expr) expr.Expr {
+	shifted := expr.NewBinary(expr.Rsh, immConst(0xffffffff), shift, width32)
+	return exprtools.BitOr(
+		expr.NewBinary(expr.Rsh, e, shift, width32),
+		exprtools.BitAnd(old, exprtools.BitNot(shifted, width32), width32),
+		width32,
+	)
+}
+
+// memOperands returns operands of load or store instruction i: the register
+// transferred and the address.
+func memOperands(i instruction, abi bool) []string {
+	return []string{
+		i.rt().name(abi),
+		fmt.Sprintf("%d(%s)", i.simm(), i.rs().name(abi)),
+	}
+}
+
+// load creates a load instruction called name with opcode op loading w bytes to
+// register rt. The value loaded is sign extended if signed is set and zero
+// extended otherwise.
+func load(name string, op string, w expr.Width, signed bool) *instructionType {
+	return &instructionType{
+		name:     name,
+		opcode:   pattern(op + " xxxxx xxxxx xxxxxxxxxxxxxxxx"),
+		operands: memOperands,
+		effects: func(i instruction) []expr.Effect {
+			value := memLoad(i, memAddr(i), w)
+			if signed {
+				value = sext(value, uint8(w.Bits()-1))
+			}
+			return []expr.Effect{regStore(value, i.rt())}
+		},
+	}
+}
+
+// store creates a store instruction called name with opcode op storing w bytes
+// of register rt.
+func store(name string, op string, w expr.Width) *instructionType {
+	return &instructionType{
+		name:     name,
+		opcode:   pattern(op + " xxxxx xxxxx xxxxxxxxxxxxxxxx"),
+		operands: memOperands,
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{memStore(i, regLoad(i.rt()), memAddr(i), w)}
+		},
+	}
+}
+
+// loadUnaligned creates an unaligned load instruction called name with opcode
+// op. The instruction merges bytes of the aligned word it addresses into
+// register rt. Lwr is created if right is set and lwl otherwise.
+//
+// Lwl loads the addressed byte and all less significant bytes of the aligned
+// word into the most significant bytes of rt. Lwr loads the addressed byte and
+// all more significant bytes into the least significant bytes of rt.
+func loadUnaligned(name string, op string, right bool) *instructionType {
+	return &instructionType{
+		name:     name,
+		opcode:   pattern(op + " xxxxx xxxxx xxxxxxxxxxxxxxxx"),
+		operands: memOperands,
+		effects: func(i instruction) []expr.Effect {
+			word := memLoad(i, alignedAddr(i), width32)
+			rt, shift := regLoad(i.rt()), unalignedShift(i, right)
+
+			value := mergeLeft(word, rt, shift)
+			if right {
+				value = mergeRight(word, rt, shift)
+			}
+			return []expr.Effect{regStore(value, i.rt())}
+		},
+	}
+}
+
+// storeUnaligned creates an unaligned store instruction called name with opcode
+// op. The instruction stores bytes of register rt into the aligned word it
+// addresses. Swr is created if right is set and swl otherwise.
+//
+// Swl stores the most significant bytes of rt to the addressed byte and all
+// less significant bytes of the aligned word. Swr stores the least significant
+// bytes of rt to the addressed byte and all more significant bytes.
+func storeUnaligned(name string, op string, right bool) *instructionType {
+	return &instructionType{
+		name:     name,
+		opcode:   pattern(op + " xxxxx xxxxx xxxxxxxxxxxxxxxx"),
+		operands: memOperands,
+		effects: func(i instruction) []expr.Effect {
+			addr := alignedAddr(i)
+			word := memLoad(i, addr, width32)
+			rt, shift := regLoad(i.rt()), unalignedShift(i, right)
+
+			// Store is inverse of load, so swl shifts rt right
+			// where lwl shifts the word left and vice versa.
+			value := mergeRight(rt, word, shift)
+			if right {
+				value = mergeLeft(rt, word, shift)
+			}
+			return []expr.Effect{memStore(i, value, addr, width32)}
+		},
+	}
+}
@@ -0,0 +1,174 @@
+package mips
+
+import (
+	"encoding/binary"
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// bigEndian returns little-endian instruction bytes bs in big-endian byte
+// order.
+func bigEndian(bs []byte) []byte {
+	return []byte{bs[3], bs[2], bs[1], bs[0]}
+}
+
+func TestMemEffects(t *testing.T) {
+	mem := map[uint64]byte{0x100: 0x81, 0x101: 0x82, 0x102: 0x83, 0x103: 0x84}
+
+	tests := []struct {
+		name    string
+		order   binary.ByteOrder
+		bytes   []byte
+		regs    map[expr.Key]uint64
+		wantReg map[expr.Key]uint64
+		wantMem map[uint64]byte
+	}{{
+		// lb $2, -4($3)
+		name:    "lb",
+		bytes:   []byte{0xfc, 0xff, 0x62, 0x80},
+		regs:    map[expr.Key]uint64{"r3": 0x104},
+		wantReg: map[expr.Key]uint64{"r2": 0xffffff81},
+		wantMem: map[uint64]byte{},
+	}, {
+		// lhu $2, 2($3)
+		name:    "lhu",
+		bytes:   []byte{0x02, 0x00, 0x62, 0x94},
+		regs:    map[expr.Key]uint64{"r3": 0x100},
+		wantReg: map[expr.Key]uint64{"r2": 0x8483},
+		wantMem: map[uint64]byte{},
+	}, {
+		// lhu $2, 2($3)
+		name:    "lhu_big_endian",
+		order:   binary.BigEndian,
+		bytes:   bigEndian([]byte{0x02, 0x00, 0x62, 0x94}),
+		regs:    map[expr.Key]uint64{"r3": 0x100},
+		wantReg: map[expr.Key]uint64{"r2": 0x8384},
+		wantMem: map[uint64]byte{},
+	}, {
+		// lh $2, 2($3)
+		name:    "lh_big_endian",
+		order:   binary.BigEndian,
+		bytes:   bigEndian([]byte{0x02, 0x00, 0x62, 0x84}),
+		regs:    map[expr.Key]uint64{"r3": 0x100},
+		wantReg: map[expr.Key]uint64{"r2": 0xffff8384},
+		wantMem: map[uint64]byte{},
+	}, {
+		// lw $ra, 28($sp)
+		name:    "lw",
+		bytes:   []byte{0x1c, 0x00, 0xbf, 0x8f},
+		regs:    map[expr.Key]uint64{"r29": 0x100 - 28},
+		wantReg: map[expr.Key]uint64{"r31": 0x84838281},
+		wantMem: map[uint64]byte{},
+	}, {
+		// sh $2, 2($3)
+		name:    "sh",
+		bytes:   []byte{0x02, 0x00, 0x62, 0xa4},
+		regs:    map[expr.Key]uint64{"r2": 0x11223344, "r3": 0x200},
+		wantReg: map[expr.Key]uint64{},
+		wantMem: map[uint64]byte{0x202: 0x44, 0x203: 0x33},
+	}, {
+		// sw $2, 4($3)
+		name:    "sw_big_endian",
+		order:   binary.BigEndian,
+		bytes:   bigEndian([]byte{0x04, 0x00, 0x62, 0xac}),
+		regs:    map[expr.Key]uint64{"r2": 0x11223344, "r3": 0x200},
+		wantReg: map[expr.Key]uint64{},
+		wantMem: map[uint64]byte{0x204: 0x11, 0x205: 0x22, 0x206: 0x33, 0x207: 0x44},
+	}, {
+		// lwl $2, 1($3)
+		name:    "lwl",
+		bytes:   []byte{0x01, 0x00, 0x62, 0x88},
+		regs:    map[expr.Key]uint64{"r2": 0x11223344, "r3": 0x100},
+		wantReg: map[expr.Key]uint64{"r2": 0x82813344},
+		wantMem: map[uint64]byte{},
+	}, {
+		// lwl $2, 3($3)
+		name:    "lwl_whole_word",
+		bytes:   []byte{0x03, 0x00, 0x62, 0x88},
+		regs:    map[expr.Key]uint64{"r2": 0x11223344, "r3": 0x100},
+		wantReg: map[expr.Key]uint64{"r2": 0x84838281},
+		wantMem: map[uint64]byte{},
+	}, {
+		// lwl $2, 1($3)
+		name:    "lwl_big_endian",
+		order:   binary.BigEndian,
+		bytes:   bigEndian([]byte{0x01, 0x00, 0x62, 0x88}),
+		regs:    map[expr.Key]uint64{"r2": 0x11223344, "r3": 0x100},
+		wantReg: map[expr.Key]uint64{"r2": 0x82838444},
+		wantMem: map[uint64]byte{},
+	}, {
+		// lwr $2, 1($3)
+		name:    "lwr",
+		bytes:   []byte{0x01, 0x00, 0x62, 0x98},
+		regs:    map[expr.Key]uint64{"r2": 0x11223344, "r3": 0x100},
+		wantReg: map[expr.Key]uint64{"r2": 0x11848382},
+		wantMem: map[uint64]byte{},
+	}, {
+		// lwr $2, 1($3)
+		name:    "lwr_big_endian",
+		order:   binary.BigEndian,
+		bytes:   bigEndian([]byte{0x01, 0x00, 0x62, 0x98}),
+		regs:    map[expr.Key]uint64{"r2": 0x11223344, "r3": 0x100},
+		wantReg: map[expr.Key]uint64{"r2": 0x11228182},
+		wantMem: map[uint64]byte{},
+	}, {
+		// swl $2, 1($3)
+		name:    "swl",
+		bytes:   []byte{0x01, 0x00, 0x62, 0xa8},
+		regs:    map[expr.Key]uint64{"r2": 0x11223344, "r3": 0x100},
+		wantReg: map[expr.Key]uint64{},
+		wantMem: map[uint64]byte{0x100: 0x22, 0x101: 0x11, 0x102: 0x83, 0x103: 0x84},
+	}, {
+		// swl $2, 1($3)
+		name:    "swl_big_endian",
+		order:   binary.BigEndian,
+		bytes:   bigEndian([]byte{0x01, 0x00, 0x62, 0xa8}),
+		regs:    map[expr.Key]uint64{"r2": 0x11223344, "r3": 0x100},
+		wantReg: map[expr.Key]uint64{},
+		wantMem: map[uint64]byte{0x100: 0x81, 0x101: 0x11, 0x102: 0x22, 0x103: 0x33},
+	}, {
+		// swr $2, 1($3)
+		name:    "swr",
+		bytes:   []byte{0x01, 0x00, 0x62, 0xb8},
+		regs:    map[expr.Key]uint64{"r2": 0x11223344, "r3": 0x100},
+		wantReg: map[expr.Key]uint64{},
+		wantMem: map[uint64]byte{0x100: 0x81, 0x101: 0x44, 0x102: 0x33, 0x103: 0x22},
+	}, {
+		// swr $2, 1($3)
+		name:    "swr_big_endian",
+		order:   binary.BigEndian,
+		bytes:   bigEndian([]byte{0x01, 0x00, 0x62, 0xb8}),
+		regs:    map[expr.Key]uint64{"r2": 0x11223344, "r3": 0x100},
+		wantReg: map[expr.Key]uint64{},
+		wantMem: map[uint64]byte{0x100: 0x33, 0x101: 0x44, 0x102: 0x83, 0x103: 0x84},
+	}, {
+		// sc $2, 0($3)
+		name:    "sc",
+		bytes:   []byte{0x00, 0x00, 0x62, 0xe0},
+		regs:    map[expr.Key]uint64{"r2": 0x11223344, "r3": 0x200},
+		wantReg: map[expr.Key]uint64{"r2": 1},
+		wantMem: map[uint64]byte{0x200: 0x44, 0x201: 0x33, 0x202: 0x22, 0x203: 0x11},
+	}, {
+		// pref 0, 8($3)
+		name:    "pref",
+		bytes:   []byte{0x08, 0x00, 0x60, 0xcc},
+		wantReg: map[expr.Key]uint64{},
+		wantMem: map[uint64]byte{},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			order := tt.order
+			if order == nil {
+				order = binary.LittleEndian
+			}
+
+			regs, m := evalEffects(t, order, tt.bytes, tt.regs, mem)
+			require.Equal(t, tt.wantReg, regs)
+			require.Equal(t, tt.wantMem, m)
+		})
+	}
+}
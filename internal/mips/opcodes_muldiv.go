@@ -0,0 +1,138 @@
+package mips
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// mulDivInstructions are multiplication and division instructions and
+// instructions accessing hi and lo registers.
+var mulDivInstructions = []*instructionType{
+	moveFrom("mfhi", "010000", HiKey),
+	moveTo("mthi", "010001", HiKey),
+	moveFrom("mflo", "010010", LoKey),
+	moveTo("mtlo", "010011", LoKey),
+
+	mulDiv("mult", "000000", "011000", func(rs, rt expr.Expr) (expr.Expr, expr.Expr) {
+		return product(signedProduct(rs, rt))
+	}),
+	mulDiv("multu", "000000", "011001", func(rs, rt expr.Expr) (expr.Expr, expr.Expr) {
+		return product(unsignedProduct(rs, rt))
+	}),
+	mulDiv("div", "000000", "011010", func(rs, rt expr.Expr) (expr.Expr, expr.Expr) {
+		return exprtools.SignedMod(rs, rt, width32), exprtools.SignedDiv(rs, rt, width32)
+	}),
+	mulDiv("divu", "000000", "011011", func(rs, rt expr.Expr) (expr.Expr, expr.Expr) {
+		return exprtools.Mod(rs, rt, width32), expr.NewBinary(expr.Div, rs, rt, width32)
+	}),
+
+	mulDiv("madd", "011100", "000000", func(rs, rt expr.Expr) (expr.Expr, expr.Expr) {
+		return product(expr.NewBinary(expr.Add, accumulator(), signedProduct(rs, rt), width64))
+	}),
+	mulDiv("maddu", "011100", "000001", func(rs, rt expr.Expr) (expr.Expr, expr.Expr) {
+		return product(expr.NewBinary(expr.Add, accumulator(), unsignedProduct(rs, rt), width64))
+	}),
+	mulDiv("msub", "011100", "000100", func(rs, rt expr.Expr) (expr.Expr, expr.Expr) {
+		return product(exprtools.Sub(accumulator(), signedProduct(rs, rt), width64))
+	}),
+	mulDiv("msubu", "011100", "000101", func(rs, rt expr.Expr) (expr.Expr, expr.Expr) {
+		return product(exprtools.Sub(accumulator(), unsignedProduct(rs, rt), width64))
+	}),
+
+	{
+		// Values of hi and lo registers are unpredictable after mul,
+		// so the instruction is described as if they kept their
+		// values.
+		name:     "mul",
+		opcode:   pattern("011100 xxxxx xxxxx xxxxx 00000 000010"),
+		operands: regOperands,
+		effects: func(i instruction) []expr.Effect {
+			value := expr.NewBinary(expr.Mul, regLoad(i.rs()), regLoad(i.rt()), width32)
+			return []expr.Effect{regStore(value, i.rd())}
+		},
+	},
+}
+
+// signedProduct returns 64 bit product of signed 32 bit values e1 and e2.
+func signedProduct(e1, e2 expr.Expr) expr.Expr {
+	signBit := expr.ConstFromUint(uint8(31))
+	return expr.NewBinary(
+		expr.Mul,
+		exprtools.SignExtend(e1, signBit, width64),
+		exprtools.SignExtend(e2, signBit, width64),
+		width64,
+	)
+}
+
+// unsignedProduct returns 64 bit product of unsigned 32 bit values e1 and e2.
+func unsignedProduct(e1, e2 expr.Expr) expr.Expr {
+	return expr.NewBinary(expr.Mul, e1, e2, width64)
+}
+
+// accumulator returns 64 bit value formed by hi and lo registers.
+func accumulator() expr.Expr {
+	hi := expr.NewRegLoad(HiKey, width32)
+	lo := expr.NewRegLoad(LoKey, width32)
+	high := expr.NewBinary(expr.Lsh, hi, expr.ConstFromUint(uint8(32)), width64)
+	return exprtools.BitOr(high, lo, width64)
+}
+
+// product splits 64 bit value e into values of hi and lo registers.
+func product(e expr.Expr) (expr.Expr, expr.Expr) {
+	return expr.NewBinary(expr.Rsh, e, expr.ConstFromUint(uint8(32)), width64), e
+}
+
+// mulDiv creates an instruction called name with opcode op and function field
+// funct. The instruction stores values computed by fn from registers rs and rt
+// to hi and lo registers.
+func mulDiv(
+	name string,
+	op string,
+	funct string,
+	fn func(rs, rt expr.Expr) (expr.Expr, expr.Expr),
+) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern(op + " xxxxx xxxxx 00000 00000 " + funct),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rs().name(abi), i.rt().name(abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			hi, lo := fn(regLoad(i.rs()), regLoad(i.rt()))
+			return []expr.Effect{
+				expr.NewRegStore(hi, HiKey, width32),
+				expr.NewRegStore(lo, LoKey, width32),
+			}
+		},
+	}
+}
+
+// moveFrom creates an instruction called name with function field funct which
+// copies register key to register rd.
+func moveFrom(name string, funct string, key expr.Key) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern("000000 00000 00000 xxxxx 00000 " + funct),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rd().name(abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{regStore(expr.NewRegLoad(key, width32), i.rd())}
+		},
+	}
+}
+
+// moveTo creates an instruction called name with function field funct which
+// copies register rs to register key.
+func moveTo(name string, funct string, key expr.Key) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern("000000 xxxxx 00000 00000 00000 " + funct),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rs().name(abi)}
+		},
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{expr.NewRegStore(regLoad(i.rs()), key, width32)}
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package mips
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMulDivEffects(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		regs  map[expr.Key]uint64
+		want  map[expr.Key]uint64
+	}{{
+		// mult $3, $4
+		name:  "mult",
+		bytes: []byte{0x18, 0x00, 0x64, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 0xffffffff, "r4": 2},
+		want:  map[expr.Key]uint64{HiKey: 0xffffffff, LoKey: 0xfffffffe},
+	}, {
+		// multu $3, $4
+		name:  "multu",
+		bytes: []byte{0x19, 0x00, 0x64, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 0xffffffff, "r4": 2},
+		want:  map[expr.Key]uint64{HiKey: 1, LoKey: 0xfffffffe},
+	}, {
+		// div $zero, $3, $4
+		name:  "div",
+		bytes: []byte{0x1a, 0x00, 0x64, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 0xfffffff9, "r4": 2},
+		want:  map[expr.Key]uint64{HiKey: 0xffffffff, LoKey: 0xfffffffd},
+	}, {
+		// divu $zero, $3, $4
+		name:  "divu",
+		bytes: []byte{0x1b, 0x00, 0x64, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 7, "r4": 2},
+		want:  map[expr.Key]uint64{HiKey: 1, LoKey: 3},
+	}, {
+		// madd $3, $4
+		name:  "madd",
+		bytes: []byte{0x00, 0x00, 0x64, 0x70},
+		regs: map[expr.Key]uint64{
+			"r3":  0xffffffff,
+			"r4":  1,
+			HiKey: 0,
+			LoKey: 0,
+		},
+		want: map[expr.Key]uint64{HiKey: 0xffffffff, LoKey: 0xffffffff},
+	}, {
+		// msubu $3, $4
+		name:  "msubu",
+		bytes: []byte{0x05, 0x00, 0x64, 0x70},
+		regs:  map[expr.Key]uint64{"r3": 2, "r4": 3, HiKey: 1, LoKey: 0},
+		want:  map[expr.Key]uint64{HiKey: 0, LoKey: 0xfffffffa},
+	}, {
+		// mul $2, $3, $4
+		name:  "mul",
+		bytes: []byte{0x02, 0x10, 0x64, 0x70},
+		regs:  map[expr.Key]uint64{"r3": 0x10000, "r4": 0x10001},
+		want:  map[expr.Key]uint64{"r2": 0x10000},
+	}, {
+		// mfhi $2
+		name:  "mfhi",
+		bytes: []byte{0x10, 0x10, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{HiKey: 5},
+		want:  map[expr.Key]uint64{"r2": 5},
+	}, {
+		// mtlo $3
+		name:  "mtlo",
+		bytes: []byte{0x13, 0x00, 0x60, 0x00},
+		regs:  map[expr.Key]uint64{"r3": 5},
+		want:  map[expr.Key]uint64{LoKey: 5},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, evalRegs(t, tt.bytes, tt.regs))
+		})
+	}
+}
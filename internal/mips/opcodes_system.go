@@ -0,0 +1,105 @@
+package mips
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+)
+
+// systemInstructions are instructions interacting with an operating system and
+// memory barriers.
+var systemInstructions = []*instructionType{
+	{
+		name:      "syscall",
+		opcode:    pattern("000000 xxxxxxxxxxxxxxxxxxxx 001100"),
+		operands:  codeOperands,
+		instrType: model.TypeSyscall,
+		effects:   func(instruction) []expr.Effect { return nil },
+	}, {
+		name:      "break",
+		opcode:    pattern("000000 xxxxxxxxxxxxxxxxxxxx 001101"),
+		operands:  codeOperands,
+		instrType: model.TypeSyscall,
+		effects:   func(instruction) []expr.Effect { return nil },
+	}, {
+		name:   "sync",
+		opcode: pattern("000000 00000 00000 00000 xxxxx 001111"),
+		operands: func(i instruction, _ bool) []string {
+			if i.sa() == 0 {
+				return nil
+			}
+			return []string{decImm(int64(i.sa()))}
+		},
+		instrType: model.TypeMemOrder,
+		effects:   func(instruction) []expr.Effect { return nil },
+	}, {
+		// Hardware registers are readable by user programs only if
+		// enabled by the operating system, so they are modelled as
+		// registers set up by the system for the program.
+		name:   "rdhwr",
+		opcode: pattern("011111 00000 xxxxx xxxxx 00000 111011"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rt().name(abi), fmt.Sprintf("$%d", i.rd())}
+		},
+		effects: func(i instruction) []expr.Effect {
+			hwr := expr.NewRegLoad(hwrKey(uint8(i.rd())), width32)
+			return []expr.Effect{regStore(hwr, i.rt())}
+		},
+	},
+
+	trapReg("tge", "110000"),
+	trapReg("tgeu", "110001"),
+	trapReg("tlt", "110010"),
+	trapReg("tltu", "110011"),
+	trapReg("teq", "110100"),
+	trapReg("tne", "110110"),
+
+	trapImm("tgei", "01000"),
+	trapImm("tgeiu", "01001"),
+	trapImm("tlti", "01010"),
+	trapImm("tltiu", "01011"),
+	trapImm("teqi", "01100"),
+	trapImm("tnei", "01110"),
+}
+
+// hwrKey returns key of hardware register number n.
+func hwrKey(n uint8) expr.Key { return expr.Key(fmt.Sprintf("hwr%d", n)) }
+
+// codeOperands returns operands of syscall and break instructions: the code
+// encoded in bits [6:25] if it's not zero.
+func codeOperands(i instruction, _ bool) []string {
+	if code := i.field(6, 20); code != 0 {
+		return []string{decImm(int64(code))}
+	}
+	return nil
+}
+
+// trapReg creates a conditional trap instruction called name with function
+// field funct comparing registers rs and rt.
+//
+// Traps are calls of an operating system, so their effects are not modelled.
+func trapReg(name string, funct string) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern("000000 xxxxx xxxxx xxxxxxxxxx " + funct),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rs().name(abi), i.rt().name(abi)}
+		},
+		instrType: model.TypeSyscall,
+		effects:   func(instruction) []expr.Effect { return nil },
+	}
+}
+
+// trapImm creates a conditional trap instruction called name of REGIMM opcode
+// with rt field op comparing register rs with an immediate value.
+func trapImm(name string, op string) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: pattern("000001 xxxxx " + op + " xxxxxxxxxxxxxxxx"),
+		operands: func(i instruction, abi bool) []string {
+			return []string{i.rs().name(abi), decImm(i.simm())}
+		},
+		instrType: model.TypeSyscall,
+		effects:   func(instruction) []expr.Effect { return nil },
+	}
+}
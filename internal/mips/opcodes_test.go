@@ -0,0 +1,41 @@
+package mips
+
+import (
+	"encoding/binary"
+	"mltwist/internal/archtest"
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstructions(t *testing.T) {
+	archtest.Instructions(t, instructions, (*instructionType).validate)
+}
+
+// testAddr is address instructions are parsed at in tests.
+const testAddr = 0x1000
+
+// evalEffects evaluates effects of an instruction encoded in bs in byte order
+// order at testAddr with registers set to regs and memory set to mem.
+// Registers and bytes of memory not present in regs or mem are zero. It
+// returns values of all registers and bytes of memory the instruction writes.
+func evalEffects(
+	t testing.TB,
+	order binary.ByteOrder,
+	bs []byte,
+	regs map[expr.Key]uint64,
+	mem map[uint64]byte,
+) (map[expr.Key]uint64, map[uint64]byte) {
+	ins, err := NewParser(order).Parse(testAddr, bs)
+	require.NoError(t, err)
+	return archtest.EvalEffects(t, ins, regs, mem)
+}
+
+// evalRegs evaluates register stores of a little-endian instruction encoded in
+// bs with registers set to regs. Registers not present in regs are zero.
+func evalRegs(t testing.TB, bs []byte, regs map[expr.Key]uint64) map[expr.Key]uint64 {
+	res, mem := evalEffects(t, binary.LittleEndian, bs, regs, nil)
+	require.Empty(t, mem)
+	return res
+}
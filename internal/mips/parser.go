@@ -0,0 +1,71 @@
+package mips
+
+import (
+	"encoding/binary"
+	"fmt"
+	"mltwist/internal/opcode"
+	"mltwist/pkg/model"
+)
+
+// Parser parses instructions of 32 bit MIPS architecture (MIPS32 release 2).
+//
+// Branch-likely instructions, which execute their delay slot only if the
+// branch is taken, are not supported. Neither are instructions of
+// coprocessors.
+type Parser struct {
+	matcher *opcode.Matcher[*instructionType]
+	order   binary.ByteOrder
+}
+
+// NewParser creates a new MIPS32 instruction parser for programs with byte
+// order order.
+func NewParser(order binary.ByteOrder) Parser {
+	decoder, err := opcode.NewMatcher(instructions)
+
+	// This means that instruction opcodes defined in this package are
+	// either invalid or they collide. This is non-recoverable as the
+	// package code has to be modified.
+	if err != nil {
+		panic(fmt.Sprintf("bug: matcher creation failed: %s", err.Error()))
+	}
+
+	return Parser{matcher: decoder, order: order}
+}
+
+// Parse parses an instruction starting at address a comprising of bytes at the
+// beginning of bs.
+//
+// The array of bytes bs is allowed to be longer than the instruction. In such a
+// case the Parse method will take into consideration only those bytes at the
+// beginning of the array which represent a single instruction. All MIPS32
+// instructions are 4 bytes long.
+func (p Parser) Parse(a model.Addr, bs []byte) (model.Instruction, error) {
+	if l := len(bs); l < instructionLen {
+		return model.Instruction{}, fmt.Errorf(
+			"bytes are too short to be a MIPS32 instruction opcode: %d", l)
+	}
+
+	// Opcodes are described by little-endian values, so the instruction
+	// is converted to the little-endian byte order for matching.
+	value := make([]byte, instructionLen)
+	binary.LittleEndian.PutUint32(value, p.order.Uint32(bs))
+
+	opcode, ok := p.matcher.Match(value)
+	if !ok {
+		return model.Instruction{}, fmt.Errorf(
+			"unknown instruction opcode: 0x%x", bs[:instructionLen])
+	}
+
+	instr := newInstruction(a, bs, p.order, opcode)
+	return model.Instruction{
+		Type:    opcode.instrType,
+		ByteLen: instructionLen,
+
+		Effects: opcode.validEffects(instr),
+		Details: instr,
+	}, nil
+}
+
+// MinInstrLen returns length of the shortest MIPS32 instruction in bytes. All
+// MIPS32 instructions have the same length.
+func (Parser) MinInstrLen() model.Addr { return instructionLen }
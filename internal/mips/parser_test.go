@@ -0,0 +1,89 @@
+package mips
+
+import (
+	"encoding/binary"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewParser(_ *testing.T) {
+	NewParser(binary.LittleEndian)
+}
+
+func TestParser_Parse(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    binary.ByteOrder
+		bytes    []byte
+		wantType model.Type
+		hasErr   bool
+	}{{
+		// addiu $sp, $sp, -32
+		name:  "valid",
+		bytes: []byte{0xe0, 0xff, 0xbd, 0x27},
+	}, {
+		// addiu $sp, $sp, -32
+		name:  "big_endian",
+		order: binary.BigEndian,
+		bytes: []byte{0x27, 0xbd, 0xff, 0xe0},
+	}, {
+		// addiu $sp, $sp, -32
+		name:  "longer_bytes",
+		bytes: []byte{0xe0, 0xff, 0xbd, 0x27, 0xff, 0xff},
+	}, {
+		name:   "too_short",
+		bytes:  []byte{0xe0, 0xff, 0xbd},
+		hasErr: true,
+	}, {
+		name:   "unknown",
+		bytes:  []byte{0xff, 0xff, 0xff, 0xff},
+		hasErr: true,
+	}, {
+		// syscall
+		name:     "syscall",
+		bytes:    []byte{0x0c, 0x00, 0x00, 0x00},
+		wantType: model.TypeSyscall,
+	}, {
+		// add $2, $3, $4
+		name:     "trapping_add",
+		bytes:    []byte{0x20, 0x10, 0x64, 0x00},
+		wantType: model.TypeSyscall,
+	}, {
+		// teq $2, $3
+		name:     "trap",
+		bytes:    []byte{0x34, 0x00, 0x43, 0x00},
+		wantType: model.TypeSyscall,
+	}, {
+		// tnei $2, 5
+		name:     "trap_immediate",
+		bytes:    []byte{0x05, 0x00, 0x4e, 0x04},
+		wantType: model.TypeSyscall,
+	}, {
+		// sync
+		name:     "sync",
+		bytes:    []byte{0x0f, 0x00, 0x00, 0x00},
+		wantType: model.TypeMemOrder,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			order := tt.order
+			if order == nil {
+				order = binary.LittleEndian
+			}
+
+			ins, err := NewParser(order).Parse(testAddr, tt.bytes)
+			if tt.hasErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, instructionLen, int(ins.ByteLen))
+			require.Equal(t, tt.wantType, ins.Type)
+			require.NoError(t, ins.Validate())
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package mips
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+)
+
+const (
+	// regBits is number of bits used to represent a register number.
+	regBits uint8 = 5
+
+	// regCnt is number of general purpose registers.
+	regCnt = 1 << regBits
+
+	// regZero is number of the zero register.
+	regZero regNum = 0
+	// regRA is number of the return address register.
+	regRA regNum = 31
+)
+
+// Keys of registers holding result of multiplication and division
+// instructions. Both registers are 32 bits wide.
+const (
+	// HiKey identifies the register holding high word of a product or
+	// remainder of a division.
+	HiKey = expr.Key("hi")
+	// LoKey identifies the register holding low word of a product or
+	// quotient of a division.
+	LoKey = expr.Key("lo")
+)
+
+// regNum represents a MIPS general purpose register number. Range of valid
+// values is [0..31] (i.e. [0..regCnt-1]).
+type regNum uint8
+
+// abiNames are names of registers defined by the O32 calling convention.
+var abiNames = [regCnt]string{
+	"zero", "at", "v0", "v1", "a0", "a1", "a2", "a3",
+	"t0", "t1", "t2", "t3", "t4", "t5", "t6", "t7",
+	"s0", "s1", "s2", "s3", "s4", "s5", "s6", "s7",
+	"t8", "t9", "k0", "k1", "gp", "sp", "fp", "ra",
+}
+
+// key returns key of register r. It returns false if r is the zero register.
+func (r regNum) key() (expr.Key, bool) {
+	if r == regZero {
+		return "", false
+	}
+	return expr.Key(fmt.Sprintf("r%d", r)), true
+}
+
+// name returns name of register r in assembler code. Names defined by the
+// calling convention are used if abi is set.
+func (r regNum) name(abi bool) string {
+	if abi {
+		return "$" + abiNames[r]
+	}
+	return fmt.Sprintf("$%d", r)
+}
+
+// regLoad loads value of register r.
+func regLoad(r regNum) expr.Expr {
+	key, ok := r.key()
+	if !ok {
+		return expr.Zero
+	}
+	return expr.NewRegLoad(key, width32)
+}
+
+// regStore stores e to register r. Writes to the zero register have no effect,
+// so nil is returned.
+func regStore(e expr.Expr, r regNum) expr.Effect {
+	key, ok := r.key()
+	if !ok {
+		return nil
+	}
+	return expr.NewRegStore(e, key, width32)
+}
@@ -0,0 +1,103 @@
+package parser
+
+import (
+	debugelf "debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"mltwist/internal/elf"
+	"mltwist/internal/state"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+)
+
+// Architecture describes everything the program needs to know about a machine
+// architecture to parse, display and emulate its code.
+type Architecture struct {
+	// Name is a human readable name of the architecture.
+	Name string
+
+	Parser Parser
+	// Assembler is nil if the architecture doesn't support assembling of
+	// instructions.
+	Assembler Assembler
+
+	// MemoryKey identifies the memory address space of instructions.
+	MemoryKey expr.Key
+	// MinInstrLen is length of the shortest instruction in bytes.
+	MinInstrLen model.Addr
+	// IPWidth is width of the instruction pointer.
+	IPWidth expr.Width
+	// ByteOrder is byte order of data words. It's nil if the byte order is
+	// unknown, data are then displayed as individual bytes.
+	ByteOrder binary.ByteOrder
+
+	// IPName is name of the instruction pointer in assembler code.
+	IPName string
+	// RegisterName returns name of register key in assembler code. Names
+	// defined by the calling convention are returned if abi is set. It's
+	// nil if keys of all registers are their names.
+	RegisterName func(key expr.Key, abi bool) string
+	// Syntax is the assembler syntax code is displayed in by default.
+	Syntax model.Syntax
+
+	// ResetState sets registers to their values after reset. It's nil if
+	// no registers have to be set.
+	ResetState func(regs *state.RegMap)
+}
+
+// RegName returns name of register key in assembler code. Names defined by the
+// calling convention are returned if abi is set.
+func (a Architecture) RegName(key expr.Key, abi bool) string {
+	switch {
+	case key == expr.IPKey && a.IPName != "":
+		return a.IPName
+	case a.RegisterName != nil:
+		return a.RegisterName(key, abi)
+	default:
+		return string(key)
+	}
+}
+
+// ErrISAUnsupported is returned by factories of architectures which have no ISA
+// strings if an ISA string is given.
+var ErrISAUnsupported = errors.New("ISA string is not supported by the architecture")
+
+// Factory creates description of the architecture of ELF file p. Target of the
+// architecture is given by ISA string isa if it's non-empty and it's detected
+// from the file otherwise. Factories of architectures which have no ISA
+// strings return ErrISAUnsupported if isa is non-empty.
+type Factory func(p *elf.Parser, isa string) (Architecture, error)
+
+// registry maps ELF machines to factories of their architectures.
+var registry = map[debugelf.Machine]Factory{}
+
+// Register makes factory f create architectures of ELF files of machine m.
+// Packages implementing architectures are expected to register themselves
+// in their init functions. Register is not safe for concurrent use.
+//
+// This function panics if a factory of machine m is already registered.
+func Register(m debugelf.Machine, f Factory) {
+	if _, ok := registry[m]; ok {
+		panic(fmt.Sprintf("architecture of %v is already registered", m))
+	}
+	registry[m] = f
+}
+
+// NewArchitecture creates description of the architecture of ELF file p using
+// factory registered for the machine of the file. ISA string isa is passed to
+// the factory.
+func NewArchitecture(p *elf.Parser, isa string) (Architecture, error) {
+	et, err := p.Target()
+	if err != nil {
+		return Architecture{}, err
+	}
+
+	f, ok := registry[et.Machine]
+	if !ok {
+		return Architecture{}, fmt.Errorf(
+			"unsupported machine architecture: %v", et.Machine)
+	}
+
+	return f(p, isa)
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	debugelf "debug/elf"
+	"mltwist/internal/elf"
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	m := debugelf.Machine(0xfff0)
+	f := func(*elf.Parser, string) (Architecture, error) {
+		return Architecture{Name: "test"}, nil
+	}
+
+	Register(m, f)
+	defer delete(registry, m)
+
+	require.Contains(t, registry, m)
+	require.Panics(t, func() { Register(m, f) })
+}
+
+func TestArchitecture_RegName(t *testing.T) {
+	named := Architecture{
+		IPName: "pc",
+		RegisterName: func(key expr.Key, abi bool) string {
+			if abi {
+				return "abi_" + string(key)
+			}
+			return "reg_" + string(key)
+		},
+	}
+
+	tests := []struct {
+		name string
+		arch Architecture
+		key  expr.Key
+		abi  bool
+		want string
+	}{{
+		name: "key",
+		key:  "x1",
+		want: "x1",
+	}, {
+		name: "register_name",
+		arch: named,
+		key:  "x1",
+		want: "reg_x1",
+	}, {
+		name: "abi_name",
+		arch: named,
+		key:  "x1",
+		abi:  true,
+		want: "abi_x1",
+	}, {
+		name: "ip",
+		arch: named,
+		key:  expr.IPKey,
+		want: "pc",
+	}, {
+		name: "ip_without_name",
+		key:  expr.IPKey,
+		want: string(expr.IPKey),
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.arch.RegName(tt.key, tt.abi))
+		})
+	}
+}
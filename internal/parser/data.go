@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"mltwist/pkg/model"
+	"strings"
+)
+
+// NewData creates a data pseudo-instruction at address addr consisting of the
+// first n bytes of b. If b is shorter than n bytes, the whole b is used. Data
+// words are written in byte order order, see DataString.
+//
+// The pseudo-instruction represents bytes which are not a valid instruction. It
+// has no effects and its type is model.TypeData.
+func NewData(
+	addr model.Addr,
+	b []byte,
+	n model.Addr,
+	order binary.ByteOrder,
+) Instruction {
+	if model.Addr(len(b)) > n {
+		b = b[:n]
+	}
+
+	return Instruction{
+		Type:    model.TypeData,
+		Addr:    addr,
+		Bytes:   b,
+		Details: dataDetails{bytes: b, order: order},
+	}
+}
+
+// dataDetails are platform details of a data pseudo-instruction.
+type dataDetails struct {
+	bytes []byte
+	order binary.ByteOrder
+}
+
+func (d dataDetails) Name() string   { return dataDirective(d.bytes, d.order) }
+func (d dataDetails) String() string { return DataString(d.bytes, d.order) }
+
+// dataWordLen is length of data word in bytes.
+const dataWordLen = 4
+
+func dataDirective(b []byte, order binary.ByteOrder) string {
+	if len(b) == dataWordLen && order != nil {
+		return ".word"
+	}
+	return ".byte"
+}
+
+// DataString represents bytes b in assembler code. Bytes exactly one word long
+// are represented as a single word in byte order order, other bytes are
+// represented individually. Nil order means that the byte order is unknown, so
+// all bytes are represented individually.
+func DataString(b []byte, order binary.ByteOrder) string {
+	if len(b) == dataWordLen && order != nil {
+		return fmt.Sprintf(".word 0x%08x", order.Uint32(b))
+	}
+
+	vals := make([]string, len(b))
+	for i, v := range b {
+		vals[i] = fmt.Sprintf("0x%02x", v)
+	}
+	return ".byte " + strings.Join(vals, ", ")
+}
@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataString(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		order binary.ByteOrder
+		want  string
+	}{{
+		name:  "little_endian",
+		bytes: []byte{0x01, 0x02, 0x03, 0x04},
+		order: binary.LittleEndian,
+		want:  ".word 0x04030201",
+	}, {
+		name:  "big_endian",
+		bytes: []byte{0x01, 0x02, 0x03, 0x04},
+		order: binary.BigEndian,
+		want:  ".word 0x01020304",
+	}, {
+		name:  "unknown_order",
+		bytes: []byte{0x01, 0x02, 0x03, 0x04},
+		want:  ".byte 0x01, 0x02, 0x03, 0x04",
+	}, {
+		name:  "not_word",
+		bytes: []byte{0x01, 0x02},
+		order: binary.BigEndian,
+		want:  ".byte 0x01, 0x02",
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, DataString(tt.bytes, tt.order))
+		})
+	}
+}
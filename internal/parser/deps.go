@@ -20,3 +20,14 @@ type Parser interface {
 	// contain the full instruction opcode.
 	Parse(addr model.Addr, b []byte) (model.Instruction, error)
 }
+
+// Assembler is a platform specific object responsible for encoding of
+// instructions written in assembler code.
+type Assembler interface {
+	// Assemble encodes a single instruction written in assembler code s
+	// into bytes of the instruction. The instruction is going to be placed
+	// at address addr.
+	//
+	// This method fails if s is not a valid instruction of the platform.
+	Assemble(addr model.Addr, s string) ([]byte, error)
+}
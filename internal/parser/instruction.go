@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"mltwist/internal/exprtransform"
 	"mltwist/pkg/expr"
 	"mltwist/pkg/model"
@@ -9,6 +10,9 @@ import (
 // Instruction represents single parsed machine code instruction.
 type Instruction struct {
 	Type model.Type
+	// Order describes memory accesses the instruction orders if it's of
+	// model.TypeMemOrder type.
+	Order model.MemOrder
 
 	// Addr is memory address of the instruction in program virtual memory.
 	Addr model.Addr
@@ -21,11 +25,32 @@ type Instruction struct {
 	Effects []expr.Effect
 	// Details provide platform-dependent functionality of the instruction.
 	Details model.PlatformDetails
+
+	// Source is position in the source code the instruction was compiled
+	// from. The position is unknown unless it's filled in from debug
+	// information of the program.
+	Source SourceLine
+}
+
+// SourceLine is a position in a source code file an instruction was compiled
+// from.
+type SourceLine struct {
+	// File is path to the source file.
+	File string
+	// Line is line number in the file starting from 1. Zero line means
+	// that the position is unknown.
+	Line int
 }
 
+// Known indicates that the source position is known.
+func (s SourceLine) Known() bool { return s.Line > 0 }
+
+func (s SourceLine) String() string { return fmt.Sprintf("%s:%d", s.File, s.Line) }
+
 func newInstruction(ins model.Instruction, addr model.Addr, bytes []byte) Instruction {
 	return Instruction{
 		Type:  ins.Type,
+		Order: ins.Order,
 		Addr:  addr,
 		Bytes: bytes[:ins.ByteLen],
 
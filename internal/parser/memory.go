@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"mltwist/pkg/model"
+	"sort"
+)
+
+// Block is a continuous sequence of machine code bytes placed at an address of
+// the program address space.
+type Block interface {
+	Begin() model.Addr
+	Bytes() []byte
+}
+
+// blockEnd returns exclusive end address of block b.
+func blockEnd(b Block) model.Addr { return b.Begin() + model.Addr(len(b.Bytes())) }
+
+// blockAddress returns the longest available slice of bytes of block b starting
+// at address a. If a doesn't belong to b, this function returns nil.
+func blockAddress(b Block, a model.Addr) []byte {
+	if a < b.Begin() || a >= blockEnd(b) {
+		return nil
+	}
+	return b.Bytes()[a-b.Begin():]
+}
+
+// memoryAddress returns the longest available slice of bytes of blocks bs
+// starting at address a. The blocks must be sorted by their ascending begin
+// addresses and they must not overlap.
+func memoryAddress(bs []Block, a model.Addr) []byte {
+	i := sort.Search(len(bs), func(i int) bool { return blockEnd(bs[i]) > a })
+	if i == len(bs) {
+		return nil
+	}
+	return blockAddress(bs[i], a)
+}
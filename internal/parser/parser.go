@@ -1,22 +1,55 @@
 package parser
 
 import (
+	"encoding/binary"
 	"fmt"
-	"mltwist/internal/elf"
 	"mltwist/pkg/model"
 )
 
-// Parse parses all instructions in a memory. This function fails if any of
-// parsings fails.
+// Option modifies behaviour of Parse.
+type Option func(*options)
+
+type options struct {
+	// dataLen is length of data pseudo-instructions emitted in place of
+	// bytes which cannot be parsed. Zero value means that parsing errors
+	// are not tolerated.
+	dataLen model.Addr
+	// dataOrder is byte order of words of data pseudo-instructions.
+	dataOrder binary.ByteOrder
+}
+
+// DataOnError makes Parse continue when an instruction cannot be parsed.
+// Instead of failing, n bytes at the address are represented by a data
+// pseudo-instruction (see NewData) with words in byte order order and parsing
+// continues right behind them.
+func DataOnError(n model.Addr, order binary.ByteOrder) Option {
+	return func(o *options) {
+		o.dataLen = n
+		o.dataOrder = order
+	}
+}
+
+// Parse parses all instructions in code blocks. The blocks must be sorted by
+// their ascending begin addresses and they must not overlap. This function
+// fails if any of parsings fails unless DataOnError option is set.
 func Parse(
-	m *elf.Memory,
+	code []Block,
 	p Parser,
+	opts ...Option,
 ) ([]Instruction, error) {
-	instrs := make([]Instruction, 0, len(m.Blocks))
-	for _, block := range m.Blocks {
-		for addr := block.Begin(); addr < block.End(); {
-			ins, err := parseIns(p, block, addr)
-			if err != nil {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	instrs := make([]Instruction, 0, len(code))
+	for _, block := range code {
+		for addr := block.Begin(); addr < blockEnd(block); {
+			b := blockAddress(block, addr)
+			ins, err := ParseInstruction(p, addr, b)
+			if err != nil && o.dataLen > 0 {
+				ins = NewData(addr, b, o.dataLen, o.dataOrder)
+			} else if err != nil {
 				return nil, fmt.Errorf(
 					"cannot parse instruction at address 0x%x: %w",
 					addr, err)
@@ -30,13 +63,10 @@ func Parse(
 	return instrs, nil
 }
 
-func parseIns(
-	p Parser,
-	block elf.Block,
-	addr model.Addr,
-) (Instruction, error) {
-	b := block.Address(addr)
-
+// ParseInstruction parses a single instruction at address addr which starts at
+// the beginning of b. Byte slice b is allowed to be longer than the
+// instruction.
+func ParseInstruction(p Parser, addr model.Addr, b []byte) (Instruction, error) {
 	ins, err := p.Parse(addr, b)
 	if err != nil {
 		return Instruction{}, fmt.Errorf("parsing error: %w", err)
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"encoding/binary"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	code := []byte{
+		testOpNop, 0, 0, 0,
+		0xff, 0xfe, 0xfd, 0xfc,
+		testOpRet, 0, 0, 0,
+		0xff, 0xff,
+	}
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		types  []model.Type
+		hasErr bool
+	}{{
+		name:   "strict",
+		hasErr: true,
+	}, {
+		name: "data_on_error",
+		opts: []Option{DataOnError(4, binary.LittleEndian)},
+		types: []model.Type{
+			model.TypeNone,
+			model.TypeData,
+			model.TypeNone,
+			model.TypeData,
+		},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			m := []Block{testBlock{0x100, code}}
+
+			instrs, err := Parse(m, testParser{}, tt.opts...)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			r.Len(instrs, len(tt.types))
+			for i, ins := range instrs {
+				r.Equal(tt.types[i], ins.Type)
+			}
+
+			r.Equal(model.Addr(0x104), instrs[1].Addr)
+			r.Empty(instrs[1].Effects)
+			r.Equal(".word 0xfcfdfeff", instrs[1].Details.String())
+			r.Equal(model.Addr(0x10c), instrs[3].Addr)
+			r.Equal(".byte 0xff, 0xff", instrs[3].Details.String())
+		})
+	}
+}
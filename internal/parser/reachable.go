@@ -0,0 +1,265 @@
+package parser
+
+import (
+	"fmt"
+	"mltwist/internal/exprtransform"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"sort"
+)
+
+// Data is a region of machine code memory which doesn't contain any reachable
+// instruction. Typical examples of such regions are literal pools, padding in
+// between functions or jump tables.
+type Data struct {
+	// Addr is memory address of the region in program virtual memory.
+	Addr model.Addr
+	// Bytes is slice of raw bytes of the region.
+	Bytes []byte
+}
+
+// Begin returns the address where d starts.
+func (d Data) Begin() model.Addr { return d.Addr }
+
+// Len returns length of d in bytes.
+func (d Data) Len() model.Addr { return model.Addr(len(d.Bytes)) }
+
+// End returns exclusive ending address of d.
+func (d Data) End() model.Addr { return d.Addr + d.Len() }
+
+// ParseReachable parses instructions in code blocks which are reachable from
+// addresses starts. Unlike Parse, this function doesn't parse memory linearly,
+// but it follows the control flow of the program instead. All bytes of the
+// memory which are not reached are returned as data regions.
+//
+// Control flow is followed only through constant jump targets. Calls (jumps
+// storing address of the following instruction into a register or memory) are
+// expected to return back to the following instruction. Delay slots of delayed
+// jumps are always reached and the instruction behind a delay slot is reached
+// only if the jump is conditional or if it's a call. Starting addresses and
+// jump targets outside of the code are ignored. The blocks must be sorted by
+// their ascending begin addresses and they must not overlap.
+//
+// This function fails if a reachable instruction cannot be parsed or if two
+// reachable instructions overlap. If DataOnError option is set, reachable bytes
+// which cannot be parsed are represented by a data pseudo-instruction instead
+// and the control flow isn't followed behind them.
+func ParseReachable(
+	code []Block,
+	p Parser,
+	starts []model.Addr,
+	opts ...Option,
+) ([]Instruction, []Data, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	parsed := make(map[model.Addr]Instruction)
+	queue := append([]model.Addr{}, starts...)
+
+	for len(queue) > 0 {
+		addr := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		// branch is the delayed jump preceding the instruction at addr.
+		var branch *Instruction
+		for {
+			if _, ok := parsed[addr]; ok {
+				break
+			}
+
+			b := memoryAddress(code, addr)
+			if b == nil {
+				break
+			}
+
+			ins, err := ParseInstruction(p, addr, b)
+			if err != nil && o.dataLen > 0 {
+				parsed[addr] = NewData(addr, b, o.dataLen, o.dataOrder)
+				break
+			} else if err != nil {
+				return nil, nil, fmt.Errorf(
+					"cannot parse instruction at address 0x%x: %w",
+					addr, err)
+			}
+			parsed[addr] = ins
+
+			targets, next := successors(ins)
+			queue = append(queue, targets...)
+			if branch != nil && !resumes(*branch, ins.End()) {
+				next = false
+			}
+			if !next {
+				break
+			}
+
+			branch = nil
+			if delayed(ins) {
+				branch = &ins
+			}
+			addr = ins.End()
+		}
+	}
+
+	instrs := make([]Instruction, 0, len(parsed))
+	for _, ins := range parsed {
+		instrs = append(instrs, ins)
+	}
+	sort.Slice(instrs, func(i, j int) bool {
+		return instrs[i].Addr < instrs[j].Addr
+	})
+
+	for i := 1; i < len(instrs); i++ {
+		if prev := instrs[i-1]; prev.End() > instrs[i].Addr {
+			return nil, nil, fmt.Errorf(
+				"instructions at addresses 0x%x and 0x%x overlap",
+				prev.Addr, instrs[i].Addr)
+		}
+	}
+
+	return instrs, unreached(code, instrs), nil
+}
+
+// successors returns all constant jump targets of ins other than the following
+// instruction. It also indicates whether the following instruction can be
+// executed after ins. The following instruction of a delayed jump is its delay
+// slot which is always executed.
+func successors(ins Instruction) ([]model.Addr, bool) {
+	var targets []model.Addr
+	jumps, next, call := false, false, false
+
+	for _, ef := range ins.Effects {
+		var e expr.RegStore
+		switch s := ef.(type) {
+		case expr.RegStore:
+			e = s
+		case expr.MemStore:
+			// Return address of a call pushed to the stack.
+			call = call || storesAddr(s.Value(), ins.End())
+			continue
+		default:
+			continue
+		}
+
+		if e.Key() == expr.DelayedIPKey {
+			next = true
+			targets = append(targets, delayedTargets(e)...)
+			continue
+		} else if e.Key() != expr.IPKey {
+			// Link register of a call.
+			call = call || storesAddr(e.Value(), ins.End())
+			continue
+		}
+
+		jumps = true
+		for _, ex := range exprtransform.Possibilities(e.Value()) {
+			c, ok := exprtransform.ConstFold(ex).(expr.Const)
+			if !ok {
+				continue
+			}
+
+			a, _ := expr.ConstUint[model.Addr](c)
+			if a == ins.End() {
+				next = true
+				continue
+			}
+			targets = append(targets, a)
+		}
+	}
+
+	return targets, !jumps || next || call
+}
+
+// delayedTargets returns all constant targets of delayed jump e. A conditional
+// jump which is not taken continues behind the delay slot, so the address
+// following the delay slot is one of the targets.
+func delayedTargets(e expr.RegStore) []model.Addr {
+	var targets []model.Addr
+	for _, ex := range exprtransform.Possibilities(e.Value()) {
+		c, ok := exprtransform.ConstFold(ex).(expr.Const)
+		if !ok {
+			continue
+		}
+
+		a, _ := expr.ConstUint[model.Addr](c)
+		targets = append(targets, a)
+	}
+	return targets
+}
+
+// delayed indicates that ins is a delayed jump.
+func delayed(ins Instruction) bool {
+	for _, ef := range ins.Effects {
+		if e, ok := ef.(expr.RegStore); ok && e.Key() == expr.DelayedIPKey {
+			return true
+		}
+	}
+	return false
+}
+
+// resumes indicates whether the execution continues at address end behind the
+// delay slot of delayed jump ins. This happens if the jump is conditional or if
+// the jump is a call returning to end.
+func resumes(ins Instruction, end model.Addr) bool {
+	for _, ef := range ins.Effects {
+		switch e := ef.(type) {
+		case expr.MemStore:
+			if storesAddr(e.Value(), end) {
+				return true
+			}
+		case expr.RegStore:
+			if e.Key() != expr.DelayedIPKey {
+				if storesAddr(e.Value(), end) {
+					return true
+				}
+				continue
+			}
+
+			for _, ex := range exprtransform.Possibilities(e.Value()) {
+				if storesAddr(exprtransform.ConstFold(ex), end) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// storesAddr indicates that value stored by an effect is constant address a.
+func storesAddr(value expr.Expr, a model.Addr) bool {
+	c, ok := value.(expr.Const)
+	if !ok {
+		return false
+	}
+	v, _ := expr.ConstUint[model.Addr](c)
+	return v == a
+}
+
+// unreached lists all regions of code blocks which are not covered by sorted
+// instructions instrs.
+func unreached(code []Block, instrs []Instruction) []Data {
+	var data []Data
+	i := 0
+	for _, b := range code {
+		addr, end := b.Begin(), blockEnd(b)
+		for ; i < len(instrs) && instrs[i].Addr < end; i++ {
+			if a := instrs[i].Addr; a > addr {
+				data = append(data, Data{
+					Addr:  addr,
+					Bytes: blockAddress(b, addr)[:a-addr],
+				})
+			}
+			addr = instrs[i].End()
+		}
+
+		if addr < end {
+			data = append(data, Data{
+				Addr:  addr,
+				Bytes: blockAddress(b, addr),
+			})
+		}
+	}
+
+	return data
+}
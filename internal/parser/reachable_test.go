@@ -0,0 +1,262 @@
+package parser
+
+import (
+	"errors"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testBlock is a block of code starting at address begin.
+type testBlock struct {
+	begin model.Addr
+	bytes []byte
+}
+
+func (b testBlock) Begin() model.Addr { return b.begin }
+func (b testBlock) Bytes() []byte     { return b.bytes }
+
+type testDetails struct{}
+
+func (testDetails) Name() string   { return "test" }
+func (testDetails) String() string { return "test" }
+
+// testParser parses 4 bytes long instructions. The first byte of an instruction
+// identifies its kind and the second byte is a jump target in multiples of 4.
+type testParser struct{}
+
+const (
+	testOpNop = iota
+	testOpJump
+	testOpBranch
+	testOpCall
+	testOpPushCall
+	testOpRet
+	testOpDelayedJump
+	testOpDelayedBranch
+	testOpDelayedCall
+)
+
+func (testParser) Parse(addr model.Addr, b []byte) (model.Instruction, error) {
+	if len(b) < 4 {
+		return model.Instruction{}, errors.New("too short")
+	}
+
+	target := expr.NewConstUint(model.Addr(b[1])*4, expr.Width64)
+	next := expr.NewConstUint(addr+4, expr.Width64)
+	afterSlot := expr.NewConstUint(addr+8, expr.Width64)
+	ipStore := func(e expr.Expr) expr.Effect {
+		return expr.NewRegStore(e, expr.IPKey, expr.Width64)
+	}
+	delayedStore := func(e expr.Expr) expr.Effect {
+		return expr.NewRegStore(e, expr.DelayedIPKey, expr.Width64)
+	}
+
+	var effects []expr.Effect
+	switch b[0] {
+	case testOpNop:
+	case testOpJump:
+		effects = []expr.Effect{ipStore(target)}
+	case testOpBranch:
+		effects = []expr.Effect{ipStore(expr.NewLess(
+			expr.NewRegLoad("r1", expr.Width64),
+			expr.NewRegLoad("r2", expr.Width64),
+			target, next, expr.Width64))}
+	case testOpCall:
+		effects = []expr.Effect{
+			ipStore(target),
+			expr.NewRegStore(next, "ra", expr.Width64),
+		}
+	case testOpPushCall:
+		sp := expr.NewRegLoad("sp", expr.Width64)
+		effects = []expr.Effect{
+			ipStore(target),
+			expr.NewMemStore(next, "mem", sp, expr.Width64),
+		}
+	case testOpRet:
+		effects = []expr.Effect{
+			ipStore(expr.NewRegLoad("ra", expr.Width64)),
+		}
+	case testOpDelayedJump:
+		effects = []expr.Effect{delayedStore(target)}
+	case testOpDelayedBranch:
+		effects = []expr.Effect{delayedStore(expr.NewLess(
+			expr.NewRegLoad("r1", expr.Width64),
+			expr.NewRegLoad("r2", expr.Width64),
+			target, afterSlot, expr.Width64))}
+	case testOpDelayedCall:
+		effects = []expr.Effect{
+			delayedStore(target),
+			expr.NewRegStore(afterSlot, "ra", expr.Width64),
+		}
+	default:
+		return model.Instruction{}, errors.New("unknown opcode")
+	}
+
+	return model.Instruction{
+		ByteLen: 4,
+		Effects: effects,
+		Details: testDetails{},
+	}, nil
+}
+
+func TestParseReachable(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   []byte
+		starts []model.Addr
+		opts   []Option
+		instrs []model.Addr
+		data   []Data
+		hasErr bool
+	}{{
+		name: "linear",
+		code: []byte{
+			testOpNop, 0, 0, 0,
+			testOpRet, 0, 0, 0,
+			0xff, 0xff, 0xff, 0xff,
+		},
+		starts: []model.Addr{0},
+		instrs: []model.Addr{0, 4},
+		data:   []Data{{Addr: 8, Bytes: []byte{0xff, 0xff, 0xff, 0xff}}},
+	}, {
+		name: "jump_over_data",
+		code: []byte{
+			testOpJump, 2, 0, 0,
+			0xff, 0xff, 0xff, 0xff,
+			testOpBranch, 0, 0, 0,
+			testOpRet, 0, 0, 0,
+		},
+		starts: []model.Addr{0},
+		instrs: []model.Addr{0, 8, 12},
+		data:   []Data{{Addr: 4, Bytes: []byte{0xff, 0xff, 0xff, 0xff}}},
+	}, {
+		name: "call_returns",
+		code: []byte{
+			testOpCall, 3, 0, 0,
+			testOpRet, 0, 0, 0,
+			0xff, 0xff, 0xff, 0xff,
+			testOpRet, 0, 0, 0,
+		},
+		starts: []model.Addr{0},
+		instrs: []model.Addr{0, 4, 12},
+		data:   []Data{{Addr: 8, Bytes: []byte{0xff, 0xff, 0xff, 0xff}}},
+	}, {
+		name: "call_pushing_return_address",
+		code: []byte{
+			testOpPushCall, 3, 0, 0,
+			testOpRet, 0, 0, 0,
+			0xff, 0xff, 0xff, 0xff,
+			testOpRet, 0, 0, 0,
+		},
+		starts: []model.Addr{0},
+		instrs: []model.Addr{0, 4, 12},
+		data:   []Data{{Addr: 8, Bytes: []byte{0xff, 0xff, 0xff, 0xff}}},
+	}, {
+		name: "delay_slot",
+		code: []byte{
+			testOpDelayedJump, 3, 0, 0,
+			testOpNop, 0, 0, 0,
+			0xff, 0xff, 0xff, 0xff,
+			testOpRet, 0, 0, 0,
+		},
+		starts: []model.Addr{0},
+		instrs: []model.Addr{0, 4, 12},
+		data:   []Data{{Addr: 8, Bytes: []byte{0xff, 0xff, 0xff, 0xff}}},
+	}, {
+		name: "delayed_branch",
+		code: []byte{
+			testOpDelayedBranch, 3, 0, 0,
+			testOpNop, 0, 0, 0,
+			testOpRet, 0, 0, 0,
+			testOpRet, 0, 0, 0,
+		},
+		starts: []model.Addr{0},
+		instrs: []model.Addr{0, 4, 8, 12},
+	}, {
+		name: "delayed_call_returns",
+		code: []byte{
+			testOpDelayedCall, 4, 0, 0,
+			testOpNop, 0, 0, 0,
+			testOpRet, 0, 0, 0,
+			0xff, 0xff, 0xff, 0xff,
+			testOpRet, 0, 0, 0,
+		},
+		starts: []model.Addr{0},
+		instrs: []model.Addr{0, 4, 8, 16},
+		data:   []Data{{Addr: 12, Bytes: []byte{0xff, 0xff, 0xff, 0xff}}},
+	}, {
+		name: "multiple_starts",
+		code: []byte{
+			testOpRet, 0, 0, 0,
+			0xff, 0xff, 0xff, 0xff,
+			testOpRet, 0, 0, 0,
+			0xff, 0xff,
+		},
+		starts: []model.Addr{0, 8, 0x100},
+		instrs: []model.Addr{0, 8},
+		data: []Data{
+			{Addr: 4, Bytes: []byte{0xff, 0xff, 0xff, 0xff}},
+			{Addr: 12, Bytes: []byte{0xff, 0xff}},
+		},
+	}, {
+		name: "jump_outside",
+		code: []byte{
+			testOpJump, 0x40, 0, 0,
+		},
+		starts: []model.Addr{0},
+		instrs: []model.Addr{0},
+	}, {
+		name: "unparsable_reachable",
+		code: []byte{
+			testOpNop, 0, 0, 0,
+			0xff, 0xff, 0xff, 0xff,
+		},
+		starts: []model.Addr{0},
+		hasErr: true,
+	}, {
+		name: "unparsable_reachable_data",
+		code: []byte{
+			testOpNop, 0, 0, 0,
+			0xff, 0xff, 0xff, 0xff,
+			testOpNop, 0, 0, 0,
+		},
+		starts: []model.Addr{0},
+		opts:   []Option{DataOnError(4, nil)},
+		instrs: []model.Addr{0, 4},
+		data:   []Data{{Addr: 8, Bytes: []byte{testOpNop, 0, 0, 0}}},
+	}, {
+		name: "overlap",
+		code: []byte{
+			testOpNop, 0, 0, 0,
+			testOpRet, 0, 0, 0,
+		},
+		starts: []model.Addr{0, 2},
+		hasErr: true,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			m := []Block{testBlock{0, tt.code}}
+
+			instrs, data, err := ParseReachable(m, testParser{}, tt.starts, tt.opts...)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			addrs := make([]model.Addr, len(instrs))
+			for i, ins := range instrs {
+				addrs[i] = ins.Addr
+			}
+			r.Equal(tt.instrs, addrs)
+			r.Equal(tt.data, data)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package riscv
+
+import (
+	debugelf "debug/elf"
+	"encoding/binary"
+	"fmt"
+	"mltwist/internal/elf"
+	"mltwist/internal/parser"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"strings"
+)
+
+func init() { parser.Register(debugelf.EM_RISCV, newArchitecture) }
+
+// newArchitecture creates description of RISC-V architecture of ELF file p. The
+// target is given by ISA string isa if it's non-empty and it's detected from
+// the file otherwise. Privileged instructions are decoded in both cases, see
+// DetectTarget.
+func newArchitecture(p *elf.Parser, isa string) (parser.Architecture, error) {
+	var target Target
+	var err error
+	if isa != "" {
+		target, err = ParseISA(isa)
+		target = target.withExtension(ExtPriv)
+	} else {
+		target, err = DetectTarget(p)
+	}
+	if err != nil {
+		return parser.Architecture{}, fmt.Errorf(
+			"cannot identify RISC-V target: %w", err)
+	}
+
+	ipWidth := width64
+	if target.Variant == Variant32 {
+		ipWidth = width32
+	}
+
+	return parser.Architecture{
+		Name:        "RISC-V",
+		Parser:      target.Parser(),
+		Assembler:   target.Encoder(),
+		MemoryKey:   MemoryKey,
+		MinInstrLen: target.MinInstrLen(),
+		IPWidth:     ipWidth,
+		ByteOrder:   binary.LittleEndian,
+
+		IPName:       "pc",
+		RegisterName: registerName,
+		// Code is displayed the same way GNU objdump displays it by
+		// default: with ABI names of registers and pseudo-instructions.
+		Syntax: model.Syntax{ABINames: true, Aliases: true},
+
+		ResetState: ResetState,
+	}, nil
+}
+
+// registerName returns name of register key in assembler code. ABI names of
+// integer and floating point registers are returned if abi is set. Other
+// registers are named by their keys.
+func registerName(key expr.Key, abi bool) string {
+	s := string(key)
+	switch {
+	case strings.HasPrefix(s, "x"):
+		if r, err := parseRegNum(s); err == nil {
+			return r.name(abi)
+		}
+	case strings.HasPrefix(s, "f"):
+		if r, err := parseFRegNum(s); err == nil {
+			return r.name(abi)
+		}
+	}
+	return s
+}
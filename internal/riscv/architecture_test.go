@@ -0,0 +1,26 @@
+package riscv
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterName(t *testing.T) {
+	tests := []struct {
+		key  expr.Key
+		abi  bool
+		want string
+	}{
+		{key: "x2", want: "x2"},
+		{key: "x2", abi: true, want: "sp"},
+		{key: "f10", abi: true, want: "fa0"},
+		{key: "fcsr", abi: true, want: "fcsr"},
+		{key: "mstatus", abi: true, want: "mstatus"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, registerName(tt.key, tt.abi), tt.key)
+	}
+}
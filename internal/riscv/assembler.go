@@ -0,0 +1,85 @@
+package riscv
+
+import (
+	"errors"
+	"fmt"
+	"mltwist/internal/parser"
+	"mltwist/pkg/model"
+	"strings"
+)
+
+var _ parser.Assembler = Encoder{}
+
+// ErrCompressed is returned when a compressed instruction is assembled.
+var ErrCompressed = errors.New("compressed instructions cannot be assembled")
+
+// Assemble encodes a single instruction written in assembler code s into bytes
+// of the instruction.
+//
+// The syntax accepted is the one produced by String method of instructions
+// parsed by Parser. Registers can be referred either by their x-names (x0, x1,
+// ..., x31) or by their ABI names (zero, ra, sp, ...). Immediate values can be
+// written in decimal or hexadecimal (0x prefixed) notation. Immediate values of
+// PC-relative instructions (branches, jal) are offsets relative to the address
+// of the instruction, so address a doesn't affect the encoding. Lui and auipc
+// take the 20 bit value of their immediate field as objdump prints it. Fence
+// sets are written as subsets of iorw letters in this order or as 0 for an
+// empty set.
+//
+// Compressed instructions are never produced - not even if the instruction has
+// a compressed form. Consequently, an instruction assembled by this method
+// can never replace a compressed instruction without changing the code length.
+// Compressed instructions written by their own names (for example c.addi) are
+// rejected with an error wrapping ErrCompressed.
+func (e Encoder) Assemble(a model.Addr, s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	name, args, _ := strings.Cut(s, " ")
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, "c.") {
+		return nil, fmt.Errorf("%w: %q", ErrCompressed, name)
+	}
+
+	var ops Operands
+
+	t, ok := e.instrs[name]
+	if !ok {
+		// Acquire and release bits of atomic instructions are written
+		// as a suffix of the instruction name.
+		base, aq, rl, cut := cutOrderingSuffix(name)
+		if t, ok = e.instrs[base]; !cut || !ok || !t.aqrl {
+			return nil, fmt.Errorf("unknown instruction: %q", name)
+		}
+		name, ops.Aq, ops.Rl = base, aq, rl
+	}
+
+	var strs []string
+	if args = strings.TrimSpace(args); args != "" {
+		strs = strings.Split(args, ",")
+	}
+
+	kinds := t.syntax()
+	if n := len(kinds); n > 0 && kinds[n-1].optional() && len(strs) == n-1 {
+		// Only the last operand (rounding mode or vector mask) can be
+		// optional. If it's omitted, its default value is used.
+		kinds[n-1].omit(&ops)
+		kinds = kinds[:n-1]
+	}
+	if n := len(kinds); n > 0 && kinds[n-1].isVtype() && len(strs) > n {
+		// Vector type is written as a comma separated list of its
+		// fields (e32, m1, ta, ma).
+		strs = append(strs[:n-1], strings.Join(strs[n-1:], ","))
+	}
+	if len(strs) != len(kinds) {
+		return nil, fmt.Errorf("%s expects %d operands, got %d",
+			name, len(kinds), len(strs))
+	}
+
+	for i, k := range kinds {
+		str := strings.TrimSpace(strs[i])
+		if err := k.parse(str, &ops); err != nil {
+			return nil, fmt.Errorf("invalid operand %d: %w", i, err)
+		}
+	}
+
+	return e.EncodeBytes(name, ops)
+}
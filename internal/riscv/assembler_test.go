@@ -0,0 +1,300 @@
+package riscv
+
+import (
+	"fmt"
+	"math/rand"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoder_Assemble_RoundTrip(t *testing.T) {
+	const iterations = 16
+
+	for v := Variant32; v < variantEnd; v++ {
+		v := v
+		t.Run(fmt.Sprintf("variant_%d", v), func(t *testing.T) {
+			e := NewEncoder(v, allExtensions()...)
+			p := NewParser(v, allExtensions()...)
+
+			for _, instrs := range instructions[v] {
+				for _, it := range instrs {
+					// Compressed instructions are never encoded.
+					if it.expand != nil {
+						continue
+					}
+
+					it := it
+					t.Run(it.name, func(t *testing.T) {
+						r := require.New(t)
+						rnd := rand.New(rand.NewSource(int64(len(it.name))))
+
+						for i := 0; i < iterations; i++ {
+							ops := randOperands(rnd, it)
+							expected, err := e.EncodeBytes(it.name, ops)
+							r.NoError(err)
+
+							ins, err := p.Parse(0x1000, expected)
+							r.NoError(err)
+
+							str := ins.Details.String()
+							bs, err := e.Assemble(0x1000, str)
+							r.NoError(err, str)
+							r.Equal(expected, bs, str)
+						}
+					})
+				}
+			}
+		})
+	}
+}
+
+func TestEncoder_Assemble(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected string
+		hasErr   bool
+	}{{
+		name:     "abi_names",
+		s:        "add a0, sp, ra",
+		expected: "add x10, x2, x1",
+	}, {
+		name:     "no_spaces",
+		s:        "addi x1,x2,-5",
+		expected: "addi x1, x2, -5",
+	}, {
+		name:     "hex_immediate",
+		s:        "  ADDI zero, t6, 0x7ff ",
+		expected: "addi x0, x31, 2047",
+	}, {
+		name:     "load",
+		s:        "ld s0, -8(fp)",
+		expected: "ld x8, -8(x8)",
+	}, {
+		name:     "load_no_offset",
+		s:        "lw a0, (a1)",
+		expected: "lw x10, 0(x11)",
+	}, {
+		name:     "store",
+		s:        "sd ra, 24(sp)",
+		expected: "sd x1, 24(x2)",
+	}, {
+		name:     "shift",
+		s:        "srai a0, a0, 63",
+		expected: "srai x10, x10, 63",
+	}, {
+		name:     "upper_immediate",
+		s:        "lui a0, 0xfffff",
+		expected: "lui x10, 1048575",
+	}, {
+		name:     "jalr_offset",
+		s:        "jalr t0, -866(tp)",
+		expected: "jalr x5, -866(x4)",
+	}, {
+		name:     "no_operands",
+		s:        "ecall",
+		expected: "ecall",
+	}, {
+		name:     "csr_immediate",
+		s:        "csrrwi x0, 0x300, 5",
+		expected: "csrrwi x0, mstatus, 5",
+	}, {
+		name:     "csr_name",
+		s:        "csrrs a0, cycle, zero",
+		expected: "csrrs x10, cycle, x0",
+	}, {
+		name:     "csr_unknown",
+		s:        "csrrw t0, 0x7c0, t1",
+		expected: "csrrw x5, 0x7c0, x6",
+	}, {
+		name:     "csr_unknown_number",
+		s:        "csrrc x5, 1984, x6",
+		expected: "csrrc x5, 0x7c0, x6",
+	}, {
+		name:     "fence",
+		s:        "fence iorw, ow",
+		expected: "fence iorw, ow",
+	}, {
+		name:     "fence_empty_set",
+		s:        "fence 0, r",
+		expected: "fence 0, r",
+	}, {
+		name:     "atomic_relaxed",
+		s:        "amoadd.d a0, a2, (a1)",
+		expected: "amoadd.d x10, x12, (x11)",
+	}, {
+		name:     "atomic_acquire_release",
+		s:        "AMOSWAP.W.AQRL a0, a2, (a1)",
+		expected: "amoswap.w.aqrl x10, x12, (x11)",
+	}, {
+		name:     "atomic_release",
+		s:        "sc.w.rl t0, t2, (t1)",
+		expected: "sc.w.rl x5, x7, (x6)",
+	}, {
+		name:     "float_dynamic_rounding",
+		s:        "fadd.s fa0, fa1, ft2",
+		expected: "fadd.s f10, f11, f2",
+	}, {
+		name:     "float_static_rounding",
+		s:        "fcvt.w.d a0, fs0, rtz",
+		expected: "fcvt.w.d x10, f8, rtz",
+	}, {
+		name:     "float_fused",
+		s:        "fnmsub.d f1, f2, f3, f4, rne",
+		expected: "fnmsub.d f1, f2, f3, f4, rne",
+	}, {
+		name:     "float_load",
+		s:        "flw fa0, 16(sp)",
+		expected: "flw f10, 16(x2)",
+	}, {
+		name:     "vector_type",
+		s:        "vsetvli t0, a0, e32, m1, ta, ma",
+		expected: "vsetvli x5, x10, e32, m1, ta, ma",
+	}, {
+		name:     "vector_type_undisturbed",
+		s:        "vsetivli zero, 8, e8,mf2",
+		expected: "vsetivli x0, 8, e8, mf2, tu, mu",
+	}, {
+		name:     "vector_type_reserved",
+		s:        "vsetvli a0, a1, 0x100",
+		expected: "vsetvli x10, x11, 256",
+	}, {
+		name:     "vector_masked",
+		s:        "vadd.vi v1, v2, -16, v0.t",
+		expected: "vadd.vi v1, v2, -16, v0.t",
+	}, {
+		name:     "vector_load",
+		s:        "vle64.v v8, (a0)",
+		expected: "vle64.v v8, (x10)",
+	}, {
+		name:     "vector_merge",
+		s:        "vmerge.vxm v4, v8, t1, v0",
+		expected: "vmerge.vxm v4, v8, x6, v0",
+	}, {
+		name:   "vector_invalid_mask",
+		s:      "vadd.vv v1, v2, v3, v1.t",
+		hasErr: true,
+	}, {
+		name:   "vector_merge_mask",
+		s:      "vmerge.vvm v1, v2, v3, v1",
+		hasErr: true,
+	}, {
+		name:   "vector_immediate_out_of_range",
+		s:      "vadd.vi v1, v2, 16",
+		hasErr: true,
+	}, {
+		name:   "vector_shift_out_of_range",
+		s:      "vsll.vi v1, v2, 32",
+		hasErr: true,
+	}, {
+		name:   "vector_type_invalid",
+		s:      "vsetvli a0, a1, e128, m1, ta, ma",
+		hasErr: true,
+	}, {
+		name:   "vector_scalar_register",
+		s:      "vadd.vv v1, v2, x3",
+		hasErr: true,
+	}, {
+		name:   "upper_immediate_out_of_range",
+		s:      "lui a0, 0x100000",
+		hasErr: true,
+	}, {
+		name:   "atomic_address_offset",
+		s:      "amoadd.w a0, a1, 8(a2)",
+		hasErr: true,
+	}, {
+		name:   "csr_out_of_range",
+		s:      "csrrw x5, 0x1000, x6",
+		hasErr: true,
+	}, {
+		name:   "csr_unknown_name",
+		s:      "csrrw x5, foo, x6",
+		hasErr: true,
+	}, {
+		name:   "fence_set_order",
+		s:      "fence wr, rw",
+		hasErr: true,
+	}, {
+		name:   "fence_missing_set",
+		s:      "fence rw",
+		hasErr: true,
+	}, {
+		name:   "acquire_non_atomic",
+		s:      "add.aq x1, x2, x3",
+		hasErr: true,
+	}, {
+		name:   "float_reserved_rounding",
+		s:      "fadd.d fa0, fa1, fa2, rm5",
+		hasErr: true,
+	}, {
+		name:   "float_integer_register",
+		s:      "fadd.s a0, fa1, fa2",
+		hasErr: true,
+	}, {
+		name:   "unknown_instruction",
+		s:      "foo x1, x2",
+		hasErr: true,
+	}, {
+		name:   "too_few_operands",
+		s:      "add x1, x2",
+		hasErr: true,
+	}, {
+		name:   "too_many_operands",
+		s:      "ecall x1",
+		hasErr: true,
+	}, {
+		name:   "unknown_register",
+		s:      "add x1, x2, x32",
+		hasErr: true,
+	}, {
+		name:   "invalid_memory_operand",
+		s:      "ld x1, x2",
+		hasErr: true,
+	}, {
+		name:   "immediate_out_of_range",
+		s:      "addi x1, x2, 4096",
+		hasErr: true,
+	}}
+
+	e := NewEncoder(Variant64, allExtensions()...)
+	p := NewParser(Variant64, allExtensions()...)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			bs, err := e.Assemble(0x1000, tt.s)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			ins, err := p.Parse(0x1000, bs)
+			r.NoError(err)
+			r.Equal(tt.expected, ins.Details.String())
+		})
+	}
+}
+
+func TestEncoder_Assemble_Compressed(t *testing.T) {
+	r := require.New(t)
+	e := NewEncoder(Variant64, allExtensions()...)
+	p := NewParser(Variant64, allExtensions()...)
+
+	// c.addi x10, 1
+	compressed, err := p.Parse(0x1000, []byte{0x05, 0x05})
+	r.NoError(err)
+	r.Equal(model.Addr(compressedLen), compressed.ByteLen)
+
+	// The expanded form is assembled even though a compressed one exists,
+	// so the instruction cannot be patched in place.
+	bs, err := e.Assemble(0x1000, compressed.Details.String())
+	r.NoError(err)
+	r.Len(bs, instructionLen)
+
+	_, err = e.Assemble(0x1000, "c.addi a0, 1")
+	r.ErrorIs(err, ErrCompressed)
+}
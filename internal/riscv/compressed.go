@@ -0,0 +1,514 @@
+package riscv
+
+import (
+	"fmt"
+	"mltwist/internal/opcode"
+)
+
+// quadrantMask is a mask of the lowest 2 bits of an instruction which identify
+// quadrant of a compressed instruction. Instructions which are not compressed
+// have both bits set (quadrant 3).
+const quadrantMask byte = 0x3
+
+// isCompressed checks whether an instruction starting with byte b is a
+// compressed instruction.
+func isCompressed(b byte) bool { return b&quadrantMask != quadrantMask }
+
+// cImmType represents encoding of a PC-relative offset in a compressed
+// instruction.
+type cImmType uint8
+
+const (
+	// cImmNone means that a compressed instruction doesn't encode any
+	// PC-relative offset.
+	cImmNone cImmType = iota
+	// cImmJ is the offset of compressed jumps (CJ format) encoded in bits
+	// [2:12] as offset[11|4|9:8|10|6|7|3:1|5].
+	cImmJ
+	// cImmB is the offset of compressed branches (CB format) encoded in bits
+	// [10:12] as offset[8|4:3] and in bits [2:6] as offset[7:6|2:1|5].
+	cImmB
+)
+
+// cBitsJ describes position of offset bits of cImmJ. The value at index i
+// is the offset bit encoded in instruction bit i+2.
+var cBitsJ = []uint8{5, 1, 2, 3, 7, 6, 10, 8, 9, 4, 11}
+
+// cBitsB describes position of offset bits of cImmB. The value at index i is
+// the offset bit encoded in instruction bit i+2.
+var cBitsB = []uint8{5, 1, 2, 6, 7, 0, 0, 0, 3, 4, 8}
+
+// bits returns description of bit positions of t.
+func (t cImmType) bits() ([]uint8, uint8) {
+	switch t {
+	case cImmJ:
+		return cBitsJ, 11
+	case cImmB:
+		return cBitsB, 8
+	default:
+		panic(fmt.Sprintf("unknown compressed immediate type: %v", t))
+	}
+}
+
+// parseValue parses an offset out of compressed instruction c.
+func (t cImmType) parseValue(c uint16) int32 {
+	bits, sign := t.bits()
+
+	var unsigned uint32
+	for i, b := range bits {
+		if b == 0 {
+			continue
+		}
+		unsigned |= parseBitRange(uint32(c), uint8(i)+2, uint8(i)+3) << b
+	}
+
+	return signExtend(unsigned, sign)
+}
+
+// fieldMask returns mask of all compressed instruction bits encoding t.
+func (t cImmType) fieldMask() uint16 {
+	if t == cImmNone {
+		return 0
+	}
+
+	bits, _ := t.bits()
+
+	var mask uint16
+	for i, b := range bits {
+		if b != 0 {
+			mask |= 1 << (i + 2)
+		}
+	}
+	return mask
+}
+
+// encodeValue encodes offset imm into compressed instruction bits. It returns
+// an error if imm cannot be represented by t.
+func (t cImmType) encodeValue(imm int32) (uint16, error) {
+	bits, sign := t.bits()
+	if !fitsSigned(imm, sign+1, 1) {
+		return 0, fmt.Errorf("value doesn't fit compressed immediate: %d", imm)
+	}
+
+	var value uint16
+	for i, b := range bits {
+		if b == 0 {
+			continue
+		}
+		value |= uint16(parseBitRange(uint32(imm), b, b+1)) << (i + 2)
+	}
+	return value, nil
+}
+
+// opcodeC returns opcode of a compressed instruction in quadrant q with funct3
+// bits [13:15] set to funct3.
+//
+// Unlike other instructions, compressed instructions are not described by
+// riscv-opcodes tables of encodingTables. They are expanded into base
+// instructions and several of them share a single opcode distinguished only
+// by values of their operands (c.jr, c.mv, c.ebreak, c.jalr and c.add for
+// example), which opcode masks cannot express.
+func opcodeC(funct3 byte, q byte) opcode.Opcode {
+	assertMask(q, quadrantMask)
+	assertMask(funct3, low3Bits)
+
+	return opcode.Opcode{
+		Bytes: []byte{q, funct3 << 5},
+		Mask:  []byte{quadrantMask, low3Bits << 5},
+	}
+}
+
+// cBits returns bits [begin, end) of compressed instruction c.
+func cBits(c uint16, begin uint8, end uint8) uint32 {
+	return parseBitRange(uint32(c), begin, end)
+}
+
+// cBitsAt composes a value out of bits of compressed instruction c. The value
+// at index i of positions is the bit of the value encoded in bit
+// firstBit+len(positions)-1-i of c - i.e. positions are listed from the most
+// significant bit of c as the RISC-V specification does.
+func cBitsAt(c uint16, firstBit uint8, positions ...uint8) uint32 {
+	var value uint32
+	for i, p := range positions {
+		bit := firstBit + uint8(len(positions)-1-i)
+		value |= cBits(c, bit, bit+1) << p
+	}
+	return value
+}
+
+// cReg returns number of a full register encoded in bits [begin, begin+5) of
+// compressed instruction c.
+func cReg(c uint16, begin uint8) uint8 { return uint8(cBits(c, begin, begin+5)) }
+
+// cRegPrime returns number of one of registers x8-x15 encoded in bits [begin,
+// begin+3) of compressed instruction c.
+func cRegPrime(c uint16, begin uint8) uint8 {
+	return 8 + uint8(cBits(c, begin, begin+3))
+}
+
+// cImm6 returns sign-extended 6 bit immediate encoded in bits [12] and [2:6]
+// of compressed instruction c.
+func cImm6(c uint16) int32 {
+	return signExtend(cBits(c, 12, 13)<<5|cBits(c, 2, 7), 5)
+}
+
+// cShamt returns shift amount encoded in bits [12] and [2:6] of compressed
+// instruction c.
+func cShamt(c uint16) int32 { return int32(cBits(c, 12, 13)<<5 | cBits(c, 2, 7)) }
+
+// stack pointer register number.
+const regSP = 2
+
+// reg numbers of link register and zero register.
+const (
+	regZero = 0
+	regRA   = 1
+)
+
+// cLoadWord expands c.lw and c.sw offsets - uimm[5:3] in bits [10:12] and
+// uimm[2|6] in bits [5:6].
+func cOffsetWord(c uint16) int32 {
+	return int32(cBitsAt(c, 10, 5, 4, 3) | cBitsAt(c, 5, 2, 6))
+}
+
+// cOffsetDouble expands c.ld and c.sd offsets - uimm[5:3] in bits [10:12] and
+// uimm[7:6] in bits [5:6].
+func cOffsetDouble(c uint16) int32 {
+	return int32(cBitsAt(c, 10, 5, 4, 3) | cBitsAt(c, 5, 7, 6))
+}
+
+func expandAddi4spn(c uint16) (string, Operands, bool) {
+	imm := cBitsAt(c, 5, 5, 4, 9, 8, 7, 6, 2, 3)
+	if imm == 0 {
+		return "", Operands{}, false
+	}
+	return "addi", Operands{Rd: cRegPrime(c, 2), Rs1: regSP, Imm: int32(imm)}, true
+}
+
+func expandLoad(name string, offset func(uint16) int32) func(uint16) (string, Operands, bool) {
+	return func(c uint16) (string, Operands, bool) {
+		return name, Operands{
+			Rd:  cRegPrime(c, 2),
+			Rs1: cRegPrime(c, 7),
+			Imm: offset(c),
+		}, true
+	}
+}
+
+func expandStore(name string, offset func(uint16) int32) func(uint16) (string, Operands, bool) {
+	return func(c uint16) (string, Operands, bool) {
+		return name, Operands{
+			Rs1: cRegPrime(c, 7),
+			Rs2: cRegPrime(c, 2),
+			Imm: offset(c),
+		}, true
+	}
+}
+
+func expandAddi(c uint16) (string, Operands, bool) {
+	rd := cReg(c, 7)
+	return "addi", Operands{Rd: rd, Rs1: rd, Imm: cImm6(c)}, true
+}
+
+func expandAddiw(c uint16) (string, Operands, bool) {
+	rd := cReg(c, 7)
+	if rd == regZero {
+		return "", Operands{}, false
+	}
+	return "addiw", Operands{Rd: rd, Rs1: rd, Imm: cImm6(c)}, true
+}
+
+func expandJal(rd uint8) func(uint16) (string, Operands, bool) {
+	return func(c uint16) (string, Operands, bool) {
+		return "jal", Operands{Rd: rd, Imm: cImmJ.parseValue(c)}, true
+	}
+}
+
+func expandLi(c uint16) (string, Operands, bool) {
+	return "addi", Operands{Rd: cReg(c, 7), Imm: cImm6(c)}, true
+}
+
+// expandLui expands both c.lui and c.addi16sp which share the same opcode and
+// differ only in the destination register.
+func expandLui(c uint16) (string, Operands, bool) {
+	rd := cReg(c, 7)
+	if rd == regSP {
+		imm := signExtend(cBitsAt(c, 12, 9)|cBitsAt(c, 2, 4, 6, 8, 7, 5), 9)
+		if imm == 0 {
+			return "", Operands{}, false
+		}
+		return "addi", Operands{Rd: regSP, Rs1: regSP, Imm: imm}, true
+	}
+
+	imm := signExtend(cBits(c, 12, 13)<<5|cBits(c, 2, 7), 5) << 12
+	if imm == 0 {
+		return "", Operands{}, false
+	}
+	return "lui", Operands{Rd: rd, Imm: imm}, true
+}
+
+// expandMiscAlu expands arithmetic instructions sharing funct3 100 in quadrant
+// 1: c.srli, c.srai, c.andi, c.sub, c.xor, c.or, c.and and on RV64 also
+// c.subw and c.addw. The shift amount has to be lower than shiftLimit.
+func expandMiscAlu(shiftLimit int32, rv64 bool) func(uint16) (string, Operands, bool) {
+	return func(c uint16) (string, Operands, bool) {
+		rd := cRegPrime(c, 7)
+
+		switch cBits(c, 10, 12) {
+		case 0b00, 0b01:
+			shamt := cShamt(c)
+			if shamt >= shiftLimit {
+				return "", Operands{}, false
+			}
+
+			name := "srli"
+			if cBits(c, 10, 12) == 0b01 {
+				name = "srai"
+			}
+			return name, Operands{Rd: rd, Rs1: rd, Imm: shamt}, true
+		case 0b10:
+			return "andi", Operands{Rd: rd, Rs1: rd, Imm: cImm6(c)}, true
+		}
+
+		names := [2][4]string{
+			{"sub", "xor", "or", "and"},
+			{"subw", "addw", "", ""},
+		}
+		name := names[cBits(c, 12, 13)][cBits(c, 5, 7)]
+		if name == "" || (!rv64 && cBits(c, 12, 13) == 1) {
+			return "", Operands{}, false
+		}
+
+		return name, Operands{Rd: rd, Rs1: rd, Rs2: cRegPrime(c, 2)}, true
+	}
+}
+
+func expandBranch(name string) func(uint16) (string, Operands, bool) {
+	return func(c uint16) (string, Operands, bool) {
+		return name, Operands{
+			Rs1: cRegPrime(c, 7),
+			Rs2: regZero,
+			Imm: cImmB.parseValue(c),
+		}, true
+	}
+}
+
+func expandSlli(shiftLimit int32) func(uint16) (string, Operands, bool) {
+	return func(c uint16) (string, Operands, bool) {
+		rd, shamt := cReg(c, 7), cShamt(c)
+		if shamt >= shiftLimit {
+			return "", Operands{}, false
+		}
+		return "slli", Operands{Rd: rd, Rs1: rd, Imm: shamt}, true
+	}
+}
+
+// cOffsetLwsp expands c.lwsp and c.flwsp offsets - uimm[5] in bit [12] and
+// uimm[4:2|7:6] in bits [2:6].
+func cOffsetLwsp(c uint16) int32 {
+	return int32(cBitsAt(c, 12, 5) | cBitsAt(c, 2, 4, 3, 2, 7, 6))
+}
+
+// cOffsetLdsp expands c.ldsp and c.fldsp offsets - uimm[5] in bit [12] and
+// uimm[4:3|8:6] in bits [2:6].
+func cOffsetLdsp(c uint16) int32 {
+	return int32(cBitsAt(c, 12, 5) | cBitsAt(c, 2, 4, 3, 8, 7, 6))
+}
+
+// cOffsetSwsp expands c.swsp and c.fswsp offsets - uimm[5:2|7:6] in bits
+// [7:12].
+func cOffsetSwsp(c uint16) int32 { return int32(cBitsAt(c, 7, 5, 4, 3, 2, 7, 6)) }
+
+// cOffsetSdsp expands c.sdsp and c.fsdsp offsets - uimm[5:3|8:6] in bits
+// [7:12].
+func cOffsetSdsp(c uint16) int32 { return int32(cBitsAt(c, 7, 5, 4, 3, 8, 7, 6)) }
+
+func expandLwsp(c uint16) (string, Operands, bool) {
+	rd := cReg(c, 7)
+	if rd == regZero {
+		return "", Operands{}, false
+	}
+	return "lw", Operands{Rd: rd, Rs1: regSP, Imm: cOffsetLwsp(c)}, true
+}
+
+func expandLdsp(c uint16) (string, Operands, bool) {
+	rd := cReg(c, 7)
+	if rd == regZero {
+		return "", Operands{}, false
+	}
+	return "ld", Operands{Rd: rd, Rs1: regSP, Imm: cOffsetLdsp(c)}, true
+}
+
+// expandFloatLoadSP expands stack pointer relative floating point loads. Unlike
+// integer loads, those can load into any register including f0.
+func expandFloatLoadSP(name string, offset func(uint16) int32) func(uint16) (string, Operands, bool) {
+	return func(c uint16) (string, Operands, bool) {
+		return name, Operands{Rd: cReg(c, 7), Rs1: regSP, Imm: offset(c)}, true
+	}
+}
+
+// expandJrMvAdd expands instructions sharing funct3 100 in quadrant 2: c.jr,
+// c.mv, c.ebreak, c.jalr and c.add.
+func expandJrMvAdd(c uint16) (string, Operands, bool) {
+	rs1, rs2 := cReg(c, 7), cReg(c, 2)
+
+	if cBits(c, 12, 13) == 0 {
+		switch {
+		case rs2 != regZero:
+			return "add", Operands{Rd: rs1, Rs2: rs2}, true
+		case rs1 != regZero:
+			return "jalr", Operands{Rs1: rs1}, true
+		default:
+			return "", Operands{}, false
+		}
+	}
+
+	switch {
+	case rs2 != regZero:
+		return "add", Operands{Rd: rs1, Rs1: rs1, Rs2: rs2}, true
+	case rs1 != regZero:
+		return "jalr", Operands{Rd: regRA, Rs1: rs1}, true
+	default:
+		return "ebreak", Operands{}, true
+	}
+}
+
+func expandStoreSP(name string, offset func(uint16) int32) func(uint16) (string, Operands, bool) {
+	return func(c uint16) (string, Operands, bool) {
+		return name, Operands{Rs1: regSP, Rs2: cReg(c, 2), Imm: offset(c)}, true
+	}
+}
+
+// compressedCommon lists compressed instructions which are shared by RV32 and
+// RV64 and which have the same encoding in both variants.
+func compressedCommon() []*instructionType {
+	return []*instructionType{{
+		name:   "c.addi4spn",
+		opcode: opcodeC(0b000, 0b00),
+		expand: expandAddi4spn,
+	}, {
+		name:   "c.lw",
+		opcode: opcodeC(0b010, 0b00),
+		expand: expandLoad("lw", cOffsetWord),
+	}, {
+		name:   "c.sw",
+		opcode: opcodeC(0b110, 0b00),
+		expand: expandStore("sw", cOffsetWord),
+	}, {
+		name:   "c.addi",
+		opcode: opcodeC(0b000, 0b01),
+		expand: expandAddi,
+	}, {
+		name:   "c.li",
+		opcode: opcodeC(0b010, 0b01),
+		expand: expandLi,
+	}, {
+		name:   "c.lui",
+		opcode: opcodeC(0b011, 0b01),
+		expand: expandLui,
+	}, {
+		name:       "c.j",
+		opcode:     opcodeC(0b101, 0b01),
+		expand:     expandJal(regZero),
+		cImmediate: cImmJ,
+	}, {
+		name:       "c.beqz",
+		opcode:     opcodeC(0b110, 0b01),
+		expand:     expandBranch("beq"),
+		cImmediate: cImmB,
+	}, {
+		name:       "c.bnez",
+		opcode:     opcodeC(0b111, 0b01),
+		expand:     expandBranch("bne"),
+		cImmediate: cImmB,
+	}, {
+		name:   "c.lwsp",
+		opcode: opcodeC(0b010, 0b10),
+		expand: expandLwsp,
+	}, {
+		name:   "c.add",
+		opcode: opcodeC(0b100, 0b10),
+		expand: expandJrMvAdd,
+	}, {
+		name:   "c.swsp",
+		opcode: opcodeC(0b110, 0b10),
+		expand: expandStoreSP("sw", cOffsetSwsp),
+	}, {
+		name:   "c.fld",
+		opcode: opcodeC(0b001, 0b00),
+		expand: expandLoad("fld", cOffsetDouble),
+	}, {
+		name:   "c.fsd",
+		opcode: opcodeC(0b101, 0b00),
+		expand: expandStore("fsd", cOffsetDouble),
+	}, {
+		name:   "c.fldsp",
+		opcode: opcodeC(0b001, 0b10),
+		expand: expandFloatLoadSP("fld", cOffsetLdsp),
+	}, {
+		name:   "c.fsdsp",
+		opcode: opcodeC(0b101, 0b10),
+		expand: expandStoreSP("fsd", cOffsetSdsp),
+	}}
+}
+
+var compressed32 = append(compressedCommon(), []*instructionType{{
+	name:       "c.jal",
+	opcode:     opcodeC(0b001, 0b01),
+	expand:     expandJal(regRA),
+	cImmediate: cImmJ,
+}, {
+	name:   "c.flw",
+	opcode: opcodeC(0b011, 0b00),
+	expand: expandLoad("flw", cOffsetWord),
+}, {
+	name:   "c.fsw",
+	opcode: opcodeC(0b111, 0b00),
+	expand: expandStore("fsw", cOffsetWord),
+}, {
+	name:   "c.flwsp",
+	opcode: opcodeC(0b011, 0b10),
+	expand: expandFloatLoadSP("flw", cOffsetLwsp),
+}, {
+	name:   "c.fswsp",
+	opcode: opcodeC(0b111, 0b10),
+	expand: expandStoreSP("fsw", cOffsetSwsp),
+}, {
+	name:   "c.sub",
+	opcode: opcodeC(0b100, 0b01),
+	expand: expandMiscAlu(32, false),
+}, {
+	name:   "c.slli",
+	opcode: opcodeC(0b000, 0b10),
+	expand: expandSlli(32),
+}}...)
+
+var compressed64 = append(compressedCommon(), []*instructionType{{
+	name:   "c.ld",
+	opcode: opcodeC(0b011, 0b00),
+	expand: expandLoad("ld", cOffsetDouble),
+}, {
+	name:   "c.sd",
+	opcode: opcodeC(0b111, 0b00),
+	expand: expandStore("sd", cOffsetDouble),
+}, {
+	name:   "c.addiw",
+	opcode: opcodeC(0b001, 0b01),
+	expand: expandAddiw,
+}, {
+	name:   "c.sub",
+	opcode: opcodeC(0b100, 0b01),
+	expand: expandMiscAlu(64, true),
+}, {
+	name:   "c.slli",
+	opcode: opcodeC(0b000, 0b10),
+	expand: expandSlli(64),
+}, {
+	name:   "c.ldsp",
+	opcode: opcodeC(0b011, 0b10),
+	expand: expandLdsp,
+}, {
+	name:   "c.sdsp",
+	opcode: opcodeC(0b111, 0b10),
+	expand: expandStoreSP("sd", cOffsetSdsp),
+}}...)
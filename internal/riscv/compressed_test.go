@@ -0,0 +1,259 @@
+package riscv
+
+import (
+	"mltwist/internal/exprtransform"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_Compressed(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		bytes   []byte
+		ins     string
+		ops     Operands
+		hasErr  bool
+	}{{
+		name:    "c.addi",
+		variant: Variant64,
+		bytes:   []byte{0x41, 0x11},
+		ins:     "addi",
+		ops:     Operands{Rd: 2, Rs1: 2, Imm: -16},
+	}, {
+		name:    "c.li",
+		variant: Variant64,
+		bytes:   []byte{0x01, 0x45},
+		ins:     "addi",
+		ops:     Operands{Rd: 10},
+	}, {
+		name:    "c.jr",
+		variant: Variant64,
+		bytes:   []byte{0x82, 0x80},
+		ins:     "jalr",
+		ops:     Operands{Rs1: 1},
+	}, {
+		name:    "c.sdsp",
+		variant: Variant64,
+		bytes:   []byte{0x06, 0xe4},
+		ins:     "sd",
+		ops:     Operands{Rs1: 2, Rs2: 1, Imm: 8},
+	}, {
+		name:    "c.mv",
+		variant: Variant64,
+		bytes:   []byte{0x2e, 0x85},
+		ins:     "add",
+		ops:     Operands{Rd: 10, Rs2: 11},
+	}, {
+		name:    "c.ld",
+		variant: Variant64,
+		bytes:   []byte{0x08, 0x61},
+		ins:     "ld",
+		ops:     Operands{Rd: 10, Rs1: 10},
+	}, {
+		name:    "c.flw_unsupported",
+		variant: Variant32,
+		bytes:   []byte{0x08, 0x61},
+		hasErr:  true,
+	}, {
+		name:    "c.j",
+		variant: Variant32,
+		bytes:   []byte{0xfd, 0xbf},
+		ins:     "jal",
+		ops:     Operands{Imm: -2},
+	}, {
+		name:    "c.beqz",
+		variant: Variant32,
+		bytes:   []byte{0x01, 0xc1},
+		ins:     "beq",
+		ops:     Operands{Rs1: 10},
+	}, {
+		name:    "reserved",
+		variant: Variant64,
+		bytes:   []byte{0x00, 0x00},
+		hasErr:  true,
+	}, {
+		name:    "truncated",
+		variant: Variant64,
+		bytes:   []byte{0x93, 0x00},
+		hasErr:  true,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			p := NewParser(tt.variant, ExtC)
+
+			ins, err := p.Parse(0x1000, tt.bytes)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			r.Equal(model.Addr(compressedLen), ins.ByteLen)
+			r.Equal(tt.ins, ins.Details.Name())
+
+			parsed := ins.Details.(instruction)
+			r.Equal(tt.ops, parsed.operands())
+			r.Equal(tt.bytes, parsed.bytes())
+		})
+	}
+}
+
+func TestParser_CompressedEffects(t *testing.T) {
+	p := NewParser(Variant64, ExtC)
+	e := NewEncoder(Variant64)
+
+	// c.addi sp, -16
+	compressed, err := p.Parse(0x1000, []byte{0x41, 0x11})
+	require.NoError(t, err)
+
+	bs, err := e.EncodeBytes("addi", Operands{Rd: 2, Rs1: 2, Imm: -16})
+	require.NoError(t, err)
+	expanded, err := p.Parse(0x1000, bs)
+	require.NoError(t, err)
+
+	require.Equal(t, expanded.Effects, compressed.Effects)
+	require.Equal(t, expanded.Type, compressed.Type)
+
+	// c.beqz a0, 0 falls through to the instruction 2 bytes further.
+	branch, err := p.Parse(0x1000, []byte{0x01, 0xc1})
+	require.NoError(t, err)
+	require.Len(t, branch.Effects, 1)
+
+	var targets []model.Addr
+	ef := branch.Effects[0].(expr.RegStore)
+	for _, ex := range exprtransform.Possibilities(ef.Value()) {
+		c, ok := exprtransform.ConstFold(ex).(expr.Const)
+		require.True(t, ok)
+
+		a, _ := expr.ConstUint[model.Addr](c)
+		targets = append(targets, a)
+	}
+	require.ElementsMatch(t, []model.Addr{0x1000, 0x1002}, targets)
+}
+
+func TestParser_Mixed(t *testing.T) {
+	r := require.New(t)
+	p := NewParser(Variant64, ExtC)
+
+	bs := []byte{
+		0x41, 0x11, // c.addi sp, -16
+		0x93, 0x00, 0x51, 0x00, // addi x1, x2, 5
+		0x82, 0x80, // c.jr ra
+	}
+
+	var names []string
+	for a := model.Addr(0); a < model.Addr(len(bs)); {
+		ins, err := p.Parse(a, bs[a:])
+		r.NoError(err)
+
+		names = append(names, ins.Details.Name())
+		a += ins.ByteLen
+	}
+
+	r.Equal([]string{"addi", "addi", "jalr"}, names)
+}
+
+func TestCImmType_encodeValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		t     cImmType
+		begin int32
+		end   int32
+	}{{
+		name:  "j",
+		t:     cImmJ,
+		begin: -2048,
+		end:   2048,
+	}, {
+		name:  "b",
+		t:     cImmB,
+		begin: -256,
+		end:   256,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			for imm := tt.begin; imm < tt.end; imm += 2 {
+				value, err := tt.t.encodeValue(imm)
+				r.NoError(err)
+				r.Zero(value &^ tt.t.fieldMask())
+				r.Equal(imm, tt.t.parseValue(value))
+			}
+
+			_, err := tt.t.encodeValue(tt.end)
+			r.Error(err)
+			_, err = tt.t.encodeValue(tt.begin - 2)
+			r.Error(err)
+			_, err = tt.t.encodeValue(1)
+			r.Error(err)
+		})
+	}
+}
+
+func TestInstruction_RelocateCompressed(t *testing.T) {
+	tests := []struct {
+		name   string
+		bytes  []byte
+		to     model.Addr
+		imm    int32
+		hasErr bool
+	}{{
+		// c.j 0
+		name:  "c.j",
+		bytes: []byte{0x01, 0xa0},
+		to:    0x1010,
+		imm:   -16,
+	}, {
+		// c.j 0
+		name:   "c.j_too_far",
+		bytes:  []byte{0x01, 0xa0},
+		to:     0x2000,
+		hasErr: true,
+	}, {
+		// c.beqz a0, 0
+		name:  "c.beqz",
+		bytes: []byte{0x01, 0xc1},
+		to:    0x0f02,
+		imm:   0xfe,
+	}, {
+		// c.beqz a0, 0
+		name:   "c.beqz_too_far",
+		bytes:  []byte{0x01, 0xc1},
+		to:     0x0f00,
+		hasErr: true,
+	}}
+
+	p := NewParser(Variant64, ExtC)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			ins, err := p.Parse(0x1000, tt.bytes)
+			r.NoError(err)
+
+			details, bytes, err := ins.Details.(model.Relocator).Relocate(tt.to)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+			r.Len(bytes, compressedLen)
+
+			moved, err := p.Parse(tt.to, bytes)
+			r.NoError(err)
+			r.Equal(details, moved.Details)
+			r.Equal(tt.imm, moved.Details.(instruction).operands().Imm)
+		})
+	}
+}
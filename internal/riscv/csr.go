@@ -1,10 +1,440 @@
 package riscv
 
-import "fmt"
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"mltwist/pkg/model"
+	"strconv"
+)
 
 // csr represents an arbitrary control and status register. Only bottom 12 bits
 // of this value are valid.
 type csr uint16
 
-// String returns a string representation of a given CSR.
-func (c csr) String() string { return fmt.Sprintf("csr%d", c) }
+// csrBits is number of bits of CSR number encoded in bits [20:31] of CSR
+// instructions.
+const csrBits = 12
+
+// String returns a string representation of a given CSR. Standard name of the
+// CSR is returned for CSRs listed in csrNames, other CSRs are represented by
+// their hexadecimal number.
+func (c csr) String() string {
+	if n, ok := csrNames[c]; ok {
+		return n
+	}
+	return fmt.Sprintf("0x%x", uint64(c))
+}
+
+// parseCsr parses a CSR written in assembler code. Both standard names of CSRs
+// and CSR numbers are accepted.
+func parseCsr(s string) (csr, error) {
+	for c, n := range csrNames {
+		if n == s {
+			return c, nil
+		}
+	}
+
+	v, err := strconv.ParseUint(s, 0, csrBits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CSR %q: %w", s, err)
+	}
+	return csr(v), nil
+}
+
+// Floating point CSRs defined by F extension.
+const (
+	// csrFflags holds accrued floating point exception flags.
+	csrFflags csr = 0x001
+	// csrFrm holds dynamic floating point rounding mode.
+	csrFrm csr = 0x002
+	// csrFcsr is floating point control and status register which
+	// combines both fflags and frm.
+	csrFcsr csr = 0x003
+)
+
+// Vector CSRs defined by V extension. The CSRs are read-only, vl and vtype are
+// written only by vector configuration instructions.
+const (
+	// csrVl is vector length - number of elements vector instructions
+	// operate on.
+	csrVl csr = 0xc20
+	// csrVtype is vector type.
+	csrVtype csr = 0xc21
+	// csrVlenb is width of vector registers in bytes.
+	csrVlenb csr = 0xc22
+)
+
+// Counters defined by Zicntr extension. The counters are read-only and they
+// are 64 bits wide. RV32 processors access their upper halves via separate
+// CSRs.
+const (
+	// csrCycle counts clock cycles.
+	csrCycle csr = 0xc00
+	// csrTime is wall-clock real time counter.
+	csrTime csr = 0xc01
+	// csrInstret counts retired instructions.
+	csrInstret csr = 0xc02
+	// csrCycleh is upper half of cycle on RV32.
+	csrCycleh csr = 0xc80
+	// csrTimeh is upper half of time on RV32.
+	csrTimeh csr = 0xc81
+	// csrInstreth is upper half of instret on RV32.
+	csrInstreth csr = 0xc82
+)
+
+// Trap handling CSRs defined by the privileged architecture.
+const (
+	// csrSstatus is a restricted view of mstatus for supervisor mode.
+	csrSstatus csr = 0x100
+	// csrSepc holds address of the instruction which trapped into
+	// supervisor mode.
+	csrSepc csr = 0x141
+	// csrMstatus is machine status register.
+	csrMstatus csr = 0x300
+	// csrMstatush is upper half of mstatus on RV32.
+	csrMstatush csr = 0x310
+	// csrMepc holds address of the instruction which trapped into machine
+	// mode.
+	csrMepc csr = 0x341
+)
+
+// csrNames lists standard names of CSRs used in assembler code as defined by
+// the unprivileged and the privileged specifications.
+var csrNames = func() map[csr]string {
+	names := map[csr]string{
+		0x000: "ustatus",
+		0x004: "uie",
+		0x005: "utvec",
+		0x040: "uscratch",
+		0x041: "uepc",
+		0x042: "ucause",
+		0x043: "utval",
+		0x044: "uip",
+
+		csrFflags: "fflags",
+		csrFrm:    "frm",
+		csrFcsr:   "fcsr",
+
+		0x008:    "vstart",
+		0x009:    "vxsat",
+		0x00a:    "vxrm",
+		0x00f:    "vcsr",
+		csrVl:    "vl",
+		csrVtype: "vtype",
+		csrVlenb: "vlenb",
+
+		0x015: "seed",
+
+		csrCycle:    "cycle",
+		csrTime:     "time",
+		csrInstret:  "instret",
+		csrCycleh:   "cycleh",
+		csrTimeh:    "timeh",
+		csrInstreth: "instreth",
+
+		csrSstatus: "sstatus",
+		0x104:      "sie",
+		0x105:      "stvec",
+		0x106:      "scounteren",
+		0x10a:      "senvcfg",
+		0x140:      "sscratch",
+		csrSepc:    "sepc",
+		0x142:      "scause",
+		0x143:      "stval",
+		0x144:      "sip",
+		0x180:      "satp",
+		0x5a8:      "scontext",
+
+		0x200: "vsstatus",
+		0x204: "vsie",
+		0x205: "vstvec",
+		0x240: "vsscratch",
+		0x241: "vsepc",
+		0x242: "vscause",
+		0x243: "vstval",
+		0x244: "vsip",
+		0x280: "vsatp",
+
+		0x600: "hstatus",
+		0x602: "hedeleg",
+		0x603: "hideleg",
+		0x604: "hie",
+		0x605: "htimedelta",
+		0x606: "hcounteren",
+		0x607: "hgeie",
+		0x60a: "henvcfg",
+		0x615: "htimedeltah",
+		0x61a: "henvcfgh",
+		0x643: "htval",
+		0x644: "hip",
+		0x645: "hvip",
+		0x64a: "htinst",
+		0x680: "hgatp",
+		0x6a8: "hcontext",
+		0xe12: "hgeip",
+
+		0xf11: "mvendorid",
+		0xf12: "marchid",
+		0xf13: "mimpid",
+		0xf14: "mhartid",
+		0xf15: "mconfigptr",
+
+		csrMstatus:  "mstatus",
+		0x301:       "misa",
+		0x302:       "medeleg",
+		0x303:       "mideleg",
+		0x304:       "mie",
+		0x305:       "mtvec",
+		0x306:       "mcounteren",
+		0x30a:       "menvcfg",
+		csrMstatush: "mstatush",
+		0x31a:       "menvcfgh",
+		0x320:       "mcountinhibit",
+		0x340:       "mscratch",
+		csrMepc:     "mepc",
+		0x342:       "mcause",
+		0x343:       "mtval",
+		0x344:       "mip",
+		0x34a:       "mtinst",
+		0x34b:       "mtval2",
+		0x747:       "mseccfg",
+		0x757:       "mseccfgh",
+
+		0xb00: "mcycle",
+		0xb02: "minstret",
+		0xb80: "mcycleh",
+		0xb82: "minstreth",
+
+		0x7a0: "tselect",
+		0x7a1: "tdata1",
+		0x7a2: "tdata2",
+		0x7a3: "tdata3",
+		0x7a8: "mcontext",
+		0x7b0: "dcsr",
+		0x7b1: "dpc",
+		0x7b2: "dscratch0",
+		0x7b3: "dscratch1",
+	}
+
+	// Numbered CSRs of the same purpose.
+	numbered := []struct {
+		first  csr
+		name   string
+		suffix string
+		from   int
+		to     int
+	}{
+		{first: 0x3a0, name: "pmpcfg", from: 0, to: 15},
+		{first: 0x3b0, name: "pmpaddr", from: 0, to: 63},
+		{first: 0xc03, name: "hpmcounter", from: 3, to: 31},
+		{first: 0xc83, name: "hpmcounter", suffix: "h", from: 3, to: 31},
+		{first: 0xb03, name: "mhpmcounter", from: 3, to: 31},
+		{first: 0xb83, name: "mhpmcounter", suffix: "h", from: 3, to: 31},
+		{first: 0x323, name: "mhpmevent", from: 3, to: 31},
+	}
+	for _, n := range numbered {
+		for i := n.from; i <= n.to; i++ {
+			c := n.first + csr(i-n.from)
+			names[c] = n.name + strconv.Itoa(i) + n.suffix
+		}
+	}
+
+	return names
+}()
+
+// csrAliases lists CSRs which are held in registers identified by other keys
+// than names of the CSRs. All of them are read-only.
+var csrAliases = map[csr]expr.Key{
+	csrVl:    vlKey,
+	csrVtype: vtypeKey,
+}
+
+// csrConstants lists CSRs of constant values. All of them are read-only.
+var csrConstants = map[csr]uint64{
+	csrVlenb: uint64(vlen),
+}
+
+// csrView describes a CSR which doesn't represent a register on its own, but
+// which is just a view of bits of another register. The view consists of bits
+// of the register shifted right by shift and masked by mask.
+type csrView struct {
+	key   expr.Key
+	w     expr.Width
+	shift uint8
+	mask  uint64
+}
+
+// csrViews lists all CSRs which are views of other registers.
+var csrViews = map[csr]csrView{
+	csrFflags: {key: fcsrKey, w: fcsrWidth, shift: 0, mask: 0x1f},
+	csrFrm:    {key: fcsrKey, w: fcsrWidth, shift: 5, mask: 0x7},
+	csrFcsr:   {key: fcsrKey, w: fcsrWidth, shift: 0, mask: 0xff},
+}
+
+// csrCounters lists read-only counters. Values of the counters are provided by
+// an environment executing the code.
+var csrCounters = map[csr]csrView{
+	csrCycle:    {key: expr.CycleKey, w: expr.Width64, shift: 0, mask: ^uint64(0)},
+	csrTime:     {key: expr.TimeKey, w: expr.Width64, shift: 0, mask: ^uint64(0)},
+	csrInstret:  {key: expr.InstretKey, w: expr.Width64, shift: 0, mask: ^uint64(0)},
+	csrCycleh:   {key: expr.CycleKey, w: expr.Width64, shift: 32, mask: 0xffffffff},
+	csrTimeh:    {key: expr.TimeKey, w: expr.Width64, shift: 32, mask: 0xffffffff},
+	csrInstreth: {key: expr.InstretKey, w: expr.Width64, shift: 32, mask: 0xffffffff},
+}
+
+// sstatusMask is mask of bits of mstatus visible in sstatus: SIE, SPIE, UBE,
+// SPP, VS, FS, XS, SUM and MXR. Read-only fields UXL and SD are not modelled.
+const sstatusMask = 0x000de762
+
+// Keys of registers holding machine status. Register mstatus is xlen bits wide.
+// On RV32, upper half of the machine status is held in 32 bit register
+// mstatush.
+const (
+	mstatusKey  = expr.Key("mstatus")
+	mstatushKey = expr.Key("mstatush")
+)
+
+// csrStatus returns view of status CSR c of a processor with registers of width
+// xlen. It returns false if c is not a status CSR. All status CSRs are views of
+// mstatus except mstatush which exists only on RV32.
+//
+// The CSRs control privileged state of the processor which is not modelled, so
+// writes to them are CPU state changes.
+func csrStatus(c csr, xlen expr.Width) (csrView, bool) {
+	switch {
+	case c == csrMstatus:
+		return csrView{key: mstatusKey, w: xlen, shift: 0, mask: ^uint64(0)}, true
+	case c == csrSstatus:
+		return csrView{key: mstatusKey, w: xlen, shift: 0, mask: sstatusMask}, true
+	case c == csrMstatush && xlen == width32:
+		return csrView{key: mstatushKey, w: width32, shift: 0, mask: 0xffffffff}, true
+	default:
+		return csrView{}, false
+	}
+}
+
+// csrNum returns number of CSR accessed by CSR instruction i.
+func csrNum(i instruction) csr {
+	return csr(parseBitRange(i.value, 20, 20+csrBits))
+}
+
+// csrWrites indicates that CSR instruction i writes the CSR. Instructions
+// setting or clearing bits of the CSR don't write it at all if their rs1 field
+// is zero - either register x0 or zero immediate.
+func csrWrites(i instruction) bool {
+	setOrClear := parseBitRange(i.value, 12, 15)&0b10 != 0
+	return !setOrClear || rs1.regNum(i.value) != 0
+}
+
+// csrReadOnly indicates that CSR c is read-only. The top two bits of numbers of
+// read-only CSRs are set.
+func csrReadOnly(c csr) bool { return c>>(csrBits-2) == 0b11 }
+
+// csrTraps indicates that CSR instruction i raises an illegal instruction
+// exception because it writes a read-only CSR. Such an instruction has no
+// effects.
+func csrTraps(i instruction) bool {
+	return csrWrites(i) && csrReadOnly(csrNum(i))
+}
+
+// csrModelled indicates that all effects of accessing CSR c are fully
+// described by expressions.
+func csrModelled(c csr) bool {
+	_, view := csrViews[c]
+	_, alias := csrAliases[c]
+	_, constant := csrConstants[c]
+	_, counter := csrCounters[c]
+	return view || alias || constant || counter
+}
+
+// csrInstrType returns instruction type of CSR instruction i.
+//
+// Writes to read-only CSRs trap into the execution environment. Accesses of
+// CSRs we don't model might change the way the processor executes any further
+// instruction, so writes of those CSRs are CPU state changes. CSR reads have no
+// side effects, so all of them (and all accesses of modelled CSRs) are ordered
+// just by their register dependencies.
+func csrInstrType(i instruction) model.Type {
+	if csrTraps(i) {
+		return model.TypeSyscall
+	}
+	if csrWrites(i) && !csrModelled(csrNum(i)) {
+		return model.TypeCPUStateChange
+	}
+	return model.TypeNone
+}
+
+// widthMask returns mask of bits of width w.
+func widthMask(w expr.Width) uint64 {
+	if w >= expr.Width64 {
+		return ^uint64(0)
+	}
+	return uint64(1)<<w.Bits() - 1
+}
+
+// read returns an expression of width w reading v. Bits of v which don't fit w
+// are cropped.
+func (v csrView) read(w expr.Width) expr.Expr {
+	val := expr.NewRegLoad(v.key, v.w)
+	shifted := expr.NewBinary(expr.Rsh, val, expr.ConstFromUint(v.shift), v.w)
+	mask := expr.NewConstUint(v.mask&widthMask(w), w)
+	return exprtools.BitAnd(shifted, mask, w)
+}
+
+// write returns an effect writing val of width w into v. Bits of val which
+// don't fit v are ignored and bits of v which don't fit w are kept unchanged.
+func (v csrView) write(val expr.Expr, w expr.Width) expr.Effect {
+	mask := expr.NewConstUint((v.mask&widthMask(w))<<v.shift, v.w)
+	kept := exprtools.BitAnd(
+		expr.NewRegLoad(v.key, v.w),
+		exprtools.BitNot(mask, v.w),
+		v.w,
+	)
+
+	shifted := expr.NewBinary(expr.Lsh, val, expr.ConstFromUint(v.shift), v.w)
+	field := exprtools.BitAnd(shifted, mask, v.w)
+
+	return expr.NewRegStore(exprtools.BitOr(kept, field, v.w), v.key, v.w)
+}
+
+// csrRead returns an expression of width w reading CSR accessed by CSR
+// instruction i.
+func csrRead(i instruction, w expr.Width) expr.Expr {
+	c := csrNum(i)
+	if v, ok := csrViews[c]; ok {
+		return v.read(w)
+	}
+	if v, ok := csrStatus(c, w); ok {
+		return v.read(w)
+	}
+	if key, ok := csrAliases[c]; ok {
+		return expr.NewRegLoad(key, w)
+	}
+	if val, ok := csrConstants[c]; ok {
+		return expr.NewConstUint(val, w)
+	}
+	if v, ok := csrCounters[c]; ok {
+		return v.read(w)
+	}
+	return expr.NewRegLoad(expr.Key(c.String()), w)
+}
+
+// csrWrite returns an effect writing val of width w into CSR accessed by CSR
+// instruction i. The effect is nil if i doesn't write the CSR at all. CSR
+// instructions writing read-only CSRs trap (see csrTraps), so this function
+// must not be called for them.
+func csrWrite(val expr.Expr, i instruction, w expr.Width) expr.Effect {
+	if !csrWrites(i) {
+		return nil
+	}
+
+	c := csrNum(i)
+	if v, ok := csrViews[c]; ok {
+		return v.write(val, w)
+	}
+	if v, ok := csrStatus(c, w); ok {
+		return v.write(val, w)
+	}
+	return expr.NewRegStore(val, expr.Key(c.String()), w)
+}
@@ -0,0 +1,162 @@
+package riscv
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCsr_String(t *testing.T) {
+	tests := []struct {
+		c    csr
+		want string
+	}{
+		{c: csrFcsr, want: "fcsr"},
+		{c: csrCycle, want: "cycle"},
+		{c: csrInstreth, want: "instreth"},
+		{c: 0x300, want: "mstatus"},
+		{c: 0x000, want: "ustatus"},
+		{c: 0x004, want: "uie"},
+		{c: 0x3a0, want: "pmpcfg0"},
+		{c: 0x3af, want: "pmpcfg15"},
+		{c: 0x3dd, want: "pmpaddr45"},
+		{c: 0xc1f, want: "hpmcounter31"},
+		{c: 0xc83, want: "hpmcounter3h"},
+		{c: 0xb9f, want: "mhpmcounter31h"},
+		{c: 0x33f, want: "mhpmevent31"},
+		{c: 0x7c0, want: "0x7c0"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.want, func(t *testing.T) {
+			r := require.New(t)
+			r.Equal(tt.want, tt.c.String())
+
+			c, err := parseCsr(tt.want)
+			r.NoError(err)
+			r.Equal(tt.c, c)
+		})
+	}
+}
+
+func TestCsrNames(t *testing.T) {
+	r := require.New(t)
+
+	seen := make(map[string]csr, len(csrNames))
+	for c, n := range csrNames {
+		r.Less(uint16(c), uint16(1<<csrBits), n)
+		prev, ok := seen[n]
+		r.False(ok, "%s is name of both 0x%x and 0x%x", n, prev, c)
+		seen[n] = c
+	}
+}
+
+func TestCsrEffects(t *testing.T) {
+	const counter = 0x00000001_00000002
+
+	tests := []struct {
+		name    string
+		variant Variant
+		s       string
+		regs    map[expr.Key]uint64
+		want    map[expr.Key]uint64
+	}{{
+		name:    "cycle_64",
+		variant: Variant64,
+		s:       "csrrs x5, cycle, x0",
+		regs:    map[expr.Key]uint64{expr.CycleKey: counter},
+		want:    map[expr.Key]uint64{"x5": counter},
+	}, {
+		name:    "cycle_32",
+		variant: Variant32,
+		s:       "csrrs x5, cycle, x0",
+		regs:    map[expr.Key]uint64{expr.CycleKey: counter},
+		want:    map[expr.Key]uint64{"x5": 2},
+	}, {
+		name:    "cycleh",
+		variant: Variant32,
+		s:       "csrrs x5, cycleh, x0",
+		regs:    map[expr.Key]uint64{expr.CycleKey: counter},
+		want:    map[expr.Key]uint64{"x5": 1},
+	}, {
+		name:    "timeh",
+		variant: Variant32,
+		s:       "csrrs x5, timeh, x0",
+		regs:    map[expr.Key]uint64{expr.TimeKey: counter},
+		want:    map[expr.Key]uint64{"x5": 1},
+	}, {
+		name:    "instret_write_traps",
+		variant: Variant64,
+		s:       "csrrw x5, instret, x6",
+		regs:    map[expr.Key]uint64{expr.InstretKey: counter, "x6": 7},
+		want:    map[expr.Key]uint64{},
+	}, {
+		name:    "instret_set_no_bits",
+		variant: Variant64,
+		s:       "csrrsi x5, instret, 0",
+		regs:    map[expr.Key]uint64{expr.InstretKey: counter},
+		want:    map[expr.Key]uint64{"x5": counter},
+	}, {
+		name:    "read_only_access",
+		variant: Variant64,
+		s:       "csrrs x5, mstatus, x0",
+		regs:    map[expr.Key]uint64{"mstatus": 0x8},
+		want:    map[expr.Key]uint64{"x5": 0x8},
+	}, {
+		name:    "set_bits",
+		variant: Variant64,
+		s:       "csrrsi x5, mstatus, 2",
+		regs:    map[expr.Key]uint64{"mstatus": 0x8},
+		want:    map[expr.Key]uint64{"x5": 0x8, "mstatus": 0xa},
+	}, {
+		name:    "write_without_read",
+		variant: Variant32,
+		s:       "csrrw x0, mscratch, x6",
+		regs:    map[expr.Key]uint64{"x6": 0x42},
+		want:    map[expr.Key]uint64{"mscratch": 0x42},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			res := evalRegs(t, tt.variant, tt.s, tt.regs)
+			require.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestParser_CsrType(t *testing.T) {
+	tests := []struct {
+		s    string
+		want model.Type
+	}{
+		{s: "csrrs x5, cycle, x0", want: model.TypeNone},
+		{s: "csrrw x5, time, x6", want: model.TypeSyscall},
+		{s: "csrrs x5, cycle, x6", want: model.TypeSyscall},
+		{s: "csrrci x5, mhartid, 1", want: model.TypeSyscall},
+		{s: "csrrc x5, mhartid, x0", want: model.TypeNone},
+		{s: "csrrs x5, mstatus, x0", want: model.TypeNone},
+		{s: "csrrci x5, mstatus, 0", want: model.TypeNone},
+		{s: "csrrw x0, fcsr, x6", want: model.TypeNone},
+		{s: "csrrs x5, mstatus, x6", want: model.TypeCPUStateChange},
+		{s: "csrrci x5, mstatus, 1", want: model.TypeCPUStateChange},
+		{s: "csrrw x0, satp, x6", want: model.TypeCPUStateChange},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.s, func(t *testing.T) {
+			r := require.New(t)
+
+			bs, err := NewEncoder(Variant64).Assemble(0x1000, tt.s)
+			r.NoError(err)
+
+			ins, err := NewParser(Variant64).Parse(0x1000, bs)
+			r.NoError(err)
+			r.Equal(tt.want, ins.Type)
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package riscv
+
+import "fmt"
+
+// Operands represents operands of a single RISC-V instruction.
+//
+// Only operands which are used by an instruction are allowed to be set. All
+// other operands have to be zero.
+type Operands struct {
+	// Rd is number of output register.
+	Rd uint8
+	// Rs1 is number of the first input register. For CSR instructions with
+	// immediate value, Rs1 is the 5 bit unsigned immediate written to CSR.
+	// The same applies to other instructions with 5 bit unsigned immediate
+	// encoded in rs1 field (vsetivli, vector shifts by immediate).
+	Rs1 uint8
+	// Rs2 is number of the second input register.
+	Rs2 uint8
+	// Rs3 is number of the third input register of fused multiply-add
+	// floating point instructions.
+	Rs3 uint8
+
+	// Rm is the rounding mode of a floating point instruction. Value 7
+	// selects the dynamic rounding mode held in fcsr register.
+	Rm uint8
+
+	// Imm is the immediate value of an instruction. For immediate shift
+	// instructions, Imm represents shift amount. For CSR instructions, Imm
+	// is the unsigned CSR number. For vsetvli and vsetivli instructions,
+	// Imm is the vector type.
+	Imm int32
+
+	// Pred and Succ are predecessor and successor sets of a fence
+	// instruction. Bits 3, 2, 1 and 0 of the sets stand for device input,
+	// device output, memory reads and memory writes respectively.
+	Pred uint8
+	Succ uint8
+
+	// Aq and Rl are acquire and release bits of atomic instructions.
+	Aq bool
+	Rl bool
+
+	// Masked indicates that a vector instruction operates only on elements
+	// enabled by mask register v0.
+	Masked bool
+}
+
+// Encoder encodes RISC-V instructions of a specified variant with specified
+// set of extensions.
+//
+// Encoder is inverse of Parser. Any instruction encoded by an Encoder can be
+// parsed by a Parser of the same variant and set of extensions and the parsed
+// instruction has the same operands as those used for encoding.
+type Encoder struct {
+	instrs map[string]*instructionType
+}
+
+// NewEncoder creates a new RISC-V instruction encoder of architecture variant v
+// with basic integer instruction set and set of extensions specified by exts.
+//
+// Usage variant not exported by this package is undefined. The same applies to
+// extensions not specified by this package. Specifying one extension multiple
+// times is undefined as well.
+func NewEncoder(v Variant, exts ...Extension) Encoder {
+	instrs := instructionSet(v, exts)
+
+	m := make(map[string]*instructionType, len(instrs))
+	for _, ins := range instrs {
+		// Compressed instructions are encoded by the linker or by the
+		// assembler only as an optimization of their expanded forms.
+		if ins.expand != nil {
+			continue
+		}
+		if _, ok := m[ins.name]; ok {
+			panic(fmt.Sprintf("bug: duplicate instruction name: %s", ins.name))
+		}
+		m[ins.name] = ins
+	}
+
+	return Encoder{instrs: m}
+}
+
+// Encode encodes an instruction called name with operands ops into an
+// instruction value. Bits [0..7] of the value represent the byte of the
+// instruction with the lowest in-memory address.
+//
+// An error is returned if no instruction is called name or if the operands
+// don't match the instruction.
+func (e Encoder) Encode(name string, ops Operands) (uint32, error) {
+	t, ok := e.instrs[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown instruction: %s", name)
+	}
+
+	value, err := t.encode(ops)
+	if err != nil {
+		return 0, fmt.Errorf("cannot encode %s: %w", name, err)
+	}
+
+	return value, nil
+}
+
+// EncodeBytes encodes an instruction called name with operands ops into bytes
+// of the instruction as stored in the memory.
+//
+// An error is returned if no instruction is called name or if the operands
+// don't match the instruction.
+func (e Encoder) EncodeBytes(name string, ops Operands) ([]byte, error) {
+	value, err := e.Encode(name, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	return instruction{value: value}.bytes(), nil
+}
+
+// encodeReg encodes register (or other 5 bit value) num into position r of an
+// instruction. Value of num is checked to fit 5 bits only if used is true. If
+// used is false, num is required to be zero.
+func encodeReg(r reg, num uint8, used bool) (uint32, error) {
+	if !used {
+		if num != 0 {
+			return 0, fmt.Errorf("unexpected operand: %d", num)
+		}
+		return 0, nil
+	}
+
+	if num >= regCnt {
+		return 0, fmt.Errorf("invalid register number: %d", num)
+	}
+
+	return uint32(num) << r.bitOffset(), nil
+}
+
+// encode encodes ops into an instruction opcode of type o.
+func (o instructionType) encode(ops Operands) (uint32, error) {
+	value := opcodeValue(o.opcode.Bytes)
+	if o.vectorOperands != nil {
+		return o.encodeVector(value, ops)
+	}
+
+	regs := []struct {
+		name string
+		reg  reg
+		num  uint8
+		used bool
+	}{
+		{"rd", rd, ops.Rd, o.hasOutputReg},
+		{"rs1", rs1, ops.Rs1, o.inputRegCnt > 0 || o.zimm},
+		{"rs2", rs2, ops.Rs2, o.inputRegCnt > 1},
+		{"rs3", rs3, ops.Rs3, o.inputRegCnt > 2},
+	}
+	for _, r := range regs {
+		bits, err := encodeReg(r.reg, r.num, r.used)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %w", r.name, err)
+		}
+		value |= bits
+	}
+
+	if o.roundingMode {
+		if !validRoundingMode(ops.Rm) {
+			return 0, fmt.Errorf("invalid rounding mode: %d", ops.Rm)
+		}
+		value |= uint32(ops.Rm) << rmBitOffset
+	} else if ops.Rm != 0 {
+		return 0, fmt.Errorf("unexpected rounding mode: %d", ops.Rm)
+	}
+
+	if o.fence {
+		if ops.Pred >= 1<<fenceSetBits || ops.Succ >= 1<<fenceSetBits {
+			return 0, fmt.Errorf(
+				"invalid fence sets: %d, %d", ops.Pred, ops.Succ)
+		}
+		value |= uint32(ops.Pred) << fencePredOffset
+		value |= uint32(ops.Succ) << fenceSuccOffset
+	} else if ops.Pred != 0 || ops.Succ != 0 {
+		return 0, fmt.Errorf(
+			"unexpected fence sets: %d, %d", ops.Pred, ops.Succ)
+	}
+
+	if o.aqrl {
+		if ops.Aq {
+			value |= 1 << aqBit
+		}
+		if ops.Rl {
+			value |= 1 << rlBit
+		}
+	} else if ops.Aq || ops.Rl {
+		return 0, fmt.Errorf("unexpected acquire or release bit")
+	}
+
+	if o.shamtBits > 0 {
+		if ops.Imm < 0 || ops.Imm >= int32(1)<<o.shamtBits {
+			return 0, fmt.Errorf("invalid shift amount: %d", ops.Imm)
+		}
+		value |= uint32(ops.Imm) << 20
+	} else if o.csr {
+		if ops.Imm < 0 || ops.Imm >= int32(1)<<csrBits {
+			return 0, fmt.Errorf("invalid CSR: %d", ops.Imm)
+		}
+		value |= uint32(ops.Imm) << 20
+	} else {
+		bits, err := o.immediate.encodeValue(ops.Imm)
+		if err != nil {
+			return 0, fmt.Errorf("invalid immediate: %w", err)
+		}
+		value |= bits
+	}
+
+	return value, nil
+}
+
+// encodeVector encodes ops of vector instruction of type o into instruction
+// opcode value.
+func (o instructionType) encodeVector(value uint32, ops Operands) (uint32, error) {
+	for _, k := range o.vectorOperands {
+		offset, bits := k.vectorField()
+
+		v, ok := k.vectorValue(ops)
+		if !ok || v >= uint32(1)<<bits {
+			return 0, fmt.Errorf("invalid operand: %s", k.format(ops, false))
+		}
+		value |= v << offset
+	}
+
+	// Operands which are not used by the instruction are not encoded, so
+	// they are lost in the round trip.
+	if decoded := (instruction{value: value, instrType: &o}).operands(); decoded != ops {
+		return 0, fmt.Errorf("unexpected operands: %+v", ops)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,222 @@
+package riscv
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// randImm generates a random immediate value which can be encoded into an
+// instruction of type t.
+func randImm(rnd *rand.Rand, t *instructionType) int32 {
+	if t.shamtBits > 0 {
+		return rnd.Int31n(int32(1) << t.shamtBits)
+	}
+
+	signed := func(bits uint8) int32 {
+		return rnd.Int31n(int32(1)<<bits) - int32(1)<<(bits-1)
+	}
+
+	switch t.immediate {
+	case immTypeR:
+		return 0
+	case immTypeI, immTypeS:
+		return signed(12)
+	case immTypeB:
+		return signed(12) * 2
+	case immTypeU:
+		return int32(rnd.Uint32() &^ 0xfff)
+	case immTypeJ:
+		return signed(20) * 2
+	default:
+		panic(fmt.Sprintf("unknown immediate type: %v", t.immediate))
+	}
+}
+
+// randOperands generates random operands of an instruction of type t.
+func randOperands(rnd *rand.Rand, t *instructionType) Operands {
+	var ops Operands
+	for _, k := range t.vectorOperands {
+		_, bits := k.vectorField()
+		k.setVectorValue(uint32(rnd.Intn(1<<bits)), &ops)
+	}
+
+	if t.hasOutputReg {
+		ops.Rd = uint8(rnd.Intn(regCnt))
+	}
+	if t.inputRegCnt > 0 || t.zimm {
+		ops.Rs1 = uint8(rnd.Intn(regCnt))
+	}
+	if t.inputRegCnt > 1 {
+		ops.Rs2 = uint8(rnd.Intn(regCnt))
+	}
+	if t.inputRegCnt > 2 {
+		ops.Rs3 = uint8(rnd.Intn(regCnt))
+	}
+	if t.roundingMode {
+		modes := []uint8{0, 1, 2, 3, 4, rmDynamic}
+		ops.Rm = modes[rnd.Intn(len(modes))]
+	}
+	if t.fence {
+		ops.Pred = uint8(rnd.Intn(1 << fenceSetBits))
+		ops.Succ = uint8(rnd.Intn(1 << fenceSetBits))
+	}
+	if t.aqrl {
+		ops.Aq = rnd.Intn(2) == 1
+		ops.Rl = rnd.Intn(2) == 1
+	}
+
+	ops.Imm = randImm(rnd, t)
+	return ops
+}
+
+func allExtensions() []Extension {
+	exts := make([]Extension, 0, extEnd-1)
+	for e := extI + 1; e < extEnd; e++ {
+		exts = append(exts, e)
+	}
+	return exts
+}
+
+func TestEncoder_RoundTrip(t *testing.T) {
+	const iterations = 64
+
+	for v := Variant32; v < variantEnd; v++ {
+		v := v
+		t.Run(fmt.Sprintf("variant_%d", v), func(t *testing.T) {
+			e := NewEncoder(v, allExtensions()...)
+			p := NewParser(v, allExtensions()...)
+
+			for _, instrs := range instructions[v] {
+				for _, it := range instrs {
+					// Compressed instructions are never encoded.
+					if it.expand != nil {
+						continue
+					}
+
+					it := it
+					t.Run(it.name, func(t *testing.T) {
+						r := require.New(t)
+						rnd := rand.New(rand.NewSource(int64(len(it.name))))
+
+						for i := 0; i < iterations; i++ {
+							ops := randOperands(rnd, it)
+
+							bs, err := e.EncodeBytes(it.name, ops)
+							r.NoError(err, "operands: %#v", ops)
+
+							ins, err := p.Parse(0x1000, bs)
+							r.NoError(err)
+							r.Equal(it.name, ins.Details.Name())
+
+							parsed := ins.Details.(instruction)
+							r.Equal(ops, parsed.operands())
+						}
+					})
+				}
+			}
+		})
+	}
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	tests := []struct {
+		name     string
+		ins      string
+		ops      Operands
+		expected uint32
+		hasErr   bool
+	}{{
+		name:     "addi",
+		ins:      "addi",
+		ops:      Operands{Rd: 1, Rs1: 2, Imm: 5},
+		expected: 0x00510093,
+	}, {
+		name:     "sw",
+		ins:      "sw",
+		ops:      Operands{Rs1: 2, Rs2: 8, Imm: -4},
+		expected: 0xfe812e23,
+	}, {
+		name:     "srai",
+		ins:      "srai",
+		ops:      Operands{Rd: 10, Rs1: 10, Imm: 63},
+		expected: 0x43f55513,
+	}, {
+		name:     "csrrwi",
+		ins:      "csrrwi",
+		ops:      Operands{Rd: 0, Rs1: 31, Imm: 0x300},
+		expected: 0x300fd073,
+	}, {
+		name:     "ecall",
+		ins:      "ecall",
+		expected: 0x00000073,
+	}, {
+		name:     "fmadd.s",
+		ins:      "fmadd.s",
+		ops:      Operands{Rd: 5, Rs1: 11, Rs2: 17, Rs3: 29, Rm: 1},
+		expected: 0xe91592c3,
+	}, {
+		name:     "fcvt.d.w",
+		ins:      "fcvt.d.w",
+		ops:      Operands{Rd: 5, Rs1: 11},
+		expected: 0xd20582d3,
+	}, {
+		name:   "reserved_rounding_mode",
+		ins:    "fadd.s",
+		ops:    Operands{Rm: 5},
+		hasErr: true,
+	}, {
+		name:   "unexpected_rounding_mode",
+		ins:    "fsgnj.d",
+		ops:    Operands{Rm: 1},
+		hasErr: true,
+	}, {
+		name:   "unknown_instruction",
+		ins:    "foo",
+		hasErr: true,
+	}, {
+		name:   "invalid_register",
+		ins:    "add",
+		ops:    Operands{Rd: 32},
+		hasErr: true,
+	}, {
+		name:   "unused_register",
+		ins:    "addi",
+		ops:    Operands{Rd: 1, Rs1: 1, Rs2: 1},
+		hasErr: true,
+	}, {
+		name:   "immediate_too_big",
+		ins:    "addi",
+		ops:    Operands{Imm: 2048},
+		hasErr: true,
+	}, {
+		name:   "shift_too_big",
+		ins:    "slli",
+		ops:    Operands{Imm: 64},
+		hasErr: true,
+	}, {
+		name:   "unexpected_immediate",
+		ins:    "add",
+		ops:    Operands{Imm: 1},
+		hasErr: true,
+	}}
+
+	e := NewEncoder(Variant64, allExtensions()...)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			value, err := e.Encode(tt.ins, tt.ops)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+
+			r.NoError(err)
+			r.Equal(tt.expected, value, "0x%08x", value)
+		})
+	}
+}
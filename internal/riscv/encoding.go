@@ -0,0 +1,333 @@
+package riscv
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"mltwist/internal/opcode"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// encodingTables contains encodings of RISC-V instructions in the text format
+// of the riscv-opcodes project (https://github.com/riscv/riscv-opcodes). Every
+// file is a single table named by the extension it describes. All instructions
+// but compressed ones (see opcodeC) are described by the tables.
+//
+//go:embed encodings
+var encodingTables embed.FS
+
+// encodingField describes an operand field of an instruction encoding as
+// written in riscv-opcodes tables.
+type encodingField struct {
+	// offset is index of the lowest bit of the field in an instruction.
+	offset uint8
+	// bits is number of bits of the field.
+	bits uint8
+	// apply sets properties of a scalar instruction type encoding the
+	// field. It's nil for fields of vector instructions only.
+	apply func(o *instructionType)
+
+	// vector indicates that the field can be an operand of vector
+	// instructions. The operand is of kind operand then.
+	vector  bool
+	operand operandKind
+}
+
+// mask returns mask of instruction bits of the field.
+func (f encodingField) mask() uint32 {
+	return (uint32(1)<<f.bits - 1) << f.offset
+}
+
+// inputRegs returns apply function of an encodingField of input register
+// number cnt.
+func inputRegs(cnt uint8) func(o *instructionType) {
+	return func(o *instructionType) {
+		if o.inputRegCnt < cnt {
+			o.inputRegCnt = cnt
+		}
+	}
+}
+
+// immediateField returns apply function of an encodingField of an immediate
+// value of type t.
+func immediateField(t immType) func(o *instructionType) {
+	return func(o *instructionType) { o.immediate = t }
+}
+
+// shamtField returns apply function of an encodingField of a shift amount of
+// bits bits.
+func shamtField(bits uint8) func(o *instructionType) {
+	return func(o *instructionType) { o.shamtBits = bits }
+}
+
+// vectorField returns encodingField of vector instruction operand of kind k.
+func vectorField(k operandKind) encodingField {
+	offset, bits := k.vectorField()
+	return encodingField{offset: offset, bits: bits, vector: true, operand: k}
+}
+
+// encodingFields lists all operand fields of riscv-opcodes tables this
+// package understands.
+var encodingFields = map[string]encodingField{
+	"rd": {
+		offset:  7,
+		bits:    5,
+		apply:   func(o *instructionType) { o.hasOutputReg = true },
+		vector:  true,
+		operand: operandRd,
+	},
+	"rs1": {offset: 15, bits: 5, apply: inputRegs(1), vector: true, operand: operandRs1},
+	"rs2": {offset: 20, bits: 5, apply: inputRegs(2), vector: true, operand: operandRs2},
+	"rs3": {offset: 27, bits: 5, apply: inputRegs(3)},
+	"rm":  {offset: 12, bits: 3, apply: func(o *instructionType) { o.roundingMode = true }},
+
+	"imm12":    {offset: 20, bits: 12, apply: immediateField(immTypeI)},
+	"imm12hi":  {offset: 25, bits: 7, apply: immediateField(immTypeS)},
+	"imm12lo":  {offset: 7, bits: 5, apply: immediateField(immTypeS)},
+	"bimm12hi": {offset: 25, bits: 7, apply: immediateField(immTypeB)},
+	"bimm12lo": {offset: 7, bits: 5, apply: immediateField(immTypeB)},
+	"imm20":    {offset: 12, bits: 20, apply: immediateField(immTypeU)},
+	"jimm20":   {offset: 12, bits: 20, apply: immediateField(immTypeJ)},
+
+	"shamtw": {offset: 20, bits: 5, apply: shamtField(5)},
+	"shamtd": {offset: 20, bits: 6, apply: shamtField(6)},
+
+	"csr": {offset: 20, bits: csrBits, apply: func(o *instructionType) { o.csr = true }},
+	"zimm": {
+		offset:  15,
+		bits:    5,
+		apply:   func(o *instructionType) { o.zimm = true },
+		vector:  true,
+		operand: operandZimm,
+	},
+
+	"pred": {offset: fencePredOffset, bits: fenceSetBits, apply: func(o *instructionType) { o.fence = true }},
+	"succ": {offset: fenceSuccOffset, bits: fenceSetBits, apply: func(o *instructionType) { o.fence = true }},
+	"aq":   {offset: aqBit, bits: 1, apply: func(o *instructionType) { o.aqrl = true }},
+	"rl":   {offset: rlBit, bits: 1, apply: func(o *instructionType) { o.aqrl = true }},
+
+	// Fields of vector instructions only. Memory address of vector loads
+	// and stores is written as (rs1) as it is in assembler code. Mask
+	// register v0 of merge instructions is implicit, so it has no bits.
+	"vd":     vectorField(operandVd),
+	"vs1":    vectorField(operandVs1),
+	"vs2":    vectorField(operandVs2),
+	"vs3":    vectorField(operandVs3),
+	"vm":     vectorField(operandVm),
+	"v0":     vectorField(operandV0),
+	"simm5":  vectorField(operandSimm5),
+	"zimm10": vectorField(operandVtype10),
+	"zimm11": vectorField(operandVtype),
+	"(rs1)":  vectorField(operandAddr),
+}
+
+// encoding is a single instruction encoding parsed from a riscv-opcodes table.
+type encoding struct {
+	// name is the instruction name.
+	name string
+	// opcode describes fixed bits of the instruction.
+	opcode opcode.Opcode
+	// fields lists operand fields of the instruction. All of them are keys
+	// of encodingFields.
+	fields []string
+}
+
+// parseFixedBits parses an argument of riscv-opcodes table line which sets
+// fixed bits of an instruction. The argument has either hi..lo=value or
+// bit=value format. The function returns mask of the bits and their value.
+func parseFixedBits(arg string) (uint32, uint32, error) {
+	bits, valStr, _ := strings.Cut(arg, "=")
+	hiStr, loStr, isRange := strings.Cut(bits, "..")
+	if !isRange {
+		loStr = hiStr
+	}
+
+	hi, err := strconv.ParseUint(hiStr, 10, 8)
+	if err != nil || hi >= 8*instructionLen {
+		return 0, 0, fmt.Errorf("invalid bit index: %q", hiStr)
+	}
+	lo, err := strconv.ParseUint(loStr, 10, 8)
+	if err != nil || lo > hi {
+		return 0, 0, fmt.Errorf("invalid bit index: %q", loStr)
+	}
+
+	val, err := strconv.ParseUint(valStr, 0, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid value of bits %s: %w", bits, err)
+	}
+
+	width := hi - lo + 1
+	if val>>width != 0 {
+		return 0, 0, fmt.Errorf("value doesn't fit bits %s: 0x%x", bits, val)
+	}
+
+	mask := uint32((uint64(1)<<width - 1) << lo)
+	return mask, uint32(val << lo), nil
+}
+
+// parseEncoding parses a single non-empty line of a riscv-opcodes table.
+//
+// The line contains the instruction name followed by operand fields and fixed
+// bits of the instruction in an arbitrary order. All bits of an instruction
+// must be described by exactly one of them.
+func parseEncoding(line string) (encoding, error) {
+	args := strings.Fields(line)
+	if strings.HasPrefix(args[0], "$") {
+		return encoding{}, fmt.Errorf("unsupported directive: %s", args[0])
+	}
+
+	enc := encoding{name: args[0]}
+
+	var used, mask, value uint32
+	for _, arg := range args[1:] {
+		var m uint32
+		if strings.Contains(arg, "=") {
+			fixedMask, fixedValue, err := parseFixedBits(arg)
+			if err != nil {
+				return encoding{}, err
+			}
+			m = fixedMask
+			mask, value = mask|fixedMask, value|fixedValue
+		} else if f, ok := encodingFields[arg]; ok {
+			m = f.mask()
+			enc.fields = append(enc.fields, arg)
+		} else {
+			return encoding{}, fmt.Errorf("unknown field: %q", arg)
+		}
+
+		if used&m != 0 {
+			return encoding{}, fmt.Errorf("%q overlaps other bits: 0x%x", arg, used&m)
+		}
+		used |= m
+	}
+
+	if used != ^uint32(0) {
+		return encoding{}, fmt.Errorf("bits not described: 0x%x", ^used)
+	}
+
+	// Opcodes must not end with bytes which are not matched at all.
+	bs, ms := instruction{value: value}.bytes(), instruction{value: mask}.bytes()
+	for len(ms) > 1 && ms[len(ms)-1] == 0 {
+		bs, ms = bs[:len(bs)-1], ms[:len(ms)-1]
+	}
+	enc.opcode = opcode.Opcode{Bytes: bs, Mask: ms}
+
+	return enc, nil
+}
+
+// parseEncodings parses all instruction encodings of a riscv-opcodes table.
+// Empty lines and comments starting with '#' are ignored.
+func parseEncodings(table string) ([]encoding, error) {
+	var encs []encoding
+
+	s := bufio.NewScanner(strings.NewReader(table))
+	for n := 1; s.Scan(); n++ {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		enc, err := parseEncoding(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		encs = append(encs, enc)
+	}
+
+	return encs, s.Err()
+}
+
+// semantics describes properties of an instruction which are not given by its
+// encoding. See instructionType for description of the fields it shares with
+// semantics.
+type semantics struct {
+	floatRegs    uint8
+	loadBytes    uint8
+	storeBytes   uint8
+	pcRelative   bool
+	offsetSyntax bool
+	instrType    model.Type
+	effects      func(i instruction) []expr.Effect
+
+	// vector indicates that the instruction is a vector instruction (V
+	// extension). All operand fields of such an instruction are its
+	// vectorOperands written in assembler code in the order of the fields
+	// in the table.
+	vector bool
+
+	// exact indicates that a floating point instruction never rounds its
+	// result. Rounding mode field of such an instruction is ignored.
+	exact bool
+}
+
+// tableInstructions creates instruction types of all instructions in
+// riscv-opcodes tables called tables. Semantics of every instruction is looked
+// up in sems by the instruction name.
+//
+// The tables are part of this package, so this function panics if any of the
+// tables is invalid or if there is either an instruction without semantics or
+// semantics without an instruction.
+func tableInstructions(sems map[string]semantics, tables ...string) []*instructionType {
+	var instrs []*instructionType
+	used := make(map[string]struct{}, len(sems))
+
+	for _, t := range tables {
+		content, err := encodingTables.ReadFile(path.Join("encodings", t))
+		if err != nil {
+			panic(fmt.Sprintf("bug: cannot read encoding table: %v", err))
+		}
+
+		encs, err := parseEncodings(string(content))
+		if err != nil {
+			panic(fmt.Sprintf("bug: invalid encoding table %s: %v", t, err))
+		}
+
+		for _, enc := range encs {
+			sem, ok := sems[enc.name]
+			if !ok {
+				panic(fmt.Sprintf("bug: missing semantics of %s", enc.name))
+			}
+			used[enc.name] = struct{}{}
+
+			o := &instructionType{
+				name:         enc.name,
+				opcode:       enc.opcode,
+				floatRegs:    sem.floatRegs,
+				loadBytes:    sem.loadBytes,
+				storeBytes:   sem.storeBytes,
+				pcRelative:   sem.pcRelative,
+				offsetSyntax: sem.offsetSyntax,
+				instrType:    sem.instrType,
+				effects:      sem.effects,
+			}
+			for _, f := range enc.fields {
+				field := encodingFields[f]
+				switch {
+				case sem.vector && field.vector:
+					o.vectorOperands = append(o.vectorOperands, field.operand)
+				case !sem.vector && field.apply != nil:
+					field.apply(o)
+				default:
+					panic(fmt.Sprintf("bug: invalid field %s of %s", f, enc.name))
+				}
+			}
+			if sem.exact {
+				o.roundingMode = false
+			}
+
+			instrs = append(instrs, o)
+		}
+	}
+
+	for name := range sems {
+		if _, ok := used[name]; !ok {
+			panic(fmt.Sprintf("bug: semantics of unknown instruction %s", name))
+		}
+	}
+
+	return instrs
+}
@@ -0,0 +1,207 @@
+package riscv
+
+import (
+	"mltwist/internal/opcode"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		opcode opcode.Opcode
+		fields []string
+		hasErr bool
+	}{{
+		name:   "major_opcode",
+		line:   "lui rd imm20 6..2=0x0D 1..0=3",
+		opcode: opcode7(0b0110111),
+		fields: []string{"rd", "imm20"},
+	}, {
+		name:   "funct3",
+		line:   "beq bimm12hi rs1 rs2 bimm12lo 14..12=0 6..2=0x18 1..0=3",
+		opcode: opcode10(0b000, 0b1100011),
+		fields: []string{"bimm12hi", "rs1", "rs2", "bimm12lo"},
+	}, {
+		name:   "funct7",
+		line:   "sub rd rs1 rs2 31..25=32 14..12=0 6..2=0x0C 1..0=3",
+		opcode: opcode17(0b0100000, 0b000, 0b0110011),
+		fields: []string{"rd", "rs1", "rs2"},
+	}, {
+		name:   "shift_amount",
+		line:   "srai rd rs1 31..26=16 shamtd 14..12=5 6..2=0x04 1..0=3",
+		opcode: opcodeShiftImm(true, 6, 0b101, 0b0010011),
+		fields: []string{"rd", "rs1", "shamtd"},
+	}, {
+		name: "single_bit",
+		line: "amoadd.w rd rs1 rs2 26=0 25=0 31..27=0 14..12=2 6..2=0x0B 1..0=3",
+		opcode: opcode.Opcode{
+			Bytes: []byte{0b0101111, 0b010 << 4, 0, 0},
+			Mask:  []byte{0x7f, 0b111 << 4, 0, 0xfe},
+		},
+		fields: []string{"rd", "rs1", "rs2"},
+	}, {
+		name: "vector_operands",
+		line: "vadd.vv vd vs2 vs1 vm 31..26=0x00 14..12=0 6..0=0x57",
+		opcode: opcode.Opcode{
+			Bytes: []byte{0b1010111, 0, 0, 0},
+			Mask:  []byte{0x7f, 0b111 << 4, 0, 0xfc},
+		},
+		fields: []string{"vd", "vs2", "vs1", "vm"},
+	}, {
+		name:   "unknown_field",
+		line:   "lui rd imm21 6..2=0x0D 1..0=3",
+		hasErr: true,
+	}, {
+		name:   "overlapping_fields",
+		line:   "lui rd imm20 rs1 6..2=0x0D 1..0=3",
+		hasErr: true,
+	}, {
+		name:   "overlapping_bits",
+		line:   "lui rd imm20 6..2=0x0D 2..0=3",
+		hasErr: true,
+	}, {
+		name:   "missing_bits",
+		line:   "lui rd imm20 6..2=0x0D",
+		hasErr: true,
+	}, {
+		name:   "value_too_big",
+		line:   "lui rd imm20 6..2=0x20 1..0=3",
+		hasErr: true,
+	}, {
+		name:   "invalid_range",
+		line:   "lui rd imm20 2..6=0x0D 1..0=3",
+		hasErr: true,
+	}, {
+		name:   "bit_out_of_range",
+		line:   "lui rd imm20 32..2=0x0D 1..0=3",
+		hasErr: true,
+	}, {
+		name:   "directive",
+		line:   "$pseudo_op rv_i::lui li rd imm20 6..2=0x0D 1..0=3",
+		hasErr: true,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			enc, err := parseEncoding(tt.line)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			r.Equal(tt.opcode, enc.opcode)
+			r.Equal(tt.fields, enc.fields)
+		})
+	}
+}
+
+func TestParseEncodings(t *testing.T) {
+	r := require.New(t)
+
+	encs, err := parseEncodings(`
+# Comment.
+lui   rd imm20 6..2=0x0D 1..0=3 # Trailing comment.
+
+auipc rd imm20 6..2=0x05 1..0=3
+`)
+	r.NoError(err)
+	r.Len(encs, 2)
+	r.Equal("lui", encs[0].name)
+	r.Equal("auipc", encs[1].name)
+
+	_, err = parseEncodings("lui rd imm20 6..2=0x0D 1..0=3\nauipc rd imm20")
+	r.ErrorContains(err, "line 2")
+}
+
+// tableSemantics returns semantics without any effects for all instructions
+// of riscv-opcodes tables called tables.
+func tableSemantics(t testing.TB, tables ...string) map[string]semantics {
+	sems := make(map[string]semantics)
+	for _, table := range tables {
+		content, err := encodingTables.ReadFile("encodings/" + table)
+		require.NoError(t, err)
+
+		encs, err := parseEncodings(string(content))
+		require.NoError(t, err)
+
+		for _, enc := range encs {
+			sems[enc.name] = semantics{effects: noEffects}
+		}
+	}
+	return sems
+}
+
+func TestTableInstructions(t *testing.T) {
+	r := require.New(t)
+
+	sems := tableSemantics(t, "rv_i", "rv32_i")
+	sems["sw"] = semantics{storeBytes: 4, effects: noEffects}
+	m := opcodeMap(t, tableInstructions(sems, "rv_i", "rv32_i"))
+
+	sw := m["sw"]
+	r.Equal(immTypeS, sw.immediate)
+	r.Equal(uint8(2), sw.inputRegCnt)
+	r.False(sw.hasOutputReg)
+	r.Equal(uint8(4), sw.storeBytes)
+
+	srai := m["srai"]
+	r.Equal(uint8(5), srai.shamtBits)
+	r.Equal(uint8(1), srai.inputRegCnt)
+	r.True(srai.hasOutputReg)
+
+	r.True(m["fence"].fence)
+	r.Equal(immTypeJ, m["jal"].immediate)
+
+	// Instruction without semantics.
+	delete(sems, "add")
+	r.Panics(func() { tableInstructions(sems, "rv_i", "rv32_i") })
+
+	// Semantics without instruction.
+	sems = tableSemantics(t, "rv_i", "rv32_i")
+	r.Panics(func() { tableInstructions(sems, "rv_i") })
+}
+
+func TestTableInstructions_Vector(t *testing.T) {
+	r := require.New(t)
+
+	sems := tableSemantics(t, "rv_v")
+	for name, sem := range sems {
+		sem.vector = true
+		sems[name] = sem
+	}
+	m := opcodeMap(t, tableInstructions(sems, "rv_v"))
+
+	r.Equal([]operandKind{operandVd, operandVs2, operandVs1, operandVm},
+		m["vadd.vv"].vectorOperands)
+	r.Equal([]operandKind{operandVd, operandVs2, operandRs1, operandV0},
+		m["vmerge.vxm"].vectorOperands)
+	r.Equal([]operandKind{operandVs3, operandAddr, operandRs2, operandVm},
+		m["vsse32.v"].vectorOperands)
+	r.Equal([]operandKind{operandRd, operandZimm, operandVtype10},
+		m["vsetivli"].vectorOperands)
+	r.Zero(m["vsetvl"].inputRegCnt)
+	r.False(m["vsetvl"].hasOutputReg)
+
+	// Vector fields of scalar instructions.
+	sems["vadd.vv"] = semantics{effects: noEffects}
+	r.Panics(func() { tableInstructions(sems, "rv_v") })
+}
+
+func TestTableInstructions_Exact(t *testing.T) {
+	r := require.New(t)
+
+	sems := tableSemantics(t, "rv_d")
+	sems["fcvt.d.s"] = semantics{floatRegs: floatRegSet(rd, rs1), exact: true, effects: noEffects}
+	m := opcodeMap(t, tableInstructions(sems, "rv_d"))
+
+	r.True(m["fcvt.s.d"].roundingMode)
+	r.False(m["fcvt.d.s"].roundingMode)
+	r.Equal(floatRegSet(rd, rs1), m["fcvt.d.s"].floatRegs)
+}
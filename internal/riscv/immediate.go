@@ -166,3 +166,77 @@ func (t immType) parseValue(value uint32) (int32, bool) {
 		panic(fmt.Sprintf("unknown immediate type: %v", t))
 	}
 }
+
+// fieldMask returns mask of all instruction opcode bits used to encode an
+// immediate value of type t. This method will panic for unknown immediate
+// type.
+func (t immType) fieldMask() uint32 {
+	switch t {
+	case immTypeR:
+		return 0
+	case immTypeI:
+		return 0xfff00000
+	case immTypeS, immTypeB:
+		return 0xfe000f80
+	case immTypeU, immTypeJ:
+		return 0xfffff000
+	default:
+		panic(fmt.Sprintf("unknown immediate type: %v", t))
+	}
+}
+
+// fitsSigned checks that imm can be represented as a signed integer of bits
+// width and that its lowest zeroBits bits are zero.
+func fitsSigned(imm int32, bits uint8, zeroBits uint8) bool {
+	min, max := -(int32(1) << (bits - 1)), int32(1)<<(bits-1)-1
+	zeroMask := int32(1)<<zeroBits - 1
+	return imm >= min && imm <= max && imm&zeroMask == 0
+}
+
+// encodeValue encodes an immediate value imm into instruction opcode bits. All
+// bits which are not part of the immediate value encoding (see fieldMask) are
+// zero in the returned value.
+//
+// This method is inverse operation to parseValue. It returns an error if imm
+// cannot be represented by immediate type t. This method will panic for unknown
+// immediate type.
+func (t immType) encodeValue(imm int32) (uint32, error) {
+	u := uint32(imm)
+	switch t {
+	case immTypeR:
+		if imm != 0 {
+			return 0, fmt.Errorf("R-type has no immediate value: %d", imm)
+		}
+		return 0, nil
+	case immTypeI:
+		if !fitsSigned(imm, 12, 0) {
+			return 0, fmt.Errorf("value doesn't fit I-type immediate: %d", imm)
+		}
+		return u << 20, nil
+	case immTypeS:
+		if !fitsSigned(imm, 12, 0) {
+			return 0, fmt.Errorf("value doesn't fit S-type immediate: %d", imm)
+		}
+		return parseBitRange(u, 0, 5)<<7 | parseBitRange(u, 5, 12)<<25, nil
+	case immTypeB:
+		if !fitsSigned(imm, 13, 1) {
+			return 0, fmt.Errorf("value doesn't fit B-type immediate: %d", imm)
+		}
+		return parseBitRange(u, 1, 5)<<8 | parseBitRange(u, 5, 11)<<25 |
+			parseBitRange(u, 11, 12)<<7 | parseBitRange(u, 12, 13)<<31, nil
+	case immTypeU:
+		if imm&0xfff != 0 {
+			return 0, fmt.Errorf(
+				"U-type immediate has to be multiple of 4096: %d", imm)
+		}
+		return u, nil
+	case immTypeJ:
+		if !fitsSigned(imm, 21, 1) {
+			return 0, fmt.Errorf("value doesn't fit J-type immediate: %d", imm)
+		}
+		return parseBitRange(u, 1, 11)<<21 | parseBitRange(u, 11, 12)<<20 |
+			parseBitRange(u, 12, 20)<<12 | parseBitRange(u, 20, 21)<<31, nil
+	default:
+		panic(fmt.Sprintf("unknown immediate type: %v", t))
+	}
+}
@@ -140,3 +140,65 @@ func TestImmediate_parseValue(t *testing.T) {
 		})
 	}
 }
+
+func TestImmediate_encodeValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		immType immType
+		values  []int32
+		invalid []int32
+	}{{
+		name:    "R-type",
+		immType: immTypeR,
+		values:  []int32{0},
+		invalid: []int32{1, -1},
+	}, {
+		name:    "I-type",
+		immType: immTypeI,
+		values:  []int32{0, 1, -1, 0x2af, -2048, 2047},
+		invalid: []int32{2048, -2049},
+	}, {
+		name:    "S-type",
+		immType: immTypeS,
+		values:  []int32{0, 1, -1, 0x2b9, -2048, 2047},
+		invalid: []int32{2048, -2049},
+	}, {
+		name:    "B-type",
+		immType: immTypeB,
+		values:  []int32{0, 2, -2, 0x553 << 1, -4096, 4094},
+		invalid: []int32{1, -3, 4096, -4098},
+	}, {
+		name:    "U-type",
+		immType: immTypeU,
+		values:  []int32{0, 1 << 12, -1 << 12, 0x33aac << 12, -1 << 31},
+		invalid: []int32{1, 0xfff, -1},
+	}, {
+		name:    "J-type",
+		immType: immTypeJ,
+		values:  []int32{0, 2, -2, 0x8642a, -1 << 20, 1<<20 - 2},
+		invalid: []int32{1, -1, 1 << 20, -1<<20 - 2},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			for _, v := range tt.values {
+				encoded, err := tt.immType.encodeValue(v)
+				r.NoError(err)
+				r.Zero(encoded &^ tt.immType.fieldMask())
+
+				// Non-immediate bits must not affect the value.
+				encoded |= ^tt.immType.fieldMask()
+				parsed, _ := tt.immType.parseValue(encoded)
+				r.Equal(v, parsed)
+			}
+
+			for _, v := range tt.invalid {
+				_, err := tt.immType.encodeValue(v)
+				r.Error(err, "value: %d", v)
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@ package riscv
 import (
 	"fmt"
 	"mltwist/pkg/model"
-	"strings"
 )
 
 // instruction represents a parser RISC-V instruction. Unlike the
@@ -21,15 +20,26 @@ type instruction struct {
 	// the smallest in-memory address is represented by bits [0..7] of the
 	// value.
 	//
-	// As all instructions in RISC-V (excluding compressed instructions
-	// which we don't support for the time being) have 4 bytes, we can use
-	// uint32 to represent them. Usage of uint32 over []byte has many
-	// reasons starting with convenience, followed by performance and ending
-	// in memory space consumption.
+	// As all instructions in RISC-V (excluding compressed instructions)
+	// have 4 bytes, we can use uint32 to represent them. Usage of uint32
+	// over []byte has many reasons starting with convenience, followed by
+	// performance and ending in memory space consumption.
+	//
+	// Compressed instructions are represented by value of their expanded
+	// 4 bytes long form.
 	value uint32
 
-	// instrType refers the type of the instruction.
+	// instrType refers the type of the instruction. For compressed
+	// instructions, this is the type of the expanded instruction.
 	instrType *instructionType
+
+	// compressed refers the type of compressed instruction if the
+	// instruction is encoded in 2 bytes (C extension). The field is nil
+	// for instructions which are not compressed.
+	compressed *instructionType
+	// cvalue is the value of 2 bytes of a compressed instruction in the
+	// memory. It's encoded in the same way as value.
+	cvalue uint16
 }
 
 // newInstruction crates a new instance of instruction. The new instruction is
@@ -40,61 +50,205 @@ func newInstruction(a model.Addr, b []byte, t *instructionType) instruction {
 		panic(fmt.Sprintf("not enough bytes to represent valid opcode: %d", l))
 	}
 
-	var value uint32
-	for i, v := range b[:instructionLen] {
-		value |= uint32(v) << (8 * i)
-	}
-
 	return instruction{
 		addr:      a,
-		value:     value,
+		value:     opcodeValue(b[:instructionLen]),
 		instrType: t,
 	}
 }
 
-// Name returns name of the instruction.
+// newCompressedInstruction creates a new instance of compressed instruction at
+// address a with compressed type c encoded in the first compressedLen bytes of
+// b. The instruction expands into value of type t.
+func newCompressedInstruction(
+	a model.Addr,
+	b []byte,
+	c *instructionType,
+	value uint32,
+	t *instructionType,
+) instruction {
+	if l := len(b); l < compressedLen {
+		panic(fmt.Sprintf("not enough bytes to represent valid opcode: %d", l))
+	}
+
+	return instruction{
+		addr:       a,
+		value:      value,
+		instrType:  t,
+		compressed: c,
+		cvalue:     uint16(opcodeValue(b[:compressedLen])),
+	}
+}
+
+// len returns length of the instruction in bytes.
+func (i instruction) len() model.Addr {
+	if i.compressed != nil {
+		return compressedLen
+	}
+	return instructionLen
+}
+
+var _ model.Relocator = instruction{}
+
+// Name returns name of the instruction. Name of an atomic instruction doesn't
+// include the acquire and release suffix.
 func (i instruction) Name() string { return i.instrType.name }
 
+// fullName returns name of the instruction in assembler code. Names of atomic
+// instructions include their acquire and release suffix.
+func (i instruction) fullName() string {
+	if !i.instrType.aqrl {
+		return i.instrType.name
+	}
+	ops := i.operands()
+	return i.instrType.name + orderingSuffix(ops.Aq, ops.Rl)
+}
+
 // String returns a string representation of an instruction which corresponds to
 // standard RISC-V assembler notation of instructions.
-func (i instruction) String() string {
-	// The value of 3 is Optimistic preallocation.
-	as := make([]string, 0, 3)
+//
+// Compressed instructions are represented by their expanded form in the same
+// way as disassemblers do by default.
+func (i instruction) String() string { return i.Format(model.Syntax{}, nil) }
+
+var _ model.Formatter = instruction{}
+
+// Format returns a string representation of an instruction in assembler syntax
+// s. See pseudo for the list of pseudo-instructions used if aliases are
+// enabled.
+func (i instruction) Format(s model.Syntax, prev model.PlatformDetails) string {
+	if s.Aliases {
+		p, _ := prev.(instruction)
+		if str, ok := i.pseudo(p, s.ABINames); ok {
+			return str
+		}
+	}
+
+	ops := i.operands()
+	kinds := i.instrType.syntax()
+
+	as := make([]string, 0, len(kinds))
+	for _, k := range kinds {
+		if !k.omitted(ops) {
+			as = append(as, k.format(ops, s.ABINames))
+		}
+	}
 
-	if i.instrType.hasOutputReg {
-		as = append(as, rd.regNum(i.value).String())
+	return pseudoString(i.fullName(), as...)
+}
+
+// operands returns operands of the instruction.
+func (i instruction) operands() Operands {
+	t := i.instrType
+
+	var ops Operands
+	for _, k := range t.vectorOperands {
+		offset, bits := k.vectorField()
+		k.setVectorValue(parseBitRange(i.value, offset, offset+bits), &ops)
+	}
+
+	if t.hasOutputReg {
+		ops.Rd = uint8(rd.regNum(i.value))
+	}
+	if t.inputRegCnt > 0 || t.zimm {
+		ops.Rs1 = uint8(rs1.regNum(i.value))
+	}
+	if t.inputRegCnt > 1 {
+		ops.Rs2 = uint8(rs2.regNum(i.value))
 	}
-	if i.instrType.inputRegCnt > 0 {
-		as = append(as, rs1.regNum(i.value).String())
+	if t.inputRegCnt > 2 {
+		ops.Rs3 = uint8(rs3.regNum(i.value))
 	}
-	if i.instrType.inputRegCnt > 1 {
-		as = append(as, rs2.regNum(i.value).String())
+	if t.roundingMode {
+		ops.Rm = rmField(i.value)
+	}
+	if t.fence {
+		const predEnd = fencePredOffset + fenceSetBits
+		const succEnd = fenceSuccOffset + fenceSetBits
+		ops.Pred = uint8(parseBitRange(i.value, fencePredOffset, predEnd))
+		ops.Succ = uint8(parseBitRange(i.value, fenceSuccOffset, succEnd))
+	}
+	if t.aqrl {
+		ops.Aq = parseBitRange(i.value, aqBit, aqBit+1) != 0
+		ops.Rl = parseBitRange(i.value, rlBit, rlBit+1) != 0
+	}
+
+	if t.shamtBits > 0 {
+		ops.Imm = int32(parseBitRange(i.value, 20, 20+t.shamtBits))
+	} else if t.csr {
+		ops.Imm = int32(csrNum(i))
+	} else if imm, ok := t.immediate.parseValue(i.value); ok {
+		ops.Imm = imm
 	}
 
-	if imm, ok := i.instrType.immediate.parseValue(i.value); ok {
-		immStr := fmt.Sprintf("%d", imm)
+	return ops
+}
 
-		// Store instruction is written in order: `s[bhwd] <reg>
-		// <mem_addr>`, even though memory base register is r1 and the
-		// register written to memory is r2. Why not to make the
-		// assembler irregular such that store is the only instruction
-		// where direction of data flow is from left operand to the
-		// right one. Long live irregularities! Intel celebrates, the
-		// rest of the world cries...
-		if i.instrType.storeBytes > 0 {
-			l := len(as)
-			as[l-1], as[l-2] = as[l-2], as[l-1]
-		}
+// bytes returns the instruction encoded as a sequence of bytes in the memory.
+func (i instruction) bytes() []byte {
+	value := i.value
+	if i.compressed != nil {
+		value = uint32(i.cvalue)
+	}
+
+	bs := make([]byte, i.len())
+	for j := range bs {
+		bs[j] = byte(value >> (8 * j))
+	}
+	return bs
+}
+
+// Relocate returns the instruction and its opcode bytes as if the instruction
+// was placed at address a.
+//
+// Immediate values of PC-relative instructions are re-encoded so that the
+// instruction refers the same address as it referred before the move. An
+// error is returned if the new offset cannot be represented by the immediate
+// value of the instruction. Other instructions are returned unchanged.
+//
+// Branches and jal always end a basic block, so they are re-encoded only when
+// the whole code is placed at a different address. Auipc encodes just upper 20
+// bits of an offset whose lower 12 bits are encoded by a following instruction
+// (addi, jalr, a load or a store). Relocate sees a single instruction, so auipc
+// can be moved only by multiples of 4096 bytes, which in practice means that it
+// cannot move within a basic block at all.
+func (i instruction) Relocate(
+	a model.Addr,
+) (model.PlatformDetails, []byte, error) {
+	if !i.instrType.pcRelative {
+		i.addr = a
+		return i, i.bytes(), nil
+	}
+
+	t := i.instrType.immediate
+	imm, _ := t.parseValue(i.value)
+
+	move := model.Offset(i.addr, a)
+	if t == immTypeU && move%(1<<12) != 0 {
+		return nil, nil, fmt.Errorf(
+			"%q can move only by multiples of 4096 bytes: %d", i, move)
+	}
+
+	offset := int64(imm) - move
+	if int64(int32(offset)) != offset {
+		return nil, nil, fmt.Errorf("offset of %q is too big: %d", i, offset)
+	}
+
+	encoded, err := t.encodeValue(int32(offset))
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot encode offset of %q: %w", i, err)
+	}
 
-		// For some weird reason load and store instructions use
-		// different syntax then all other instructions - memory offset
-		// syntax.
-		if i.instrType.loadBytes > 0 || i.instrType.storeBytes > 0 {
-			as[len(as)-1] = fmt.Sprintf("%s(%s)", immStr, as[len(as)-1])
-		} else {
-			as = append(as, immStr)
+	if c := i.compressed; c != nil {
+		cEncoded, err := c.cImmediate.encodeValue(int32(offset))
+		if err != nil {
+			return nil, nil, fmt.Errorf(
+				"cannot encode offset of %q: %w", i, err)
 		}
+		i.cvalue = i.cvalue&^c.cImmediate.fieldMask() | cEncoded
 	}
 
-	return fmt.Sprintf("%s %s", i.instrType.name, strings.Join(as, ", "))
+	i.addr = a
+	i.value = i.value&^t.fieldMask() | encoded
+	return i, i.bytes(), nil
 }
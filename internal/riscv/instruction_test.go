@@ -0,0 +1,227 @@
+package riscv
+
+import (
+	"mltwist/internal/deps"
+	"mltwist/internal/parser"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstruction_Relocate(t *testing.T) {
+	tests := []struct {
+		name   string
+		bytes  []byte
+		addr   model.Addr
+		to     model.Addr
+		imm    int32
+		hasErr bool
+	}{{
+		// jal x1, 16
+		name:  "jal_forward",
+		bytes: []byte{0xef, 0x00, 0x00, 0x01},
+		addr:  0x1000,
+		to:    0x1008,
+		imm:   8,
+	}, {
+		// jal x1, 16
+		name:  "jal_backward_target",
+		bytes: []byte{0xef, 0x00, 0x00, 0x01},
+		addr:  0x1000,
+		to:    0x1020,
+		imm:   -16,
+	}, {
+		// beq x1, x2, 4094
+		name:   "beq_too_far",
+		bytes:  []byte{0xe3, 0x8f, 0x20, 0x7e},
+		addr:   0x1000,
+		to:     0x0ffc,
+		hasErr: true,
+	}, {
+		// beq x1, x2, -8
+		name:  "beq",
+		bytes: []byte{0xe3, 0x8c, 0x20, 0xfe},
+		addr:  0x1000,
+		to:    0x0ff0,
+		imm:   8,
+	}, {
+		// auipc x5, 2
+		name:  "auipc_page",
+		bytes: []byte{0x97, 0x22, 0x00, 0x00},
+		addr:  0x1000,
+		to:    0x2000,
+		imm:   0x1000,
+	}, {
+		// auipc x5, 2
+		name:   "auipc_unaligned",
+		bytes:  []byte{0x97, 0x22, 0x00, 0x00},
+		addr:   0x1000,
+		to:     0x1004,
+		hasErr: true,
+	}, {
+		// addi x1, x2, 5
+		name:  "not_relative",
+		bytes: []byte{0x93, 0x00, 0x51, 0x00},
+		addr:  0x1000,
+		to:    0x1010,
+		imm:   5,
+	}}
+
+	p := NewParser(Variant64)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			ins, err := p.Parse(tt.addr, tt.bytes)
+			r.NoError(err)
+
+			details, bytes, err := ins.Details.(model.Relocator).Relocate(tt.to)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			moved, err := p.Parse(tt.to, bytes)
+			r.NoError(err)
+			r.Equal(moved.Details, details)
+
+			i := details.(instruction)
+			r.Equal(tt.to, i.addr)
+			r.Equal(ins.Details.Name(), i.Name())
+
+			imm, _ := i.instrType.immediate.parseValue(i.value)
+			r.Equal(tt.imm, imm)
+		})
+	}
+}
+
+func TestInstruction_FormatNoAliases(t *testing.T) {
+	// Expected strings are output of objdump -M no-aliases -M numeric
+	// (llvm-objdump 14) for the bytes with the tab after the instruction
+	// name replaced by a space.
+	tests := []struct {
+		bytes []byte
+		want  string
+	}{
+		{bytes: []byte{0xb7, 0x87, 0x98, 0x01}, want: "lui x15, 6536"},
+		{bytes: []byte{0x37, 0xf5, 0xff, 0xff}, want: "lui x10, 1048575"},
+		{bytes: []byte{0x97, 0xf7, 0xff, 0xff}, want: "auipc x15, 1048575"},
+		{bytes: []byte{0x97, 0x00, 0x00, 0x00}, want: "auipc x1, 0"},
+		{bytes: []byte{0xef, 0xf0, 0x1f, 0x80}, want: "jal x1, -2048"},
+		{bytes: []byte{0xe7, 0x02, 0xe2, 0xc9}, want: "jalr x5, -866(x4)"},
+		{bytes: []byte{0x67, 0x80, 0x00, 0x00}, want: "jalr x0, 0(x1)"},
+		{bytes: []byte{0xe3, 0x0c, 0xb5, 0xfe}, want: "beq x10, x11, -8"},
+		{bytes: []byte{0x03, 0x05, 0xf1, 0xff}, want: "lb x10, -1(x2)"},
+		{bytes: []byte{0x83, 0xe5, 0xf1, 0x7f}, want: "lwu x11, 2047(x3)"},
+		{bytes: []byte{0x03, 0x36, 0x81, 0x00}, want: "ld x12, 8(x2)"},
+		{bytes: []byte{0x23, 0x00, 0xa1, 0x80}, want: "sb x10, -2048(x2)"},
+		{bytes: []byte{0x23, 0x38, 0xd1, 0x00}, want: "sd x13, 16(x2)"},
+		{bytes: []byte{0x13, 0x85, 0xb5, 0xff}, want: "addi x10, x11, -5"},
+		{bytes: []byte{0x13, 0x95, 0xf5, 0x03}, want: "slli x10, x11, 63"},
+		{bytes: []byte{0x1b, 0xd5, 0xf5, 0x41}, want: "sraiw x10, x11, 31"},
+		{bytes: []byte{0xb3, 0x02, 0x73, 0x40}, want: "sub x5, x6, x7"},
+		{bytes: []byte{0x0f, 0x00, 0x50, 0x0f}, want: "fence iorw, ow"},
+		{bytes: []byte{0x0f, 0x10, 0x00, 0x00}, want: "fence.i"},
+		{bytes: []byte{0x73, 0x00, 0x00, 0x00}, want: "ecall"},
+		{bytes: []byte{0x73, 0x12, 0xcc, 0x58}, want: "csrrw x4, 0x58c, x24"},
+		{bytes: []byte{0x73, 0x25, 0x00, 0xc0}, want: "csrrs x10, cycle, x0"},
+		{bytes: []byte{0xf3, 0x30, 0x01, 0x30}, want: "csrrc x1, mstatus, x2"},
+		{bytes: []byte{0x73, 0xd0, 0x21, 0x00}, want: "csrrwi x0, frm, 3"},
+		{bytes: []byte{0xf3, 0xee, 0xd3, 0x3d}, want: "csrrsi x29, pmpaddr45, 7"},
+		{bytes: []byte{0xf3, 0xf2, 0x2f, 0x3a}, want: "csrrci x5, pmpcfg2, 31"},
+		{bytes: []byte{0x33, 0x85, 0xc5, 0x02}, want: "mul x10, x11, x12"},
+		{bytes: []byte{0x3b, 0xd5, 0xc5, 0x02}, want: "divuw x10, x11, x12"},
+		{bytes: []byte{0x2f, 0x2d, 0x0e, 0x10}, want: "lr.w x26, (x28)"},
+		{bytes: []byte{0x2f, 0xb5, 0x05, 0x14}, want: "lr.d.aq x10, (x11)"},
+		{bytes: []byte{0xaf, 0x22, 0x73, 0x1a}, want: "sc.w.rl x5, x7, (x6)"},
+		{bytes: []byte{0xaf, 0x24, 0x34, 0x09}, want: "amoswap.w x9, x19, (x8)"},
+		{bytes: []byte{0x2f, 0xb5, 0xc5, 0x06}, want: "amoadd.d.aqrl x10, x12, (x11)"},
+		{bytes: []byte{0xaf, 0xa0, 0x21, 0xe4}, want: "amomaxu.w.aq x1, x2, (x3)"},
+		{bytes: []byte{0x07, 0x25, 0xc1, 0xff}, want: "flw f10, -4(x2)"},
+		{bytes: []byte{0x27, 0x3c, 0x81, 0x00}, want: "fsd f8, 24(x2)"},
+		{bytes: []byte{0x53, 0x9b, 0xb5, 0x01}, want: "fadd.s f22, f11, f27, rtz"},
+		{bytes: []byte{0x43, 0x0e, 0x39, 0x3b}, want: "fmadd.d f28, f18, f19, f7, rne"},
+		{bytes: []byte{0x53, 0xc5, 0x05, 0xc0}, want: "fcvt.w.s x10, f11, rmm"},
+		{bytes: []byte{0xd3, 0x00, 0x01, 0x42}, want: "fcvt.d.s f1, f2"},
+		{bytes: []byte{0x53, 0x85, 0x05, 0xe2}, want: "fmv.x.d x10, f11"},
+		{bytes: []byte{0x53, 0xa5, 0xc5, 0xa2}, want: "feq.d x10, f11, f12"},
+		{bytes: []byte{0x53, 0x95, 0x05, 0xe0}, want: "fclass.s x10, f11"},
+		{bytes: []byte{0x33, 0xa5, 0xc5, 0x20}, want: "sh1add x10, x11, x12"},
+		{bytes: []byte{0x3b, 0x85, 0xc5, 0x08}, want: "add.uw x10, x11, x12"},
+		{bytes: []byte{0x13, 0xd5, 0x35, 0x60}, want: "rori x10, x11, 3"},
+		{bytes: []byte{0x33, 0xf5, 0xc5, 0x40}, want: "andn x10, x11, x12"},
+		{bytes: []byte{0x13, 0x95, 0x85, 0x2a}, want: "bseti x10, x11, 40"},
+		{bytes: []byte{0x13, 0x95, 0x45, 0x60}, want: "sext.b x10, x11"},
+		{bytes: []byte{0x57, 0xf5, 0x05, 0x0d}, want: "vsetvli x10, x11, e32, m1, ta, ma"},
+		{bytes: []byte{0x57, 0x75, 0x74, 0xc0}, want: "vsetivli x10, 8, e8, mf2, tu, mu"},
+		{bytes: []byte{0x87, 0x60, 0x05, 0x02}, want: "vle32.v v1, (x10)"},
+		{bytes: []byte{0x27, 0x81, 0x05, 0x00}, want: "vse8.v v2, (x11), v0.t"},
+		{bytes: []byte{0x87, 0x61, 0xb5, 0x0a}, want: "vlse32.v v3, (x10), x11"},
+		{bytes: []byte{0xd7, 0x80, 0x21, 0x02}, want: "vadd.vv v1, v2, v3"},
+		{bytes: []byte{0xd7, 0x40, 0x25, 0x00}, want: "vadd.vx v1, v2, x10, v0.t"},
+		{bytes: []byte{0xd7, 0xb0, 0x2e, 0x02}, want: "vadd.vi v1, v2, -3"},
+		{bytes: []byte{0xd7, 0x80, 0x21, 0x5c}, want: "vmerge.vvm v1, v2, v3, v0"},
+		{bytes: []byte{0x73, 0x00, 0x20, 0x30}, want: "mret"},
+		{bytes: []byte{0x73, 0x00, 0x20, 0x10}, want: "sret"},
+		{bytes: []byte{0x73, 0x00, 0x50, 0x10}, want: "wfi"},
+		{bytes: []byte{0x73, 0x00, 0xb5, 0x12}, want: "sfence.vma x10, x11"},
+	}
+
+	p := NewParser(Variant64, allExtensions()...)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.want, func(t *testing.T) {
+			r := require.New(t)
+
+			ins, err := p.Parse(0x1000, tt.bytes)
+			r.NoError(err)
+
+			f := ins.Details.(model.Formatter)
+			r.Equal(tt.want, f.Format(model.Syntax{}, nil))
+		})
+	}
+}
+
+type testBlock struct {
+	begin model.Addr
+	bytes []byte
+}
+
+func (b testBlock) Begin() model.Addr { return b.begin }
+func (b testBlock) Bytes() []byte     { return b.bytes }
+
+// TestCode_Move_AuipcPair checks that auipc stays pinned to its address. The
+// lower 12 bits of its offset are encoded by the following instruction, so a
+// move has to be refused instead of silently changing the address computed.
+func TestCode_Move_AuipcPair(t *testing.T) {
+	r := require.New(t)
+
+	e := NewEncoder(Variant64)
+	var code []byte
+	for _, s := range []string{
+		"addi a1, a1, 1",
+		"auipc a0, 0x1",
+		"addi a0, a0, 16",
+		"jalr zero, 0(ra)",
+	} {
+		bs, err := e.Assemble(0, s)
+		r.NoError(err, s)
+		code = append(code, bs...)
+	}
+
+	blocks := []parser.Block{testBlock{begin: 0x1000, bytes: code}}
+	instrs, err := parser.Parse(blocks, NewParser(Variant64))
+	r.NoError(err)
+
+	c, err := deps.NewCode(0x1000, instrs, nil)
+	r.NoError(err)
+	r.Equal(1, c.Len())
+
+	b := c.Index(0)
+	r.ErrorContains(b.Move(1, 0), "multiples of 4096")
+	r.Equal(code[4:8], b.Index(1).Bytes())
+	r.Equal(model.Addr(0x1004), b.Index(1).Begin())
+}
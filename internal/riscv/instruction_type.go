@@ -10,6 +10,9 @@ import (
 // instructionLen is length of RISC V opcode in bytes.
 const instructionLen = 4
 
+// compressedLen is length of RISC V compressed (C extension) opcode in bytes.
+const compressedLen = 2
+
 // instructionType describes a single RISC-V instruction opcode.
 type instructionType struct {
 	// name is a symbolic name of an instruction in assembler code.
@@ -20,11 +23,18 @@ type instructionType struct {
 	opcode opcode.Opcode
 
 	// inputRegCnt is number of input registers of an instruction. Valid
-	// values are 0, 1 and 2.
+	// values are 0, 1, 2 and 3.
 	inputRegCnt uint8
 	// hasOutputReg indicates whether an instruction writes output to
 	// register.
 	hasOutputReg bool
+	// floatRegs is a set of register positions which refer floating point
+	// registers (F and D extensions) instead of integer registers. See
+	// floatRegSet for details.
+	floatRegs uint8
+	// roundingMode indicates that bits [12:14] of an instruction encode
+	// floating point rounding mode.
+	roundingMode bool
 
 	// loadBytes is number of bytes the instruction loads from memory.
 	loadBytes uint8
@@ -34,8 +44,52 @@ type instructionType struct {
 	// immediate describes an immediate value encoding format in an
 	// instruction.
 	immediate immType
+	// shamtBits is number of bits of a shift amount immediate encoded in
+	// bits [20:20+shamtBits] of an instruction opcode. Zero value indicates
+	// that the instruction doesn't encode a shift amount.
+	//
+	// Shift amount is not an immediate type on its own as it's encoded
+	// in instruction opcode bits which are otherwise reserved for
+	// immediate type I. See opcodeShiftImm for details.
+	shamtBits uint8
+	// zimm indicates that rs1 field of an instruction encodes a 5 bit
+	// unsigned immediate value instead of an input register number.
+	zimm bool
+	// csr indicates that bits [20:31] of an instruction encode number of a
+	// control and status register (CSR) the instruction accesses. The CSR
+	// number is unsigned, so it's not an immediate value of type I even
+	// though it's encoded in the same bits.
+	csr bool
+	// fence indicates that bits [24:27] and [20:23] of an instruction
+	// encode predecessor and successor sets of a fence instruction. See
+	// fenceSetNames for details.
+	fence bool
+	// aqrl indicates that bits 26 and 25 of an atomic instruction encode
+	// acquire and release bits of the instruction. The bits are not
+	// operands in assembler code, they are written as a suffix of the
+	// instruction name instead.
+	aqrl bool
+	// offsetSyntax indicates that rs1 and the immediate value of an
+	// instruction which doesn't access memory are written in assembler
+	// code as a memory operand imm(rs1). It's used by jalr whose target
+	// address is computed in the same way as addresses of loads.
+	offsetSyntax bool
+
+	// vectorOperands lists operands of a vector instruction (V extension)
+	// in the order they are written in assembler code. Vector instructions
+	// don't follow the operand layout of scalar instructions, so all the
+	// other fields describing operands have to be unset and encoding of
+	// every operand is given by its kind instead.
+	vectorOperands []operandKind
 
-	// instrType is set of instruction types of an opcode.
+	// pcRelative indicates that the immediate value of an instruction is
+	// an offset relative to the instruction address. Bytes of such an
+	// instruction have to be re-encoded when the instruction is moved.
+	pcRelative bool
+
+	// instrType is set of instruction types of an opcode. Memory order
+	// of fence and atomic instructions is given by their operands, so
+	// it's not part of instrType.
 	instrType model.Type
 
 	// effects is a function which based on specific instruction i evaluates
@@ -46,8 +100,35 @@ type instructionType struct {
 	// nil effects is simplification of handling of writes to x0 register
 	// which are effectively defined as having no side effect.
 	effects func(i instruction) []expr.Effect
+
+	// expand is set only for compressed instructions. It converts value of
+	// a compressed instruction c into name and operands of an equivalent
+	// instruction which is not compressed. It returns false if c is a
+	// reserved encoding.
+	//
+	// Compressed instructions have no other properties but opcode, name
+	// and cImmediate as all their properties are given by the expanded
+	// instruction.
+	expand func(c uint16) (string, Operands, bool)
+	// cImmediate describes encoding of PC-relative offset in a compressed
+	// instruction.
+	cImmediate cImmType
+}
+
+// floatRegSet creates a set of register positions which refer floating point
+// registers. Bit r of the set is set for every register position r.
+func floatRegSet(regs ...reg) uint8 {
+	var set uint8
+	for _, r := range regs {
+		set |= 1 << r
+	}
+	return set
 }
 
+// isFloat indicates that register position r of o refers a floating point
+// register.
+func (o instructionType) isFloat(r reg) bool { return o.floatRegs&(1<<r) != 0 }
+
 // Opcode returns the opcode definition nof a given instruction type.
 func (o instructionType) Opcode() opcode.Opcode { return o.opcode }
 func (o instructionType) Name() string          { return o.name }
@@ -66,32 +147,101 @@ func (o instructionType) validate(xlenBytes uint8) error {
 		return fmt.Errorf("invalid opcode description: %w", err)
 	}
 
-	if o.inputRegCnt > 2 {
+	if o.expand != nil {
+		return o.validateCompressed()
+	}
+	if o.cImmediate != cImmNone {
+		return fmt.Errorf("compressed immediate of uncompressed instruction")
+	}
+
+	if o.vectorOperands != nil {
+		if err := o.validateVector(); err != nil {
+			return err
+		}
+	}
+
+	if o.inputRegCnt > 3 {
 		return fmt.Errorf("too many input registers: %d", o.inputRegCnt)
 	}
 
-	if l := o.loadBytes; l > xlenBytes {
-		return fmt.Errorf("load is too wide: %d > XLEN(%d)", l, xlenBytes)
+	var used uint8
+	if o.hasOutputReg {
+		used |= floatRegSet(rd)
+	}
+	for r := rs1; r < rs1+reg(o.inputRegCnt); r++ {
+		used |= floatRegSet(r)
+	}
+	if o.floatRegs&^used != 0 {
+		return fmt.Errorf("unused floating point register: 0x%x", o.floatRegs)
+	}
+
+	// Floating point registers are not limited by XLEN, but by FLEN. The
+	// widest floating point register this package supports has 8 bytes.
+	loadLimit, storeLimit := xlenBytes, xlenBytes
+	if o.isFloat(rd) {
+		loadLimit = maxFloatBytes
+	}
+	if o.isFloat(rs2) {
+		storeLimit = maxFloatBytes
+	}
+
+	if l := o.loadBytes; l > loadLimit {
+		return fmt.Errorf("load is too wide: %d > %d", l, loadLimit)
 	} else if !isPow2(l) {
 		return fmt.Errorf("load width is not power of 2: %d", l)
 	}
 
-	if s := o.storeBytes; s > xlenBytes {
-		return fmt.Errorf("store is too wide: %d > XLEN(%d)", s, xlenBytes)
+	if s := o.storeBytes; s > storeLimit {
+		return fmt.Errorf("store is too wide: %d > %d", s, storeLimit)
 	} else if !isPow2(s) {
 		return fmt.Errorf("store width is not power of 2: %d", s)
 	}
 
-	if o.loadBytes > 0 && o.storeBytes > 0 && !o.instrType.MemOrder() {
+	if o.loadBytes > 0 && o.storeBytes > 0 && !o.aqrl {
 		return fmt.Errorf("non-atomic instruction can be either load or store")
 	}
 
-	if cnt := o.inputRegCnt; !o.instrType.MemOrder() && o.loadBytes > 0 && cnt != 1 {
+	if cnt := o.inputRegCnt; !o.aqrl && o.loadBytes > 0 && cnt != 1 {
 		return fmt.Errorf("non-atomic load must have 1 input register: %d", cnt)
-	} else if !o.instrType.MemOrder() && o.storeBytes > 0 && cnt != 2 {
+	} else if !o.aqrl && o.storeBytes > 0 && cnt != 2 {
 		return fmt.Errorf("non-atomic store must have 2 input registers: %d", cnt)
 	}
 
+	if o.shamtBits != 0 && o.immediate != immTypeR {
+		return fmt.Errorf("shift amount cannot be combined with immediate")
+	}
+	if o.zimm && o.inputRegCnt > 0 {
+		return fmt.Errorf("zimm cannot be combined with input registers")
+	}
+	if o.csr && (o.immediate != immTypeR || o.shamtBits != 0) {
+		return fmt.Errorf("CSR number cannot be combined with immediate")
+	}
+	if o.csr && o.instrType != model.TypeNone {
+		return fmt.Errorf("type of CSR instruction is given by the CSR")
+	}
+	if o.fence && (o.inputRegCnt > 0 || o.hasOutputReg || o.immediate != immTypeR) {
+		return fmt.Errorf("fence sets cannot be combined with other operands")
+	}
+	if o.offsetSyntax && (o.immediate != immTypeI || o.inputRegCnt != 1 ||
+		o.loadBytes > 0 || o.storeBytes > 0) {
+		return fmt.Errorf("offset syntax requires rs1 and immediate of type I")
+	}
+	if o.aqrl && o.loadBytes == 0 && o.storeBytes == 0 {
+		return fmt.Errorf("acquire and release bits of non-memory instruction")
+	}
+	if (o.fence || o.aqrl) && o.instrType.MemOrder() {
+		return fmt.Errorf("memory order is given by operands")
+	}
+
+	opcodeMask := opcodeValue(o.opcode.Mask)
+	if m := opcodeMask & o.operandMask(); m != 0 {
+		return fmt.Errorf("opcode bits overlap with operands: 0x%x", m)
+	}
+
+	if o.pcRelative && o.immediate == immTypeR {
+		return fmt.Errorf("PC-relative instruction has to have an immediate")
+	}
+
 	if o.effects == nil {
 		return fmt.Errorf("effects function must be always set")
 	}
@@ -99,6 +249,116 @@ func (o instructionType) validate(xlenBytes uint8) error {
 	return nil
 }
 
+// validateCompressed checks that a compressed instructionType is valid.
+func (o instructionType) validateCompressed() error {
+	if l := len(o.opcode.Mask); l != compressedLen {
+		return fmt.Errorf("invalid compressed opcode length: %d", l)
+	}
+	if o.opcode.Mask[0]&quadrantMask != quadrantMask {
+		return fmt.Errorf("compressed opcode has to match quadrant bits")
+	}
+	if o.opcode.Bytes[0]&quadrantMask == quadrantMask {
+		return fmt.Errorf("compressed opcode cannot be in quadrant 3")
+	}
+
+	if o.inputRegCnt != 0 || o.hasOutputReg || o.loadBytes != 0 ||
+		o.storeBytes != 0 || o.immediate != immTypeR ||
+		o.shamtBits != 0 || o.zimm || o.csr || o.fence || o.aqrl ||
+		o.pcRelative || o.offsetSyntax ||
+		o.floatRegs != 0 || o.roundingMode || o.vectorOperands != nil ||
+		o.instrType != model.TypeNone || o.effects != nil {
+		return fmt.Errorf("compressed instruction properties must be unset")
+	}
+
+	return nil
+}
+
+// validateVector checks that operands of a vector instructionType are
+// described only by vectorOperands.
+func (o instructionType) validateVector() error {
+	if o.inputRegCnt != 0 || o.hasOutputReg || o.floatRegs != 0 ||
+		o.roundingMode || o.loadBytes != 0 || o.storeBytes != 0 ||
+		o.immediate != immTypeR || o.shamtBits != 0 || o.zimm ||
+		o.csr || o.fence || o.aqrl || o.pcRelative || o.offsetSyntax {
+		return fmt.Errorf("vector instruction operands must be vectorOperands only")
+	}
+
+	var mask uint32
+	for _, k := range o.vectorOperands {
+		offset, bits := k.vectorField()
+		field := (uint32(1)<<bits - 1) << offset
+		if mask&field != 0 {
+			return fmt.Errorf("vector operands overlap: 0x%x", mask&field)
+		}
+		mask |= field
+	}
+
+	return nil
+}
+
+// opcodeValue converts opcode bytes (or mask) into an instruction value.
+func opcodeValue(bs []byte) uint32 {
+	var value uint32
+	for i, b := range bs {
+		value |= uint32(b) << (8 * i)
+	}
+	return value
+}
+
+// operandMask returns mask of all instruction bits which encode instruction
+// operands - registers and immediate values.
+func (o instructionType) operandMask() uint32 {
+	const regMask = regCnt - 1
+
+	var mask uint32
+	for _, k := range o.vectorOperands {
+		offset, bits := k.vectorField()
+		mask |= (uint32(1)<<bits - 1) << offset
+	}
+
+	if o.hasOutputReg {
+		mask |= regMask << rd.bitOffset()
+	}
+	if o.inputRegCnt > 0 || o.zimm {
+		mask |= regMask << rs1.bitOffset()
+	}
+	if o.inputRegCnt > 1 {
+		mask |= regMask << rs2.bitOffset()
+	}
+	if o.inputRegCnt > 2 {
+		mask |= regMask << rs3.bitOffset()
+	}
+	if o.roundingMode {
+		mask |= rmMask << rmBitOffset
+	}
+	if o.shamtBits > 0 {
+		mask |= (uint32(1)<<o.shamtBits - 1) << 20
+	}
+	if o.csr {
+		mask |= (uint32(1)<<csrBits - 1) << 20
+	}
+	if o.fence {
+		const setMask = 1<<fenceSetBits - 1
+		mask |= setMask<<fencePredOffset | setMask<<fenceSuccOffset
+	}
+	if o.aqrl {
+		mask |= 1<<aqBit | 1<<rlBit
+	}
+
+	return mask | o.immediate.fieldMask()
+}
+
+// typeOf returns instruction type of instruction i of type o.
+func (o instructionType) typeOf(i instruction) model.Type {
+	if o.csr {
+		return csrInstrType(i)
+	}
+	if _, ok := i.memOrder(); ok {
+		return o.instrType | model.TypeMemOrder
+	}
+	return o.instrType
+}
+
 // validEffects filters nil effects from a list of effects returned by effects
 // function.
 //
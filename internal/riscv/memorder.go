@@ -0,0 +1,157 @@
+package riscv
+
+import (
+	"fmt"
+	"mltwist/pkg/model"
+	"strings"
+)
+
+const (
+	// aqBit is index of the acquire bit of atomic instructions.
+	aqBit = 26
+	// rlBit is index of the release bit of atomic instructions.
+	rlBit = 25
+
+	// fencePredOffset is index of the lowest bit of predecessor set of
+	// fence instruction.
+	fencePredOffset = 24
+	// fenceSuccOffset is index of the lowest bit of successor set of fence
+	// instruction.
+	fenceSuccOffset = 20
+	// fenceSetBits is number of bits of predecessor and successor sets.
+	fenceSetBits = 4
+)
+
+// fenceSetNames are names of bits of fence predecessor and successor sets in
+// assembler code. Index in the array is index of the bit in the set from the
+// highest one.
+var fenceSetNames = [fenceSetBits]byte{'i', 'o', 'r', 'w'}
+
+// fenceAccesses are kinds of memory accesses represented by bits of fence
+// predecessor and successor sets. Index in the array is index of the bit in
+// the set from the highest one.
+var fenceAccesses = [fenceSetBits]model.Access{
+	model.AccessInput,
+	model.AccessOutput,
+	model.AccessRead,
+	model.AccessWrite,
+}
+
+// orderingSuffixes maps suffixes of names of atomic instructions to their
+// acquire and release bits.
+var orderingSuffixes = []struct {
+	suffix string
+	aq, rl bool
+}{
+	{".aqrl", true, true},
+	{".aq", true, false},
+	{".rl", false, true},
+}
+
+// orderingSuffix returns suffix of name of an atomic instruction with acquire
+// bit aq and release bit rl.
+func orderingSuffix(aq, rl bool) string {
+	for _, s := range orderingSuffixes {
+		if s.aq == aq && s.rl == rl {
+			return s.suffix
+		}
+	}
+	return ""
+}
+
+// cutOrderingSuffix removes acquire and release suffix from name of an atomic
+// instruction. It returns false if name has no such suffix.
+func cutOrderingSuffix(name string) (base string, aq, rl, ok bool) {
+	for _, s := range orderingSuffixes {
+		if strings.HasSuffix(name, s.suffix) {
+			return strings.TrimSuffix(name, s.suffix), s.aq, s.rl, true
+		}
+	}
+	return name, false, false, false
+}
+
+// fenceSetString returns representation of fence predecessor or successor set
+// s in assembler code.
+func fenceSetString(s uint8) string {
+	if s == 0 {
+		return "0"
+	}
+
+	var b strings.Builder
+	for i, n := range fenceSetNames {
+		if s&(1<<(fenceSetBits-1-i)) != 0 {
+			b.WriteByte(n)
+		}
+	}
+	return b.String()
+}
+
+// parseFenceSet parses fence predecessor or successor set s written in
+// assembler code. Names of the bits have to be written in the iorw order.
+func parseFenceSet(s string) (uint8, error) {
+	if s == "0" {
+		return 0, nil
+	}
+
+	var set uint8
+	rest := s
+	for i, n := range fenceSetNames {
+		if rest != "" && rest[0] == n {
+			set |= 1 << (fenceSetBits - 1 - i)
+			rest = rest[1:]
+		}
+	}
+	if s == "" || rest != "" {
+		return 0, fmt.Errorf("invalid fence set: %q", s)
+	}
+
+	return set, nil
+}
+
+// fenceSetAccess returns kinds of memory accesses of fence predecessor or
+// successor set s.
+func fenceSetAccess(s uint8) model.Access {
+	var a model.Access
+	for i, acc := range fenceAccesses {
+		if s&(1<<(fenceSetBits-1-i)) != 0 {
+			a |= acc
+		}
+	}
+	return a
+}
+
+// memOrder returns memory accesses ordered by instruction i. The second value
+// returned is false if i doesn't order any memory accesses at all.
+//
+// Fences with an empty predecessor or successor set don't order anything.
+// Atomic instructions with the acquire bit set order all later loads and
+// stores after themselves, the release bit orders all earlier loads and stores
+// before them. The zero order (a full barrier) is returned for all other
+// instructions ordering memory accesses.
+func (i instruction) memOrder() (model.MemOrder, bool) {
+	t := i.instrType
+	ops := i.operands()
+
+	switch {
+	case t.fence:
+		order := model.MemOrder{
+			Pred: fenceSetAccess(ops.Pred),
+			Succ: fenceSetAccess(ops.Succ),
+		}
+		if order.Pred == 0 || order.Succ == 0 {
+			return model.MemOrder{}, false
+		}
+		return order, true
+	case t.aqrl:
+		var order model.MemOrder
+		if ops.Aq {
+			order.Succ = model.AccessRead | model.AccessWrite
+		}
+		if ops.Rl {
+			order.Pred = model.AccessRead | model.AccessWrite
+		}
+		return order, ops.Aq || ops.Rl
+	default:
+		return model.MemOrder{}, t.instrType.MemOrder()
+	}
+}
@@ -0,0 +1,86 @@
+package riscv
+
+import (
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_MemOrder(t *testing.T) {
+	const rw = model.AccessRead | model.AccessWrite
+
+	tests := []struct {
+		name  string
+		bytes []byte
+		s     string
+		typ   model.Type
+		order model.MemOrder
+	}{{
+		name:  "fence_full",
+		bytes: []byte{0x0f, 0x00, 0xf0, 0x0f},
+		s:     "fence iorw, iorw",
+		typ:   model.TypeMemOrder,
+		order: model.MemOrder{Pred: model.AccessAll, Succ: model.AccessAll},
+	}, {
+		name:  "fence_read_write",
+		bytes: []byte{0x0f, 0x00, 0x10, 0x02},
+		s:     "fence r, w",
+		typ:   model.TypeMemOrder,
+		order: model.MemOrder{Pred: model.AccessRead, Succ: model.AccessWrite},
+	}, {
+		name:  "fence_io",
+		bytes: []byte{0x0f, 0x00, 0x40, 0x08},
+		s:     "fence i, o",
+		typ:   model.TypeMemOrder,
+		order: model.MemOrder{Pred: model.AccessInput, Succ: model.AccessOutput},
+	}, {
+		name:  "fence_empty_set",
+		bytes: []byte{0x0f, 0x00, 0x30, 0x00},
+		s:     "fence 0, rw",
+		typ:   model.TypeNone,
+	}, {
+		name:  "fence_tso",
+		bytes: []byte{0x0f, 0x00, 0x30, 0x83},
+		s:     "fence.tso",
+		typ:   model.TypeMemOrder,
+	}, {
+		name:  "amo_relaxed",
+		bytes: []byte{0x2f, 0x25, 0xb6, 0x08},
+		s:     "amoswap.w x10, x11, (x12)",
+		typ:   model.TypeNone,
+	}, {
+		name:  "amo_aqrl",
+		bytes: []byte{0x2f, 0x25, 0xb6, 0x0e},
+		s:     "amoswap.w.aqrl x10, x11, (x12)",
+		typ:   model.TypeMemOrder,
+		order: model.MemOrder{Pred: rw, Succ: rw},
+	}, {
+		name:  "lr_acquire",
+		bytes: []byte{0x2f, 0xb5, 0x05, 0x14},
+		s:     "lr.d.aq x10, (x11)",
+		typ:   model.TypeMemOrder,
+		order: model.MemOrder{Succ: rw},
+	}, {
+		name:  "sc_release",
+		bytes: []byte{0x2f, 0x25, 0xb6, 0x1a},
+		s:     "sc.w.rl x10, x11, (x12)",
+		typ:   model.TypeMemOrder,
+		order: model.MemOrder{Pred: rw},
+	}}
+
+	p := NewParser(Variant64, ExtA)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			ins, err := p.Parse(0x1000, tt.bytes)
+			r.NoError(err)
+			r.NoError(ins.Validate())
+			r.Equal(tt.typ, ins.Type)
+			r.Equal(tt.order, ins.Order)
+			r.Equal(tt.s, ins.Details.String())
+		})
+	}
+}
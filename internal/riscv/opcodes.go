@@ -33,26 +33,6 @@ func assertMask(b byte, mask byte) {
 	}
 }
 
-// revertBytes is an utility function which reverts b as a slice. For more
-// convenience, this function also returns b.
-//
-// The purpose of this method is to lower cognitive complexity of RISC-V
-// instruction definition in the code. The problem is that even though RISC-V
-// instructions are encoded in little endian byte order, the RICS-V
-// specification ifself user big endian notation (least significant byte is the
-// right-most byte). This misalignment in between ste specs and real
-// implementation is expected. In human readable documents, it's just common to
-// write least significant byte and bit to right. But as humans are not good in
-// reverting byte sequences they read, it's much better solution to write
-// instruction opcodes in big endian and then to revert the array.
-func revertBytes(b []byte) []byte {
-	for i := 0; i < len(b)/2; i++ {
-		j := len(b) - i - 1
-		b[i], b[j] = b[j], b[i]
-	}
-	return b
-}
-
 // opcode7 returns opcode matching low with bottom 7 bits of an instruction.
 //
 // As 1 byte opcodes have only 7 bits, this method will panic for values of low
@@ -139,54 +119,36 @@ func assertShiftBits(shiftBits uint8) {
 // opcode as every other bit (but bit [30]) of an immediate value is reserved to
 // be zero.
 func opcodeShiftImm(arithmetic bool, shiftBits uint8, mid byte, low byte) opcode.Opcode {
-	assertMask(low, low7Bits)
-	assertMask(mid, low3Bits)
-	assertShiftBits(shiftBits)
-
 	var high byte = 0
 	if arithmetic {
-		high = byte(1) << 6
-	}
-
-	// Shift is encoded in bits [20:(20+shiftBits)]. So we do 1<<shiftBits
-	// to get 2^shiftBits. Then we subtract 1 which creates is a bit mask
-	// for bits encoding values 0..(2^shiftBits)-1. We then invert the mask
-	// to ensure that all other reserved bits of the actual opcode are zero.
-	shiftBitMask := (uint16(1) << shiftBits) - 1
-	// Then we have to shift this mask to the right place - to 20th bit of
-	// opcode. As we have just high half of instruction opcode, we are
-	// already shifted 16 bits. So 4 bits are remaining.
-	highHalfMask := (^shiftBitMask) << 4
-
-	return opcode.Opcode{
-		Bytes: []byte{low, mid << 4, 0, high},
-		Mask: []byte{
-			low7Bits,
-			low3Bits << 4,
-			byte(highHalfMask),
-			byte(highHalfMask >> 8),
-		},
+		high = 0b0100000
 	}
+	return opcodeShiftFunct(high, shiftBits, mid, low)
 }
 
-// opcodeAtomic creates an opcode definition for atomic-type instruction.
+// opcodeShiftFunct creates an opcode definition for an instruction with shift
+// amount of shiftBits bits encoded in the instruction opcode. All the bits
+// above the shift amount are part of the opcode and high is value of bits
+// [25..31] of the instruction. See opcodeShiftImm for details.
 //
-// As atomic instructions contain acquire and release bits in bits [25] and [24]
-// respectively. As those 2 bits doesn't matter for instruction opcode matching,
-// we have to exclude those from opcode matching.
-func opcodeAtomic(high byte, mid byte, low byte) opcode.Opcode {
-	assertMask(low, low7Bits)
-	assertMask(mid, low3Bits)
-	assertMask(high, low5Bits)
+// Bits of high overlapping with the shift amount must be zero. This method
+// panics otherwise.
+func opcodeShiftFunct(high byte, shiftBits uint8, mid byte, low byte) opcode.Opcode {
+	assertShiftBits(shiftBits)
+
+	o := opcode17(high, mid, low)
+	value := opcodeValue(o.Bytes)
+
+	// Shift is encoded in bits [20:(20+shiftBits)], all other bits of
+	// I immediate are the opcode.
+	mask := opcodeValue(opcode10(mid, low).Mask) | ^uint32(0)<<(20+shiftBits)
+	if value&^mask != 0 {
+		panic(fmt.Sprintf("opcode overlaps with shift amount: 0x%x", high))
+	}
 
 	return opcode.Opcode{
-		Bytes: []byte{low, mid << 4, 0, high << 3},
-		Mask: []byte{
-			low7Bits,
-			low3Bits << 4,
-			0,
-			low5Bits << 3,
-		},
+		Bytes: instruction{value: value}.bytes(),
+		Mask:  instruction{value: mask}.bytes(),
 	}
 }
 
@@ -244,7 +206,7 @@ func maskedRegOp(f binaryExprFunc, i instruction, bits uint8, w expr.Width) expr
 
 func regImmShift(f binaryExprFunc, i instruction, bits uint8, w expr.Width) expr.Expr {
 	assertShiftBits(bits)
-	mask := int32(1) << int32(bits)
+	mask := int32(1)<<int32(bits) - 1
 	imm, _ := immTypeI.parseValue(i.value)
 	immShift := expr.ConstFromInt(imm & mask)
 	return f(regLoad(rs1, i, w), immShift, w)
@@ -272,12 +234,22 @@ func regStore(e expr.Expr, i instruction, w expr.Width) expr.Effect {
 	return expr.NewRegStore(e, expr.Key(num.String()), w)
 }
 
+// addrConst creates a constant of width w representing address a. Address
+// arithmetic wraps around in the address space of width w, so bits of a which
+// don't fit into w bytes are dropped.
+func addrConst(a model.Addr, w expr.Width) expr.Const {
+	if w < model.AddrWidth {
+		a &= model.Addr(1)<<(8*model.Addr(w)) - 1
+	}
+	return expr.NewConstUint(a, w)
+}
+
 func addrImmConst(t immType, i instruction, w expr.Width) expr.Const {
 	imm, ok := t.parseValue(i.value)
 	if !ok {
 		panic(fmt.Sprintf("immediate encoding %d has no value", t))
 	}
-	return expr.NewConstUint(addrAddImm(i.addr, imm), w)
+	return addrConst(addrAddImm(i.addr, imm), w)
 }
 
 func branchCmp(
@@ -287,7 +259,7 @@ func branchCmp(
 	w expr.Width,
 ) expr.Effect {
 	jumpTarget := addrImmConst(immTypeB, i, w)
-	nextInstr := expr.NewConstUint(i.addr+instructionLen, w)
+	nextInstr := addrConst(i.addr+i.len(), w)
 
 	condTrue, condFalse := jumpTarget, nextInstr
 	if !branchIfTrue {
@@ -342,11 +314,6 @@ func atomicOpWidth(f binaryExprFunc, i instruction, addrW, opW expr.Width) []exp
 	}
 }
 
-func csrKey(i instruction) expr.Key {
-	csrNum, _ := immTypeI.parseValue(i.value)
-	return expr.Key(csr(csrNum).String())
-}
-
 func csrImm(i instruction) expr.Const {
 	val := uint8((i.value >> 15) & 0x1f)
 	return expr.ConstFromUint(val)
@@ -354,14 +321,32 @@ func csrImm(i instruction) expr.Const {
 
 var instructions = map[Variant]map[Extension][]*instructionType{
 	Variant32: {
-		extI: integer32,
-		ExtM: mul32,
-		ExtA: atomic32,
+		extI:      integerInstructions(width32),
+		ExtM:      mulInstructions(width32),
+		ExtA:      atomicInstructions(width32),
+		ExtC:      compressed32,
+		ExtF:      singleInstructions(singleFPU(width32, width32)),
+		ExtD:      doubleInstructions(width32),
+		ExtZba:    zbaInstructions(width32),
+		ExtZbb:    zbbInstructions(width32),
+		ExtZbs:    zbsInstructions(width32),
+		ExtZicond: zicondInstructions(width32),
+		ExtV:      vectorInstructions(width32),
+		ExtPriv:   privInstructions(width32),
 	},
 	Variant64: {
-		extI: integer64,
-		ExtM: mul64,
-		ExtA: atomic64,
+		extI:      integerInstructions(width64),
+		ExtM:      mulInstructions(width64),
+		ExtA:      atomicInstructions(width64),
+		ExtC:      compressed64,
+		ExtF:      singleInstructions(singleFPU(width64, width32)),
+		ExtD:      doubleInstructions(width64),
+		ExtZba:    zbaInstructions(width64),
+		ExtZbb:    zbbInstructions(width64),
+		ExtZbs:    zbsInstructions(width64),
+		ExtZicond: zicondInstructions(width64),
+		ExtV:      vectorInstructions(width64),
+		ExtPriv:   privInstructions(width64),
 	},
 }
 
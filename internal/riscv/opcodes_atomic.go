@@ -0,0 +1,82 @@
+package riscv
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// atomicInstructions returns instructions of A (atomic) extension of a
+// processor with registers of width xlen.
+func atomicInstructions(xlen expr.Width) []*instructionType {
+	// Values narrower than a register (words on RV64) are sign extended
+	// when loaded into a register.
+	extend := func(e expr.Expr, w expr.Width) expr.Expr {
+		if w < xlen {
+			return sext32To64(e)
+		}
+		return e
+	}
+
+	amo := func(f binaryExprFunc, w expr.Width) semantics {
+		return semantics{
+			loadBytes:  uint8(w),
+			storeBytes: uint8(w),
+			effects: func(i instruction) []expr.Effect {
+				if w < xlen {
+					return atomicOpWidth(f, i, xlen, w)
+				}
+				return atomicOp(f, i, w)
+			},
+		}
+	}
+
+	sems := make(map[string]semantics)
+	add := func(suffix string, w expr.Width) {
+		sems["lr"+suffix] = semantics{
+			loadBytes: uint8(w),
+			effects: func(i instruction) []expr.Effect {
+				val := memLoad(regLoad(rs1, i, xlen), w)
+				return []expr.Effect{regStore(extend(val, w), i, xlen)}
+			},
+		}
+		sems["sc"+suffix] = semantics{
+			storeBytes: uint8(w),
+			effects: func(i instruction) []expr.Effect {
+				val := regLoad(rs2, i, w)
+				addr := regLoad(rs1, i, xlen)
+				// TODO: Find a way how to emulate the race check.
+				return []expr.Effect{
+					memStore(val, addr, w),
+					regStore(expr.Zero, i, xlen),
+				}
+			},
+		}
+		sems["amoswap"+suffix] = semantics{
+			loadBytes:  uint8(w),
+			storeBytes: uint8(w),
+			effects: func(i instruction) []expr.Effect {
+				addr := regLoad(rs1, i, xlen)
+				return []expr.Effect{
+					regStore(extend(memLoad(addr, w), w), i, xlen),
+					memStore(regLoad(rs2, i, w), addr, w),
+				}
+			},
+		}
+		sems["amoadd"+suffix] = amo(binOpFunc(expr.Add), w)
+		sems["amoxor"+suffix] = amo(exprtools.BitXor, w)
+		sems["amoand"+suffix] = amo(exprtools.BitAnd, w)
+		sems["amoor"+suffix] = amo(exprtools.BitOr, w)
+		sems["amomin"+suffix] = amo(atomicMinMax(exprtools.Lts, false), w)
+		sems["amomax"+suffix] = amo(atomicMinMax(exprtools.Lts, true), w)
+		sems["amominu"+suffix] = amo(atomicMinMax(lessFunc, false), w)
+		sems["amomaxu"+suffix] = amo(atomicMinMax(lessFunc, true), w)
+	}
+
+	add(".w", width32)
+	if xlen == width32 {
+		return tableInstructions(sems, "rv_a")
+	}
+
+	add(".d", width64)
+	return tableInstructions(sems, "rv_a", "rv64_a")
+}
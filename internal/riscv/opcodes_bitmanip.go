@@ -0,0 +1,222 @@
+package riscv
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// shamtBits returns number of bits of shift amount of a processor with
+// registers of width xlen.
+func shamtBits(xlen expr.Width) uint8 {
+	if xlen == width32 {
+		return 5
+	}
+	return 6
+}
+
+// zext32 returns value of register at position r of i zero extended from 32
+// bits.
+func zext32(r reg, i instruction) expr.Expr { return regLoad(r, i, width32) }
+
+// bitOpFunc returns a binaryExprFunc which applies f on e1 and a mask with a
+// single bit set at index e2.
+func bitOpFunc(f binaryExprFunc) binaryExprFunc {
+	return func(e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		return f(e1, expr.NewBinary(expr.Lsh, expr.One, e2, w), w)
+	}
+}
+
+func bitClear(e1, e2 expr.Expr, w expr.Width) expr.Expr {
+	return exprtools.BitAnd(e1, exprtools.BitNot(e2, w), w)
+}
+
+func bitExtract(e1, e2 expr.Expr, w expr.Width) expr.Expr {
+	return exprtools.MaskBits(expr.NewBinary(expr.Rsh, e1, e2, w), 1, w)
+}
+
+// orCombineBytes sets all bits of every nonzero byte of e of width w.
+func orCombineBytes(e expr.Expr, w expr.Width) expr.Expr {
+	var res expr.Expr = expr.Zero
+	for i := expr.Width(0); i < w; i++ {
+		shift := expr.ConstFromUint(8 * uint16(i))
+		b := exprtools.MaskBits(expr.NewBinary(expr.Rsh, e, shift, w), 8, w)
+		ones := expr.NewBinary(expr.Lsh, expr.ConstFromUint[uint8](0xff), shift, w)
+		res = exprtools.BitOr(res, exprtools.BoolCond(b, ones, expr.Zero, w), w)
+	}
+	return res
+}
+
+// zbaInstructions returns instructions of Zba (address generation) extension
+// of a processor with registers of width xlen.
+func zbaInstructions(xlen expr.Width) []*instructionType {
+	// Shift and add instructions add rs2 to rs1 shifted left. Their
+	// unsigned word versions zero extend rs1 before the shift.
+	shiftAdd := func(shift uint8, word bool) semantics {
+		return semantics{effects: regResult(func(i instruction) expr.Expr {
+			val := regLoad(rs1, i, xlen)
+			if word {
+				val = zext32(rs1, i)
+			}
+			shifted := expr.NewBinary(expr.Lsh, val, expr.ConstFromUint(shift), xlen)
+			return expr.NewBinary(expr.Add, shifted, regLoad(rs2, i, xlen), xlen)
+		}, xlen)}
+	}
+
+	sems := map[string]semantics{
+		"sh1add": shiftAdd(1, false),
+		"sh2add": shiftAdd(2, false),
+		"sh3add": shiftAdd(3, false),
+	}
+	if xlen != width64 {
+		return tableInstructions(sems, "rv_zba")
+	}
+
+	sems["add.uw"] = shiftAdd(0, true)
+	sems["sh1add.uw"] = shiftAdd(1, true)
+	sems["sh2add.uw"] = shiftAdd(2, true)
+	sems["sh3add.uw"] = shiftAdd(3, true)
+	sems["slli.uw"] = semantics{effects: regResult(func(i instruction) expr.Expr {
+		imm, _ := immTypeI.parseValue(i.value)
+		shift := expr.ConstFromInt(imm & 0x3f)
+		return expr.NewBinary(expr.Lsh, zext32(rs1, i), shift, width64)
+	}, width64)}
+
+	return tableInstructions(sems, "rv_zba", "rv64_zba")
+}
+
+// zbbInstructions returns instructions of Zbb (basic bit manipulation)
+// extension of a processor with registers of width xlen.
+func zbbInstructions(xlen expr.Width) []*instructionType {
+	bits := shamtBits(xlen)
+
+	op := func(f func(i instruction) expr.Expr) semantics {
+		return semantics{effects: regResult(f, xlen)}
+	}
+	rs1Op := func(f func(e expr.Expr, w expr.Width) expr.Expr, w expr.Width) semantics {
+		return op(func(i instruction) expr.Expr { return f(regLoad(rs1, i, w), w) })
+	}
+	negatedRs2 := func(f binaryExprFunc) semantics {
+		return op(func(i instruction) expr.Expr {
+			neg := exprtools.BitNot(regLoad(rs2, i, xlen), xlen)
+			return f(regLoad(rs1, i, xlen), neg, xlen)
+		})
+	}
+	minMax := func(f condExprFunc, lower bool) semantics {
+		return op(func(i instruction) expr.Expr {
+			a1, a2 := regLoad(rs1, i, xlen), regLoad(rs2, i, xlen)
+			if lower {
+				return f(a1, a2, a1, a2, xlen)
+			}
+			return f(a1, a2, a2, a1, xlen)
+		})
+	}
+
+	sems := map[string]semantics{
+		"andn":  negatedRs2(exprtools.BitAnd),
+		"orn":   negatedRs2(exprtools.BitOr),
+		"xnor":  negatedRs2(exprtools.BitXor),
+		"clz":   rs1Op(exprtools.LeadingZeros, xlen),
+		"ctz":   rs1Op(exprtools.TrailingZeros, xlen),
+		"cpop":  rs1Op(exprtools.PopCount, xlen),
+		"max":   minMax(exprtools.Lts, false),
+		"maxu":  minMax(lessFunc, false),
+		"min":   minMax(exprtools.Lts, true),
+		"minu":  minMax(lessFunc, true),
+		"orc.b": rs1Op(orCombineBytes, xlen),
+		"rev8":  rs1Op(exprtools.ReverseBytes, xlen),
+		"sext.b": op(func(i instruction) expr.Expr {
+			return sext(regLoad(rs1, i, xlen), 7, xlen)
+		}),
+		"sext.h": op(func(i instruction) expr.Expr {
+			return sext(regLoad(rs1, i, xlen), 15, xlen)
+		}),
+		"zext.h": op(func(i instruction) expr.Expr {
+			return regLoad(rs1, i, width16)
+		}),
+		"rol": op(func(i instruction) expr.Expr {
+			return maskedRegOp(exprtools.RotateLeft, i, bits, xlen)
+		}),
+		"ror": op(func(i instruction) expr.Expr {
+			return maskedRegOp(exprtools.RotateRight, i, bits, xlen)
+		}),
+		"rori": op(func(i instruction) expr.Expr {
+			return regImmShift(exprtools.RotateRight, i, bits, xlen)
+		}),
+	}
+	if xlen != width64 {
+		return tableInstructions(sems, "rv_zbb", "rv32_zbb")
+	}
+
+	// Word instructions operate on the lower 32 bits of registers and sign
+	// extend the result.
+	word := func(f func(i instruction) expr.Expr) semantics {
+		return op(func(i instruction) expr.Expr { return sext32To64(f(i)) })
+	}
+
+	sems["clzw"] = rs1Op(exprtools.LeadingZeros, width32)
+	sems["ctzw"] = rs1Op(exprtools.TrailingZeros, width32)
+	sems["cpopw"] = rs1Op(exprtools.PopCount, width32)
+	sems["rolw"] = word(func(i instruction) expr.Expr {
+		return maskedRegOp(exprtools.RotateLeft, i, 5, width32)
+	})
+	sems["rorw"] = word(func(i instruction) expr.Expr {
+		return maskedRegOp(exprtools.RotateRight, i, 5, width32)
+	})
+	sems["roriw"] = word(func(i instruction) expr.Expr {
+		return regImmShift(exprtools.RotateRight, i, 5, width32)
+	})
+
+	return tableInstructions(sems, "rv_zbb", "rv64_zbb")
+}
+
+// zbsInstructions returns instructions of Zbs (single bit) extension of a
+// processor with registers of width xlen.
+func zbsInstructions(xlen expr.Width) []*instructionType {
+	bits := shamtBits(xlen)
+
+	regOp := func(f binaryExprFunc) semantics {
+		return semantics{effects: regResult(func(i instruction) expr.Expr {
+			return maskedRegOp(f, i, bits, xlen)
+		}, xlen)}
+	}
+	immOp := func(f binaryExprFunc) semantics {
+		return semantics{effects: regResult(func(i instruction) expr.Expr {
+			return regImmShift(f, i, bits, xlen)
+		}, xlen)}
+	}
+
+	sems := map[string]semantics{
+		"bclr":  regOp(bitOpFunc(bitClear)),
+		"bclri": immOp(bitOpFunc(bitClear)),
+		"bext":  regOp(bitExtract),
+		"bexti": immOp(bitExtract),
+		"binv":  regOp(bitOpFunc(exprtools.BitXor)),
+		"binvi": immOp(bitOpFunc(exprtools.BitXor)),
+		"bset":  regOp(bitOpFunc(exprtools.BitOr)),
+		"bseti": immOp(bitOpFunc(exprtools.BitOr)),
+	}
+
+	if xlen == width32 {
+		return tableInstructions(sems, "rv_zbs", "rv32_zbs")
+	}
+	return tableInstructions(sems, "rv_zbs", "rv64_zbs")
+}
+
+// zicondInstructions returns instructions of Zicond (integer conditional
+// operations) extension of a processor with registers of width xlen.
+func zicondInstructions(xlen expr.Width) []*instructionType {
+	czero := func(zeroIfNonzero bool) semantics {
+		return semantics{effects: regResult(func(i instruction) expr.Expr {
+			val, cond := regLoad(rs1, i, xlen), regLoad(rs2, i, xlen)
+			if zeroIfNonzero {
+				return exprtools.BoolCond(cond, expr.Zero, val, xlen)
+			}
+			return exprtools.BoolCond(cond, val, expr.Zero, xlen)
+		}, xlen)}
+	}
+
+	return tableInstructions(map[string]semantics{
+		"czero.eqz": czero(false),
+		"czero.nez": czero(true),
+	}, "rv_zicond")
+}
@@ -0,0 +1,205 @@
+package riscv
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitmanipEffects(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		s       string
+		regs    map[expr.Key]uint64
+		want    uint64
+	}{{
+		name:    "sh2add",
+		variant: Variant32,
+		s:       "sh2add x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 0x40000003, "x3": 0x100},
+		want:    0x10c,
+	}, {
+		name:    "sh3add.uw",
+		variant: Variant64,
+		s:       "sh3add.uw x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 0xffffffff_80000001, "x3": 1},
+		want:    0x4_00000009,
+	}, {
+		name:    "slli.uw",
+		variant: Variant64,
+		s:       "slli.uw x1, x2, 36",
+		regs:    map[expr.Key]uint64{"x2": 0x12345678_9abcdef1},
+		want:    0xabcdef10_00000000,
+	}, {
+		name:    "andn",
+		variant: Variant64,
+		s:       "andn x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 0xff00ff, "x3": 0x0f000f},
+		want:    0xf000f0,
+	}, {
+		name:    "xnor",
+		variant: Variant32,
+		s:       "xnor x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 0xff00ff00, "x3": 0x0f0f0f0f},
+		want:    0x0ff00ff0,
+	}, {
+		name:    "clz",
+		variant: Variant64,
+		s:       "clz x1, x2",
+		regs:    map[expr.Key]uint64{"x2": 0x00000100_00000000},
+		want:    23,
+	}, {
+		name:    "clz_zero",
+		variant: Variant32,
+		s:       "clz x1, x2",
+		want:    32,
+	}, {
+		name:    "clzw",
+		variant: Variant64,
+		s:       "clzw x1, x2",
+		regs:    map[expr.Key]uint64{"x2": 0xffffffff_00001000},
+		want:    19,
+	}, {
+		name:    "ctz",
+		variant: Variant64,
+		s:       "ctz x1, x2",
+		regs:    map[expr.Key]uint64{"x2": 0x80000000_00000000},
+		want:    63,
+	}, {
+		name:    "ctzw_zero",
+		variant: Variant64,
+		s:       "ctzw x1, x2",
+		regs:    map[expr.Key]uint64{"x2": 0xffffffff_00000000},
+		want:    32,
+	}, {
+		name:    "cpop",
+		variant: Variant64,
+		s:       "cpop x1, x2",
+		regs:    map[expr.Key]uint64{"x2": 0xf0f0f0f0_00000007},
+		want:    19,
+	}, {
+		name:    "max",
+		variant: Variant64,
+		s:       "max x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 0xffffffff_ffffffff, "x3": 2},
+		want:    2,
+	}, {
+		name:    "minu",
+		variant: Variant64,
+		s:       "minu x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 0xffffffff_ffffffff, "x3": 2},
+		want:    2,
+	}, {
+		name:    "min",
+		variant: Variant32,
+		s:       "min x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 0xfffffffe, "x3": 2},
+		want:    0xfffffffe,
+	}, {
+		name:    "sext.b",
+		variant: Variant64,
+		s:       "sext.b x1, x2",
+		regs:    map[expr.Key]uint64{"x2": 0x1280},
+		want:    0xffffffff_ffffff80,
+	}, {
+		name:    "zext.h",
+		variant: Variant64,
+		s:       "zext.h x1, x2",
+		regs:    map[expr.Key]uint64{"x2": 0xffffffff_ffff8001},
+		want:    0x8001,
+	}, {
+		name:    "rol",
+		variant: Variant32,
+		s:       "rol x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 0x80000001, "x3": 33},
+		want:    0x00000003,
+	}, {
+		name:    "rori",
+		variant: Variant64,
+		s:       "rori x1, x2, 4",
+		regs:    map[expr.Key]uint64{"x2": 0x01234567_89abcdef},
+		want:    0xf0123456_789abcde,
+	}, {
+		name:    "rorw",
+		variant: Variant64,
+		s:       "rorw x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 0x00000000_00000002, "x3": 2},
+		want:    0xffffffff_80000000,
+	}, {
+		name:    "roriw",
+		variant: Variant64,
+		s:       "roriw x1, x2, 0",
+		regs:    map[expr.Key]uint64{"x2": 0x12345678_87654321},
+		want:    0xffffffff_87654321,
+	}, {
+		name:    "orc.b",
+		variant: Variant64,
+		s:       "orc.b x1, x2",
+		regs:    map[expr.Key]uint64{"x2": 0x00010080_00200000},
+		want:    0x00ff00ff_00ff0000,
+	}, {
+		name:    "rev8_rv32",
+		variant: Variant32,
+		s:       "rev8 x1, x2",
+		regs:    map[expr.Key]uint64{"x2": 0x12345678},
+		want:    0x78563412,
+	}, {
+		name:    "rev8_rv64",
+		variant: Variant64,
+		s:       "rev8 x1, x2",
+		regs:    map[expr.Key]uint64{"x2": 0x01234567_89abcdef},
+		want:    0xefcdab89_67452301,
+	}, {
+		name:    "bclr",
+		variant: Variant64,
+		s:       "bclr x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 0xffffffff_ffffffff, "x3": 64 + 63},
+		want:    0x7fffffff_ffffffff,
+	}, {
+		name:    "bseti",
+		variant: Variant32,
+		s:       "bseti x1, x2, 31",
+		regs:    map[expr.Key]uint64{"x2": 1},
+		want:    0x80000001,
+	}, {
+		name:    "binvi",
+		variant: Variant64,
+		s:       "binvi x1, x2, 40",
+		regs:    map[expr.Key]uint64{"x2": 0x100_00000001},
+		want:    1,
+	}, {
+		name:    "bext",
+		variant: Variant32,
+		s:       "bext x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 0x10, "x3": 4},
+		want:    1,
+	}, {
+		name:    "czero.eqz_zero",
+		variant: Variant64,
+		s:       "czero.eqz x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 42},
+		want:    0,
+	}, {
+		name:    "czero.eqz_nonzero",
+		variant: Variant64,
+		s:       "czero.eqz x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 42, "x3": 1 << 63},
+		want:    42,
+	}, {
+		name:    "czero.nez",
+		variant: Variant32,
+		s:       "czero.nez x1, x2, x3",
+		regs:    map[expr.Key]uint64{"x2": 42, "x3": 7},
+		want:    0,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			res := evalRegs(t, tt.variant, tt.s, tt.regs)
+			require.Equal(t, map[expr.Key]uint64{"x1": tt.want}, res)
+		})
+	}
+}
@@ -0,0 +1,435 @@
+package riscv
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// maxFloatBytes is width of the widest floating point register (FLEN) this
+// package supports in bytes.
+const maxFloatBytes = 8
+
+const (
+	// rmBitOffset is index of the lowest bit of rounding mode field of an
+	// instruction.
+	rmBitOffset = 12
+	// rmMask is mask of rounding mode field value.
+	rmMask = 0x7
+	// rmDynamic is value of rounding mode field selecting the dynamic
+	// rounding mode held in frm field of fcsr.
+	rmDynamic = 7
+)
+
+// fcsrKey is key of floating point control and status register.
+//
+// The register holds accrued exception flags (fflags CSR) in bits [0:4] and
+// dynamic rounding mode (frm CSR) in bits [5:7]. Exception flags are laid out
+// in the same way as expr.FloatException is.
+const fcsrKey = expr.Key("fcsr")
+
+// fcsrWidth is width of fcsr register.
+const fcsrWidth = width32
+
+// rmField returns value of rounding mode field of an instruction value.
+func rmField(value uint32) uint8 { return uint8(value>>rmBitOffset) & rmMask }
+
+// validRoundingMode indicates that rm is a rounding mode which can be encoded
+// into an instruction. Values 5 and 6 are reserved by the specification.
+func validRoundingMode(rm uint8) bool {
+	return rm <= uint8(expr.RoundNearestMaxMagnitude) || rm == rmDynamic
+}
+
+// fpu describes floating point instructions of a single floating point format
+// and registers those instructions operate on.
+type fpu struct {
+	// xlen is width of integer registers.
+	xlen expr.Width
+	// flen is width of floating point registers.
+	flen expr.Width
+	// w is width of the floating point format.
+	w expr.Width
+
+	// suffix is suffix of names of arithmetic instructions (fadd.s).
+	suffix string
+	// intSuffix is suffix of names of loads, stores and moves (flw,
+	// fmv.x.w) - the name of integer type of the same width as the format.
+	intSuffix string
+}
+
+// singleFPU returns description of single precision instructions of a
+// processor with integer registers of width xlen and floating point registers
+// of width flen.
+func singleFPU(xlen expr.Width, flen expr.Width) fpu {
+	return fpu{xlen: xlen, flen: flen, w: width32, suffix: "s", intSuffix: "w"}
+}
+
+// doubleFPU returns description of double precision instructions of a
+// processor with integer registers of width xlen.
+func doubleFPU(xlen expr.Width) fpu {
+	return fpu{xlen: xlen, flen: width64, w: width64, suffix: "d", intSuffix: "d"}
+}
+
+// fregKey returns key of floating point register at position r of i.
+func fregKey(r reg, i instruction) expr.Key {
+	return expr.Key(fregNum(r.regNum(i.value)).String())
+}
+
+// boxMask returns mask of bits of a floating point register which are set if
+// a value of the format is NaN-boxed.
+func (u fpu) boxMask() expr.Const {
+	return expr.NewConstUint(^uint64(0)<<u.w.Bits(), u.flen)
+}
+
+// nan returns the canonical NaN of the format.
+func (u fpu) nan() expr.Const {
+	if u.w == width32 {
+		return expr.NewConstUint(uint32(0x7fc00000), u.w)
+	}
+	return expr.NewConstUint(uint64(0x7ff8000000000000), u.w)
+}
+
+// load returns value of floating point register at position r of i.
+//
+// Floating point values narrower than FLEN are NaN-boxed in registers - all
+// the bits above the value are set. A value which is not properly NaN-boxed is
+// interpreted as the canonical NaN.
+func (u fpu) load(r reg, i instruction) expr.Expr {
+	key := fregKey(r, i)
+	if u.w == u.flen {
+		return expr.NewRegLoad(key, u.w)
+	}
+
+	reg := expr.NewRegLoad(key, u.flen)
+	value := expr.NewRegLoad(key, u.w)
+	return expr.NewLess(reg, u.boxMask(), u.nan(), value, u.flen)
+}
+
+// store returns an effect storing floating point value e into register at
+// position rd of i. The value is NaN-boxed if it's narrower than FLEN.
+func (u fpu) store(e expr.Expr, i instruction) expr.Effect {
+	if u.w != u.flen {
+		e = exprtools.BitOr(e, u.boxMask(), u.flen)
+	}
+	return expr.NewRegStore(e, fregKey(rd, i), u.flen)
+}
+
+// rounding returns rounding mode of instruction i.
+func (u fpu) rounding(i instruction) expr.Expr {
+	rm := rmField(i.value)
+	if rm == rmDynamic {
+		return csrViews[csrFrm].read(width8)
+	}
+	return expr.Rounding(rm).Const()
+}
+
+// accrue returns an effect accruing exception flags raised by f into fcsr.
+func accrue(f expr.Float) expr.Effect {
+	fcsr := expr.NewRegLoad(fcsrKey, fcsrWidth)
+	return expr.NewRegStore(exprtools.BitOr(fcsr, f.Flags(), fcsrWidth), fcsrKey, fcsrWidth)
+}
+
+// floatResult returns effects of an instruction i storing floating point
+// result of f into floating point register.
+func (u fpu) floatResult(f expr.Float, i instruction) []expr.Effect {
+	return []expr.Effect{u.store(f, i), accrue(f)}
+}
+
+// intResult returns effects of an instruction i storing integer result of f
+// into integer register. Results narrower than XLEN are sign-extended.
+func (u fpu) intResult(f expr.Float, i instruction) []expr.Effect {
+	var val expr.Expr = f
+	if w := f.Width(); w < u.xlen {
+		val = sext(f, uint8(w.Bits()-1), u.xlen)
+	}
+	return []expr.Effect{regStore(val, i, u.xlen), accrue(f)}
+}
+
+// class returns an expression of width XLEN classifying floating point value e
+// as fclass instruction does. Exactly one bit of the result is set:
+//
+//	0: negative infinity
+//	1: negative normal number
+//	2: negative subnormal number
+//	3: negative zero
+//	4: positive zero
+//	5: positive subnormal number
+//	6: positive normal number
+//	7: positive infinity
+//	8: signaling NaN
+//	9: quiet NaN
+func (u fpu) class(e expr.Expr) expr.Expr {
+	fracBits := uint64(23)
+	if u.w == width64 {
+		fracBits = 52
+	}
+
+	bit := func(n int) expr.Expr { return expr.NewConstUint(uint64(1)<<n, u.xlen) }
+	c := func(v uint64) expr.Expr { return expr.NewConstUint(v, u.w) }
+
+	sign := uint64(1) << (u.w.Bits() - 1)
+	inf := (sign - 1) &^ (uint64(1)<<fracBits - 1)
+	minNormal := uint64(1) << fracBits
+	quiet := inf | uint64(1)<<(fracBits-1)
+
+	abs := exprtools.FloatAbs(e, u.w)
+	bySign := func(posBit, negBit int) expr.Expr {
+		return expr.NewLess(e, c(sign), bit(posBit), bit(negBit), u.w)
+	}
+
+	nan := expr.NewLess(abs, c(quiet), bit(8), bit(9), u.w)
+	infinite := expr.NewLess(abs, c(inf+1), bySign(7, 0), nan, u.w)
+	normal := expr.NewLess(abs, c(inf), bySign(6, 1), infinite, u.w)
+	subnormal := expr.NewLess(abs, c(minNormal), bySign(5, 2), normal, u.w)
+	return expr.NewLess(abs, c(1), bySign(4, 3), subnormal, u.w)
+}
+
+// floatSemantics returns semantics of floating point instructions of format u
+// which are common to all formats.
+func floatSemantics(u fpu) map[string]semantics {
+	binaryRegs := floatRegSet(rd, rs1, rs2)
+
+	arith := func(op expr.FloatOp) semantics {
+		return semantics{
+			floatRegs: binaryRegs,
+			effects: func(i instruction) []expr.Effect {
+				f := expr.NewFloat(op, u.rounding(i), u.w, u.load(rs1, i), u.load(rs2, i))
+				return u.floatResult(f, i)
+			},
+		}
+	}
+
+	// Fused multiply-add instructions differ only in negation of their
+	// arguments. Negation of floating point value is exact, so it doesn't
+	// affect rounding of the result.
+	fused := func(negProduct bool, negAddend bool) semantics {
+		return semantics{
+			floatRegs: floatRegSet(rd, rs1, rs2, rs3),
+			effects: func(i instruction) []expr.Effect {
+				a1, a2, a3 := u.load(rs1, i), u.load(rs2, i), u.load(rs3, i)
+				if negProduct {
+					a1 = exprtools.FloatNegate(a1, u.w)
+				}
+				if negAddend {
+					a3 = exprtools.FloatNegate(a3, u.w)
+				}
+
+				f := expr.NewFloat(expr.FMulAdd, u.rounding(i), u.w, a1, a2, a3)
+				return u.floatResult(f, i)
+			},
+		}
+	}
+
+	signInject := func(sign func(e1, e2 expr.Expr) expr.Expr) semantics {
+		return semantics{
+			floatRegs: binaryRegs,
+			effects: func(i instruction) []expr.Effect {
+				a1, a2 := u.load(rs1, i), u.load(rs2, i)
+				val := exprtools.FloatCopySign(a1, sign(a1, a2), u.w)
+				return []expr.Effect{u.store(val, i)}
+			},
+		}
+	}
+
+	// Comparisons and minimum/maximum don't round, so any rounding mode
+	// would do.
+	rne := expr.RoundNearestEven.Const()
+
+	minMax := func(op expr.FloatOp) semantics {
+		return semantics{
+			floatRegs: binaryRegs,
+			effects: func(i instruction) []expr.Effect {
+				f := expr.NewFloat(op, rne, u.w, u.load(rs1, i), u.load(rs2, i))
+				return u.floatResult(f, i)
+			},
+		}
+	}
+
+	compare := func(op expr.FloatOp) semantics {
+		return semantics{
+			floatRegs: floatRegSet(rs1, rs2),
+			effects: func(i instruction) []expr.Effect {
+				f := expr.NewFloat(op, rne, u.w, u.load(rs1, i), u.load(rs2, i))
+				return []expr.Effect{regStore(f, i, u.xlen), accrue(f)}
+			},
+		}
+	}
+
+	toInt := func(op expr.FloatOp, w expr.Width) semantics {
+		return semantics{
+			floatRegs: floatRegSet(rs1),
+			effects: func(i instruction) []expr.Effect {
+				f := expr.NewFloatConversion(op, u.rounding(i), u.load(rs1, i), u.w, w)
+				return u.intResult(f, i)
+			},
+		}
+	}
+
+	fromInt := func(op expr.FloatOp, w expr.Width) semantics {
+		// Conversion of a 32 bit integer to double precision is always
+		// exact. Assemblers don't accept rounding mode of such
+		// conversions and they encode the field as zero.
+		exact := u.w == width64 && w == width32
+
+		return semantics{
+			floatRegs: floatRegSet(rd),
+			exact:     exact,
+			effects: func(i instruction) []expr.Effect {
+				var rm expr.Expr = rne
+				if !exact {
+					rm = u.rounding(i)
+				}
+
+				f := expr.NewFloatConversion(op, rm, regLoad(rs1, i, w), w, u.w)
+				return u.floatResult(f, i)
+			},
+		}
+	}
+
+	sems := map[string]semantics{
+		"fl" + u.intSuffix: {
+			floatRegs: floatRegSet(rd),
+			loadBytes: uint8(u.w),
+			effects: func(i instruction) []expr.Effect {
+				addr := regImmOp(binOpFunc(expr.Add), immTypeI, i, u.xlen)
+				return []expr.Effect{u.store(memLoad(addr, u.w), i)}
+			},
+		},
+		"fs" + u.intSuffix: {
+			floatRegs:  floatRegSet(rs2),
+			storeBytes: uint8(u.w),
+			effects: func(i instruction) []expr.Effect {
+				// Stores copy bits of registers regardless of
+				// NaN-boxing.
+				val := expr.NewRegLoad(fregKey(rs2, i), u.w)
+				addr := regImmOp(binOpFunc(expr.Add), immTypeS, i, u.xlen)
+				return []expr.Effect{memStore(val, addr, u.w)}
+			},
+		},
+
+		"fmadd." + u.suffix:  fused(false, false),
+		"fmsub." + u.suffix:  fused(false, true),
+		"fnmsub." + u.suffix: fused(true, false),
+		"fnmadd." + u.suffix: fused(true, true),
+
+		"fadd." + u.suffix: arith(expr.FAdd),
+		"fsub." + u.suffix: arith(expr.FSub),
+		"fmul." + u.suffix: arith(expr.FMul),
+		"fdiv." + u.suffix: arith(expr.FDiv),
+		"fsqrt." + u.suffix: {
+			floatRegs: floatRegSet(rd, rs1),
+			effects: func(i instruction) []expr.Effect {
+				f := expr.NewFloat(expr.FSqrt, u.rounding(i), u.w, u.load(rs1, i))
+				return u.floatResult(f, i)
+			},
+		},
+
+		"fsgnj." + u.suffix: signInject(func(_, e2 expr.Expr) expr.Expr {
+			return e2
+		}),
+		"fsgnjn." + u.suffix: signInject(func(_, e2 expr.Expr) expr.Expr {
+			return exprtools.FloatNegate(e2, u.w)
+		}),
+		"fsgnjx." + u.suffix: signInject(func(e1, e2 expr.Expr) expr.Expr {
+			return exprtools.BitXor(e1, e2, u.w)
+		}),
+
+		"fmin." + u.suffix: minMax(expr.FMin),
+		"fmax." + u.suffix: minMax(expr.FMax),
+
+		"feq." + u.suffix: compare(expr.FEq),
+		"flt." + u.suffix: compare(expr.FLt),
+		"fle." + u.suffix: compare(expr.FLe),
+
+		"fcvt.w." + u.suffix:       toInt(expr.FToInt, width32),
+		"fcvt.wu." + u.suffix:      toInt(expr.FToUint, width32),
+		"fcvt." + u.suffix + ".w":  fromInt(expr.FFromInt, width32),
+		"fcvt." + u.suffix + ".wu": fromInt(expr.FFromUint, width32),
+
+		"fclass." + u.suffix: {
+			floatRegs: floatRegSet(rs1),
+			effects: func(i instruction) []expr.Effect {
+				return []expr.Effect{regStore(u.class(u.load(rs1, i)), i, u.xlen)}
+			},
+		},
+	}
+
+	if u.xlen >= width64 {
+		sems["fcvt.l."+u.suffix] = toInt(expr.FToInt, width64)
+		sems["fcvt.lu."+u.suffix] = toInt(expr.FToUint, width64)
+		sems["fcvt."+u.suffix+".l"] = fromInt(expr.FFromInt, width64)
+		sems["fcvt."+u.suffix+".lu"] = fromInt(expr.FFromUint, width64)
+	}
+
+	// Moves in between integer and floating point registers copy bits of
+	// registers without any interpretation. Consequently, they are
+	// available only if the integer register is wide enough.
+	if u.xlen >= u.w {
+		sems["fmv.x."+u.intSuffix] = semantics{
+			floatRegs: floatRegSet(rs1),
+			effects: func(i instruction) []expr.Effect {
+				var val expr.Expr = expr.NewRegLoad(fregKey(rs1, i), u.w)
+				if u.w < u.xlen {
+					val = sext(val, uint8(u.w.Bits()-1), u.xlen)
+				}
+				return []expr.Effect{regStore(val, i, u.xlen)}
+			},
+		}
+		sems["fmv."+u.intSuffix+".x"] = semantics{
+			floatRegs: floatRegSet(rd),
+			effects: func(i instruction) []expr.Effect {
+				return []expr.Effect{u.store(regLoad(rs1, i, u.w), i)}
+			},
+		}
+	}
+
+	return sems
+}
+
+// singleInstructions returns instructions of F extension described by u.
+func singleInstructions(u fpu) []*instructionType {
+	if u.xlen == width32 {
+		return tableInstructions(floatSemantics(u), "rv_f")
+	}
+	return tableInstructions(floatSemantics(u), "rv_f", "rv64_f")
+}
+
+// doubleInstructions returns instructions of D extension for processor with
+// integer registers of width xlen.
+func doubleInstructions(xlen expr.Width) []*instructionType {
+	single, double := singleFPU(xlen, width64), doubleFPU(xlen)
+
+	sems := floatSemantics(double)
+	sems["fcvt.s.d"] = semantics{
+		floatRegs: floatRegSet(rd, rs1),
+		effects: func(i instruction) []expr.Effect {
+			f := expr.NewFloatConversion(expr.FConvert, single.rounding(i),
+				double.load(rs1, i), width64, width32)
+			return single.floatResult(f, i)
+		},
+	}
+	// Widening conversion is exact, so it has no rounding mode operand
+	// even though it has the field.
+	sems["fcvt.d.s"] = semantics{
+		floatRegs: floatRegSet(rd, rs1),
+		exact:     true,
+		effects: func(i instruction) []expr.Effect {
+			f := expr.NewFloatConversion(expr.FConvert,
+				expr.RoundNearestEven.Const(),
+				single.load(rs1, i), width32, width64)
+			return double.floatResult(f, i)
+		},
+	}
+
+	if xlen == width32 {
+		return tableInstructions(sems, "rv_d")
+	}
+	return tableInstructions(sems, "rv_d", "rv64_d")
+}
+
+// nanBoxedSingle lists instructions of F extension of processors implementing
+// D extension as well. Such processors have 8 bytes wide floating point
+// registers, so single precision values are NaN-boxed in registers.
+var nanBoxedSingle = map[Variant][]*instructionType{
+	Variant32: singleInstructions(singleFPU(width32, width64)),
+	Variant64: singleInstructions(singleFPU(width64, width64)),
+}
@@ -0,0 +1,263 @@
+package riscv
+
+import (
+	"fmt"
+	"math"
+	"mltwist/internal/archtest"
+	"mltwist/internal/exprtransform"
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNanBoxedSingle(t *testing.T) {
+	for v, instrs := range nanBoxedSingle {
+		instrs := instrs
+		t.Run(fmt.Sprintf("arch_%v", v), func(t *testing.T) {
+			single := instructions[v][ExtF]
+			require.Len(t, instrs, len(single))
+
+			all := append(append([]*instructionType{}, instrs...),
+				instructions[v][ExtD]...)
+			archtest.UniqueOpcodes(t, all)
+			assertUniqueNames(t, all)
+			assertValidOpcode(t, maxFloatBytes, instrs)
+		})
+	}
+}
+
+// boxed returns single precision value f NaN-boxed in a 64 bit register.
+func boxed(f float32) uint64 { return 0xffffffff00000000 | uint64(math.Float32bits(f)) }
+
+// evalRegs evaluates register stores of an instruction s with registers set to
+// regs. Registers not present in regs are zero.
+func evalRegs(t testing.TB, v Variant, s string, regs map[expr.Key]uint64) map[expr.Key]uint64 {
+	bs, err := NewEncoder(v, allExtensions()...).Assemble(0x1000, s)
+	require.NoError(t, err)
+
+	ins, err := NewParser(v, allExtensions()...).Parse(0x1000, bs)
+	require.NoError(t, err)
+
+	res := make(map[expr.Key]uint64, len(ins.Effects))
+	for _, ef := range ins.Effects {
+		store := ef.(expr.RegStore)
+		ex := exprtransform.ReplaceAll(store.Value(), func(r expr.RegLoad) (expr.Expr, bool) {
+			c := expr.NewConstUint(regs[r.Key()], expr.Width64)
+			return exprtransform.SetWidth(c, r.Width()), true
+		})
+
+		c, ok := exprtransform.ConstFold(ex).(expr.Const)
+		require.True(t, ok)
+
+		res[store.Key()], _ = expr.ConstUint[uint64](exprtransform.SetWidth(c, store.Width()).(expr.Const))
+	}
+
+	return res
+}
+
+func TestFloatEffects(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		s       string
+		regs    map[expr.Key]uint64
+		want    map[expr.Key]uint64
+	}{{
+		name:    "fadd.s",
+		variant: Variant64,
+		s:       "fadd.s f1, f2, f3",
+		regs:    map[expr.Key]uint64{"f2": boxed(1.5), "f3": boxed(2.25)},
+		want:    map[expr.Key]uint64{"f1": boxed(3.75), "fcsr": 0},
+	}, {
+		name:    "fadd.s_not_boxed",
+		variant: Variant64,
+		s:       "fadd.s f1, f2, f3",
+		regs: map[expr.Key]uint64{
+			"f2": uint64(math.Float32bits(1.5)),
+			"f3": boxed(2.25),
+		},
+		want: map[expr.Key]uint64{"f1": boxed(float32(math.NaN())), "fcsr": 0},
+	}, {
+		name:    "fdiv.d_by_zero",
+		variant: Variant32,
+		s:       "fdiv.d f1, f2, f3",
+		regs: map[expr.Key]uint64{
+			"f2":   math.Float64bits(-1),
+			"fcsr": 0x01,
+		},
+		want: map[expr.Key]uint64{
+			"f1":   math.Float64bits(math.Inf(-1)),
+			"fcsr": 0x01 | uint64(expr.FloatDivByZero),
+		},
+	}, {
+		name:    "dynamic_rounding",
+		variant: Variant64,
+		s:       "fadd.d f1, f2, f3",
+		regs: map[expr.Key]uint64{
+			"f2":   math.Float64bits(1),
+			"f3":   math.Float64bits(0x1p-60),
+			"fcsr": uint64(expr.RoundUp) << 5,
+		},
+		want: map[expr.Key]uint64{
+			"f1":   math.Float64bits(1 + 0x1p-52),
+			"fcsr": uint64(expr.RoundUp)<<5 | uint64(expr.FloatInexact),
+		},
+	}, {
+		name:    "fnmadd.d",
+		variant: Variant64,
+		s:       "fnmadd.d f1, f2, f3, f4, rne",
+		regs: map[expr.Key]uint64{
+			"f2": math.Float64bits(2),
+			"f3": math.Float64bits(3),
+			"f4": math.Float64bits(1),
+		},
+		want: map[expr.Key]uint64{"f1": math.Float64bits(-7), "fcsr": 0},
+	}, {
+		name:    "fcvt.w.s_sign_extended",
+		variant: Variant64,
+		s:       "fcvt.w.s x1, f2, rtz",
+		regs:    map[expr.Key]uint64{"f2": boxed(-2.75)},
+		want: map[expr.Key]uint64{
+			"x1":   uint64(math.MaxUint64) - 1,
+			"fcsr": uint64(expr.FloatInexact),
+		},
+	}, {
+		name:    "fcvt.s.d",
+		variant: Variant64,
+		s:       "fcvt.s.d f1, f2",
+		regs:    map[expr.Key]uint64{"f2": math.Float64bits(0.1)},
+		want: map[expr.Key]uint64{
+			"f1":   boxed(0.1),
+			"fcsr": uint64(expr.FloatInexact),
+		},
+	}, {
+		name:    "flt.d_nan",
+		variant: Variant64,
+		s:       "flt.d x1, f2, f3",
+		regs:    map[expr.Key]uint64{"f2": math.Float64bits(math.NaN())},
+		want: map[expr.Key]uint64{
+			"x1":   0,
+			"fcsr": uint64(expr.FloatInvalid),
+		},
+	}, {
+		name:    "fsgnjn.s",
+		variant: Variant64,
+		s:       "fsgnjn.s f1, f2, f3",
+		regs:    map[expr.Key]uint64{"f2": boxed(-1.5), "f3": boxed(2)},
+		want:    map[expr.Key]uint64{"f1": boxed(-1.5)},
+	}, {
+		name:    "fmv.x.w",
+		variant: Variant64,
+		s:       "fmv.x.w x1, f2",
+		regs:    map[expr.Key]uint64{"f2": 0x12345678_80000000},
+		want:    map[expr.Key]uint64{"x1": 0xffffffff_80000000},
+	}, {
+		name:    "fclass.d_negative_subnormal",
+		variant: Variant32,
+		s:       "fclass.d x1, f2",
+		regs:    map[expr.Key]uint64{"f2": 0x80000000_00000001},
+		want:    map[expr.Key]uint64{"x1": 1 << 2},
+	}, {
+		name:    "fclass.s_signaling_nan",
+		variant: Variant64,
+		s:       "fclass.s x1, f2",
+		regs:    map[expr.Key]uint64{"f2": boxed(math.Float32frombits(0x7f800001))},
+		want:    map[expr.Key]uint64{"x1": 1 << 8},
+	}, {
+		name:    "fclass.s_positive_infinity",
+		variant: Variant64,
+		s:       "fclass.s x1, f2",
+		regs:    map[expr.Key]uint64{"f2": boxed(float32(math.Inf(1)))},
+		want:    map[expr.Key]uint64{"x1": 1 << 7},
+	}, {
+		name:    "csrrw_frm",
+		variant: Variant64,
+		s:       "csrrw x1, 2, x2",
+		regs:    map[expr.Key]uint64{"x2": 0xff, "fcsr": 0x5f},
+		want:    map[expr.Key]uint64{"x1": 0x2, "fcsr": 0xff},
+	}, {
+		name:    "csrrs_fflags",
+		variant: Variant32,
+		s:       "csrrs x1, 1, x2",
+		regs:    map[expr.Key]uint64{"x2": 0x3, "fcsr": 0xe4},
+		want:    map[expr.Key]uint64{"x1": 0x4, "fcsr": 0xe7},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			res := evalRegs(t, tt.variant, tt.s, tt.regs)
+			require.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestParser_Float(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		exts    []Extension
+		bytes   []byte
+		str     string
+		hasErr  bool
+	}{{
+		name:    "fmadd.s",
+		variant: Variant64,
+		exts:    []Extension{ExtF},
+		bytes:   []byte{0xc3, 0x92, 0x15, 0xe9},
+		str:     "fmadd.s f5, f11, f17, f29, rtz",
+	}, {
+		name:    "fcvt.l.d",
+		variant: Variant64,
+		exts:    []Extension{ExtD},
+		bytes:   []byte{0xd3, 0xf2, 0x25, 0xc2},
+		str:     "fcvt.l.d x5, f11",
+	}, {
+		name:    "fcvt.l.d_rv32",
+		variant: Variant32,
+		exts:    []Extension{ExtF, ExtD},
+		bytes:   []byte{0xd3, 0xf2, 0x25, 0xc2},
+		hasErr:  true,
+	}, {
+		name:    "fld_without_d",
+		variant: Variant64,
+		exts:    []Extension{ExtF},
+		bytes:   []byte{0x87, 0xb2, 0x85, 0xff},
+		hasErr:  true,
+	}, {
+		name:    "c.fldsp",
+		variant: Variant64,
+		exts:    []Extension{ExtC, ExtD},
+		bytes:   []byte{0x62, 0x20},
+		str:     "fld f0, 24(x2)",
+	}, {
+		name:    "c.flw",
+		variant: Variant32,
+		exts:    []Extension{ExtC, ExtF},
+		bytes:   []byte{0x64, 0x7e},
+		str:     "flw f9, 124(x12)",
+	}, {
+		name:    "c.fsdsp_without_d",
+		variant: Variant32,
+		exts:    []Extension{ExtC, ExtF},
+		bytes:   []byte{0xaa, 0xbf},
+		hasErr:  true,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			p := NewParser(tt.variant, tt.exts...)
+
+			ins, err := p.Parse(0x1000, tt.bytes)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tt.str, ins.Details.String())
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package riscv
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"mltwist/pkg/model"
+)
+
+// sext32 sign extends 32 bit value e to fill a register of width xlen.
+func sext32(e expr.Expr, xlen expr.Width) expr.Expr {
+	if xlen == width32 {
+		return e
+	}
+	return sext32To64(e)
+}
+
+// regResult returns effects function of an instruction which stores value
+// returned by f into its output register of width xlen.
+func regResult(f func(i instruction) expr.Expr, xlen expr.Width) func(i instruction) []expr.Effect {
+	return func(i instruction) []expr.Effect {
+		return []expr.Effect{regStore(f(i), i, xlen)}
+	}
+}
+
+// noEffects is an effects function of instructions without any effect on
+// registers or memory.
+func noEffects(i instruction) []expr.Effect { return nil }
+
+// integerInstructions returns instructions of base integer instruction set
+// including Zicsr and Zifencei extensions of a processor with registers of
+// width xlen.
+func integerInstructions(xlen expr.Width) []*instructionType {
+	bits := shamtBits(xlen)
+
+	regImm := func(f binaryExprFunc) semantics {
+		return semantics{effects: regResult(func(i instruction) expr.Expr {
+			return regImmOp(f, immTypeI, i, xlen)
+		}, xlen)}
+	}
+	reg2 := func(f binaryExprFunc) semantics {
+		return semantics{effects: regResult(func(i instruction) expr.Expr {
+			return reg2Op(f, i, xlen)
+		}, xlen)}
+	}
+	shiftImm := func(f binaryExprFunc) semantics {
+		return semantics{effects: regResult(func(i instruction) expr.Expr {
+			return regImmShift(f, i, bits, xlen)
+		}, xlen)}
+	}
+	shiftReg := func(f binaryExprFunc) semantics {
+		return semantics{effects: regResult(func(i instruction) expr.Expr {
+			return maskedRegOp(f, i, bits, xlen)
+		}, xlen)}
+	}
+	setLess := func(f condExprFunc, imm bool) semantics {
+		return semantics{effects: regResult(func(i instruction) expr.Expr {
+			var e2 expr.Expr = regLoad(rs2, i, xlen)
+			if imm {
+				e2 = immConst(immTypeI, i)
+			}
+			return f(regLoad(rs1, i, xlen), e2, expr.One, expr.Zero, xlen)
+		}, xlen)}
+	}
+	branch := func(f condExprFunc, branchIfTrue bool) semantics {
+		return semantics{
+			pcRelative: true,
+			effects: func(i instruction) []expr.Effect {
+				return []expr.Effect{branchCmp(f, branchIfTrue, i, xlen)}
+			},
+		}
+	}
+	// Values narrower than a register are sign extended by signed loads
+	// and zero extended otherwise.
+	load := func(w expr.Width, signed bool) semantics {
+		return semantics{
+			loadBytes: uint8(w),
+			effects: regResult(func(i instruction) expr.Expr {
+				addr := regImmOp(binOpFunc(expr.Add), immTypeI, i, xlen)
+				val := memLoad(addr, w)
+				if signed && w < xlen {
+					val = sext(val, 8*uint8(w)-1, xlen)
+				}
+				return val
+			}, xlen),
+		}
+	}
+	store := func(w expr.Width) semantics {
+		return semantics{
+			storeBytes: uint8(w),
+			effects: func(i instruction) []expr.Effect {
+				val := regLoad(rs2, i, xlen)
+				addr := regImmOp(binOpFunc(expr.Add), immTypeS, i, xlen)
+				return []expr.Effect{memStore(val, addr, w)}
+			},
+		}
+	}
+	csrOp := func(f func(val expr.Expr, i instruction) expr.Expr) semantics {
+		return semantics{effects: func(i instruction) []expr.Effect {
+			if csrTraps(i) {
+				return nil
+			}
+
+			val := csrRead(i, xlen)
+			return []expr.Effect{
+				regStore(val, i, xlen),
+				csrWrite(f(val, i), i, xlen),
+			}
+		}}
+	}
+
+	sems := map[string]semantics{
+		"lui": {effects: regResult(func(i instruction) expr.Expr {
+			imm, _ := immTypeU.parseValue(i.value)
+			return sext32(expr.ConstFromInt(imm), xlen)
+		}, xlen)},
+		"auipc": {
+			pcRelative: true,
+			effects: regResult(func(i instruction) expr.Expr {
+				imm, _ := immTypeU.parseValue(i.value)
+				return addrConst(addrAddImm(i.addr, imm), xlen)
+			}, xlen),
+		},
+		"jal": {
+			pcRelative: true,
+			effects: func(i instruction) []expr.Effect {
+				target := addrImmConst(immTypeJ, i, xlen)
+				// Address of following instruction.
+				following := addrConst(i.addr+i.len(), xlen)
+				return []expr.Effect{
+					expr.NewRegStore(target, expr.IPKey, xlen),
+					regStore(following, i, xlen),
+				}
+			},
+		},
+		// FIXME: Find a way how to represent those jump targets.
+		"jalr": {
+			offsetSyntax: true,
+			effects: func(i instruction) []expr.Effect {
+				target := regImmOp(binOpFunc(expr.Add), immTypeI, i, xlen)
+				// Address of following instruction.
+				following := addrConst(i.addr+i.len(), xlen)
+				return []expr.Effect{
+					expr.NewRegStore(target, expr.IPKey, xlen),
+					regStore(following, i, xlen),
+				}
+			},
+		},
+
+		"beq":  branch(exprtools.Eq, true),
+		"bne":  branch(exprtools.Eq, false),
+		"blt":  branch(exprtools.Lts, true),
+		"bge":  branch(exprtools.Lts, false),
+		"bltu": branch(lessFunc, true),
+		"bgeu": branch(lessFunc, false),
+
+		"lb":  load(width8, true),
+		"lh":  load(width16, true),
+		"lw":  load(width32, true),
+		"lbu": load(width8, false),
+		"lhu": load(width16, false),
+		"sb":  store(width8),
+		"sh":  store(width16),
+		"sw":  store(width32),
+
+		"addi":  regImm(binOpFunc(expr.Add)),
+		"slti":  setLess(exprtools.Lts, true),
+		"sltiu": setLess(lessFunc, true),
+		"xori":  regImm(exprtools.BitXor),
+		"ori":   regImm(exprtools.BitOr),
+		"andi":  regImm(exprtools.BitAnd),
+		"slli":  shiftImm(binOpFunc(expr.Lsh)),
+		"srli":  shiftImm(binOpFunc(expr.Rsh)),
+		"srai":  shiftImm(exprtools.RshA),
+
+		"add":  reg2(binOpFunc(expr.Add)),
+		"sub":  reg2(exprtools.Sub),
+		"slt":  setLess(exprtools.Lts, false),
+		"sltu": setLess(lessFunc, false),
+		"or":   reg2(exprtools.BitOr),
+		"and":  reg2(exprtools.BitAnd),
+		"xor":  reg2(exprtools.BitXor),
+		"sll":  shiftReg(binOpFunc(expr.Lsh)),
+		"srl":  shiftReg(binOpFunc(expr.Rsh)),
+		"sra":  shiftReg(exprtools.RshA),
+
+		"fence": {effects: noEffects},
+		// Total store ordering fence orders loads before all later
+		// memory accesses and stores before later stores. Ordering of
+		// a store before a later load is relaxed. The order can't be
+		// expressed by a single pair of predecessor and successor sets,
+		// so the fence is conservatively a full barrier.
+		"fence.tso": {instrType: model.TypeMemOrder, effects: noEffects},
+		"fence.i":   {instrType: model.TypeMemOrder, effects: noEffects},
+		"ecall":     {instrType: model.TypeSyscall, effects: noEffects},
+		"ebreak":    {instrType: model.TypeSyscall, effects: noEffects},
+
+		"csrrw": csrOp(func(_ expr.Expr, i instruction) expr.Expr {
+			return regLoad(rs1, i, xlen)
+		}),
+		"csrrs": csrOp(func(val expr.Expr, i instruction) expr.Expr {
+			return exprtools.BitOr(val, regLoad(rs1, i, xlen), xlen)
+		}),
+		"csrrc": csrOp(func(val expr.Expr, i instruction) expr.Expr {
+			mask := exprtools.BitNot(regLoad(rs1, i, xlen), xlen)
+			return exprtools.BitAnd(val, mask, xlen)
+		}),
+		"csrrwi": csrOp(func(_ expr.Expr, i instruction) expr.Expr {
+			return csrImm(i)
+		}),
+		"csrrsi": csrOp(func(val expr.Expr, i instruction) expr.Expr {
+			return exprtools.BitOr(val, csrImm(i), xlen)
+		}),
+		"csrrci": csrOp(func(val expr.Expr, i instruction) expr.Expr {
+			mask := exprtools.BitNot(csrImm(i), xlen)
+			return exprtools.BitAnd(val, mask, xlen)
+		}),
+	}
+
+	tables := []string{"rv_i", "rv_zicsr", "rv_zifencei"}
+	if xlen == width32 {
+		return tableInstructions(sems, append(tables, "rv32_i")...)
+	}
+
+	// Word instructions operate on the lower 32 bits of registers and sign
+	// extend the result.
+	word := func(f func(i instruction) expr.Expr) semantics {
+		return semantics{effects: regResult(func(i instruction) expr.Expr {
+			return sext32To64(f(i))
+		}, xlen)}
+	}
+	shiftWord := func(f binaryExprFunc, imm bool) semantics {
+		return word(func(i instruction) expr.Expr {
+			if imm {
+				return regImmShift(f, i, 5, width32)
+			}
+			return maskedRegOp(f, i, 5, width32)
+		})
+	}
+
+	sems["ld"] = load(width64, true)
+	sems["lwu"] = load(width32, false)
+	sems["sd"] = store(width64)
+
+	sems["addiw"] = word(func(i instruction) expr.Expr {
+		return regImmOp(binOpFunc(expr.Add), immTypeI, i, width32)
+	})
+	sems["slliw"] = shiftWord(binOpFunc(expr.Lsh), true)
+	sems["srliw"] = shiftWord(binOpFunc(expr.Rsh), true)
+	sems["sraiw"] = shiftWord(exprtools.RshA, true)
+	sems["addw"] = word(func(i instruction) expr.Expr {
+		return reg2Op(binOpFunc(expr.Add), i, width32)
+	})
+	sems["subw"] = word(func(i instruction) expr.Expr {
+		return reg2Op(exprtools.Sub, i, width32)
+	})
+	sems["sllw"] = shiftWord(binOpFunc(expr.Lsh), false)
+	sems["srlw"] = shiftWord(binOpFunc(expr.Rsh), false)
+	sems["sraw"] = shiftWord(exprtools.RshA, false)
+
+	return tableInstructions(sems, append(tables, "rv64_i")...)
+}
@@ -0,0 +1,71 @@
+package riscv
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// mulInstructions returns instructions of M (integer multiplication and
+// division) extension of a processor with registers of width xlen.
+func mulInstructions(xlen expr.Width) []*instructionType {
+	// Upper half of a product is computed in double width and shifted right
+	// by xlen bits.
+	double := 2 * xlen
+	shift := expr.ConstFromUint(8 * uint8(xlen))
+
+	// Operations on values narrower than a register (word instructions of
+	// RV64) sign extend the result.
+	op := func(f func(r1, r2 expr.Expr) expr.Expr, w expr.Width) semantics {
+		return semantics{effects: regResult(func(i instruction) expr.Expr {
+			val := f(regLoad(rs1, i, w), regLoad(rs2, i, w))
+			if w < xlen {
+				val = sext32To64(val)
+			}
+			return val
+		}, xlen)}
+	}
+	binOp := func(f binaryExprFunc, w expr.Width) semantics {
+		return op(func(r1, r2 expr.Expr) expr.Expr { return f(r1, r2, w) }, w)
+	}
+
+	sems := map[string]semantics{
+		"mul": binOp(binOpFunc(expr.Mul), xlen),
+		"mulh": op(func(r1, r2 expr.Expr) expr.Expr {
+			mul := exprtools.SignedMul(r1, r2, xlen)
+			shifted := expr.NewBinary(expr.Rsh, mul, shift, double)
+			return exprtools.NewWidthGadget(shifted, xlen)
+		}, xlen),
+		"mulhu": op(func(r1, r2 expr.Expr) expr.Expr {
+			mul := expr.NewBinary(expr.Mul, r1, r2, double)
+			shifted := expr.NewBinary(expr.Rsh, mul, shift, double)
+			return exprtools.NewWidthGadget(shifted, xlen)
+		}, xlen),
+		"mulhsu": op(func(r1, r2 expr.Expr) expr.Expr {
+			r1Abs := exprtools.Abs(r1, xlen)
+			mul := expr.NewBinary(expr.Mul, r1Abs, r2, double)
+			shifted := expr.NewBinary(expr.Rsh, mul, shift, double)
+			return exprtools.BoolCond(
+				exprtools.IntNegative(r1, xlen),
+				shifted,
+				exprtools.Negate(shifted, xlen),
+				xlen,
+			)
+		}, xlen),
+		"div":  binOp(exprtools.SignedDiv, xlen),
+		"divu": binOp(binOpFunc(expr.Div), xlen),
+		"rem":  binOp(exprtools.SignedMod, xlen),
+		"remu": binOp(exprtools.Mod, xlen),
+	}
+
+	if xlen == width32 {
+		return tableInstructions(sems, "rv_m")
+	}
+
+	sems["mulw"] = binOp(binOpFunc(expr.Mul), width32)
+	sems["divw"] = binOp(exprtools.SignedDiv, width32)
+	sems["divuw"] = binOp(binOpFunc(expr.Div), width32)
+	sems["remw"] = binOp(exprtools.SignedMod, width32)
+	sems["remuw"] = binOp(exprtools.Mod, width32)
+
+	return tableInstructions(sems, "rv_m", "rv64_m")
+}
@@ -0,0 +1,117 @@
+package riscv
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"mltwist/pkg/model"
+)
+
+// Fields of mstatus register used by trap return instructions. Values are bit
+// indices of the fields.
+const (
+	// statusSIE is supervisor interrupt enable bit.
+	statusSIE = 1
+	// statusMIE is machine interrupt enable bit.
+	statusMIE = 3
+	// statusSPIE is supervisor interrupt enable bit prior to the trap.
+	statusSPIE = 5
+	// statusMPIE is machine interrupt enable bit prior to the trap.
+	statusMPIE = 7
+	// statusSPP is supervisor previous privilege mode. The field is 1 bit
+	// wide.
+	statusSPP = 8
+	// statusMPP is machine previous privilege mode. The field is 2 bits
+	// wide.
+	statusMPP = 11
+	// statusMPRV is modify privilege bit. Loads and stores are translated
+	// as if the privilege mode was MPP when the bit is set.
+	statusMPRV = 17
+)
+
+// statusField returns bits [shift:shift+bits) of machine status register
+// value st of width w.
+func statusField(
+	st expr.Expr,
+	shift uint8,
+	bits exprtools.BitCnt,
+	w expr.Width,
+) expr.Expr {
+	shifted := expr.NewBinary(expr.Rsh, st, expr.ConstFromUint(shift), w)
+	return exprtools.MaskBits(shifted, bits, w)
+}
+
+// trapReturn returns effects of an instruction returning from a trap handler
+// to an address stored in CSR epc. The previous privilege mode is held in
+// ppBits wide field of mstatus at index pp, the interrupt enable bit and its
+// value prior to the trap are at indices ie and pie.
+//
+// The trap return restores the privilege mode and the interrupt enable bit,
+// sets the prior interrupt enable bit and sets the previous privilege mode to
+// user mode. MPRV bit is cleared if the new privilege mode is not machine
+// mode. All the fields are in the lower half of mstatus on RV32, so mstatus is
+// accessed with width xlen.
+func trapReturn(epc csr, pp, ppBits, ie, pie uint8, xlen expr.Width) []expr.Effect {
+	st := expr.NewRegLoad(mstatusKey, xlen)
+	prev := statusField(st, pp, exprtools.BitCnt(ppBits), xlen)
+
+	clear := uint64(1)<<ie | uint64(1)<<pie | (uint64(1)<<ppBits-1)<<pp
+	newSt := exprtools.BitOr(
+		exprtools.BitAnd(st, expr.NewConstUint(^clear&widthMask(xlen), xlen), xlen),
+		expr.NewBinary(
+			expr.Lsh,
+			statusField(st, pie, 1, xlen),
+			expr.ConstFromUint(ie),
+			xlen,
+		),
+		xlen,
+	)
+	newSt = exprtools.BitOr(newSt, expr.NewConstUint(uint64(1)<<pie, xlen), xlen)
+
+	mprv := expr.NewConstUint(^(uint64(1)<<statusMPRV)&widthMask(xlen), xlen)
+	newSt = exprtools.Eq(
+		prev,
+		expr.ConstFromUint(uint8(PrivilegeMachine)),
+		newSt,
+		exprtools.BitAnd(newSt, mprv, xlen),
+		xlen,
+	)
+
+	return []expr.Effect{
+		expr.NewRegStore(expr.NewRegLoad(expr.Key(epc.String()), xlen), expr.IPKey, xlen),
+		expr.NewRegStore(prev, PrivilegeKey, privilegeWidth),
+		expr.NewRegStore(newSt, mstatusKey, xlen),
+	}
+}
+
+// privInstructions returns privileged instructions of machine and supervisor
+// mode of a processor with registers of width xlen.
+func privInstructions(xlen expr.Width) []*instructionType {
+	return tableInstructions(map[string]semantics{
+		"mret": {
+			instrType: model.TypeCPUStateChange,
+			effects: func(i instruction) []expr.Effect {
+				return trapReturn(csrMepc, statusMPP, 2, statusMIE, statusMPIE, xlen)
+			},
+		},
+		"sret": {
+			instrType: model.TypeCPUStateChange,
+			effects: func(i instruction) []expr.Effect {
+				return trapReturn(csrSepc, statusSPP, 1, statusSIE, statusSPIE, xlen)
+			},
+		},
+		// Waiting for an interrupt is a valid implementation of wfi
+		// as well as a no-op is. But interrupt handlers might change
+		// anything, so the instruction cannot be moved.
+		"wfi": {
+			instrType: model.TypeCPUStateChange,
+			effects:   noEffects,
+		},
+		// The instruction orders stores to page tables with implicit
+		// memory accesses of address translation. Address translation
+		// is not modelled, so the fence is a CPU state change.
+		"sfence.vma": {
+			instrType: model.TypeMemOrder | model.TypeCPUStateChange,
+			effects:   noEffects,
+		},
+	}, "rv_system", "rv_s")
+}
@@ -0,0 +1,148 @@
+package riscv
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_Priv(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		s     string
+		typ   model.Type
+	}{{
+		name:  "mret",
+		bytes: []byte{0x73, 0x00, 0x20, 0x30},
+		s:     "mret",
+		typ:   model.TypeCPUStateChange,
+	}, {
+		name:  "sret",
+		bytes: []byte{0x73, 0x00, 0x20, 0x10},
+		s:     "sret",
+		typ:   model.TypeCPUStateChange,
+	}, {
+		name:  "wfi",
+		bytes: []byte{0x73, 0x00, 0x50, 0x10},
+		s:     "wfi",
+		typ:   model.TypeCPUStateChange,
+	}, {
+		name:  "sfence.vma",
+		bytes: []byte{0x73, 0x00, 0xb5, 0x12},
+		s:     "sfence.vma x10, x11",
+		typ:   model.TypeMemOrder | model.TypeCPUStateChange,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			_, err := NewParser(Variant64).Parse(0x1000, tt.bytes)
+			r.Error(err)
+
+			ins, err := NewParser(Variant64, ExtPriv).Parse(0x1000, tt.bytes)
+			r.NoError(err)
+			r.Equal(tt.typ, ins.Type)
+			r.Equal(tt.s, ins.Details.(instruction).String())
+		})
+	}
+}
+
+func TestPrivEffects(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		s       string
+		regs    map[expr.Key]uint64
+		want    map[expr.Key]uint64
+	}{{
+		name:    "mret_to_supervisor",
+		variant: Variant64,
+		s:       "mret",
+		regs: map[expr.Key]uint64{
+			"mepc":    0x80001000,
+			"mstatus": 1<<statusMPRV | 1<<statusMPP | 1<<statusMPIE,
+		},
+		want: map[expr.Key]uint64{
+			expr.IPKey:   0x80001000,
+			PrivilegeKey: uint64(PrivilegeSupervisor),
+			"mstatus":    1<<statusMPIE | 1<<statusMIE,
+		},
+	}, {
+		name:    "mret_to_machine",
+		variant: Variant32,
+		s:       "mret",
+		regs: map[expr.Key]uint64{
+			"mepc":    0x2000,
+			"mstatus": 1<<statusMPRV | 3<<statusMPP | 1<<statusMIE,
+		},
+		want: map[expr.Key]uint64{
+			expr.IPKey:   0x2000,
+			PrivilegeKey: uint64(PrivilegeMachine),
+			"mstatus":    1<<statusMPRV | 1<<statusMPIE,
+		},
+	}, {
+		name:    "sret_to_user",
+		variant: Variant64,
+		s:       "sret",
+		regs: map[expr.Key]uint64{
+			"sepc":    0x10000,
+			"mstatus": 1<<statusMPRV | 1<<statusSPIE | 1<<statusMIE,
+		},
+		want: map[expr.Key]uint64{
+			expr.IPKey:   0x10000,
+			PrivilegeKey: uint64(PrivilegeUser),
+			"mstatus":    1<<statusSPIE | 1<<statusSIE | 1<<statusMIE,
+		},
+	}, {
+		name:    "sstatus_view",
+		variant: Variant64,
+		s:       "csrrw x5, sstatus, x6",
+		regs: map[expr.Key]uint64{
+			"x6":      1<<statusSPP | 1<<statusMIE,
+			"mstatus": 3<<statusMPP | 1<<statusSIE,
+		},
+		want: map[expr.Key]uint64{
+			"x5":      1 << statusSIE,
+			"mstatus": 3<<statusMPP | 1<<statusSPP,
+		},
+	}, {
+		name:    "mstatush",
+		variant: Variant32,
+		s:       "csrrs x5, mstatush, x6",
+		regs: map[expr.Key]uint64{
+			"x6":       0x10,
+			"mstatus":  0x8,
+			"mstatush": 0x1,
+		},
+		want: map[expr.Key]uint64{
+			"x5":       0x1,
+			"mstatush": 0x11,
+		},
+	}, {
+		name:    "mret_rv32_all_bits_set",
+		variant: Variant32,
+		s:       "mret",
+		regs: map[expr.Key]uint64{
+			"mepc":    0x2000,
+			"mstatus": 0xffffffff,
+		},
+		want: map[expr.Key]uint64{
+			expr.IPKey:   0x2000,
+			PrivilegeKey: uint64(PrivilegeMachine),
+			"mstatus":    0xffffffff &^ (3 << statusMPP),
+		},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			res := evalRegs(t, tt.variant, tt.s, tt.regs)
+			require.Equal(t, tt.want, res)
+		})
+	}
+}
@@ -2,44 +2,22 @@ package riscv
 
 import (
 	"fmt"
-	"mltwist/internal/opcode"
+	"mltwist/internal/archtest"
+	"mltwist/internal/exprtransform"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 )
 
-type hashableOpcode struct {
-	Bytes string
-	Mask  string
-}
-
-func newHashableOpcode(o opcode.Opcode) hashableOpcode {
-	return hashableOpcode{
-		Bytes: fmt.Sprintf("%x", o.Bytes),
-		Mask:  fmt.Sprintf("%x", o.Mask),
-	}
-}
-
 func assertValidOpcode(t testing.TB, xlenBytes uint8, instrs []*instructionType) {
 	for _, ins := range instrs {
 		require.NoError(t, ins.validate(xlenBytes), ins.Name())
 	}
 }
 
-func assertUniqueOpcode(t testing.TB, instrs []*instructionType) {
-	opcodeSet := make(map[hashableOpcode]struct{}, len(instrs))
-	for i, ins := range instrs {
-		o := ins.Opcode()
-		h := newHashableOpcode(o)
-		if _, ok := opcodeSet[h]; ok {
-			require.Failf(t, "opcode is not unique", "%d: %#v (%#v)",
-				i, o, ins)
-		}
-		opcodeSet[h] = struct{}{}
-	}
-}
-
 func assertUniqueNames(t testing.TB, instrs []*instructionType) {
 	m := make(map[string]*instructionType, len(instrs))
 	for _, ins := range instrs {
@@ -49,6 +27,14 @@ func assertUniqueNames(t testing.TB, instrs []*instructionType) {
 	}
 }
 
+// assertLowerCaseNames asserts that all instruction names are lower case as
+// written by objdump and expected by the assembler.
+func assertLowerCaseNames(t testing.TB, instrs []*instructionType) {
+	for _, ins := range instrs {
+		require.Equal(t, strings.ToLower(ins.name), ins.name)
+	}
+}
+
 func TestInstructions(t *testing.T) {
 	for arch, exts := range instructions {
 		exts := exts
@@ -58,8 +44,9 @@ func TestInstructions(t *testing.T) {
 				allInstrs = append(allInstrs, instrs...)
 			}
 
-			assertUniqueOpcode(t, allInstrs)
+			archtest.UniqueOpcodes(t, allInstrs)
 			assertUniqueNames(t, allInstrs)
+			assertLowerCaseNames(t, allInstrs)
 
 			for ext, instrs := range exts {
 				instrs := instrs
@@ -97,12 +84,28 @@ func TestRV32ToRV64(t *testing.T) {
 	lists := []instrSet{
 		{
 			name: "integer",
-			rv32: integer32,
-			rv64: integer64,
+			rv32: integerInstructions(width32),
+			rv64: integerInstructions(width64),
 		}, {
 			name: "mul",
-			rv32: mul32,
-			rv64: mul64,
+			rv32: mulInstructions(width32),
+			rv64: mulInstructions(width64),
+		}, {
+			name: "zba",
+			rv32: zbaInstructions(width32),
+			rv64: zbaInstructions(width64),
+		}, {
+			name: "zbb",
+			rv32: zbbInstructions(width32),
+			rv64: zbbInstructions(width64),
+		}, {
+			name: "zbs",
+			rv32: zbsInstructions(width32),
+			rv64: zbsInstructions(width64),
+		}, {
+			name: "zicond",
+			rv32: zicondInstructions(width32),
+			rv64: zicondInstructions(width64),
 		},
 	}
 
@@ -120,6 +123,7 @@ func TestRV32ToRV64(t *testing.T) {
 				r.Equal(opc32.inputRegCnt, opc64.inputRegCnt)
 				r.Equal(opc32.hasOutputReg, opc64.hasOutputReg)
 				r.Equal(opc32.immediate, opc64.immediate)
+				r.Equal(opc32.pcRelative, opc64.pcRelative)
 				r.Equal(opc32.instrType, opc64.instrType)
 			}
 
@@ -141,3 +145,97 @@ func TestRV32ToRV64(t *testing.T) {
 		})
 	}
 }
+
+func TestShiftImmEffects(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant Variant
+		s       string
+		regs    map[expr.Key]uint64
+		want    uint64
+	}{{
+		name:    "slli_rv32",
+		variant: Variant32,
+		s:       "slli x1, x2, 1",
+		regs:    map[expr.Key]uint64{"x2": 0x40000001},
+		want:    0x80000002,
+	}, {
+		name:    "slli_rv64",
+		variant: Variant64,
+		s:       "slli x1, x2, 33",
+		regs:    map[expr.Key]uint64{"x2": 3},
+		want:    0x6_00000000,
+	}, {
+		// Bit 10 of the immediate distinguishes srai from srli and it
+		// must not be a part of the shift amount.
+		name:    "srai_rv64",
+		variant: Variant64,
+		s:       "srai x1, x2, 63",
+		regs:    map[expr.Key]uint64{"x2": 0x80000000_00000000},
+		want:    0xffffffff_ffffffff,
+	}, {
+		name:    "srliw",
+		variant: Variant64,
+		s:       "srliw x1, x2, 31",
+		regs:    map[expr.Key]uint64{"x2": 0x80000000},
+		want:    1,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			res := evalRegs(t, tt.variant, tt.s, tt.regs)
+			require.Equal(t, map[expr.Key]uint64{"x1": tt.want}, res)
+		})
+	}
+}
+
+func TestAddressWrapAround(t *testing.T) {
+	const addr model.Addr = 0xfffffffc
+
+	tests := []struct {
+		name string
+		s    string
+		want map[expr.Key]uint64
+	}{{
+		name: "jal",
+		s:    "jal x1, 8",
+		want: map[expr.Key]uint64{expr.IPKey: 4, "x1": 0},
+	}, {
+		name: "beq",
+		s:    "beq x0, x0, 8",
+		want: map[expr.Key]uint64{expr.IPKey: 4},
+	}, {
+		name: "bne",
+		s:    "bne x0, x0, 8",
+		want: map[expr.Key]uint64{expr.IPKey: 0},
+	}, {
+		name: "auipc",
+		s:    "auipc x1, 1",
+		want: map[expr.Key]uint64{"x1": 0xffc},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			bs, err := NewEncoder(Variant32).Assemble(addr, tt.s)
+			r.NoError(err)
+
+			ins, err := NewParser(Variant32).Parse(addr, bs)
+			r.NoError(err)
+
+			res := make(map[expr.Key]uint64, len(ins.Effects))
+			for _, ef := range ins.Effects {
+				store := ef.(expr.RegStore)
+				r.Equal(width32, store.Width())
+
+				c, ok := exprtransform.ConstFold(store.Value()).(expr.Const)
+				r.True(ok)
+				res[store.Key()], _ = expr.ConstUint[uint64](c)
+			}
+			r.Equal(tt.want, res)
+		})
+	}
+}
@@ -0,0 +1,663 @@
+package riscv
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+const (
+	// vlen is width of vector registers (VLEN). The package implements
+	// the minimal VLEN of V extension - 128 bits.
+	vlen = width128
+	// elen is width of the widest vector element (ELEN).
+	elen = width64
+
+	// maxLMUL is the maximal number of registers in a register group.
+	maxLMUL = 8
+)
+
+const (
+	// vlKey is the key of vl register holding number of vector elements
+	// vector instructions operate on.
+	vlKey = expr.Key("vl")
+	// vtypeKey is the key of vtype register holding type of vector
+	// elements vector instructions operate on.
+	vtypeKey = expr.Key("vtype")
+)
+
+// vd returns number of vector register in rd field of i.
+func vd(i instruction) uint8 { return uint8(rd.regNum(i.value)) }
+
+// vs1 returns number of vector register in rs1 field of i.
+func vs1(i instruction) uint8 { return uint8(rs1.regNum(i.value)) }
+
+// vs2 returns number of vector register in rs2 field of i.
+func vs2(i instruction) uint8 { return uint8(rs2.regNum(i.value)) }
+
+// vmasked indicates that vector instruction i is masked by v0 register.
+func vmasked(i instruction) bool { return i.value&(1<<25) == 0 }
+
+// vregKey returns key of vector register num.
+func vregKey(num uint8) expr.Key { return expr.Key(vregNum(num).String()) }
+
+// vregLoad returns value of vector register num.
+func vregLoad(num uint8) expr.Expr { return expr.NewRegLoad(vregKey(num), vlen) }
+
+// vregStore returns an effect storing e into vector register num.
+func vregStore(e expr.Expr, num uint8) expr.Effect {
+	return expr.NewRegStore(e, vregKey(num), vlen)
+}
+
+// groupSize returns the highest number of registers register groups starting
+// at vector registers nums can have. Register groups have to be aligned to
+// their size, so any other value of LMUL is reserved for such registers.
+func groupSize(nums ...uint8) int {
+	g := maxLMUL
+	for _, n := range nums {
+		for int(n)%g != 0 {
+			g /= 2
+		}
+	}
+	return g
+}
+
+// elementCnt returns number of elements of width sew in a single vector
+// register.
+func elementCnt(sew expr.Width) int { return int(vlen / sew) }
+
+// element returns element e of width sew of vector register value reg.
+func element(reg expr.Expr, e int, sew expr.Width) expr.Expr {
+	shift := expr.ConstFromUint(uint16(e) * sew.Bits())
+	return exprtools.NewWidthGadget(expr.NewBinary(expr.Rsh, reg, shift, vlen), sew)
+}
+
+// elementOnes returns a vector register value with all bits of element e of
+// width sew set.
+func elementOnes(e int, sew expr.Width) expr.Expr {
+	shift := expr.ConstFromUint(uint16(e) * sew.Bits())
+	return expr.NewBinary(expr.Lsh, exprtools.Ones(sew), shift, vlen)
+}
+
+// fromElements returns a vector register value composed of elements elems of
+// width sew.
+func fromElements(elems []expr.Expr, sew expr.Width) expr.Expr {
+	var res expr.Expr = expr.Zero
+	for e, el := range elems {
+		shift := expr.ConstFromUint(uint16(e) * sew.Bits())
+		res = exprtools.BitOr(res, expr.NewBinary(expr.Lsh, el, shift, vlen), vlen)
+	}
+	return res
+}
+
+// merge returns bits of e selected by mask combined with bits of old which are
+// not selected by mask.
+func merge(e, old, mask expr.Expr, w expr.Width) expr.Expr {
+	return exprtools.BitOr(
+		exprtools.BitAnd(e, mask, w),
+		exprtools.BitAnd(old, exprtools.BitNot(mask, w), w),
+		w,
+	)
+}
+
+// maskBit returns bit idx of mask register v0.
+func maskBit(idx int) expr.Expr {
+	shifted := expr.NewBinary(expr.Rsh, vregLoad(0), expr.ConstFromUint(uint16(idx)), vlen)
+	return exprtools.MaskBits(shifted, 1, vlen)
+}
+
+// vectorSource describes the second source operand of vector arithmetic
+// instructions.
+type vectorSource uint8
+
+const (
+	// srcVector is vector register vs1.
+	srcVector vectorSource = iota
+	// srcScalar is integer register rs1.
+	srcScalar
+	// srcSimm is 5 bit signed immediate.
+	srcSimm
+	// srcUimm is 5 bit unsigned immediate.
+	srcUimm
+)
+
+// suffix returns suffix of names of instructions with source s.
+func (s vectorSource) suffix() string {
+	switch s {
+	case srcVector:
+		return ".vv"
+	case srcScalar:
+		return ".vx"
+	default:
+		return ".vi"
+	}
+}
+
+// vectorUnit describes vector instructions of a processor with integer
+// registers of width xlen.
+type vectorUnit struct {
+	xlen expr.Width
+}
+
+// vl returns value of vl register.
+func (u vectorUnit) vl() expr.Expr { return expr.NewRegLoad(vlKey, u.xlen) }
+
+// vtype returns value of vtype register.
+func (u vectorUnit) vtype() expr.Expr { return expr.NewRegLoad(vtypeKey, u.xlen) }
+
+// sewSelect returns an expression of width w evaluating f for selected element
+// width (SEW) given by vtype register.
+//
+// Element width is dynamic, so expressions for all element widths are built
+// and the one selected by vtype is evaluated. Reserved widths evaluate the
+// widest element which is fine as vl is zero in such a case.
+func (u vectorUnit) sewSelect(w expr.Width, f func(sew expr.Width) expr.Expr) expr.Expr {
+	shifted := expr.NewBinary(expr.Rsh, u.vtype(), expr.ConstFromUint[uint8](3), u.xlen)
+	vsew := exprtools.MaskBits(shifted, 3, u.xlen)
+
+	res := f(width64)
+	for v := uint8(2); v < 3; v-- {
+		limit := expr.ConstFromUint(v + 1)
+		res = expr.NewLess(vsew, limit, f(width8<<v), res, w)
+	}
+	return res
+}
+
+// active returns an expression of width vlen which is nonzero if element idx
+// of a register group is active. Active elements are those with index lower
+// than vl and, if masked is true, which are enabled by mask register v0.
+func (u vectorUnit) active(idx int, masked bool) expr.Expr {
+	var enabled expr.Expr = expr.One
+	if masked {
+		enabled = maskBit(idx)
+	}
+	return expr.NewLess(expr.ConstFromUint(uint16(idx)), u.vl(), enabled, expr.Zero, vlen)
+}
+
+// activeMask returns mask of bits of register k of a register group with
+// elements of width sew which hold active elements.
+func (u vectorUnit) activeMask(k int, sew expr.Width, masked bool) expr.Expr {
+	n := elementCnt(sew)
+
+	var mask expr.Expr = expr.Zero
+	for e := 0; e < n; e++ {
+		ones := exprtools.BoolCond(u.active(k*n+e, masked), elementOnes(e, sew), expr.Zero, vlen)
+		mask = exprtools.BitOr(mask, ones, vlen)
+	}
+	return mask
+}
+
+// scalar returns value of integer register at position r of i as an element
+// of width sew. The value is truncated or sign-extended to the element width.
+func (u vectorUnit) scalar(r reg, i instruction, sew expr.Width) expr.Expr {
+	if sew <= u.xlen {
+		return regLoad(r, i, sew)
+	}
+	return sext(regLoad(r, i, u.xlen), uint8(u.xlen.Bits()-1), sew)
+}
+
+// source returns element e of register k of a register group of source s of
+// vector instruction i. The element is of width sew.
+func (u vectorUnit) source(s vectorSource, i instruction, k, e int, sew expr.Width) expr.Expr {
+	switch s {
+	case srcVector:
+		return element(vregLoad(vs1(i)+uint8(k)), e, sew)
+	case srcScalar:
+		return u.scalar(rs1, i, sew)
+	case srcSimm:
+		return expr.NewConstInt(int64(signExtend(uint32(vs1(i)), 4)), width64)
+	default:
+		return expr.ConstFromUint(vs1(i))
+	}
+}
+
+// sourceRegs returns vector registers source s of instruction i refers.
+func sourceRegs(s vectorSource, i instruction) []uint8 {
+	if s == srcVector {
+		return []uint8{vs1(i)}
+	}
+	return nil
+}
+
+// elementwise returns effects of vector instruction i writing elements
+// returned by f into register group vd. Function f returns element e of
+// register k of the group for elements of width sew. Vector registers regs
+// are source register groups of the instruction.
+//
+// Elements which are not active keep their original value. This is valid for
+// both undisturbed and agnostic policies, so policies are ignored.
+func (u vectorUnit) elementwise(
+	i instruction,
+	regs []uint8,
+	masked bool,
+	f func(k, e int, sew expr.Width) expr.Expr,
+) []expr.Effect {
+	dst := vd(i)
+	g := groupSize(append(regs, dst)...)
+
+	effects := make([]expr.Effect, g)
+	for k := range effects {
+		k := k
+		old := vregLoad(dst + uint8(k))
+		val := u.sewSelect(vlen, func(sew expr.Width) expr.Expr {
+			elems := make([]expr.Expr, elementCnt(sew))
+			for e := range elems {
+				elems[e] = f(k, e, sew)
+			}
+			return merge(fromElements(elems, sew), old, u.activeMask(k, sew, masked), vlen)
+		})
+		effects[k] = vregStore(val, dst+uint8(k))
+	}
+
+	return effects
+}
+
+// arith returns semantics of vector arithmetic instruction with source s
+// computing f of elements of vs2 and the source.
+func (u vectorUnit) arith(s vectorSource, f binaryExprFunc) semantics {
+	return semantics{
+		vector: true,
+		effects: func(i instruction) []expr.Effect {
+			regs := append(sourceRegs(s, i), vs2(i))
+			return u.elementwise(i, regs, vmasked(i), func(k, e int, sew expr.Width) expr.Expr {
+				src := element(vregLoad(vs2(i)+uint8(k)), e, sew)
+				return f(src, u.source(s, i, k, e, sew), sew)
+			})
+		},
+	}
+}
+
+// integer adds semantics of integer vector instructions name for all sources
+// srcs to sems.
+func (u vectorUnit) integer(sems map[string]semantics, name string, f binaryExprFunc, srcs ...vectorSource) {
+	for _, s := range srcs {
+		sems[name+s.suffix()] = u.arith(s, f)
+	}
+}
+
+// compare adds semantics of integer vector compare instructions name for all
+// sources srcs to sems. Bits of the mask written into vd are set for elements
+// for which f returns its true expression.
+func (u vectorUnit) compare(sems map[string]semantics, name string, f condExprFunc, srcs ...vectorSource) {
+	for _, s := range srcs {
+		s := s
+		sems[name+s.suffix()] = semantics{
+			vector: true,
+			effects: func(i instruction) []expr.Effect {
+				return u.compareEffects(i, s, f)
+			},
+		}
+	}
+}
+
+// compareEffects returns effects of vector compare instruction i with source
+// s writing a bit to the mask register vd for every element.
+func (u vectorUnit) compareEffects(i instruction, s vectorSource, f condExprFunc) []expr.Effect {
+	g := groupSize(append(sourceRegs(s, i), vs2(i))...)
+
+	val := u.sewSelect(vlen, func(sew expr.Width) expr.Expr {
+		n := elementCnt(sew)
+
+		var bits expr.Expr = expr.Zero
+		for k := 0; k < g; k++ {
+			for e := 0; e < n; e++ {
+				src := element(vregLoad(vs2(i)+uint8(k)), e, sew)
+				bit := f(src, u.source(s, i, k, e, sew), expr.One, expr.Zero, sew)
+				shift := expr.ConstFromUint(uint16(k*n + e))
+				bits = exprtools.BitOr(bits, expr.NewBinary(expr.Lsh, bit, shift, vlen), vlen)
+			}
+		}
+		return bits
+	})
+
+	// Each element has a single bit in the mask, so first vl bits are
+	// written.
+	mask := exprtools.Sub(expr.NewBinary(expr.Lsh, expr.One, u.vl(), vlen), expr.One, vlen)
+	if vmasked(i) {
+		mask = exprtools.BitAnd(mask, vregLoad(0), vlen)
+	}
+
+	return []expr.Effect{vregStore(merge(val, vregLoad(vd(i)), mask, vlen), vd(i))}
+}
+
+// swapArgs returns a condExprFunc comparing arguments of f in reverse order.
+func swapArgs(f condExprFunc) condExprFunc {
+	return func(a1, a2, t, f2 expr.Expr, w expr.Width) expr.Expr {
+		return f(a2, a1, t, f2, w)
+	}
+}
+
+// negateCond returns a condExprFunc returning true expression if f returns its
+// false expression and vice versa.
+func negateCond(f condExprFunc) condExprFunc {
+	return func(a1, a2, t, f2 expr.Expr, w expr.Width) expr.Expr {
+		return f(a1, a2, f2, t, w)
+	}
+}
+
+// shiftFunc returns a binaryExprFunc shifting e1 by e2 with shift f. Only low
+// log2(SEW) bits of the shift amount are used.
+func shiftFunc(f binaryExprFunc) binaryExprFunc {
+	return func(e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		bits := exprtools.BitCnt(3)
+		for b := w; b > 1; b /= 2 {
+			bits++
+		}
+		return f(e1, exprtools.MaskBits(e2, bits, w), w)
+	}
+}
+
+// merges adds semantics of vmerge and vmv.v instructions for all sources srcs
+// to sems.
+func (u vectorUnit) merges(sems map[string]semantics, srcs ...vectorSource) {
+	for _, s := range srcs {
+		s := s
+
+		sems["vmerge"+s.suffix()+"m"] = semantics{
+			vector: true,
+			effects: func(i instruction) []expr.Effect {
+				regs := append(sourceRegs(s, i), vs2(i))
+				return u.elementwise(i, regs, false, func(k, e int, sew expr.Width) expr.Expr {
+					src := element(vregLoad(vs2(i)+uint8(k)), e, sew)
+					idx := k*elementCnt(sew) + e
+					return exprtools.BoolCond(maskBit(idx), u.source(s, i, k, e, sew), src, sew)
+				})
+			},
+		}
+		sems["vmv.v."+s.suffix()[2:]] = semantics{
+			vector: true,
+			effects: func(i instruction) []expr.Effect {
+				return u.elementwise(i, sourceRegs(s, i), false, func(k, e int, sew expr.Width) expr.Expr {
+					return u.source(s, i, k, e, sew)
+				})
+			},
+		}
+	}
+}
+
+// vset returns effects of vector configuration instruction i setting vtype
+// register to t with application vector length avl. Value of vlmax is the
+// maximal vector length of t or zero if t is not supported.
+func (u vectorUnit) vset(i instruction, t, avl, vlmax expr.Expr) []expr.Effect {
+	vill := expr.NewBinary(expr.Lsh, expr.One, expr.ConstFromUint(u.xlen.Bits()-1), u.xlen)
+
+	// Unsupported vector type sets vill bit and vl to zero.
+	vl := expr.NewLess(avl, vlmax, avl, vlmax, u.xlen)
+	vtype := exprtools.BoolCond(vlmax, t, vill, u.xlen)
+
+	return []expr.Effect{
+		expr.NewRegStore(vl, vlKey, u.xlen),
+		expr.NewRegStore(vtype, vtypeKey, u.xlen),
+		regStore(vl, i, u.xlen),
+	}
+}
+
+// avl returns application vector length of vsetvli or vsetvl instruction i
+// which sets vector type with maximal vector length vlmax.
+//
+// If rs1 is x0, the maximal vector length is requested. If also rd is x0, vl
+// is kept.
+func (u vectorUnit) avl(i instruction, vlmax expr.Expr) expr.Expr {
+	switch {
+	case rs1.regNum(i.value) != 0:
+		return regLoad(rs1, i, u.xlen)
+	case rd.regNum(i.value) != 0:
+		return vlmax
+	default:
+		return u.vl()
+	}
+}
+
+// vlmax returns maximal vector length of vector type t of width w. Zero is
+// returned if t is not supported. See vtype.vlmax for details.
+func vlmax(t expr.Expr, w expr.Width) expr.Expr {
+	vlmul := exprtools.MaskBits(t, 3, w)
+	shifted := expr.NewBinary(expr.Rsh, t, expr.ConstFromUint[uint8](3), w)
+	vsew := exprtools.MaskBits(shifted, 3, w)
+
+	bits := expr.ConstFromUint(vlen.Bits())
+	four := expr.ConstFromUint[uint8](4)
+	grouped := expr.NewLess(
+		vlmul,
+		four,
+		expr.NewBinary(expr.Lsh, bits, vlmul, w),
+		expr.NewBinary(expr.Rsh, bits, exprtools.Sub(expr.ConstFromUint[uint8](8), vlmul, w), w),
+		w,
+	)
+
+	shift := expr.NewBinary(expr.Add, vsew, expr.ConstFromUint[uint8](3), w)
+	var max expr.Expr = expr.NewBinary(expr.Rsh, grouped, shift, w)
+	max = expr.NewLess(max, expr.ConstFromUint(uint8(vlen/elen)), expr.Zero, max, w)
+	max = expr.NewLess(vsew, four, max, expr.Zero, w)
+	max = exprtools.Eq(vlmul, four, expr.Zero, max, w)
+
+	reserved := expr.NewBinary(expr.Rsh, t, expr.ConstFromUint[uint8](vtypeFields), w)
+	return exprtools.BoolCond(reserved, expr.Zero, max, w)
+}
+
+// config adds semantics of vector configuration instructions to sems.
+func (u vectorUnit) config(sems map[string]semantics) {
+	sems["vsetvli"] = semantics{
+		vector: true,
+		effects: func(i instruction) []expr.Effect {
+			t := vtype(parseBitRange(i.value, 20, 31))
+			max := expr.NewConstUint(t.vlmax(), u.xlen)
+			return u.vset(i, expr.NewConstUint(uint64(t), u.xlen), u.avl(i, max), max)
+		},
+	}
+	sems["vsetivli"] = semantics{
+		vector: true,
+		effects: func(i instruction) []expr.Effect {
+			t := vtype(parseBitRange(i.value, 20, 30))
+			max := expr.NewConstUint(t.vlmax(), u.xlen)
+			avl := expr.ConstFromUint(vs1(i))
+			return u.vset(i, expr.NewConstUint(uint64(t), u.xlen), avl, max)
+		},
+	}
+	sems["vsetvl"] = semantics{
+		vector: true,
+		effects: func(i instruction) []expr.Effect {
+			t := regLoad(rs2, i, u.xlen)
+			max := vlmax(t, u.xlen)
+			return u.vset(i, t, u.avl(i, max), max)
+		},
+	}
+}
+
+// loadStore adds semantics of vector unit-stride and strided loads and stores
+// of elements of width eew to sems.
+func (u vectorUnit) loadStore(sems map[string]semantics, eew expr.Width) {
+	bits := eew.Bits()
+
+	sems[fmt.Sprintf("vle%d.v", bits)] = semantics{
+		vector: true,
+		effects: func(i instruction) []expr.Effect {
+			return u.unitLoad(i, eew)
+		},
+	}
+	sems[fmt.Sprintf("vlse%d.v", bits)] = semantics{
+		vector: true,
+		effects: func(i instruction) []expr.Effect {
+			return u.stridedLoad(i, eew)
+		},
+	}
+	sems[fmt.Sprintf("vse%d.v", bits)] = semantics{
+		vector: true,
+		effects: func(i instruction) []expr.Effect {
+			return u.unitStore(i, eew)
+		},
+	}
+	sems[fmt.Sprintf("vsse%d.v", bits)] = semantics{
+		vector: true,
+		effects: func(i instruction) []expr.Effect {
+			return u.stridedStore(i, eew)
+		},
+	}
+}
+
+// regAddr returns address of register k of a register group accessed by
+// unit-stride vector memory instruction i.
+func (u vectorUnit) regAddr(i instruction, k int) expr.Expr {
+	offset := expr.ConstFromUint(uint16(k) * uint16(vlen))
+	return expr.NewBinary(expr.Add, regLoad(rs1, i, u.xlen), offset, u.xlen)
+}
+
+// elementAddr returns address of element idx of a register group accessed by
+// strided vector memory instruction i.
+func (u vectorUnit) elementAddr(i instruction, idx int) expr.Expr {
+	offset := expr.NewBinary(expr.Mul, regLoad(rs2, i, u.xlen), expr.ConstFromUint(uint16(idx)), u.xlen)
+	return expr.NewBinary(expr.Add, regLoad(rs1, i, u.xlen), offset, u.xlen)
+}
+
+// unitLoad returns effects of unit-stride vector load i of elements of width
+// eew. Whole registers are loaded from memory and only active elements are
+// written.
+func (u vectorUnit) unitLoad(i instruction, eew expr.Width) []expr.Effect {
+	dst := vd(i)
+
+	effects := make([]expr.Effect, groupSize(dst))
+	for k := range effects {
+		old := vregLoad(dst + uint8(k))
+		val := memLoad(u.regAddr(i, k), vlen)
+		mask := u.activeMask(k, eew, vmasked(i))
+		effects[k] = vregStore(merge(val, old, mask, vlen), dst+uint8(k))
+	}
+	return effects
+}
+
+// stridedLoad returns effects of strided vector load i of elements of width
+// eew.
+func (u vectorUnit) stridedLoad(i instruction, eew expr.Width) []expr.Effect {
+	dst := vd(i)
+	n := elementCnt(eew)
+
+	effects := make([]expr.Effect, groupSize(dst))
+	for k := range effects {
+		elems := make([]expr.Expr, n)
+		for e := range elems {
+			elems[e] = memLoad(u.elementAddr(i, k*n+e), eew)
+		}
+
+		old := vregLoad(dst + uint8(k))
+		mask := u.activeMask(k, eew, vmasked(i))
+		effects[k] = vregStore(merge(fromElements(elems, eew), old, mask, vlen), dst+uint8(k))
+	}
+	return effects
+}
+
+// unitStore returns effects of unit-stride vector store i of elements of width
+// eew. Whole registers are stored into memory, but memory of inactive elements
+// keeps its original value.
+func (u vectorUnit) unitStore(i instruction, eew expr.Width) []expr.Effect {
+	src := vd(i)
+
+	effects := make([]expr.Effect, groupSize(src))
+	for k := range effects {
+		addr := u.regAddr(i, k)
+		mask := u.activeMask(k, eew, vmasked(i))
+		val := merge(vregLoad(src+uint8(k)), memLoad(addr, vlen), mask, vlen)
+		effects[k] = memStore(val, addr, vlen)
+	}
+	return effects
+}
+
+// stridedStore returns effects of strided vector store i of elements of width
+// eew.
+func (u vectorUnit) stridedStore(i instruction, eew expr.Width) []expr.Effect {
+	src := vd(i)
+	n := elementCnt(eew)
+	g := groupSize(src)
+
+	effects := make([]expr.Effect, 0, g*n)
+	for k := 0; k < g; k++ {
+		for e := 0; e < n; e++ {
+			addr := u.elementAddr(i, k*n+e)
+			elem := element(vregLoad(src+uint8(k)), e, eew)
+			val := exprtools.BoolCond(u.active(k*n+e, vmasked(i)), elem, memLoad(addr, eew), eew)
+			effects = append(effects, memStore(val, addr, eew))
+		}
+	}
+	return effects
+}
+
+// moves adds semantics of instructions moving the first element of a vector
+// register from and to an integer register to sems.
+func (u vectorUnit) moves(sems map[string]semantics) {
+	sems["vmv.x.s"] = semantics{
+		vector: true,
+		effects: func(i instruction) []expr.Effect {
+			val := u.sewSelect(u.xlen, func(sew expr.Width) expr.Expr {
+				el := element(vregLoad(vs2(i)), 0, sew)
+				if sew >= u.xlen {
+					return exprtools.NewWidthGadget(el, u.xlen)
+				}
+				return sext(el, uint8(sew.Bits()-1), u.xlen)
+			})
+			return []expr.Effect{regStore(val, i, u.xlen)}
+		},
+	}
+	sems["vmv.s.x"] = semantics{
+		vector: true,
+		effects: func(i instruction) []expr.Effect {
+			old := vregLoad(vd(i))
+			val := u.sewSelect(vlen, func(sew expr.Width) expr.Expr {
+				// The element is written only if vl is nonzero.
+				mask := exprtools.BoolCond(u.vl(), elementOnes(0, sew), expr.Zero, vlen)
+				return merge(u.scalar(rs1, i, sew), old, mask, vlen)
+			})
+			return []expr.Effect{vregStore(val, vd(i))}
+		},
+	}
+}
+
+// vectorInstructions returns instructions of V (vector) extension of a
+// processor with registers of width xlen.
+//
+// Only a subset of the extension is supported: configuration instructions,
+// unit-stride and strided loads and stores and integer arithmetic. Vector
+// registers are VLEN=128 bits wide.
+func vectorInstructions(xlen expr.Width) []*instructionType {
+	u := vectorUnit{xlen: xlen}
+	vv, vx, vi, vu := srcVector, srcScalar, srcSimm, srcUimm
+
+	min := atomicMinMax(exprtools.Lts, false)
+	max := atomicMinMax(exprtools.Lts, true)
+	minu := atomicMinMax(lessFunc, false)
+	maxu := atomicMinMax(lessFunc, true)
+	rsub := func(e1, e2 expr.Expr, w expr.Width) expr.Expr {
+		return exprtools.Sub(e2, e1, w)
+	}
+
+	sems := map[string]semantics{}
+	u.config(sems)
+	for _, eew := range []expr.Width{width8, width16, width32, width64} {
+		u.loadStore(sems, eew)
+	}
+
+	u.integer(sems, "vadd", binOpFunc(expr.Add), vv, vx, vi)
+	u.integer(sems, "vsub", exprtools.Sub, vv, vx)
+	u.integer(sems, "vrsub", rsub, vx, vi)
+	u.integer(sems, "vminu", minu, vv, vx)
+	u.integer(sems, "vmin", min, vv, vx)
+	u.integer(sems, "vmaxu", maxu, vv, vx)
+	u.integer(sems, "vmax", max, vv, vx)
+	u.integer(sems, "vand", exprtools.BitAnd, vv, vx, vi)
+	u.integer(sems, "vor", exprtools.BitOr, vv, vx, vi)
+	u.integer(sems, "vxor", exprtools.BitXor, vv, vx, vi)
+	u.merges(sems, vv, vx, vi)
+	u.compare(sems, "vmseq", exprtools.Eq, vv, vx, vi)
+	u.compare(sems, "vmsne", negateCond(exprtools.Eq), vv, vx, vi)
+	u.compare(sems, "vmsltu", lessFunc, vv, vx)
+	u.compare(sems, "vmslt", exprtools.Lts, vv, vx)
+	u.compare(sems, "vmsleu", exprtools.Leu, vv, vx, vi)
+	u.compare(sems, "vmsle", exprtools.Les, vv, vx, vi)
+	u.compare(sems, "vmsgtu", swapArgs(lessFunc), vx, vi)
+	u.compare(sems, "vmsgt", swapArgs(exprtools.Lts), vx, vi)
+	u.integer(sems, "vsll", shiftFunc(binOpFunc(expr.Lsh)), vv, vx, vu)
+	u.integer(sems, "vsrl", shiftFunc(binOpFunc(expr.Rsh)), vv, vx, vu)
+	u.integer(sems, "vsra", shiftFunc(exprtools.RshA), vv, vx, vu)
+	u.integer(sems, "vmul", binOpFunc(expr.Mul), vv, vx)
+	u.moves(sems)
+
+	return tableInstructions(sems, "rv_v")
+}
@@ -0,0 +1,501 @@
+package riscv
+
+import (
+	"fmt"
+	"mltwist/internal/exprtransform"
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVlmax(t *testing.T) {
+	for _, v := range []Variant{Variant32, Variant64} {
+		xlen := width32
+		if v == Variant64 {
+			xlen = width64
+		}
+
+		for vt := vtype(0); vt < 0x200; vt++ {
+			e := vlmax(expr.NewConstUint(uint64(vt), xlen), xlen)
+			want := expr.NewConstUint(vt.vlmax(), xlen)
+			require.Equal(t, want, exprtransform.ConstFold(e), "vtype 0x%x", uint64(vt))
+		}
+	}
+
+	// Spot check of the static implementation.
+	require.Equal(t, uint64(4), vtype(0b010_000).vlmax())
+	require.Equal(t, uint64(128), vtype(0b000_011).vlmax())
+	require.Equal(t, uint64(2), vtype(0b000_101).vlmax())
+	require.Equal(t, uint64(0), vtype(0b001_101).vlmax())
+	require.Equal(t, uint64(0), vtype(0b000_100).vlmax())
+}
+
+// vec returns a vector register value with elements elems of width sew. All
+// elements not listed are zero.
+func vec(sew expr.Width, elems ...uint64) expr.Const {
+	bs := make([]byte, vlen)
+	for i, e := range elems {
+		for b := 0; b < int(sew); b++ {
+			bs[i*int(sew)+b] = byte(e >> (8 * b))
+		}
+	}
+	return expr.NewConst(bs, vlen)
+}
+
+// ones returns a vector register value with all bits set.
+func ones() expr.Const {
+	bs := make([]byte, vlen)
+	for i := range bs {
+		bs[i] = 0xff
+	}
+	return expr.NewConst(bs, vlen)
+}
+
+// xreg returns a value of an integer register or a CSR.
+func xreg(v uint64) expr.Const { return expr.NewConstUint(v, width64) }
+
+// evalVector evaluates effects of an instruction s with registers set to regs
+// and memory bytes set to mem. Registers and memory bytes not present in regs
+// and mem are zero. It returns registers and memory bytes whose values were
+// changed by the instruction.
+func evalVector(
+	t testing.TB,
+	v Variant,
+	s string,
+	regs map[expr.Key]expr.Const,
+	mem map[uint64]byte,
+) (map[expr.Key]expr.Const, map[uint64]byte) {
+	r := require.New(t)
+
+	bs, err := NewEncoder(v, ExtV).Assemble(0x1000, s)
+	r.NoError(err)
+	ins, err := NewParser(v, ExtV).Parse(0x1000, bs)
+	r.NoError(err)
+
+	var eval func(ex expr.Expr, w expr.Width) expr.Const
+	eval = func(ex expr.Expr, w expr.Width) expr.Const {
+		ex = exprtransform.ReplaceAll(ex, func(l expr.RegLoad) (expr.Expr, bool) {
+			c, ok := regs[l.Key()]
+			if !ok {
+				c = expr.Zero
+			}
+			return exprtransform.SetWidth(c, l.Width()), true
+		})
+		ex = exprtransform.ReplaceAll(ex, func(l expr.MemLoad) (expr.Expr, bool) {
+			addr, _ := expr.ConstUint[uint64](eval(l.Addr(), width64))
+			val := make([]byte, l.Width())
+			for i := range val {
+				val[i] = mem[addr+uint64(i)]
+			}
+			return expr.NewConst(val, l.Width()), true
+		})
+
+		c, ok := exprtransform.ConstFold(ex).(expr.Const)
+		r.True(ok)
+		return exprtransform.SetWidth(c, w).(expr.Const)
+	}
+
+	regsOut := map[expr.Key]expr.Const{}
+	memOut := map[uint64]byte{}
+	for _, ef := range ins.Effects {
+		switch ef := ef.(type) {
+		case expr.RegStore:
+			val := eval(ef.Value(), ef.Width())
+			old, ok := regs[ef.Key()]
+			if !ok {
+				old = expr.Zero
+			}
+			if !val.Equal(exprtransform.SetWidth(old, ef.Width()).(expr.Const)) {
+				regsOut[ef.Key()] = val
+			}
+		case expr.MemStore:
+			addr, _ := expr.ConstUint[uint64](eval(ef.Addr(), width64))
+			for i, b := range eval(ef.Value(), ef.Width()).Bytes() {
+				if a := addr + uint64(i); mem[a] != b {
+					memOut[a] = b
+				}
+			}
+		default:
+			r.Fail(fmt.Sprintf("unexpected effect: %#v", ef))
+		}
+	}
+
+	return regsOut, memOut
+}
+
+// Vector types used in tests.
+const (
+	e8  = 0b000_000
+	e16 = 0b001_000
+	e32 = 0b010_000
+	e64 = 0b011_000
+	m2  = 0b001
+	m8  = 0b011
+	mf8 = 0b101
+)
+
+func TestVectorEffects(t *testing.T) {
+	const addr = 0x2000
+
+	memory := make(map[uint64]byte, 32)
+	for i := uint64(0); i < 32; i++ {
+		memory[addr+i] = byte(i + 1)
+	}
+
+	tests := []struct {
+		name    string
+		variant Variant
+		s       string
+		regs    map[expr.Key]expr.Const
+		mem     map[uint64]byte
+		want    map[expr.Key]expr.Const
+		wantMem map[uint64]byte
+	}{{
+		name:    "vsetvli",
+		variant: Variant64,
+		s:       "vsetvli x5, x10, e32, m1, ta, ma",
+		regs:    map[expr.Key]expr.Const{"x10": xreg(10)},
+		want: map[expr.Key]expr.Const{
+			"vl":    xreg(4),
+			"vtype": xreg(0xc0 | e32),
+			"x5":    xreg(4),
+		},
+	}, {
+		name:    "vsetvli_vlmax",
+		variant: Variant64,
+		s:       "vsetvli x5, x0, e8, m8, ta, ma",
+		want: map[expr.Key]expr.Const{
+			"vl":    xreg(128),
+			"vtype": xreg(0xc0 | e8 | m8),
+			"x5":    xreg(128),
+		},
+	}, {
+		name:    "vsetvli_keep_vl",
+		variant: Variant64,
+		s:       "vsetvli x0, x0, e16, m2, tu, mu",
+		regs:    map[expr.Key]expr.Const{"vl": xreg(3), "vtype": xreg(e32)},
+		want:    map[expr.Key]expr.Const{"vtype": xreg(e16 | m2)},
+	}, {
+		name:    "vsetivli",
+		variant: Variant32,
+		s:       "vsetivli x5, 3, e64, m2, ta, mu",
+		want: map[expr.Key]expr.Const{
+			"vl":    xreg(3),
+			"vtype": xreg(0x40 | e64 | m2),
+			"x5":    xreg(3),
+		},
+	}, {
+		name:    "vsetvl_fractional",
+		variant: Variant64,
+		s:       "vsetvl x5, x10, x11",
+		regs: map[expr.Key]expr.Const{
+			"x10": xreg(100),
+			"x11": xreg(e8 | mf8),
+		},
+		want: map[expr.Key]expr.Const{
+			"vl":    xreg(2),
+			"vtype": xreg(e8 | mf8),
+			"x5":    xreg(2),
+		},
+	}, {
+		name:    "vsetvl_unsupported",
+		variant: Variant32,
+		s:       "vsetvl x5, x10, x11",
+		regs: map[expr.Key]expr.Const{
+			"vl":  xreg(4),
+			"x10": xreg(100),
+			"x11": xreg(e16 | mf8),
+		},
+		want: map[expr.Key]expr.Const{
+			"vl":    xreg(0),
+			"vtype": xreg(1 << 31),
+		},
+	}, {
+		name:    "vadd.vv_tail",
+		variant: Variant64,
+		s:       "vadd.vv v1, v2, v3",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(3),
+			"vtype": xreg(e32),
+			"v1":    vec(width32, 7, 7, 7, 7),
+			"v2":    vec(width32, 1, 2, 3, 4),
+			"v3":    vec(width32, 10, 20, 30, 40),
+		},
+		want: map[expr.Key]expr.Const{"v1": vec(width32, 11, 22, 33, 7)},
+	}, {
+		name:    "vadd.vi_masked",
+		variant: Variant64,
+		s:       "vadd.vi v1, v2, -1, v0.t",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(4),
+			"vtype": xreg(e32),
+			"v0":    vec(width8, 0b0101),
+			"v1":    vec(width32, 7, 7, 7, 7),
+			"v2":    vec(width32, 1, 2, 3, 4),
+		},
+		want: map[expr.Key]expr.Const{"v1": vec(width32, 0, 7, 2, 7)},
+	}, {
+		name:    "vsub.vx_group",
+		variant: Variant64,
+		s:       "vsub.vx v4, v8, x10",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(20),
+			"vtype": xreg(e8 | m2),
+			"v8":    vec(width64, 0x1010101010101010, 0x1010101010101010),
+			"v9":    vec(width64, 0x1010101010101010, 0x1010101010101010),
+			"x10":   xreg(0x101),
+		},
+		want: map[expr.Key]expr.Const{
+			"v4": vec(width64, 0x0f0f0f0f0f0f0f0f, 0x0f0f0f0f0f0f0f0f),
+			"v5": vec(width32, 0x0f0f0f0f),
+		},
+	}, {
+		name:    "vrsub.vx_sign_extended",
+		variant: Variant32,
+		s:       "vrsub.vx v1, v2, x10",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(2),
+			"vtype": xreg(e64),
+			"v2":    vec(width64, 1, 2),
+			"x10":   xreg(0xffffffff),
+		},
+		want: map[expr.Key]expr.Const{
+			"v1": vec(width64, 0xfffffffffffffffe, 0xfffffffffffffffd),
+		},
+	}, {
+		name:    "vsra.vi",
+		variant: Variant64,
+		s:       "vsra.vi v1, v2, 4",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(2),
+			"vtype": xreg(e16),
+			"v2":    vec(width16, 0x8000, 0x0100),
+		},
+		want: map[expr.Key]expr.Const{"v1": vec(width16, 0xf800, 0x0010)},
+	}, {
+		name:    "vsll.vx_shift_masked",
+		variant: Variant64,
+		s:       "vsll.vx v1, v2, x10",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(2),
+			"vtype": xreg(e8),
+			"v2":    vec(width8, 0x81, 0x03),
+			"x10":   xreg(9),
+		},
+		want: map[expr.Key]expr.Const{"v1": vec(width8, 0x02, 0x06)},
+	}, {
+		name:    "vmin.vv",
+		variant: Variant64,
+		s:       "vmin.vv v1, v2, v3",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(2),
+			"vtype": xreg(e16),
+			"v2":    vec(width16, 0xffff, 5),
+			"v3":    vec(width16, 1, 3),
+		},
+		want: map[expr.Key]expr.Const{"v1": vec(width16, 0xffff, 3)},
+	}, {
+		name:    "vmaxu.vv",
+		variant: Variant64,
+		s:       "vmaxu.vv v1, v2, v3",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(2),
+			"vtype": xreg(e16),
+			"v2":    vec(width16, 0xffff, 5),
+			"v3":    vec(width16, 1, 3),
+		},
+		want: map[expr.Key]expr.Const{"v1": vec(width16, 0xffff, 5)},
+	}, {
+		name:    "vmul.vx",
+		variant: Variant64,
+		s:       "vmul.vx v1, v2, x10",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(2),
+			"vtype": xreg(e16),
+			"v2":    vec(width16, 300, 2),
+			"x10":   xreg(300),
+		},
+		want: map[expr.Key]expr.Const{"v1": vec(width16, 0x5f90, 600)},
+	}, {
+		name:    "vmseq.vi",
+		variant: Variant64,
+		s:       "vmseq.vi v1, v2, 3",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(4),
+			"vtype": xreg(e32),
+			"v1":    ones(),
+			"v2":    vec(width32, 3, 1, 3, 3),
+		},
+		want: map[expr.Key]expr.Const{
+			"v1": vec(width64, 0xffff_ffff_ffff_fffd, 0xffff_ffff_ffff_ffff),
+		},
+	}, {
+		name:    "vmslt.vx_group",
+		variant: Variant64,
+		s:       "vmslt.vx v1, v2, x10, v0.t",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(4),
+			"vtype": xreg(e64 | m2),
+			"v0":    vec(width8, 0b1011),
+			"v2":    vec(width64, 0xffffffffffffffff, 5),
+			"v3":    vec(width64, 0xfffffffffffffffe, 0xfffffffffffffffd),
+		},
+		want: map[expr.Key]expr.Const{"v1": vec(width8, 0b1001)},
+	}, {
+		name:    "vmerge.vim",
+		variant: Variant64,
+		s:       "vmerge.vim v1, v2, 7, v0",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(4),
+			"vtype": xreg(e8),
+			"v0":    vec(width8, 0b1010),
+			"v2":    vec(width8, 1, 2, 3, 4, 5),
+		},
+		want: map[expr.Key]expr.Const{"v1": vec(width8, 1, 7, 3, 7)},
+	}, {
+		name:    "vmv.v.x",
+		variant: Variant64,
+		s:       "vmv.v.x v1, x10",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(1),
+			"vtype": xreg(e64),
+			"x10":   xreg(0xffffffffffffffff),
+		},
+		want: map[expr.Key]expr.Const{"v1": vec(width64, 0xffffffffffffffff)},
+	}, {
+		name:    "vmv.x.s",
+		variant: Variant64,
+		s:       "vmv.x.s x5, v2",
+		regs: map[expr.Key]expr.Const{
+			"vtype": xreg(e8),
+			"v2":    vec(width8, 0x80, 1),
+		},
+		want: map[expr.Key]expr.Const{"x5": xreg(0xffffffffffffff80)},
+	}, {
+		name:    "vmv.x.s_truncated",
+		variant: Variant32,
+		s:       "vmv.x.s x5, v2",
+		regs: map[expr.Key]expr.Const{
+			"vtype": xreg(e64),
+			"v2":    vec(width64, 0x12345678_9abcdef0),
+		},
+		want: map[expr.Key]expr.Const{"x5": xreg(0x9abcdef0)},
+	}, {
+		name:    "vmv.s.x",
+		variant: Variant64,
+		s:       "vmv.s.x v1, x10",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(1),
+			"vtype": xreg(e16),
+			"v1":    vec(width16, 1, 2),
+			"x10":   xreg(0x12345),
+		},
+		want: map[expr.Key]expr.Const{"v1": vec(width16, 0x2345, 2)},
+	}, {
+		name:    "vmv.s.x_zero_vl",
+		variant: Variant64,
+		s:       "vmv.s.x v1, x10",
+		regs: map[expr.Key]expr.Const{
+			"vtype": xreg(e16),
+			"v1":    vec(width16, 1, 2),
+			"x10":   xreg(0x12345),
+		},
+		want: map[expr.Key]expr.Const{},
+	}, {
+		name:    "vle32.v",
+		variant: Variant64,
+		s:       "vle32.v v1, (x10)",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(3),
+			"vtype": xreg(e32),
+			"v1":    ones(),
+			"x10":   xreg(addr),
+		},
+		mem: memory,
+		want: map[expr.Key]expr.Const{
+			"v1": vec(width32, 0x04030201, 0x08070605, 0x0c0b0a09, 0xffffffff),
+		},
+	}, {
+		name:    "vlse16.v_masked",
+		variant: Variant64,
+		s:       "vlse16.v v1, (x10), x11, v0.t",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(3),
+			"vtype": xreg(e16),
+			"v0":    vec(width8, 0b110),
+			"x10":   xreg(addr),
+			"x11":   xreg(4),
+		},
+		mem:  memory,
+		want: map[expr.Key]expr.Const{"v1": vec(width16, 0, 0x0605, 0x0a09)},
+	}, {
+		name:    "vse8.v",
+		variant: Variant64,
+		s:       "vse8.v v1, (x10)",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(3),
+			"vtype": xreg(e8),
+			"v1":    vec(width8, 0xa0, 0xa1, 0xa2, 0xa3),
+			"x10":   xreg(addr),
+		},
+		mem:     memory,
+		want:    map[expr.Key]expr.Const{},
+		wantMem: map[uint64]byte{addr: 0xa0, addr + 1: 0xa1, addr + 2: 0xa2},
+	}, {
+		name:    "vsse32.v_masked",
+		variant: Variant32,
+		s:       "vsse32.v v1, (x10), x11, v0.t",
+		regs: map[expr.Key]expr.Const{
+			"vl":    xreg(2),
+			"vtype": xreg(e32),
+			"v0":    vec(width8, 0b110),
+			"v1":    vec(width32, 0xa0a0a0a0, 0xb0b0b0b0, 0xc0c0c0c0),
+			"x10":   xreg(addr),
+			"x11":   xreg(8),
+		},
+		mem:  memory,
+		want: map[expr.Key]expr.Const{},
+		wantMem: map[uint64]byte{
+			addr + 8: 0xb0, addr + 9: 0xb0, addr + 10: 0xb0, addr + 11: 0xb0,
+		},
+	}, {
+		name:    "csrrs_vl",
+		variant: Variant32,
+		s:       "csrrs x5, vl, x0",
+		regs:    map[expr.Key]expr.Const{"vl": xreg(7)},
+		want:    map[expr.Key]expr.Const{"x5": xreg(7)},
+	}, {
+		name:    "csrrs_vlenb",
+		variant: Variant64,
+		s:       "csrrs x5, vlenb, x0",
+		want:    map[expr.Key]expr.Const{"x5": xreg(16)},
+	}, {
+		// Vector CSRs are read-only, so writes trap.
+		name:    "csrrw_vlenb",
+		variant: Variant64,
+		s:       "csrrw x5, vlenb, x10",
+		regs:    map[expr.Key]expr.Const{"x10": xreg(1)},
+		want:    map[expr.Key]expr.Const{},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			regs, mem := evalVector(t, tt.variant, tt.s, tt.regs, tt.mem)
+			r.Len(regs, len(tt.want))
+			for k, want := range tt.want {
+				r.Contains(regs, k)
+				got := regs[k]
+				r.Equal(exprtransform.SetWidth(want, got.Width()), got, k)
+			}
+
+			if tt.wantMem == nil {
+				tt.wantMem = map[uint64]byte{}
+			}
+			r.Equal(tt.wantMem, mem)
+		})
+	}
+}
@@ -39,6 +39,51 @@ const (
 	// ExtA represents atomic instruction extension of RISC-V ISA.
 	ExtA
 
+	// ExtC represents compressed instruction extension of RISC-V ISA.
+	//
+	// Compressed instructions are 2 bytes long and they are parsed as
+	// their expanded 4 bytes long equivalents.
+	ExtC
+
+	// ExtF represents single precision floating point extension of RISC-V
+	// ISA.
+	ExtF
+
+	// ExtD represents double precision floating point extension of RISC-V
+	// ISA. It depends on ExtF, so it implies ExtF.
+	//
+	// Floating point registers are 8 bytes wide if ExtD is present and
+	// single precision values are NaN-boxed in them. Without ExtD, the
+	// registers are 4 bytes wide.
+	ExtD
+
+	// ExtZba represents address generation bit manipulation extension of
+	// RISC-V ISA.
+	ExtZba
+
+	// ExtZbb represents basic bit manipulation extension of RISC-V ISA.
+	ExtZbb
+
+	// ExtZbs represents single bit manipulation extension of RISC-V ISA.
+	ExtZbs
+
+	// ExtZicond represents integer conditional operations extension of
+	// RISC-V ISA.
+	ExtZicond
+
+	// ExtV represents vector extension of RISC-V ISA.
+	//
+	// Only a subset of the extension is supported - vector configuration,
+	// unit-stride and strided loads and stores and integer arithmetic.
+	// Vector registers are 128 bits wide (VLEN=128).
+	ExtV
+
+	// ExtPriv represents privileged instructions of machine and supervisor
+	// mode defined by RISC-V privileged architecture - mret, sret, wfi and
+	// sfence.vma. The instructions are used by firmware and operating
+	// system kernels.
+	ExtPriv
+
 	// extEnd marks first invalid value of extension.
 	extEnd
 )
@@ -47,6 +92,10 @@ const (
 // of extensions.
 type Parser struct {
 	matcher *opcode.Matcher[*instructionType]
+	// instrs maps names of all instructions which are not compressed to
+	// their types. Compressed instructions are expanded to instructions
+	// found in this map.
+	instrs map[string]*instructionType
 }
 
 // NewParser creates a new RISC-V instruction parser parsing RISC-V architecture
@@ -66,19 +115,39 @@ func NewParser(v Variant, exts ...Extension) Parser {
 		panic(fmt.Sprintf("bug: matcher creation failed: %s", err.Error()))
 	}
 
+	m := make(map[string]*instructionType, len(instrs))
+	for _, ins := range instrs {
+		if ins.expand == nil {
+			m[ins.name] = ins
+		}
+	}
+
 	return Parser{
 		matcher: decoder,
+		instrs:  m,
 	}
 }
 
 // instructionSet generates a new set of instructions based on RISC-V variant v
 // and list of extensions. The extI extension instructions are always added to
 // the set.
+//
+// ExtD implies ExtF. If ExtD is present, instructions of ExtF operating on
+// NaN-boxed values are used.
 func instructionSet(v Variant, exts []Extension) []*instructionType {
 	variantExtensions := instructions[v]
 
-	extensions := make([][]*instructionType, 1, len(exts)+1)
+	hasD := false
+	for _, e := range exts {
+		hasD = hasD || e == ExtD
+	}
+
+	extensions := make([][]*instructionType, 1, len(exts)+2)
 	extensions[0] = variantExtensions[extI]
+	if hasD {
+		extensions = append(extensions, nanBoxedSingle[v])
+	}
+
 	for _, e := range exts {
 		// This is technically a user error which could be recoverable.
 		// But it's violation of API contract, so panic is in a way
@@ -87,6 +156,10 @@ func instructionSet(v Variant, exts []Extension) []*instructionType {
 			panic(fmt.Sprintf("invalid extension: %d", e))
 		}
 
+		if e == ExtF && hasD {
+			continue
+		}
+
 		// The map is written in the code -> changing this requires code
 		// modification. As this issue is non-recoverable, panic it is.
 		ext, ok := variantExtensions[e]
@@ -108,24 +181,80 @@ func instructionSet(v Variant, exts []Extension) []*instructionType {
 // beginning of the array which represent a single instruction. This allows to
 // implement paginated parsing of a sequence of instructions by incrementally
 // cutting the start of bs.
+//
+// Length of the instruction is given by its lowest 2 bits. Instructions with
+// any of the bits unset are compressed and therefore 2 bytes long, all other
+// instructions are 4 bytes long.
 func (p Parser) Parse(a model.Addr, bs []byte) (model.Instruction, error) {
-	if l := len(bs); l < instructionLen {
+	if l := len(bs); l < compressedLen {
 		return model.Instruction{}, fmt.Errorf(
 			"bytes are too short to be a RISCV instruction opcode: %d", l)
 	}
 
-	opcode, ok := p.matcher.Match(bs)
+	l := instructionLen
+	if isCompressed(bs[0]) {
+		l = compressedLen
+	}
+	if len(bs) < l {
+		return model.Instruction{}, fmt.Errorf(
+			"bytes are too short to be a RISCV instruction opcode: %d",
+			len(bs))
+	}
+
+	opcode, ok := p.matcher.Match(bs[:l])
 	if !ok {
 		return model.Instruction{}, fmt.Errorf(
-			"unknown instruction opcode: 0x%x", bs[:instructionLen])
+			"unknown instruction opcode: 0x%x", bs[:l])
+	}
+
+	var instr instruction
+	if opcode.expand == nil {
+		instr = newInstruction(a, bs, opcode)
+	} else {
+		var err error
+		instr, err = p.expand(a, bs, opcode)
+		if err != nil {
+			return model.Instruction{}, err
+		}
 	}
 
-	instr := newInstruction(a, bs, opcode)
+	t := instr.instrType
+	order, _ := instr.memOrder()
 	return model.Instruction{
-		Type:    opcode.instrType,
-		ByteLen: instructionLen,
+		Type:    t.typeOf(instr),
+		ByteLen: instr.len(),
+		Order:   order,
 
-		Effects: opcode.validEffects(instr),
+		Effects: t.validEffects(instr),
 		Details: instr,
 	}, nil
 }
+
+// expand parses compressed instruction of type c at address a and expands it
+// into an equivalent instruction which is not compressed.
+func (p Parser) expand(
+	a model.Addr,
+	bs []byte,
+	c *instructionType,
+) (instruction, error) {
+	value := uint16(opcodeValue(bs[:compressedLen]))
+
+	name, ops, ok := c.expand(value)
+	if !ok {
+		return instruction{}, fmt.Errorf(
+			"reserved compressed instruction: 0x%04x", value)
+	}
+
+	t, ok := p.instrs[name]
+	if !ok {
+		return instruction{}, fmt.Errorf(
+			"%s expands to unsupported instruction: %s", c.name, name)
+	}
+
+	expanded, err := t.encode(ops)
+	if err != nil {
+		return instruction{}, fmt.Errorf("cannot expand %s: %w", c.name, err)
+	}
+
+	return newCompressedInstruction(a, bs, c, expanded, t), nil
+}
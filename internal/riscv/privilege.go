@@ -0,0 +1,37 @@
+package riscv
+
+import (
+	"mltwist/internal/state"
+	"mltwist/pkg/expr"
+)
+
+// Privilege is a privilege mode a RISC-V processor executes code in.
+type Privilege uint8
+
+const (
+	// PrivilegeUser is user mode (U-mode) running application code.
+	PrivilegeUser Privilege = 0
+	// PrivilegeSupervisor is supervisor mode (S-mode) running operating
+	// system kernels.
+	PrivilegeSupervisor Privilege = 1
+	// PrivilegeMachine is machine mode (M-mode) running firmware. This is
+	// the most privileged mode and processors start in this mode after
+	// reset.
+	PrivilegeMachine Privilege = 3
+)
+
+// PrivilegeKey is key of register holding the current privilege mode. The
+// register is written by trap return instructions (mret and sret). Values of
+// the register are Privilege values.
+const PrivilegeKey = expr.Key("priv")
+
+// privilegeWidth is width of register holding the current privilege mode.
+const privilegeWidth = width8
+
+// ResetState stores state of registers of a processor right after reset into
+// regs. The processor starts in machine mode, all other registers are left
+// unknown.
+func ResetState(regs *state.RegMap) {
+	c := expr.ConstFromUint(uint8(PrivilegeMachine))
+	regs.Store(PrivilegeKey, c, privilegeWidth)
+}
@@ -0,0 +1,101 @@
+package riscv
+
+import (
+	"fmt"
+	"mltwist/pkg/model"
+	"strings"
+)
+
+// pseudoString returns assembler code of pseudo-instruction called name with
+// operands ops. The notation is the same as the one of instructions.
+func pseudoString(name string, ops ...string) string {
+	if len(ops) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s %s", name, strings.Join(ops, ", "))
+}
+
+// is indicates that i is an instruction called name. The zero value of
+// instruction is not any instruction.
+func (i instruction) is(name string) bool {
+	return i.instrType != nil && i.instrType.name == name
+}
+
+// pairAddr returns address computed by auipc instruction prev writing register
+// r followed by an instruction adding imm to r. It returns false if prev is not
+// an auipc instruction writing r or if r is x0.
+func pairAddr(prev instruction, r uint8, imm int32) (model.Addr, bool) {
+	if !prev.is("auipc") || r == 0 {
+		return 0, false
+	}
+
+	ops := prev.operands()
+	if ops.Rd != r {
+		return 0, false
+	}
+
+	return addrAddImm(addrAddImm(prev.addr, ops.Imm), imm), true
+}
+
+// pseudo returns instruction i written as a pseudo-instruction. Instruction
+// prev precedes i in the same basic block, the zero value of prev means that
+// there is no such instruction. Registers are referred by their ABI names if
+// abi is set. It returns false if i is not written as a pseudo-instruction.
+//
+// The pseudo-instructions are those GNU objdump uses unless aliases are
+// disabled by -M no-aliases:
+//
+//   - nop is addi x0, x0, 0,
+//   - li rd, imm is addi rd, x0, imm,
+//   - mv rd, rs is addi rd, rs, 0 or compressed c.mv,
+//   - ret is jalr x0, 0(x1),
+//   - j offset is jal x0, offset,
+//   - beqz rs, offset is beq rs, x0, offset.
+//
+// Pseudo-instructions expanded into a pair of instructions are written on the
+// second instruction of the pair. Their operands are absolute addresses:
+//
+//   - la rd, addr is auipc rd, hi followed by addi rd, rd, lo,
+//   - call addr is auipc x1, hi followed by jalr x1, lo(x1).
+func (i instruction) pseudo(prev instruction, abi bool) (string, bool) {
+	ops := i.operands()
+	reg := func(r uint8) string { return regNum(r).name(abi) }
+	imm := fmt.Sprintf("%d", ops.Imm)
+
+	switch i.instrType.name {
+	case "addi":
+		a, ok := pairAddr(prev, ops.Rs1, ops.Imm)
+		switch {
+		case ok && ops.Rd == ops.Rs1:
+			return pseudoString("la", reg(ops.Rd), fmt.Sprintf("0x%x", a)), true
+		case ops.Rd == 0 && ops.Rs1 == 0 && ops.Imm == 0:
+			return pseudoString("nop"), true
+		case ops.Rs1 == 0:
+			return pseudoString("li", reg(ops.Rd), imm), true
+		case ops.Imm == 0:
+			return pseudoString("mv", reg(ops.Rd), reg(ops.Rs1)), true
+		}
+	case "add":
+		if i.compressed != nil && ops.Rs1 == 0 {
+			return pseudoString("mv", reg(ops.Rd), reg(ops.Rs2)), true
+		}
+	case "jalr":
+		a, ok := pairAddr(prev, ops.Rs1, ops.Imm)
+		switch {
+		case ok && ops.Rd == 1 && ops.Rs1 == 1:
+			return pseudoString("call", fmt.Sprintf("0x%x", a)), true
+		case ops.Rd == 0 && ops.Rs1 == 1 && ops.Imm == 0:
+			return pseudoString("ret"), true
+		}
+	case "jal":
+		if ops.Rd == 0 {
+			return pseudoString("j", imm), true
+		}
+	case "beq":
+		if ops.Rs2 == 0 {
+			return pseudoString("beqz", reg(ops.Rs1), imm), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,161 @@
+package riscv
+
+import (
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstruction_Format(t *testing.T) {
+	abi := model.Syntax{ABINames: true}
+	aliases := model.Syntax{Aliases: true}
+	both := model.Syntax{ABINames: true, Aliases: true}
+
+	tests := []struct {
+		name   string
+		prev   string
+		s      string
+		bytes  []byte
+		syntax model.Syntax
+		want   string
+	}{{
+		name:   "default",
+		s:      "add a0, sp, ra",
+		syntax: model.Syntax{},
+		want:   "add x10, x2, x1",
+	}, {
+		name:   "abi_names",
+		s:      "sd ra, 8(sp)",
+		syntax: abi,
+		want:   "sd ra, 8(sp)",
+	}, {
+		name:   "abi_float_names",
+		s:      "fmadd.d f10, f11, f8, f28",
+		syntax: abi,
+		want:   "fmadd.d fa0, fa1, fs0, ft8",
+	}, {
+		name:   "abi_no_aliases",
+		s:      "addi a0, zero, 5",
+		syntax: abi,
+		want:   "addi a0, zero, 5",
+	}, {
+		name:   "nop",
+		s:      "addi x0, x0, 0",
+		syntax: both,
+		want:   "nop",
+	}, {
+		name:   "li",
+		s:      "addi x10, x0, -5",
+		syntax: aliases,
+		want:   "li x10, -5",
+	}, {
+		name:   "mv",
+		s:      "addi s0, sp, 0",
+		syntax: both,
+		want:   "mv s0, sp",
+	}, {
+		name:   "mv_compressed",
+		bytes:  []byte{0x2e, 0x85},
+		syntax: both,
+		want:   "mv a0, a1",
+	}, {
+		name:   "add_zero",
+		s:      "add a0, zero, a1",
+		syntax: both,
+		want:   "add a0, zero, a1",
+	}, {
+		name:   "ret",
+		s:      "jalr x0, 0(x1)",
+		syntax: both,
+		want:   "ret",
+	}, {
+		name:   "jalr",
+		s:      "jalr x0, 0(x5)",
+		syntax: both,
+		want:   "jalr zero, 0(t0)",
+	}, {
+		name:   "j",
+		s:      "jal x0, 16",
+		syntax: both,
+		want:   "j 16",
+	}, {
+		name:   "jal",
+		s:      "jal x1, 16",
+		syntax: both,
+		want:   "jal ra, 16",
+	}, {
+		name:   "beqz",
+		s:      "beq a0, zero, -8",
+		syntax: both,
+		want:   "beqz a0, -8",
+	}, {
+		name:   "bnez",
+		s:      "bne a0, zero, -8",
+		syntax: both,
+		want:   "bne a0, zero, -8",
+	}, {
+		name:   "la",
+		prev:   "auipc a0, 2",
+		s:      "addi a0, a0, -8",
+		syntax: both,
+		want:   "la a0, 0x2ff8",
+	}, {
+		name:   "la_other_register",
+		prev:   "auipc a0, 2",
+		s:      "addi a1, a0, -8",
+		syntax: both,
+		want:   "addi a1, a0, -8",
+	}, {
+		name:   "la_no_aliases",
+		prev:   "auipc a0, 2",
+		s:      "addi a0, a0, -8",
+		syntax: abi,
+		want:   "addi a0, a0, -8",
+	}, {
+		name:   "call",
+		prev:   "auipc ra, 0",
+		s:      "jalr ra, 24(ra)",
+		syntax: aliases,
+		want:   "call 0x1018",
+	}, {
+		name:   "call_without_auipc",
+		prev:   "addi ra, ra, 0",
+		s:      "jalr ra, 24(ra)",
+		syntax: both,
+		want:   "jalr ra, 24(ra)",
+	}}
+
+	e := NewEncoder(Variant64, allExtensions()...)
+	p := NewParser(Variant64, allExtensions()...)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			addr := model.Addr(0x1000)
+			var prev model.PlatformDetails
+			if tt.prev != "" {
+				bs, err := e.Assemble(addr, tt.prev)
+				r.NoError(err)
+				ins, err := p.Parse(addr, bs)
+				r.NoError(err)
+
+				prev = ins.Details
+				addr += ins.ByteLen
+			}
+
+			bs := tt.bytes
+			if bs == nil {
+				var err error
+				bs, err = e.Assemble(addr, tt.s)
+				r.NoError(err)
+			}
+			ins, err := p.Parse(addr, bs)
+			r.NoError(err)
+
+			f := ins.Details.(model.Formatter)
+			r.Equal(tt.want, f.Format(tt.syntax, prev))
+		})
+	}
+}
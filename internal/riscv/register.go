@@ -1,6 +1,10 @@
 package riscv
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 const (
 	// regBits is number of bits used to represent a register number.
@@ -16,9 +20,115 @@ type regNum uint8
 
 func (r regNum) String() string { return fmt.Sprintf("x%d", r) }
 
+// name returns name of register r in assembler code. ABI name of the register
+// is returned if abi is set.
+func (r regNum) name(abi bool) string {
+	if abi {
+		return abiNames[r]
+	}
+	return r.String()
+}
+
+// abiNames are names of registers defined by RISC-V calling convention (ABI).
+// Index in the array is the register number.
+var abiNames = [regCnt]string{
+	"zero", "ra", "sp", "gp", "tp", "t0", "t1", "t2",
+	"s0", "s1", "a0", "a1", "a2", "a3", "a4", "a5",
+	"a6", "a7", "s2", "s3", "s4", "s5", "s6", "s7",
+	"s8", "s9", "s10", "s11", "t3", "t4", "t5", "t6",
+}
+
+// parseRegNum parses a register name in assembler code. Both x-names (x0, x1,
+// ..., x31) and ABI names (zero, ra, sp, ...) of registers are accepted. Frame
+// pointer alias fp is accepted as well.
+func parseRegNum(s string) (regNum, error) {
+	if s == "fp" {
+		return 8, nil
+	}
+
+	for i, n := range abiNames {
+		if n == s {
+			return regNum(i), nil
+		}
+	}
+
+	if strings.HasPrefix(s, "x") {
+		n, err := strconv.ParseUint(s[1:], 10, 8)
+		if err == nil && n < regCnt {
+			return regNum(n), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown register: %q", s)
+}
+
+// fregNum represents a RISC-V floating point register number. Range of valid
+// values is [0..31].
+type fregNum uint8
+
+func (r fregNum) String() string { return fmt.Sprintf("f%d", r) }
+
+// name returns name of floating point register r in assembler code. ABI name
+// of the register is returned if abi is set.
+func (r fregNum) name(abi bool) string {
+	if abi {
+		return fabiNames[r]
+	}
+	return r.String()
+}
+
+// fabiNames are names of floating point registers defined by RISC-V calling
+// convention (ABI). Index in the array is the register number.
+var fabiNames = [regCnt]string{
+	"ft0", "ft1", "ft2", "ft3", "ft4", "ft5", "ft6", "ft7",
+	"fs0", "fs1", "fa0", "fa1", "fa2", "fa3", "fa4", "fa5",
+	"fa6", "fa7", "fs2", "fs3", "fs4", "fs5", "fs6", "fs7",
+	"fs8", "fs9", "fs10", "fs11", "ft8", "ft9", "ft10", "ft11",
+}
+
+// parseFRegNum parses a floating point register name in assembler code. Both
+// f-names (f0, f1, ..., f31) and ABI names (ft0, fa0, fs0, ...) of registers
+// are accepted.
+func parseFRegNum(s string) (fregNum, error) {
+	for i, n := range fabiNames {
+		if n == s {
+			return fregNum(i), nil
+		}
+	}
+
+	if strings.HasPrefix(s, "f") {
+		n, err := strconv.ParseUint(s[1:], 10, 8)
+		if err == nil && n < regCnt {
+			return fregNum(n), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown floating point register: %q", s)
+}
+
+// vregNum represents a RISC-V vector register number. Range of valid values is
+// [0..31].
+type vregNum uint8
+
+func (r vregNum) String() string { return fmt.Sprintf("v%d", r) }
+
+// parseVRegNum parses a vector register name in assembler code. Vector
+// registers have no ABI names, so only v-names (v0, v1, ..., v31) are
+// accepted.
+func parseVRegNum(s string) (vregNum, error) {
+	if strings.HasPrefix(s, "v") {
+		n, err := strconv.ParseUint(s[1:], 10, 8)
+		if err == nil && n < regCnt {
+			return vregNum(n), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown vector register: %q", s)
+}
+
 // reg represents a register number position in an instruction opcode. Valid
-// values are rd (output register), rs1 (input register 1) and rs2 (input
-// register 2).
+// values are rd (output register), rs1 (input register 1), rs2 (input
+// register 2) and rs3 (input register 3).
 type reg uint8
 
 const (
@@ -28,6 +138,9 @@ const (
 	rs1
 	// rs2 is position of second input register in an instruction opcode.
 	rs2
+	// rs3 is position of third input register in an instruction opcode.
+	// Only fused multiply-add instructions (R4 type) have rs3.
+	rs3
 )
 
 // bitOffset returns index if starting bit of register position in in
@@ -40,6 +153,8 @@ func (r reg) bitOffset() uint8 {
 		return 15
 	case rs2:
 		return 20
+	case rs3:
+		return 27
 	default:
 		panic(fmt.Sprintf("invalid register: %d", r))
 	}
@@ -31,6 +31,12 @@ func TestReg_regNum(t *testing.T) {
 			r:     rs2,
 			want:  0b10011,
 		},
+		{
+			name:  "rs3",
+			value: valueFromBytes(0, 0, 0, 0b10011<<3),
+			r:     rs3,
+			want:  0b10011,
+		},
 	}
 
 	for _, tt := range tests {
@@ -41,3 +47,79 @@ func TestReg_regNum(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRegNum(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   regNum
+		hasErr bool
+	}{
+		{name: "x0", want: 0},
+		{name: "x31", want: 31},
+		{name: "zero", want: 0},
+		{name: "ra", want: 1},
+		{name: "sp", want: 2},
+		{name: "fp", want: 8},
+		{name: "s0", want: 8},
+		{name: "a0", want: 10},
+		{name: "s11", want: 27},
+		{name: "t6", want: 31},
+		{name: "x32", hasErr: true},
+		{name: "x-1", hasErr: true},
+		{name: "x", hasErr: true},
+		{name: "a8", hasErr: true},
+		{name: "", hasErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			num, err := parseRegNum(tt.name)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+
+			r.NoError(err)
+			r.Equal(tt.want, num)
+		})
+	}
+}
+
+func TestParseFRegNum(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   fregNum
+		hasErr bool
+	}{
+		{name: "f0", want: 0},
+		{name: "f31", want: 31},
+		{name: "ft0", want: 0},
+		{name: "fs0", want: 8},
+		{name: "fa0", want: 10},
+		{name: "fs11", want: 27},
+		{name: "ft11", want: 31},
+		{name: "f32", hasErr: true},
+		{name: "x1", hasErr: true},
+		{name: "fa8", hasErr: true},
+		{name: "", hasErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			num, err := parseFRegNum(tt.name)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+
+			r.NoError(err)
+			r.Equal(tt.want, num)
+		})
+	}
+}
@@ -0,0 +1,400 @@
+package riscv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// operandKind describes a single operand of an instruction in assembler code.
+type operandKind uint8
+
+const (
+	// operandRd is output register.
+	operandRd operandKind = iota
+	// operandRs1 is the first input register.
+	operandRs1
+	// operandRs2 is the second input register.
+	operandRs2
+	// operandZimm is 5 bit unsigned immediate encoded in rs1 field of an
+	// instruction.
+	operandZimm
+	// operandImm is an immediate value (or shift amount).
+	operandImm
+	// operandUimm is 20 bit unsigned immediate of lui and auipc
+	// instructions. It's written as the value of the immediate field, not
+	// as the value the instruction works with.
+	operandUimm
+	// operandMem is a memory address in form of imm(rs1).
+	operandMem
+	// operandAddr is a memory address without an offset in form of (rs1)
+	// used by atomic instructions and vector loads and stores.
+	operandAddr
+	// operandCsr is a control and status register accessed by CSR
+	// instructions.
+	operandCsr
+	// operandPred is predecessor set of a fence instruction.
+	operandPred
+	// operandSucc is successor set of a fence instruction.
+	operandSucc
+
+	// operandFd is output floating point register.
+	operandFd
+	// operandFs1 is the first input floating point register.
+	operandFs1
+	// operandFs2 is the second input floating point register.
+	operandFs2
+	// operandFs3 is the third input floating point register.
+	operandFs3
+	// operandRm is floating point rounding mode. The operand is optional
+	// and it's omitted for the dynamic rounding mode.
+	operandRm
+
+	// operandVd is output vector register.
+	operandVd
+	// operandVs1 is vector register encoded in rs1 field of an instruction.
+	operandVs1
+	// operandVs2 is vector register encoded in rs2 field of an instruction.
+	operandVs2
+	// operandVs3 is vector register stored into memory by vector store
+	// instructions. It's encoded in rd field of an instruction.
+	operandVs3
+	// operandSimm5 is 5 bit signed immediate encoded in rs1 field of an
+	// instruction.
+	operandSimm5
+	// operandVtype is 11 bit vector type immediate of vsetvli instruction.
+	operandVtype
+	// operandVtype10 is 10 bit vector type immediate of vsetivli
+	// instruction.
+	operandVtype10
+	// operandVm is the v0.t mask operand of vector instructions. The operand
+	// is optional and it's omitted for instructions which are not masked.
+	operandVm
+	// operandV0 is the v0 mask register of vector merge instructions. The
+	// operand is implicit - it's not encoded in an instruction.
+	operandV0
+)
+
+// roundingNames are names of rounding modes in assembler code. Index in the
+// array is the value of rounding mode encoded in an instruction.
+var roundingNames = [rmDynamic + 1]string{
+	"rne", "rtz", "rdn", "rup", "rmm", "", "", "dyn",
+}
+
+// regOperand returns kind of operand at register position r of o.
+func (o instructionType) regOperand(r reg) operandKind {
+	float := o.isFloat(r)
+
+	switch r {
+	case rd:
+		if float {
+			return operandFd
+		}
+		return operandRd
+	case rs1:
+		if float {
+			return operandFs1
+		}
+		return operandRs1
+	case rs2:
+		if float {
+			return operandFs2
+		}
+		return operandRs2
+	case rs3:
+		// Only floating point instructions have the third input
+		// register.
+		return operandFs3
+	default:
+		panic(fmt.Sprintf("invalid register: %d", r))
+	}
+}
+
+// syntax returns list of operands of an instruction in the order they are
+// written in assembler code.
+func (o instructionType) syntax() []operandKind {
+	if o.vectorOperands != nil {
+		return o.vectorOperands
+	}
+
+	_, hasImm := o.immediate.parseValue(0)
+
+	// For some weird reason load and store instructions use different
+	// syntax then all other instructions - memory offset syntax. Jalr
+	// uses the same syntax for its target address.
+	if hasImm && (o.loadBytes > 0 || o.offsetSyntax) {
+		return []operandKind{o.regOperand(rd), operandMem}
+	}
+
+	// Store instruction is written in order: `s[bhwd] <reg> <mem_addr>`,
+	// even though memory base register is r1 and the register written to
+	// memory is r2. Why not to make the assembler irregular such that store
+	// is the only instruction where direction of data flow is from left
+	// operand to the right one. Long live irregularities! Intel
+	// celebrates, the rest of the world cries...
+	if hasImm && o.storeBytes > 0 {
+		return []operandKind{o.regOperand(rs2), operandMem}
+	}
+
+	// Atomic instructions have no memory offset, the address is written
+	// as the last operand: `amo<op> rd, rs2, (rs1)` and `lr rd, (rs1)`.
+	if o.aqrl {
+		if o.inputRegCnt > 1 {
+			return []operandKind{operandRd, operandRs2, operandAddr}
+		}
+		return []operandKind{operandRd, operandAddr}
+	}
+
+	// CSR instructions write the CSR before the value written into it:
+	// `csrrw rd, csr, rs1` and `csrrwi rd, csr, zimm`.
+	if o.csr {
+		if o.zimm {
+			return []operandKind{operandRd, operandCsr, operandZimm}
+		}
+		return []operandKind{operandRd, operandCsr, operandRs1}
+	}
+
+	// The value of 5 is optimistic preallocation.
+	kinds := make([]operandKind, 0, 5)
+	if o.hasOutputReg {
+		kinds = append(kinds, o.regOperand(rd))
+	}
+	if o.inputRegCnt > 0 {
+		kinds = append(kinds, o.regOperand(rs1))
+	}
+	if o.inputRegCnt > 1 {
+		kinds = append(kinds, o.regOperand(rs2))
+	}
+	if o.inputRegCnt > 2 {
+		kinds = append(kinds, o.regOperand(rs3))
+	}
+	if o.immediate == immTypeU {
+		kinds = append(kinds, operandUimm)
+	} else if hasImm || o.shamtBits > 0 {
+		kinds = append(kinds, operandImm)
+	}
+	if o.fence {
+		kinds = append(kinds, operandPred, operandSucc)
+	}
+	if o.roundingMode {
+		kinds = append(kinds, operandRm)
+	}
+
+	return kinds
+}
+
+// omitted indicates that operand k of ops is not written in assembler code as
+// it has its default value.
+func (k operandKind) omitted(ops Operands) bool {
+	switch k {
+	case operandRm:
+		return ops.Rm == rmDynamic
+	case operandVm:
+		return !ops.Masked
+	default:
+		return false
+	}
+}
+
+// optional indicates that operand k can be omitted in assembler code.
+func (k operandKind) optional() bool { return k == operandRm || k == operandVm }
+
+// omit sets operand k of ops to the default value an operand omitted in
+// assembler code has.
+func (k operandKind) omit(ops *Operands) {
+	switch k {
+	case operandRm:
+		ops.Rm = rmDynamic
+	case operandVm:
+		ops.Masked = false
+	default:
+		panic(fmt.Sprintf("operand kind %d cannot be omitted", k))
+	}
+}
+
+// isVtype indicates that operand k is a vector type immediate.
+func (k operandKind) isVtype() bool { return k == operandVtype || k == operandVtype10 }
+
+// format returns a string representation of operand k of operands ops.
+// Integer and floating point registers are referred by their ABI names if abi
+// is set.
+func (k operandKind) format(ops Operands, abi bool) string {
+	switch k {
+	case operandRd:
+		return regNum(ops.Rd).name(abi)
+	case operandRs1:
+		return regNum(ops.Rs1).name(abi)
+	case operandRs2:
+		return regNum(ops.Rs2).name(abi)
+	case operandZimm:
+		return fmt.Sprintf("%d", ops.Rs1)
+	case operandImm:
+		return fmt.Sprintf("%d", ops.Imm)
+	case operandUimm:
+		return fmt.Sprintf("%d", uint32(ops.Imm)>>12)
+	case operandMem:
+		return fmt.Sprintf("%d(%s)", ops.Imm, regNum(ops.Rs1).name(abi))
+	case operandCsr:
+		return csr(ops.Imm).String()
+	case operandPred:
+		return fenceSetString(ops.Pred)
+	case operandSucc:
+		return fenceSetString(ops.Succ)
+	case operandFd:
+		return fregNum(ops.Rd).name(abi)
+	case operandFs1:
+		return fregNum(ops.Rs1).name(abi)
+	case operandFs2:
+		return fregNum(ops.Rs2).name(abi)
+	case operandFs3:
+		return fregNum(ops.Rs3).name(abi)
+	case operandRm:
+		if int(ops.Rm) < len(roundingNames) && roundingNames[ops.Rm] != "" {
+			return roundingNames[ops.Rm]
+		}
+		return fmt.Sprintf("rm%d", ops.Rm)
+	case operandVd, operandVs3:
+		return vregNum(ops.Rd).String()
+	case operandVs1:
+		return vregNum(ops.Rs1).String()
+	case operandVs2:
+		return vregNum(ops.Rs2).String()
+	case operandSimm5:
+		return fmt.Sprintf("%d", ops.Imm)
+	case operandAddr:
+		return fmt.Sprintf("(%s)", regNum(ops.Rs1).name(abi))
+	case operandVtype, operandVtype10:
+		return vtype(uint32(ops.Imm)).String()
+	case operandVm:
+		return "v0.t"
+	case operandV0:
+		return "v0"
+	default:
+		panic(fmt.Sprintf("unknown operand kind: %d", k))
+	}
+}
+
+// parseReg parses a register name s into an 8 bit register number.
+func parseReg(s string) (uint8, error) {
+	r, err := parseRegNum(s)
+	return uint8(r), err
+}
+
+// parseFReg parses a floating point register name s into an 8 bit register
+// number.
+func parseFReg(s string) (uint8, error) {
+	r, err := parseFRegNum(s)
+	return uint8(r), err
+}
+
+// parseVReg parses a vector register name s into an 8 bit register number.
+func parseVReg(s string) (uint8, error) {
+	r, err := parseVRegNum(s)
+	return uint8(r), err
+}
+
+// parseRounding parses name of a rounding mode s.
+func parseRounding(s string) (uint8, error) {
+	for i, n := range roundingNames {
+		if n != "" && n == s {
+			return uint8(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown rounding mode: %q", s)
+}
+
+// parseImm parses an immediate value in decimal or hexadecimal notation.
+func parseImm(s string) (int32, error) {
+	v, err := strconv.ParseInt(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid immediate value %q: %w", s, err)
+	}
+	return int32(v), nil
+}
+
+// parse parses an operand of kind k from s and stores its value into ops.
+func (k operandKind) parse(s string, ops *Operands) error {
+	var err error
+	switch k {
+	case operandRd:
+		ops.Rd, err = parseReg(s)
+	case operandRs1:
+		ops.Rs1, err = parseReg(s)
+	case operandRs2:
+		ops.Rs2, err = parseReg(s)
+	case operandZimm:
+		var v uint64
+		v, err = strconv.ParseUint(s, 0, 5)
+		ops.Rs1 = uint8(v)
+	case operandImm:
+		ops.Imm, err = parseImm(s)
+	case operandUimm:
+		var v uint64
+		v, err = strconv.ParseUint(s, 0, 20)
+		ops.Imm = int32(uint32(v) << 12)
+	case operandMem:
+		open := strings.IndexByte(s, '(')
+		if open < 0 || !strings.HasSuffix(s, ")") {
+			return fmt.Errorf("memory operand has to be imm(reg): %q", s)
+		}
+
+		immStr := strings.TrimSpace(s[:open])
+		if immStr == "" {
+			immStr = "0"
+		}
+		if ops.Imm, err = parseImm(immStr); err != nil {
+			return err
+		}
+
+		regStr := strings.TrimSpace(s[open+1 : len(s)-1])
+		ops.Rs1, err = parseReg(regStr)
+	case operandCsr:
+		var c csr
+		c, err = parseCsr(s)
+		ops.Imm = int32(c)
+	case operandPred:
+		ops.Pred, err = parseFenceSet(s)
+	case operandSucc:
+		ops.Succ, err = parseFenceSet(s)
+	case operandFd:
+		ops.Rd, err = parseFReg(s)
+	case operandFs1:
+		ops.Rs1, err = parseFReg(s)
+	case operandFs2:
+		ops.Rs2, err = parseFReg(s)
+	case operandFs3:
+		ops.Rs3, err = parseFReg(s)
+	case operandRm:
+		ops.Rm, err = parseRounding(s)
+	case operandVd, operandVs3:
+		ops.Rd, err = parseVReg(s)
+	case operandVs1:
+		ops.Rs1, err = parseVReg(s)
+	case operandVs2:
+		ops.Rs2, err = parseVReg(s)
+	case operandSimm5:
+		ops.Imm, err = parseImm(s)
+	case operandAddr:
+		if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+			return fmt.Errorf("vector memory operand has to be (reg): %q", s)
+		}
+		ops.Rs1, err = parseReg(strings.TrimSpace(s[1 : len(s)-1]))
+	case operandVtype, operandVtype10:
+		var t vtype
+		t, err = parseVtype(s)
+		ops.Imm = int32(t)
+	case operandVm:
+		if s != "v0.t" {
+			return fmt.Errorf("vector mask operand has to be v0.t: %q", s)
+		}
+		ops.Masked = true
+	case operandV0:
+		if s != "v0" {
+			return fmt.Errorf("vector mask register has to be v0: %q", s)
+		}
+	default:
+		panic(fmt.Sprintf("unknown operand kind: %d", k))
+	}
+
+	return err
+}
@@ -0,0 +1,303 @@
+package riscv
+
+import (
+	debugelf "debug/elf"
+	"errors"
+	"fmt"
+	"mltwist/internal/elf"
+	"mltwist/pkg/model"
+	"strings"
+)
+
+// ErrUnsupportedExtension is returned when a program requires a RISC-V
+// extension which is not implemented by this package.
+var ErrUnsupportedExtension = errors.New("unsupported RISC-V extension")
+
+// Target is a RISC-V variant together with a set of extensions a program is
+// built for.
+type Target struct {
+	Variant    Variant
+	Extensions []Extension
+}
+
+// Parser creates a new parser of instructions of t.
+func (t Target) Parser() Parser { return NewParser(t.Variant, t.Extensions...) }
+
+// Encoder creates a new encoder of instructions of t.
+func (t Target) Encoder() Encoder { return NewEncoder(t.Variant, t.Extensions...) }
+
+// MinInstrLen returns length of the shortest instruction of t in bytes.
+func (t Target) MinInstrLen() model.Addr {
+	for _, e := range t.Extensions {
+		if e == ExtC {
+			return compressedLen
+		}
+	}
+	return instructionLen
+}
+
+// extensionNames maps names of extensions in ISA strings to extensions.
+// Extensions which are part of the base instruction set of this package are
+// mapped to extI. Sub-extensions are mapped to the extension containing them.
+var extensionNames = map[string]Extension{
+	"i":        extI,
+	"m":        ExtM,
+	"a":        ExtA,
+	"c":        ExtC,
+	"f":        ExtF,
+	"d":        ExtD,
+	"zicsr":    extI,
+	"zifencei": extI,
+	"zba":      ExtZba,
+	"zbb":      ExtZbb,
+	"zbs":      ExtZbs,
+	"zicond":   ExtZicond,
+	"v":        ExtV,
+	// Embedded vector subsets are implied by V and they are supported as
+	// far as V is. Minimal VLEN extensions are satisfied by VLEN=128.
+	"zve32x":  ExtV,
+	"zve32f":  ExtV,
+	"zve64x":  ExtV,
+	"zve64f":  ExtV,
+	"zve64d":  ExtV,
+	"zvl32b":  extI,
+	"zvl64b":  extI,
+	"zvl128b": extI,
+	// Machine-level and supervisor-level ISAs of the privileged
+	// architecture.
+	"sm": ExtPriv,
+	"ss": ExtPriv,
+	// Hint-only extensions define no new encodings, so their instructions
+	// decode as the base instructions they alias.
+	"zihintpause": extI,
+	"zihintntl":   extI,
+	// Counters are CSRs accessed by Zicsr instructions and data-independent
+	// execution latency adds no encodings.
+	"zicntr": extI,
+	"zihpm":  extI,
+	"zkt":    extI,
+	// Subsets of M, A and C which current toolchains list next to the
+	// extension containing them.
+	"zmmul":  ExtM,
+	"zaamo":  ExtA,
+	"zalrsc": ExtA,
+	"zca":    ExtC,
+	"zcf":    ExtC,
+	"zcd":    ExtC,
+}
+
+// extensionG lists extensions the G shorthand stands for.
+var extensionG = []string{"i", "m", "a", "f", "d", "zicsr", "zifencei"}
+
+// ParseISA parses RISC-V ISA string s (for example rv64imac or
+// rv64i2p1_m2p0_zicsr2p0) into a target. Versions of extensions are ignored.
+//
+// If s contains an extension which is not supported by this package, an error
+// wrapping ErrUnsupportedExtension is returned.
+func ParseISA(s string) (Target, error) {
+	s = strings.ToLower(s)
+
+	var t Target
+	switch {
+	case strings.HasPrefix(s, "rv32"):
+		t.Variant = Variant32
+	case strings.HasPrefix(s, "rv64"):
+		t.Variant = Variant64
+	default:
+		return Target{}, fmt.Errorf("unknown ISA variant: %q", s)
+	}
+
+	names, err := isaExtensions(s[len("rv32"):])
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid ISA string %q: %w", s, err)
+	}
+	if len(names) == 0 || (names[0] != "i" && names[0] != "g" && names[0] != "e") {
+		return Target{}, fmt.Errorf("ISA string %q has no base ISA", s)
+	}
+
+	seen := make(map[Extension]struct{}, len(names))
+	for _, n := range expandG(names) {
+		ext, ok := extensionNames[n]
+		if !ok {
+			return Target{}, fmt.Errorf("%w: %q", ErrUnsupportedExtension, n)
+		}
+
+		if _, ok := seen[ext]; ok || ext == extI {
+			continue
+		}
+		seen[ext] = struct{}{}
+		t.Extensions = append(t.Extensions, ext)
+	}
+
+	return t, nil
+}
+
+// expandG replaces the G shorthand in names by extensions it stands for.
+func expandG(names []string) []string {
+	expanded := make([]string, 0, len(names)+len(extensionG))
+	for _, n := range names {
+		if n == "g" {
+			expanded = append(expanded, extensionG...)
+		} else {
+			expanded = append(expanded, n)
+		}
+	}
+	return expanded
+}
+
+// isaExtensions splits extensions part of an ISA string s into names of
+// extensions without versions.
+func isaExtensions(s string) ([]string, error) {
+	var names []string
+	for len(s) > 0 {
+		if s[0] == '_' {
+			s = s[1:]
+			continue
+		}
+
+		var name string
+		if c := s[0]; c == 'z' || c == 's' || c == 'x' {
+			end := strings.IndexByte(s, '_')
+			if end < 0 {
+				end = len(s)
+			}
+			name, s = trimVersion(s[:end]), s[end:]
+		} else if c >= 'a' && c <= 'z' {
+			name, s = s[:1], skipVersion(s[1:])
+		} else {
+			return nil, fmt.Errorf("unexpected character: %q", c)
+		}
+
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// skipVersion removes extension version (for example 2p1) from the start of s.
+func skipVersion(s string) string {
+	s = strings.TrimLeft(s, "0123456789")
+	if len(s) > 1 && s[0] == 'p' && isDigit(s[1]) {
+		s = strings.TrimLeft(s[1:], "0123456789")
+	}
+	return s
+}
+
+// trimVersion removes extension version (for example 2p0) from the end of
+// multi-letter extension name s.
+func trimVersion(s string) string {
+	s = strings.TrimRight(s, "0123456789")
+	if n := len(s); n > 1 && s[n-1] == 'p' && isDigit(s[n-2]) {
+		s = strings.TrimRight(s[:n-1], "0123456789")
+	}
+	return s
+}
+
+// Flags of RISC-V ELF files (e_flags).
+const (
+	flagRVC        = 0x1
+	flagFloatABI   = 0x6
+	flagRVE        = 0x8
+	flagFloatABISP = 0x2
+	flagFloatABIDP = 0x4
+)
+
+// tagArch is tag of RISC-V build attribute holding the ISA string.
+const tagArch = 5
+
+// DetectTarget identifies the RISC-V target an ELF file p is built for.
+//
+// The target is primarily read from the ISA string in .riscv.attributes
+// section. If the section is missing, the variant is derived from class of the
+// file and the file is expected to use M and A extensions. In such a case, ELF
+// flags are used to identify other extensions the program requires.
+//
+// ISA strings of firmware and kernels rarely list the privileged architecture,
+// so ExtPriv is always part of the target detected. Privileged instructions
+// never collide with other instructions, so they are harmless in application
+// code.
+func DetectTarget(p *elf.Parser) (Target, error) {
+	et, err := p.Target()
+	if err != nil {
+		return Target{}, err
+	}
+
+	attrs, err := p.Attributes(".riscv.attributes", "riscv")
+	if err != nil {
+		return Target{}, err
+	}
+
+	var arch string
+	if attrs != nil {
+		arch, _ = attrs.String(tagArch)
+	}
+
+	return targetFromELF(et, arch)
+}
+
+// targetFromELF identifies RISC-V target of a file with ELF target et and ISA
+// string arch. Empty arch means that the ISA string is unknown.
+func targetFromELF(et elf.Target, arch string) (Target, error) {
+	if et.Machine != debugelf.EM_RISCV {
+		return Target{}, fmt.Errorf("not a RISC-V file: %v", et.Machine)
+	}
+
+	var v Variant
+	switch et.Class {
+	case debugelf.ELFCLASS32:
+		v = Variant32
+	case debugelf.ELFCLASS64:
+		v = Variant64
+	default:
+		return Target{}, fmt.Errorf("unknown ELF class: %v", et.Class)
+	}
+
+	if arch != "" {
+		t, err := ParseISA(arch)
+		if err != nil {
+			return Target{}, err
+		}
+		if t.Variant != v {
+			return Target{}, fmt.Errorf(
+				"ISA string %q doesn't match ELF class %v", arch, et.Class)
+		}
+		return t.withExtension(ExtPriv), nil
+	}
+
+	if et.Flags&flagRVE != 0 {
+		return Target{}, fmt.Errorf("%w: %q", ErrUnsupportedExtension, "e")
+	}
+
+	t := Target{Variant: v, Extensions: []Extension{ExtM, ExtA}}
+	switch et.Flags & flagFloatABI {
+	case 0:
+	case flagFloatABISP:
+		t.Extensions = append(t.Extensions, ExtF)
+	case flagFloatABIDP:
+		t.Extensions = append(t.Extensions, ExtF, ExtD)
+	default:
+		// Quad precision floating point ABI.
+		return Target{}, fmt.Errorf("%w: %q", ErrUnsupportedExtension, "q")
+	}
+	if et.Flags&flagRVC != 0 {
+		t.Extensions = append(t.Extensions, ExtC)
+	}
+
+	return t.withExtension(ExtPriv), nil
+}
+
+// withExtension returns t with extension e added unless t already has it.
+func (t Target) withExtension(e Extension) Target {
+	for _, ext := range t.Extensions {
+		if ext == e {
+			return t
+		}
+	}
+
+	exts := make([]Extension, len(t.Extensions), len(t.Extensions)+1)
+	copy(exts, t.Extensions)
+	t.Extensions = append(exts, e)
+	return t
+}
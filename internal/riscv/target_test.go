@@ -0,0 +1,254 @@
+package riscv
+
+import (
+	debugelf "debug/elf"
+	"mltwist/internal/elf"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseISA(t *testing.T) {
+	tests := []struct {
+		isa         string
+		want        Target
+		unsupported bool
+		hasErr      bool
+	}{{
+		isa:  "rv32i",
+		want: Target{Variant: Variant32},
+	}, {
+		isa:  "rv64ima",
+		want: Target{Variant: Variant64, Extensions: []Extension{ExtM, ExtA}},
+	}, {
+		isa:  "RV64IAM",
+		want: Target{Variant: Variant64, Extensions: []Extension{ExtA, ExtM}},
+	}, {
+		isa:  "rv64i2p1_m2p0_a2p1_zicsr2p0_zifencei2p0",
+		want: Target{Variant: Variant64, Extensions: []Extension{ExtM, ExtA}},
+	}, {
+		isa:  "rv32i2_m_zicsr",
+		want: Target{Variant: Variant32, Extensions: []Extension{ExtM}},
+	}, {
+		isa: "rv64gc",
+		want: Target{
+			Variant:    Variant64,
+			Extensions: []Extension{ExtM, ExtA, ExtF, ExtD, ExtC},
+		},
+	}, {
+		isa: "rv32imf",
+		want: Target{
+			Variant:    Variant32,
+			Extensions: []Extension{ExtM, ExtF},
+		},
+	}, {
+		isa:         "rv64imafdq",
+		unsupported: true,
+	}, {
+		isa: "rv64imac",
+		want: Target{
+			Variant:    Variant64,
+			Extensions: []Extension{ExtM, ExtA, ExtC},
+		},
+	}, {
+		isa:         "rv32e",
+		unsupported: true,
+	}, {
+		isa:         "rv64i_zbc1p0",
+		unsupported: true,
+	}, {
+		isa: "rv64imac_zba1p0_zbb1p0_zbs1p0_zicond1p0",
+		want: Target{
+			Variant: Variant64,
+			Extensions: []Extension{
+				ExtM, ExtA, ExtC, ExtZba, ExtZbb, ExtZbs, ExtZicond,
+			},
+		},
+	}, {
+		isa: "rv64gcv",
+		want: Target{
+			Variant:    Variant64,
+			Extensions: []Extension{ExtM, ExtA, ExtF, ExtD, ExtC, ExtV},
+		},
+	}, {
+		isa: "rv32i2p1_v1p0_zve32x1p0_zve64x1p0_zvl128b1p0_zvl32b1p0_zvl64b1p0",
+		want: Target{
+			Variant:    Variant32,
+			Extensions: []Extension{ExtV},
+		},
+	}, {
+		isa: "rv64imac_zicsr_sm1p12_ss1p12",
+		want: Target{
+			Variant:    Variant64,
+			Extensions: []Extension{ExtM, ExtA, ExtC, ExtPriv},
+		},
+	}, {
+		// RVA22U64 profile.
+		isa: "rv64i2p1_m2p0_a2p1_f2p2_d2p2_c2p0_zicsr2p0_zicntr2p0_" +
+			"zihpm2p0_zifencei2p0_zihintpause2p0_zmmul1p0_zba1p0_zbb1p0_" +
+			"zbs1p0_zkt1p0",
+		want: Target{
+			Variant: Variant64,
+			Extensions: []Extension{
+				ExtM, ExtA, ExtF, ExtD, ExtC, ExtZba, ExtZbb, ExtZbs,
+			},
+		},
+	}, {
+		// GCC 14 default for rv64gc.
+		isa: "rv64i2p1_m2p0_a2p1_f2p2_d2p2_c2p0_zicsr2p0_zifencei2p0_zmmul1p0",
+		want: Target{
+			Variant:    Variant64,
+			Extensions: []Extension{ExtM, ExtA, ExtF, ExtD, ExtC},
+		},
+	}, {
+		isa: "rv64i2p1_m2p0_a2p1_f2p2_d2p2_c2p0_zicsr2p0_zifencei2p0_" +
+			"zihintpause2p0_zmmul1p0_zaamo1p0_zalrsc1p0_zca1p0_zcd1p0",
+		want: Target{
+			Variant:    Variant64,
+			Extensions: []Extension{ExtM, ExtA, ExtF, ExtD, ExtC},
+		},
+	}, {
+		isa: "rv32i2p1_zmmul1p0_zalrsc1p0_zca1p0_zcf1p0",
+		want: Target{
+			Variant:    Variant32,
+			Extensions: []Extension{ExtM, ExtA, ExtC},
+		},
+	}, {
+		isa:         "rv64iv_zvl256b",
+		unsupported: true,
+	}, {
+		isa:    "rv128i",
+		hasErr: true,
+	}, {
+		isa:    "rv64ma",
+		hasErr: true,
+	}, {
+		isa:    "rv64",
+		hasErr: true,
+	}, {
+		isa:    "rv64i+m",
+		hasErr: true,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.isa, func(t *testing.T) {
+			r := require.New(t)
+
+			target, err := ParseISA(tt.isa)
+			if tt.unsupported {
+				r.ErrorIs(err, ErrUnsupportedExtension)
+				return
+			} else if tt.hasErr {
+				r.Error(err)
+				r.NotErrorIs(err, ErrUnsupportedExtension)
+				return
+			}
+
+			r.NoError(err)
+			r.Equal(tt.want, target)
+		})
+	}
+}
+
+func TestTargetFromELF(t *testing.T) {
+	rv := func(c debugelf.Class, flags uint32) elf.Target {
+		return elf.Target{
+			Class:   c,
+			Machine: debugelf.EM_RISCV,
+			Flags:   flags,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		target      elf.Target
+		arch        string
+		want        Target
+		unsupported bool
+		hasErr      bool
+	}{{
+		name:   "rv64_no_attributes",
+		target: rv(debugelf.ELFCLASS64, 0),
+		want: Target{
+			Variant:    Variant64,
+			Extensions: []Extension{ExtM, ExtA, ExtPriv},
+		},
+	}, {
+		name:   "rv32_attributes",
+		target: rv(debugelf.ELFCLASS32, 0),
+		arch:   "rv32i2p1_m2p0",
+		want: Target{
+			Variant:    Variant32,
+			Extensions: []Extension{ExtM, ExtPriv},
+		},
+	}, {
+		name:   "privileged_attributes",
+		target: rv(debugelf.ELFCLASS64, 0),
+		arch:   "rv64i2p1_m2p0_sm1p12",
+		want: Target{
+			Variant:    Variant64,
+			Extensions: []Extension{ExtM, ExtPriv},
+		},
+	}, {
+		name:   "class_mismatch",
+		target: rv(debugelf.ELFCLASS32, 0),
+		arch:   "rv64i",
+		hasErr: true,
+	}, {
+		name:   "compressed",
+		target: rv(debugelf.ELFCLASS64, flagRVC),
+		want: Target{
+			Variant:    Variant64,
+			Extensions: []Extension{ExtM, ExtA, ExtC, ExtPriv},
+		},
+	}, {
+		name:   "single_float_abi",
+		target: rv(debugelf.ELFCLASS32, flagFloatABISP|flagRVC),
+		want: Target{
+			Variant:    Variant32,
+			Extensions: []Extension{ExtM, ExtA, ExtF, ExtC, ExtPriv},
+		},
+	}, {
+		name:   "double_float_abi",
+		target: rv(debugelf.ELFCLASS64, flagFloatABIDP),
+		want: Target{
+			Variant:    Variant64,
+			Extensions: []Extension{ExtM, ExtA, ExtF, ExtD, ExtPriv},
+		},
+	}, {
+		name:        "quad_float_abi",
+		target:      rv(debugelf.ELFCLASS64, flagFloatABI),
+		unsupported: true,
+	}, {
+		name:        "embedded",
+		target:      rv(debugelf.ELFCLASS32, flagRVE),
+		unsupported: true,
+	}, {
+		name: "other_machine",
+		target: elf.Target{
+			Class:   debugelf.ELFCLASS64,
+			Machine: debugelf.EM_X86_64,
+		},
+		hasErr: true,
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			target, err := targetFromELF(tt.target, tt.arch)
+			if tt.unsupported {
+				r.ErrorIs(err, ErrUnsupportedExtension)
+				return
+			} else if tt.hasErr {
+				r.Error(err)
+				return
+			}
+
+			r.NoError(err)
+			r.Equal(tt.want, target)
+		})
+	}
+}
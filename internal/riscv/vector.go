@@ -0,0 +1,210 @@
+package riscv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// vectorField returns position of operand k of vector instruction in bits of
+// the instruction - index of the lowest bit and number of bits.
+func (k operandKind) vectorField() (offset uint8, bits uint8) {
+	switch k {
+	case operandRd, operandVd, operandVs3:
+		return rd.bitOffset(), regBits
+	case operandRs1, operandVs1, operandZimm, operandSimm5, operandAddr:
+		return rs1.bitOffset(), regBits
+	case operandRs2, operandVs2:
+		return rs2.bitOffset(), regBits
+	case operandVtype:
+		return 20, 11
+	case operandVtype10:
+		return 20, 10
+	case operandVm:
+		return 25, 1
+	case operandV0:
+		return 0, 0
+	default:
+		panic(fmt.Sprintf("operand kind %d is not a vector operand", k))
+	}
+}
+
+// vectorValue returns value of operand k of ops as encoded in bits of vector
+// instruction. It returns false if the value cannot be encoded.
+func (k operandKind) vectorValue(ops Operands) (uint32, bool) {
+	switch k {
+	case operandRd, operandVd, operandVs3:
+		return uint32(ops.Rd), true
+	case operandRs1, operandVs1, operandZimm, operandAddr:
+		return uint32(ops.Rs1), true
+	case operandRs2, operandVs2:
+		return uint32(ops.Rs2), true
+	case operandSimm5:
+		if ops.Imm < -16 || ops.Imm > 15 {
+			return 0, false
+		}
+		return uint32(ops.Imm) & uint32(low5Bits), true
+	case operandVtype, operandVtype10:
+		return uint32(ops.Imm), ops.Imm >= 0
+	case operandVm:
+		if ops.Masked {
+			return 0, true
+		}
+		return 1, true
+	case operandV0:
+		return 0, true
+	default:
+		panic(fmt.Sprintf("operand kind %d is not a vector operand", k))
+	}
+}
+
+// setVectorValue sets operand k of ops to value v encoded in bits of vector
+// instruction.
+func (k operandKind) setVectorValue(v uint32, ops *Operands) {
+	switch k {
+	case operandRd, operandVd, operandVs3:
+		ops.Rd = uint8(v)
+	case operandRs1, operandVs1, operandZimm, operandAddr:
+		ops.Rs1 = uint8(v)
+	case operandRs2, operandVs2:
+		ops.Rs2 = uint8(v)
+	case operandSimm5:
+		ops.Imm = signExtend(v, 4)
+	case operandVtype, operandVtype10:
+		ops.Imm = int32(v)
+	case operandVm:
+		ops.Masked = v == 0
+	case operandV0:
+	default:
+		panic(fmt.Sprintf("operand kind %d is not a vector operand", k))
+	}
+}
+
+// vtype represents vector type - value of vtype CSR or vector type immediate of
+// vsetvli and vsetivli instructions. Vector type selects width of vector
+// elements and number of registers in a register group.
+type vtype uint64
+
+// vtypeFields is number of bits of vtype which encode its fields. All the
+// higher bits are reserved (or they are vill bit).
+const vtypeFields = 8
+
+// lmulNames are names of register group multipliers (LMUL) in assembler code.
+// Index in the array is value of vlmul field of vtype.
+var lmulNames = [8]string{"m1", "m2", "m4", "m8", "", "mf8", "mf4", "mf2"}
+
+// vlmul returns vlmul field of t encoding register group multiplier (LMUL).
+func (t vtype) vlmul() uint8 { return uint8(t & 0x7) }
+
+// vsew returns vsew field of t encoding selected element width (SEW).
+func (t vtype) vsew() uint8 { return uint8(t >> 3 & 0x7) }
+
+// symbolic indicates that t can be written in assembler code in the symbolic
+// form (e32, m1, ta, ma).
+func (t vtype) symbolic() bool {
+	return t>>vtypeFields == 0 && t.vsew() <= 3 && lmulNames[t.vlmul()] != ""
+}
+
+// vlmax returns maximal number of elements (VLMAX) a register group of type t
+// holds. Zero is returned if t is reserved or if it's not supported by the
+// implementation.
+func (t vtype) vlmax() uint64 {
+	if !t.symbolic() {
+		return 0
+	}
+
+	bits := uint64(vlen.Bits())
+	if l := t.vlmul(); l < 4 {
+		bits <<= l
+	} else {
+		bits >>= 8 - l
+	}
+
+	// Fractional LMUL has to be at least SEW/ELEN. As VLEN is fixed, this
+	// is the same as VLMAX being at least VLEN/ELEN.
+	max := bits >> (3 + t.vsew())
+	if max < uint64(vlen/elen) {
+		return 0
+	}
+	return max
+}
+
+// String returns representation of t in assembler code.
+func (t vtype) String() string {
+	if !t.symbolic() {
+		return fmt.Sprintf("%d", uint64(t))
+	}
+
+	ta, ma := "tu", "mu"
+	if t&(1<<6) != 0 {
+		ta = "ta"
+	}
+	if t&(1<<7) != 0 {
+		ma = "ma"
+	}
+
+	return fmt.Sprintf("e%d, %s, %s, %s",
+		8<<t.vsew(), lmulNames[t.vlmul()], ta, ma)
+}
+
+// parseVtype parses vector type s in assembler code. Both the symbolic form
+// (e32, m1, ta, ma) and an integer are accepted. Tail and mask policies can be
+// omitted in the symbolic form, undisturbed policies are used in such a case.
+func parseVtype(s string) (vtype, error) {
+	parts := strings.Split(s, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	if len(parts) == 1 {
+		v, err := strconv.ParseUint(parts[0], 0, 11)
+		if err != nil {
+			return 0, fmt.Errorf("invalid vector type %q: %w", s, err)
+		}
+		return vtype(v), nil
+	}
+	if len(parts) != 2 && len(parts) != 4 {
+		return 0, fmt.Errorf("invalid vector type: %q", s)
+	}
+
+	var t vtype
+	for vsew := uint8(0); vsew <= 3; vsew++ {
+		if parts[0] == fmt.Sprintf("e%d", 8<<vsew) {
+			t |= vtype(vsew) << 3
+			break
+		} else if vsew == 3 {
+			return 0, fmt.Errorf("invalid element width: %q", parts[0])
+		}
+	}
+
+	for vlmul, n := range lmulNames {
+		if n != "" && n == parts[1] {
+			t |= vtype(vlmul)
+			break
+		} else if vlmul == len(lmulNames)-1 {
+			return 0, fmt.Errorf("invalid register group multiplier: %q", parts[1])
+		}
+	}
+
+	if len(parts) == 2 {
+		return t, nil
+	}
+
+	switch parts[2] {
+	case "ta":
+		t |= 1 << 6
+	case "tu":
+	default:
+		return 0, fmt.Errorf("invalid tail policy: %q", parts[2])
+	}
+
+	switch parts[3] {
+	case "ma":
+		t |= 1 << 7
+	case "mu":
+	default:
+		return 0, fmt.Errorf("invalid mask policy: %q", parts[3])
+	}
+
+	return t, nil
+}
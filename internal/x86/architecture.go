@@ -0,0 +1,32 @@
+package x86
+
+import (
+	debugelf "debug/elf"
+	"encoding/binary"
+	"mltwist/internal/elf"
+	"mltwist/internal/parser"
+)
+
+func init() { parser.Register(debugelf.EM_X86_64, newArchitecture) }
+
+// newArchitecture creates description of x86-64 architecture. ISA strings are
+// not supported.
+//
+// Keys of registers are their names, so no register names have to be
+// provided.
+func newArchitecture(_ *elf.Parser, isa string) (parser.Architecture, error) {
+	if isa != "" {
+		return parser.Architecture{}, parser.ErrISAUnsupported
+	}
+
+	p := NewParser()
+	return parser.Architecture{
+		Name:        "x86-64",
+		Parser:      p,
+		MemoryKey:   MemoryKey,
+		MinInstrLen: p.MinInstrLen(),
+		IPWidth:     width64,
+		ByteOrder:   binary.LittleEndian,
+		IPName:      "rip",
+	}, nil
+}
@@ -0,0 +1,205 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// condNames are names of condition codes in assembler code. Index in the array
+// is the value of condition code encoded in the lowest 4 bits of opcodes of
+// conditional instructions.
+var condNames = [...]string{
+	"o", "no", "b", "ae", "e", "ne", "be", "a",
+	"s", "ns", "p", "np", "l", "ge", "le", "g",
+}
+
+// condName returns name of condition code cc in assembler code.
+func condName(cc uint8) string { return condNames[cc] }
+
+// flagLoad loads value of flag identified by key k.
+func flagLoad(k expr.Key) expr.Expr { return expr.NewRegLoad(k, flagWidth) }
+
+// condition returns an expression of width w which evaluates to t if condition
+// code cc holds for the current value of flags and to f otherwise.
+//
+// Condition codes are organized in pairs where the lowest bit of a condition
+// code inverts the condition.
+func condition(cc uint8, t, f expr.Expr, w expr.Width) expr.Expr {
+	if cc&1 != 0 {
+		t, f = f, t
+	}
+
+	cf, zf, sf, of := flagLoad(CFKey), flagLoad(ZFKey), flagLoad(SFKey), flagLoad(OFKey)
+	switch cc >> 1 {
+	case 0: // o
+		return exprtools.BoolCond(of, t, f, w)
+	case 1: // b
+		return exprtools.BoolCond(cf, t, f, w)
+	case 2: // e
+		return exprtools.BoolCond(zf, t, f, w)
+	case 3: // be
+		return exprtools.BoolCond(cf, t, exprtools.BoolCond(zf, t, f, w), w)
+	case 4: // s
+		return exprtools.BoolCond(sf, t, f, w)
+	case 5: // p
+		return exprtools.BoolCond(flagLoad(PFKey), t, f, w)
+	case 6: // l
+		return exprtools.Eq(sf, of, f, t, w)
+	default: // le
+		return exprtools.BoolCond(zf, t, exprtools.Eq(sf, of, f, t, w), w)
+	}
+}
+
+// rflags holds values of status flags an instruction writes. Every value is an
+// expression evaluating to 1 if the flag is set and to 0 otherwise. Flags with
+// nil value are not changed by the instruction.
+//
+// Flags the architecture leaves undefined after an instruction are modelled as
+// not changed.
+type rflags struct {
+	cf, pf, af, zf, sf, of expr.Expr
+}
+
+// isZero returns an expression evaluating to 1 if e of width w is zero and to 0
+// otherwise.
+func isZero(e expr.Expr, w expr.Width) expr.Expr {
+	return expr.NewLess(e, expr.One, expr.One, expr.Zero, w)
+}
+
+// signBit returns an expression evaluating to the sign bit of e of width w.
+func signBit(e expr.Expr, w expr.Width) expr.Expr {
+	return exprtools.Bool(exprtools.IntNegative(e, w))
+}
+
+// bitOf returns an expression evaluating to bit n of e of width w.
+func bitOf(e expr.Expr, n uint16, w expr.Width) expr.Expr {
+	sh := expr.NewBinary(expr.Rsh, e, expr.ConstFromUint(n), w)
+	return exprtools.MaskBits(sh, 1, w)
+}
+
+// parity returns an expression evaluating to 1 if the lowest byte of e has even
+// number of bits set.
+func parity(e expr.Expr) expr.Expr {
+	cnt := exprtools.PopCount(e, width8)
+	return isZero(exprtools.MaskBits(cnt, 1, width8), width8)
+}
+
+// resultFlags returns PF, ZF and SF flags set according to value res of width
+// w. Other flags are not changed.
+func resultFlags(res expr.Expr, w expr.Width) rflags {
+	return rflags{
+		pf: parity(res),
+		zf: isZero(res, w),
+		sf: signBit(res, w),
+	}
+}
+
+// logicFlags returns flags set by logical operations producing value res of
+// width w. Such operations always clear CF and OF flags.
+func logicFlags(res expr.Expr, w expr.Width) rflags {
+	f := resultFlags(res, w)
+	f.cf, f.of = expr.Zero, expr.Zero
+	return f
+}
+
+// auxCarry returns AF flag of addition or subtraction of e1 and e2 with result
+// res. The flag is set if there is a carry (or a borrow) out of bit 3.
+func auxCarry(e1, e2, res expr.Expr, w expr.Width) expr.Expr {
+	return bitOf(exprtools.BitXor(exprtools.BitXor(e1, e2, w), res, w), 4, w)
+}
+
+// addCarry returns sum of values e1 and e2 of width w and carry c together with
+// flags the sum sets.
+//
+// Carry flag is set if the unsigned sum doesn't fit w bytes, overflow flag is
+// set if the signed sum does not.
+func addCarry(e1, e2, c expr.Expr, w expr.Width) (expr.Expr, rflags) {
+	res := expr.NewBinary(expr.Add, expr.NewBinary(expr.Add, e1, e2, w), c, w)
+
+	// Both arguments are zero extended to double width, so the bit above
+	// the sum is the unsigned carry.
+	wide := expr.NewBinary(expr.Add, expr.NewBinary(expr.Add, e1, e2, 2*w), c, 2*w)
+	carry := expr.NewBinary(expr.Rsh, wide, expr.ConstFromUint(w.Bits()), 2*w)
+
+	// Signed overflow happens if both arguments have the same sign and
+	// the sign of the sum differs.
+	overflow := exprtools.BitAnd(
+		exprtools.BitXor(res, e1, w),
+		exprtools.BitXor(res, e2, w),
+		w,
+	)
+
+	f := resultFlags(res, w)
+	f.cf = exprtools.Bool(carry)
+	f.of = signBit(overflow, w)
+	f.af = auxCarry(e1, e2, res, w)
+	return res, f
+}
+
+// subBorrow returns difference of values e1 and e2 of width w with borrow b
+// subtracted together with flags the subtraction sets.
+//
+// The subtraction is implemented as addition of bitwise negation of e2 with
+// inverted borrow as carry. Carry flag then holds the inverted borrow.
+func subBorrow(e1, e2, b expr.Expr, w expr.Width) (expr.Expr, rflags) {
+	res, f := addCarry(e1, exprtools.BitNot(e2, w), exprtools.Not(b), w)
+	f.cf = exprtools.Not(f.cf)
+	f.af = auxCarry(e1, e2, res, w)
+	return res, f
+}
+
+// store returns effects writing flags f to their registers.
+func (f rflags) store() []expr.Effect {
+	var effects []expr.Effect
+	for _, flag := range []struct {
+		v expr.Expr
+		k expr.Key
+	}{
+		{f.cf, CFKey}, {f.pf, PFKey}, {f.af, AFKey},
+		{f.zf, ZFKey}, {f.sf, SFKey}, {f.of, OFKey},
+	} {
+		if flag.v != nil {
+			effects = append(effects, expr.NewRegStore(flag.v, flag.k, flagWidth))
+		}
+	}
+	return effects
+}
+
+// unlessZero returns flags which keep their values if count of width w is zero
+// and which are set to f otherwise.
+func (f rflags) unlessZero(count expr.Expr, w expr.Width) rflags {
+	sel := func(v expr.Expr, k expr.Key) expr.Expr {
+		if v == nil {
+			return nil
+		}
+		return exprtools.BoolCond(isZero(count, w), flagLoad(k), v, flagWidth)
+	}
+	return rflags{
+		cf: sel(f.cf, CFKey),
+		pf: sel(f.pf, PFKey),
+		af: sel(f.af, AFKey),
+		zf: sel(f.zf, ZFKey),
+		sf: sel(f.sf, SFKey),
+		of: sel(f.of, OFKey),
+	}
+}
+
+// rflagsBits lists positions of flags in the rflags register.
+var rflagsBits = []struct {
+	k   expr.Key
+	bit uint8
+}{
+	{CFKey, 0}, {PFKey, 2}, {AFKey, 4}, {ZFKey, 6},
+	{SFKey, 7}, {DFKey, 10}, {OFKey, 11},
+}
+
+// rflagsValue returns value of the rflags register composed of the flags the
+// package models. Bit 1 is always set, all other bits are zero.
+func rflagsValue() expr.Expr {
+	var v expr.Expr = expr.NewConstUint[uint64](1<<1, width64)
+	for _, b := range rflagsBits {
+		f := expr.NewBinary(expr.Lsh, flagLoad(b.k), expr.ConstFromUint(b.bit), width64)
+		v = exprtools.BitOr(v, f, width64)
+	}
+	return v
+}
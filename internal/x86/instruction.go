@@ -0,0 +1,290 @@
+package x86
+
+import (
+	"encoding/binary"
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"strings"
+)
+
+// instruction represents a parsed x86-64 instruction. Unlike the
+// instructionType which describes only instruction opcode and properties of the
+// opcode, instruction represents the whole instruction including its prefixes,
+// operands and last but not least the position in the code.
+type instruction struct {
+	// addr is virtual address of the instruction in a program memory.
+	addr model.Addr
+	// bytes are all bytes of the instruction including its prefixes.
+	bytes []byte
+
+	prefixes prefixes
+	// opcodeEnd is index of the first byte following opcode bytes of the
+	// instruction in bytes.
+	opcodeEnd int
+
+	// modRM is the ModRM byte of the instruction. It's valid only if the
+	// instruction type has ModRM byte.
+	modRM modRM
+	// mem is the memory operand of the instruction. It's valid only if
+	// the ModRM byte refers memory.
+	mem memOperand
+
+	// immAt is index of the first byte of the immediate operand in bytes.
+	immAt int
+
+	// instrType refers the type of the instruction.
+	instrType *instructionType
+}
+
+// errTooShort returns an error reporting that bs are too short to be an
+// instruction.
+func errTooShort(bs []byte) error {
+	return fmt.Errorf("bytes are too short to be an x86-64 instruction: 0x%x", bs)
+}
+
+// decodeInstruction decodes an instruction of type t with prefixes p at
+// address a. The instruction is at the beginning of bs and its opcode starts
+// at index opcodeAt.
+func decodeInstruction(
+	a model.Addr,
+	bs []byte,
+	p prefixes,
+	opcodeAt int,
+	t *instructionType,
+) (instruction, error) {
+	i := instruction{
+		addr:      a,
+		prefixes:  p,
+		opcodeEnd: opcodeAt + t.opcodeLen(),
+		instrType: t,
+	}
+
+	n := i.opcodeEnd
+	if t.modRM {
+		if n >= len(bs) {
+			return instruction{}, errTooShort(bs)
+		}
+		i.modRM = modRM(bs[n])
+		n++
+
+		if i.modRM.memory() {
+			mem, l, err := decodeMemOperand(i.modRM, p, bs[n:])
+			if err != nil {
+				return instruction{}, err
+			}
+			mem.dispAt += n
+			i.mem = mem
+			n += l
+		}
+	}
+
+	i.immAt = n
+	n += t.imm.len(i.width())
+	if n > maxInstructionLen {
+		return instruction{}, fmt.Errorf(
+			"instruction is longer than %d bytes: 0x%x",
+			maxInstructionLen, bs[:maxInstructionLen])
+	}
+	if n > len(bs) {
+		return instruction{}, errTooShort(bs)
+	}
+
+	i.bytes = append([]byte(nil), bs[:n]...)
+	return i, nil
+}
+
+// len returns length of the instruction in bytes.
+func (i instruction) len() int { return len(i.bytes) }
+
+// next returns address of the instruction following i.
+func (i instruction) next() model.Addr { return i.addr + model.Addr(i.len()) }
+
+// width returns size of operands of the instruction.
+func (i instruction) width() expr.Width {
+	switch i.instrType.size {
+	case sizeB:
+		return width8
+	case sizeF64:
+		return width64
+	case sizeD64:
+		if i.prefixes.opSize {
+			return width16
+		}
+		return width64
+	default:
+		switch {
+		case i.prefixes.rex&rexW != 0:
+			return width64
+		case i.prefixes.opSize:
+			return width16
+		default:
+			return width32
+		}
+	}
+}
+
+// lastOpcodeByte returns the last byte of the opcode of the instruction.
+func (i instruction) lastOpcodeByte() byte { return i.bytes[i.opcodeEnd-1] }
+
+// opcodeLow returns the lowest 4 bits of the last opcode byte. They encode
+// condition codes of conditional instructions.
+func (i instruction) opcodeLow() uint8 { return i.lastOpcodeByte() & 0xf }
+
+// opcodeRegNum returns register encoded in the lowest 3 bits of the last
+// opcode byte extended by REX.B bit.
+func (i instruction) opcodeRegNum() regNum {
+	return regNum(i.lastOpcodeByte()&regMask) | i.prefixes.rexBit(rexB)
+}
+
+// regNum returns register encoded in the reg field of ModRM byte extended by
+// REX.R bit.
+func (i instruction) regNum() regNum {
+	return regNum(i.modRM.reg()) | i.prefixes.rexBit(rexR)
+}
+
+// rmNum returns register encoded in the rm field of ModRM byte extended by
+// REX.B bit.
+func (i instruction) rmNum() regNum {
+	return regNum(i.modRM.rm()) | i.prefixes.rexBit(rexB)
+}
+
+// rmIsMem indicates that the r/m operand of the instruction is in memory.
+func (i instruction) rmIsMem() bool { return i.modRM.memory() }
+
+// immBytes returns bytes of the immediate operand.
+func (i instruction) immBytes() []byte {
+	return i.bytes[i.immAt : i.immAt+i.instrType.imm.len(i.width())]
+}
+
+// immValue returns value of the immediate operand. Signed immediates are sign
+// extended to 64 bits.
+func (i instruction) immValue() uint64 {
+	return readValue(i.immBytes(), i.instrType.imm.signed())
+}
+
+// readValue reads little-endian value of bs. The value is sign extended if
+// signed is set.
+func readValue(bs []byte, signed bool) uint64 {
+	var buf [8]byte
+	copy(buf[:], bs)
+	if signed && len(bs) > 0 && bs[len(bs)-1]&0x80 != 0 {
+		for j := len(bs); j < len(buf); j++ {
+			buf[j] = 0xff
+		}
+	}
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+// target returns the address relative branches jump to.
+func (i instruction) target() model.Addr { return i.next() + model.Addr(i.immValue()) }
+
+var _ model.Relocator = instruction{}
+
+// Name returns name of the instruction in assembler code.
+func (i instruction) Name() string {
+	name := i.instrType.name
+	if m := i.instrType.mnemonic; m != nil {
+		name = m(i)
+	}
+
+	if i.instrType.suffix && i.rmIsMem() {
+		name += sizeSuffix(i.width())
+	}
+	return name
+}
+
+// sizeSuffixes are suffixes of names of instructions with operands of a given
+// width.
+var sizeSuffixes = map[expr.Width]string{
+	width8:  "b",
+	width16: "w",
+	width32: "l",
+	width64: "q",
+}
+
+// sizeSuffix returns suffix of name of an instruction with operands of width
+// w.
+func sizeSuffix(w expr.Width) string { return sizeSuffixes[w] }
+
+// String returns a string representation of an instruction in AT&T syntax used
+// by GNU assembler.
+func (i instruction) String() string {
+	name := i.Name()
+	switch {
+	case i.prefixes.lock:
+		name = "lock " + name
+	case i.prefixes.rep && i.instrType.repeatable:
+		name = "rep " + name
+	}
+
+	if i.instrType.branch {
+		if i.prefixes.segment == prefixDS && i.instrType.modRM {
+			name = "notrack " + name
+		}
+		switch {
+		case i.prefixes.repNE:
+			name = "bnd " + name
+		case i.prefixes.rep:
+			name = "repz " + name
+		}
+		if i.prefixes.addrSize && !i.instrType.modRM && i.lastOpcodeByte() != 0xe3 {
+			name = "addr32 " + name
+		}
+	}
+
+	ops := i.instrType.operands(i)
+	if len(ops) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s %s", name, strings.Join(ops, ","))
+}
+
+// Relocate returns the instruction and its opcode bytes as if the instruction
+// was placed at address a.
+//
+// Offsets of relative branches and RIP-relative memory operands are re-encoded
+// so that the instruction refers the same address as it referred before the
+// move. An error is returned if the new offset cannot be represented by the
+// instruction. Other instructions are returned unchanged.
+func (i instruction) Relocate(
+	a model.Addr,
+) (model.PlatformDetails, []byte, error) {
+	moved := i
+	moved.addr = a
+	moved.bytes = append([]byte(nil), i.bytes...)
+
+	if i.instrType.imm.relative() {
+		_, err := moved.writeOffset(i.immAt, len(i.immBytes()), i.target())
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if i.rmIsMem() && i.mem.rip {
+		off, err := moved.writeOffset(i.mem.dispAt, 4, i.mem.ripTarget(i))
+		if err != nil {
+			return nil, nil, err
+		}
+		moved.mem.disp = off
+	}
+
+	return moved, moved.bytes, nil
+}
+
+// writeOffset encodes offset of address to from the following instruction to
+// l bytes of the instruction starting at index at. It returns the offset
+// encoded.
+func (i instruction) writeOffset(at, l int, to model.Addr) (int64, error) {
+	off := model.Offset(i.next(), to)
+	bits := 8 * l
+	if off < -(1<<(bits-1)) || off >= 1<<(bits-1) {
+		return 0, fmt.Errorf(
+			"cannot encode offset of %q: offset doesn't fit %d bits: %d",
+			i, bits, off)
+	}
+
+	for j := 0; j < l; j++ {
+		i.bytes[at+j] = byte(off >> (8 * j))
+	}
+	return off, nil
+}
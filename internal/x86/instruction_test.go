@@ -0,0 +1,251 @@
+package x86
+
+import (
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstruction_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		want  string
+	}{{
+		name:  "register_operands",
+		bytes: []byte{0x48, 0x01, 0xf7},
+		want:  "add %rsi,%rdi",
+	}, {
+		name:  "rex_registers",
+		bytes: []byte{0x4d, 0x89, 0xc8},
+		want:  "mov %r9,%r8",
+	}, {
+		name:  "rex_byte_registers",
+		bytes: []byte{0x45, 0x88, 0xc8},
+		want:  "mov %r9b,%r8b",
+	}, {
+		name:  "high_byte_register",
+		bytes: []byte{0x08, 0xe0},
+		want:  "or %ah,%al",
+	}, {
+		name:  "immediate_suffix",
+		bytes: []byte{0x83, 0x03, 0x01},
+		want:  "addl $0x1,(%rbx)",
+	}, {
+		name:  "negative_displacement",
+		bytes: []byte{0x89, 0x45, 0xfc},
+		want:  "mov %eax,-0x4(%rbp)",
+	}, {
+		name:  "sib",
+		bytes: []byte{0x8b, 0x44, 0x8b, 0x10},
+		want:  "mov 0x10(%rbx,%rcx,4),%eax",
+	}, {
+		name:  "index_without_base",
+		bytes: []byte{0x48, 0x8b, 0x04, 0xcd, 0x00, 0x10, 0x00, 0x00},
+		want:  "mov 0x1000(,%rcx,8),%rax",
+	}, {
+		name:  "rip_relative",
+		bytes: []byte{0x48, 0x8d, 0x05, 0x08, 0x00, 0x00, 0x00},
+		want:  "lea 0x8(%rip),%rax",
+	}, {
+		name:  "segment_absolute",
+		bytes: []byte{0x64, 0x48, 0x8b, 0x04, 0x25, 0x28, 0x00, 0x00, 0x00},
+		want:  "mov %fs:0x28,%rax",
+	}, {
+		name:  "movabs",
+		bytes: []byte{0x48, 0xb8, 0x88, 0x77, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11},
+		want:  "movabs $0x1122334455667788,%rax",
+	}, {
+		name:  "extension",
+		bytes: []byte{0x48, 0x0f, 0xb7, 0x03},
+		want:  "movzwq (%rbx),%rax",
+	}, {
+		name:  "sign_extension",
+		bytes: []byte{0x48, 0x63, 0xc0},
+		want:  "movslq %eax,%rax",
+	}, {
+		name:  "lock",
+		bytes: []byte{0xf0, 0x0f, 0xc1, 0x03},
+		want:  "lock xadd %eax,(%rbx)",
+	}, {
+		name:  "rep",
+		bytes: []byte{0xf3, 0x48, 0xa5},
+		want:  "rep movsq %ds:(%rsi),%es:(%rdi)",
+	}, {
+		name:  "shift_by_one",
+		bytes: []byte{0xd1, 0xe8},
+		want:  "shr %eax",
+	}, {
+		name:  "shift_by_cl",
+		bytes: []byte{0x48, 0xd3, 0xf8},
+		want:  "sar %cl,%rax",
+	}, {
+		name:  "imul_immediate",
+		bytes: []byte{0x48, 0x6b, 0xfe, 0xfd},
+		want:  "imul $0xfffffffffffffffd,%rsi,%rdi",
+	}, {
+		name:  "accumulator_extension",
+		bytes: []byte{0x48, 0x98},
+		want:  "cltq",
+	}, {
+		name:  "condition",
+		bytes: []byte{0x48, 0x0f, 0x4f, 0xc6},
+		want:  "cmovg %rsi,%rax",
+	}, {
+		name:  "tzcnt",
+		bytes: []byte{0xf3, 0x48, 0x0f, 0xbc, 0xc6},
+		want:  "tzcnt %rsi,%rax",
+	}, {
+		name:  "push",
+		bytes: []byte{0x41, 0x54},
+		want:  "push %r12",
+	}, {
+		name:  "push_memory",
+		bytes: []byte{0xff, 0x73, 0x08},
+		want:  "push 0x8(%rbx)",
+	}, {
+		name:  "jump",
+		bytes: []byte{0xeb, 0x0e},
+		want:  "jmp 0x1010",
+	}, {
+		name:  "conditional_jump",
+		bytes: []byte{0x0f, 0x85, 0xfa, 0x01, 0x00, 0x00},
+		want:  "jne 0x1200",
+	}, {
+		name:  "indirect_call",
+		bytes: []byte{0xff, 0x53, 0x08},
+		want:  "call *0x8(%rbx)",
+	}, {
+		name:  "notrack",
+		bytes: []byte{0x3e, 0xff, 0xe0},
+		want:  "notrack jmp *%rax",
+	}, {
+		name:  "bnd",
+		bytes: []byte{0xf2, 0xff, 0xe0},
+		want:  "bnd jmp *%rax",
+	}, {
+		name:  "repz_ret",
+		bytes: []byte{0xf3, 0xc3},
+		want:  "repz ret",
+	}, {
+		name:  "jecxz",
+		bytes: []byte{0x67, 0xe3, 0x0d},
+		want:  "jecxz 0x1010",
+	}, {
+		name:  "nop",
+		bytes: []byte{0x0f, 0x1f, 0x40, 0x00},
+		want:  "nopl 0x0(%rax)",
+	}, {
+		name:  "nop_segment",
+		bytes: []byte{0x2e, 0x66, 0x0f, 0x1f, 0x84, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  "cs nopw 0x0(%rax,%rax,1)",
+	}, {
+		name:  "xchg_nop",
+		bytes: []byte{0x66, 0x90},
+		want:  "xchg %ax,%ax",
+	}, {
+		name:  "endbr64",
+		bytes: []byte{0xf3, 0x0f, 0x1e, 0xfa},
+		want:  "endbr64",
+	}}
+
+	p := NewParser()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := p.Parse(testAddr, tt.bytes)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, ins.Details.String())
+		})
+	}
+}
+
+func TestInstruction_Relocate(t *testing.T) {
+	tests := []struct {
+		name   string
+		bytes  []byte
+		addr   model.Addr
+		to     model.Addr
+		hasErr bool
+	}{{
+		// je 0x1010
+		name:  "jcc_rel8",
+		bytes: []byte{0x74, 0x0e},
+		addr:  0x1000,
+		to:    0x1080,
+	}, {
+		// je 0x1010
+		name:   "jcc_rel8_too_far",
+		bytes:  []byte{0x74, 0x0e},
+		addr:   0x1000,
+		to:     0x2000,
+		hasErr: true,
+	}, {
+		// call 0x1100
+		name:  "call",
+		bytes: []byte{0xe8, 0xfb, 0x00, 0x00, 0x00},
+		addr:  0x1000,
+		to:    0x40000000,
+	}, {
+		// call 0x1100
+		name:   "call_too_far",
+		bytes:  []byte{0xe8, 0xfb, 0x00, 0x00, 0x00},
+		addr:   0x1000,
+		to:     0x1_00000000,
+		hasErr: true,
+	}, {
+		// mov 0x100(%rip),%rax
+		name:  "rip_relative",
+		bytes: []byte{0x48, 0x8b, 0x05, 0x00, 0x01, 0x00, 0x00},
+		addr:  0x1000,
+		to:    0x8000,
+	}, {
+		// cmpl $0x1,0x100(%rip)
+		name:  "rip_relative_with_immediate",
+		bytes: []byte{0x83, 0x3d, 0x00, 0x01, 0x00, 0x00, 0x01},
+		addr:  0x1000,
+		to:    0x0800,
+	}, {
+		// add %rsi,%rdi
+		name:  "not_relative",
+		bytes: []byte{0x48, 0x01, 0xf7},
+		addr:  0x1000,
+		to:    0x1010,
+	}}
+
+	p := NewParser()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			ins, err := p.Parse(tt.addr, tt.bytes)
+			r.NoError(err)
+
+			details, bytes, err := ins.Details.(model.Relocator).Relocate(tt.to)
+			if tt.hasErr {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+
+			moved, err := p.Parse(tt.to, bytes)
+			r.NoError(err)
+			r.Equal(moved.Details, details)
+
+			orig, i := ins.Details.(instruction), details.(instruction)
+			r.Equal(tt.to, i.addr)
+			r.Equal(orig.Name(), i.Name())
+			switch {
+			case orig.instrType.imm.relative():
+				r.Equal(orig.target(), i.target())
+			case orig.rmIsMem() && orig.mem.rip:
+				r.Equal(orig.mem.ripTarget(orig), i.mem.ripTarget(i))
+				r.Equal(orig.immBytes(), i.immBytes())
+			default:
+				r.Equal(orig.bytes, i.bytes)
+			}
+		})
+	}
+}
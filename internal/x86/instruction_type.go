@@ -0,0 +1,332 @@
+package x86
+
+import (
+	"fmt"
+	"mltwist/internal/exprtransform"
+	"mltwist/internal/opcode"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+)
+
+// maxInstructionLen is the maximal length of an instruction in bytes
+// including all its prefixes.
+const maxInstructionLen = 15
+
+// operandSize describes how size of operands of an instruction is determined.
+type operandSize uint8
+
+const (
+	// sizeV means that operands are 64 bit with REX.W prefix, 16 bit with
+	// operand size prefix and 32 bit otherwise.
+	sizeV operandSize = iota
+	// sizeB means that operands are always 8 bit.
+	sizeB
+	// sizeD64 means that operands are 16 bit with operand size prefix and
+	// 64 bit otherwise. It's used by stack operations.
+	sizeD64
+	// sizeF64 means that operands are always 64 bit. It's used by near
+	// branches.
+	sizeF64
+)
+
+// immKind describes an immediate operand following ModRM byte, SIB byte and
+// displacement of an instruction.
+type immKind uint8
+
+const (
+	// immNone means that the instruction has no immediate operand.
+	immNone immKind = iota
+	// immB is an 8 bit immediate sign extended to operand size.
+	immB
+	// immW is a 16 bit unsigned immediate.
+	immW
+	// immZ is a 16 bit immediate for 16 bit operand size and a 32 bit
+	// immediate sign extended to operand size otherwise.
+	immZ
+	// immV is an immediate of operand size.
+	immV
+	// relB is an 8 bit signed offset relative to the following instruction.
+	relB
+	// relZ is a 32 bit signed offset relative to the following instruction.
+	relZ
+)
+
+// len returns length of immediate of kind k for operand size w in bytes.
+func (k immKind) len(w expr.Width) int {
+	switch k {
+	case immB, relB:
+		return 1
+	case immW:
+		return 2
+	case immZ:
+		if w == width16 {
+			return 2
+		}
+		return 4
+	case immV:
+		return int(w)
+	case relZ:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// signed indicates that immediate of kind k is sign extended.
+func (k immKind) signed() bool {
+	return k == immB || k == immZ || k == relB || k == relZ
+}
+
+// relative indicates that immediate of kind k is an offset relative to the
+// following instruction.
+func (k immKind) relative() bool { return k == relB || k == relZ }
+
+// instructionType describes a single x86-64 instruction opcode.
+type instructionType struct {
+	// name is a symbolic name of an instruction in assembler code.
+	//
+	// For example: add, mov, jmp etc.
+	name string
+	// opcode describes opcode bytes of an instruction following its
+	// prefixes. It includes the reg field of the ModRM byte if the field
+	// is an extension of the opcode.
+	opcode opcode.Opcode
+
+	// modRM indicates that the ModRM byte follows the opcode.
+	modRM bool
+	// opcodeReg indicates that the lowest 3 bits of the last opcode byte
+	// encode a register.
+	opcodeReg bool
+	// size describes how size of operands is determined.
+	size operandSize
+	// imm describes the immediate operand of the instruction.
+	imm immKind
+	// suffix indicates that the name of the instruction is written with
+	// operand size suffix if its r/m operand is in memory. Size of such
+	// instructions cannot be derived from their other operands.
+	suffix bool
+	// lockable indicates that the instruction can be prefixed with the
+	// lock prefix if its r/m operand is in memory.
+	lockable bool
+	// repeatable indicates that the instruction can be repeated using the
+	// rep prefix.
+	repeatable bool
+	// branch indicates that the instruction is a near branch. Branches
+	// can be prefixed with bnd prefix (0xf2) of memory protection
+	// extensions and indirect branches with notrack prefix (0x3e) of
+	// control flow enforcement. Both prefixes don't change semantics of
+	// the branch, neither do rep and address size prefixes some compilers
+	// emit for alignment or branch prediction reasons.
+	branch bool
+
+	// mnemonic returns the name of instruction i in assembler code if it
+	// depends on operands or prefixes of the instruction (for example
+	// condition of a conditional jump). The name field is used if
+	// mnemonic is nil.
+	mnemonic func(i instruction) string
+	// operands returns operands of instruction i in assembler code in the
+	// order they are written in AT&T syntax.
+	operands func(i instruction) []string
+	// reserved indicates that operands or prefixes of instruction i make
+	// the instruction invalid even though its opcode matched. All
+	// encodings of the opcode are valid if reserved is nil.
+	reserved func(i instruction) bool
+
+	// instrType is set of instruction types of an opcode.
+	instrType model.Type
+	// typeOf returns instruction type of instruction i if it depends on
+	// operands or prefixes of the instruction. The instrType field is
+	// used if typeOf is nil.
+	typeOf func(i instruction) model.Type
+	// order returns memory accesses ordered by instruction i. It has to be
+	// set if the instruction is of model.TypeMemOrder type and it's not a
+	// full barrier.
+	order func(i instruction) model.MemOrder
+
+	// effects is a function which based on specific instruction i evaluates
+	// all effects of the given instruction.
+	//
+	// The array returned is allowed to contain nil expr.Effect values.
+	// Those nils will be interpreted as no effects.
+	effects func(i instruction) []expr.Effect
+}
+
+// Opcode returns the opcode definition of a given instruction type.
+func (t instructionType) Opcode() opcode.Opcode { return t.opcode }
+func (t instructionType) Name() string          { return t.name }
+
+// op creates an opcode of bytes bs.
+func op(bs ...byte) opcode.Opcode {
+	mask := make([]byte, len(bs))
+	for i := range mask {
+		mask[i] = 0xff
+	}
+	return opcode.Opcode{Bytes: bs, Mask: mask}
+}
+
+// opExt creates an opcode of bytes bs followed by ModRM byte with reg field
+// ext.
+func opExt(ext byte, bs ...byte) opcode.Opcode {
+	o := op(bs...)
+	o.Bytes = append(o.Bytes, ext<<3)
+	o.Mask = append(o.Mask, regMask<<3)
+	return o
+}
+
+// opExtReg creates an opcode of bytes bs followed by ModRM byte with reg field
+// ext which refers a register.
+func opExtReg(ext byte, bs ...byte) opcode.Opcode {
+	o := op(bs...)
+	o.Bytes = append(o.Bytes, modReg<<6|ext<<3)
+	o.Mask = append(o.Mask, 0b11<<6|regMask<<3)
+	return o
+}
+
+// opReg creates an opcode of bytes bs where the lowest 3 bits of the last byte
+// encode a register.
+func opReg(bs ...byte) opcode.Opcode {
+	o := op(bs...)
+	o.Mask[len(o.Mask)-1] &^= regMask
+	return o
+}
+
+// opcodeLen returns number of opcode bytes excluding the ModRM byte.
+func (t instructionType) opcodeLen() int {
+	l := len(t.opcode.Mask)
+	if t.modRM && t.opcode.Mask[l-1] != 0xff {
+		return l - 1
+	}
+	return l
+}
+
+// validate checks that instructionType description is valid (follows all the
+// assumptions the code imposes on the struct).
+func (t instructionType) validate() error {
+	if t.name == "" {
+		return fmt.Errorf("instruction name cannot be empty")
+	}
+	if err := t.opcode.Validate(); err != nil {
+		return fmt.Errorf("invalid opcode description: %w", err)
+	}
+
+	last := t.opcode.Mask[len(t.opcode.Mask)-1]
+	if t.opcodeReg && (t.modRM || last != 0xff&^regMask) {
+		return fmt.Errorf("register in opcode byte not encoded in mask")
+	}
+	if !t.opcodeReg && !t.modRM && last != 0xff {
+		return fmt.Errorf("opcode mask without ModRM byte: 0x%x", t.opcode.Mask)
+	}
+	if l := t.opcodeLen(); l > 3 {
+		return fmt.Errorf("invalid opcode length: %d", l)
+	}
+	if t.lockable && !t.modRM {
+		return fmt.Errorf("lockable instruction without memory operand")
+	}
+
+	if t.typeOf != nil && t.instrType != model.TypeNone {
+		return fmt.Errorf("instruction type is given by operands")
+	}
+	if t.order != nil && t.typeOf == nil && !t.instrType.MemOrder() {
+		return fmt.Errorf("memory order of instruction not ordering memory")
+	}
+
+	if t.operands == nil {
+		return fmt.Errorf("operands function must be always set")
+	}
+	if t.effects == nil {
+		return fmt.Errorf("effects function must be always set")
+	}
+
+	return nil
+}
+
+// typeOfInstr returns instruction type of instruction i of type t.
+func (t instructionType) typeOfInstr(i instruction) model.Type {
+	typ := t.instrType
+	if t.typeOf != nil {
+		typ = t.typeOf(i)
+	}
+
+	// Locked instructions are full barriers.
+	if i.prefixes.lock {
+		typ |= model.TypeMemOrder
+	}
+	return typ
+}
+
+// memOrder returns memory accesses ordered by instruction i of type t.
+func (t instructionType) memOrder(i instruction) model.MemOrder {
+	if t.order == nil || !t.typeOfInstr(i).MemOrder() {
+		return model.MemOrder{}
+	}
+	return t.order(i)
+}
+
+// validEffects filters nil effects from a list of effects returned by effects
+// function and merges stores to the same register.
+func (t instructionType) validEffects(i instruction) []expr.Effect {
+	effs := t.effects(i)
+	if len(effs) == 0 {
+		return nil
+	}
+
+	effects := make([]expr.Effect, 0, len(effs))
+	stores := make(map[expr.Key]int, len(effs))
+	for _, e := range effs {
+		if e == nil {
+			continue
+		}
+
+		s, ok := e.(expr.RegStore)
+		if !ok {
+			effects = append(effects, e)
+			continue
+		}
+
+		j, ok := stores[s.Key()]
+		if !ok {
+			stores[s.Key()] = len(effects)
+			effects = append(effects, e)
+			continue
+		}
+		effects[j] = mergeRegStores(effects[j].(expr.RegStore), s)
+	}
+
+	return effects
+}
+
+// mergeRegStores merges stores s1 and s2 to the same register into a single
+// store as if s2 was applied after s1.
+//
+// Instructions with two register operands can write two views of the same
+// register, for example both al and ah. Each of the stores keeps other bits of
+// the register unchanged by loading the whole register, so those loads are
+// replaced by the value written by s1. Loads of operands are narrower than the
+// register and they are kept unchanged. If both operands are the same whole
+// register, s1 writes the original value to the register, so the replacement
+// doesn't change the value of s2.
+func mergeRegStores(s1, s2 expr.RegStore) expr.RegStore {
+	v := exprtransform.ReplaceAll(s2.Value(), func(l expr.RegLoad) (expr.Expr, bool) {
+		if l.Key() != s1.Key() || l.Width() != width64 {
+			return l, false
+		}
+		return s1.Value(), true
+	})
+	return expr.NewRegStore(v, s2.Key(), s2.Width())
+}
+
+// mergeInstructions merges multiple lists of instructionType into a single
+// list.
+func mergeInstructions(lists ...[]*instructionType) []*instructionType {
+	length := 0
+	for _, a := range lists {
+		length += len(a)
+	}
+
+	merged := make([]*instructionType, 0, length)
+	for _, a := range lists {
+		merged = append(merged, a...)
+	}
+
+	return merged
+}
@@ -0,0 +1,43 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+)
+
+// MemoryKey is identifier of the memory address space.
+//
+// Given that segments other than fs and gs have zero base in 64 bit mode, all
+// of them address the same memory and there is no reason to explain which
+// memory is identified by this key.
+const MemoryKey = expr.Key("memory")
+
+const (
+	width8   = expr.Width8
+	width16  = expr.Width16
+	width32  = expr.Width32
+	width64  = expr.Width64
+	width128 = expr.Width128
+)
+
+// instructions is the list of all instructions the package parses.
+var instructions = mergeInstructions(
+	arithInstructions(),
+	moveInstructions(),
+	branchInstructions(),
+	systemInstructions,
+)
+
+// addrConst creates a constant of width64 representing address a.
+func addrConst(a model.Addr) expr.Const { return expr.NewConstUint(a, width64) }
+
+// ipStore returns an effect jumping to address e.
+func ipStore(e expr.Expr) expr.Effect { return expr.NewRegStore(e, expr.IPKey, width64) }
+
+func memLoad(addr expr.Expr, w expr.Width) expr.Expr {
+	return expr.NewMemLoad(MemoryKey, addr, w)
+}
+
+func memStore(e expr.Expr, addr expr.Expr, w expr.Width) expr.Effect {
+	return expr.NewMemStore(e, MemoryKey, addr, w)
+}
@@ -0,0 +1,426 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// Operand functions of instructions with operands in standard positions. They
+// list operands in AT&T order, so the destination is the last one.
+
+func regRMOperands(i instruction) []string {
+	w := i.width()
+	return []string{i.regString(w), i.rmString(w)}
+}
+
+func rmRegOperands(i instruction) []string {
+	w := i.width()
+	return []string{i.rmString(w), i.regString(w)}
+}
+
+func immRMOperands(i instruction) []string {
+	w := i.width()
+	return []string{i.immString(w), i.rmString(w)}
+}
+
+func immAccOperands(i instruction) []string {
+	w := i.width()
+	return []string{i.immString(w), gpr{num: regAX}.name(w)}
+}
+
+func rmOperands(i instruction) []string { return []string{i.rmString(i.width())} }
+
+func noOperands(instruction) []string { return nil }
+
+// operandsFunc returns destination operand and source value of width w of
+// instruction i.
+type operandsFunc func(i instruction, w expr.Width) (dst operand, src expr.Expr)
+
+// Source functions of instructions with operands in standard positions.
+
+func rmRegSource(i instruction, w expr.Width) (operand, expr.Expr) {
+	return i.rmOperand(w), i.regOperand(w).load(w)
+}
+
+func regRMSource(i instruction, w expr.Width) (operand, expr.Expr) {
+	return i.regOperand(w), i.rmOperand(w).load(w)
+}
+
+func rmImmSource(i instruction, w expr.Width) (operand, expr.Expr) {
+	return i.rmOperand(w), i.immConst(w)
+}
+
+func accImmSource(i instruction, w expr.Width) (operand, expr.Expr) {
+	return gpr{num: regAX}, i.immConst(w)
+}
+
+// aluOp is a binary operation of the integer ALU.
+type aluOp struct {
+	name string
+	// apply returns result of the operation on values e1 and e2 of width
+	// w together with flags the operation sets.
+	apply func(e1, e2 expr.Expr, w expr.Width) (expr.Expr, rflags)
+	// compare indicates that the result is not written to the
+	// destination, only flags are set.
+	compare bool
+}
+
+func logicOp(f func(e1, e2 expr.Expr, w expr.Width) expr.Expr) func(
+	e1, e2 expr.Expr,
+	w expr.Width,
+) (expr.Expr, rflags) {
+	return func(e1, e2 expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		res := f(e1, e2, w)
+		return res, logicFlags(res, w)
+	}
+}
+
+// aluOps are operations of the ALU indexed by bits [3:5] of their opcode or by
+// the opcode extension in ModRM byte.
+var aluOps = [...]aluOp{{
+	name: "add",
+	apply: func(e1, e2 expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		return addCarry(e1, e2, expr.Zero, w)
+	},
+}, {
+	name:  "or",
+	apply: logicOp(exprtools.BitOr),
+}, {
+	name: "adc",
+	apply: func(e1, e2 expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		return addCarry(e1, e2, flagLoad(CFKey), w)
+	},
+}, {
+	name: "sbb",
+	apply: func(e1, e2 expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		return subBorrow(e1, e2, flagLoad(CFKey), w)
+	},
+}, {
+	name:  "and",
+	apply: logicOp(exprtools.BitAnd),
+}, {
+	name: "sub",
+	apply: func(e1, e2 expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		return subBorrow(e1, e2, expr.Zero, w)
+	},
+}, {
+	name:  "xor",
+	apply: logicOp(exprtools.BitXor),
+}, {
+	name: "cmp",
+	apply: func(e1, e2 expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		return subBorrow(e1, e2, expr.Zero, w)
+	},
+	compare: true,
+}}
+
+// testOp is the operation of test instruction.
+var testOp = aluOp{name: "test", apply: logicOp(exprtools.BitAnd), compare: true}
+
+// effects returns effects of instruction i applying the operation on operands
+// ops returns.
+func (o aluOp) effects(ops operandsFunc) func(i instruction) []expr.Effect {
+	return func(i instruction) []expr.Effect {
+		w := i.width()
+		dst, src := ops(i, w)
+		res, f := o.apply(dst.load(w), src, w)
+
+		effects := f.store()
+		if !o.compare {
+			effects = append(effects, dst.store(res, w))
+		}
+		return effects
+	}
+}
+
+// aluInstructions returns all encodings of ALU operation o with index n in
+// aluOps.
+func aluInstructions(n byte, o aluOp) []*instructionType {
+	base := n << 3
+	return []*instructionType{{
+		name:     o.name,
+		opcode:   op(base),
+		modRM:    true,
+		size:     sizeB,
+		lockable: !o.compare,
+		operands: regRMOperands,
+		effects:  o.effects(rmRegSource),
+	}, {
+		name:     o.name,
+		opcode:   op(base + 1),
+		modRM:    true,
+		lockable: !o.compare,
+		operands: regRMOperands,
+		effects:  o.effects(rmRegSource),
+	}, {
+		name:     o.name,
+		opcode:   op(base + 2),
+		modRM:    true,
+		size:     sizeB,
+		operands: rmRegOperands,
+		effects:  o.effects(regRMSource),
+	}, {
+		name:     o.name,
+		opcode:   op(base + 3),
+		modRM:    true,
+		operands: rmRegOperands,
+		effects:  o.effects(regRMSource),
+	}, {
+		name:     o.name,
+		opcode:   op(base + 4),
+		size:     sizeB,
+		imm:      immB,
+		operands: immAccOperands,
+		effects:  o.effects(accImmSource),
+	}, {
+		name:     o.name,
+		opcode:   op(base + 5),
+		imm:      immZ,
+		operands: immAccOperands,
+		effects:  o.effects(accImmSource),
+	}, {
+		name:     o.name,
+		opcode:   opExt(n, 0x80),
+		modRM:    true,
+		size:     sizeB,
+		imm:      immB,
+		suffix:   true,
+		lockable: !o.compare,
+		operands: immRMOperands,
+		effects:  o.effects(rmImmSource),
+	}, {
+		name:     o.name,
+		opcode:   opExt(n, 0x81),
+		modRM:    true,
+		imm:      immZ,
+		suffix:   true,
+		lockable: !o.compare,
+		operands: immRMOperands,
+		effects:  o.effects(rmImmSource),
+	}, {
+		name:     o.name,
+		opcode:   opExt(n, 0x83),
+		modRM:    true,
+		imm:      immB,
+		suffix:   true,
+		lockable: !o.compare,
+		operands: immRMOperands,
+		effects:  o.effects(rmImmSource),
+	}}
+}
+
+// arithInstructions returns all integer arithmetic and logical instructions.
+func arithInstructions() []*instructionType {
+	var list []*instructionType
+	for n, o := range aluOps {
+		list = append(list, aluInstructions(byte(n), o)...)
+	}
+
+	list = append(list, testInstructions...)
+	list = append(list, unaryInstructions...)
+	list = append(list, multiplyInstructions()...)
+	list = append(list, shiftInstructions()...)
+	list = append(list, bitInstructions()...)
+	list = append(list, conditionalInstructions()...)
+	return append(list, atomicInstructions...)
+}
+
+var testInstructions = []*instructionType{{
+	name:     "test",
+	opcode:   op(0x84),
+	modRM:    true,
+	size:     sizeB,
+	operands: regRMOperands,
+	effects:  testOp.effects(rmRegSource),
+}, {
+	name:     "test",
+	opcode:   op(0x85),
+	modRM:    true,
+	operands: regRMOperands,
+	effects:  testOp.effects(rmRegSource),
+}, {
+	name:     "test",
+	opcode:   op(0xa8),
+	size:     sizeB,
+	imm:      immB,
+	operands: immAccOperands,
+	effects:  testOp.effects(accImmSource),
+}, {
+	name:     "test",
+	opcode:   op(0xa9),
+	imm:      immZ,
+	operands: immAccOperands,
+	effects:  testOp.effects(accImmSource),
+}, {
+	name:     "test",
+	opcode:   opExt(0, 0xf6),
+	modRM:    true,
+	size:     sizeB,
+	imm:      immB,
+	suffix:   true,
+	operands: immRMOperands,
+	effects:  testOp.effects(rmImmSource),
+}, {
+	name:     "test",
+	opcode:   opExt(0, 0xf7),
+	modRM:    true,
+	imm:      immZ,
+	suffix:   true,
+	operands: immRMOperands,
+	effects:  testOp.effects(rmImmSource),
+}}
+
+// unaryInstructions are instructions with a single r/m operand which is both
+// the source and the destination.
+var unaryInstructions = unaryEncodings([]unaryOp{{
+	name:    "not",
+	ext:     2,
+	opcodes: [2]byte{0xf6, 0xf7},
+	apply: func(e expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		return exprtools.BitNot(e, w), rflags{}
+	},
+}, {
+	name:    "neg",
+	ext:     3,
+	opcodes: [2]byte{0xf6, 0xf7},
+	apply: func(e expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		return subBorrow(expr.Zero, e, expr.Zero, w)
+	},
+}, {
+	name:    "inc",
+	ext:     0,
+	opcodes: [2]byte{0xfe, 0xff},
+	apply: func(e expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		res, f := addCarry(e, expr.One, expr.Zero, w)
+		f.cf = nil
+		return res, f
+	},
+}, {
+	name:    "dec",
+	ext:     1,
+	opcodes: [2]byte{0xfe, 0xff},
+	apply: func(e expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		res, f := subBorrow(e, expr.One, expr.Zero, w)
+		f.cf = nil
+		return res, f
+	},
+}})
+
+// unaryOp is an operation of an instruction with a single r/m operand.
+type unaryOp struct {
+	name string
+	// ext is the opcode extension in ModRM byte.
+	ext byte
+	// opcodes are opcodes of the 8 bit and the full size variant of the
+	// instruction.
+	opcodes [2]byte
+	apply   func(e expr.Expr, w expr.Width) (expr.Expr, rflags)
+}
+
+// unaryEncodings returns both 8 bit and full size encodings of operations ops.
+func unaryEncodings(ops []unaryOp) []*instructionType {
+	var list []*instructionType
+	for _, o := range ops {
+		o := o
+		effects := func(i instruction) []expr.Effect {
+			w := i.width()
+			dst := i.rmOperand(w)
+			res, f := o.apply(dst.load(w), w)
+			return append(f.store(), dst.store(res, w))
+		}
+
+		for n, size := range [...]operandSize{sizeB, sizeV} {
+			list = append(list, &instructionType{
+				name:     o.name,
+				opcode:   opExt(o.ext, o.opcodes[n]),
+				modRM:    true,
+				size:     size,
+				suffix:   true,
+				lockable: true,
+				operands: rmOperands,
+				effects:  effects,
+			})
+		}
+	}
+	return list
+}
+
+// atomicInstructions are read-modify-write instructions commonly used with the
+// lock prefix to implement atomic operations.
+var atomicInstructions = []*instructionType{{
+	name:     "xadd",
+	opcode:   op(0x0f, 0xc0),
+	modRM:    true,
+	size:     sizeB,
+	lockable: true,
+	operands: regRMOperands,
+	effects:  xaddEffects,
+}, {
+	name:     "xadd",
+	opcode:   op(0x0f, 0xc1),
+	modRM:    true,
+	lockable: true,
+	operands: regRMOperands,
+	effects:  xaddEffects,
+}, {
+	name:     "cmpxchg",
+	opcode:   op(0x0f, 0xb0),
+	modRM:    true,
+	size:     sizeB,
+	lockable: true,
+	operands: regRMOperands,
+	effects:  cmpxchgEffects,
+}, {
+	name:     "cmpxchg",
+	opcode:   op(0x0f, 0xb1),
+	modRM:    true,
+	lockable: true,
+	operands: regRMOperands,
+	effects:  cmpxchgEffects,
+}}
+
+// xaddEffects returns effects of xadd instruction i which writes the sum of
+// both its operands to the r/m operand and the original value of the r/m
+// operand to the register operand.
+func xaddEffects(i instruction) []expr.Effect {
+	w := i.width()
+	dst, src := i.rmOperand(w), i.regOperand(w)
+	old := dst.load(w)
+	res, f := addCarry(old, src.load(w), expr.Zero, w)
+
+	// The sum is written after the original value, so it's the result if
+	// both operands are the same register.
+	return append(f.store(), src.store(old, w), dst.store(res, w))
+}
+
+// cmpxchgEffects returns effects of cmpxchg instruction i. The instruction
+// compares the accumulator with the r/m operand. If they are equal, the register
+// operand is written to the r/m operand. Otherwise the r/m operand is loaded to
+// the accumulator.
+//
+// The processor always writes the memory operand, so the original value is
+// written back if the comparison fails. On the other hand registers are written
+// only if they change, so their upper half is kept unchanged otherwise even for
+// 32 bit operands.
+func cmpxchgEffects(i instruction) []expr.Effect {
+	w := i.width()
+	dst := i.rmOperand(w)
+	old, acc := dst.load(w), regLoad(regAX, w)
+	_, f := subBorrow(acc, old, expr.Zero, w)
+
+	regW := w
+	if w == width32 {
+		regW = width64
+	}
+
+	newAcc := exprtools.Eq(acc, old, regLoad(regAX, regW), old, regW)
+	effects := append(f.store(), regStore(newAcc, regAX, regW))
+
+	src := i.regOperand(w).load(w)
+	if i.rmIsMem() {
+		return append(effects, dst.store(exprtools.Eq(acc, old, src, old, w), w))
+	}
+
+	r := i.rmRegOperand(w)
+	return append(effects, r.store(exprtools.Eq(acc, old, src, r.load(regW), regW), regW))
+}
@@ -0,0 +1,165 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+)
+
+func TestArithEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// add %rsi,%rdi
+		name:  "add_carry",
+		bytes: []byte{0x48, 0x01, 0xf7},
+		regs:  map[expr.Key]uint64{"rsi": 1, "rdi": 0xffffffff_ffffffff},
+		want:  withFlags(map[expr.Key]uint64{"rdi": 0}, 1, 1, 1, 1, 0, 0),
+	}, {
+		// add $0x1,%eax
+		name:  "add_imm_overflow",
+		bytes: []byte{0x83, 0xc0, 0x01},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_7fffffff},
+		want:  withFlags(map[expr.Key]uint64{"rax": 0x80000000}, 0, 1, 1, 0, 1, 1),
+	}, {
+		// add $0x7f,%al
+		name:  "add_acc_8",
+		bytes: []byte{0x04, 0x7f},
+		regs:  map[expr.Key]uint64{"rax": 0x1234_0001},
+		want:  withFlags(map[expr.Key]uint64{"rax": 0x1234_0080}, 0, 0, 1, 0, 1, 1),
+	}, {
+		// sub %ecx,%eax
+		name:  "sub_borrow",
+		bytes: []byte{0x29, 0xc8},
+		regs:  map[expr.Key]uint64{"rax": 1, "rcx": 2},
+		want:  withFlags(map[expr.Key]uint64{"rax": 0xffffffff}, 1, 1, 1, 0, 1, 0),
+	}, {
+		// cmp %rsi,%rdi
+		name:  "cmp_equal",
+		bytes: []byte{0x48, 0x39, 0xf7},
+		regs:  map[expr.Key]uint64{"rsi": 5, "rdi": 5},
+		want:  flags(0, 1, 0, 1, 0, 0),
+	}, {
+		// adc %rbx,%rax
+		name:  "adc",
+		bytes: []byte{0x48, 0x11, 0xd8},
+		regs:  map[expr.Key]uint64{"rax": 1, "rbx": 2, CFKey: 1},
+		want:  withFlags(map[expr.Key]uint64{"rax": 4}, 0, 0, 0, 0, 0, 0),
+	}, {
+		// sbb $0x1,%al
+		name:  "sbb",
+		bytes: []byte{0x1c, 0x01},
+		regs:  map[expr.Key]uint64{CFKey: 1},
+		want:  withFlags(map[expr.Key]uint64{"rax": 0xfe}, 1, 0, 1, 0, 1, 0),
+	}, {
+		// and $0xf0,%eax
+		name:  "and",
+		bytes: []byte{0x25, 0xf0, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_ffffffff},
+		want: map[expr.Key]uint64{
+			"rax": 0xf0,
+			CFKey: 0, PFKey: 1, ZFKey: 0, SFKey: 0, OFKey: 0,
+		},
+	}, {
+		// or %ah,%al
+		name:  "or_high_byte",
+		bytes: []byte{0x08, 0xe0},
+		regs:  map[expr.Key]uint64{"rax": 0x1201},
+		want: map[expr.Key]uint64{
+			"rax": 0x1213,
+			CFKey: 0, PFKey: 0, ZFKey: 0, SFKey: 0, OFKey: 0,
+		},
+	}, {
+		// xor %eax,%eax
+		name:  "xor_zero",
+		bytes: []byte{0x31, 0xc0},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_ffffffff},
+		want: map[expr.Key]uint64{
+			"rax": 0,
+			CFKey: 0, PFKey: 1, ZFKey: 1, SFKey: 0, OFKey: 0,
+		},
+	}, {
+		// test %al,%al
+		name:  "test",
+		bytes: []byte{0x84, 0xc0},
+		regs:  map[expr.Key]uint64{"rax": 0x80, CFKey: 1},
+		want: map[expr.Key]uint64{
+			CFKey: 0, PFKey: 0, ZFKey: 0, SFKey: 1, OFKey: 0,
+		},
+	}, {
+		// inc %rax
+		name:  "inc",
+		bytes: []byte{0x48, 0xff, 0xc0},
+		regs:  map[expr.Key]uint64{"rax": 0x7fffffff_ffffffff, CFKey: 1},
+		want: map[expr.Key]uint64{
+			"rax": 0x80000000_00000000,
+			PFKey: 1, AFKey: 1, ZFKey: 0, SFKey: 1, OFKey: 1,
+		},
+	}, {
+		// dec %ecx
+		name:  "dec",
+		bytes: []byte{0xff, 0xc9},
+		want: map[expr.Key]uint64{
+			"rcx": 0xffffffff,
+			PFKey: 1, AFKey: 1, ZFKey: 0, SFKey: 1, OFKey: 0,
+		},
+	}, {
+		// neg %rax
+		name:  "neg",
+		bytes: []byte{0x48, 0xf7, 0xd8},
+		regs:  map[expr.Key]uint64{"rax": 1},
+		want:  withFlags(map[expr.Key]uint64{"rax": 0xffffffff_ffffffff}, 1, 1, 1, 0, 1, 0),
+	}, {
+		// not %edx
+		name:  "not",
+		bytes: []byte{0xf7, 0xd2},
+		regs:  map[expr.Key]uint64{"rdx": 0xffff0000_000000f0},
+		want:  map[expr.Key]uint64{"rdx": 0xffffff0f},
+	}, {
+		// lock xadd %eax,(%rbx)
+		name:    "xadd",
+		bytes:   []byte{0xf0, 0x0f, 0xc1, 0x03},
+		regs:    map[expr.Key]uint64{"rax": 1, "rbx": 0x2000},
+		want:    withFlags(map[expr.Key]uint64{"rax": 0x83828180}, 0, 1, 0, 0, 1, 0),
+		wantMem: memBytes(0x2000, 0x81, 0x81, 0x82, 0x83),
+	}, {
+		// xadd %al,%ah
+		name:  "xadd_same_register",
+		bytes: []byte{0x0f, 0xc0, 0xc4},
+		regs:  map[expr.Key]uint64{"rax": 0x0201},
+		want:  withFlags(map[expr.Key]uint64{"rax": 0x0302}, 0, 1, 0, 0, 0, 0),
+	}, {
+		// lock cmpxchg %rcx,(%rbx)
+		name:  "cmpxchg_success",
+		bytes: []byte{0xf0, 0x48, 0x0f, 0xb1, 0x0b},
+		regs: map[expr.Key]uint64{
+			"rax": 0x87868584_83828180,
+			"rbx": 0x2000,
+			"rcx": 5,
+		},
+		want:    withFlags(map[expr.Key]uint64{"rax": 0x87868584_83828180}, 0, 1, 0, 1, 0, 0),
+		wantMem: memBytes(0x2000, 5, 0, 0, 0, 0, 0, 0, 0),
+	}, {
+		// lock cmpxchg %rcx,(%rbx)
+		name:  "cmpxchg_failure",
+		bytes: []byte{0xf0, 0x48, 0x0f, 0xb1, 0x0b},
+		regs:  map[expr.Key]uint64{"rax": 0x87868584_83828181, "rbx": 0x2000},
+		want: withFlags(
+			map[expr.Key]uint64{"rax": 0x87868584_83828180},
+			0, 0, 0, 0, 0, 0,
+		),
+		wantMem: memBytes(0x2000, 0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87),
+	}, {
+		// cmpxchg %ecx,%edx
+		name:  "cmpxchg_register_success",
+		bytes: []byte{0x0f, 0xb1, 0xca},
+		regs:  map[expr.Key]uint64{"rax": 2, "rcx": 7, "rdx": 0xffffffff_00000002},
+		want:  withFlags(map[expr.Key]uint64{"rax": 2, "rdx": 7}, 0, 1, 0, 1, 0, 0),
+	}, {
+		// cmpxchg %ecx,%edx
+		name:  "cmpxchg_register_failure",
+		bytes: []byte{0x0f, 0xb1, 0xca},
+		regs:  map[expr.Key]uint64{"rax": 1, "rcx": 7, "rdx": 0xffffffff_00000002},
+		want: withFlags(
+			map[expr.Key]uint64{"rax": 2, "rdx": 0xffffffff_00000002},
+			1, 1, 1, 0, 1, 0,
+		),
+	}})
+}
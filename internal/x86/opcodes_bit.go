@@ -0,0 +1,216 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// bitInstructions returns instructions scanning, counting, testing and
+// reordering bits of their operands.
+func bitInstructions() []*instructionType {
+	list := []*instructionType{{
+		name:     "bsf",
+		opcode:   op(0x0f, 0xbc),
+		modRM:    true,
+		mnemonic: repName("tzcnt"),
+		operands: rmRegOperands,
+		effects:  bitScanEffects(exprtools.TrailingZeros, false),
+	}, {
+		name:     "bsr",
+		opcode:   op(0x0f, 0xbd),
+		modRM:    true,
+		mnemonic: repName("lzcnt"),
+		operands: rmRegOperands,
+		effects:  bitScanEffects(exprtools.LeadingZeros, true),
+	}, {
+		name:     "popcnt",
+		opcode:   op(0x0f, 0xb8),
+		modRM:    true,
+		operands: rmRegOperands,
+		reserved: func(i instruction) bool { return !i.prefixes.rep },
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			src := i.rmOperand(w).load(w)
+			f := rflags{
+				cf: expr.Zero,
+				pf: expr.Zero,
+				af: expr.Zero,
+				zf: isZero(src, w),
+				sf: expr.Zero,
+				of: expr.Zero,
+			}
+			return append(f.store(), i.regOperand(w).store(exprtools.PopCount(src, w), w))
+		},
+	}, {
+		name:      "bswap",
+		opcode:    opReg(0x0f, 0xc8),
+		opcodeReg: true,
+		operands: func(i instruction) []string {
+			w := i.width()
+			return []string{i.opcodeRegOperand(w).name(w)}
+		},
+		// Result of byte swap of 16 bit operands is undefined.
+		reserved: func(i instruction) bool { return i.width() == width16 },
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			r := i.opcodeRegOperand(w)
+			return []expr.Effect{r.store(exprtools.ReverseBytes(r.load(w), w), w)}
+		},
+	}}
+
+	for n, name := range [...]string{"bt", "bts", "btr", "btc"} {
+		n := byte(n)
+		list = append(list, &instructionType{
+			name:     name,
+			opcode:   op(0x0f, 0xa3+n<<3),
+			modRM:    true,
+			lockable: n != 0,
+			operands: regRMOperands,
+			effects:  bitTestEffects(n, false),
+		}, &instructionType{
+			name:     name,
+			opcode:   opExt(4+n, 0x0f, 0xba),
+			modRM:    true,
+			imm:      immB,
+			suffix:   true,
+			lockable: n != 0,
+			operands: immRMOperands8,
+			effects:  bitTestEffects(n, true),
+		})
+	}
+
+	return list
+}
+
+// repName returns mnemonic function of an instruction which is called name if
+// it's prefixed with the rep prefix.
+func repName(name string) func(i instruction) string {
+	return func(i instruction) string {
+		if i.prefixes.rep {
+			return name
+		}
+		return i.instrType.name
+	}
+}
+
+// bitScanEffects returns effects of bit scan instructions counting zero bits
+// using count function. Argument reverse indicates that the instruction scans
+// bits from the most significant one.
+//
+// Without the rep prefix, the instruction writes index of the first set bit to
+// its destination and sets ZF if the source is zero. The destination is not
+// changed in such a case. With the prefix, the instruction writes number of
+// zero bits preceding the first set bit, sets CF if the source is zero and ZF
+// if the result is zero. Other flags are undefined.
+func bitScanEffects(
+	count func(e expr.Expr, w expr.Width) expr.Expr,
+	reverse bool,
+) func(i instruction) []expr.Effect {
+	return func(i instruction) []expr.Effect {
+		w := i.width()
+		dst, src := i.regOperand(w), i.rmOperand(w).load(w)
+		cnt := count(src, w)
+		srcZero := isZero(src, w)
+
+		if i.prefixes.rep {
+			f := rflags{cf: srcZero, zf: isZero(cnt, w)}
+			return append(f.store(), dst.store(cnt, w))
+		}
+
+		idx := cnt
+		if reverse {
+			idx = exprtools.Sub(expr.ConstFromUint(w.Bits()-1), cnt, w)
+		}
+		res := exprtools.BoolCond(srcZero, dst.load(w), idx, w)
+		return append(rflags{zf: srcZero}.store(), dst.store(res, w))
+	}
+}
+
+// bitTestEffects returns effects of bit test instructions. The instruction
+// copies the selected bit to CF and then keeps (n=0), sets (n=1), clears (n=2)
+// or complements (n=3) it. Argument imm indicates that the bit offset is an
+// immediate operand rather than a register.
+//
+// Register bit offsets of memory operands are signed and they can select a bit
+// outside of the operand. Other offsets select a bit modulo operand size.
+func bitTestEffects(n byte, imm bool) func(i instruction) []expr.Effect {
+	return func(i instruction) []expr.Effect {
+		w := i.width()
+		bitMask := expr.ConstFromUint(w.Bits() - 1)
+
+		var offset expr.Expr
+		if imm {
+			offset = i.immConst(width8)
+		} else {
+			offset = i.regOperand(w).load(w)
+		}
+
+		dst := i.rmOperand(w)
+		if !imm && i.rmIsMem() {
+			// Offset in operands of width w is the bit offset shifted
+			// arithmetically by log2 of number of bits of w.
+			var shift uint8
+			for b := w.Bits(); b > 1; b >>= 1 {
+				shift++
+			}
+			idx := exprtools.SignExtend(offset, expr.ConstFromUint(w.Bits()-1), width64)
+			idx = exprtools.RshA(idx, expr.ConstFromUint(shift), width64)
+			idx = expr.NewBinary(expr.Mul, idx, expr.ConstFromUint(uint8(w)), width64)
+			dst = memRef{addr: expr.NewBinary(expr.Add, i.memAddr(), idx, width64)}
+		}
+
+		bit := exprtools.BitAnd(offset, bitMask, w)
+		old := dst.load(w)
+		f := rflags{cf: dynBitOf(old, bit, w)}
+		if n == 0 {
+			return f.store()
+		}
+
+		mask := expr.NewBinary(expr.Lsh, expr.One, bit, w)
+		var res expr.Expr
+		switch n {
+		case 1:
+			res = exprtools.BitOr(old, mask, w)
+		case 2:
+			res = exprtools.BitAnd(old, exprtools.BitNot(mask, w), w)
+		default:
+			res = exprtools.BitXor(old, mask, w)
+		}
+		return append(f.store(), dst.store(res, w))
+	}
+}
+
+// conditionalInstructions returns instructions setting a byte or moving a
+// value based on a condition code.
+func conditionalInstructions() []*instructionType {
+	var list []*instructionType
+	for cc := uint8(0); cc < uint8(len(condNames)); cc++ {
+		cc := cc
+		list = append(list, &instructionType{
+			name:     "set" + condName(cc),
+			opcode:   op(0x0f, 0x90+cc),
+			modRM:    true,
+			size:     sizeB,
+			operands: rmOperands,
+			effects: func(i instruction) []expr.Effect {
+				v := condition(cc, expr.One, expr.Zero, width8)
+				return []expr.Effect{i.rmOperand(width8).store(v, width8)}
+			},
+		}, &instructionType{
+			name:     "cmov" + condName(cc),
+			opcode:   op(0x0f, 0x40+cc),
+			modRM:    true,
+			operands: rmRegOperands,
+			// The destination is written even if the condition
+			// doesn't hold, so 32 bit operations always clear the
+			// upper half of the register.
+			effects: func(i instruction) []expr.Effect {
+				w := i.width()
+				dst := i.regOperand(w)
+				v := condition(cc, i.rmOperand(w).load(w), dst.load(w), w)
+				return []expr.Effect{dst.store(v, w)}
+			},
+		})
+	}
+	return list
+}
@@ -0,0 +1,141 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+)
+
+func TestBitEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// bsf %rsi,%rax
+		name:  "bsf",
+		bytes: []byte{0x48, 0x0f, 0xbc, 0xc6},
+		regs:  map[expr.Key]uint64{"rsi": 0x80},
+		want:  map[expr.Key]uint64{"rax": 7, ZFKey: 0},
+	}, {
+		// bsf %rsi,%rax
+		name:  "bsf_zero",
+		bytes: []byte{0x48, 0x0f, 0xbc, 0xc6},
+		regs:  map[expr.Key]uint64{"rax": 0x1234},
+		want:  map[expr.Key]uint64{"rax": 0x1234, ZFKey: 1},
+	}, {
+		// bsr %esi,%eax
+		name:  "bsr",
+		bytes: []byte{0x0f, 0xbd, 0xc6},
+		regs:  map[expr.Key]uint64{"rsi": 0x1_00010000},
+		want:  map[expr.Key]uint64{"rax": 16, ZFKey: 0},
+	}, {
+		// tzcnt %rsi,%rax
+		name:  "tzcnt_zero",
+		bytes: []byte{0xf3, 0x48, 0x0f, 0xbc, 0xc6},
+		want:  map[expr.Key]uint64{"rax": 64, CFKey: 1, ZFKey: 0},
+	}, {
+		// lzcnt %esi,%eax
+		name:  "lzcnt",
+		bytes: []byte{0xf3, 0x0f, 0xbd, 0xc6},
+		regs:  map[expr.Key]uint64{"rsi": 1},
+		want:  map[expr.Key]uint64{"rax": 31, CFKey: 0, ZFKey: 0},
+	}, {
+		// popcnt %rsi,%rax
+		name:  "popcnt",
+		bytes: []byte{0xf3, 0x48, 0x0f, 0xb8, 0xc6},
+		regs:  map[expr.Key]uint64{"rsi": 0xff00ff},
+		want:  withFlags(map[expr.Key]uint64{"rax": 16}, 0, 0, 0, 0, 0, 0),
+	}, {
+		// bswap %eax
+		name:  "bswap_32",
+		bytes: []byte{0x0f, 0xc8},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_11223344},
+		want:  map[expr.Key]uint64{"rax": 0x44332211},
+	}, {
+		// bswap %r8
+		name:  "bswap_64",
+		bytes: []byte{0x49, 0x0f, 0xc8},
+		regs:  map[expr.Key]uint64{"r8": 0x11223344_55667788},
+		want:  map[expr.Key]uint64{"r8": 0x88776655_44332211},
+	}, {
+		// bt $0x3,%rax
+		name:  "bt",
+		bytes: []byte{0x48, 0x0f, 0xba, 0xe0, 0x03},
+		regs:  map[expr.Key]uint64{"rax": 8},
+		want:  map[expr.Key]uint64{CFKey: 1},
+	}, {
+		// bts %rcx,%rax
+		name:  "bts_register_modulo",
+		bytes: []byte{0x48, 0x0f, 0xab, 0xc8},
+		regs:  map[expr.Key]uint64{"rcx": 65},
+		want:  map[expr.Key]uint64{"rax": 2, CFKey: 0},
+	}, {
+		// btr $0x3f,%rax
+		name:  "btr",
+		bytes: []byte{0x48, 0x0f, 0xba, 0xf0, 0x3f},
+		regs:  map[expr.Key]uint64{"rax": 0x80000000_00000001},
+		want:  map[expr.Key]uint64{"rax": 1, CFKey: 1},
+	}, {
+		// btc %ecx,%eax
+		name:  "btc",
+		bytes: []byte{0x0f, 0xbb, 0xc8},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_00000001},
+		want:  map[expr.Key]uint64{"rax": 0, CFKey: 1},
+	}, {
+		// btl $0x21,(%rbx)
+		name:  "bt_memory_imm",
+		bytes: []byte{0x0f, 0xba, 0x23, 0x21},
+		regs:  map[expr.Key]uint64{"rbx": 0x2000},
+		want:  map[expr.Key]uint64{CFKey: 0},
+	}, {
+		// bts %rcx,(%rbx)
+		name:    "bts_memory_negative_offset",
+		bytes:   []byte{0x48, 0x0f, 0xab, 0x0b},
+		regs:    map[expr.Key]uint64{"rbx": 0x2000, "rcx": 0xffffffff_ffffffff},
+		want:    map[expr.Key]uint64{CFKey: 0},
+		wantMem: memBytes(0x1ff8, 0, 0, 0, 0, 0, 0, 0, 0x80),
+	}, {
+		// bts %rcx,(%rbx)
+		name:    "bts_memory_offset",
+		bytes:   []byte{0x48, 0x0f, 0xab, 0x0b},
+		regs:    map[expr.Key]uint64{"rbx": 0x2000, "rcx": 64},
+		want:    map[expr.Key]uint64{CFKey: 0},
+		wantMem: memBytes(0x2008, 0x89, 0x89, 0x8a, 0x8b, 0x8c, 0x8d, 0x8e, 0x8f),
+	}})
+}
+
+func TestConditionalEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// sete %al
+		name:  "sete",
+		bytes: []byte{0x0f, 0x94, 0xc0},
+		regs:  map[expr.Key]uint64{"rax": 0x1234, ZFKey: 1},
+		want:  map[expr.Key]uint64{"rax": 0x1201},
+	}, {
+		// setl %dl
+		name:  "setl",
+		bytes: []byte{0x0f, 0x9c, 0xc2},
+		regs:  map[expr.Key]uint64{SFKey: 1},
+		want:  map[expr.Key]uint64{"rdx": 1},
+	}, {
+		// setbe %ah
+		name:  "setbe_high_byte",
+		bytes: []byte{0x0f, 0x96, 0xc4},
+		regs:  map[expr.Key]uint64{"rax": 0xff00, CFKey: 1},
+		want:  map[expr.Key]uint64{"rax": 0x0100},
+	}, {
+		// cmovg %rsi,%rax
+		name:  "cmovg",
+		bytes: []byte{0x48, 0x0f, 0x4f, 0xc6},
+		regs:  map[expr.Key]uint64{"rsi": 5, SFKey: 1, OFKey: 1},
+		want:  map[expr.Key]uint64{"rax": 5},
+	}, {
+		// cmova %esi,%eax
+		name:  "cmova_not_taken",
+		bytes: []byte{0x0f, 0x47, 0xc6},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_00000005, "rsi": 7, CFKey: 1},
+		want:  map[expr.Key]uint64{"rax": 5},
+	}, {
+		// cmovp (%rbx),%ax
+		name:  "cmovp_memory",
+		bytes: []byte{0x66, 0x0f, 0x4a, 0x03},
+		regs:  map[expr.Key]uint64{"rbx": 0x2000, PFKey: 1},
+		want:  map[expr.Key]uint64{"rax": 0x8180},
+	}})
+}
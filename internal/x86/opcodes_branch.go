@@ -0,0 +1,148 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// branchInstructions returns all near jumps, calls and returns.
+func branchInstructions() []*instructionType {
+	list := []*instructionType{{
+		name:     "jmp",
+		opcode:   op(0xeb),
+		size:     sizeF64,
+		imm:      relB,
+		branch:   true,
+		operands: targetOperands,
+		effects:  jumpEffects,
+	}, {
+		name:     "jmp",
+		opcode:   op(0xe9),
+		size:     sizeF64,
+		imm:      relZ,
+		branch:   true,
+		operands: targetOperands,
+		effects:  jumpEffects,
+	}, {
+		name:     "call",
+		opcode:   op(0xe8),
+		size:     sizeF64,
+		imm:      relZ,
+		branch:   true,
+		operands: targetOperands,
+		effects: func(i instruction) []expr.Effect {
+			return append(push(addrConst(i.next()), width64), ipStore(addrConst(i.target())))
+		},
+	}, {
+		name:     "jmp",
+		opcode:   opExt(4, 0xff),
+		modRM:    true,
+		size:     sizeF64,
+		branch:   true,
+		operands: indirectOperands,
+		effects: func(i instruction) []expr.Effect {
+			return []expr.Effect{ipStore(i.rmOperand(width64).load(width64))}
+		},
+	}, {
+		name:     "call",
+		opcode:   opExt(2, 0xff),
+		modRM:    true,
+		size:     sizeF64,
+		branch:   true,
+		operands: indirectOperands,
+		effects: func(i instruction) []expr.Effect {
+			target := i.rmOperand(width64).load(width64)
+			return append(push(addrConst(i.next()), width64), ipStore(target))
+		},
+	}, {
+		name:     "ret",
+		opcode:   op(0xc3),
+		size:     sizeF64,
+		branch:   true,
+		operands: noOperands,
+		effects:  retEffects,
+	}, {
+		name:     "ret",
+		opcode:   op(0xc2),
+		size:     sizeF64,
+		imm:      immW,
+		branch:   true,
+		operands: immOperands,
+		effects:  retEffects,
+	}, {
+		name:   "jrcxz",
+		opcode: op(0xe3),
+		size:   sizeF64,
+		imm:    relB,
+		branch: true,
+		mnemonic: func(i instruction) string {
+			if i.addrWidth() == width32 {
+				return "jecxz"
+			}
+			return "jrcxz"
+		},
+		operands: targetOperands,
+		effects: func(i instruction) []expr.Effect {
+			w := i.addrWidth()
+			target := exprtools.BoolCond(
+				regLoad(regCX, w),
+				addrConst(i.next()),
+				addrConst(i.target()),
+				width64,
+			)
+			return []expr.Effect{ipStore(target)}
+		},
+	}}
+
+	for cc := uint8(0); cc < uint8(len(condNames)); cc++ {
+		list = append(list, &instructionType{
+			name:     "j" + condName(cc),
+			opcode:   op(0x70 + cc),
+			size:     sizeF64,
+			imm:      relB,
+			branch:   true,
+			operands: targetOperands,
+			effects:  condJumpEffects,
+		}, &instructionType{
+			name:     "j" + condName(cc),
+			opcode:   op(0x0f, 0x80+cc),
+			size:     sizeF64,
+			imm:      relZ,
+			branch:   true,
+			operands: targetOperands,
+			effects:  condJumpEffects,
+		})
+	}
+
+	return list
+}
+
+func targetOperands(i instruction) []string { return []string{i.targetString()} }
+
+// indirectOperands returns operand of an indirect branch i in assembler code.
+func indirectOperands(i instruction) []string {
+	return []string{"*" + i.rmString(width64)}
+}
+
+func jumpEffects(i instruction) []expr.Effect {
+	return []expr.Effect{ipStore(addrConst(i.target()))}
+}
+
+func condJumpEffects(i instruction) []expr.Effect {
+	target := condition(i.opcodeLow(), addrConst(i.target()), addrConst(i.next()), width64)
+	return []expr.Effect{ipStore(target)}
+}
+
+// retEffects returns effects of return instruction i. The instruction pops the
+// return address and then it optionally releases number of bytes of the stack
+// given by its immediate operand.
+func retEffects(i instruction) []expr.Effect {
+	off := int64(width64)
+	if i.instrType.imm != immNone {
+		off += int64(i.immValue())
+	}
+	return []expr.Effect{
+		ipStore(memLoad(stackPtr(), width64)),
+		regStore(stackOffset(off), regSP, width64),
+	}
+}
@@ -0,0 +1,132 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+)
+
+func TestBranchEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// jmp 0x1010
+		name:  "jmp_rel8",
+		bytes: []byte{0xeb, 0x0e},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// jmp 0xff0
+		name:  "jmp_rel32_backwards",
+		bytes: []byte{0xe9, 0xeb, 0xff, 0xff, 0xff},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr - 0x10},
+	}, {
+		// call 0x1100
+		name:  "call",
+		bytes: []byte{0xe8, 0xfb, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"rsp": 0x3008},
+		want: map[expr.Key]uint64{
+			expr.IPKey: testAddr + 0x100,
+			"rsp":      0x3000,
+		},
+		wantMem: memBytes(0x3000, 0x05, 0x10, 0, 0, 0, 0, 0, 0),
+	}, {
+		// jmp *%rax
+		name:  "jmp_register",
+		bytes: []byte{0xff, 0xe0},
+		regs:  map[expr.Key]uint64{"rax": 0x5000},
+		want:  map[expr.Key]uint64{expr.IPKey: 0x5000},
+	}, {
+		// call *0x8(%rbx)
+		name:  "call_memory",
+		bytes: []byte{0xff, 0x53, 0x08},
+		regs:  map[expr.Key]uint64{"rbx": 0x2000, "rsp": 0x3008},
+		want: map[expr.Key]uint64{
+			expr.IPKey: 0x8f8e8d8c_8b8a8988,
+			"rsp":      0x3000,
+		},
+		wantMem: memBytes(0x3000, 0x03, 0x10, 0, 0, 0, 0, 0, 0),
+	}, {
+		// ret
+		name:  "ret",
+		bytes: []byte{0xc3},
+		regs:  map[expr.Key]uint64{"rsp": 0x2000},
+		want: map[expr.Key]uint64{
+			expr.IPKey: 0x87868584_83828180,
+			"rsp":      0x2008,
+		},
+	}, {
+		// ret $0x10
+		name:  "ret_imm",
+		bytes: []byte{0xc2, 0x10, 0x00},
+		regs:  map[expr.Key]uint64{"rsp": 0x2000},
+		want: map[expr.Key]uint64{
+			expr.IPKey: 0x87868584_83828180,
+			"rsp":      0x2018,
+		},
+	}, {
+		// jrcxz 0x1010
+		name:  "jrcxz",
+		bytes: []byte{0xe3, 0x0e},
+		regs:  map[expr.Key]uint64{"rcx": 0x1_00000000},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 2},
+	}, {
+		// jecxz 0x1010
+		name:  "jecxz",
+		bytes: []byte{0x67, 0xe3, 0x0d},
+		regs:  map[expr.Key]uint64{"rcx": 0x1_00000000},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// je 0x1010
+		name:  "je_taken",
+		bytes: []byte{0x74, 0x0e},
+		regs:  map[expr.Key]uint64{ZFKey: 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// je 0x1010
+		name:  "je_not_taken",
+		bytes: []byte{0x74, 0x0e},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 2},
+	}, {
+		// jne 0x1200
+		name:  "jne_rel32",
+		bytes: []byte{0x0f, 0x85, 0xfa, 0x01, 0x00, 0x00},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x200},
+	}, {
+		// jl 0x1010
+		name:  "jl",
+		bytes: []byte{0x7c, 0x0e},
+		regs:  map[expr.Key]uint64{OFKey: 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// jg 0x1010
+		name:  "jg_zero",
+		bytes: []byte{0x7f, 0x0e},
+		regs:  map[expr.Key]uint64{ZFKey: 1, SFKey: 1, OFKey: 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 2},
+	}, {
+		// ja 0x1010
+		name:  "ja",
+		bytes: []byte{0x77, 0x0e},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// jbe 0x1010
+		name:  "jbe",
+		bytes: []byte{0x76, 0x0e},
+		regs:  map[expr.Key]uint64{ZFKey: 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// jo 0x1010
+		name:  "jo",
+		bytes: []byte{0x70, 0x0e},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 2},
+	}, {
+		// js 0x1010
+		name:  "js",
+		bytes: []byte{0x78, 0x0e},
+		regs:  map[expr.Key]uint64{SFKey: 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 0x10},
+	}, {
+		// jnp 0x1010
+		name:  "jnp",
+		bytes: []byte{0x7b, 0x0e},
+		regs:  map[expr.Key]uint64{PFKey: 1},
+		want:  map[expr.Key]uint64{expr.IPKey: testAddr + 2},
+	}})
+}
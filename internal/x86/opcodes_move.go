@@ -0,0 +1,467 @@
+package x86
+
+import (
+	"mltwist/internal/opcode"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"mltwist/pkg/model"
+)
+
+// moveInstructions returns instructions moving data between registers, memory
+// and the stack.
+func moveInstructions() []*instructionType {
+	return mergeInstructions(
+		movInstructions,
+		extendInstructions,
+		exchangeInstructions,
+		stackInstructions,
+		stringInstructions,
+	)
+}
+
+// movEffects returns effects of instruction i copying source value to the
+// destination operand returned by ops.
+func movEffects(ops operandsFunc) func(i instruction) []expr.Effect {
+	return func(i instruction) []expr.Effect {
+		w := i.width()
+		dst, src := ops(i, w)
+		return []expr.Effect{dst.store(src, w)}
+	}
+}
+
+func opcodeRegImmSource(i instruction, w expr.Width) (operand, expr.Expr) {
+	return i.opcodeRegOperand(w), i.immConst(w)
+}
+
+func immOpcodeRegOperands(i instruction) []string {
+	w := i.width()
+	return []string{i.immString(w), i.opcodeRegOperand(w).name(w)}
+}
+
+var movInstructions = []*instructionType{{
+	name:     "mov",
+	opcode:   op(0x88),
+	modRM:    true,
+	size:     sizeB,
+	operands: regRMOperands,
+	effects:  movEffects(rmRegSource),
+}, {
+	name:     "mov",
+	opcode:   op(0x89),
+	modRM:    true,
+	operands: regRMOperands,
+	effects:  movEffects(rmRegSource),
+}, {
+	name:     "mov",
+	opcode:   op(0x8a),
+	modRM:    true,
+	size:     sizeB,
+	operands: rmRegOperands,
+	effects:  movEffects(regRMSource),
+}, {
+	name:     "mov",
+	opcode:   op(0x8b),
+	modRM:    true,
+	operands: rmRegOperands,
+	effects:  movEffects(regRMSource),
+}, {
+	name:     "mov",
+	opcode:   opExt(0, 0xc6),
+	modRM:    true,
+	size:     sizeB,
+	imm:      immB,
+	suffix:   true,
+	operands: immRMOperands,
+	effects:  movEffects(rmImmSource),
+}, {
+	name:     "mov",
+	opcode:   opExt(0, 0xc7),
+	modRM:    true,
+	imm:      immZ,
+	suffix:   true,
+	operands: immRMOperands,
+	effects:  movEffects(rmImmSource),
+}, {
+	name:      "mov",
+	opcode:    opReg(0xb0),
+	opcodeReg: true,
+	size:      sizeB,
+	imm:       immB,
+	operands:  immOpcodeRegOperands,
+	effects:   movEffects(opcodeRegImmSource),
+}, {
+	name:      "mov",
+	opcode:    opReg(0xb8),
+	opcodeReg: true,
+	imm:       immV,
+	mnemonic: func(i instruction) string {
+		if i.width() == width64 {
+			return "movabs"
+		}
+		return "mov"
+	},
+	operands: immOpcodeRegOperands,
+	effects:  movEffects(opcodeRegImmSource),
+}, {
+	name:     "lea",
+	opcode:   op(0x8d),
+	modRM:    true,
+	operands: rmRegOperands,
+	reserved: func(i instruction) bool { return !i.rmIsMem() },
+	effects: func(i instruction) []expr.Effect {
+		w := i.width()
+		return []expr.Effect{i.regOperand(w).store(i.effectiveAddr(), w)}
+	},
+}}
+
+// extendInstructions are instructions moving a narrower value to a register
+// extending it to the register width.
+var extendInstructions = []*instructionType{
+	extendInstruction("movz", op(0x0f, 0xb6), width8, false),
+	extendInstruction("movz", op(0x0f, 0xb7), width16, false),
+	extendInstruction("movs", op(0x0f, 0xbe), width8, true),
+	extendInstruction("movs", op(0x0f, 0xbf), width16, true),
+	extendInstruction("movs", op(0x63), width32, true),
+}
+
+// extendInstruction returns instruction type with name prefix name and opcode o
+// extending r/m operand of width from to the width of its register operand.
+// The value is sign extended if signed is set and zero extended otherwise.
+//
+// Names of the instructions are completed by suffixes of both widths, so they
+// read movzbl, movswq etc.
+func extendInstruction(name string, o opcode.Opcode, from expr.Width, signed bool) *instructionType {
+	return &instructionType{
+		name:   name,
+		opcode: o,
+		modRM:  true,
+		mnemonic: func(i instruction) string {
+			return name + sizeSuffix(from) + sizeSuffix(i.width())
+		},
+		operands: func(i instruction) []string {
+			return []string{i.rmString(from), i.regString(i.width())}
+		},
+		// Extension to the same width is not an extension.
+		reserved: func(i instruction) bool { return i.width() <= from },
+		effects: func(i instruction) []expr.Effect {
+			w := i.width()
+			src := i.rmOperand(from).load(from)
+			if signed {
+				src = sext(src, from, w)
+			} else {
+				src = zext(src, from, w)
+			}
+			return []expr.Effect{i.regOperand(w).store(src, w)}
+		},
+	}
+}
+
+// exchangeEffects returns effects of instruction i swapping values of its two
+// operands returned by ops.
+func exchangeEffects(ops func(i instruction, w expr.Width) (operand, operand)) func(i instruction) []expr.Effect {
+	return func(i instruction) []expr.Effect {
+		w := i.width()
+		op1, op2 := ops(i, w)
+		return []expr.Effect{op1.store(op2.load(w), w), op2.store(op1.load(w), w)}
+	}
+}
+
+func rmRegExchange(i instruction, w expr.Width) (operand, operand) {
+	return i.rmOperand(w), i.regOperand(w)
+}
+
+// isNop indicates that exchange of the accumulator with a register encoded in
+// opcode of instruction i is a nop. The accumulator exchanged with itself
+// doesn't clear the upper half of the register in this case unlike other 32
+// bit operations.
+func isNop(i instruction) bool {
+	return i.opcodeRegNum() == regAX && !i.prefixes.opSize
+}
+
+var exchangeInstructions = []*instructionType{{
+	name:     "xchg",
+	opcode:   op(0x86),
+	modRM:    true,
+	size:     sizeB,
+	lockable: true,
+	operands: regRMOperands,
+	typeOf:   exchangeType,
+	effects:  exchangeEffects(rmRegExchange),
+}, {
+	name:     "xchg",
+	opcode:   op(0x87),
+	modRM:    true,
+	lockable: true,
+	operands: regRMOperands,
+	typeOf:   exchangeType,
+	effects:  exchangeEffects(rmRegExchange),
+}, {
+	name:      "xchg",
+	opcode:    opReg(0x90),
+	opcodeReg: true,
+	mnemonic: func(i instruction) string {
+		switch {
+		case !isNop(i):
+			return "xchg"
+		case i.prefixes.rep:
+			return "pause"
+		default:
+			return "nop"
+		}
+	},
+	operands: func(i instruction) []string {
+		if isNop(i) {
+			return nil
+		}
+		w := i.width()
+		return []string{gpr{num: regAX}.name(w), i.opcodeRegOperand(w).name(w)}
+	},
+	effects: func(i instruction) []expr.Effect {
+		if isNop(i) {
+			return nil
+		}
+		return exchangeEffects(func(i instruction, w expr.Width) (operand, operand) {
+			return gpr{num: regAX}, i.opcodeRegOperand(w)
+		})(i)
+	},
+}}
+
+// exchangeType returns instruction type of exchange of a register with an r/m
+// operand. Exchange with memory is always locked, so it's a full barrier.
+func exchangeType(i instruction) model.Type {
+	if i.rmIsMem() {
+		return model.TypeMemOrder
+	}
+	return model.TypeNone
+}
+
+// stackPtr returns value of the stack pointer.
+func stackPtr() expr.Expr { return regLoad(regSP, width64) }
+
+// stackOffset returns the stack pointer moved by off bytes.
+func stackOffset(off int64) expr.Expr {
+	return expr.NewBinary(expr.Add, stackPtr(), expr.NewConstInt(off, width64), width64)
+}
+
+// push returns effects pushing value e of width w to the stack.
+func push(e expr.Expr, w expr.Width) []expr.Effect {
+	sp := stackOffset(-int64(w))
+	return []expr.Effect{memStore(e, sp, w), regStore(sp, regSP, width64)}
+}
+
+// pop returns effects popping a value of width w from the stack to operand dst.
+func pop(dst operand, w expr.Width) []expr.Effect {
+	v := memLoad(stackPtr(), w)
+	if r, ok := dst.(gpr); ok && r.num == regSP {
+		return []expr.Effect{dst.store(v, w)}
+	}
+	return []expr.Effect{dst.store(v, w), regStore(stackOffset(int64(w)), regSP, width64)}
+}
+
+func opcodeRegOperands(i instruction) []string {
+	w := i.width()
+	return []string{i.opcodeRegOperand(w).name(w)}
+}
+
+func immOperands(i instruction) []string { return []string{i.immString(i.width())} }
+
+var stackInstructions = []*instructionType{{
+	name:      "push",
+	opcode:    opReg(0x50),
+	opcodeReg: true,
+	size:      sizeD64,
+	operands:  opcodeRegOperands,
+	effects: func(i instruction) []expr.Effect {
+		w := i.width()
+		return push(i.opcodeRegOperand(w).load(w), w)
+	},
+}, {
+	name:     "push",
+	opcode:   op(0x6a),
+	size:     sizeD64,
+	imm:      immB,
+	operands: immOperands,
+	effects: func(i instruction) []expr.Effect {
+		w := i.width()
+		return push(i.immConst(w), w)
+	},
+}, {
+	name:     "push",
+	opcode:   op(0x68),
+	size:     sizeD64,
+	imm:      immZ,
+	operands: immOperands,
+	effects: func(i instruction) []expr.Effect {
+		w := i.width()
+		return push(i.immConst(w), w)
+	},
+}, {
+	name:     "push",
+	opcode:   opExt(6, 0xff),
+	modRM:    true,
+	size:     sizeD64,
+	operands: rmOperands,
+	effects: func(i instruction) []expr.Effect {
+		w := i.width()
+		return push(i.rmOperand(w).load(w), w)
+	},
+}, {
+	name:      "pop",
+	opcode:    opReg(0x58),
+	opcodeReg: true,
+	size:      sizeD64,
+	operands:  opcodeRegOperands,
+	effects: func(i instruction) []expr.Effect {
+		w := i.width()
+		return pop(i.opcodeRegOperand(w), w)
+	},
+}, {
+	name:     "pop",
+	opcode:   opExt(0, 0x8f),
+	modRM:    true,
+	size:     sizeD64,
+	operands: rmOperands,
+	effects: func(i instruction) []expr.Effect {
+		w := i.width()
+		dst := i.rmOperand(w)
+		if m := i.mem; i.rmIsMem() && m.hasBase && m.base == regSP {
+			// Address based on the stack pointer is computed after
+			// the value is popped.
+			addr := expr.NewBinary(expr.Add, i.memAddr(), expr.ConstFromUint(uint8(w)), width64)
+			dst = memRef{addr: addr}
+		}
+		return pop(dst, w)
+	},
+}, {
+	name:     "leave",
+	opcode:   op(0xc9),
+	size:     sizeD64,
+	operands: noOperands,
+	effects: func(i instruction) []expr.Effect {
+		w := i.width()
+		bp := regLoad(regBP, width64)
+		sp := expr.NewBinary(expr.Add, bp, expr.ConstFromUint(uint8(w)), width64)
+		return []expr.Effect{
+			regStore(memLoad(bp, w), regBP, w),
+			regStore(sp, regSP, width64),
+		}
+	},
+}}
+
+// stringInstructions are instructions operating on memory addressed by rsi and
+// rdi registers. They can be repeated rcx times using the rep prefix.
+var stringInstructions = []*instructionType{{
+	name:       "movs",
+	opcode:     op(0xa4),
+	size:       sizeB,
+	repeatable: true,
+	mnemonic:   movsName,
+	operands:   movsOperands,
+	effects:    stringEffects(movsIteration),
+}, {
+	name:       "movs",
+	opcode:     op(0xa5),
+	repeatable: true,
+	mnemonic:   movsName,
+	operands:   movsOperands,
+	effects:    stringEffects(movsIteration),
+}, {
+	name:       "stos",
+	opcode:     op(0xaa),
+	size:       sizeB,
+	repeatable: true,
+	operands:   stosOperands,
+	effects:    stringEffects(stosIteration),
+}, {
+	name:       "stos",
+	opcode:     op(0xab),
+	repeatable: true,
+	operands:   stosOperands,
+	effects:    stringEffects(stosIteration),
+}}
+
+func movsName(i instruction) string { return "movs" + sizeSuffix(i.width()) }
+
+// stringOperand returns memory operand of a string instruction i addressed by
+// register r in segment seg in assembler code.
+func stringOperand(i instruction, seg string, r regNum) string {
+	return "%" + seg + ":(" + gpr{num: r}.name(i.addrWidth()) + ")"
+}
+
+func movsOperands(i instruction) []string {
+	return []string{stringOperand(i, "ds", regSI), stringOperand(i, "es", regDI)}
+}
+
+func stosOperands(i instruction) []string {
+	return []string{gpr{num: regAX}.name(i.width()), stringOperand(i, "es", regDI)}
+}
+
+// stringIteration returns effects of a single iteration of a string instruction
+// i with operands of width w. Argument step is the value rsi and rdi registers
+// are moved by and argument active evaluates to a nonzero value if the
+// iteration is executed. Inactive iterations must not change any state.
+type stringIteration func(i instruction, w expr.Width, step, active expr.Expr) []expr.Effect
+
+// stringReg returns effect moving string register r by step if active is
+// nonzero.
+func stringReg(i instruction, r regNum, step, active expr.Expr) expr.Effect {
+	aw := i.addrWidth()
+	v := regLoad(r, aw)
+	moved := expr.NewBinary(expr.Add, v, step, aw)
+	return regStore(exprtools.BoolCond(active, moved, v, aw), r, aw)
+}
+
+func movsIteration(i instruction, w expr.Width, step, active expr.Expr) []expr.Effect {
+	aw := i.addrWidth()
+	dst := regLoad(regDI, aw)
+	v := exprtools.BoolCond(active, memLoad(regLoad(regSI, aw), w), memLoad(dst, w), w)
+	return []expr.Effect{
+		memStore(v, dst, w),
+		stringReg(i, regSI, step, active),
+		stringReg(i, regDI, step, active),
+	}
+}
+
+func stosIteration(i instruction, w expr.Width, step, active expr.Expr) []expr.Effect {
+	aw := i.addrWidth()
+	dst := regLoad(regDI, aw)
+	v := exprtools.BoolCond(active, regLoad(regAX, w), memLoad(dst, w), w)
+	return []expr.Effect{
+		memStore(v, dst, w),
+		stringReg(i, regDI, step, active),
+	}
+}
+
+// stringEffects returns effects of a string instruction which performs a single
+// iteration.
+//
+// The direction flag decides whether string registers are incremented or
+// decremented. Instructions prefixed with rep perform a single iteration and
+// decrement rcx at a time. They jump back to themselves until rcx reaches zero,
+// no iteration is performed if rcx is zero initially. Memory written by the
+// instruction is rewritten by its original value in such a case.
+func stringEffects(iteration stringIteration) func(i instruction) []expr.Effect {
+	return func(i instruction) []expr.Effect {
+		w, aw := i.width(), i.addrWidth()
+		step := exprtools.BoolCond(
+			flagLoad(DFKey),
+			expr.NewConstInt(-int64(w), aw),
+			expr.NewConstUint(uint64(w), aw),
+			aw,
+		)
+
+		if !i.prefixes.rep {
+			return iteration(i, w, step, expr.One)
+		}
+
+		cnt := regLoad(regCX, aw)
+		active := exprtools.Not(isZero(cnt, aw))
+		left := exprtools.BoolCond(active, exprtools.Sub(cnt, expr.One, aw), cnt, aw)
+		ip := exprtools.BoolCond(isZero(left, aw), addrConst(i.next()), addrConst(i.addr), width64)
+
+		return append(iteration(i, w, step, active),
+			regStore(left, regCX, aw),
+			ipStore(ip),
+		)
+	}
+}
@@ -0,0 +1,320 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// mov %esi,%edi
+		name:  "mov_32",
+		bytes: []byte{0x89, 0xf7},
+		regs:  map[expr.Key]uint64{"rsi": 0x11111111_22222222, "rdi": 0xffffffff_ffffffff},
+		want:  map[expr.Key]uint64{"rdi": 0x22222222},
+	}, {
+		// mov $0x12,%ah
+		name:  "mov_high_byte",
+		bytes: []byte{0xb4, 0x12},
+		regs:  map[expr.Key]uint64{"rax": 0xffff},
+		want:  map[expr.Key]uint64{"rax": 0x12ff},
+	}, {
+		// mov $0x12,%spl
+		name:  "mov_rex_byte",
+		bytes: []byte{0x40, 0xb4, 0x12},
+		regs:  map[expr.Key]uint64{"rsp": 0xffff},
+		want:  map[expr.Key]uint64{"rsp": 0xff12},
+	}, {
+		// mov $0x1234,%ax
+		name:  "mov_16",
+		bytes: []byte{0x66, 0xb8, 0x34, 0x12},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_ffffffff},
+		want:  map[expr.Key]uint64{"rax": 0xffffffff_ffff1234},
+	}, {
+		// movabs $0x1122334455667788,%rax
+		name:  "movabs",
+		bytes: []byte{0x48, 0xb8, 0x88, 0x77, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11},
+		want:  map[expr.Key]uint64{"rax": 0x11223344_55667788},
+	}, {
+		// mov $0xffffffffffffffff,%rax
+		name:  "mov_imm_sign_extended",
+		bytes: []byte{0x48, 0xc7, 0xc0, 0xff, 0xff, 0xff, 0xff},
+		want:  map[expr.Key]uint64{"rax": 0xffffffff_ffffffff},
+	}, {
+		// mov 0x8(%rbx),%rax
+		name:  "mov_load",
+		bytes: []byte{0x48, 0x8b, 0x43, 0x08},
+		regs:  map[expr.Key]uint64{"rbx": 0x2000},
+		want:  map[expr.Key]uint64{"rax": 0x8f8e8d8c_8b8a8988},
+	}, {
+		// mov %eax,-0x4(%rbp)
+		name:    "mov_store",
+		bytes:   []byte{0x89, 0x45, 0xfc},
+		regs:    map[expr.Key]uint64{"rax": 0x11223344, "rbp": 0x3004},
+		want:    map[expr.Key]uint64{},
+		wantMem: memBytes(0x3000, 0x44, 0x33, 0x22, 0x11),
+	}, {
+		// mov 0x10(%rbx,%rcx,4),%eax
+		name:  "mov_sib",
+		bytes: []byte{0x8b, 0x44, 0x8b, 0x10},
+		regs:  map[expr.Key]uint64{"rbx": 0x1ff0, "rcx": 1},
+		want:  map[expr.Key]uint64{"rax": 0x87868584},
+	}, {
+		// mov 0xff9(%rip),%rax
+		name:  "mov_rip_relative",
+		bytes: []byte{0x48, 0x8b, 0x05, 0xf9, 0x0f, 0x00, 0x00},
+		want:  map[expr.Key]uint64{"rax": 0x87868584_83828180},
+	}, {
+		// mov %fs:0x28,%rax
+		name:  "mov_fs",
+		bytes: []byte{0x64, 0x48, 0x8b, 0x04, 0x25, 0x28, 0x00, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{FSBaseKey: 0x1fd8},
+		want:  map[expr.Key]uint64{"rax": 0x87868584_83828180},
+	}, {
+		// mov (%ebx),%al
+		name:  "mov_addr32",
+		bytes: []byte{0x67, 0x8a, 0x03},
+		regs:  map[expr.Key]uint64{"rbx": 0x1_00002001},
+		want:  map[expr.Key]uint64{"rax": 0x81},
+	}, {
+		// lea 0x8(%rbx,%rcx,2),%rax
+		name:  "lea",
+		bytes: []byte{0x48, 0x8d, 0x44, 0x4b, 0x08},
+		regs:  map[expr.Key]uint64{"rbx": 0x100, "rcx": 0x10},
+		want:  map[expr.Key]uint64{"rax": 0x128},
+	}, {
+		// lea 0x8(%rip),%rax
+		name:  "lea_rip_relative",
+		bytes: []byte{0x48, 0x8d, 0x05, 0x08, 0x00, 0x00, 0x00},
+		want:  map[expr.Key]uint64{"rax": testAddr + 7 + 8},
+	}, {
+		// movzbl %al,%eax
+		name:  "movzbl",
+		bytes: []byte{0x0f, 0xb6, 0xc0},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_ffffff80},
+		want:  map[expr.Key]uint64{"rax": 0x80},
+	}, {
+		// movzwq (%rbx),%rax
+		name:  "movzwq",
+		bytes: []byte{0x48, 0x0f, 0xb7, 0x03},
+		regs:  map[expr.Key]uint64{"rbx": 0x2000},
+		want:  map[expr.Key]uint64{"rax": 0x8180},
+	}, {
+		// movsbq %al,%rax
+		name:  "movsbq",
+		bytes: []byte{0x48, 0x0f, 0xbe, 0xc0},
+		regs:  map[expr.Key]uint64{"rax": 0x80},
+		want:  map[expr.Key]uint64{"rax": 0xffffffff_ffffff80},
+	}, {
+		// movslq %eax,%rax
+		name:  "movslq",
+		bytes: []byte{0x48, 0x63, 0xc0},
+		regs:  map[expr.Key]uint64{"rax": 0x80000000},
+		want:  map[expr.Key]uint64{"rax": 0xffffffff_80000000},
+	}, {
+		// movswl %ax,%eax
+		name:  "movswl",
+		bytes: []byte{0x0f, 0xbf, 0xc0},
+		regs:  map[expr.Key]uint64{"rax": 0xffff_ffff_0000_8000},
+		want:  map[expr.Key]uint64{"rax": 0xffff8000},
+	}})
+}
+
+func TestExchangeEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// xchg %rax,%rsi
+		name:  "xchg_acc",
+		bytes: []byte{0x48, 0x96},
+		regs:  map[expr.Key]uint64{"rax": 1, "rsi": 2},
+		want:  map[expr.Key]uint64{"rax": 2, "rsi": 1},
+	}, {
+		// xchg %al,%ah
+		name:  "xchg_same_register",
+		bytes: []byte{0x86, 0xc4},
+		regs:  map[expr.Key]uint64{"rax": 0x1234},
+		want:  map[expr.Key]uint64{"rax": 0x3412},
+	}, {
+		// xchg %rax,(%rbx)
+		name:    "xchg_memory",
+		bytes:   []byte{0x48, 0x87, 0x03},
+		regs:    map[expr.Key]uint64{"rax": 1, "rbx": 0x2000},
+		want:    map[expr.Key]uint64{"rax": 0x87868584_83828180},
+		wantMem: memBytes(0x2000, 1, 0, 0, 0, 0, 0, 0, 0),
+	}, {
+		// xchg %eax,%eax
+		name:  "xchg_eax",
+		bytes: []byte{0x87, 0xc0},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_00000001},
+		want:  map[expr.Key]uint64{"rax": 1},
+	}, {
+		// nop
+		name:  "nop",
+		bytes: []byte{0x90},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_00000001},
+		want:  map[expr.Key]uint64{},
+	}, {
+		// pause
+		name:  "pause",
+		bytes: []byte{0xf3, 0x90},
+		want:  map[expr.Key]uint64{},
+	}, {
+		// xchg %r8,%rax
+		name:  "xchg_r8",
+		bytes: []byte{0x49, 0x90},
+		regs:  map[expr.Key]uint64{"rax": 1, "r8": 2},
+		want:  map[expr.Key]uint64{"rax": 2, "r8": 1},
+	}})
+}
+
+func TestExchangeType(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		typ   model.Type
+	}{{
+		// xchg %rax,(%rbx)
+		name:  "memory",
+		bytes: []byte{0x48, 0x87, 0x03},
+		typ:   model.TypeMemOrder,
+	}, {
+		// xchg %rax,%rbx
+		name:  "register",
+		bytes: []byte{0x48, 0x87, 0xc3},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := NewParser().Parse(testAddr, tt.bytes)
+			require.NoError(t, err)
+			require.Equal(t, tt.typ, ins.Type)
+		})
+	}
+}
+
+func TestStackEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// push %rbp
+		name:    "push",
+		bytes:   []byte{0x55},
+		regs:    map[expr.Key]uint64{"rbp": 0x1122, "rsp": 0x3008},
+		want:    map[expr.Key]uint64{"rsp": 0x3000},
+		wantMem: memBytes(0x3000, 0x22, 0x11, 0, 0, 0, 0, 0, 0),
+	}, {
+		// push %r12
+		name:    "push_rex",
+		bytes:   []byte{0x41, 0x54},
+		regs:    map[expr.Key]uint64{"r12": 0x33, "rsp": 0x3008},
+		want:    map[expr.Key]uint64{"rsp": 0x3000},
+		wantMem: memBytes(0x3000, 0x33, 0, 0, 0, 0, 0, 0, 0),
+	}, {
+		// push %rsp
+		name:    "push_rsp",
+		bytes:   []byte{0x54},
+		regs:    map[expr.Key]uint64{"rsp": 0x3008},
+		want:    map[expr.Key]uint64{"rsp": 0x3000},
+		wantMem: memBytes(0x3000, 0x08, 0x30, 0, 0, 0, 0, 0, 0),
+	}, {
+		// push $0xffffffffffffffff
+		name:    "push_imm",
+		bytes:   []byte{0x6a, 0xff},
+		regs:    map[expr.Key]uint64{"rsp": 0x3008},
+		want:    map[expr.Key]uint64{"rsp": 0x3000},
+		wantMem: memBytes(0x3000, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff),
+	}, {
+		// push 0x8(%rbx)
+		name:    "push_memory",
+		bytes:   []byte{0xff, 0x73, 0x08},
+		regs:    map[expr.Key]uint64{"rbx": 0x2000, "rsp": 0x3008},
+		want:    map[expr.Key]uint64{"rsp": 0x3000},
+		wantMem: memBytes(0x3000, 0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x8d, 0x8e, 0x8f),
+	}, {
+		// pop %rbp
+		name:  "pop",
+		bytes: []byte{0x5d},
+		regs:  map[expr.Key]uint64{"rsp": 0x2000},
+		want:  map[expr.Key]uint64{"rbp": 0x87868584_83828180, "rsp": 0x2008},
+	}, {
+		// pop %rsp
+		name:  "pop_rsp",
+		bytes: []byte{0x5c},
+		regs:  map[expr.Key]uint64{"rsp": 0x2000},
+		want:  map[expr.Key]uint64{"rsp": 0x87868584_83828180},
+	}, {
+		// pop 0x8(%rsp)
+		name:    "pop_memory_rsp_base",
+		bytes:   []byte{0x8f, 0x44, 0x24, 0x08},
+		regs:    map[expr.Key]uint64{"rsp": 0x2000},
+		want:    map[expr.Key]uint64{"rsp": 0x2008},
+		wantMem: memBytes(0x2010, 0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87),
+	}, {
+		// leave
+		name:  "leave",
+		bytes: []byte{0xc9},
+		regs:  map[expr.Key]uint64{"rbp": 0x2000, "rsp": 0x1000},
+		want:  map[expr.Key]uint64{"rbp": 0x87868584_83828180, "rsp": 0x2008},
+	}})
+}
+
+func TestStringEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// movsb %ds:(%rsi),%es:(%rdi)
+		name:    "movsb",
+		bytes:   []byte{0xa4},
+		regs:    map[expr.Key]uint64{"rsi": 0x2001, "rdi": 0x3000},
+		want:    map[expr.Key]uint64{"rsi": 0x2002, "rdi": 0x3001},
+		wantMem: memBytes(0x3000, 0x81),
+	}, {
+		// movsb %ds:(%rsi),%es:(%rdi)
+		name:    "movsb_backwards",
+		bytes:   []byte{0xa4},
+		regs:    map[expr.Key]uint64{"rsi": 0x2001, "rdi": 0x3000, DFKey: 1},
+		want:    map[expr.Key]uint64{"rsi": 0x2000, "rdi": 0x2fff},
+		wantMem: memBytes(0x3000, 0x81),
+	}, {
+		// stos %eax,%es:(%rdi)
+		name:    "stosl",
+		bytes:   []byte{0xab},
+		regs:    map[expr.Key]uint64{"rax": 0x11223344, "rdi": 0x3000},
+		want:    map[expr.Key]uint64{"rdi": 0x3004},
+		wantMem: memBytes(0x3000, 0x44, 0x33, 0x22, 0x11),
+	}, {
+		// rep movsq %ds:(%rsi),%es:(%rdi)
+		name:  "rep_movsq",
+		bytes: []byte{0xf3, 0x48, 0xa5},
+		regs:  map[expr.Key]uint64{"rsi": 0x2000, "rdi": 0x3000, "rcx": 2},
+		want: map[expr.Key]uint64{
+			"rsi":      0x2008,
+			"rdi":      0x3008,
+			"rcx":      1,
+			expr.IPKey: testAddr,
+		},
+		wantMem: memBytes(0x3000, 0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87),
+	}, {
+		// rep movsq %ds:(%rsi),%es:(%rdi)
+		name:  "rep_movsq_last",
+		bytes: []byte{0xf3, 0x48, 0xa5},
+		regs:  map[expr.Key]uint64{"rsi": 0x2000, "rdi": 0x3000, "rcx": 1},
+		want: map[expr.Key]uint64{
+			"rsi":      0x2008,
+			"rdi":      0x3008,
+			"rcx":      0,
+			expr.IPKey: testAddr + 3,
+		},
+		wantMem: memBytes(0x3000, 0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87),
+	}, {
+		// rep stos %al,%es:(%rdi)
+		name:  "rep_stosb_empty",
+		bytes: []byte{0xf3, 0xaa},
+		regs:  map[expr.Key]uint64{"rax": 0xff, "rdi": 0x2000},
+		want: map[expr.Key]uint64{
+			"rdi":      0x2000,
+			"rcx":      0,
+			expr.IPKey: testAddr + 2,
+		},
+		wantMem: memBytes(0x2000, 0x80),
+	}})
+}
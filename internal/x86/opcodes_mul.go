@@ -0,0 +1,237 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// multiplyInstructions returns all multiplication and division instructions
+// including sign extension of the accumulator preceding a division.
+func multiplyInstructions() []*instructionType {
+	return append(imulInstructions, accMultiplyInstructions()...)
+}
+
+// imulInstructions are signed multiplications with an explicit destination
+// register and instructions sign extending the accumulator.
+var imulInstructions = []*instructionType{{
+	name:     "imul",
+	opcode:   op(0x0f, 0xaf),
+	modRM:    true,
+	operands: rmRegOperands,
+	effects: func(i instruction) []expr.Effect {
+		w := i.width()
+		return imulEffects(i.regOperand(w), i.regOperand(w).load(w), i.rmOperand(w).load(w), w)
+	},
+}, {
+	name:     "imul",
+	opcode:   op(0x69),
+	modRM:    true,
+	imm:      immZ,
+	operands: imulImmOperands,
+	effects:  imulImmEffects,
+}, {
+	name:     "imul",
+	opcode:   op(0x6b),
+	modRM:    true,
+	imm:      immB,
+	operands: imulImmOperands,
+	effects:  imulImmEffects,
+}, {
+	name:     "cbw",
+	opcode:   op(0x98),
+	mnemonic: extendAccName,
+	operands: noOperands,
+	effects: func(i instruction) []expr.Effect {
+		w := i.width()
+		return []expr.Effect{regStore(sext(regLoad(regAX, w/2), w/2, w), regAX, w)}
+	},
+}, {
+	name:     "cwd",
+	opcode:   op(0x99),
+	mnemonic: extendAccName,
+	operands: noOperands,
+	effects: func(i instruction) []expr.Effect {
+		w := i.width()
+		high := exprtools.RshA(regLoad(regAX, w), expr.ConstFromUint(w.Bits()-1), w)
+		return []expr.Effect{regStore(high, regDX, w)}
+	},
+}}
+
+// extendAccNames are names of instructions sign extending the accumulator in
+// AT&T syntax. The first index is the lowest bit of the opcode and the second
+// one is the operand size.
+var extendAccNames = [2]map[expr.Width]string{{
+	width16: "cbtw",
+	width32: "cwtl",
+	width64: "cltq",
+}, {
+	width16: "cwtd",
+	width32: "cltd",
+	width64: "cqto",
+}}
+
+// extendAccName returns name of instruction i sign extending the accumulator.
+func extendAccName(i instruction) string {
+	return extendAccNames[i.lastOpcodeByte()&1][i.width()]
+}
+
+// sext sign extends value e of width from to width w.
+func sext(e expr.Expr, from, w expr.Width) expr.Expr {
+	return exprtools.SignExtend(e, expr.ConstFromUint(from.Bits()-1), w)
+}
+
+// zext zero extends value e of width from to width w.
+func zext(e expr.Expr, from, w expr.Width) expr.Expr {
+	return exprtools.MaskBits(e, bitCnt(from), w)
+}
+
+// signedMul returns signed product of width 2*w of values e1 and e2 of width w.
+//
+// Unlike exprtools.SignedMul, the function doesn't derive position of sign bits
+// from widths of the expressions, so it works for loads of high byte registers
+// as well.
+func signedMul(e1, e2 expr.Expr, w expr.Width) expr.Expr {
+	return expr.NewBinary(expr.Mul, sext(e1, w, 2*w), sext(e2, w, 2*w), 2*w)
+}
+
+// signedOverflow returns an expression evaluating to 1 if product of width 2*w
+// doesn't fit w bytes as a signed integer and to 0 otherwise.
+func signedOverflow(product expr.Expr, w expr.Width) expr.Expr {
+	return exprtools.Eq(product, sext(product, w, 2*w), expr.Zero, expr.One, 2*w)
+}
+
+func imulImmOperands(i instruction) []string {
+	w := i.width()
+	return []string{i.immString(w), i.rmString(w), i.regString(w)}
+}
+
+func imulImmEffects(i instruction) []expr.Effect {
+	w := i.width()
+	return imulEffects(i.regOperand(w), i.rmOperand(w).load(w), i.immConst(w), w)
+}
+
+// imulEffects returns effects of signed multiplication of e1 and e2 of width w
+// writing the lower half of the product to dst.
+//
+// Carry and overflow flags are set if the product doesn't fit w bytes. Other
+// status flags are undefined.
+func imulEffects(dst operand, e1, e2 expr.Expr, w expr.Width) []expr.Effect {
+	product := signedMul(e1, e2, w)
+	ovf := signedOverflow(product, w)
+	return append(rflags{cf: ovf, of: ovf}.store(), dst.store(product, w))
+}
+
+// accMultiplyInstructions returns multiplication and division instructions
+// with implicit accumulator operands.
+func accMultiplyInstructions() []*instructionType {
+	ops := []struct {
+		name    string
+		ext     byte
+		effects func(i instruction, src expr.Expr, w expr.Width) []expr.Effect
+	}{
+		{"mul", 4, mulEffects(false)},
+		{"imul", 5, mulEffects(true)},
+		{"div", 6, divEffects(false)},
+		{"idiv", 7, divEffects(true)},
+	}
+
+	var list []*instructionType
+	for _, o := range ops {
+		o := o
+		effects := func(i instruction) []expr.Effect {
+			w := i.width()
+			return o.effects(i, i.rmOperand(w).load(w), w)
+		}
+
+		for n, size := range [...]operandSize{sizeB, sizeV} {
+			list = append(list, &instructionType{
+				name:     o.name,
+				opcode:   opExt(o.ext, 0xf6+byte(n)),
+				modRM:    true,
+				size:     size,
+				suffix:   true,
+				operands: rmOperands,
+				effects:  effects,
+			})
+		}
+	}
+	return list
+}
+
+// storeWide returns effects writing value e of width 2*w to the accumulator
+// pair. The lower half is written to the accumulator and the upper half to the
+// data register (rdx). Products of 8 bit operands are written to 16 bits of the
+// accumulator instead.
+func storeWide(e expr.Expr, w expr.Width) []expr.Effect {
+	if w == width8 {
+		return []expr.Effect{regStore(e, regAX, width16)}
+	}
+
+	high := expr.NewBinary(expr.Rsh, e, expr.ConstFromUint(w.Bits()), 2*w)
+	return []expr.Effect{regStore(e, regAX, w), regStore(high, regDX, w)}
+}
+
+// loadWide loads value of width 2*w from the accumulator pair in the same way
+// storeWide writes it.
+func loadWide(w expr.Width) expr.Expr {
+	if w == width8 {
+		return regLoad(regAX, width16)
+	}
+
+	high := expr.NewBinary(expr.Lsh, regLoad(regDX, w), expr.ConstFromUint(w.Bits()), 2*w)
+	return exprtools.BitOr(high, zext(regLoad(regAX, w), w, 2*w), 2*w)
+}
+
+// mulEffects returns effects of multiplication of the accumulator by an operand
+// src of width w. The product is written to the accumulator pair. Carry and
+// overflow flags are set if the upper half of the product is significant. Other
+// status flags are undefined.
+func mulEffects(signed bool) func(i instruction, src expr.Expr, w expr.Width) []expr.Effect {
+	return func(_ instruction, src expr.Expr, w expr.Width) []expr.Effect {
+		acc := regLoad(regAX, w)
+
+		var product, ovf expr.Expr
+		if signed {
+			product = signedMul(acc, src, w)
+			ovf = signedOverflow(product, w)
+		} else {
+			product = expr.NewBinary(expr.Mul, zext(acc, w, 2*w), zext(src, w, 2*w), 2*w)
+			high := expr.NewBinary(expr.Rsh, product, expr.ConstFromUint(w.Bits()), 2*w)
+			ovf = exprtools.Bool(high)
+		}
+
+		return append(rflags{cf: ovf, of: ovf}.store(), storeWide(product, w)...)
+	}
+}
+
+// divEffects returns effects of division of the accumulator pair by an operand
+// src of width w. The quotient is written to the accumulator and the remainder
+// to the data register (or to the upper half of 16 bits of the accumulator for
+// 8 bit operands). All status flags are undefined.
+//
+// Division by zero and quotient which doesn't fit w bytes raise an exception.
+// Exceptions are not modelled, the quotient and the remainder follow semantics
+// of expr.Div instead.
+func divEffects(signed bool) func(i instruction, src expr.Expr, w expr.Width) []expr.Effect {
+	return func(_ instruction, src expr.Expr, w expr.Width) []expr.Effect {
+		dividend := loadWide(w)
+
+		var q, r expr.Expr
+		if signed {
+			d := sext(src, w, 2*w)
+			// The remainder has the sign of the dividend.
+			q = exprtools.SignedDiv(dividend, d, 2*w)
+			r = exprtools.Sub(dividend, expr.NewBinary(expr.Mul, q, d, 2*w), 2*w)
+		} else {
+			d := zext(src, w, 2*w)
+			q, r = expr.NewBinary(expr.Div, dividend, d, 2*w), exprtools.Mod(dividend, d, 2*w)
+		}
+
+		if w == width8 {
+			ah := expr.NewBinary(expr.Lsh, r, expr.ConstFromUint[uint8](8), width16)
+			res := exprtools.BitOr(ah, zext(q, width8, width16), width16)
+			return []expr.Effect{regStore(res, regAX, width16)}
+		}
+		return []expr.Effect{regStore(q, regAX, w), regStore(r, regDX, w)}
+	}
+}
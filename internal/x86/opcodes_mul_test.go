@@ -0,0 +1,104 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+)
+
+func TestMultiplyEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// imul %rsi,%rdi
+		name:  "imul",
+		bytes: []byte{0x48, 0x0f, 0xaf, 0xfe},
+		regs:  map[expr.Key]uint64{"rdi": 0xffffffff_fffffffe, "rsi": 3},
+		want: map[expr.Key]uint64{
+			"rdi": 0xffffffff_fffffffa,
+			CFKey: 0, OFKey: 0,
+		},
+	}, {
+		// imul $0xfffffffffffffffd,%rsi,%rdi
+		name:  "imul_imm8",
+		bytes: []byte{0x48, 0x6b, 0xfe, 0xfd},
+		regs:  map[expr.Key]uint64{"rsi": 4},
+		want: map[expr.Key]uint64{
+			"rdi": 0xffffffff_fffffff4,
+			CFKey: 0, OFKey: 0,
+		},
+	}, {
+		// imul $0x100,%ecx,%eax
+		name:  "imul_overflow",
+		bytes: []byte{0x69, 0xc1, 0x00, 0x01, 0x00, 0x00},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_ffffffff, "rcx": 0x01000000},
+		want: map[expr.Key]uint64{
+			"rax": 0,
+			CFKey: 1, OFKey: 1,
+		},
+	}, {
+		// imul %cl
+		name:  "imul_acc_8",
+		bytes: []byte{0xf6, 0xe9},
+		regs:  map[expr.Key]uint64{"rax": 0x1234_00fe, "rcx": 3},
+		want: map[expr.Key]uint64{
+			"rax": 0x1234_fffa,
+			CFKey: 0, OFKey: 0,
+		},
+	}, {
+		// mul %rcx
+		name:  "mul",
+		bytes: []byte{0x48, 0xf7, 0xe1},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_ffffffff, "rcx": 2},
+		want: map[expr.Key]uint64{
+			"rax": 0xffffffff_fffffffe,
+			"rdx": 1,
+			CFKey: 1, OFKey: 1,
+		},
+	}, {
+		// div %rcx
+		name:  "div",
+		bytes: []byte{0x48, 0xf7, 0xf1},
+		regs:  map[expr.Key]uint64{"rdx": 1, "rcx": 2},
+		want:  map[expr.Key]uint64{"rax": 0x80000000_00000000, "rdx": 0},
+	}, {
+		// idiv %ecx
+		name:  "idiv",
+		bytes: []byte{0xf7, 0xf9},
+		regs:  map[expr.Key]uint64{"rax": 0xfffffff9, "rdx": 0xffffffff, "rcx": 2},
+		want:  map[expr.Key]uint64{"rax": 0xfffffffd, "rdx": 0xffffffff},
+	}, {
+		// div %cl
+		name:  "div_8",
+		bytes: []byte{0xf6, 0xf1},
+		regs:  map[expr.Key]uint64{"rax": 0x1_0000_0107, "rcx": 2},
+		want:  map[expr.Key]uint64{"rax": 0x1_0000_0183},
+	}, {
+		// divq 0x8(%rbx)
+		name:  "div_memory",
+		bytes: []byte{0x48, 0xf7, 0x73, 0x08},
+		regs:  map[expr.Key]uint64{"rax": 0x8f8e8d8c_8b8a8989, "rbx": 0x2000},
+		want:  map[expr.Key]uint64{"rax": 1, "rdx": 1},
+	}, {
+		// cltq
+		name:  "cltq",
+		bytes: []byte{0x48, 0x98},
+		regs:  map[expr.Key]uint64{"rax": 0x80000000},
+		want:  map[expr.Key]uint64{"rax": 0xffffffff_80000000},
+	}, {
+		// cbtw
+		name:  "cbtw",
+		bytes: []byte{0x66, 0x98},
+		regs:  map[expr.Key]uint64{"rax": 0xffff_0080},
+		want:  map[expr.Key]uint64{"rax": 0xffff_ff80},
+	}, {
+		// cqto
+		name:  "cqto",
+		bytes: []byte{0x48, 0x99},
+		regs:  map[expr.Key]uint64{"rax": 0x80000000_00000000},
+		want:  map[expr.Key]uint64{"rdx": 0xffffffff_ffffffff},
+	}, {
+		// cltd
+		name:  "cltd",
+		bytes: []byte{0x99},
+		regs:  map[expr.Key]uint64{"rax": 0x7fffffff, "rdx": 0xffffffff_ffffffff},
+		want:  map[expr.Key]uint64{"rdx": 0},
+	}})
+}
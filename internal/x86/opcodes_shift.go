@@ -0,0 +1,278 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+// shiftOp is a shift or a rotation of an r/m operand.
+type shiftOp struct {
+	name string
+	// ext is the opcode extension in ModRM byte.
+	ext byte
+	// apply returns result of the operation on value e of width w shifted
+	// by count bits together with flags the operation sets. The flags are
+	// used only if count is nonzero.
+	apply func(e, count expr.Expr, w expr.Width) (expr.Expr, rflags)
+}
+
+// shiftOps are all shift and rotate operations the package supports.
+//
+// Flags which are defined only for shifts by a single bit (OF) are modelled as
+// if the last bit shifted was the only one.
+var shiftOps = []shiftOp{{
+	name:  "rol",
+	ext:   0,
+	apply: rotateEffects(exprtools.RotateLeft, false),
+}, {
+	name:  "ror",
+	ext:   1,
+	apply: rotateEffects(exprtools.RotateRight, true),
+}, {
+	name: "shl",
+	ext:  4,
+	apply: func(e, count expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		res := expr.NewBinary(expr.Lsh, e, count, w)
+		f := resultFlags(res, w)
+		f.cf = dynBitOf(e, exprtools.Sub(expr.ConstFromUint(w.Bits()), count, w), w)
+		f.of = exprtools.BitXor(signBit(res, w), f.cf, flagWidth)
+		return res, f
+	},
+}, {
+	name: "shr",
+	ext:  5,
+	apply: func(e, count expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		res := expr.NewBinary(expr.Rsh, e, count, w)
+		f := resultFlags(res, w)
+		f.cf = dynBitOf(e, exprtools.Sub(count, expr.One, w), w)
+		f.of = signBit(e, w)
+		return res, f
+	},
+}, {
+	name: "sar",
+	ext:  7,
+	apply: func(e, count expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		res := exprtools.RshA(e, count, w)
+		f := resultFlags(res, w)
+		f.cf = exprtools.MaskBits(exprtools.RshA(e, exprtools.Sub(count, expr.One, w), w), 1, w)
+		f.of = expr.Zero
+		return res, f
+	},
+}}
+
+// dynBitOf returns an expression evaluating to bit n of e of width w where n is
+// an expression.
+func dynBitOf(e, n expr.Expr, w expr.Width) expr.Expr {
+	return exprtools.MaskBits(expr.NewBinary(expr.Rsh, e, n, w), 1, w)
+}
+
+// rotateEffects returns apply function of a rotation implemented by rotate.
+// Argument right indicates that bits are rotated right.
+//
+// Rotations change only CF and OF flags. Carry flag holds the last bit rotated
+// and overflow flag is XOR of the two most significant bits of the result
+// after a right rotation or XOR of the most significant bit and the carry after
+// a left rotation.
+func rotateEffects(
+	rotate func(e, shift expr.Expr, w expr.Width) expr.Expr,
+	right bool,
+) func(e, count expr.Expr, w expr.Width) (expr.Expr, rflags) {
+	return func(e, count expr.Expr, w expr.Width) (expr.Expr, rflags) {
+		// Rotations of 8 and 16 bit operands use count masked to 5 bits
+		// as well, so the count can exceed number of bits of w.
+		mod := exprtools.BitAnd(count, expr.ConstFromUint(w.Bits()-1), width8)
+		res := rotate(e, mod, w)
+
+		if right {
+			msb := signBit(res, w)
+			return res, rflags{
+				cf: msb,
+				of: exprtools.BitXor(msb, bitOf(res, w.Bits()-2, w), flagWidth),
+			}
+		}
+
+		cf := exprtools.MaskBits(res, 1, w)
+		return res, rflags{cf: cf, of: exprtools.BitXor(signBit(res, w), cf, flagWidth)}
+	}
+}
+
+// shiftCount is a function returning count of bits instruction i shifts its
+// operand by. The count is masked by mask.
+type shiftCount func(i instruction, mask uint8) expr.Expr
+
+func shiftByImm(i instruction, mask uint8) expr.Expr {
+	return expr.ConstFromUint(uint8(i.immValue()) & mask)
+}
+
+func shiftByOne(instruction, uint8) expr.Expr { return expr.One }
+
+func shiftByCL(_ instruction, mask uint8) expr.Expr {
+	return exprtools.BitAnd(regLoad(regCX, width8), expr.ConstFromUint(mask), width8)
+}
+
+// effects returns effects of instruction i shifting its r/m operand by count
+// bits.
+//
+// The count is masked to 5 bits (6 bits for 64 bit operands). Flags are not
+// changed if the masked count is zero.
+func (o shiftOp) effects(count shiftCount) func(i instruction) []expr.Effect {
+	return func(i instruction) []expr.Effect {
+		w := i.width()
+		dst := i.rmOperand(w)
+
+		c := count(i, countMask(w))
+
+		res, f := o.apply(dst.load(w), c, w)
+		f = shiftFlags(f, c)
+		return append(f.store(), dst.store(res, w))
+	}
+}
+
+// countMask returns mask of shift counts of operands of width w.
+func countMask(w expr.Width) uint8 {
+	if w == width64 {
+		return 0x3f
+	}
+	return 0x1f
+}
+
+// shiftFlags returns flags f of a shift by masked count c. Flags are not
+// changed if the count is zero.
+func shiftFlags(f rflags, c expr.Expr) rflags {
+	cnt, ok := c.(expr.Const)
+	if !ok {
+		return f.unlessZero(c, width8)
+	}
+	if v, _ := expr.ConstUint[uint8](cnt); v == 0 {
+		return rflags{}
+	}
+	return f
+}
+
+// shiftOperands returns operands function of a shift by count.
+func shiftOperands(count string) func(i instruction) []string {
+	return func(i instruction) []string {
+		if count == "" {
+			return rmOperands(i)
+		}
+		return []string{count, i.rmString(i.width())}
+	}
+}
+
+// shiftInstructions returns all encodings of all shift and rotate operations.
+func shiftInstructions() []*instructionType {
+	var list []*instructionType
+	for _, o := range shiftOps {
+		for n, size := range [...]operandSize{sizeB, sizeV} {
+			n := byte(n)
+			list = append(list, &instructionType{
+				name:     o.name,
+				opcode:   opExt(o.ext, 0xc0+n),
+				modRM:    true,
+				size:     size,
+				imm:      immB,
+				suffix:   true,
+				operands: immRMOperands8,
+				effects:  o.effects(shiftByImm),
+			}, &instructionType{
+				name:     o.name,
+				opcode:   opExt(o.ext, 0xd0+n),
+				modRM:    true,
+				size:     size,
+				suffix:   true,
+				operands: shiftOperands(""),
+				effects:  o.effects(shiftByOne),
+			}, &instructionType{
+				name:     o.name,
+				opcode:   opExt(o.ext, 0xd2+n),
+				modRM:    true,
+				size:     size,
+				suffix:   true,
+				operands: shiftOperands("%cl"),
+				effects:  o.effects(shiftByCL),
+			})
+		}
+	}
+	return append(list, doubleShiftInstructions...)
+}
+
+// immRMOperands8 returns operands of instruction i with an 8 bit immediate
+// operand which is not extended to the operand size and an r/m operand.
+func immRMOperands8(i instruction) []string {
+	return []string{i.immString(width8), i.rmString(i.width())}
+}
+
+// doubleShiftInstructions are shifts filling bits shifted in from a register
+// operand.
+var doubleShiftInstructions = []*instructionType{{
+	name:     "shld",
+	opcode:   op(0x0f, 0xa4),
+	modRM:    true,
+	imm:      immB,
+	operands: doubleShiftOperands,
+	effects:  doubleShiftEffects(false, shiftByImm),
+}, {
+	name:     "shld",
+	opcode:   op(0x0f, 0xa5),
+	modRM:    true,
+	operands: doubleShiftOperands,
+	effects:  doubleShiftEffects(false, shiftByCL),
+}, {
+	name:     "shrd",
+	opcode:   op(0x0f, 0xac),
+	modRM:    true,
+	imm:      immB,
+	operands: doubleShiftOperands,
+	effects:  doubleShiftEffects(true, shiftByImm),
+}, {
+	name:     "shrd",
+	opcode:   op(0x0f, 0xad),
+	modRM:    true,
+	operands: doubleShiftOperands,
+	effects:  doubleShiftEffects(true, shiftByCL),
+}}
+
+func doubleShiftOperands(i instruction) []string {
+	w := i.width()
+	count := "%cl"
+	if i.instrType.imm != immNone {
+		count = i.immString(width8)
+	}
+	return []string{count, i.regString(w), i.rmString(w)}
+}
+
+// doubleShiftEffects returns effects of a shift of the r/m operand by count
+// bits filling the vacated bits from the register operand. Argument right
+// indicates that bits are shifted right.
+//
+// The shift is implemented as a shift of concatenation of both operands of
+// double width. Flags are set the same way as flags of shl and shr. Result of
+// shifts of 16 bit operands by more than 16 bits is undefined.
+func doubleShiftEffects(right bool, count shiftCount) func(i instruction) []expr.Effect {
+	return func(i instruction) []expr.Effect {
+		w := i.width()
+		dst := i.rmOperand(w)
+		d, s := dst.load(w), i.regOperand(w).load(w)
+
+		c := count(i, countMask(w))
+
+		bits := expr.ConstFromUint(w.Bits())
+		var res expr.Expr
+		var f rflags
+		if right {
+			wide := exprtools.BitOr(expr.NewBinary(expr.Lsh, s, bits, 2*w), zext(d, w, 2*w), 2*w)
+			res = expr.NewBinary(expr.Rsh, wide, c, 2*w)
+			f = resultFlags(res, w)
+			f.cf = dynBitOf(d, exprtools.Sub(c, expr.One, w), w)
+		} else {
+			wide := exprtools.BitOr(expr.NewBinary(expr.Lsh, d, bits, 2*w), zext(s, w, 2*w), 2*w)
+			res = expr.NewBinary(expr.Rsh, expr.NewBinary(expr.Lsh, wide, c, 2*w), bits, 2*w)
+			f = resultFlags(res, w)
+			f.cf = dynBitOf(d, exprtools.Sub(bits, c, w), w)
+		}
+		f.of = exprtools.BitXor(signBit(res, w), signBit(d, w), flagWidth)
+
+		f = shiftFlags(f, c)
+		return append(f.store(), dst.store(res, w))
+	}
+}
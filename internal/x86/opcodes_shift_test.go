@@ -0,0 +1,89 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+)
+
+func TestShiftEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// shl $0x4,%rax
+		name:  "shl_imm",
+		bytes: []byte{0x48, 0xc1, 0xe0, 0x04},
+		regs:  map[expr.Key]uint64{"rax": 0x10000000_00000001},
+		want: map[expr.Key]uint64{
+			"rax": 0x10,
+			CFKey: 1, PFKey: 0, ZFKey: 0, SFKey: 0, OFKey: 1,
+		},
+	}, {
+		// shr %eax
+		name:  "shr_one",
+		bytes: []byte{0xd1, 0xe8},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_00000003},
+		want: map[expr.Key]uint64{
+			"rax": 1,
+			CFKey: 1, PFKey: 0, ZFKey: 0, SFKey: 0, OFKey: 0,
+		},
+	}, {
+		// sar %cl,%rax
+		name:  "sar_masked_count",
+		bytes: []byte{0x48, 0xd3, 0xf8},
+		regs:  map[expr.Key]uint64{"rax": 0x80000000_00000000, "rcx": 0x43},
+		want: map[expr.Key]uint64{
+			"rax": 0xf0000000_00000000,
+			CFKey: 0, PFKey: 1, ZFKey: 0, SFKey: 1, OFKey: 0,
+		},
+	}, {
+		// sar %cl,%rax
+		name:  "sar_zero_count",
+		bytes: []byte{0x48, 0xd3, 0xf8},
+		regs:  map[expr.Key]uint64{"rax": 0x80, "rcx": 0x40, CFKey: 1},
+		want: map[expr.Key]uint64{
+			"rax": 0x80,
+			CFKey: 1, PFKey: 0, ZFKey: 0, SFKey: 0, OFKey: 0,
+		},
+	}, {
+		// shl $0x0,%eax
+		name:  "shl_zero_imm",
+		bytes: []byte{0xc1, 0xe0, 0x00},
+		regs:  map[expr.Key]uint64{"rax": 0xffffffff_00000001},
+		want:  map[expr.Key]uint64{"rax": 1},
+	}, {
+		// rol $0x8,%ax
+		name:  "rol_16",
+		bytes: []byte{0x66, 0xc1, 0xc0, 0x08},
+		regs:  map[expr.Key]uint64{"rax": 0xffff_1234},
+		want:  map[expr.Key]uint64{"rax": 0xffff_3412, CFKey: 0, OFKey: 0},
+	}, {
+		// ror %cl,%eax
+		name:  "ror",
+		bytes: []byte{0xd3, 0xc8},
+		regs:  map[expr.Key]uint64{"rax": 1, "rcx": 1},
+		want:  map[expr.Key]uint64{"rax": 0x80000000, CFKey: 1, OFKey: 1},
+	}, {
+		// rolb (%rbx)
+		name:    "rol_memory",
+		bytes:   []byte{0xd0, 0x03},
+		regs:    map[expr.Key]uint64{"rbx": 0x2000},
+		want:    map[expr.Key]uint64{CFKey: 1, OFKey: 1},
+		wantMem: memBytes(0x2000, 0x01),
+	}, {
+		// shld $0x4,%rsi,%rdi
+		name:  "shld",
+		bytes: []byte{0x48, 0x0f, 0xa4, 0xf7, 0x04},
+		regs:  map[expr.Key]uint64{"rdi": 1, "rsi": 0xf0000000_00000000},
+		want: map[expr.Key]uint64{
+			"rdi": 0x1f,
+			CFKey: 0, PFKey: 0, ZFKey: 0, SFKey: 0, OFKey: 0,
+		},
+	}, {
+		// shrd %cl,%esi,%edi
+		name:  "shrd",
+		bytes: []byte{0x0f, 0xad, 0xf7},
+		regs:  map[expr.Key]uint64{"rdi": 0x18, "rsi": 0xf, "rcx": 4},
+		want: map[expr.Key]uint64{
+			"rdi": 0xf0000001,
+			CFKey: 1, PFKey: 0, ZFKey: 0, SFKey: 1, OFKey: 1,
+		},
+	}})
+}
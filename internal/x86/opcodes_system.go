@@ -0,0 +1,209 @@
+package x86
+
+import (
+	"mltwist/internal/opcode"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+)
+
+// systemInstructions are hints, barriers and instructions interacting with the
+// operating system or changing state of the processor not modelled by the
+// package.
+var systemInstructions = []*instructionType{{
+	name:     "nop",
+	opcode:   opExt(0, 0x0f, 0x1f),
+	modRM:    true,
+	suffix:   true,
+	mnemonic: nopName,
+	operands: nopOperands,
+	effects:  noEffects,
+}, {
+	// Opcode 0x0f 0x1e is a nop reserved for future extensions. Control
+	// flow enforcement uses it to mark valid targets of indirect branches
+	// and to read the shadow stack pointer.
+	name:     "nop",
+	opcode:   op(0x0f, 0x1e),
+	modRM:    true,
+	suffix:   true,
+	mnemonic: hintName,
+	operands: func(i instruction) []string {
+		if hintName(i) == "nop" {
+			return nopOperands(i)
+		}
+		if i.modRM.reg() == 1 {
+			return rmOperands(i)
+		}
+		return nil
+	},
+	effects: noEffects,
+}, {
+	name:      "hlt",
+	opcode:    op(0xf4),
+	operands:  noOperands,
+	instrType: model.TypeCPUStateChange,
+	effects:   noEffects,
+}, {
+	name:      "int3",
+	opcode:    op(0xcc),
+	operands:  noOperands,
+	instrType: model.TypeSyscall,
+	effects:   noEffects,
+}, {
+	name:      "ud2",
+	opcode:    op(0x0f, 0x0b),
+	operands:  noOperands,
+	instrType: model.TypeSyscall,
+	effects:   noEffects,
+}, {
+	name:      "syscall",
+	opcode:    op(0x0f, 0x05),
+	operands:  noOperands,
+	instrType: model.TypeSyscall,
+	// The processor saves address of the following instruction to rcx
+	// and the rflags register to r11.
+	effects: func(i instruction) []expr.Effect {
+		return []expr.Effect{
+			regStore(addrConst(i.next()), regCX, width64),
+			regStore(rflagsValue(), regR11, width64),
+		}
+	},
+}, {
+	name:     "rdtsc",
+	opcode:   op(0x0f, 0x31),
+	operands: noOperands,
+	effects: func(i instruction) []expr.Effect {
+		t := expr.NewRegLoad(expr.TimeKey, width64)
+		return storeWide(t, width32)
+	},
+}, {
+	name:      "mfence",
+	opcode:    opExtReg(6, 0x0f, 0xae),
+	modRM:     true,
+	operands:  noOperands,
+	reserved:  hasSSEPrefix,
+	instrType: model.TypeMemOrder,
+	effects:   noEffects,
+}, {
+	// The rep prefix changes lfence to incssp instruction incrementing
+	// the shadow stack pointer.
+	name:   "lfence",
+	opcode: opExtReg(5, 0x0f, 0xae),
+	modRM:  true,
+	mnemonic: func(i instruction) string {
+		if i.prefixes.rep {
+			return "incssp" + shadowStackSuffix(i)
+		}
+		return "lfence"
+	},
+	operands: func(i instruction) []string {
+		if i.prefixes.rep {
+			return rmOperands(i)
+		}
+		return nil
+	},
+	reserved: func(i instruction) bool {
+		return i.prefixes.opSize || i.prefixes.repNE
+	},
+	typeOf: func(i instruction) model.Type {
+		if i.prefixes.rep {
+			return model.TypeNone
+		}
+		return model.TypeMemOrder
+	},
+	order: func(instruction) model.MemOrder {
+		return model.MemOrder{
+			Pred: model.AccessRead | model.AccessInput,
+			Succ: model.AccessAll,
+		}
+	},
+	effects: noEffects,
+}, {
+	name:      "sfence",
+	opcode:    opExtReg(7, 0x0f, 0xae),
+	modRM:     true,
+	operands:  noOperands,
+	reserved:  hasSSEPrefix,
+	instrType: model.TypeMemOrder,
+	order: func(instruction) model.MemOrder {
+		return model.MemOrder{
+			Pred: model.AccessWrite | model.AccessOutput,
+			Succ: model.AccessWrite | model.AccessOutput,
+		}
+	},
+	effects: noEffects,
+},
+	flagInstruction("clc", op(0xf8), CFKey, expr.Zero),
+	flagInstruction("stc", op(0xf9), CFKey, expr.One),
+	flagInstruction("cmc", op(0xf5), CFKey, isZero(flagLoad(CFKey), flagWidth)),
+	flagInstruction("cld", op(0xfc), DFKey, expr.Zero),
+	flagInstruction("std", op(0xfd), DFKey, expr.One),
+}
+
+func noEffects(instruction) []expr.Effect { return nil }
+
+// nopName returns name of a nop instruction i. Segment override prefixes of
+// nops are written as separate prefixes because the instruction doesn't access
+// its memory operand.
+func nopName(i instruction) string {
+	if s := i.prefixes.segment; s != 0 {
+		return segmentNames[s] + " nop"
+	}
+	return "nop"
+}
+
+func nopOperands(i instruction) []string {
+	if i.rmIsMem() {
+		return []string{i.addrString()}
+	}
+	return rmOperands(i)
+}
+
+// hintName returns name of an instruction in the reserved nop opcode space.
+func hintName(i instruction) string {
+	if !i.prefixes.rep || i.rmIsMem() {
+		return nopName(i)
+	}
+
+	switch {
+	case i.modRM == 0xfa:
+		return "endbr64"
+	case i.modRM == 0xfb:
+		return "endbr32"
+	case i.modRM.reg() == 1:
+		return "rdssp" + shadowStackSuffix(i)
+	default:
+		return nopName(i)
+	}
+}
+
+// shadowStackSuffix returns suffix of name of shadow stack instruction i.
+//
+// Shadow stack instructions of control flow enforcement are encoded in the nop
+// opcode space, so they don't have any effect on processors and systems without
+// shadow stacks. The package models them as nops. Programs detect whether
+// shadow stacks are enabled using exactly this behaviour.
+func shadowStackSuffix(i instruction) string {
+	if i.prefixes.rex&rexW != 0 {
+		return "q"
+	}
+	return "d"
+}
+
+// hasSSEPrefix indicates that instruction i has one of prefixes which select
+// different instructions in the SSE opcode space.
+func hasSSEPrefix(i instruction) bool {
+	return i.prefixes.opSize || i.prefixes.rep || i.prefixes.repNE
+}
+
+// flagInstruction returns instruction type named name with opcode o setting
+// flag k to value v.
+func flagInstruction(name string, o opcode.Opcode, k expr.Key, v expr.Expr) *instructionType {
+	return &instructionType{
+		name:     name,
+		opcode:   o,
+		operands: noOperands,
+		effects: func(instruction) []expr.Effect {
+			return []expr.Effect{expr.NewRegStore(v, k, flagWidth)}
+		},
+	}
+}
@@ -0,0 +1,137 @@
+package x86
+
+import (
+	"mltwist/pkg/expr"
+	"mltwist/pkg/model"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemEffects(t *testing.T) {
+	runEvalTests(t, []evalTest{{
+		// syscall
+		name:  "syscall",
+		bytes: []byte{0x0f, 0x05},
+		regs:  map[expr.Key]uint64{CFKey: 1, ZFKey: 1, DFKey: 1},
+		want: map[expr.Key]uint64{
+			"rcx": testAddr + 2,
+			"r11": 0x443,
+		},
+	}, {
+		// rdtsc
+		name:  "rdtsc",
+		bytes: []byte{0x0f, 0x31},
+		regs: map[expr.Key]uint64{
+			"rax":        0xffffffff_ffffffff,
+			expr.TimeKey: 0x11223344_55667788,
+		},
+		want: map[expr.Key]uint64{"rax": 0x55667788, "rdx": 0x11223344},
+	}, {
+		// endbr64
+		name:  "endbr64",
+		bytes: []byte{0xf3, 0x0f, 0x1e, 0xfa},
+		want:  map[expr.Key]uint64{},
+	}, {
+		// nopw %cs:0x0(%rax,%rax,1)
+		name:  "nopw",
+		bytes: []byte{0x2e, 0x66, 0x0f, 0x1f, 0x84, 0x00, 0x00, 0x00, 0x00, 0x00},
+		want:  map[expr.Key]uint64{},
+	}, {
+		// clc
+		name:  "clc",
+		bytes: []byte{0xf8},
+		regs:  map[expr.Key]uint64{CFKey: 1},
+		want:  map[expr.Key]uint64{CFKey: 0},
+	}, {
+		// cmc
+		name:  "cmc",
+		bytes: []byte{0xf5},
+		want:  map[expr.Key]uint64{CFKey: 1},
+	}, {
+		// std
+		name:  "std",
+		bytes: []byte{0xfd},
+		want:  map[expr.Key]uint64{DFKey: 1},
+	}})
+}
+
+func TestSystemTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		typ   model.Type
+		order model.MemOrder
+	}{{
+		// hlt
+		name:  "hlt",
+		bytes: []byte{0xf4},
+		typ:   model.TypeCPUStateChange,
+	}, {
+		// int3
+		name:  "int3",
+		bytes: []byte{0xcc},
+		typ:   model.TypeSyscall,
+	}, {
+		// ud2
+		name:  "ud2",
+		bytes: []byte{0x0f, 0x0b},
+		typ:   model.TypeSyscall,
+	}, {
+		// syscall
+		name:  "syscall",
+		bytes: []byte{0x0f, 0x05},
+		typ:   model.TypeSyscall,
+	}, {
+		// mfence
+		name:  "mfence",
+		bytes: []byte{0x0f, 0xae, 0xf0},
+		typ:   model.TypeMemOrder,
+	}, {
+		// lfence
+		name:  "lfence",
+		bytes: []byte{0x0f, 0xae, 0xe8},
+		typ:   model.TypeMemOrder,
+		order: model.MemOrder{
+			Pred: model.AccessRead | model.AccessInput,
+			Succ: model.AccessAll,
+		},
+	}, {
+		// incsspq %rax
+		name:  "incssp",
+		bytes: []byte{0xf3, 0x48, 0x0f, 0xae, 0xe8},
+	}, {
+		// sfence
+		name:  "sfence",
+		bytes: []byte{0x0f, 0xae, 0xf8},
+		typ:   model.TypeMemOrder,
+		order: model.MemOrder{
+			Pred: model.AccessWrite | model.AccessOutput,
+			Succ: model.AccessWrite | model.AccessOutput,
+		},
+	}, {
+		// lock addl $0x1,(%rbx)
+		name:  "lock",
+		bytes: []byte{0xf0, 0x83, 0x03, 0x01},
+		typ:   model.TypeMemOrder,
+	}, {
+		// addl $0x1,(%rbx)
+		name:  "no_lock",
+		bytes: []byte{0x83, 0x03, 0x01},
+	}, {
+		// endbr64
+		name:  "endbr64",
+		bytes: []byte{0xf3, 0x0f, 0x1e, 0xfa},
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := NewParser().Parse(testAddr, tt.bytes)
+			require.NoError(t, err)
+			require.NoError(t, ins.Validate())
+			require.Equal(t, tt.typ, ins.Type)
+			require.Equal(t, tt.order, ins.Order)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package x86
+
+import (
+	"mltwist/internal/archtest"
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstructions(t *testing.T) {
+	archtest.Instructions(t, instructions, (*instructionType).validate)
+}
+
+// testAddr is address instructions are parsed at in tests.
+const testAddr = 0x1000
+
+// testMem returns memory of tests: 16 bytes at address 0x2000 with values
+// 0x80, 0x81 etc.
+func testMem() map[uint64]byte {
+	var bs []byte
+	for i := 0; i < 16; i++ {
+		bs = append(bs, byte(0x80+i))
+	}
+	return memBytes(0x2000, bs...)
+}
+
+// memBytes returns memory with bytes bs starting at address a.
+func memBytes(a uint64, bs ...byte) map[uint64]byte {
+	m := make(map[uint64]byte, len(bs))
+	for i, b := range bs {
+		m[a+uint64(i)] = b
+	}
+	return m
+}
+
+// evalEffects evaluates effects of an instruction encoded in bs at testAddr
+// with registers set to regs and memory set to mem. Registers and bytes of
+// memory not present in regs or mem are zero. It returns values of all
+// registers and bytes of memory the instruction writes.
+func evalEffects(
+	t testing.TB,
+	bs []byte,
+	regs map[expr.Key]uint64,
+	mem map[uint64]byte,
+) (map[expr.Key]uint64, map[uint64]byte) {
+	ins, err := NewParser().Parse(testAddr, bs)
+	require.NoError(t, err)
+	return archtest.EvalEffects(t, ins, regs, mem)
+}
+
+// evalRegs evaluates register stores of an instruction encoded in bs with
+// registers set to regs. Registers not present in regs are zero.
+func evalRegs(t testing.TB, bs []byte, regs map[expr.Key]uint64) map[expr.Key]uint64 {
+	res, mem := evalEffects(t, bs, regs, nil)
+	require.Empty(t, mem)
+	return res
+}
+
+// flags returns values of all status flags set by arithmetic instructions.
+func flags(cf, pf, af, zf, sf, of uint64) map[expr.Key]uint64 {
+	return map[expr.Key]uint64{
+		CFKey: cf, PFKey: pf, AFKey: af,
+		ZFKey: zf, SFKey: sf, OFKey: of,
+	}
+}
+
+// withFlags returns regs with status flags set to the given values.
+func withFlags(regs map[expr.Key]uint64, cf, pf, af, zf, sf, of uint64) map[expr.Key]uint64 {
+	res := flags(cf, pf, af, zf, sf, of)
+	for k, val := range regs {
+		res[k] = val
+	}
+	return res
+}
+
+// evalTest is a test case of effects of an instruction.
+type evalTest struct {
+	name    string
+	bytes   []byte
+	regs    map[expr.Key]uint64
+	want    map[expr.Key]uint64
+	wantMem map[uint64]byte
+}
+
+// runEvalTests evaluates effects of instructions of tests with memory of tests
+// and compares them with expected registers and memory.
+func runEvalTests(t *testing.T, tests []evalTest) {
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			regs, m := evalEffects(t, tt.bytes, tt.regs, testMem())
+			require.Equal(t, tt.want, regs)
+
+			if tt.wantMem == nil {
+				tt.wantMem = map[uint64]byte{}
+			}
+			require.Equal(t, tt.wantMem, m)
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package x86
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"mltwist/pkg/model"
+)
+
+// modReg is value of mod field of ModRM byte which means that the r/m operand
+// is a register.
+const modReg = 0b11
+
+// modRM is the ModRM byte of an instruction. It encodes a register operand in
+// bits [3:5] (reg field) and a register or memory operand in bits [0:2] (rm
+// field) and [6:7] (mod field).
+type modRM byte
+
+func (m modRM) mod() uint8 { return uint8(m) >> 6 }
+func (m modRM) reg() uint8 { return uint8(m) >> 3 & regMask }
+func (m modRM) rm() uint8  { return uint8(m) & regMask }
+
+// memory indicates that the r/m operand is in memory.
+func (m modRM) memory() bool { return m.mod() != modReg }
+
+// memOperand is a memory operand encoded by ModRM byte and optionally by SIB
+// byte and displacement.
+type memOperand struct {
+	base, index       regNum
+	hasBase, hasIndex bool
+	// scale is log2 of the factor index is multiplied by.
+	scale uint8
+
+	disp int64
+	// dispLen is length of the displacement in bytes.
+	dispLen int
+	// dispAt is index of the first byte of the displacement in bytes of
+	// the instruction.
+	dispAt int
+
+	// rip indicates that the address is relative to the following
+	// instruction.
+	rip bool
+}
+
+// decodeMemOperand decodes memory operand encoded by ModRM byte m of an
+// instruction with prefixes p. Argument bs are bytes following the ModRM byte.
+// It returns the operand and number of bytes it takes in bs.
+func decodeMemOperand(m modRM, p prefixes, bs []byte) (memOperand, int, error) {
+	var mem memOperand
+	n := 0
+
+	rm := m.rm()
+	switch {
+	case rm == uint8(regSP):
+		if len(bs) < 1 {
+			return memOperand{}, 0, errTooShort(bs)
+		}
+		sib := bs[0]
+		n++
+
+		mem.scale = sib >> 6
+		mem.index = regNum(sib>>3&regMask) | p.rexBit(rexX)
+		mem.hasIndex = mem.index != regSP
+
+		mem.base = regNum(sib&regMask) | p.rexBit(rexB)
+		mem.hasBase = sib&regMask != uint8(regBP) || m.mod() != 0
+		if !mem.hasBase {
+			mem.dispLen = 4
+		}
+	case rm == uint8(regBP) && m.mod() == 0:
+		mem.rip = true
+		mem.dispLen = 4
+	default:
+		mem.base = regNum(rm) | p.rexBit(rexB)
+		mem.hasBase = true
+	}
+
+	switch m.mod() {
+	case 1:
+		mem.dispLen = 1
+	case 2:
+		mem.dispLen = 4
+	}
+
+	if len(bs) < n+mem.dispLen {
+		return memOperand{}, 0, errTooShort(bs)
+	}
+	mem.dispAt = n
+	mem.disp = int64(readValue(bs[n:n+mem.dispLen], true))
+	return mem, n + mem.dispLen, nil
+}
+
+// ripTarget returns the address RIP-relative operand of instruction i refers.
+func (m memOperand) ripTarget(i instruction) model.Addr {
+	return i.next() + model.Addr(m.disp)
+}
+
+// addrWidth returns width of addresses computed by instruction i.
+func (i instruction) addrWidth() expr.Width {
+	if i.prefixes.addrSize {
+		return width32
+	}
+	return width64
+}
+
+// segmentBase returns base address of the segment selected by segment override
+// prefix of instruction i or nil if the segment has zero base.
+func (i instruction) segmentBase() expr.Expr {
+	switch i.prefixes.segment {
+	case prefixFS:
+		return expr.NewRegLoad(FSBaseKey, width64)
+	case prefixGS:
+		return expr.NewRegLoad(GSBaseKey, width64)
+	default:
+		return nil
+	}
+}
+
+// effectiveAddr returns the address memory operand of instruction i refers
+// within its segment.
+func (i instruction) effectiveAddr() expr.Expr {
+	m, w := i.mem, i.addrWidth()
+
+	var addr expr.Expr = expr.NewConstInt(m.disp, w)
+	if m.rip {
+		addr = addrConst(m.ripTarget(i))
+	}
+	if m.hasBase {
+		addr = expr.NewBinary(expr.Add, regLoad(m.base, w), addr, w)
+	}
+	if m.hasIndex {
+		idx := regLoad(m.index, w)
+		if m.scale != 0 {
+			idx = expr.NewBinary(expr.Lsh, idx, expr.ConstFromUint(m.scale), w)
+		}
+		addr = expr.NewBinary(expr.Add, addr, idx, w)
+	}
+
+	if w != width64 {
+		addr = exprtools.MaskBits(addr, bitCnt(w), width64)
+	}
+	return addr
+}
+
+// memAddr returns the address memory operand of instruction i refers.
+func (i instruction) memAddr() expr.Expr {
+	addr := i.effectiveAddr()
+	if base := i.segmentBase(); base != nil {
+		addr = expr.NewBinary(expr.Add, base, addr, width64)
+	}
+	return addr
+}
+
+// segmentOperand returns segment override prefix of instruction i in assembler
+// code.
+//
+// The ds prefix of indirect branches is the notrack prefix of control flow
+// enforcement rather than a segment override.
+func (i instruction) segmentOperand() string {
+	s := i.prefixes.segment
+	if s == prefixDS && i.instrType.branch {
+		return ""
+	}
+	if s != 0 {
+		return "%" + segmentNames[s] + ":"
+	}
+	return ""
+}
+
+// memOperandString returns memory operand of instruction i in assembler code.
+func (i instruction) memOperandString() string {
+	return i.segmentOperand() + i.addrString()
+}
+
+// addrString returns address of memory operand of instruction i in assembler
+// code without its segment.
+func (i instruction) addrString() string {
+	m, w := i.mem, i.addrWidth()
+
+	disp := ""
+	switch {
+	case !m.hasBase && !m.hasIndex && !m.rip:
+		// Absolute addresses are unsigned.
+		disp = fmt.Sprintf("0x%x", uint64(m.disp)&widthMask(w))
+	case m.dispLen != 0 || m.rip:
+		disp = signedHex(m.disp)
+	}
+
+	var regs string
+	switch {
+	case m.rip && w == width32:
+		regs = "(%eip)"
+	case m.rip:
+		regs = "(%rip)"
+	case m.hasIndex:
+		base := ""
+		if m.hasBase {
+			base = gpr{num: m.base}.name(w)
+		}
+		regs = fmt.Sprintf("(%s,%s,%d)", base, gpr{num: m.index}.name(w), 1<<m.scale)
+	case m.hasBase:
+		regs = fmt.Sprintf("(%s)", gpr{num: m.base}.name(w))
+	}
+
+	return disp + regs
+}
+
+// signedHex returns hexadecimal representation of signed value v.
+func signedHex(v int64) string {
+	if v < 0 {
+		return fmt.Sprintf("-0x%x", uint64(-v))
+	}
+	return fmt.Sprintf("0x%x", v)
+}
+
+// regOperand returns the register operand of width w encoded in the reg field
+// of ModRM byte of instruction i.
+func (i instruction) regOperand(w expr.Width) gpr {
+	return newGPR(i.regNum(), w, i.prefixes.hasRex())
+}
+
+// rmRegOperand returns the register operand of width w encoded in the rm field
+// of ModRM byte of instruction i. The r/m operand has to be a register.
+func (i instruction) rmRegOperand(w expr.Width) gpr {
+	return newGPR(i.rmNum(), w, i.prefixes.hasRex())
+}
+
+// opcodeRegOperand returns the register operand of width w encoded in the
+// last opcode byte of instruction i.
+func (i instruction) opcodeRegOperand(w expr.Width) gpr {
+	return newGPR(i.opcodeRegNum(), w, i.prefixes.hasRex())
+}
+
+// regString returns the register operand of width w encoded in the reg field of
+// ModRM byte of instruction i in assembler code.
+func (i instruction) regString(w expr.Width) string { return i.regOperand(w).name(w) }
+
+// rmString returns the r/m operand of width w of instruction i in assembler
+// code.
+func (i instruction) rmString(w expr.Width) string {
+	if i.rmIsMem() {
+		return i.memOperandString()
+	}
+	return i.rmRegOperand(w).name(w)
+}
+
+// immString returns the immediate operand of instruction i truncated to width w
+// in assembler code.
+func (i instruction) immString(w expr.Width) string {
+	return fmt.Sprintf("$0x%x", i.immValue()&widthMask(w))
+}
+
+// targetString returns target address of a relative branch in assembler code.
+func (i instruction) targetString() string { return fmt.Sprintf("0x%x", i.target()) }
+
+// operand is a register or memory operand of an instruction.
+type operand interface {
+	// load returns value of w bytes of the operand.
+	load(w expr.Width) expr.Expr
+	// store returns an effect writing value e of width w to the operand.
+	store(e expr.Expr, w expr.Width) expr.Effect
+}
+
+// memRef is a memory operand at address addr.
+type memRef struct {
+	addr expr.Expr
+}
+
+func (m memRef) load(w expr.Width) expr.Expr { return memLoad(m.addr, w) }
+
+func (m memRef) store(e expr.Expr, w expr.Width) expr.Effect {
+	return memStore(e, m.addr, w)
+}
+
+// rmOperand returns the r/m operand of width w of instruction i.
+func (i instruction) rmOperand(w expr.Width) operand {
+	if i.rmIsMem() {
+		return memRef{addr: i.memAddr()}
+	}
+	return i.rmRegOperand(w)
+}
+
+// immConst returns the immediate operand of instruction i as a constant of width
+// w.
+func (i instruction) immConst(w expr.Width) expr.Const {
+	return expr.NewConstUint(i.immValue()&widthMask(w), w)
+}
+
+// widthMask returns mask of all bits of width w.
+func widthMask(w expr.Width) uint64 {
+	if w >= width64 {
+		return ^uint64(0)
+	}
+	return uint64(1)<<w.Bits() - 1
+}
@@ -0,0 +1,82 @@
+package x86
+
+import (
+	"fmt"
+	"mltwist/internal/opcode"
+	"mltwist/pkg/model"
+)
+
+// Parser parses instructions of 64 bit mode of x86 architecture (x86-64).
+type Parser struct {
+	matcher *opcode.Matcher[*instructionType]
+}
+
+// NewParser creates a new x86-64 instruction parser.
+func NewParser() Parser {
+	decoder, err := opcode.NewMatcher(instructions)
+
+	// This means that instruction opcodes defined in this package are
+	// either invalid or they collide. This is non-recoverable as the
+	// package code has to be modified.
+	if err != nil {
+		panic(fmt.Sprintf("bug: matcher creation failed: %s", err.Error()))
+	}
+
+	return Parser{matcher: decoder}
+}
+
+// Parse parses an instruction starting at address a comprising of bytes at the
+// beginning of bs.
+//
+// The array of bytes bs is allowed to be longer than the instruction. In such a
+// case the Parse method will take into consideration only those bytes at the
+// beginning of the array which represent a single instruction. Instructions
+// are 1 to 15 bytes long.
+func (p Parser) Parse(a model.Addr, bs []byte) (model.Instruction, error) {
+	prefixes, n, err := parsePrefixes(bs)
+	if err != nil {
+		return model.Instruction{}, err
+	}
+
+	opcode, ok := p.matcher.Match(bs[n:])
+	if !ok {
+		end := n + 3
+		if end > len(bs) {
+			end = len(bs)
+		}
+		return model.Instruction{}, fmt.Errorf(
+			"unknown instruction opcode: 0x%x", bs[:end])
+	}
+
+	instr, err := decodeInstruction(a, bs, prefixes, n, opcode)
+	if err != nil {
+		return model.Instruction{}, err
+	}
+
+	if reservedEncoding(instr) {
+		return model.Instruction{}, fmt.Errorf(
+			"invalid encoding of %s: 0x%x", opcode.name, instr.bytes)
+	}
+
+	return model.Instruction{
+		Type:    opcode.typeOfInstr(instr),
+		ByteLen: model.Addr(instr.len()),
+		Order:   opcode.memOrder(instr),
+
+		Effects: opcode.validEffects(instr),
+		Details: instr,
+	}, nil
+}
+
+// reservedEncoding indicates that instruction i is not a valid instruction even
+// though its opcode was recognized.
+func reservedEncoding(i instruction) bool {
+	t := i.instrType
+	if i.prefixes.lock && (!t.lockable || !i.rmIsMem()) {
+		return true
+	}
+	return t.reserved != nil && t.reserved(i)
+}
+
+// MinInstrLen returns length of the shortest x86-64 instruction in bytes.
+func (Parser) MinInstrLen() model.Addr { return 1 }
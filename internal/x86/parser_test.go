@@ -0,0 +1,93 @@
+package x86
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewParser(_ *testing.T) {
+	NewParser()
+}
+
+func TestParser_Parse(t *testing.T) {
+	tests := []struct {
+		name   string
+		bytes  []byte
+		len    int
+		hasErr bool
+	}{{
+		// add %rsi,%rdi
+		name:  "valid",
+		bytes: []byte{0x48, 0x01, 0xf7},
+		len:   3,
+	}, {
+		// add %rsi,%rdi
+		name:  "longer_bytes",
+		bytes: []byte{0x48, 0x01, 0xf7, 0xff, 0xff},
+		len:   3,
+	}, {
+		// nopw %cs:0x0(%rax,%rax,1)
+		name:  "prefixes_and_displacement",
+		bytes: []byte{0x66, 0x2e, 0x0f, 0x1f, 0x84, 0x00, 0x00, 0x00, 0x00, 0x00},
+		len:   10,
+	}, {
+		// mov 0x8(%rbx),%rax
+		name:   "too_short",
+		bytes:  []byte{0x48, 0x8b, 0x43},
+		hasErr: true,
+	}, {
+		name:   "prefixes_only",
+		bytes:  []byte{0x66, 0x48},
+		hasErr: true,
+	}, {
+		name:   "too_long",
+		bytes:  []byte{0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x90},
+		hasErr: true,
+	}, {
+		// cpuid
+		name:   "unknown",
+		bytes:  []byte{0x0f, 0xa2},
+		hasErr: true,
+	}, {
+		// lock add %rsi,%rdi
+		name:   "lock_register",
+		bytes:  []byte{0xf0, 0x48, 0x01, 0xf7},
+		hasErr: true,
+	}, {
+		// lock mov %eax,(%rbx)
+		name:   "lock_not_lockable",
+		bytes:  []byte{0xf0, 0x89, 0x03},
+		hasErr: true,
+	}, {
+		// lea with a register operand
+		name:   "reserved_lea",
+		bytes:  []byte{0x48, 0x8d, 0xc0},
+		hasErr: true,
+	}, {
+		// popcnt without the rep prefix
+		name:   "reserved_popcnt",
+		bytes:  []byte{0x0f, 0xb8, 0xc6},
+		hasErr: true,
+	}, {
+		// movsxd without REX.W
+		name:   "reserved_movsxd",
+		bytes:  []byte{0x63, 0xc0},
+		hasErr: true,
+	}}
+
+	p := NewParser()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ins, err := p.Parse(testAddr, tt.bytes)
+			if tt.hasErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.len, int(ins.ByteLen))
+			require.NoError(t, ins.Validate())
+		})
+	}
+}
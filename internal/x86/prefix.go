@@ -0,0 +1,102 @@
+package x86
+
+import "fmt"
+
+// Legacy prefixes of instructions.
+const (
+	prefixOpSize   = 0x66
+	prefixAddrSize = 0x67
+	prefixLock     = 0xf0
+	prefixRepNE    = 0xf2
+	prefixRep      = 0xf3
+
+	prefixCS = 0x2e
+	prefixSS = 0x36
+	prefixDS = 0x3e
+	prefixES = 0x26
+	prefixFS = 0x64
+	prefixGS = 0x65
+)
+
+// segmentNames are names of segment registers selected by segment override
+// prefixes.
+var segmentNames = map[byte]string{
+	prefixCS: "cs",
+	prefixSS: "ss",
+	prefixDS: "ds",
+	prefixES: "es",
+	prefixFS: "fs",
+	prefixGS: "gs",
+}
+
+// Bits of the REX prefix.
+const (
+	rexB = 1 << iota
+	rexX
+	rexR
+	rexW
+)
+
+// prefixes are prefixes an instruction is encoded with.
+type prefixes struct {
+	opSize   bool
+	addrSize bool
+	lock     bool
+	rep      bool
+	repNE    bool
+	// segment is the segment override prefix or zero if there is none.
+	segment byte
+	// rex is the REX prefix or zero if there is none.
+	rex byte
+}
+
+// hasRex indicates that the REX prefix is present. Some registers can be
+// encoded only in instructions without the REX prefix and some only in
+// instructions with it.
+func (p prefixes) hasRex() bool { return p.rex != 0 }
+
+// rexBit returns value of bit b of the REX prefix shifted to the position of
+// the fourth bit of a register number.
+func (p prefixes) rexBit(b byte) regNum {
+	if p.rex&b != 0 {
+		return 1 << 3
+	}
+	return 0
+}
+
+// parsePrefixes parses prefixes at the beginning of bs. It returns the
+// prefixes together with number of bytes they take.
+//
+// The REX prefix has effect only if it's directly followed by the opcode, so
+// a REX prefix followed by other prefixes is ignored.
+func parsePrefixes(bs []byte) (prefixes, int, error) {
+	var p prefixes
+	for n, b := range bs {
+		if n >= maxInstructionLen {
+			break
+		}
+
+		switch {
+		case b&0xf0 == 0x40:
+			p.rex = b
+			continue
+		case b == prefixOpSize:
+			p.opSize = true
+		case b == prefixAddrSize:
+			p.addrSize = true
+		case b == prefixLock:
+			p.lock = true
+		case b == prefixRep:
+			p.rep, p.repNE = true, false
+		case b == prefixRepNE:
+			p.repNE, p.rep = true, false
+		case segmentNames[b] != "":
+			p.segment = b
+		default:
+			return p, n, nil
+		}
+		p.rex = 0
+	}
+
+	return prefixes{}, 0, fmt.Errorf("no opcode follows prefixes: 0x%x", bs)
+}
@@ -0,0 +1,173 @@
+package x86
+
+import (
+	"fmt"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+)
+
+const (
+	// regCnt is number of general purpose registers in 64 bit mode.
+	regCnt = 16
+
+	// regMask is mask of register number bits encoded in an opcode or in
+	// a ModRM byte. The fourth bit is encoded in the REX prefix.
+	regMask = 0b111
+)
+
+// Numbers of general purpose registers with special meaning for some
+// instructions.
+const (
+	regAX  regNum = 0
+	regCX  regNum = 1
+	regDX  regNum = 2
+	regSP  regNum = 4
+	regBP  regNum = 5
+	regSI  regNum = 6
+	regDI  regNum = 7
+	regR11 regNum = 11
+)
+
+// regNum represents number of a general purpose register. Range of valid
+// values is [0..15] (i.e. [0..regCnt-1]).
+type regNum uint8
+
+// regNames are names of 64 bit views of registers. The names are used as keys
+// of the registers as well.
+var regNames = [regCnt]string{
+	"rax", "rcx", "rdx", "rbx", "rsp", "rbp", "rsi", "rdi",
+	"r8", "r9", "r10", "r11", "r12", "r13", "r14", "r15",
+}
+
+// Names of 32, 16 and 8 bit views of the first 8 registers. The remaining
+// registers have the size of the view appended to their name (r8d, r8w, r8b).
+var (
+	regNames32 = [...]string{"eax", "ecx", "edx", "ebx", "esp", "ebp", "esi", "edi"}
+	regNames16 = [...]string{"ax", "cx", "dx", "bx", "sp", "bp", "si", "di"}
+	regNames8  = [...]string{"al", "cl", "dl", "bl", "spl", "bpl", "sil", "dil"}
+
+	// regNamesHigh are names of bits [8:15] of the first 4 registers.
+	regNamesHigh = [...]string{"ah", "ch", "dh", "bh"}
+)
+
+// key returns key of register r.
+func (r regNum) key() expr.Key { return expr.Key(regNames[r]) }
+
+// Keys of the rflags register bits the package models. Every flag is a single
+// byte register which holds value 1 if the flag is set and value 0 otherwise.
+const (
+	// CFKey identifies the carry flag.
+	CFKey = expr.Key("cf")
+	// PFKey identifies the parity flag.
+	PFKey = expr.Key("pf")
+	// AFKey identifies the auxiliary carry flag.
+	AFKey = expr.Key("af")
+	// ZFKey identifies the zero flag.
+	ZFKey = expr.Key("zf")
+	// SFKey identifies the sign flag.
+	SFKey = expr.Key("sf")
+	// OFKey identifies the overflow flag.
+	OFKey = expr.Key("of")
+	// DFKey identifies the direction flag of string instructions.
+	DFKey = expr.Key("df")
+)
+
+// flagWidth is width of registers of rflags bits.
+const flagWidth = expr.Width8
+
+// Keys of base addresses of segments which can be used in 64 bit mode. Bases
+// of all other segments are always zero.
+const (
+	// FSBaseKey identifies the base address of the fs segment.
+	FSBaseKey = expr.Key("fs_base")
+	// GSBaseKey identifies the base address of the gs segment.
+	GSBaseKey = expr.Key("gs_base")
+)
+
+// gpr is a view of a general purpose register used as an operand.
+type gpr struct {
+	num regNum
+	// high means that the operand is bits [8:15] of the register rather
+	// than the lowest byte. Only the first 4 registers can be accessed
+	// this way and only in instructions without the REX prefix.
+	high bool
+}
+
+// newGPR returns view of register r in an operand of width w of an
+// instruction. Argument rex indicates that the instruction has the REX prefix.
+func newGPR(r regNum, w expr.Width, rex bool) gpr {
+	if w == width8 && !rex && r >= regSP && r <= regDI {
+		return gpr{num: r - regSP, high: true}
+	}
+	return gpr{num: r}
+}
+
+// name returns name of the view of width w of g in assembler code.
+func (g gpr) name(w expr.Width) string {
+	if g.high {
+		return "%" + regNamesHigh[g.num]
+	}
+
+	if g.num >= 8 {
+		suffix := map[expr.Width]string{width8: "b", width16: "w", width32: "d"}
+		return fmt.Sprintf("%%%s%s", regNames[g.num], suffix[w])
+	}
+
+	switch w {
+	case width8:
+		return "%" + regNames8[g.num]
+	case width16:
+		return "%" + regNames16[g.num]
+	case width32:
+		return "%" + regNames32[g.num]
+	default:
+		return "%" + regNames[g.num]
+	}
+}
+
+// load returns value of w bytes of g.
+func (g gpr) load(w expr.Width) expr.Expr {
+	if g.high {
+		v := expr.NewRegLoad(g.num.key(), width16)
+		return expr.NewBinary(expr.Rsh, v, expr.ConstFromUint[uint8](8), width16)
+	}
+	return expr.NewRegLoad(g.num.key(), w)
+}
+
+// store stores value e of width w to g.
+//
+// Writes to 32 bit views of registers clear the upper half of the register,
+// writes to 8 and 16 bit views keep all other bits of the register unchanged.
+func (g gpr) store(e expr.Expr, w expr.Width) expr.Effect {
+	k := g.num.key()
+	switch w {
+	case width64:
+		return expr.NewRegStore(e, k, width64)
+	case width32:
+		return expr.NewRegStore(exprtools.MaskBits(e, bitCnt(w), width64), k, width64)
+	}
+
+	shift := uint8(0)
+	if g.high {
+		shift = 8
+	}
+	mask := expr.NewConstUint((uint64(1)<<w.Bits()-1)<<shift, width64)
+
+	old := exprtools.BitAnd(expr.NewRegLoad(k, width64), exprtools.BitNot(mask, width64), width64)
+	v := exprtools.MaskBits(e, bitCnt(w), width64)
+	if shift != 0 {
+		v = expr.NewBinary(expr.Lsh, v, expr.ConstFromUint(shift), width64)
+	}
+	return expr.NewRegStore(exprtools.BitOr(old, v, width64), k, width64)
+}
+
+// regLoad loads w bytes of register r.
+func regLoad(r regNum, w expr.Width) expr.Expr { return gpr{num: r}.load(w) }
+
+// regStore stores value e of width w to register r.
+func regStore(e expr.Expr, r regNum, w expr.Width) expr.Effect {
+	return gpr{num: r}.store(e, w)
+}
+
+// bitCnt returns number of bits of width w.
+func bitCnt(w expr.Width) exprtools.BitCnt { return exprtools.BitCnt(w.Bits()) }
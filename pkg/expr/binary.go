@@ -31,6 +31,9 @@ func (b Binary) Arg1() Expr { return b.arg1 }
 // Arg2 returns second operang of a binary operation.
 func (b Binary) Arg2() Expr { return b.arg2 }
 
+// String returns b in the S-expression format. See Parse for details.
+func (b Binary) String() string { return exprString(b) }
+
 // Width returns width of b.
 func (b Binary) Width() Width { return b.w }
 func (Binary) internalExpr()  {}
@@ -101,6 +101,9 @@ func ConstFromInt[T constraints.Signed](val T) Const {
 // immutable.
 func (c Const) Bytes() []byte { return c.bs }
 
+// String returns c in the S-expression format. See Parse for details.
+func (c Const) String() string { return exprString(c) }
+
 // Width returns width of c.
 func (c Const) Width() Width { return Width(len(c.bs)) }
 
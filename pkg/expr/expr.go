@@ -128,6 +128,9 @@ package expr
 type Expr interface {
 	// Width returns width of an expression.
 	Width() Width
+	// String returns the expression in the S-expression format. See Parse
+	// for details.
+	String() string
 
 	// internal is a blocker function which prevents defining custom
 	// expressions anywhere else than in this package.
@@ -149,6 +152,9 @@ type Expr interface {
 type Effect interface {
 	// Width returns width of an effect.
 	Width() Width
+	// String returns the effect in the S-expression format. See
+	// ParseEffect for details.
+	String() string
 
 	// internalEffect is a blocker function which prevents defining custom
 	// effects anywhere else than in this package.
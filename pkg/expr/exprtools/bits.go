@@ -27,3 +27,40 @@ func BitXor(e1 expr.Expr, e2 expr.Expr, w expr.Width) expr.Expr {
 	nand2 := expr.NewBinary(expr.Nand, e2, nandInputs, w)
 	return expr.NewBinary(expr.Nand, nand1, nand2, w)
 }
+
+// RotateLeft rotates e of width w left by shift bits. Bits shifted out of the
+// top of e are shifted back in the bottom of the result. The result is
+// undefined if shift is greater than number of bits of w.
+func RotateLeft(e expr.Expr, shift expr.Expr, w expr.Width) expr.Expr {
+	antiShift := Sub(expr.ConstFromUint(w.Bits()), shift, w)
+	return BitOr(
+		expr.NewBinary(expr.Lsh, e, shift, w),
+		expr.NewBinary(expr.Rsh, e, antiShift, w),
+		w,
+	)
+}
+
+// RotateRight rotates e of width w right by shift bits. Bits shifted out of
+// the bottom of e are shifted back in the top of the result. The result is
+// undefined if shift is greater than number of bits of w.
+func RotateRight(e expr.Expr, shift expr.Expr, w expr.Width) expr.Expr {
+	antiShift := Sub(expr.ConstFromUint(w.Bits()), shift, w)
+	return BitOr(
+		expr.NewBinary(expr.Rsh, e, shift, w),
+		expr.NewBinary(expr.Lsh, e, antiShift, w),
+		w,
+	)
+}
+
+// ReverseBytes reverses order of bytes of e of width w.
+func ReverseBytes(e expr.Expr, w expr.Width) expr.Expr {
+	var res expr.Expr = expr.Zero
+	for i := expr.Width(0); i < w; i++ {
+		b := MaskBits(
+			expr.NewBinary(expr.Rsh, e, expr.ConstFromUint(8*uint16(i)), w),
+			8, w)
+		shifted := expr.NewBinary(expr.Lsh, b, expr.ConstFromUint(8*uint16(w-1-i)), w)
+		res = BitOr(res, shifted, w)
+	}
+	return res
+}
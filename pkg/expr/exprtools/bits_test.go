@@ -95,3 +95,88 @@ func TestAndOrXor(t *testing.T) {
 		})
 	}
 }
+
+func TestRotate(t *testing.T) {
+	tests := []struct {
+		name  string
+		e     expr.Expr
+		shift expr.Expr
+		w     expr.Width
+		left  expr.Expr
+		right expr.Expr
+	}{{
+		name:  "zero_shift",
+		e:     expr.ConstFromUint[uint32](0x12345678),
+		shift: expr.Zero,
+		w:     expr.Width32,
+		left:  expr.ConstFromUint[uint32](0x12345678),
+		right: expr.ConstFromUint[uint32](0x12345678),
+	}, {
+		name:  "byte_shift",
+		e:     expr.ConstFromUint[uint32](0x12345678),
+		shift: expr.ConstFromUint[uint8](8),
+		w:     expr.Width32,
+		left:  expr.ConstFromUint[uint32](0x34567812),
+		right: expr.ConstFromUint[uint32](0x78123456),
+	}, {
+		name:  "cut_width",
+		e:     expr.ConstFromUint[uint64](0xffffffff_80000001),
+		shift: expr.ConstFromUint[uint8](1),
+		w:     expr.Width32,
+		left:  expr.ConstFromUint[uint32](0x00000003),
+		right: expr.ConstFromUint[uint32](0xc0000000),
+	}, {
+		name:  "full_shift",
+		e:     expr.ConstFromUint[uint16](0xabcd),
+		shift: expr.ConstFromUint[uint8](16),
+		w:     expr.Width16,
+		left:  expr.ConstFromUint[uint16](0xabcd),
+		right: expr.ConstFromUint[uint16](0xabcd),
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Run("left", func(t *testing.T) {
+				e := exprtools.RotateLeft(tt.e, tt.shift, tt.w)
+				require.Equal(t, tt.left, exprtransform.ConstFold(e))
+			})
+			t.Run("right", func(t *testing.T) {
+				e := exprtools.RotateRight(tt.e, tt.shift, tt.w)
+				require.Equal(t, tt.right, exprtransform.ConstFold(e))
+			})
+		})
+	}
+}
+
+func TestReverseBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		e    expr.Expr
+		w    expr.Width
+		exp  expr.Expr
+	}{{
+		name: "single_byte",
+		e:    expr.ConstFromUint[uint8](0xab),
+		w:    expr.Width8,
+		exp:  expr.ConstFromUint[uint8](0xab),
+	}, {
+		name: "keep_width",
+		e:    expr.ConstFromUint[uint64](0x0123456789abcdef),
+		w:    expr.Width64,
+		exp:  expr.ConstFromUint[uint64](0xefcdab8967452301),
+	}, {
+		name: "cut_width",
+		e:    expr.ConstFromUint[uint64](0x0123456789abcdef),
+		w:    expr.Width32,
+		exp:  expr.ConstFromUint[uint32](0xefcdab89),
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			e := exprtools.ReverseBytes(tt.e, tt.w)
+			require.Equal(t, tt.exp, exprtransform.ConstFold(e))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package exprtools
+
+import "mltwist/pkg/expr"
+
+// LeadingZeros returns number of leading (most significant) zero bits of e
+// cropped to w bits. The result is an expression of width w. If e is zero,
+// the result is number of bits of w.
+func LeadingZeros(e expr.Expr, w expr.Width) expr.Expr {
+	bits := w.Bits()
+
+	// Binary search for the highest bit set: in every step, the value is
+	// shifted left by s bits if its top s bits are all zeros. Shift
+	// amounts are powers of two which sum to at least bits-1, so any
+	// nonzero value ends up with its top bit set.
+	var n expr.Expr = expr.Zero
+	x := e
+	for s := highestPow2Below(bits); s > 0; s /= 2 {
+		shift := expr.NewLess(x, bitAt(bits-s, w), expr.ConstFromUint(s), expr.Zero, w)
+		n = expr.NewBinary(expr.Add, n, shift, w)
+		x = expr.NewBinary(expr.Lsh, x, shift, w)
+	}
+
+	return expr.NewLess(e, expr.One, expr.ConstFromUint(bits), n, w)
+}
+
+// highestPow2Below returns the highest power of two lower than n. Zero is
+// returned for n lower than 2.
+func highestPow2Below(n uint16) uint16 {
+	var p uint16 = 1
+	for p*2 < n {
+		p *= 2
+	}
+	if p >= n {
+		return 0
+	}
+	return p
+}
+
+// TrailingZeros returns number of trailing (least significant) zero bits of e
+// cropped to w bits. The result is an expression of width w. If e is zero, the
+// result is number of bits of w.
+func TrailingZeros(e expr.Expr, w expr.Width) expr.Expr {
+	// Bits of (e - 1) & ^e are set exactly in positions of trailing zeros
+	// of e.
+	trailing := BitAnd(Sub(e, expr.One, w), BitNot(e, w), w)
+	return PopCount(trailing, w)
+}
+
+// PopCount returns population count of e cropped to w bits - number of bits
+// set in e. The result is an expression of width w.
+func PopCount(e expr.Expr, w expr.Width) expr.Expr {
+	bits := w.Bits()
+
+	// Bits are summed in fields of doubling size. In every step, each field
+	// of size 2*f holds the sum of its two halves, so the final field
+	// spanning all the bits holds number of all bits set.
+	x := e
+	for f := uint16(1); f < bits; f *= 2 {
+		m := fieldMask(f, w)
+		low := BitAnd(x, m, w)
+		high := BitAnd(expr.NewBinary(expr.Rsh, x, expr.ConstFromUint(f), w), m, w)
+		x = expr.NewBinary(expr.Add, low, high, w)
+	}
+
+	return x
+}
+
+// fieldMask returns a constant of width w which splits bits into fields of f
+// bits and which has ones in all bits of even fields. The lowest field is the
+// field zero.
+func fieldMask(f uint16, w expr.Width) expr.Const {
+	bs := make([]byte, w)
+	for i := uint16(0); i < w.Bits(); i++ {
+		if (i/f)%2 == 0 {
+			bs[i/8] |= 1 << (i % 8)
+		}
+	}
+	return expr.NewConst(bs, w)
+}
@@ -0,0 +1,117 @@
+package exprtools_test
+
+import (
+	"math/bits"
+	"math/rand"
+	"mltwist/internal/exprtransform"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name string
+		e    expr.Expr
+		w    expr.Width
+
+		leading  expr.Expr
+		trailing expr.Expr
+		pop      expr.Expr
+	}{{
+		name:     "zero",
+		e:        expr.ConstFromUint[uint64](0),
+		w:        expr.Width64,
+		leading:  expr.ConstFromUint[uint64](64),
+		trailing: expr.ConstFromUint[uint64](64),
+		pop:      expr.ConstFromUint[uint64](0),
+	}, {
+		name:     "ones",
+		e:        expr.ConstFromUint[uint32](0xffffffff),
+		w:        expr.Width32,
+		leading:  expr.ConstFromUint[uint32](0),
+		trailing: expr.ConstFromUint[uint32](0),
+		pop:      expr.ConstFromUint[uint32](32),
+	}, {
+		name:     "single_byte",
+		e:        expr.ConstFromUint[uint8](0x28),
+		w:        expr.Width8,
+		leading:  expr.ConstFromUint[uint8](2),
+		trailing: expr.ConstFromUint[uint8](3),
+		pop:      expr.ConstFromUint[uint8](2),
+	}, {
+		name:     "cut_width",
+		e:        expr.ConstFromUint[uint64](0xffff0000_00400100),
+		w:        expr.Width32,
+		leading:  expr.ConstFromUint[uint32](9),
+		trailing: expr.ConstFromUint[uint32](8),
+		pop:      expr.ConstFromUint[uint32](2),
+	}, {
+		name:     "odd_width",
+		e:        expr.NewConst([]byte{0x00, 0x80, 0x01}, 3),
+		w:        3,
+		leading:  expr.NewConst([]byte{7, 0, 0}, 3),
+		trailing: expr.NewConst([]byte{15, 0, 0}, 3),
+		pop:      expr.NewConst([]byte{2, 0, 0}, 3),
+	}, {
+		name:     "odd_width_zero",
+		e:        expr.NewConst([]byte{0, 0, 0}, 3),
+		w:        3,
+		leading:  expr.NewConst([]byte{24, 0, 0}, 3),
+		trailing: expr.NewConst([]byte{24, 0, 0}, 3),
+		pop:      expr.NewConst([]byte{0, 0, 0}, 3),
+	}, {
+		name: "big_int",
+		e: expr.NewConst([]byte{
+			0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x10, 0xff, 0, 0, 0, 0x01,
+		}, expr.Width128),
+		w:        expr.Width128,
+		leading:  expr.NewConst([]byte{7}, expr.Width128),
+		trailing: expr.NewConst([]byte{84}, expr.Width128),
+		pop:      expr.NewConst([]byte{10}, expr.Width128),
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Run("leading", func(t *testing.T) {
+				e := exprtools.LeadingZeros(tt.e, tt.w)
+				require.Equal(t, tt.leading, exprtransform.ConstFold(e))
+			})
+			t.Run("trailing", func(t *testing.T) {
+				e := exprtools.TrailingZeros(tt.e, tt.w)
+				require.Equal(t, tt.trailing, exprtransform.ConstFold(e))
+			})
+			t.Run("pop", func(t *testing.T) {
+				e := exprtools.PopCount(tt.e, tt.w)
+				require.Equal(t, tt.pop, exprtransform.ConstFold(e))
+			})
+		})
+	}
+}
+
+func TestCount_Random(t *testing.T) {
+	const iterations = 256
+
+	r := require.New(t)
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < iterations; i++ {
+		// Use sparse values as well to test wide range of counts.
+		val := rnd.Uint64() >> rnd.Intn(64) << rnd.Intn(64)
+		e := expr.ConstFromUint(val)
+
+		leading := exprtools.LeadingZeros(e, expr.Width64)
+		r.Equal(expr.ConstFromUint(uint64(bits.LeadingZeros64(val))),
+			exprtransform.ConstFold(leading), "0x%x", val)
+
+		trailing := exprtools.TrailingZeros(e, expr.Width64)
+		r.Equal(expr.ConstFromUint(uint64(bits.TrailingZeros64(val))),
+			exprtransform.ConstFold(trailing), "0x%x", val)
+
+		pop := exprtools.PopCount(e, expr.Width64)
+		r.Equal(expr.ConstFromUint(uint64(bits.OnesCount64(val))),
+			exprtransform.ConstFold(pop), "0x%x", val)
+	}
+}
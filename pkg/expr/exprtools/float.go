@@ -0,0 +1,24 @@
+package exprtools
+
+import "mltwist/pkg/expr"
+
+// Floating point operations which only manipulate the sign bit of an IEEE 754
+// value are not arithmetic operations - they never round, they never raise any
+// exception and they preserve NaN payloads. Consequently, they are not part of
+// expr.Float, but they are composed of bit operations instead.
+
+// FloatNegate flips sign of floating point value e of width w.
+func FloatNegate(e expr.Expr, w expr.Width) expr.Expr {
+	return BitXor(e, signBitMask(w), w)
+}
+
+// FloatAbs returns absolute value of floating point value e of width w.
+func FloatAbs(e expr.Expr, w expr.Width) expr.Expr {
+	return BitAnd(e, BitNot(signBitMask(w), w), w)
+}
+
+// FloatCopySign returns floating point value of width w with magnitude of mag
+// and with sign of sign.
+func FloatCopySign(mag expr.Expr, sign expr.Expr, w expr.Width) expr.Expr {
+	return BitOr(FloatAbs(mag, w), IntNegative(sign, w), w)
+}
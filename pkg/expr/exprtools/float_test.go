@@ -0,0 +1,68 @@
+package exprtools_test
+
+import (
+	"math"
+	"mltwist/internal/exprtransform"
+	"mltwist/pkg/expr"
+	"mltwist/pkg/expr/exprtools"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func float64Const(f float64) expr.Const {
+	return expr.ConstFromUint(math.Float64bits(f))
+}
+
+func TestFloatSign(t *testing.T) {
+	tests := []struct {
+		name string
+		e1   expr.Expr
+		e2   expr.Expr
+		w    expr.Width
+
+		neg      expr.Expr
+		abs      expr.Expr
+		copySign expr.Expr
+	}{{
+		name:     "positive",
+		e1:       float64Const(1.5),
+		e2:       float64Const(-2),
+		w:        expr.Width64,
+		neg:      float64Const(-1.5),
+		abs:      float64Const(1.5),
+		copySign: float64Const(-1.5),
+	}, {
+		name:     "negative_zero",
+		e1:       float64Const(math.Copysign(0, -1)),
+		e2:       float64Const(3),
+		w:        expr.Width64,
+		neg:      float64Const(0),
+		abs:      float64Const(0),
+		copySign: float64Const(0),
+	}, {
+		name:     "single_nan",
+		e1:       expr.ConstFromUint[uint32](0xffc00001),
+		e2:       expr.ConstFromUint[uint32](0x80000000),
+		w:        expr.Width32,
+		neg:      expr.ConstFromUint[uint32](0x7fc00001),
+		abs:      expr.ConstFromUint[uint32](0x7fc00001),
+		copySign: expr.ConstFromUint[uint32](0xffc00001),
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			neg := exprtools.FloatNegate(tt.e1, tt.w)
+			r.Equal(tt.neg, exprtransform.ConstFold(neg))
+
+			abs := exprtools.FloatAbs(tt.e1, tt.w)
+			r.Equal(tt.abs, exprtransform.ConstFold(abs))
+
+			copySign := exprtools.FloatCopySign(tt.e1, tt.e2, tt.w)
+			r.Equal(tt.copySign, exprtransform.ConstFold(copySign))
+		})
+	}
+}
@@ -30,18 +30,22 @@ func MaskBits(e expr.Expr, cnt BitCnt, w expr.Width) expr.Expr {
 	return BitAnd(e, bitMask(cnt, w), w)
 }
 
-// signBitMask returns a mask with width w with bit at position (w*8)-1 set. In
-// other words, the mask returned is a mask of highest bit in the expression.
-func signBitMask(w expr.Width) expr.Expr {
-	signBit := w.Bits() - 1
-	if signBit < 64 {
-		return expr.NewConstUint(uint64(1)<<uint64(signBit), w)
+// bitAt returns a mask with width w with only bit at position pos set.
+func bitAt(pos uint16, w expr.Width) expr.Expr {
+	if pos < 64 {
+		return expr.NewConstUint(uint64(1)<<uint64(pos), w)
 	}
 
-	shift := expr.ConstFromUint(signBit)
+	shift := expr.ConstFromUint(pos)
 	return expr.NewBinary(expr.Lsh, expr.One, shift, w)
 }
 
+// signBitMask returns a mask with width w with bit at position (w*8)-1 set. In
+// other words, the mask returned is a mask of highest bit in the expression.
+func signBitMask(w expr.Width) expr.Expr {
+	return bitAt(w.Bits()-1, w)
+}
+
 // IntNegative returns a nonzero expression if value of e cropped to w bits is
 // negative signed integer. On the other hand if e is a positive integer with w
 // bits, the value returned is zero.
@@ -0,0 +1,274 @@
+package expr
+
+import "fmt"
+
+var _ Expr = Float{}
+
+// Float represents a floating point operation on IEEE 754 binary values.
+//
+// Floating point values are encoded in the IEEE 754 binary interchange formats.
+// Width 4 (Width32) represents binary32 (single precision) and width 8
+// (Width64) represents binary64 (double precision). No other widths are
+// supported as floating point formats. Integer arguments and results of
+// conversion operations are allowed to have any width up to 8 bytes.
+//
+// Arguments of a floating point operation are zero-extended or truncated to
+// the width of arguments in the same way as arguments of Binary are. The width
+// of arguments equals to the width of the result for all operations but
+// conversions.
+//
+// The result of an operation is rounded according to the rounding mode. The
+// rounding mode is an expression which is evaluated as an unsigned integer
+// whose values are described by Rounding type. Any other value is treated as
+// RoundNearestEven.
+//
+// Even though IEEE 754 doesn't specify payload of NaN values produced by
+// operations, the expression model has to be deterministic. For this reason,
+// every operation producing NaN produces the canonical quiet NaN - the one with
+// sign bit unset and only the most significant bit of the significand set.
+// Tininess is detected after rounding.
+//
+// Every floating point operation might raise IEEE 754 exceptions. Those are not
+// part of the result of the operation, but they can be obtained by an
+// expression returned by Flags method.
+type Float struct {
+	op    FloatOp
+	args  []Expr
+	rm    Expr
+	argW  Width
+	w     Width
+	flags bool
+}
+
+// NewFloat creates a new floating point operation op of arguments args with
+// rounding mode rm. Both the arguments and the result are floating point values
+// of width w.
+//
+// This function panics if op is a conversion operation, if the number of
+// arguments doesn't match op or if w is not a floating point width.
+func NewFloat(op FloatOp, rm Expr, w Width, args ...Expr) Float {
+	if op.Conversion() {
+		panic(fmt.Sprintf("conversion requires argument width: %v", op))
+	}
+	return newFloat(op, rm, w, w, args)
+}
+
+// NewFloatConversion creates a new floating point conversion op of argument
+// arg of width argW to a value of width w with rounding mode rm.
+//
+// This function panics if op is not a conversion or if widths of arguments
+// don't match op.
+func NewFloatConversion(op FloatOp, rm Expr, arg Expr, argW Width, w Width) Float {
+	if !op.Conversion() {
+		panic(fmt.Sprintf("operation is not a conversion: %v", op))
+	}
+	return newFloat(op, rm, argW, w, []Expr{arg})
+}
+
+func newFloat(op FloatOp, rm Expr, argW Width, w Width, args []Expr) Float {
+	if err := validateFloat(op, argW, w, len(args)); err != nil {
+		panic(err.Error())
+	}
+
+	return Float{
+		op:   op,
+		args: args,
+		rm:   rm,
+		argW: argW,
+		w:    w,
+	}
+}
+
+// validateFloat checks that floating point operation op of argCnt arguments of
+// width argW and result of width w is valid.
+func validateFloat(op FloatOp, argW Width, w Width, argCnt int) error {
+	if n := op.Arity(); n != argCnt {
+		return fmt.Errorf("%v requires %d arguments, got %d", op, n, argCnt)
+	}
+
+	argFloat, resFloat := true, true
+	switch op {
+	case FFromInt, FFromUint:
+		argFloat = false
+	case FToInt, FToUint:
+		resFloat = false
+	case FEq, FLt, FLe:
+		resFloat = false
+	}
+
+	if err := validateFloatWidth(argW, argFloat); err != nil {
+		return err
+	}
+	return validateFloatWidth(w, resFloat)
+}
+
+// validateFloatWidth checks that w is valid width of a floating point value (if
+// float is set) or of an integer.
+func validateFloatWidth(w Width, float bool) error {
+	if float && w != Width32 && w != Width64 {
+		return fmt.Errorf("unsupported floating point width: %d", w)
+	}
+	if w == 0 || w > Width64 {
+		return fmt.Errorf("unsupported width: %d", w)
+	}
+	return nil
+}
+
+// Op returns the floating point operation.
+func (f Float) Op() FloatOp { return f.op }
+
+// Args returns arguments of the operation.
+//
+// The slice returned must be treated as read-only.
+func (f Float) Args() []Expr { return f.args }
+
+// Rounding returns the rounding mode expression of the operation.
+func (f Float) Rounding() Expr { return f.rm }
+
+// ArgWidth returns width of arguments of f.
+func (f Float) ArgWidth() Width { return f.argW }
+
+// WithArgs returns a copy of f with rounding mode rm and arguments args. This
+// function panics if the number of arguments doesn't match operation of f.
+func (f Float) WithArgs(rm Expr, args ...Expr) Float {
+	if n := f.op.Arity(); n != len(args) {
+		panic(fmt.Sprintf("%v requires %d arguments, got %d", f.op, n, len(args)))
+	}
+
+	f.rm = rm
+	f.args = args
+	return f
+}
+
+// Flags returns an expression which evaluates to IEEE 754 exception flags
+// raised by f instead of the result of f. The flags are described by
+// FloatException.
+func (f Float) Flags() Float {
+	f.flags = true
+	return f
+}
+
+// IsFlags indicates that f evaluates to exception flags rather than to the
+// result of the operation.
+func (f Float) IsFlags() bool { return f.flags }
+
+// String returns f in the S-expression format. See Parse for details.
+func (f Float) String() string { return exprString(f) }
+
+// Width returns width of f.
+func (f Float) Width() Width { return f.w }
+func (Float) internalExpr()  {}
+
+// FloatOp represents a floating point operation.
+type FloatOp uint8
+
+const (
+	// FAdd adds two floating point numbers.
+	FAdd FloatOp = iota + 1
+	// FSub subtracts the second argument from the first one.
+	FSub
+	// FMul multiplies two floating point numbers.
+	FMul
+	// FDiv divides the first argument by the second one.
+	FDiv
+	// FSqrt calculates square root of its only argument.
+	FSqrt
+	// FMulAdd multiplies the first two arguments and adds the third one
+	// with a single rounding (fused multiply-add).
+	FMulAdd
+
+	// FMin returns the smaller of two numbers. Negative zero is smaller
+	// than positive zero. If exactly one of arguments is NaN, the other
+	// argument is returned (minimumNumber of IEEE 754-2019).
+	FMin
+	// FMax returns the bigger of two numbers. It follows the same rules as
+	// FMin does (maximumNumber of IEEE 754-2019).
+	FMax
+
+	// FEq returns 1 if the arguments are equal and 0 otherwise. It's a
+	// quiet comparison - only signaling NaNs raise the invalid exception.
+	FEq
+	// FLt returns 1 if the first argument is less than the second one and
+	// 0 otherwise. It's a signaling comparison - any NaN argument raises
+	// the invalid exception.
+	FLt
+	// FLe returns 1 if the first argument is less than or equal to the
+	// second one and 0 otherwise. It's a signaling comparison.
+	FLe
+
+	// FConvert converts a floating point value to a floating point value
+	// of another width.
+	FConvert
+	// FFromInt converts a signed integer to a floating point value.
+	FFromInt
+	// FFromUint converts an unsigned integer to a floating point value.
+	FFromUint
+	// FToInt converts a floating point value to a signed integer. Values
+	// out of range of the integer saturate to the minimal or to the maximal
+	// integer and raise the invalid exception. NaN converts to the maximal
+	// integer.
+	FToInt
+	// FToUint converts a floating point value to an unsigned integer. It
+	// follows the same rules as FToInt does.
+	FToUint
+)
+
+// Arity returns number of arguments of op.
+func (op FloatOp) Arity() int {
+	switch op {
+	case FSqrt, FConvert, FFromInt, FFromUint, FToInt, FToUint:
+		return 1
+	case FMulAdd:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// Conversion indicates that op converts in between values of different
+// widths or types.
+func (op FloatOp) Conversion() bool {
+	switch op {
+	case FConvert, FFromInt, FFromUint, FToInt, FToUint:
+		return true
+	default:
+		return false
+	}
+}
+
+// Rounding represents IEEE 754 rounding direction.
+type Rounding uint8
+
+const (
+	// RoundNearestEven rounds to the nearest value. Ties are rounded to the
+	// value with even least significant digit.
+	RoundNearestEven Rounding = iota
+	// RoundTowardZero rounds toward zero (truncates).
+	RoundTowardZero
+	// RoundDown rounds toward negative infinity.
+	RoundDown
+	// RoundUp rounds toward positive infinity.
+	RoundUp
+	// RoundNearestMaxMagnitude rounds to the nearest value. Ties are
+	// rounded away from zero.
+	RoundNearestMaxMagnitude
+)
+
+// Const returns constant expression representing r.
+func (r Rounding) Const() Const { return ConstFromUint(uint8(r)) }
+
+// FloatException represents bits of IEEE 754 exception flags.
+type FloatException uint8
+
+const (
+	// FloatInexact is raised when a result was rounded.
+	FloatInexact FloatException = 1 << iota
+	// FloatUnderflow is raised when a tiny result was rounded.
+	FloatUnderflow
+	// FloatOverflow is raised when a result didn't fit the format.
+	FloatOverflow
+	// FloatDivByZero is raised by division of a finite number by zero.
+	FloatDivByZero
+	// FloatInvalid is raised by operations without a meaningful result.
+	FloatInvalid
+)
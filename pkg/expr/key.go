@@ -20,12 +20,41 @@ const (
 	// address is equivalent to no jump at all, register write of a
 	// conditional expression can be used to represent conditional jump.
 	//
-	// Please note that this design of jump expressions is not sufficient to
-	// describe jump delay in any reasonable way. To support architectures
-	// where jump don't take an immediate effect some sort of redesign of
-	// this package or at least of this way of expressing jumps will be
-	// necessary.
+	// Jumps which don't take an immediate effect are expressed by writes
+	// of DelayedIPKey.
 	IPKey Key = "#r:w:ip"
+
+	// DelayedIPKey identifies instruction pointer register of delayed
+	// jumps. Writes to this register will be interpreted as jumps which
+	// take effect only after the instruction following the jump in memory
+	// (a delay slot) executes. The delay slot is executed regardless of
+	// whether the jump is taken or not.
+	//
+	// The value written is the address where the execution continues
+	// after the delay slot. The same way as for IPKey, conditional delayed
+	// jump has to unconditionally write this register. A jump which is not
+	// taken writes the address following the delay slot, so the execution
+	// continues behind the delay slot. A write of address of the delay
+	// slot is a jump into the delay slot which executes the slot again.
+	//
+	// An instruction must not write both IPKey and DelayedIPKey and there
+	// must be no jump in the delay slot.
+	DelayedIPKey Key = "#r:w:dip"
+
+	// CycleKey identifies a read-only register counting clock cycles the
+	// program has been running for. The value is 64 bits wide and it's
+	// provided by whoever executes the code, the register cannot be
+	// written by instructions.
+	CycleKey Key = "#r:r:cycle"
+	// TimeKey identifies a read-only register holding wall-clock time
+	// counter. The value is 64 bits wide and it's provided by whoever
+	// executes the code, the register cannot be written by instructions.
+	TimeKey Key = "#r:r:time"
+	// InstretKey identifies a read-only register counting instructions
+	// retired by the program. The value is 64 bits wide and it's provided
+	// by whoever executes the code, the register cannot be written by
+	// instructions.
+	InstretKey Key = "#r:r:instret"
 )
 
 // Key represents an arbitrary memory or register key used to identify memory
@@ -47,7 +76,7 @@ func NewKey(s string) Key { return Key(s) }
 // package.
 func (k Key) allowedReserved() bool {
 	switch k {
-	case IPKey:
+	case IPKey, DelayedIPKey, CycleKey, TimeKey, InstretKey:
 		return true
 	default:
 		return false
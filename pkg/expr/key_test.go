@@ -109,6 +109,17 @@ func TestKey_Validate(t *testing.T) {
 		k:    "#r:w:ip",
 		s:    'r',
 		p:    'w',
+	}, {
+		name: "cycle_counter",
+		k:    "#r:r:cycle",
+		s:    'r',
+		p:    'r',
+	}, {
+		name:   "cycle_counter_write",
+		k:      "#r:r:cycle",
+		s:      'r',
+		p:      'w',
+		hasErr: true,
 	}, {
 		name:   "empty",
 		k:      "",
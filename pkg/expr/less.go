@@ -53,6 +53,9 @@ func (l Less) ExprTrue() Expr { return l.trueExpr }
 // ExprTrue returns the expression returned in case of condition being false.
 func (l Less) ExprFalse() Expr { return l.falseExpr }
 
+// String returns l in the S-expression format. See Parse for details.
+func (l Less) String() string { return exprString(l) }
+
 // Width returns width of l.
 func (l Less) Width() Width { return l.w }
 func (Less) internalExpr()  {}
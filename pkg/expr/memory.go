@@ -34,6 +34,9 @@ func (l MemLoad) Key() Key { return l.key }
 // Addr returns address of memory load.
 func (l MemLoad) Addr() Expr { return l.addr }
 
+// String returns l in the S-expression format. See Parse for details.
+func (l MemLoad) String() string { return exprString(l) }
+
 // Width returns width of l.
 func (l MemLoad) Width() Width { return l.w }
 func (MemLoad) internalExpr()  {}
@@ -72,6 +75,9 @@ func (s MemStore) Key() Key { return s.key }
 // Addr returns address of memory store.
 func (s MemStore) Addr() Expr { return s.addr }
 
+// String returns s in the S-expression format. See Parse for details.
+func (s MemStore) String() string { return effectString(s) }
+
 // Width returns width of s.
 func (s MemStore) Width() Width  { return s.w }
 func (MemStore) internalEffect() {}
@@ -0,0 +1,416 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Parse parses an expression written in the canonical S-expression format.
+// String method of every expression returns the expression in this format and
+// Parse(e.String()) returns an expression equal to e.
+//
+// Every node of an expression is written as a list starting with a head of form
+// name:width followed by arguments of the node. Widths are written in bytes.
+// For example:
+//
+//	(add:8 (reg:8 x1) (const:8 0x10))
+//
+// Constants are written as hexadecimal numbers without leading zeros. Keys are
+// written as they are unless they contain whitespace, parentheses or quotes,
+// in which case they are written as Go string literals. Floating point
+// conversions write the width of their argument as another component of the
+// head, so (ftoint:4:8 rm arg) converts an 8 byte float into a 4 byte integer.
+// Floating point operations returning exception flags have .flags suffix of
+// their name. The list of all expression nodes is:
+//
+//	(const:w value)
+//	(reg:w key)
+//	(mem:w key addr)
+//	(less:w arg1 arg2 true false)
+//	(add:w arg1 arg2), and analogously lsh, rsh, mul, div and nand
+//	(fadd:w rm arg1 arg2), and analogously fsub, fmul, fdiv, fmin, fmax,
+//	    feq, flt and fle
+//	(fsqrt:w rm arg)
+//	(fmuladd:w rm arg1 arg2 arg3)
+//	(fconvert:w:argw rm arg), and analogously ffromint, ffromuint, ftoint
+//	    and ftouint
+func Parse(s string) (Expr, error) {
+	p := sexprParser{s: s}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ParseEffect parses an effect written in the canonical S-expression format.
+// String method of every effect returns the effect in this format and
+// ParseEffect(ef.String()) returns an effect equal to ef.
+//
+// The format of effects is following (see Parse for details):
+//
+//	(reg-store:w key value)
+//	(mem-store:w key addr value)
+func ParseEffect(s string) (Effect, error) {
+	p := sexprParser{s: s}
+	ef, err := p.effect()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	return ef, nil
+}
+
+// sexprParser parses expressions and effects written in the S-expression
+// format.
+type sexprParser struct {
+	// s is the string parsed.
+	s string
+	// pos is the position of the next character of s to parse.
+	pos int
+}
+
+// errorf returns an error at the current position of the parser.
+func (p *sexprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// skipSpace moves the parser behind all whitespace characters.
+func (p *sexprParser) skipSpace() {
+	for p.pos < len(p.s) {
+		r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		p.pos += size
+	}
+}
+
+// end checks that there is nothing but whitespace left in the input.
+func (p *sexprParser) end() error {
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return p.errorf("unexpected trailing characters")
+	}
+	return nil
+}
+
+// expect reads character c.
+func (p *sexprParser) expect(c byte) error {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return p.errorf("expected %q, got end of input", c)
+	}
+	if p.s[p.pos] != c {
+		return p.errorf("expected %q, got %q", c, p.s[p.pos])
+	}
+	p.pos++
+	return nil
+}
+
+// atom reads a token which is not a list. The token is terminated by
+// whitespace or a parenthesis.
+func (p *sexprParser) atom() (string, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) {
+		r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+		if unicode.IsSpace(r) || r == '(' || r == ')' {
+			break
+		}
+		p.pos += size
+	}
+
+	if p.pos == start {
+		return "", p.errorf("expected atom")
+	}
+	return p.s[start:p.pos], nil
+}
+
+// key reads a key, either bare or quoted.
+func (p *sexprParser) key() (Key, error) {
+	p.skipSpace()
+	if p.pos >= len(p.s) || p.s[p.pos] != '"' {
+		a, err := p.atom()
+		return Key(a), err
+	}
+
+	quoted, err := strconv.QuotedPrefix(p.s[p.pos:])
+	if err != nil {
+		return "", p.errorf("invalid quoted key: %v", err)
+	}
+	p.pos += len(quoted)
+
+	k, _ := strconv.Unquote(quoted)
+	return Key(k), nil
+}
+
+// validKey reads a key and validates it in scope s with permissions perm.
+func (p *sexprParser) validKey(s keyScope, perm keyPermission) (Key, error) {
+	k, err := p.key()
+	if err != nil {
+		return "", err
+	}
+	if err := k.validate(s, perm); err != nil {
+		return "", p.errorf("invalid key %q: %v", k, err)
+	}
+	return k, nil
+}
+
+// head reads an opening parenthesis and a head of a list. It returns name from
+// the head and all widths following the name. Widths must be non-zero, only
+// constants can be empty.
+func (p *sexprParser) head() (string, []Width, error) {
+	if err := p.expect('('); err != nil {
+		return "", nil, err
+	}
+
+	a, err := p.atom()
+	if err != nil {
+		return "", nil, err
+	}
+
+	parts := strings.Split(a, ":")
+	ws := make([]Width, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		w, err := strconv.ParseUint(part, 10, 8)
+		if err != nil || (w == 0 && parts[0] != constName) {
+			return "", nil, p.errorf("invalid width %q of %s", part, parts[0])
+		}
+		ws = append(ws, Width(w))
+	}
+
+	return parts[0], ws, nil
+}
+
+// width returns the only width of a node name of widths ws.
+func (p *sexprParser) width(name string, ws []Width) (Width, error) {
+	if len(ws) != 1 {
+		return 0, p.errorf("%s requires one width, got %d", name, len(ws))
+	}
+	return ws[0], nil
+}
+
+// exprs reads all expressions until the end of a list including the closing
+// parenthesis.
+func (p *sexprParser) exprs() ([]Expr, error) {
+	var es []Expr
+	for {
+		p.skipSpace()
+		if p.pos < len(p.s) && p.s[p.pos] == ')' {
+			p.pos++
+			return es, nil
+		}
+
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+}
+
+// exprsN reads exactly n expressions of node name until the end of a list.
+func (p *sexprParser) exprsN(name string, n int) ([]Expr, error) {
+	es, err := p.exprs()
+	if err != nil {
+		return nil, err
+	}
+	if len(es) != n {
+		return nil, p.errorf("%s requires %d arguments, got %d", name, n, len(es))
+	}
+	return es, nil
+}
+
+// constValue reads a hexadecimal value of a constant of width w.
+func (p *sexprParser) constValue(w Width) (Const, error) {
+	a, err := p.atom()
+	if err != nil {
+		return Const{}, err
+	}
+
+	digits := strings.TrimPrefix(a, "0x")
+	if len(digits) == len(a) || digits == "" {
+		return Const{}, p.errorf("constant is not hexadecimal: %q", a)
+	}
+
+	bs := make([]byte, 0, (len(digits)+1)/2)
+	for end := len(digits); end > 0; end -= 2 {
+		start := end - 2
+		if start < 0 {
+			start = 0
+		}
+
+		b, err := strconv.ParseUint(digits[start:end], 16, 8)
+		if err != nil {
+			return Const{}, p.errorf("constant is not hexadecimal: %q", a)
+		}
+		bs = append(bs, byte(b))
+	}
+
+	for len(bs) > int(w) {
+		if bs[len(bs)-1] != 0 {
+			return Const{}, p.errorf("constant doesn't fit width %d: %s", w, a)
+		}
+		bs = bs[:len(bs)-1]
+	}
+
+	return NewConst(bs, w), nil
+}
+
+// expr reads an expression.
+func (p *sexprParser) expr() (Expr, error) {
+	name, ws, err := p.head()
+	if err != nil {
+		return nil, err
+	}
+
+	if op, ok := parseFloatOp(name); ok {
+		return p.float(name, op, ws)
+	}
+
+	w, err := p.width(name, ws)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case constName:
+		c, err := p.constValue(w)
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(')')
+	case regName:
+		k, err := p.validKey(keyScopeReg, keyPermissionRead)
+		if err != nil {
+			return nil, err
+		}
+		return NewRegLoad(k, w), p.expect(')')
+	case memName:
+		k, err := p.validKey(keyScopeMem, keyPermissionRead)
+		if err != nil {
+			return nil, err
+		}
+		es, err := p.exprsN(name, 1)
+		if err != nil {
+			return nil, err
+		}
+		return NewMemLoad(k, es[0], w), nil
+	case lessName:
+		es, err := p.exprsN(name, 4)
+		if err != nil {
+			return nil, err
+		}
+		return NewLess(es[0], es[1], es[2], es[3], w), nil
+	}
+
+	for op, opName := range binaryOpNames {
+		if name != opName {
+			continue
+		}
+
+		es, err := p.exprsN(name, 2)
+		if err != nil {
+			return nil, err
+		}
+		return NewBinary(op, es[0], es[1], w), nil
+	}
+
+	return nil, p.errorf("unknown expression: %s", name)
+}
+
+// parseFloatOp returns floating point operation of node name. It returns false
+// if name is not a name of a floating point operation.
+func parseFloatOp(name string) (FloatOp, bool) {
+	name = strings.TrimSuffix(name, flagsSuffix)
+	for op, opName := range floatOpNames {
+		if name == opName {
+			return op, true
+		}
+	}
+	return 0, false
+}
+
+// float reads arguments of floating point operation op of node name with
+// widths ws.
+func (p *sexprParser) float(name string, op FloatOp, ws []Width) (Expr, error) {
+	var w, argW Width
+	if op.Conversion() {
+		if len(ws) != 2 {
+			return nil, p.errorf("%s requires two widths, got %d", name, len(ws))
+		}
+		w, argW = ws[0], ws[1]
+	} else {
+		var err error
+		if w, err = p.width(name, ws); err != nil {
+			return nil, err
+		}
+		argW = w
+	}
+
+	es, err := p.exprs()
+	if err != nil {
+		return nil, err
+	}
+	if len(es) == 0 {
+		return nil, p.errorf("%s requires rounding mode", name)
+	}
+
+	rm, args := es[0], es[1:]
+	if err := validateFloat(op, argW, w, len(args)); err != nil {
+		return nil, p.errorf("invalid %s: %v", name, err)
+	}
+
+	f := newFloat(op, rm, argW, w, args)
+	if strings.HasSuffix(name, flagsSuffix) {
+		f = f.Flags()
+	}
+	return f, nil
+}
+
+// effect reads an effect.
+func (p *sexprParser) effect() (Effect, error) {
+	name, ws, err := p.head()
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := p.width(name, ws)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case regStoreName:
+		k, err := p.validKey(keyScopeReg, keyPermissionWrite)
+		if err != nil {
+			return nil, err
+		}
+		es, err := p.exprsN(name, 1)
+		if err != nil {
+			return nil, err
+		}
+		return NewRegStore(es[0], k, w), nil
+	case memStoreName:
+		k, err := p.validKey(keyScopeMem, keyPermissionWrite)
+		if err != nil {
+			return nil, err
+		}
+		es, err := p.exprsN(name, 2)
+		if err != nil {
+			return nil, err
+		}
+		return NewMemStore(es[1], k, es[0], w), nil
+	default:
+		return nil, p.errorf("unknown effect: %s", name)
+	}
+}
@@ -30,6 +30,9 @@ func (l1 RegLoad) Equal(l2 RegLoad) bool {
 	return l1.Key() == l2.Key() && l1.Width() == l2.Width()
 }
 
+// String returns l in the S-expression format. See Parse for details.
+func (l RegLoad) String() string { return exprString(l) }
+
 // Width returns width of cl
 func (l RegLoad) Width() Width { return l.w }
 func (RegLoad) internalExpr()  {}
@@ -67,6 +70,9 @@ func (s1 RegStore) Equal(s2 MemStore) bool {
 	return s1.Key() == s2.Key() && s1.Width() == s2.Width()
 }
 
+// String returns s in the S-expression format. See Parse for details.
+func (s RegStore) String() string { return effectString(s) }
+
 // Width returns width of s.
 func (s RegStore) Width() Width  { return s.w }
 func (RegStore) internalEffect() {}
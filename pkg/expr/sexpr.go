@@ -0,0 +1,193 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	constName    = "const"
+	regName      = "reg"
+	memName      = "mem"
+	lessName     = "less"
+	regStoreName = "reg-store"
+	memStoreName = "mem-store"
+
+	// flagsSuffix is a suffix of names of floating point operations which
+	// return exception flags.
+	flagsSuffix = ".flags"
+)
+
+// binaryOpNames maps binary operations to their names.
+var binaryOpNames = map[BinaryOp]string{
+	Add:  "add",
+	Lsh:  "lsh",
+	Rsh:  "rsh",
+	Mul:  "mul",
+	Div:  "div",
+	Nand: "nand",
+}
+
+// floatOpNames maps floating point operations to their names.
+var floatOpNames = map[FloatOp]string{
+	FAdd:      "fadd",
+	FSub:      "fsub",
+	FMul:      "fmul",
+	FDiv:      "fdiv",
+	FSqrt:     "fsqrt",
+	FMulAdd:   "fmuladd",
+	FMin:      "fmin",
+	FMax:      "fmax",
+	FEq:       "feq",
+	FLt:       "flt",
+	FLe:       "fle",
+	FConvert:  "fconvert",
+	FFromInt:  "ffromint",
+	FFromUint: "ffromuint",
+	FToInt:    "ftoint",
+	FToUint:   "ftouint",
+}
+
+// sexprWriter writes expressions and effects in the S-expression format
+// described by Parse.
+type sexprWriter struct {
+	strings.Builder
+}
+
+// head writes an opening parenthesis followed by name and widths ws.
+func (w *sexprWriter) head(name string, ws ...Width) {
+	w.WriteByte('(')
+	w.WriteString(name)
+	for _, width := range ws {
+		w.WriteByte(':')
+		w.WriteString(strconv.Itoa(int(width)))
+	}
+}
+
+// key writes key k.
+func (w *sexprWriter) key(k Key) {
+	w.WriteByte(' ')
+	if bareKey(k) {
+		w.WriteString(string(k))
+	} else {
+		w.WriteString(strconv.Quote(string(k)))
+	}
+}
+
+// bareKey indicates that key k can be written without quotes.
+func bareKey(k Key) bool {
+	if k == "" {
+		return false
+	}
+	for _, r := range k {
+		if !unicode.IsGraphic(r) || unicode.IsSpace(r) || strings.ContainsRune(`()"`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// args writes all expressions es, each of them preceded by a space, followed
+// by a closing parenthesis.
+func (w *sexprWriter) args(es ...Expr) {
+	for _, e := range es {
+		w.WriteByte(' ')
+		w.expr(e)
+	}
+	w.WriteByte(')')
+}
+
+// constValue writes value of c as a hexadecimal number.
+func (w *sexprWriter) constValue(c Const) {
+	bs := c.Bytes()
+	i := len(bs) - 1
+	for i > 0 && bs[i] == 0 {
+		i--
+	}
+
+	w.WriteString(" 0x")
+	if i < 0 {
+		w.WriteByte('0')
+		return
+	}
+
+	w.WriteString(strconv.FormatUint(uint64(bs[i]), 16))
+	for i--; i >= 0; i-- {
+		fmt.Fprintf(w, "%02x", bs[i])
+	}
+}
+
+func (w *sexprWriter) expr(e Expr) {
+	switch e := e.(type) {
+	case Const:
+		w.head(constName, e.Width())
+		w.constValue(e)
+		w.WriteByte(')')
+	case RegLoad:
+		w.head(regName, e.Width())
+		w.key(e.Key())
+		w.WriteByte(')')
+	case MemLoad:
+		w.head(memName, e.Width())
+		w.key(e.Key())
+		w.args(e.Addr())
+	case Binary:
+		name, ok := binaryOpNames[e.Op()]
+		if !ok {
+			name = fmt.Sprintf("binary%d", e.Op())
+		}
+		w.head(name, e.Width())
+		w.args(e.Arg1(), e.Arg2())
+	case Less:
+		w.head(lessName, e.Width())
+		w.args(e.Arg1(), e.Arg2(), e.ExprTrue(), e.ExprFalse())
+	case Float:
+		name, ok := floatOpNames[e.Op()]
+		if !ok {
+			name = fmt.Sprintf("float%d", e.Op())
+		}
+		if e.IsFlags() {
+			name += flagsSuffix
+		}
+
+		if e.Op().Conversion() {
+			w.head(name, e.Width(), e.ArgWidth())
+		} else {
+			w.head(name, e.Width())
+		}
+		w.args(append([]Expr{e.Rounding()}, e.Args()...)...)
+	default:
+		w.WriteString("nil")
+	}
+}
+
+func (w *sexprWriter) effect(ef Effect) {
+	switch ef := ef.(type) {
+	case RegStore:
+		w.head(regStoreName, ef.Width())
+		w.key(ef.Key())
+		w.args(ef.Value())
+	case MemStore:
+		w.head(memStoreName, ef.Width())
+		w.key(ef.Key())
+		w.args(ef.Addr(), ef.Value())
+	default:
+		w.WriteString("nil")
+	}
+}
+
+// exprString returns e in the S-expression format.
+func exprString(e Expr) string {
+	var w sexprWriter
+	w.expr(e)
+	return w.String()
+}
+
+// effectString returns ef in the S-expression format.
+func effectString(ef Effect) string {
+	var w sexprWriter
+	w.effect(ef)
+	return w.String()
+}
@@ -0,0 +1,149 @@
+package expr_test
+
+import (
+	"mltwist/pkg/expr"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExprString(t *testing.T) {
+	x1 := expr.NewRegLoad("x1", expr.Width64)
+	c16 := expr.NewConstUint[uint8](0x10, expr.Width64)
+	rm := expr.NewConstUint[uint8](0, expr.Width8)
+
+	tests := []struct {
+		name string
+		e    expr.Expr
+		str  string
+	}{{
+		name: "binary",
+		e:    expr.NewBinary(expr.Add, x1, c16, expr.Width64),
+		str:  "(add:8 (reg:8 x1) (const:8 0x10))",
+	}, {
+		name: "const",
+		e:    expr.NewConstUint[uint32](0x1020304, expr.Width32),
+		str:  "(const:4 0x1020304)",
+	}, {
+		name: "const_zero",
+		e:    expr.NewConstUint[uint8](0, expr.Width16),
+		str:  "(const:2 0x0)",
+	}, {
+		name: "const_empty",
+		e:    expr.NewConst(nil, 0),
+		str:  "(const:0 0x0)",
+	}, {
+		name: "reserved_key",
+		e:    expr.NewRegLoad(expr.CycleKey, expr.Width64),
+		str:  "(reg:8 #r:r:cycle)",
+	}, {
+		name: "quoted_key",
+		e:    expr.NewMemLoad("my mem", x1, expr.Width8),
+		str:  `(mem:1 "my mem" (reg:8 x1))`,
+	}, {
+		name: "less",
+		e:    expr.NewLess(x1, c16, expr.One, expr.Zero, expr.Width8),
+		str:  "(less:1 (reg:8 x1) (const:8 0x10) (const:1 0x1) (const:1 0x0))",
+	}, {
+		name: "float",
+		e:    expr.NewFloat(expr.FAdd, rm, expr.Width64, x1, c16),
+		str:  "(fadd:8 (const:1 0x0) (reg:8 x1) (const:8 0x10))",
+	}, {
+		name: "float_flags",
+		e:    expr.NewFloat(expr.FSqrt, rm, expr.Width64, x1).Flags(),
+		str:  "(fsqrt.flags:8 (const:1 0x0) (reg:8 x1))",
+	}, {
+		name: "float_conversion",
+		e:    expr.NewFloatConversion(expr.FToInt, rm, x1, expr.Width64, expr.Width32),
+		str:  "(ftoint:4:8 (const:1 0x0) (reg:8 x1))",
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			r.Equal(tt.str, tt.e.String())
+
+			e, err := expr.Parse(tt.str)
+			r.NoError(err)
+			r.Equal(tt.e, e)
+		})
+	}
+}
+
+func TestEffectString(t *testing.T) {
+	x1 := expr.NewRegLoad("x1", expr.Width64)
+
+	tests := []struct {
+		name string
+		ef   expr.Effect
+		str  string
+	}{{
+		name: "reg_store",
+		ef:   expr.NewRegStore(x1, expr.IPKey, expr.Width64),
+		str:  "(reg-store:8 #r:w:ip (reg:8 x1))",
+	}, {
+		name: "mem_store",
+		ef:   expr.NewMemStore(expr.One, "mem", x1, expr.Width8),
+		str:  "(mem-store:1 mem (reg:8 x1) (const:1 0x1))",
+	}}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			r.Equal(tt.str, tt.ef.String())
+
+			ef, err := expr.ParseEffect(tt.str)
+			r.NoError(err)
+			r.Equal(tt.ef, ef)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	r := require.New(t)
+
+	e, err := expr.Parse(" (add:2\n\t(const:2 0x00ff)(reg:2 \"x1\") ) ")
+	r.NoError(err)
+	r.Equal(expr.NewBinary(
+		expr.Add,
+		expr.NewConstUint[uint8](0xff, expr.Width16),
+		expr.NewRegLoad("x1", expr.Width16),
+		expr.Width16,
+	), e)
+
+	invalid := []string{
+		"",
+		"(add:8 (const:8 0x1))",
+		"(add:8 (const:8 0x1) (const:8 0x1)",
+		"(add:8 (const:8 0x1) (const:8 0x1)) x",
+		"(add (const:8 0x1) (const:8 0x1))",
+		"(add:256 (const:8 0x1) (const:8 0x1))",
+		"(add:0 (const:0 0x0) (const:0 0x0))",
+		"(reg:0 x1)",
+		"(ftoint:8:0 (const:1 0x0) (const:8 0x0))",
+		"(xor:8 (const:8 0x1) (const:8 0x1))",
+		"(const:1 0x100)",
+		"(const:1 16)",
+		"(const:1 0xg)",
+		"(reg:8 #r:x:unknown)",
+		"(reg:8 \"\")",
+		"(fsqrt:3 (const:1 0x0) (const:3 0x0))",
+		"(fsqrt:8 (const:1 0x0))",
+		"(ftoint:8 (const:1 0x0) (const:8 0x0))",
+		"(reg-store:8 x1 (const:8 0x0))",
+	}
+	for _, s := range invalid {
+		_, err := expr.Parse(s)
+		r.Error(err, s)
+	}
+
+	// Read-only registers cannot be written.
+	_, err = expr.ParseEffect("(reg-store:8 #r:r:cycle (const:8 0x0))")
+	r.Error(err)
+	_, err = expr.Parse("(reg:8 #r:r:cycle)")
+	r.NoError(err)
+}
@@ -32,3 +32,8 @@ const (
 
 // AddrWidth is expression width capable of capturing any addr value.
 const AddrWidth = expr.Width(unsafe.Sizeof(Addr(0)))
+
+// Offset returns signed distance from address from to address to. The
+// subtraction of addresses can overflow, but as addresses wrap around, the
+// result is correct for any distance representable by int64.
+func Offset(from, to Addr) int64 { return int64(to - from) }
@@ -11,6 +11,9 @@ type Instruction struct {
 	Type Type
 	// ByteLen is length if an instruction opcode in bytes.
 	ByteLen Addr
+	// Order describes memory accesses the instruction orders. The value is
+	// meaningful only for instructions of TypeMemOrder type.
+	Order MemOrder
 
 	Effects []expr.Effect
 
@@ -35,12 +38,66 @@ type PlatformDetails interface {
 	String() string
 }
 
+// Syntax configures how instructions are written in assembler code. The zero
+// value is the syntax of String method of PlatformDetails.
+type Syntax struct {
+	// ABINames selects names of registers defined by the calling
+	// convention of the platform instead of their architectural names.
+	ABINames bool
+	// Aliases enables pseudo-instructions. Instructions (or short
+	// sequences of instructions) which implement a common idiom are
+	// written using the pseudo-instruction assemblers accept for the
+	// idiom.
+	Aliases bool
+}
+
+// Formatter is an optional interface PlatformDetails can implement to support
+// other assembler syntaxes than the one of String method.
+type Formatter interface {
+	// Format returns a string representation of an instruction in
+	// assembler syntax s.
+	//
+	// Argument prev is the instruction preceding the instruction in the
+	// same basic block or nil if there is no such instruction. Some
+	// pseudo-instructions are implemented by a pair of instructions. The
+	// second instruction of such a pair is written as the
+	// pseudo-instruction, so that the pair reads correctly as a sequence
+	// of instructions.
+	Format(s Syntax, prev PlatformDetails) string
+}
+
+// Relocator is an optional interface PlatformDetails can implement to allow an
+// instruction to be moved to a different address in the program.
+//
+// The typical example of an instruction which has to be relocated is a
+// PC-relative instruction (relative jump, relative address computation etc.).
+// Such an instruction encodes an offset from its own address, so if the
+// instruction moved and its bytes remained unchanged, it would refer a
+// different address than before the move.
+type Relocator interface {
+	// Relocate returns platform details and opcode bytes of the instruction
+	// if it was placed at address a instead of its current address. The
+	// instruction returned has to have the same semantics (effects) as the
+	// original instruction and the same length in bytes.
+	//
+	// An error is returned if the instruction cannot be encoded at address
+	// a - for example because a relative offset doesn't fit into the
+	// instruction opcode.
+	Relocate(a Addr) (PlatformDetails, []byte, error)
+}
+
 // Validate assert that an Instruction description is valid (makes sense). If
 // it's not, this method provides a human readable error describing the problem.
 func (i *Instruction) Validate() error {
 	if t := i.Type; t >= TypeMax {
 		return fmt.Errorf("invalid value of type: 0x%x (%d)", t, t)
 	}
+	if i.Order != (MemOrder{}) && !i.Type.MemOrder() {
+		return fmt.Errorf("memory order set for non-memory order instruction")
+	}
+	if i.Order.Pred|i.Order.Succ > AccessAll {
+		return fmt.Errorf("invalid memory order: %+v", i.Order)
+	}
 	if i.ByteLen == 0 {
 		return fmt.Errorf("zero ByteLen makes no sense for an instruction")
 	}
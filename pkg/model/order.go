@@ -0,0 +1,44 @@
+package model
+
+// Access is a set of kinds of memory accesses. Every kind of access is
+// represented by a single bit of the set.
+type Access uint8
+
+const (
+	// AccessRead represents reads of main memory.
+	AccessRead Access = 1 << iota
+	// AccessWrite represents writes to main memory.
+	AccessWrite
+	// AccessInput represents reads of device (I/O) memory.
+	AccessInput
+	// AccessOutput represents writes to device (I/O) memory.
+	AccessOutput
+
+	// AccessAll is a set of all kinds of memory accesses.
+	AccessAll = AccessRead | AccessWrite | AccessInput | AccessOutput
+)
+
+// MemOrder describes which memory accesses an instruction of TypeMemOrder type
+// orders.
+//
+// Memory accesses of kinds in Pred preceding the instruction in the program
+// order have to be performed before the instruction and memory accesses of kinds
+// in Succ following the instruction have to be performed after the instruction.
+// If the instruction accesses memory itself, its own accesses are ordered the
+// same way.
+//
+// The zero value represents a full barrier ordering all memory accesses in both
+// directions. Instructions which don't order any memory accesses shouldn't be
+// of TypeMemOrder type at all.
+type MemOrder struct {
+	Pred Access
+	Succ Access
+}
+
+// Full returns o with the zero value replaced by explicit full barrier.
+func (o MemOrder) Full() MemOrder {
+	if o == (MemOrder{}) {
+		return MemOrder{Pred: AccessAll, Succ: AccessAll}
+	}
+	return o
+}
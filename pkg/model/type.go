@@ -27,6 +27,11 @@ const (
 	// trap which results in operating system interraction with the
 	// userspace program.
 	TypeSyscall
+	// TypeData is a pseudo-instruction representing bytes in between
+	// instructions which are not a valid instruction. Such a
+	// pseudo-instruction has no effects, it cannot be moved and it always
+	// forms a basic block on its own.
+	TypeData
 
 	// TypeMax is maximal exclusive allowed value of Type. Any value higher
 	// or equal to this is invalid.
@@ -45,3 +50,4 @@ func (t Type) Is(other Type) bool { return t&other != 0 }
 func (t Type) MemOrder() bool       { return t.Is(TypeMemOrder) }
 func (t Type) CPUStateChange() bool { return t.Is(TypeCPUStateChange) }
 func (t Type) Syscall() bool        { return t.Is(TypeSyscall) }
+func (t Type) Data() bool           { return t.Is(TypeData) }